@@ -1,10 +1,10 @@
 import type { JSX, ReactNode } from "react"
-import { useState } from "react"
+import { useEffect, useState } from "react"
 import { Link, useLocation, useNavigate } from "react-router-dom"
-import { login, logout } from "../../domains/user/api/user.ts"
+import { isLoginChallenge, login, loginTwoFactor, logout } from "../../domains/user/api/user.ts"
 import { useAuth } from "../contexts/AuthContext.tsx"
 import { LoginDialog } from "./LoginDialog.tsx"
-import { alert } from "@ayonli/jsext/dialog"
+import { alert, prompt } from "@ayonli/jsext/dialog"
 
 interface LayoutProps {
     readonly children: ReactNode
@@ -17,6 +17,41 @@ export function Layout({ children }: LayoutProps): JSX.Element {
     const { loading, user, setUser } = useAuth()
     const [showLoginDialog, setShowLoginDialog] = useState(false)
 
+    // Failed single sign-on sends the user back with the error, and users with
+    // two-factor authentication complete it with a code
+    useEffect(() => {
+        const params = new URLSearchParams(location.search)
+        const ssoError = params.get("sso_error")
+        const twoFactorRequired = params.get("two_factor_required")
+        if (ssoError || twoFactorRequired) {
+            params.delete("sso_error")
+            params.delete("two_factor_required")
+            const search = params.toString()
+            navigate({ search: search ? "?" + search : "" }, { replace: true })
+        }
+
+        if (ssoError) {
+            alert("单点登录失败: " + ssoError)
+        } else if (twoFactorRequired) {
+            void completeSsoTwoFactor()
+        }
+    }, [location.search, navigate])
+
+    async function completeSsoTwoFactor(): Promise<void> {
+        const code = await prompt("请输入两步验证码或恢复码")
+        if (!code) {
+            return
+        }
+
+        // The challenge token of the single sign-on is kept in a cookie
+        const result = await loginTwoFactor({ challenge_token: "", code })
+        if (result.success) {
+            setUser(result.data)
+        } else {
+            await alert("登录失败: " + result.message)
+        }
+    }
+
     async function handleLogout(): Promise<void> {
         const result = await logout()
         if (result.success) {
@@ -28,12 +63,23 @@ export function Layout({ children }: LayoutProps): JSX.Element {
     }
 
     async function handleLogin(email: string, password: string): Promise<void> {
-        const result = await login({ email, password })
-        if (result.success) {
+        let result = await login({ email, password })
+        if (result.success && isLoginChallenge(result.data)) {
+            const code = await prompt("请输入两步验证码或恢复码")
+            if (!code) {
+                throw new Error("需要两步验证码")
+            }
+            result = await loginTwoFactor({
+                challenge_token: result.data.challenge_token,
+                code,
+            })
+        }
+
+        if (result.success && !isLoginChallenge(result.data)) {
             setUser(result.data)
             setShowLoginDialog(false)
         } else {
-            throw new Error(result.message)
+            throw new Error(result.message ?? "登录失败")
         }
     }
 
@@ -1,5 +1,8 @@
 import type { JSX } from "react"
-import { useState } from "react"
+import { useEffect, useState } from "react"
+import { useLocation } from "react-router-dom"
+import { getSsoLoginUrl, listSsoProviders } from "../../domains/user/api/user.ts"
+import type { SsoProvider } from "../../domains/user/types"
 
 interface LoginDialogProps {
     readonly onClose: () => void
@@ -11,6 +14,16 @@ export function LoginDialog({ onClose, onLogin }: LoginDialogProps): JSX.Element
     const [password, setPassword] = useState("")
     const [loading, setLoading] = useState(false)
     const [error, setError] = useState("")
+    const [providers, setProviders] = useState<SsoProvider[]>([])
+    const location = useLocation()
+
+    useEffect(() => {
+        listSsoProviders().then((result) => {
+            if (result.success) {
+                setProviders(result.data)
+            }
+        })
+    }, [])
 
     async function handleSubmit(e: React.FormEvent): Promise<void> {
         e.preventDefault()
@@ -112,6 +125,20 @@ export function LoginDialog({ onClose, onLogin }: LoginDialogProps): JSX.Element
                             {loading ? "登录中..." : "登录"}
                         </button>
                     </div>
+
+                    {providers.length > 0 && (
+                        <div className="pt-4 border-t border-gray-200 space-y-2">
+                            {providers.map((provider) => (
+                                <a
+                                    key={provider.name}
+                                    href={getSsoLoginUrl(provider.name, location.pathname + location.search)}
+                                    className="block w-full text-center border border-gray-300 text-gray-700 px-6 py-2 rounded-lg hover:bg-gray-50"
+                                >
+                                    使用 {provider.display_name} 登录
+                                </a>
+                            ))}
+                        </div>
+                    )}
                 </form>
             </div>
         </div>
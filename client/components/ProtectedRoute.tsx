@@ -2,7 +2,8 @@ import type { JSX, ReactNode } from "react"
 import { useState } from "react"
 import { LoginDialog } from "../components/LoginDialog.tsx"
 import { useAuth } from "../contexts/AuthContext.tsx"
-import { login } from "../../domains/user/api/user.ts"
+import { isLoginChallenge, login, loginTwoFactor } from "../../domains/user/api/user.ts"
+import { prompt } from "@ayonli/jsext/dialog"
 
 interface ProtectedRouteProps {
     readonly children: ReactNode
@@ -13,12 +14,23 @@ export function ProtectedRoute({ children }: ProtectedRouteProps): JSX.Element {
     const [showLoginDialog, setShowLoginDialog] = useState(false)
 
     async function handleLogin(email: string, password: string): Promise<void> {
-        const result = await login({ email, password })
-        if (result.success) {
+        let result = await login({ email, password })
+        if (result.success && isLoginChallenge(result.data)) {
+            const code = await prompt("请输入两步验证码或恢复码")
+            if (!code) {
+                throw new Error("需要两步验证码")
+            }
+            result = await loginTwoFactor({
+                challenge_token: result.data.challenge_token,
+                code,
+            })
+        }
+
+        if (result.success && !isLoginChallenge(result.data)) {
             setUser(result.data)
             setShowLoginDialog(false)
         } else {
-            throw new Error(result.message)
+            throw new Error(result.message ?? "登录失败")
         }
     }
 
@@ -0,0 +1,34 @@
+export interface RealtimeMessage<T = unknown> {
+    topic: string
+    event: string
+    data: T
+    time: string
+}
+
+/**
+ * Subscribes to the realtime topics, e.g. `article:42` or `comments:article:42`,
+ * over Server-Sent Events, calling `onMessage` with the messages of the events,
+ * e.g. `article.updated`. The browser reconnects after the connection drops,
+ * and messages published in between are missed, so what the topics are about
+ * should be reloaded in `onOpen`. Returns the function to unsubscribe.
+ */
+export function subscribe<T = unknown>(
+    topics: string[],
+    events: string[],
+    onMessage: (message: RealtimeMessage<T>) => void,
+    onOpen?: () => void,
+): () => void {
+    const query = new URLSearchParams({ topics: topics.join(",") })
+    const source = new EventSource(`/api/realtime/sse?${query}`, { withCredentials: true })
+    const listener = (event: MessageEvent<string>) => {
+        onMessage(JSON.parse(event.data) as RealtimeMessage<T>)
+    }
+
+    for (const event of events) {
+        source.addEventListener(event, listener)
+    }
+    if (onOpen) {
+        source.addEventListener("open", onOpen)
+    }
+    return () => source.close()
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	domain "bilingo/domains/article"
+	"bilingo/domains/article/service"
+	"bilingo/domains/article/types"
+	"bilingo/server/db"
+	"bilingo/server/oplog"
+)
+
+func init() {
+	register("export", "Export articles as JSON Lines, CSV or a zip of Markdown files", exportArticles)
+	register("import", "Import articles from JSON Lines, CSV or a zip of Markdown files", importArticles)
+}
+
+func optionalFlag(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func exportArticles(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", types.FormatJsonl, "output format: jsonl, csv or markdown")
+	output := fs.String("o", "", "output file, defaults to stdout")
+	search := fs.String("search", "", "only export articles whose title or content contains the text")
+	author := fs.String("author", "", "only export articles of the author, by email or ID")
+	category := fs.String("category", "", "only export articles in the category")
+	_ = fs.Parse(args)
+
+	query := types.ArticleExportQuery{
+		ArticleListQuery: types.ArticleListQuery{
+			Search:   optionalFlag(*search),
+			Author:   optionalFlag(*author),
+			Category: optionalFlag(*category),
+		},
+		Format: *format,
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	return service.ExportArticles(context.Background(), &query, w)
+}
+
+func importArticles(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	format := fs.String("format", types.FormatJsonl, "input format: jsonl, csv or markdown")
+	dryRun := fs.Bool("dry-run", false, "validate the input without importing anything")
+	author := fs.String("author", "", "the author of records that don't specify one, by email or ID")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: bilingo import [options] <file>")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("an input file is required")
+	}
+
+	// Record the imported articles in the oplogs like the server does
+	conn, err := db.For(context.Background(), domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+	if err := conn.Use(&oplog.AuditPlugin{}); err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		_ = oplog.Shutdown(ctx)
+	}()
+
+	file, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to open input file: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read input file: %w", err)
+	}
+
+	report, err := service.ImportArticles(context.Background(), file, stat.Size(), &types.ArticleImportOptions{
+		Format:        *format,
+		DryRun:        *dryRun,
+		DefaultAuthor: optionalFlag(*author),
+	})
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+
+	if report.Failed > 0 {
+		return fmt.Errorf("%d of %d records failed", report.Failed, report.Total)
+	}
+	return nil
+}
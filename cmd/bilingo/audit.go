@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"bilingo/domains/system/service"
+	"bilingo/domains/system/types"
+)
+
+func init() {
+	register("audit:verify", "Verify the hash chain of the audit log, or of an exported one", verifyAuditChain)
+	register("audit:export", "Export the hash chain of the audit log for offline verification", exportAuditChain)
+}
+
+func verifyAuditChain(args []string) error {
+	fs := flag.NewFlagSet("audit:verify", flag.ExitOnError)
+	file := fs.String("file", "", "verify an exported chain instead of the database")
+	_ = fs.Parse(args)
+
+	var report *types.OpLogChainReport
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return fmt.Errorf("failed to open input file: %w", err)
+		}
+		defer f.Close()
+
+		if report, err = service.VerifyOpLogChainExport(f); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if report, err = service.VerifyOpLogChain(context.Background()); err != nil {
+			return err
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+
+	if !report.Valid {
+		return fmt.Errorf("the chain is broken at seq %d: %s", *report.BrokenAt, *report.Reason)
+	}
+	return nil
+}
+
+func exportAuditChain(args []string) error {
+	fs := flag.NewFlagSet("audit:export", flag.ExitOnError)
+	output := fs.String("o", "", "output file, defaults to stdout")
+	_ = fs.Parse(args)
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	return service.ExportOpLogChain(context.Background(), w)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+type command struct {
+	name        string
+	description string
+	run         func(args []string) error
+}
+
+var commands []command
+
+func init() {
+	// Load .env file if it exists (ignore errors if file doesn't exist)
+	_ = godotenv.Load()
+}
+
+func register(name string, description string, run func(args []string) error) {
+	commands = append(commands, command{name: name, description: description, run: run})
+}
+
+func usage() {
+	var sb strings.Builder
+	sb.WriteString("Usage: bilingo <command> [options]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(&sb, "  %-16s %s\n", cmd.name, cmd.description)
+	}
+	sb.WriteString("\nRun 'bilingo <command> -h' for the options of a command.\n")
+	fmt.Fprint(os.Stderr, sb.String())
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	idx := slices.IndexFunc(commands, func(cmd command) bool {
+		return cmd.name == os.Args[1]
+	})
+	if idx == -1 {
+		usage()
+		os.Exit(2)
+	}
+
+	if err := commands[idx].run(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
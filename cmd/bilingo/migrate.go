@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"bilingo/server/db"
+
+	_ "bilingo/domains/article/repo/db"
+	_ "bilingo/domains/system/repo/db"
+	_ "bilingo/domains/user/repo/db"
+)
+
+func init() {
+	register("migrate", "Apply pending database migrations to every database", migrate)
+}
+
+func migrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	_ = fs.Parse(args)
+
+	return db.Migrate(context.Background())
+}
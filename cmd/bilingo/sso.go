@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+
+	"bilingo/server/sso/mockidp"
+)
+
+func init() {
+	register("sso:mock", "Run a mock OpenID Connect issuer for developing single sign-on", runMockIdp)
+}
+
+func runMockIdp(args []string) error {
+	fs := flag.NewFlagSet("sso:mock", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:9000", "the address to listen on")
+	issuer := fs.String("issuer", "http://localhost:9000", "the issuer URL, as configured for the provider")
+	clientId := fs.String("client-id", "bilingo", "the client ID accepted")
+	clientSecret := fs.String("client-secret", "bilingo-secret", "the client secret accepted")
+	_ = fs.Parse(args)
+
+	server := &mockidp.Server{
+		Issuer:       *issuer,
+		ClientId:     *clientId,
+		ClientSecret: *clientSecret,
+	}
+
+	fmt.Printf("Mock OpenID Connect issuer listening on %s, log in as anyone with `login_hint=<email>`\n", *addr)
+	return http.ListenAndServe(*addr, server)
+}
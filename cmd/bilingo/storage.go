@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+
+	"bilingo/server/storage/mocks3"
+)
+
+func init() {
+	register("storage:mock", "Run a mock S3-compatible server for developing the S3 storage", runMockS3)
+}
+
+func runMockS3(args []string) error {
+	fs := flag.NewFlagSet("storage:mock", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:9001", "the address to listen on")
+	bucket := fs.String("bucket", "bilingo", "the bucket served")
+	region := fs.String("region", "us-east-1", "the region requests are signed for")
+	accessKey := fs.String("access-key", "bilingo", "the access key accepted")
+	secretKey := fs.String("secret-key", "bilingo-secret", "the secret key of the access key")
+	_ = fs.Parse(args)
+
+	server := &mocks3.Server{
+		Bucket:    *bucket,
+		Region:    *region,
+		AccessKey: *accessKey,
+		SecretKey: *secretKey,
+	}
+
+	fmt.Printf("Mock S3 server listening on %s, objects are kept in memory at /%s/<key>\n", *addr, *bucket)
+	return http.ListenAndServe(*addr, server)
+}
@@ -21,6 +21,15 @@ export interface Range<T extends unknown> {
     end?: T
 }
 
+/**
+ * TocEntry is a heading in the table of contents of rendered Markdown.
+ */
+export interface TocEntry {
+    level: number /* int */
+    id: string // The ID of the heading element to link to
+    text: string
+}
+
 export interface PaginatedQuery {
     page: number /* int */
     page_size: number /* int */
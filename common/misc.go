@@ -4,3 +4,10 @@ type Range[T any] struct {
 	Start T `json:"start" query:"start" form:"start"`
 	End   T `json:"end" query:"end" form:"end"`
 }
+
+// TocEntry is a heading in the table of contents of rendered Markdown.
+type TocEntry struct {
+	Level int    `json:"level"`
+	ID    string `json:"id"` // The ID of the heading element to link to
+	Text  string `json:"text"`
+}
@@ -7,8 +7,39 @@ var Dev = Config{
 	AppUrl:  "http://localhost:5173",
 	DBUrl:   "sqlite://bilingo.db",
 	Auth: AuthConfig{
-		CookieName: "auth_token",
-		Duration:   7 * 24 * time.Hour, // 7 days
-		Secret:     "bilingo-secret-key-change-in-production",
+		CookieName:   "auth_token",
+		Duration:     7 * 24 * time.Hour, // 7 days
+		Secret:       "bilingo-secret-key-change-in-production",
+		Registration: RegistrationOpen,
+		Sso: []SsoProviderConfig{
+			{
+				// Started with `npm run cli -- sso:mock`
+				Name:          "mock",
+				DisplayName:   "Mock SSO",
+				Issuer:        "http://localhost:9000",
+				ClientId:      "bilingo",
+				ClientSecret:  "bilingo-secret",
+				AutoProvision: true,
+			},
+		},
+	},
+	OpLog: OpLogConfig{
+		Retention: 0, // keep forever
+	},
+	Password: PasswordConfig{
+		RejectCommon: true,
+	},
+	Privacy: PrivacyConfig{
+		DeletionPolicy:   DeletionAnonymize,
+		DeletionPolicies: []string{DeletionAnonymize, DeletionCascade},
+	},
+	Storage: StorageConfig{
+		Driver: StorageLocal,
+		Dir:    "tmp/storage",
+	},
+	Mail: MailConfig{
+		Transport: MailFile,
+		From:      "noreply@localhost",
+		Dir:       "tmp/mails",
 	},
 }
@@ -1,35 +1,291 @@
 package config
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"os"
 	"time"
 
 	"github.com/joho/godotenv"
 )
 
+const (
+	RegistrationClosed = "closed" // Only authenticated users can create accounts
+	RegistrationInvite = "invite" // Anyone invited by an admin can sign up
+	RegistrationOpen   = "open"   // Anyone can sign up
+)
+
 type AuthConfig struct {
-	CookieName string        // The name of the authentication cookie
-	Duration   time.Duration // The duration for which the authentication is valid
-	Secret     string        // The secret key used for authentication
+	CookieName   string        // The name of the authentication cookie
+	Duration     time.Duration // The duration for which the authentication is valid
+	Secret       string        // The secret key used for authentication
+	Admins       []string      // The emails of users granted administrative privileges
+	Registration string        // Who can sign up, one of the Registration* modes, defaults to closed
+	// How long the links sent for email verification and invitations are valid
+	VerificationDuration time.Duration
+	InviteDuration       time.Duration
+	ResetDuration        time.Duration // How long password reset links are valid
+	ChallengeDuration    time.Duration // How long users have to enter the two-factor code after the password
+	Sso                  []SsoProviderConfig
+}
+
+// SsoProviderConfig is an external OpenID Connect identity provider users can
+// log in with.
+type SsoProviderConfig struct {
+	Name         string   // The name used in the login URLs, e.g. `company`
+	DisplayName  string   // The name shown to users, defaults to Name
+	Issuer       string   // The issuer URL, the provider is discovered from it
+	ClientId     string   // The client registered at the provider
+	ClientSecret string   // The secret of the client, if it's confidential
+	Scopes       []string // The scopes requested, defaults to openid, email and profile
+	// Whether users without an account are created on their first login,
+	// otherwise only users with an account of the same verified email can log in
+	AutoProvision  bool
+	AllowedDomains []string // The email domains of users allowed to log in, empty means any
+}
+
+const (
+	HashBcrypt   = "bcrypt"
+	HashArgon2id = "argon2id"
+)
+
+type PasswordConfig struct {
+	MinLength     int  // The minimum number of characters, defaults to 8
+	MaxLength     int  // The maximum number of bytes, defaults to 72, which is the limit of bcrypt
+	RequireUpper  bool // Whether an upper case letter is required
+	RequireLower  bool // Whether a lower case letter is required
+	RequireDigit  bool // Whether a digit is required
+	RequireSymbol bool // Whether a character other than letters and digits is required
+	RejectCommon  bool // Whether commonly used and breached passwords are rejected
+	// A file of extra passwords to reject, one per line, e.g. a local copy of
+	// a breached password list
+	CommonPasswordsFile string
+
+	// The hash algorithm of new passwords, one of the Hash* algorithms, defaults
+	// to bcrypt. Hashes of other algorithms or parameters are upgraded when the
+	// users log in.
+	Algorithm     string
+	BcryptCost    int    // Defaults to 10
+	Argon2Time    uint32 // The number of iterations, defaults to 2
+	Argon2Memory  uint32 // The memory in KiB, defaults to 19456 (19 MiB)
+	Argon2Threads uint8  // Defaults to 1
+}
+
+const (
+	MailSmtp   = "smtp"   // Send mails through an SMTP server
+	MailFile   = "file"   // Write mails as .eml files to a directory
+	MailMemory = "memory" // Keep mails in memory, useful for tests
+)
+
+type MailConfig struct {
+	Transport    string // One of the Mail* transports, defaults to memory
+	From         string // The sender address of the mails
+	SmtpHost     string
+	SmtpPort     int
+	SmtpUsername string
+	SmtpPassword string
+	Dir          string // The directory mails are written to by the file transport
+}
+
+const (
+	StorageLocal  = "local"  // Store files in a local directory
+	StorageMemory = "memory" // Keep files in memory, useful for tests
+	StorageS3     = "s3"     // Store files in a bucket of S3 or an S3-compatible service
+)
+
+type StorageConfig struct {
+	Driver  string // One of the Storage* drivers, defaults to memory
+	Dir     string // The directory files are stored in by the local driver
+	BaseUrl string // The URL stored files are served at, defaults to /api/system/files
+	// The key signing the URLs of private files served at BaseUrl, defaults
+	// to a key derived from Auth.Secret, so the key signing the tokens isn't
+	// used for anything else
+	Secret string
+
+	// The bucket of the S3 driver, the endpoint is e.g.
+	// `https://s3.us-east-1.amazonaws.com`
+	Endpoint  string
+	Region    string // Defaults to us-east-1
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool // Address the bucket in the path, as most S3-compatible services require
+}
+
+type AttachmentConfig struct {
+	MaxSize int64 // The maximum size of attachments in bytes, defaults to 10 MiB
+	// The allowed types of the content, with wildcards like `image/*`, the
+	// content is sniffed rather than trusting the uploaded type
+	ContentTypes []string
+	UrlExpiry    time.Duration // How long download URLs are valid, defaults to 1 hour
+}
+
+type CommentConfig struct {
+	// Whether visitors who aren't logged in may post comments, which have no
+	// author, otherwise only logged in users may
+	AllowAnonymous bool
+}
+
+const (
+	LogSilent = "silent" // Log nothing
+	LogError  = "error"  // Log failed queries
+	LogWarn   = "warn"   // Log failed and slow queries
+	LogInfo   = "info"   // Log every query
+)
+
+type DBConfig struct {
+	// The URLs of the read replicas of DBUrl, which reads of records and lists
+	// are spread over, while writes and other reads go to DBUrl
+	Replicas        []string
+	MaxOpenConns    int           // The maximum number of open connections to each database, zero means unlimited
+	MaxIdleConns    int           // The maximum number of idle connections to each database, defaults to 2
+	ConnMaxLifetime time.Duration // How long a connection is reused at most, zero means forever
+	ConnMaxIdleTime time.Duration // How long a connection is kept idle at most, zero means forever
+	LogLevel        string        // Which queries are logged, one of the Log* levels, defaults to warn
+	SlowThreshold   time.Duration // Queries taking longer are logged as slow, defaults to 200ms
+	PrepareStmt     bool          // Whether statements are prepared and cached for reuse
+	// Options added to the query of the URLs unless set there, e.g.
+	// `_busy_timeout` for SQLite or `application_name` for PostgreSQL
+	Params map[string]string
+	// Whether PostgreSQL queries are sent without preparing them, as required
+	// behind PgBouncer in the transaction mode
+	PreferSimpleProtocol bool
+}
+
+const (
+	RepoDB     = "db"     // Keep the records of the repositories in the databases
+	RepoMemory = "memory" // Keep the records in memory, for demos and tests, they're gone once the server stops
+)
+
+// DatabaseConfig is a named database besides the default one.
+type DatabaseConfig struct {
+	Url      string // The connection URL, in the formats of DBUrl
+	DBConfig        // The options of the connections, the logging defaults to that of DB
+}
+
+type OpLogConfig struct {
+	QueueSize     int           // The maximum number of oplogs waiting to be written, extra ones are dropped
+	BatchSize     int           // The maximum number of oplogs written in one batch
+	FlushInterval time.Duration // How often queued oplogs are written if the batch is not full
+	Retention     time.Duration // How long oplogs are kept, zero means forever
+	PruneInterval time.Duration // How often expired oplogs are pruned
+	// Chained enables the append-only audit mode, in which oplogs are never
+	// merged or pruned, and each one is hash-chained to the previous one.
+	Chained bool
+}
+
+type EventsConfig struct {
+	PollInterval  time.Duration // How often the outbox is checked for due events, besides right after commits
+	BatchSize     int           // The maximum number of events claimed at once
+	Concurrency   int           // The maximum number of asynchronous handlers running at once
+	Timeout       time.Duration // How long an asynchronous handler may run
+	MaxAttempts   int           // How many times a handler is run before the event is marked failed
+	RetryDelay    time.Duration // The delay before the first retry, doubled for each next one
+	MaxRetryDelay time.Duration // The longest delay between retries
+}
+
+type WebhookConfig struct {
+	Timeout       time.Duration // How long a delivery may take before it counts as failed
+	MaxAttempts   int           // How many times a delivery is attempted before it's marked failed
+	RetryDelay    time.Duration // The delay before the first retry, doubled for each next one
+	MaxRetryDelay time.Duration // The longest delay between retries
+	// The number of deliveries in a row that fail every attempt after which a
+	// webhook is disabled
+	DisableAfter int
+	Concurrency  int           // The maximum number of deliveries sent at once
+	PollInterval time.Duration // How often due deliveries are checked for, besides right after new ones
+}
+
+type JobsConfig struct {
+	PollInterval  time.Duration // How often due jobs are checked for, besides right after they're enqueued
+	Concurrency   int           // The maximum number of jobs a server runs at once
+	Timeout       time.Duration // How long a job may run, unless its handler sets otherwise
+	MaxAttempts   int           // How many times a job is run before it's marked failed, unless set otherwise
+	RetryDelay    time.Duration // The delay before the first retry, doubled for each next one
+	MaxRetryDelay time.Duration // The longest delay between retries
+	Retention     time.Duration // How long finished jobs are kept, negative means forever
+}
+
+const (
+	RealtimeMemory = "memory" // Deliver the messages within the server, which is enough for a single instance
+	RealtimeDB     = "db"     // Deliver the messages to all instances through a table of the database
+)
+
+type RealtimeConfig struct {
+	Broker       string        // One of the Realtime* brokers, defaults to memory
+	PollInterval time.Duration // How often the db broker checks for new messages
+	Heartbeat    time.Duration // How often idle connections are pinged to keep them open
+	// The number of messages a connection may fall behind by, after which it's
+	// closed for the client to reconnect
+	BufferSize int
+	MaxTopics  int // The maximum number of topics a connection may subscribe to
+}
+
+type NotificationConfig struct {
+	DigestSchedule string        // The cron expression of daily digests in UTC, see jobs.ParseCron
+	DigestLimit    int           // The maximum number of notifications listed in a digest, the others are counted
+	Retention      time.Duration // How long notifications are kept, negative means forever
+}
+
+const (
+	DeletionCascade   = "cascade"   // Delete the content of the user along with the account
+	DeletionAnonymize = "anonymize" // Keep the content, attributed to a placeholder user for deleted accounts
+	DeletionTransfer  = "transfer"  // Keep the content, attributed to another user chosen by an admin
+)
+
+type PrivacyConfig struct {
+	// What happens to the content of deleted accounts unless the request picks
+	// another policy, one of the Deletion* policies, defaults to anonymize
+	DeletionPolicy string
+	// The policies users may pick when deleting their own accounts, defaults
+	// to DeletionPolicy only. Admins may pick any policy, and only they may
+	// transfer the content to another user.
+	DeletionPolicies []string
 }
 
 type Config struct {
 	AppName string // The name of the application
 	AppUrl  string // The base URL of the application
 	DBUrl   string // The database connection URL
-	Auth    AuthConfig
+	DB      DBConfig
+	// The named databases besides the default one at DBUrl, e.g. `audit` or
+	// `analytics`, which the tables of domains can be bound to in DBBindings
+	Databases map[string]DatabaseConfig
+	// The databases the bindings of the domains are in, e.g. `oplog: audit`
+	// keeps the oplogs in the `audit` database, the others are in the default one
+	DBBindings map[string]string
+	// Where the repositories of the domains keep their records, one of the
+	// Repo* drivers, defaults to db. The oplogs are always kept in a database.
+	Repo         string
+	Auth         AuthConfig
+	OpLog        OpLogConfig
+	Events       EventsConfig
+	Webhook      WebhookConfig
+	Jobs         JobsConfig
+	Realtime     RealtimeConfig
+	Notification NotificationConfig
+	Mail         MailConfig
+	Password     PasswordConfig
+	Privacy      PrivacyConfig
+	Storage      StorageConfig
+	Attachment   AttachmentConfig
+	Comment      CommentConfig
 }
 
 func init() {
 	_ = godotenv.Load()
 }
 
+// GetConfig returns the configuration of the environment at APP_ENV, see
+// ForEnv.
 func GetConfig() Config {
-	appEnv := os.Getenv("APP_ENV")
-	if appEnv == "" {
-		appEnv = "dev"
-	}
+	return ForEnv(os.Getenv("APP_ENV"))
+}
 
+// ForEnv returns the configuration of the environment, one of dev, test and
+// prod, with the default values set. Unknown environments are dev.
+func ForEnv(appEnv string) Config {
 	cfg := func() Config {
 		switch appEnv {
 		case "prod", "production":
@@ -54,6 +310,200 @@ func GetConfig() Config {
 	if cfg.Auth.Secret == "" {
 		cfg.Auth.Secret = "bilingo-secret-key-change-in-production"
 	}
+	if cfg.Auth.Registration == "" {
+		cfg.Auth.Registration = RegistrationClosed
+	}
+	if cfg.Auth.VerificationDuration == 0 {
+		cfg.Auth.VerificationDuration = 24 * time.Hour
+	}
+	if cfg.Auth.InviteDuration == 0 {
+		cfg.Auth.InviteDuration = 7 * 24 * time.Hour // 7 days
+	}
+	if cfg.Auth.ResetDuration == 0 {
+		cfg.Auth.ResetDuration = time.Hour
+	}
+	if cfg.Password.MinLength == 0 {
+		cfg.Password.MinLength = 8
+	}
+	if cfg.Password.MaxLength == 0 {
+		cfg.Password.MaxLength = 72
+	}
+	if cfg.Password.Algorithm == "" {
+		cfg.Password.Algorithm = HashBcrypt
+	}
+	if cfg.Password.BcryptCost == 0 {
+		cfg.Password.BcryptCost = 10
+	}
+	if cfg.Password.Argon2Time == 0 {
+		cfg.Password.Argon2Time = 2
+	}
+	if cfg.Password.Argon2Memory == 0 {
+		cfg.Password.Argon2Memory = 19 * 1024
+	}
+	if cfg.Password.Argon2Threads == 0 {
+		cfg.Password.Argon2Threads = 1
+	}
+	if cfg.Auth.ChallengeDuration == 0 {
+		cfg.Auth.ChallengeDuration = 5 * time.Minute
+	}
+	if cfg.Mail.Transport == "" {
+		cfg.Mail.Transport = MailMemory
+	}
+	if cfg.Mail.From == "" {
+		cfg.Mail.From = "noreply@localhost"
+	}
+	if cfg.Mail.SmtpPort == 0 {
+		cfg.Mail.SmtpPort = 587
+	}
+	if cfg.Privacy.DeletionPolicy == "" {
+		cfg.Privacy.DeletionPolicy = DeletionAnonymize
+	}
+	if len(cfg.Privacy.DeletionPolicies) == 0 {
+		cfg.Privacy.DeletionPolicies = []string{cfg.Privacy.DeletionPolicy}
+	}
+	if cfg.Repo == "" {
+		cfg.Repo = RepoDB
+	}
+	if cfg.Storage.Driver == "" {
+		cfg.Storage.Driver = StorageMemory
+	}
+	if cfg.Storage.BaseUrl == "" {
+		cfg.Storage.BaseUrl = "/api/system/files"
+	}
+	if cfg.Storage.Secret == "" {
+		mac := hmac.New(sha256.New, []byte(cfg.Auth.Secret))
+		mac.Write([]byte("storage-url"))
+		cfg.Storage.Secret = hex.EncodeToString(mac.Sum(nil))
+	}
+	if cfg.Storage.Region == "" {
+		cfg.Storage.Region = "us-east-1"
+	}
+	if cfg.Attachment.MaxSize == 0 {
+		cfg.Attachment.MaxSize = 10 << 20
+	}
+	if len(cfg.Attachment.ContentTypes) == 0 {
+		cfg.Attachment.ContentTypes = []string{"image/*", "text/plain", "application/pdf", "application/zip"}
+	}
+	if cfg.Attachment.UrlExpiry == 0 {
+		cfg.Attachment.UrlExpiry = time.Hour
+	}
+	if cfg.DB.LogLevel == "" {
+		cfg.DB.LogLevel = LogWarn
+	}
+	if cfg.DB.SlowThreshold == 0 {
+		cfg.DB.SlowThreshold = 200 * time.Millisecond
+	}
+	// The map is shared by every copy of the config
+	databases := make(map[string]DatabaseConfig, len(cfg.Databases))
+	for name, database := range cfg.Databases {
+		if database.LogLevel == "" {
+			database.LogLevel = cfg.DB.LogLevel
+		}
+		if database.SlowThreshold == 0 {
+			database.SlowThreshold = cfg.DB.SlowThreshold
+		}
+		databases[name] = database
+	}
+	cfg.Databases = databases
+	if cfg.OpLog.QueueSize == 0 {
+		cfg.OpLog.QueueSize = 1024
+	}
+	if cfg.OpLog.BatchSize == 0 {
+		cfg.OpLog.BatchSize = 100
+	}
+	if cfg.OpLog.FlushInterval == 0 {
+		cfg.OpLog.FlushInterval = time.Second
+	}
+	if cfg.OpLog.PruneInterval == 0 {
+		cfg.OpLog.PruneInterval = time.Hour
+	}
+	if cfg.Events.PollInterval == 0 {
+		cfg.Events.PollInterval = time.Second
+	}
+	if cfg.Events.BatchSize == 0 {
+		cfg.Events.BatchSize = 100
+	}
+	if cfg.Events.Concurrency == 0 {
+		cfg.Events.Concurrency = 4
+	}
+	if cfg.Events.Timeout == 0 {
+		cfg.Events.Timeout = 30 * time.Second
+	}
+	if cfg.Events.MaxAttempts == 0 {
+		cfg.Events.MaxAttempts = 8
+	}
+	if cfg.Events.RetryDelay == 0 {
+		cfg.Events.RetryDelay = time.Second
+	}
+	if cfg.Events.MaxRetryDelay == 0 {
+		cfg.Events.MaxRetryDelay = time.Hour
+	}
+	if cfg.Webhook.Timeout == 0 {
+		cfg.Webhook.Timeout = 10 * time.Second
+	}
+	if cfg.Webhook.MaxAttempts == 0 {
+		cfg.Webhook.MaxAttempts = 8
+	}
+	if cfg.Webhook.RetryDelay == 0 {
+		cfg.Webhook.RetryDelay = 30 * time.Second
+	}
+	if cfg.Webhook.MaxRetryDelay == 0 {
+		cfg.Webhook.MaxRetryDelay = 6 * time.Hour
+	}
+	if cfg.Webhook.DisableAfter == 0 {
+		cfg.Webhook.DisableAfter = 10
+	}
+	if cfg.Webhook.Concurrency == 0 {
+		cfg.Webhook.Concurrency = 4
+	}
+	if cfg.Webhook.PollInterval == 0 {
+		cfg.Webhook.PollInterval = 5 * time.Second
+	}
+	if cfg.Jobs.PollInterval == 0 {
+		cfg.Jobs.PollInterval = time.Second
+	}
+	if cfg.Jobs.Concurrency == 0 {
+		cfg.Jobs.Concurrency = 4
+	}
+	if cfg.Jobs.Timeout == 0 {
+		cfg.Jobs.Timeout = 5 * time.Minute
+	}
+	if cfg.Jobs.MaxAttempts == 0 {
+		cfg.Jobs.MaxAttempts = 5
+	}
+	if cfg.Jobs.RetryDelay == 0 {
+		cfg.Jobs.RetryDelay = 10 * time.Second
+	}
+	if cfg.Jobs.MaxRetryDelay == 0 {
+		cfg.Jobs.MaxRetryDelay = time.Hour
+	}
+	if cfg.Jobs.Retention == 0 {
+		cfg.Jobs.Retention = 7 * 24 * time.Hour
+	}
+	if cfg.Realtime.Broker == "" {
+		cfg.Realtime.Broker = RealtimeMemory
+	}
+	if cfg.Realtime.PollInterval == 0 {
+		cfg.Realtime.PollInterval = 500 * time.Millisecond
+	}
+	if cfg.Realtime.Heartbeat == 0 {
+		cfg.Realtime.Heartbeat = 25 * time.Second
+	}
+	if cfg.Realtime.BufferSize == 0 {
+		cfg.Realtime.BufferSize = 64
+	}
+	if cfg.Realtime.MaxTopics == 0 {
+		cfg.Realtime.MaxTopics = 50
+	}
+	if cfg.Notification.DigestSchedule == "" {
+		cfg.Notification.DigestSchedule = "0 8 * * *"
+	}
+	if cfg.Notification.DigestLimit == 0 {
+		cfg.Notification.DigestLimit = 20
+	}
+	if cfg.Notification.Retention == 0 {
+		cfg.Notification.Retention = 90 * 24 * time.Hour
+	}
 
 	return cfg
 }
@@ -6,9 +6,37 @@ var Prod = Config{
 	AppName: "Bilingo (Prod)",
 	AppUrl:  "http://localhost:5173",
 	DBUrl:   "sqlite://bilingo.db",
+	DB: DBConfig{
+		MaxIdleConns:    10,
+		ConnMaxLifetime: time.Hour,
+		Params: map[string]string{
+			"_busy_timeout": "5000", // Wait for the lock of other writers
+		},
+	},
 	Auth: AuthConfig{
-		CookieName: "auth_token",
-		Duration:   7 * 24 * time.Hour, // 7 days
-		Secret:     "bilingo-secret-key-change-in-production",
+		CookieName:   "auth_token",
+		Duration:     7 * 24 * time.Hour, // 7 days
+		Secret:       "bilingo-secret-key-change-in-production",
+		Registration: RegistrationInvite,
+	},
+	OpLog: OpLogConfig{
+		Retention: 90 * 24 * time.Hour, // 90 days
+	},
+	Password: PasswordConfig{
+		MinLength:    10,
+		RequireLower: true,
+		RequireDigit: true,
+		RejectCommon: true,
+		Algorithm:    HashArgon2id,
+	},
+	Storage: StorageConfig{
+		Driver: StorageLocal,
+		Dir:    "storage",
+	},
+	Mail: MailConfig{
+		Transport: MailSmtp,
+		From:      "noreply@localhost",
+		SmtpHost:  "localhost",
+		SmtpPort:  587,
 	},
 }
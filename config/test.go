@@ -7,8 +7,18 @@ var Test = Config{
 	AppUrl:  "http://localhost:5173",
 	DBUrl:   "sqlite://bilingo.db",
 	Auth: AuthConfig{
-		CookieName: "auth_token",
-		Duration:   7 * 24 * time.Hour, // 7 days
-		Secret:     "bilingo-secret-key-change-in-production",
+		CookieName:   "auth_token",
+		Duration:     7 * 24 * time.Hour, // 7 days
+		Secret:       "bilingo-secret-key-change-in-production",
+		Registration: RegistrationOpen,
+	},
+	OpLog: OpLogConfig{
+		Retention: 0, // keep forever
+	},
+	Storage: StorageConfig{
+		Driver: StorageMemory,
+	},
+	Mail: MailConfig{
+		Transport: MailMemory,
 	},
 }
@@ -1,35 +1,49 @@
 package api
 
 import (
+	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"log"
 	"strconv"
 
 	domain "bilingo/domains/article"
 	"bilingo/domains/article/service"
 	"bilingo/domains/article/types"
 	"bilingo/server"
+	"bilingo/server/app"
 	"bilingo/server/auth"
+	"bilingo/server/markdown"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-var ArticleApi = server.NewApiEntry("/articles", auth.UseAuth)
-
-func init() {
-	ArticleApi.Get("/", listArticles)
-	ArticleApi.Get("/:id", getArticle)
-	ArticleApi.Post("/", auth.RequireAuth, createArticle)
-	ArticleApi.Patch("/:id", auth.RequireAuth, updateArticle)
-	ArticleApi.Delete("/:id", auth.RequireAuth, deleteArticle)
-	ArticleApi.Post("/:id/like", auth.RequireAuth, likeArticle)
+// Register adds the routes of the articles to the app.
+func Register(a *app.App) {
+	api := a.Group("/articles", auth.UseAuth)
+	api.Get("/", listArticles)
+	api.Get("/export", exportArticles)
+	api.Post("/import", auth.RequireAuth, importArticles)
+	api.Get("/:id", getArticle)
+	api.Post("/", auth.RequireAuth, createArticle)
+	api.Patch("/:id", auth.RequireAuth, updateArticle)
+	api.Delete("/:id", auth.RequireAuth, deleteArticle)
+	api.Post("/:id/like", auth.RequireAuth, likeArticle)
 }
 
+// getArticle returns the article, with the content rendered to sanitized HTML
+// and the table of contents if the `format` query is `html`.
 func getArticle(ctx *fiber.Ctx) error {
 	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
 	if err != nil {
 		return server.Error(ctx, 400, fmt.Errorf("invalid article ID: %w", err))
 	}
+	format := ctx.Query("format", markdown.FormatMarkdown)
+	if format != markdown.FormatMarkdown && format != markdown.FormatHtml {
+		return server.Error(ctx, 400, fmt.Errorf("invalid format: %s", format))
+	}
 
 	article, err := service.GetArticle(ctx.UserContext(), uint(id))
 	if errors.Is(err, domain.ErrArticleNotFound) {
@@ -38,6 +52,9 @@ func getArticle(ctx *fiber.Ctx) error {
 		return server.Error(ctx, 500, err)
 	}
 
+	if err := service.RenderArticle(article, format == markdown.FormatHtml); err != nil {
+		return server.Error(ctx, 500, err)
+	}
 	return server.Success(ctx, article)
 }
 
@@ -67,7 +84,7 @@ func createArticle(ctx *fiber.Ctx) error {
 		return server.Error(ctx, 400, fmt.Errorf("malformed request body: %w", err))
 	}
 
-	article, err := service.CreateArticle(ctx.UserContext(), &data, user.Email)
+	article, err := service.CreateArticle(ctx.UserContext(), &data, user.ID)
 	if err != nil {
 		return server.Error(ctx, 500, err)
 	}
@@ -90,7 +107,7 @@ func updateArticle(ctx *fiber.Ctx) error {
 
 	// Check if user is the author
 	user := auth.GetUser(ctx.UserContext())
-	if user == nil || article.Author != user.Email {
+	if user == nil || article.Author != user.ID {
 		return server.Error(ctx, 403, auth.ErrForbidden)
 	}
 
@@ -125,7 +142,7 @@ func deleteArticle(ctx *fiber.Ctx) error {
 
 	// Check if user is the author
 	user := auth.GetUser(ctx.UserContext())
-	if user == nil || article.Author != user.Email {
+	if user == nil || article.Author != user.ID {
 		return server.Error(ctx, 403, auth.ErrForbidden)
 	}
 
@@ -160,3 +177,83 @@ func likeArticle(ctx *fiber.Ctx) error {
 
 	return server.Success[any](ctx, article)
 }
+
+var exportContentTypes = map[string]struct {
+	contentType string
+	filename    string
+}{
+	types.FormatJsonl:    {"application/x-ndjson", "articles.jsonl"},
+	types.FormatCsv:      {"text/csv; charset=utf-8", "articles.csv"},
+	types.FormatMarkdown: {"application/zip", "articles.zip"},
+}
+
+func exportArticles(ctx *fiber.Ctx) error {
+	query := types.ArticleExportQuery{Format: types.FormatJsonl}
+	if err := ctx.QueryParser(&query); err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("malformed query: %w", err))
+	}
+
+	output, ok := exportContentTypes[query.Format]
+	if !ok {
+		return server.Error(ctx, 400, fmt.Errorf("%w: %q", domain.ErrUnsupportedFormat, query.Format))
+	}
+
+	ctx.Set(fiber.HeaderContentType, output.contentType)
+	ctx.Attachment(output.filename)
+
+	// The handler returns before the body is written, so the user context must
+	// be captured here instead of being read from the fiber context later.
+	userCtx := ctx.UserContext()
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := service.ExportArticles(userCtx, &query, w); err != nil {
+			log.Printf("failed to export articles: %v", err)
+		}
+	})
+
+	return nil
+}
+
+func importArticles(ctx *fiber.Ctx) error {
+	user := auth.GetUser(ctx.UserContext())
+	if user == nil {
+		return server.Error(ctx, 401, auth.ErrUnauthorized)
+	}
+
+	opts := types.ArticleImportOptions{Format: types.FormatJsonl}
+	if err := ctx.QueryParser(&opts); err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("malformed query: %w", err))
+	}
+
+	// Only admins may import articles on behalf of other authors
+	opts.DefaultAuthor = &user.ID
+	if !auth.IsAdmin(ctx.UserContext(), user) {
+		opts.AllowedAuthor = &user.ID
+	}
+
+	// Accept either a multipart file upload or the raw request body
+	var src io.ReaderAt
+	var size int64
+	if header, err := ctx.FormFile("file"); err == nil {
+		file, err := header.Open()
+		if err != nil {
+			return server.Error(ctx, 400, fmt.Errorf("malformed upload: %w", err))
+		}
+		defer file.Close()
+
+		src = file
+		size = header.Size
+	} else {
+		body := ctx.Body()
+		src = bytes.NewReader(body)
+		size = int64(len(body))
+	}
+
+	report, err := service.ImportArticles(ctx.UserContext(), src, size, &opts)
+	if errors.Is(err, domain.ErrUnsupportedFormat) || errors.Is(err, domain.ErrMalformedArchive) {
+		return server.Error(ctx, 400, err)
+	} else if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success(ctx, report)
+}
@@ -1,12 +1,27 @@
 import type { ApiResponse, PaginatedResult } from "../../../common"
 import { ApiEntry } from "../../../client"
 import type { Article } from "../models"
-import type { ArticleCreate, ArticleListQuery, ArticleUpdate } from "../types"
+import qs from "qs"
+import type {
+    ArticleCreate,
+    ArticleExportQuery,
+    ArticleImportOptions,
+    ArticleImportReport,
+    ArticleListQuery,
+    ArticleUpdate,
+} from "../types"
 
 const articleApi = new ApiEntry("/articles")
 
-export async function getArticle(id: number): ApiResponse<Article> {
-    return await articleApi.get("/" + id)
+/**
+ * Returns the article, with the content rendered to sanitized HTML and the
+ * table of contents if the format is `html`.
+ */
+export async function getArticle(
+    id: number,
+    format?: "markdown" | "html",
+): ApiResponse<Article> {
+    return await articleApi.get("/" + id, format ? { format } : undefined)
 }
 
 export async function listArticles(
@@ -33,3 +48,20 @@ export async function likeArticle(
 ): ApiResponse<Article> {
     return await articleApi.post(`/${id}/like`, null, { action })
 }
+
+/**
+ * Returns the URL to download the articles matching the query, the response is
+ * streamed as a file so it's meant to be used as a link rather than fetched.
+ */
+export function getArticleExportUrl(query: Partial<ArticleExportQuery>): string {
+    return "/api/articles/export?" + qs.stringify(query)
+}
+
+export async function importArticles(
+    file: Blob,
+    options: Partial<ArticleImportOptions>,
+): ApiResponse<ArticleImportReport> {
+    const data = new FormData()
+    data.append("file", file)
+    return await articleApi.post("/import", options, data)
+}
@@ -0,0 +1,47 @@
+package api_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"bilingo/common"
+	"bilingo/domains/article/models"
+	"bilingo/domains/article/types"
+	userModels "bilingo/domains/user/models"
+	"bilingo/server/testutil"
+)
+
+func TestCreateArticle(t *testing.T) {
+	t.Parallel()
+	a := testutil.NewApp(t)
+	fixtures := testutil.LoadFixtures(t, a)
+	alice := fixtures.User(t, "alice@example.com")
+	client := testutil.NewClient(t, a)
+
+	data := types.ArticleCreate{Title: "Numbers", Content: "One, two, three."}
+	if resp := client.Post("/articles", data); resp.Status != http.StatusUnauthorized {
+		t.Fatalf("created an article logged out: %s", resp)
+	}
+
+	client.Login(alice.Email, *alice.Password)
+	me := testutil.Decode[userModels.User](t, client.Get("/users/me"))
+	if me.ID != alice.ID {
+		t.Fatalf("logged in as %s, expected %s", me.ID, alice.ID)
+	}
+
+	created := testutil.Decode[models.Article](t, client.Post("/articles", data))
+	if created.Author != alice.ID || created.Title != data.Title {
+		t.Fatalf("unexpected article: %+v", created)
+	}
+
+	client.Logout()
+	article := testutil.Decode[models.Article](t, client.Get(fmt.Sprintf("/articles/%d", created.ID)))
+	if article.Content != data.Content {
+		t.Fatalf("unexpected content: %q", article.Content)
+	}
+	list := testutil.Decode[common.PaginatedResult[models.Article]](t, client.Get("/articles?page=1&page_size=10&author="+alice.ID))
+	if list.Total != 3 {
+		t.Fatalf("expected the 2 fixture articles of alice and the new one, got %d", list.Total)
+	}
+}
@@ -0,0 +1,5 @@
+package article
+
+// DBBinding is the binding of the database the tables of the domain are in,
+// see db.Bind.
+const DBBinding = "article"
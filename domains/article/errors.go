@@ -2,4 +2,9 @@ package article
 
 import "errors"
 
-var ErrArticleNotFound = errors.New("article not found")
+var (
+	ErrArticleNotFound    = errors.New("article not found")
+	ErrUnsupportedFormat  = errors.New("unsupported format")
+	ErrMalformedArchive   = errors.New("malformed archive")
+	ErrInvalidFrontMatter = errors.New("invalid front matter")
+)
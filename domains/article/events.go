@@ -0,0 +1,44 @@
+package article
+
+// The events of the domain, published by the service, see events.Publish.
+
+// ArticleCreated is published when an article is created, including imports.
+type ArticleCreated struct {
+	ID     uint   `json:"id"`
+	Author string `json:"author"` // The user ID of the author
+	Title  string `json:"title"`
+}
+
+func (ArticleCreated) EventName() string { return "article.created" }
+
+// ArticleUpdated is published when the title or the content of an article
+// changes.
+type ArticleUpdated struct {
+	ID     uint   `json:"id"`
+	Author string `json:"author"`
+	Title  string `json:"title"`
+}
+
+func (ArticleUpdated) EventName() string { return "article.updated" }
+
+// ArticleDeleted is published when an article is deleted.
+type ArticleDeleted struct {
+	ID     uint   `json:"id"`
+	Author string `json:"author"`
+	Title  string `json:"title"`
+}
+
+func (ArticleDeleted) EventName() string { return "article.deleted" }
+
+// ArticleReacted is published when a reader likes or dislikes an article, or
+// takes it back.
+type ArticleReacted struct {
+	ID       uint   `json:"id"`
+	Author   string `json:"author"`
+	Action   string `json:"action"` // One of like, unlike, dislike and undislike
+	User     string `json:"user"`   // The user ID of the reader, empty for anonymous readers
+	Likes    int    `json:"likes"`
+	Dislikes int    `json:"dislikes"`
+}
+
+func (ArticleReacted) EventName() string { return "article.reacted" }
@@ -1,20 +1,35 @@
 package models
 
-import "time"
+import (
+	"strconv"
+	"time"
 
+	"bilingo/common"
+)
+
+//tygo:emit import type * as common from "@/common"
 type Article struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	Title     string    `json:"title"`
 	Content   string    `json:"content"`
-	Author    string    `json:"author"`
+	Author    string    `json:"author" gorm:"size:36;index"`
 	Category  *string   `json:"category"`
 	Tags      *string   `json:"tags"`
 	Likes     int       `json:"likes"`
 	Dislikes  int       `json:"dislikes"`
+	// Derived from the content when the article is returned, not stored
+	Excerpt     string            `json:"excerpt,omitempty" gorm:"-"`
+	ReadingTime int               `json:"reading_time,omitempty" gorm:"-"` // The estimated minutes to read
+	Html        *string           `json:"html,omitempty" gorm:"-"`         // The sanitized HTML of the content
+	Toc         []common.TocEntry `json:"toc,omitempty" gorm:"-"`
 }
 
 func (a *Article) TableName() string {
 	return "article"
 }
+
+func (a *Article) AuditInfo() (string, string) {
+	return "article", strconv.FormatUint(uint64(a.ID), 10)
+}
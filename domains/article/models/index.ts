@@ -3,6 +3,8 @@
 //////////
 // source: article.go
 
+import type * as common from "@/common"
+
 export interface Article {
     id: number /* uint */
     created_at: string /* RFC3339 */
@@ -14,4 +16,11 @@ export interface Article {
     tags?: string
     likes: number /* int */
     dislikes: number /* int */
+    /**
+     * Derived from the content when the article is returned, not stored
+     */
+    excerpt?: string
+    reading_time?: number /* int */ // The estimated minutes to read
+    html?: string // The sanitized HTML of the content
+    toc?: common.TocEntry[]
 }
@@ -0,0 +1,20 @@
+// Package module assembles the article domain into an app.
+package module
+
+import (
+	"bilingo/domains/article/api"
+	"bilingo/domains/article/repo"
+	"bilingo/domains/article/service"
+	"bilingo/server/app"
+	"bilingo/server/events"
+	"bilingo/server/realtime"
+)
+
+// Register provides the repositories of the articles, publishes their changes
+// to the realtime topics of the articles, and adds their routes.
+func Register(a *app.App) error {
+	repo.Provide(a)
+	service.PublishRealtime(events.ForApp(a), realtime.ForApp(a))
+	api.Register(a)
+	return nil
+}
@@ -7,9 +7,14 @@ import (
 	"bilingo/domains/article/models"
 	impl "bilingo/domains/article/repo/db"
 	"bilingo/domains/article/types"
+	"bilingo/server/app"
 )
 
-var ArticleRepo IArticleRepo = &impl.ArticleRepo{}
+// Articles returns the article repository of the app the context carries, or the
+// database implementation outside of apps.
+func Articles(ctx context.Context) IArticleRepo {
+	return app.Resolve[IArticleRepo](ctx, &impl.ArticleRepo{})
+}
 
 type IArticleRepo interface {
 	Get(ctx context.Context, id uint) (*models.Article, error)
@@ -19,4 +24,15 @@ type IArticleRepo interface {
 	Delete(ctx context.Context, id uint) error
 	UpdateLikes(ctx context.Context, id uint, likes int) (*models.Article, error)
 	UpdateDislikes(ctx context.Context, id uint, dislikes int) (*models.Article, error)
+	// Each walks through all articles matching the query in batches, ignoring
+	// the pagination options of the query.
+	Each(ctx context.Context, query *types.ArticleListQuery, fn func(batch []models.Article) error) error
+	// DeleteByAuthor deletes all articles of the author, and returns the number
+	// of deleted articles.
+	DeleteByAuthor(ctx context.Context, author string) (int, error)
+	// ReassignAuthor attributes all articles of the author to another user, and
+	// returns the number of changed articles.
+	ReassignAuthor(ctx context.Context, from string, to string) (int, error)
+	// Import inserts an article as is, preserving its author and timestamps.
+	Import(ctx context.Context, article *models.Article) error
 }
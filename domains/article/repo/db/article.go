@@ -20,7 +20,7 @@ import (
 type ArticleRepo struct{}
 
 func (r *ArticleRepo) Get(ctx context.Context, id uint) (*models.Article, error) {
-	conn, err := db.Default()
+	conn, err := db.Reader(ctx, domain.DBBinding)
 	if err != nil {
 		return nil, db.ConnError(err)
 	}
@@ -36,30 +36,12 @@ func (r *ArticleRepo) Get(ctx context.Context, id uint) (*models.Article, error)
 }
 
 func (r *ArticleRepo) List(ctx context.Context, query *types.ArticleListQuery) (*common.PaginatedResult[models.Article], error) {
-	conn, err := db.Default()
+	conn, err := db.Reader(ctx, domain.DBBinding)
 	if err != nil {
 		return nil, db.ConnError(err)
 	}
 
-	q := gorm.G[models.Article](conn).Where("1 = 1")
-
-	if query.Search != nil && *query.Search != "" {
-		likePattern := "%" + *query.Search + "%"
-		q = q.Where(
-			q.Or(
-				tables.Article.Title.Like(likePattern),
-				tables.Article.Content.Like(likePattern),
-			),
-		)
-	}
-
-	if query.Author != nil && *query.Author != "" {
-		q = q.Where(tables.Article.Author.Eq(*query.Author))
-	}
-
-	if query.Category != nil && *query.Category != "" {
-		q = q.Where(tables.Article.Category.Eq(*query.Category))
-	}
+	q := filterArticles(gorm.G[models.Article](conn).Where("1 = 1"), query)
 
 	// Count total before applying pagination
 	total, err := q.Count(ctx, "*")
@@ -81,8 +63,31 @@ func (r *ArticleRepo) List(ctx context.Context, query *types.ArticleListQuery) (
 	return &common.PaginatedResult[models.Article]{Total: int(total), List: articles}, nil
 }
 
+func filterArticles(
+	q gorm.ChainInterface[models.Article],
+	query *types.ArticleListQuery,
+) gorm.ChainInterface[models.Article] {
+	if query.Search != nil && *query.Search != "" {
+		likePattern := "%" + *query.Search + "%"
+		q = q.Where(clause.Or(
+			tables.Article.Title.Like(likePattern),
+			tables.Article.Content.Like(likePattern),
+		))
+	}
+
+	if query.Author != nil && *query.Author != "" {
+		q = q.Where(tables.Article.Author.Eq(*query.Author))
+	}
+
+	if query.Category != nil && *query.Category != "" {
+		q = q.Where(tables.Article.Category.Eq(*query.Category))
+	}
+
+	return q
+}
+
 func (r *ArticleRepo) Create(ctx context.Context, data *types.ArticleCreate, author string) (*models.Article, error) {
-	conn, err := db.Default()
+	conn, err := db.For(ctx, domain.DBBinding)
 	if err != nil {
 		return nil, db.ConnError(err)
 	}
@@ -108,6 +113,7 @@ func (r *ArticleRepo) Create(ctx context.Context, data *types.ArticleCreate, aut
 }
 
 func (r *ArticleRepo) Update(ctx context.Context, id uint, data *types.ArticleUpdate) (*models.Article, error) {
+	ctx = db.UsePrimary(ctx)
 	article, err := r.Get(ctx, id)
 	if err != nil {
 		return nil, err
@@ -121,10 +127,10 @@ func (r *ArticleRepo) Update(ctx context.Context, id uint, data *types.ArticleUp
 	if data.Content != nil && *data.Content != "" && *data.Content != article.Content {
 		updates = append(updates, tables.Article.Content.Set(*data.Content))
 	}
-	if data.Category != nil && *data.Category != "" && *data.Category != *article.Category {
+	if data.Category != nil && *data.Category != "" && (article.Category == nil || *data.Category != *article.Category) {
 		updates = append(updates, tables.Article.Category.Set(*data.Category))
 	}
-	if data.Tags != nil && *data.Tags != "" && *data.Tags != *article.Tags {
+	if data.Tags != nil && *data.Tags != "" && (article.Tags == nil || *data.Tags != *article.Tags) {
 		updates = append(updates, tables.Article.Tags.Set(*data.Tags))
 	}
 
@@ -134,7 +140,7 @@ func (r *ArticleRepo) Update(ctx context.Context, id uint, data *types.ArticleUp
 
 	updates = append(updates, tables.Article.UpdatedAt.Set(time.Now()))
 
-	conn, err := db.Default()
+	conn, err := db.For(ctx, domain.DBBinding)
 	if err != nil {
 		return nil, db.ConnError(err)
 	}
@@ -150,7 +156,7 @@ func (r *ArticleRepo) Update(ctx context.Context, id uint, data *types.ArticleUp
 }
 
 func (r *ArticleRepo) Delete(ctx context.Context, id uint) error {
-	conn, err := db.Default()
+	conn, err := db.For(ctx, domain.DBBinding)
 	if err != nil {
 		return db.ConnError(err)
 	}
@@ -165,13 +171,45 @@ func (r *ArticleRepo) Delete(ctx context.Context, id uint) error {
 	return nil
 }
 
+func (r *ArticleRepo) DeleteByAuthor(ctx context.Context, author string) (int, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return 0, db.ConnError(err)
+	}
+
+	rowsAffected, err := gorm.G[models.Article](conn).Where(tables.Article.Author.Eq(author)).Delete(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete articles of author: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
+func (r *ArticleRepo) ReassignAuthor(ctx context.Context, from string, to string) (int, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return 0, db.ConnError(err)
+	}
+
+	rowsAffected, err := gorm.G[models.Article](conn).
+		Where(tables.Article.Author.Eq(from)).
+		Set(tables.Article.Author.Set(to)).
+		Update(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reassign articles: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
 func (r *ArticleRepo) UpdateLikes(ctx context.Context, id uint, likes int) (*models.Article, error) {
-	conn, err := db.Default()
+	ctx = db.UsePrimary(ctx)
+	conn, err := db.For(ctx, domain.DBBinding)
 	if err != nil {
 		return nil, db.ConnError(err)
 	}
 
-	err = conn.Model(&models.Article{}).Where("id = ?", id).Update("likes", likes).Error
+	err = conn.WithContext(ctx).Model(&models.Article{}).Where("id = ?", id).Update("likes", likes).Error
 	if err != nil {
 		return nil, fmt.Errorf("failed to update likes: %w", err)
 	}
@@ -180,15 +218,51 @@ func (r *ArticleRepo) UpdateLikes(ctx context.Context, id uint, likes int) (*mod
 }
 
 func (r *ArticleRepo) UpdateDislikes(ctx context.Context, id uint, dislikes int) (*models.Article, error) {
-	conn, err := db.Default()
+	ctx = db.UsePrimary(ctx)
+	conn, err := db.For(ctx, domain.DBBinding)
 	if err != nil {
 		return nil, db.ConnError(err)
 	}
 
-	err = conn.Model(&models.Article{}).Where("id = ?", id).Update("dislikes", dislikes).Error
+	err = conn.WithContext(ctx).Model(&models.Article{}).Where("id = ?", id).Update("dislikes", dislikes).Error
 	if err != nil {
 		return nil, fmt.Errorf("failed to update dislikes: %w", err)
 	}
 
 	return r.Get(ctx, id)
 }
+
+func (r *ArticleRepo) Each(
+	ctx context.Context,
+	query *types.ArticleListQuery,
+	fn func(batch []models.Article) error,
+) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	q := filterArticles(gorm.G[models.Article](conn).Where("1 = 1"), query)
+	err = q.FindInBatches(ctx, 500, func(batch []models.Article, _ int) error {
+		return fn(batch)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to iterate articles: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ArticleRepo) Import(ctx context.Context, article *models.Article) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	article.ID = 0 // Always assign a new ID to avoid conflicts with existing articles
+	if err := gorm.G[models.Article](conn).Create(ctx, article); err != nil {
+		return fmt.Errorf("failed to import article: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,18 @@
+package impl_test
+
+import (
+	"testing"
+
+	"bilingo/domains/article/repo"
+	impl "bilingo/domains/article/repo/db"
+	"bilingo/domains/article/repo/repotest"
+	"bilingo/server/testutil"
+)
+
+func TestArticleRepo(t *testing.T) {
+	repotest.TestArticleRepo(t, func(t *testing.T, authors ...string) repo.IArticleRepo {
+		testutil.NewDB(t)
+		testutil.CreateUsers(t, authors...)
+		return &impl.ArticleRepo{}
+	})
+}
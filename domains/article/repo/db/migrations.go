@@ -0,0 +1,72 @@
+package impl
+
+import (
+	"fmt"
+
+	domain "bilingo/domains/article"
+	"bilingo/domains/article/models"
+	userModels "bilingo/domains/user/models"
+	userImpl "bilingo/domains/user/repo/db"
+	"bilingo/server/db"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	db.RegisterMigrations(
+		domain.DBBinding,
+		db.Migration{ID: "2026101900_article_create_table", Up: db.CreateTableIfNotExists(&models.Article{})},
+		db.Migration{ID: "2026101908_article_author_user_id", Up: addAuthorForeignKey},
+	)
+}
+
+// articleAuthor declares the relation between articles and their authors for
+// the foreign key, which the models leave out to keep the domains apart.
+type articleAuthor struct {
+	models.Article
+	AuthorUser userModels.User `gorm:"foreignKey:Author;references:ID"`
+}
+
+// addAuthorForeignKey replaces the emails of the authors with their user IDs,
+// and adds the foreign key to user. Authors without an account get a
+// placeholder user, so that their articles are kept.
+func addAuthorForeignKey(tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	if migrator.HasConstraint(&articleAuthor{}, "AuthorUser") {
+		return nil
+	}
+
+	var authors []string
+	if err := tx.Model(&models.Article{}).Distinct().Pluck("author", &authors).Error; err != nil {
+		return err
+	}
+	ids, err := userImpl.ResolveUserRefs(tx, authors, true)
+	if err != nil {
+		return err
+	}
+	for author, id := range ids {
+		if author == id {
+			continue
+		}
+		err := tx.Model(&models.Article{}).Where("author = ?", author).UpdateColumn("author", id).Error
+		if err != nil {
+			return fmt.Errorf("failed to migrate articles of %s: %w", author, err)
+		}
+	}
+
+	// SQLite doesn't enforce the lengths of columns
+	if tx.Dialector.Name() != "sqlite" {
+		if err := migrator.AlterColumn(&models.Article{}, "Author"); err != nil {
+			return err
+		}
+	}
+	if err := migrator.CreateConstraint(&articleAuthor{}, "AuthorUser"); err != nil {
+		return err
+	}
+
+	// SQLite rebuilds the table for the constraint, so the index comes after it
+	if !migrator.HasIndex(&models.Article{}, "Author") {
+		return migrator.CreateIndex(&models.Article{}, "Author")
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package repo
+
+import (
+	"bilingo/config"
+	impl "bilingo/domains/article/repo/db"
+	"bilingo/domains/article/repo/memory"
+	"bilingo/server/app"
+)
+
+// Provide provides the repositories of the driver in the configuration of the
+// app, which keep their records in memory rather than the databases with the
+// memory driver, see config.RepoMemory.
+func Provide(a *app.App) {
+	if a.Config.Repo == config.RepoMemory {
+		app.Provide[IArticleRepo](a, &memory.ArticleRepo{})
+		return
+	}
+
+	app.Provide[IArticleRepo](a, &impl.ArticleRepo{})
+}
@@ -0,0 +1,182 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"bilingo/common"
+	domain "bilingo/domains/article"
+	"bilingo/domains/article/models"
+	"bilingo/domains/article/types"
+	"bilingo/server/memdb"
+)
+
+type ArticleRepo struct {
+	articles memdb.Table[uint, models.Article]
+}
+
+func (r *ArticleRepo) Get(ctx context.Context, id uint) (*models.Article, error) {
+	article, ok := r.articles.Get(id)
+	if !ok {
+		return nil, domain.ErrArticleNotFound
+	}
+
+	return &article, nil
+}
+
+func (r *ArticleRepo) List(ctx context.Context, query *types.ArticleListQuery) (*common.PaginatedResult[models.Article], error) {
+	articles := r.articles.Find(filterArticles(query))
+	slices.SortStableFunc(articles, func(a, b models.Article) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+
+	return memdb.Paginate(articles, query.PaginatedQuery), nil
+}
+
+// filterArticles matches the search case-insensitively like LIKE of SQLite.
+func filterArticles(query *types.ArticleListQuery) func(article models.Article) bool {
+	return func(article models.Article) bool {
+		if query.Search != nil && *query.Search != "" {
+			search := strings.ToLower(*query.Search)
+			if !strings.Contains(strings.ToLower(article.Title), search) &&
+				!strings.Contains(strings.ToLower(article.Content), search) {
+				return false
+			}
+		}
+
+		if query.Author != nil && *query.Author != "" && article.Author != *query.Author {
+			return false
+		}
+
+		if query.Category != nil && *query.Category != "" &&
+			(article.Category == nil || *article.Category != *query.Category) {
+			return false
+		}
+
+		return true
+	}
+}
+
+func (r *ArticleRepo) Create(ctx context.Context, data *types.ArticleCreate, author string) (*models.Article, error) {
+	now := time.Now()
+	article := &models.Article{
+		ID:        r.articles.NextID(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Title:     data.Title,
+		Content:   data.Content,
+		Author:    author,
+		Category:  data.Category,
+		Tags:      data.Tags,
+	}
+	r.articles.Put(article.ID, *article)
+
+	return article, nil
+}
+
+func (r *ArticleRepo) Update(ctx context.Context, id uint, data *types.ArticleUpdate) (*models.Article, error) {
+	var updated models.Article
+	found := false
+	r.articles.Update(id, func(article *models.Article) bool {
+		found = true
+		changed := false
+		if data.Title != nil && *data.Title != "" && *data.Title != article.Title {
+			article.Title = *data.Title
+			changed = true
+		}
+		if data.Content != nil && *data.Content != "" && *data.Content != article.Content {
+			article.Content = *data.Content
+			changed = true
+		}
+		if data.Category != nil && *data.Category != "" && (article.Category == nil || *data.Category != *article.Category) {
+			category := *data.Category
+			article.Category = &category
+			changed = true
+		}
+		if data.Tags != nil && *data.Tags != "" && (article.Tags == nil || *data.Tags != *article.Tags) {
+			tags := *data.Tags
+			article.Tags = &tags
+			changed = true
+		}
+
+		if changed {
+			article.UpdatedAt = time.Now()
+		}
+		updated = *article
+		return changed
+	})
+	if !found {
+		return nil, domain.ErrArticleNotFound
+	}
+
+	return &updated, nil
+}
+
+func (r *ArticleRepo) Delete(ctx context.Context, id uint) error {
+	if !r.articles.Delete(id) {
+		return domain.ErrArticleNotFound
+	}
+
+	return nil
+}
+
+func (r *ArticleRepo) DeleteByAuthor(ctx context.Context, author string) (int, error) {
+	return r.articles.DeleteWhere(func(article models.Article) bool {
+		return article.Author == author
+	}), nil
+}
+
+func (r *ArticleRepo) ReassignAuthor(ctx context.Context, from string, to string) (int, error) {
+	return r.articles.UpdateWhere(
+		func(article models.Article) bool { return article.Author == from },
+		func(article *models.Article) { article.Author = to },
+	), nil
+}
+
+func (r *ArticleRepo) UpdateLikes(ctx context.Context, id uint, likes int) (*models.Article, error) {
+	r.articles.Update(id, func(article *models.Article) bool {
+		article.Likes = likes
+		return true
+	})
+
+	return r.Get(ctx, id)
+}
+
+func (r *ArticleRepo) UpdateDislikes(ctx context.Context, id uint, dislikes int) (*models.Article, error) {
+	r.articles.Update(id, func(article *models.Article) bool {
+		article.Dislikes = dislikes
+		return true
+	})
+
+	return r.Get(ctx, id)
+}
+
+func (r *ArticleRepo) Each(
+	ctx context.Context,
+	query *types.ArticleListQuery,
+	fn func(batch []models.Article) error,
+) error {
+	for batch := range slices.Chunk(r.articles.Find(filterArticles(query)), 500) {
+		if err := fn(batch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *ArticleRepo) Import(ctx context.Context, article *models.Article) error {
+	now := time.Now()
+	article.ID = r.articles.NextID() // Always assign a new ID to avoid conflicts with existing articles
+	if article.CreatedAt.IsZero() {
+		article.CreatedAt = now
+	}
+	if article.UpdatedAt.IsZero() {
+		article.UpdatedAt = now
+	}
+	r.articles.Put(article.ID, *article)
+
+	return nil
+}
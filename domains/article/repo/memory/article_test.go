@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"bilingo/domains/article/repo"
+	"bilingo/domains/article/repo/memory"
+	"bilingo/domains/article/repo/repotest"
+)
+
+func TestArticleRepo(t *testing.T) {
+	repotest.TestArticleRepo(t, func(t *testing.T, authors ...string) repo.IArticleRepo {
+		return &memory.ArticleRepo{}
+	})
+}
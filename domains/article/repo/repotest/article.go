@@ -0,0 +1,220 @@
+// Package repotest is the contract the implementations of the article
+// repositories must fulfil, which is run against each of them by their tests.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	domain "bilingo/domains/article"
+	"bilingo/domains/article/models"
+	"bilingo/domains/article/repo"
+	"bilingo/domains/article/types"
+)
+
+// NewArticleRepo creates an empty repository for a test, in which the users of
+// the authors exist if the implementation checks the references.
+type NewArticleRepo func(t *testing.T, authors ...string) repo.IArticleRepo
+
+// The authors of the articles created by the tests.
+const (
+	Author1 = "00000000-0000-4000-8000-000000000001"
+	Author2 = "00000000-0000-4000-8000-000000000002"
+)
+
+// TestArticleRepo tests the article repository against the contract.
+func TestArticleRepo(t *testing.T, newRepo NewArticleRepo) {
+	ctx := context.Background()
+
+	create := func(t *testing.T, r repo.IArticleRepo, title string, author string, category string) *models.Article {
+		t.Helper()
+		article, err := r.Create(ctx, &types.ArticleCreate{
+			Title:    title,
+			Content:  "The content of " + title,
+			Category: &category,
+		}, author)
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		return article
+	}
+
+	t.Run("CreateAndGet", func(t *testing.T) {
+		r := newRepo(t, Author1)
+		created := create(t, r, "First", Author1, "news")
+		if created.ID == 0 || created.CreatedAt.IsZero() {
+			t.Fatalf("Create: got ID %d and time %v, want both set", created.ID, created.CreatedAt)
+		}
+
+		article, err := r.Get(ctx, created.ID)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if article.Title != "First" || article.Author != Author1 || *article.Category != "news" {
+			t.Errorf("Get: got %+v", article)
+		}
+
+		if _, err := r.Get(ctx, created.ID+100); !errors.Is(err, domain.ErrArticleNotFound) {
+			t.Errorf("Get of a missing article: got %v, want ErrArticleNotFound", err)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		r := newRepo(t, Author1, Author2)
+		for i, title := range []string{"Alpha", "Beta", "Gamma"} {
+			author := Author1
+			if i == 2 {
+				author = Author2
+			}
+			create(t, r, title, author, "news")
+			time.Sleep(10 * time.Millisecond) // Orders the articles by the time of creation
+		}
+		create(t, r, "Delta", Author1, "guide")
+
+		list := func(query types.ArticleListQuery) []string {
+			t.Helper()
+			if query.Page == 0 {
+				query.Page, query.PageSize = 1, 10
+			}
+			result, err := r.List(ctx, &query)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			var titles []string
+			for _, article := range result.List {
+				titles = append(titles, article.Title)
+			}
+			return titles
+		}
+
+		assertTitles(t, "all", list(types.ArticleListQuery{}), "Delta", "Gamma", "Beta", "Alpha")
+		author := Author2
+		assertTitles(t, "by author", list(types.ArticleListQuery{Author: &author}), "Gamma")
+		category := "guide"
+		assertTitles(t, "by category", list(types.ArticleListQuery{Category: &category}), "Delta")
+		search := "et"
+		assertTitles(t, "by search", list(types.ArticleListQuery{Search: &search}), "Beta")
+
+		query := types.ArticleListQuery{}
+		query.Page, query.PageSize = 2, 3
+		result, err := r.List(ctx, &query)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if result.Total != 4 || len(result.List) != 1 || result.List[0].Title != "Alpha" {
+			t.Errorf("List of the second page: got total %d and %d articles", result.Total, len(result.List))
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		r := newRepo(t, Author1)
+		created, err := r.Create(ctx, &types.ArticleCreate{Title: "Draft", Content: "Draft"}, Author1)
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		title, category, empty := "Final", "news", ""
+		article, err := r.Update(ctx, created.ID, &types.ArticleUpdate{Title: &title, Category: &category, Content: &empty})
+		if err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if article.Title != "Final" || article.Content != "Draft" || article.Category == nil || *article.Category != "news" {
+			t.Errorf("Update: got %+v", article)
+		}
+		if article, err = r.Get(ctx, created.ID); err != nil || article.Title != "Final" {
+			t.Errorf("Get after Update: got %+v, %v", article, err)
+		}
+
+		if _, err := r.Update(ctx, created.ID+100, &types.ArticleUpdate{Title: &title}); !errors.Is(err, domain.ErrArticleNotFound) {
+			t.Errorf("Update of a missing article: got %v, want ErrArticleNotFound", err)
+		}
+	})
+
+	t.Run("Likes", func(t *testing.T) {
+		r := newRepo(t, Author1)
+		created := create(t, r, "Liked", Author1, "news")
+
+		article, err := r.UpdateLikes(ctx, created.ID, 3)
+		if err != nil || article.Likes != 3 {
+			t.Fatalf("UpdateLikes: got %+v, %v", article, err)
+		}
+		article, err = r.UpdateDislikes(ctx, created.ID, 2)
+		if err != nil || article.Likes != 3 || article.Dislikes != 2 {
+			t.Fatalf("UpdateDislikes: got %+v, %v", article, err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		r := newRepo(t, Author1)
+		created := create(t, r, "Doomed", Author1, "news")
+
+		if err := r.Delete(ctx, created.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := r.Get(ctx, created.ID); !errors.Is(err, domain.ErrArticleNotFound) {
+			t.Errorf("Get after Delete: got %v, want ErrArticleNotFound", err)
+		}
+		if err := r.Delete(ctx, created.ID); !errors.Is(err, domain.ErrArticleNotFound) {
+			t.Errorf("Delete of a missing article: got %v, want ErrArticleNotFound", err)
+		}
+	})
+
+	t.Run("ByAuthor", func(t *testing.T) {
+		r := newRepo(t, Author1, Author2)
+		create(t, r, "One", Author1, "news")
+		create(t, r, "Two", Author1, "news")
+		create(t, r, "Three", Author2, "news")
+
+		if n, err := r.ReassignAuthor(ctx, Author1, Author2); err != nil || n != 2 {
+			t.Errorf("ReassignAuthor: got %d, %v, want 2", n, err)
+		}
+		if n, err := r.DeleteByAuthor(ctx, Author1); err != nil || n != 0 {
+			t.Errorf("DeleteByAuthor of the old author: got %d, %v, want 0", n, err)
+		}
+		if n, err := r.DeleteByAuthor(ctx, Author2); err != nil || n != 3 {
+			t.Errorf("DeleteByAuthor: got %d, %v, want 3", n, err)
+		}
+	})
+
+	t.Run("EachAndImport", func(t *testing.T) {
+		r := newRepo(t, Author1)
+		createdAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+		for i := range 3 {
+			article := &models.Article{
+				ID:        uint(i + 1000), // Replaced with new IDs
+				CreatedAt: createdAt,
+				UpdatedAt: createdAt,
+				Title:     "Imported",
+				Content:   "Imported",
+				Author:    Author1,
+			}
+			if err := r.Import(ctx, article); err != nil {
+				t.Fatalf("Import: %v", err)
+			}
+		}
+
+		count := 0
+		err := r.Each(ctx, &types.ArticleListQuery{}, func(batch []models.Article) error {
+			for _, article := range batch {
+				count++
+				if !article.CreatedAt.Equal(createdAt) || article.Author != Author1 {
+					t.Errorf("Each: got %+v, want the time and author imported", article)
+				}
+			}
+			return nil
+		})
+		if err != nil || count != 3 {
+			t.Errorf("Each: got %d articles, %v, want 3", count, err)
+		}
+	})
+}
+
+func assertTitles(t *testing.T, name string, got []string, want ...string) {
+	t.Helper()
+	if !slices.Equal(got, want) {
+		t.Errorf("List %s: got %v, want %v", name, got, want)
+	}
+}
@@ -3,102 +3,211 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strconv"
+	"strings"
 
 	"bilingo/common"
+	domain "bilingo/domains/article"
 	"bilingo/domains/article/models"
 	"bilingo/domains/article/repo"
 	"bilingo/domains/article/types"
+	systemService "bilingo/domains/system/service"
+	userDomain "bilingo/domains/user"
+	userRepo "bilingo/domains/user/repo"
+	"bilingo/server/auth"
+	"bilingo/server/db"
+	"bilingo/server/events"
+	"bilingo/server/markdown"
 	"bilingo/server/oplog"
 )
 
 var logger = oplog.NewOpLogger("article")
 
+func init() {
+	systemService.RegisterObjectOwner("article", func(ctx context.Context, objectId string) (string, error) {
+		id, err := strconv.ParseUint(objectId, 10, 32)
+		if err != nil {
+			return "", fmt.Errorf("invalid article ID: %w", err)
+		}
+
+		article, err := repo.Articles(ctx).Get(ctx, uint(id))
+		if err != nil {
+			return "", err
+		}
+		return article.Author, nil
+	})
+	// Articles are public
+	systemService.RegisterObjectReader("article", func(ctx context.Context, objectId string) (bool, error) {
+		_, err := systemService.GetObjectOwner(ctx, "article", objectId)
+		return err == nil, err
+	})
+}
+
 func GetArticle(ctx context.Context, id uint) (*models.Article, error) {
-	return repo.ArticleRepo.Get(ctx, id)
+	return repo.Articles(ctx).Get(ctx, id)
 }
 
 func ListArticles(ctx context.Context, query types.ArticleListQuery) (*common.PaginatedResult[models.Article], error) {
-	return repo.ArticleRepo.List(ctx, &query)
-}
+	if found, err := resolveAuthorFilter(ctx, &query); err != nil {
+		return nil, err
+	} else if !found {
+		return &common.PaginatedResult[models.Article]{Total: 0, List: []models.Article{}}, nil
+	}
 
-func CreateArticle(ctx context.Context, data *types.ArticleCreate, author string) (*models.Article, error) {
-	article, err := repo.ArticleRepo.Create(ctx, data, author)
+	result, err := repo.Articles(ctx).List(ctx, &query)
 	if err != nil {
 		return nil, err
 	}
+	for i := range result.List {
+		if err := RenderArticle(&result.List[i], false); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
 
-	_ = logger.Success(ctx, oplog.LogData{
-		ObjectId:  strconv.FormatUint(uint64(article.ID), 10),
-		Operation: "create",
-		NewData:   &article,
-	})
+// RenderArticle fills the excerpt and the reading time of the article derived
+// from its content, and the sanitized HTML and the table of contents if withHtml
+// is set.
+func RenderArticle(article *models.Article, withHtml bool) error {
+	rendered, err := markdown.Render(article.Content)
+	if err != nil {
+		return fmt.Errorf("failed to render article %d: %w", article.ID, err)
+	}
 
-	return article, nil
+	article.Excerpt = rendered.Excerpt
+	article.ReadingTime = rendered.ReadingTime
+	if withHtml {
+		article.Html = &rendered.Html
+		article.Toc = rendered.Toc
+	}
+	return nil
 }
 
-func UpdateArticle(ctx context.Context, id uint, updates *types.ArticleUpdate) (*models.Article, error) {
-	oldData, err := repo.ArticleRepo.Get(ctx, id)
-	if err != nil {
-		return nil, err
+// resolveAuthorFilter replaces the email of the author to filter articles by
+// with the user ID the articles refer to, it returns false if there's no such
+// user.
+func resolveAuthorFilter(ctx context.Context, query *types.ArticleListQuery) (bool, error) {
+	if query.Author == nil || !strings.Contains(*query.Author, "@") {
+		return true, nil
 	}
 
-	newData, err := repo.ArticleRepo.Update(ctx, id, updates)
-	if err != nil {
-		return nil, err
+	user, err := userRepo.Users(ctx).GetByEmail(ctx, *query.Author)
+	if errors.Is(err, userDomain.ErrUserNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
 	}
 
-	_ = logger.Success(ctx, oplog.LogData{
-		ObjectId:  strconv.FormatUint(uint64(oldData.ID), 10),
-		Operation: "update",
-		OldData:   &oldData,
-		NewData:   &newData,
+	query.Author = &user.ID
+	return true, nil
+}
+
+func CreateArticle(ctx context.Context, data *types.ArticleCreate, author string) (*models.Article, error) {
+	var article *models.Article
+	err := db.Transaction(ctx, domain.DBBinding, func(ctx context.Context) error {
+		var err error
+		article, err = repo.Articles(ctx).Create(ctx, data, author)
+		if err != nil {
+			return err
+		}
+		return events.Publish(ctx, domain.ArticleCreated{ID: article.ID, Author: article.Author, Title: article.Title})
 	})
+	return article, err
+}
 
-	return newData, nil
+func UpdateArticle(ctx context.Context, id uint, updates *types.ArticleUpdate) (*models.Article, error) {
+	var article *models.Article
+	err := db.Transaction(ctx, domain.DBBinding, func(ctx context.Context) error {
+		var err error
+		article, err = repo.Articles(ctx).Update(ctx, id, updates)
+		if err != nil {
+			return err
+		}
+		return events.Publish(ctx, domain.ArticleUpdated{ID: article.ID, Author: article.Author, Title: article.Title})
+	})
+	return article, err
 }
 
 func DeleteArticle(ctx context.Context, id uint) error {
-	return repo.ArticleRepo.Delete(ctx, id)
+	err := db.Transaction(ctx, domain.DBBinding, func(ctx context.Context) error {
+		article, err := repo.Articles(ctx).Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := repo.Articles(ctx).Delete(ctx, id); err != nil {
+			return err
+		}
+		return events.Publish(ctx, domain.ArticleDeleted{ID: article.ID, Author: article.Author, Title: article.Title})
+	})
+	if err != nil {
+		return err
+	}
+	return systemService.DeleteAttachmentsOf(ctx, "article", []string{strconv.FormatUint(uint64(id), 10)})
 }
 
 func LikeArticle(ctx context.Context, id uint, action string) (*models.Article, error) {
-	article, err := repo.ArticleRepo.Get(ctx, id)
+	// The counts are updated based on the current ones
+	article, err := repo.Articles(ctx).Get(db.UsePrimary(ctx), id)
 	if err != nil {
 		return nil, err
 	}
 
-	article, err = func() (*models.Article, error) {
-		switch action {
-		case "like":
-			return repo.ArticleRepo.UpdateLikes(ctx, id, article.Likes+1)
-		case "unlike":
-			if article.Likes > 0 {
-				return repo.ArticleRepo.UpdateLikes(ctx, id, article.Likes-1)
-			}
-			return article, nil
-		case "dislike":
-			return repo.ArticleRepo.UpdateDislikes(ctx, id, article.Dislikes+1)
-		case "undislike":
-			if article.Dislikes > 0 {
-				return repo.ArticleRepo.UpdateDislikes(ctx, id, article.Dislikes-1)
-			}
-			return article, nil
-		default:
-			return nil, errors.New("invalid action")
+	// Reactions are recorded as they are instead of as plain updates
+	ctx = oplog.SkipAudit(ctx)
+	oldData := *article
+	err = db.Transaction(ctx, domain.DBBinding, func(ctx context.Context) error {
+		var err error
+		article, err = reactToArticle(ctx, article, action)
+		if err != nil {
+			return err
 		}
-	}()
 
+		event := domain.ArticleReacted{
+			ID:       article.ID,
+			Author:   article.Author,
+			Action:   action,
+			Likes:    article.Likes,
+			Dislikes: article.Dislikes,
+		}
+		if user := auth.GetUser(ctx); user != nil {
+			event.User = user.ID
+		}
+		return events.Publish(ctx, event)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	_ = logger.Success(ctx, oplog.LogData{
+	logger.Success(ctx, oplog.LogData{
 		ObjectId:  strconv.FormatUint(uint64(article.ID), 10),
 		Operation: action,
-		OldData:   &article,
-		NewData:   &article,
+		OldData:   &oldData,
+		NewData:   article,
 	})
 
-	return article, err
+	return article, nil
+}
+
+// reactToArticle updates the counts of the article for the reaction.
+func reactToArticle(ctx context.Context, article *models.Article, action string) (*models.Article, error) {
+	switch action {
+	case "like":
+		return repo.Articles(ctx).UpdateLikes(ctx, article.ID, article.Likes+1)
+	case "unlike":
+		if article.Likes > 0 {
+			return repo.Articles(ctx).UpdateLikes(ctx, article.ID, article.Likes-1)
+		}
+		return article, nil
+	case "dislike":
+		return repo.Articles(ctx).UpdateDislikes(ctx, article.ID, article.Dislikes+1)
+	case "undislike":
+		if article.Dislikes > 0 {
+			return repo.Articles(ctx).UpdateDislikes(ctx, article.ID, article.Dislikes-1)
+		}
+		return article, nil
+	default:
+		return nil, errors.New("invalid action")
+	}
 }
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	domain "bilingo/domains/article"
+	"bilingo/domains/article/repo"
+	"bilingo/domains/user/models"
+	"bilingo/server/events"
+	"bilingo/server/realtime"
+)
+
+// ArticleTopic returns the realtime topic of the changes of the article, e.g.
+// `article:42`, which gets the article.updated, article.reacted and
+// article.deleted events.
+func ArticleTopic(id uint) string {
+	return realtime.Topic("article", strconv.FormatUint(uint64(id), 10))
+}
+
+// PublishRealtime lets anyone subscribe to the topics of the articles that
+// exist, like anyone may read them, and publishes the changes of the articles
+// to them once committed.
+func PublishRealtime(bus *events.Bus, hub *realtime.Hub) {
+	realtime.Authorize(hub, "article", func(ctx context.Context, user *models.User, key string) error {
+		id, err := strconv.ParseUint(key, 10, 32)
+		if err != nil {
+			return realtime.ErrInvalidTopic
+		}
+		_, err = repo.Articles(ctx).Get(ctx, uint(id))
+		if errors.Is(err, domain.ErrArticleNotFound) {
+			return realtime.ErrTopicNotFound
+		}
+		return err
+	})
+
+	events.SubscribeSync(bus, func(ctx context.Context, event domain.ArticleUpdated) error {
+		return hub.Publish(ctx, ArticleTopic(event.ID), event.EventName(), event)
+	})
+	events.SubscribeSync(bus, func(ctx context.Context, event domain.ArticleReacted) error {
+		return hub.Publish(ctx, ArticleTopic(event.ID), event.EventName(), event)
+	})
+	events.SubscribeSync(bus, func(ctx context.Context, event domain.ArticleDeleted) error {
+		return hub.Publish(ctx, ArticleTopic(event.ID), event.EventName(), event)
+	})
+}
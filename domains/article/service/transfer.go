@@ -0,0 +1,605 @@
+package service
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	domain "bilingo/domains/article"
+	"bilingo/domains/article/models"
+	"bilingo/domains/article/repo"
+	"bilingo/domains/article/types"
+	userDomain "bilingo/domains/user"
+	userModels "bilingo/domains/user/models"
+	userRepo "bilingo/domains/user/repo"
+	"bilingo/server/db"
+	"bilingo/server/events"
+
+	"gopkg.in/yaml.v3"
+)
+
+var csvHeader = []string{
+	"id", "title", "content", "author", "category", "tags", "likes", "dislikes", "created_at", "updated_at",
+}
+
+const maxLineSize = 16 << 20 // 16 MiB, large enough for a single article in a JSON line
+
+// ExportArticles streams all articles matching the query to the writer in the
+// requested format, the pagination options of the query are ignored.
+func ExportArticles(ctx context.Context, query *types.ArticleExportQuery, w io.Writer) error {
+	if found, err := resolveAuthorFilter(ctx, &query.ArticleListQuery); err != nil {
+		return err
+	} else if !found {
+		return fmt.Errorf("%w: %s", userDomain.ErrAuthorNotFound, *query.Author)
+	}
+
+	switch query.Format {
+	case types.FormatJsonl:
+		return exportJsonl(ctx, &query.ArticleListQuery, w)
+	case types.FormatCsv:
+		return exportCsv(ctx, &query.ArticleListQuery, w)
+	case types.FormatMarkdown:
+		return exportMarkdown(ctx, &query.ArticleListQuery, w)
+	default:
+		return fmt.Errorf("%w: %q", domain.ErrUnsupportedFormat, query.Format)
+	}
+}
+
+func exportJsonl(ctx context.Context, query *types.ArticleListQuery, w io.Writer) error {
+	buf := bufio.NewWriter(w)
+	enc := json.NewEncoder(buf)
+	emails := authorEmails{}
+	err := repo.Articles(ctx).Each(ctx, query, func(batch []models.Article) error {
+		for i := range batch {
+			record, err := toRecord(ctx, &batch[i], emails)
+			if err != nil {
+				return err
+			}
+			if err := enc.Encode(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export articles: %w", err)
+	}
+
+	return buf.Flush()
+}
+
+func exportCsv(ctx context.Context, query *types.ArticleListQuery, w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return fmt.Errorf("failed to export articles: %w", err)
+	}
+
+	emails := authorEmails{}
+	err := repo.Articles(ctx).Each(ctx, query, func(batch []models.Article) error {
+		for i := range batch {
+			record, err := toRecord(ctx, &batch[i], emails)
+			if err != nil {
+				return err
+			}
+			if err := cw.Write(toCsvRow(record)); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export articles: %w", err)
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func exportMarkdown(ctx context.Context, query *types.ArticleListQuery, w io.Writer) error {
+	zw := zip.NewWriter(w)
+	emails := authorEmails{}
+	err := repo.Articles(ctx).Each(ctx, query, func(batch []models.Article) error {
+		for i := range batch {
+			record, err := toRecord(ctx, &batch[i], emails)
+			if err != nil {
+				return err
+			}
+			file, err := zw.Create(fmt.Sprintf("%d-%s.md", record.ID, slugify(record.Title)))
+			if err != nil {
+				return err
+			}
+
+			content, err := toMarkdown(record)
+			if err != nil {
+				return err
+			}
+
+			if _, err := file.Write(content); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export articles: %w", err)
+	}
+
+	return zw.Close()
+}
+
+// ImportArticles reads articles from the source in the given format, validates
+// them and inserts the valid ones, unless it's a dry run. Invalid records don't
+// stop the import, they're reported with their line numbers instead.
+func ImportArticles(
+	ctx context.Context,
+	src io.ReaderAt,
+	size int64,
+	opts *types.ArticleImportOptions,
+) (*types.ArticleImportReport, error) {
+	report := &types.ArticleImportReport{
+		DryRun: opts.DryRun,
+		Errors: []types.ArticleImportError{},
+	}
+	authors := authorIds{}
+
+	err := decodeRecords(src, size, opts.Format, func(line int, name *string, record *types.ArticleRecord, decodeErr error) error {
+		report.Total++
+
+		article, err := func() (*models.Article, error) {
+			if decodeErr != nil {
+				return nil, fmt.Errorf("malformed record: %w", decodeErr)
+			}
+			return validateRecord(ctx, record, opts, authors)
+		}()
+		if err == nil && !opts.DryRun {
+			err = db.Transaction(ctx, domain.DBBinding, func(ctx context.Context) error {
+				if err := repo.Articles(ctx).Import(ctx, article); err != nil {
+					return err
+				}
+				return events.Publish(ctx, domain.ArticleCreated{ID: article.ID, Author: article.Author, Title: article.Title})
+			})
+		}
+
+		if err != nil {
+			report.Failed++
+			report.Errors = append(report.Errors, types.ArticleImportError{
+				Line:    line,
+				Name:    name,
+				Message: err.Error(),
+			})
+		} else {
+			report.Imported++
+		}
+
+		return ctx.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to import articles: %w", err)
+	}
+
+	return report, nil
+}
+
+// decodeRecords decodes records from the source one by one, the error is passed
+// to the callback instead of the record if the line or entry cannot be decoded.
+func decodeRecords(
+	src io.ReaderAt,
+	size int64,
+	format string,
+	fn func(line int, name *string, record *types.ArticleRecord, err error) error,
+) error {
+	switch format {
+	case types.FormatJsonl:
+		return decodeJsonl(io.NewSectionReader(src, 0, size), fn)
+	case types.FormatCsv:
+		return decodeCsv(io.NewSectionReader(src, 0, size), fn)
+	case types.FormatMarkdown:
+		return decodeMarkdown(src, size, fn)
+	default:
+		return fmt.Errorf("%w: %q", domain.ErrUnsupportedFormat, format)
+	}
+}
+
+func decodeJsonl(r io.Reader, fn func(line int, name *string, record *types.ArticleRecord, err error) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var record types.ArticleRecord
+		if err := json.Unmarshal(text, &record); err != nil {
+			if err := fn(line, nil, nil, err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := fn(line, nil, &record, nil); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+func decodeCsv(r io.Reader, fn func(line int, name *string, record *types.ArticleRecord, err error) error) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+
+	for {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			if err := fn(parseErr.StartLine, nil, nil, parseErr.Err); err != nil {
+				return err
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		line, _ := cr.FieldPos(0)
+		record, err := fromCsvRow(row, columns)
+		if err := fn(line, nil, record, err); err != nil {
+			return err
+		}
+	}
+}
+
+func decodeMarkdown(
+	src io.ReaderAt,
+	size int64,
+	fn func(line int, name *string, record *types.ArticleRecord, err error) error,
+) error {
+	zr, err := zip.NewReader(src, size)
+	if err != nil {
+		return fmt.Errorf("%w: %w", domain.ErrMalformedArchive, err)
+	}
+
+	files := slices.DeleteFunc(slices.Clone(zr.File), func(f *zip.File) bool {
+		return f.FileInfo().IsDir() || path.Ext(f.Name) != ".md"
+	})
+	slices.SortFunc(files, func(a *zip.File, b *zip.File) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	for i, file := range files {
+		name := file.Name
+		record, err := readMarkdownFile(file)
+		if err := fn(i+1, &name, record, err); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readMarkdownFile(file *zip.File) (*types.ArticleRecord, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+
+	return fromMarkdown(data)
+}
+
+func validateRecord(
+	ctx context.Context,
+	record *types.ArticleRecord,
+	opts *types.ArticleImportOptions,
+	authors authorIds,
+) (*models.Article, error) {
+	title := strings.TrimSpace(record.Title)
+	if title == "" {
+		return nil, errors.New("title is required")
+	} else if len([]rune(title)) > 200 {
+		return nil, errors.New("title must not exceed 200 characters")
+	}
+
+	if strings.TrimSpace(record.Content) == "" {
+		return nil, errors.New("content is required")
+	}
+
+	if record.Category != nil && len([]rune(*record.Category)) > 64 {
+		return nil, errors.New("category must not exceed 64 characters")
+	}
+
+	if record.Likes < 0 || record.Dislikes < 0 {
+		return nil, errors.New("likes and dislikes must not be negative")
+	}
+
+	author := record.Author
+	if author == "" && opts.DefaultAuthor != nil {
+		author = *opts.DefaultAuthor
+	}
+	if author == "" {
+		return nil, errors.New("author is required")
+	}
+
+	authorId, err := authors.resolve(ctx, author)
+	if errors.Is(err, userDomain.ErrUserNotFound) {
+		return nil, fmt.Errorf("%w: %s", userDomain.ErrAuthorNotFound, author)
+	} else if err != nil {
+		return nil, err
+	} else if opts.AllowedAuthor != nil && authorId != *opts.AllowedAuthor {
+		return nil, fmt.Errorf("not allowed to import articles of %s", author)
+	}
+
+	createdAt := time.Now()
+	if record.CreatedAt != nil {
+		createdAt = *record.CreatedAt
+	}
+	updatedAt := createdAt
+	if record.UpdatedAt != nil {
+		updatedAt = *record.UpdatedAt
+	}
+
+	return &models.Article{
+		CreatedAt: createdAt,
+		UpdatedAt: updatedAt,
+		Title:     title,
+		Content:   record.Content,
+		Author:    authorId,
+		Category:  record.Category,
+		Tags:      record.Tags,
+		Likes:     record.Likes,
+		Dislikes:  record.Dislikes,
+	}, nil
+}
+
+// authorIds caches the IDs of the authors by how the records refer to them, so
+// each author is only looked up once per import.
+type authorIds map[string]authorLookup
+
+type authorLookup struct {
+	id  string
+	err error
+}
+
+// resolve returns the ID of the user referred to by the email, or by the ID as
+// well for files exported before authors were written as emails.
+func (c authorIds) resolve(ctx context.Context, author string) (string, error) {
+	if lookup, ok := c[author]; ok {
+		return lookup.id, lookup.err
+	}
+
+	var user *userModels.User
+	var err error
+	if strings.Contains(author, "@") {
+		user, err = userRepo.Users(ctx).GetByEmail(ctx, author)
+	} else {
+		user, err = userRepo.Users(ctx).Get(ctx, author)
+	}
+
+	lookup := authorLookup{err: err}
+	if err == nil {
+		lookup.id = user.ID
+	}
+	c[author] = lookup
+	return lookup.id, lookup.err
+}
+
+// authorEmails caches the emails of the authors by their IDs, the exported
+// records refer to the authors by emails, which stay meaningful elsewhere.
+type authorEmails map[string]string
+
+func (c authorEmails) resolve(ctx context.Context, authorId string) (string, error) {
+	if email, ok := c[authorId]; ok {
+		return email, nil
+	}
+
+	user, err := userRepo.Users(ctx).Get(ctx, authorId)
+	if err != nil {
+		return "", fmt.Errorf("failed to find author %s: %w", authorId, err)
+	}
+
+	c[authorId] = user.Email
+	return user.Email, nil
+}
+
+func toRecord(ctx context.Context, article *models.Article, emails authorEmails) (*types.ArticleRecord, error) {
+	author, err := emails.resolve(ctx, article.Author)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.ArticleRecord{
+		ID:        article.ID,
+		Title:     article.Title,
+		Content:   article.Content,
+		Author:    author,
+		Category:  article.Category,
+		Tags:      article.Tags,
+		Likes:     article.Likes,
+		Dislikes:  article.Dislikes,
+		CreatedAt: &article.CreatedAt,
+		UpdatedAt: &article.UpdatedAt,
+	}, nil
+}
+
+func toCsvRow(record *types.ArticleRecord) []string {
+	optional := func(value *string) string {
+		if value == nil {
+			return ""
+		}
+		return *value
+	}
+	timestamp := func(value *time.Time) string {
+		if value == nil {
+			return ""
+		}
+		return value.Format(time.RFC3339Nano)
+	}
+
+	return []string{
+		strconv.FormatUint(uint64(record.ID), 10),
+		record.Title,
+		record.Content,
+		record.Author,
+		optional(record.Category),
+		optional(record.Tags),
+		strconv.Itoa(record.Likes),
+		strconv.Itoa(record.Dislikes),
+		timestamp(record.CreatedAt),
+		timestamp(record.UpdatedAt),
+	}
+}
+
+func fromCsvRow(row []string, columns map[string]int) (*types.ArticleRecord, error) {
+	field := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return row[i]
+	}
+	optional := func(name string) *string {
+		value := field(name)
+		if value == "" {
+			return nil
+		}
+		return &value
+	}
+	number := func(name string) (int, error) {
+		value := field(name)
+		if value == "" {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, fmt.Errorf("%s is not a number: %q", name, value)
+		}
+		return n, nil
+	}
+	timestamp := func(name string) (*time.Time, error) {
+		value := field(name)
+		if value == "" {
+			return nil, nil
+		}
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not an RFC 3339 time: %q", name, value)
+		}
+		return &t, nil
+	}
+
+	record := &types.ArticleRecord{
+		Title:    field("title"),
+		Content:  field("content"),
+		Author:   field("author"),
+		Category: optional("category"),
+		Tags:     optional("tags"),
+	}
+
+	var err error
+	if record.Likes, err = number("likes"); err != nil {
+		return nil, err
+	}
+	if record.Dislikes, err = number("dislikes"); err != nil {
+		return nil, err
+	}
+	if record.CreatedAt, err = timestamp("created_at"); err != nil {
+		return nil, err
+	}
+	if record.UpdatedAt, err = timestamp("updated_at"); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// toMarkdown renders the record as a Markdown document with YAML front matter.
+func toMarkdown(record *types.ArticleRecord) ([]byte, error) {
+	frontMatter, err := yaml.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("---\n")
+	buf.Write(frontMatter)
+	buf.WriteString("---\n\n")
+	buf.WriteString(record.Content)
+	return buf.Bytes(), nil
+}
+
+func fromMarkdown(data []byte) (*types.ArticleRecord, error) {
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	rest, ok := bytes.CutPrefix(data, []byte("---\n"))
+	if !ok {
+		return nil, domain.ErrInvalidFrontMatter
+	}
+
+	// The front matter may be empty, in which case it ends right away
+	var frontMatter []byte
+	content, ok := bytes.CutPrefix(rest, []byte("---\n"))
+	if !ok {
+		frontMatter, content, ok = bytes.Cut(rest, []byte("\n---\n"))
+	}
+	if !ok {
+		return nil, domain.ErrInvalidFrontMatter
+	}
+
+	var record types.ArticleRecord
+	if err := yaml.Unmarshal(frontMatter, &record); err != nil {
+		return nil, fmt.Errorf("%w: %w", domain.ErrInvalidFrontMatter, err)
+	}
+
+	// Only the blank line toMarkdown adds is part of the front matter, content
+	// may start with blank lines of its own
+	content, _ = bytes.CutPrefix(content, []byte("\n"))
+	record.Content = string(content)
+	return &record, nil
+}
+
+var nonSlugChars = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+func slugify(title string) string {
+	slug := strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if runes := []rune(slug); len(runes) > 50 {
+		slug = strings.TrimRight(string(runes[:50]), "-")
+	}
+	if slug == "" {
+		return "untitled"
+	}
+	return slug
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	domain "bilingo/domains/article"
+	"bilingo/domains/article/types"
+)
+
+func TestMarkdownRoundTrip(t *testing.T) {
+	category := "news"
+	for _, content := range []string{"", "Hello", "\n\n    indented code", "---\nnot front matter\n---\n", "trailing\n\n"} {
+		record := &types.ArticleRecord{ID: 1, Title: "Title", Content: content, Author: "alice", Category: &category}
+		data, err := toMarkdown(record)
+		if err != nil {
+			t.Fatalf("failed to render %q: %v", content, err)
+		}
+		parsed, err := fromMarkdown(data)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", data, err)
+		}
+		if parsed.Content != content || parsed.Title != record.Title || parsed.Category == nil || *parsed.Category != category {
+			t.Errorf("expected %+v, got %+v", record, parsed)
+		}
+	}
+}
+
+func TestFromMarkdown(t *testing.T) {
+	tests := []struct {
+		data    string
+		title   string
+		content string
+	}{
+		{"---\ntitle: Title\n---\nContent", "Title", "Content"},
+		{"---\r\ntitle: Title\r\n---\r\n\r\nContent\r\n", "Title", "Content\n"},
+		{"---\n---\nContent", "", "Content"},
+		{"---\n---\n\n\nContent", "", "\nContent"},
+		{"---\n---\n", "", ""},
+	}
+	for _, test := range tests {
+		record, err := fromMarkdown([]byte(test.data))
+		if err != nil {
+			t.Errorf("failed to parse %q: %v", test.data, err)
+		} else if record.Title != test.title || record.Content != test.content {
+			t.Errorf("expected %q and %q from %q, got %q and %q", test.title, test.content, test.data, record.Title, record.Content)
+		}
+	}
+
+	for _, data := range []string{"", "Content", "---\ntitle: Title\n", "---\ntitle: [\n---\n"} {
+		if _, err := fromMarkdown([]byte(data)); !errors.Is(err, domain.ErrInvalidFrontMatter) {
+			t.Errorf("expected ErrInvalidFrontMatter for %q, got %v", data, err)
+		}
+	}
+}
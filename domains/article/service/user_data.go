@@ -0,0 +1,115 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	"bilingo/domains/article/models"
+	"bilingo/domains/article/repo"
+	"bilingo/domains/article/types"
+	systemService "bilingo/domains/system/service"
+	systemTypes "bilingo/domains/system/types"
+)
+
+// reactionOperations are the operations reactions to articles are recorded as.
+var reactionOperations = []string{"like", "unlike", "dislike", "undislike"}
+
+// reactionRecord is a reaction of a user to an article in the data export.
+type reactionRecord struct {
+	Article   string    `json:"article"`
+	Reaction  string    `json:"reaction"`
+	Times     uint32    `json:"times"`
+	Timestamp time.Time `json:"timestamp"` // The time of the latest of the repeated reactions
+}
+
+func init() {
+	systemService.RegisterUserData("article", systemService.UserDataHandler{
+		Export: exportUserArticles,
+		Delete: deleteUserArticles,
+		Reassign: func(ctx context.Context, userId string, toUserId string) error {
+			_, err := repo.Articles(ctx).ReassignAuthor(ctx, userId, toUserId)
+			return err
+		},
+	})
+}
+
+// exportUserArticles writes the articles of the user and the reactions of the
+// user to articles to the zip archive.
+func exportUserArticles(ctx context.Context, userId string, zw *zip.Writer) error {
+	w, err := zw.Create("articles.jsonl")
+	if err != nil {
+		return err
+	}
+	if err := exportJsonl(ctx, &types.ArticleListQuery{Author: &userId}, w); err != nil {
+		return err
+	}
+
+	w, err = zw.Create("reactions.jsonl")
+	if err != nil {
+		return err
+	}
+	return exportUserReactions(ctx, userId, w)
+}
+
+// exportUserReactions writes the reactions of the user, which are only kept as
+// the oplogs of the reactions, as JSON lines.
+func exportUserReactions(ctx context.Context, userId string, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	objectType := "article"
+	query := systemTypes.OpLogListQuery{ObjectType: &objectType, User: &userId}
+	query.PageSize = 100
+	for query.Page = 1; ; query.Page++ {
+		result, err := systemService.ListOpLogs(ctx, query)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range result.List {
+			if entry.Result != "success" || !slices.Contains(reactionOperations, entry.Operation) {
+				continue
+			}
+			err := enc.Encode(reactionRecord{
+				Article:   entry.ObjectId,
+				Reaction:  entry.Operation,
+				Times:     entry.Times,
+				Timestamp: entry.Timestamp,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if query.Page*query.PageSize >= result.Total {
+			return nil
+		}
+	}
+}
+
+// deleteUserArticles deletes the articles of the user along with the comments
+// and attachments on them.
+func deleteUserArticles(ctx context.Context, userId string) error {
+	var ids []string
+	err := repo.Articles(ctx).Each(ctx, &types.ArticleListQuery{Author: &userId}, func(batch []models.Article) error {
+		for _, article := range batch {
+			ids = append(ids, strconv.FormatUint(uint64(article.ID), 10))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := systemService.DeleteCommentsOf(ctx, "article", ids); err != nil {
+		return err
+	}
+	if err := systemService.DeleteAttachmentsOf(ctx, "article", ids); err != nil {
+		return err
+	}
+	_, err = repo.Articles(ctx).DeleteByAuthor(ctx, userId)
+	return err
+}
@@ -25,3 +25,44 @@ export interface ArticleListQuery extends common.PaginatedQuery {
 export interface ArticleLikeAction {
     action: string
 }
+
+//////////
+// source: transfer.go
+
+export const FormatJsonl = "jsonl"
+export const FormatCsv = "csv"
+export const FormatMarkdown = "markdown"
+/**
+ * ArticleRecord is the shape of an article in the import/export files.
+ */
+export interface ArticleRecord {
+    id: number /* uint */
+    title: string
+    content: string
+    author: string
+    category?: string
+    tags?: string
+    likes: number /* int */
+    dislikes: number /* int */
+    created_at?: string /* RFC3339 */
+    updated_at?: string /* RFC3339 */
+}
+export interface ArticleExportQuery extends ArticleListQuery {
+    format: string
+}
+export interface ArticleImportOptions {
+    format: string
+    dry_run: boolean
+}
+export interface ArticleImportError {
+    line: number /* int */ // The line (jsonl, csv) or entry (markdown) number, starting from 1
+    name?: string // The file name of the entry in the markdown archive
+    message: string
+}
+export interface ArticleImportReport {
+    dry_run: boolean
+    total: number /* int */
+    imported: number /* int */
+    failed: number /* int */
+    errors: ArticleImportError[]
+}
@@ -0,0 +1,51 @@
+package types
+
+import "time"
+
+const (
+	FormatJsonl    = "jsonl"
+	FormatCsv      = "csv"
+	FormatMarkdown = "markdown"
+)
+
+// ArticleRecord is the shape of an article in the import/export files.
+type ArticleRecord struct {
+	ID        uint       `json:"id" yaml:"id"`
+	Title     string     `json:"title" yaml:"title"`
+	Content   string     `json:"content" yaml:"-"`
+	Author    string     `json:"author" yaml:"author"`
+	Category  *string    `json:"category" yaml:"category"`
+	Tags      *string    `json:"tags" yaml:"tags"`
+	Likes     int        `json:"likes" yaml:"likes"`
+	Dislikes  int        `json:"dislikes" yaml:"dislikes"`
+	CreatedAt *time.Time `json:"created_at" yaml:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at" yaml:"updated_at"`
+}
+
+type ArticleExportQuery struct {
+	ArticleListQuery `tstype:",extends"`
+	Format           string `json:"format" query:"format" validate:"oneof=jsonl csv markdown"`
+}
+
+type ArticleImportOptions struct {
+	Format string `json:"format" query:"format" validate:"oneof=jsonl csv markdown"`
+	DryRun bool   `json:"dry_run" query:"dry_run"`
+	// DefaultAuthor is used for records without an author.
+	DefaultAuthor *string `json:"-" query:"-"`
+	// AllowedAuthor, if set, rejects records written by anyone else.
+	AllowedAuthor *string `json:"-" query:"-"`
+}
+
+type ArticleImportError struct {
+	Line    int     `json:"line"`           // The line (jsonl, csv) or entry (markdown) number, starting from 1
+	Name    *string `json:"name,omitempty"` // The file name of the entry in the markdown archive
+	Message string  `json:"message"`
+}
+
+type ArticleImportReport struct {
+	DryRun   bool                 `json:"dry_run"`
+	Total    int                  `json:"total"`
+	Imported int                  `json:"imported"`
+	Failed   int                  `json:"failed"`
+	Errors   []ArticleImportError `json:"errors"`
+}
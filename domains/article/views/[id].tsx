@@ -8,8 +8,8 @@ import type { ArticleUpdate } from "../types"
 import { deleteArticle, getArticle, likeArticle, updateArticle } from "../api/article.ts"
 import { useAuth } from "@/client/contexts/AuthContext.tsx"
 import { alert, confirm } from "@ayonli/jsext/dialog"
-import type { User } from "@/domains/user/models"
-import { getUser } from "@/domains/user/api/user.ts"
+import type { Profile } from "@/domains/user/types"
+import { getProfile } from "@/domains/user/api/user.ts"
 import { CommentAndLogSection } from "@/domains/system/components/index.ts"
 
 export default function ArticleDetail(): JSX.Element {
@@ -18,7 +18,7 @@ export default function ArticleDetail(): JSX.Element {
     const navigate = useNavigate()
     const { user } = useAuth()
     const [article, setArticle] = useState<Article | null>(null)
-    const [authorUser, setAuthorUser] = useState<User | null>(null)
+    const [authorUser, setAuthorUser] = useState<Profile | null>(null)
     const [loading, setLoading] = useState(true)
     const [editMode, setEditMode] = useState(searchParams.get("edit") === "true")
     const [title, setTitle] = useState("")
@@ -28,14 +28,14 @@ export default function ArticleDetail(): JSX.Element {
     const [saving, setSaving] = useState(false)
 
     // Check if current user is the author
-    const isAuthor = user && article && user.email === article.author
+    const isAuthor = user && article && user.id === article.author
 
     async function loadArticle(): Promise<void> {
         if (!id) { return }
 
         setLoading(true)
         try {
-            const result = await getArticle(Number(id))
+            const result = await getArticle(Number(id), "html")
             if (result.success) {
                 const article = result.data
                 setArticle(article)
@@ -44,8 +44,8 @@ export default function ArticleDetail(): JSX.Element {
                 setCategory(article.category || "")
                 setTags(article.tags || "")
 
-                // Load author user info
-                const authorResult = await getUser(article.author)
+                // Load the profile of the author
+                const authorResult = await getProfile(article.author)
                 if (authorResult.success) {
                     setAuthorUser(authorResult.data)
                 }
@@ -316,10 +316,11 @@ export default function ArticleDetail(): JSX.Element {
                             作者: {authorUser
                                 ? (
                                     <Link
-                                        to={`/users/${encodeURIComponent(authorUser.email)}`}
+                                        to={`/users/${authorUser.id}`}
                                         className="font-semibold text-blue-600 hover:text-blue-800 hover:underline"
                                     >
-                                        {authorUser.name} &lt;{authorUser.email}&gt;
+                                        {authorUser.display_name ?? authorUser.name}
+                                        {authorUser.email && <> &lt;{authorUser.email}&gt;</>}
                                     </Link>
                                 )
                                 : <strong>{article.author}</strong>}
@@ -344,11 +345,21 @@ export default function ArticleDetail(): JSX.Element {
                     </div>
                 </header>
 
-                <article className="prose prose-slate prose-lg max-w-none mb-8">
-                    <ReactMarkdown remarkPlugins={[remarkGfm]}>
-                        {article.content}
-                    </ReactMarkdown>
-                </article>
+                {article.html !== undefined
+                    ? (
+                        <article
+                            className="prose prose-slate prose-lg max-w-none mb-8"
+                            // The HTML is sanitized by the server
+                            dangerouslySetInnerHTML={{ __html: article.html }}
+                        />
+                    )
+                    : (
+                        <article className="prose prose-slate prose-lg max-w-none mb-8">
+                            <ReactMarkdown remarkPlugins={[remarkGfm]}>
+                                {article.content}
+                            </ReactMarkdown>
+                        </article>
+                    )}
 
                 <footer className="border-t pt-6">
                     <div className="flex items-center justify-between">
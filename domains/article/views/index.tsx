@@ -5,10 +5,15 @@ import type { Article } from "../models"
 import type { ArticleListQuery } from "../types"
 import { listArticles } from "../api/article.ts"
 import { alert } from "@ayonli/jsext/dialog"
+import { useAuth } from "@/client/contexts/AuthContext.tsx"
+import { listProfiles } from "@/domains/user/api/user.ts"
+import type { Profile } from "@/domains/user/types"
 
 export default function ArticleIndex(): JSX.Element {
     const navigate = useNavigate()
+    const { user } = useAuth()
     const [articles, setArticles] = useState<Article[]>([])
+    const [authors, setAuthors] = useState<Record<string, Profile>>({})
     const [total, setTotal] = useState(0)
     const [page, setPage] = useState(1)
     const [searchTerm, setSearchTerm] = useState("")
@@ -40,6 +45,7 @@ export default function ArticleIndex(): JSX.Element {
             if (result.success) {
                 setArticles(result.data.list)
                 setTotal(result.data.total)
+                await loadAuthors(result.data.list)
             } else {
                 await alert("加载文章失败: " + result.message)
             }
@@ -48,9 +54,23 @@ export default function ArticleIndex(): JSX.Element {
         }
     }
 
+    // Articles refer to their authors by user IDs, whose profiles tell who they
+    // are
+    async function loadAuthors(list: Article[]): Promise<void> {
+        const ids = [...new Set(list.map((a) => a.author))]
+        if (ids.length === 0) {
+            return
+        }
+
+        const result = await listProfiles(ids)
+        if (result.success) {
+            setAuthors(Object.fromEntries(result.data.map((p) => [p.id, p])))
+        }
+    }
+
     useEffect(() => {
         loadArticles()
-    }, [page])
+    }, [page, user])
 
     function handleSearch(e: React.FormEvent): void {
         e.preventDefault()
@@ -75,24 +95,6 @@ export default function ArticleIndex(): JSX.Element {
         })
     }
 
-    function getExcerpt(content: string, length = 100): string {
-        // Remove markdown syntax for plain text preview
-        const plainText = content
-            .replace(/^#{1,6}\s+/gm, "") // Remove headers
-            .replace(/\*\*(.+?)\*\*/g, "$1") // Remove bold
-            .replace(/\*(.+?)\*/g, "$1") // Remove italic
-            .replace(/\[(.+?)\]\(.+?\)/g, "$1") // Remove links, keep text
-            .replace(/`(.+?)`/g, "$1") // Remove code
-            .replace(/^\s*[-*+]\s+/gm, "") // Remove list markers
-            .replace(/^\s*\d+\.\s+/gm, "") // Remove numbered list markers
-            .trim()
-
-        if (plainText.length <= length) {
-            return plainText
-        }
-        return plainText.slice(0, length) + "..."
-    }
-
     function parseTags(tags?: string): string[] {
         if (!tags) { return [] }
         return tags.split(",").map((tag) => tag.trim()).filter(Boolean)
@@ -144,7 +146,7 @@ export default function ArticleIndex(): JSX.Element {
                             type="text"
                             value={author}
                             onChange={(e) => setAuthor(e.target.value)}
-                            placeholder="作者..."
+                            placeholder="作者邮箱..."
                             className="flex-1 px-4 py-2 border border-gray-300 rounded-lg focus:ring-2 focus:ring-blue-500 focus:border-transparent"
                         />
                         <input
@@ -183,13 +185,14 @@ export default function ArticleIndex(): JSX.Element {
                                         {article.title}
                                     </h2>
                                     <p className="text-gray-600 text-sm mb-4 line-clamp-3">
-                                        {getExcerpt(article.content)}
+                                        {article.excerpt}
                                     </p>
                                     <div className="space-y-2 mb-4">
                                         <div className="flex items-center gap-2 text-sm text-gray-500">
                                             <span>作者:</span>
                                             <span className="font-medium text-gray-700">
-                                                {article.author}
+                                                {authors[article.author]?.display_name ?? authors[article.author]?.name ??
+                                                    article.author}
                                             </span>
                                         </div>
                                         <div className="flex items-center gap-4 text-xs text-gray-500">
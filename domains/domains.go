@@ -0,0 +1,19 @@
+// Package domains assembles the domains into an app.
+package domains
+
+import (
+	article "bilingo/domains/article/module"
+	notification "bilingo/domains/notification/module"
+	system "bilingo/domains/system/module"
+	user "bilingo/domains/user/module"
+	webhook "bilingo/domains/webhook/module"
+	"bilingo/server/app"
+)
+
+// Register adds the domains to the app, which must have the databases, the
+// mailer, the storage, the event bus, the job queue and the realtime hub. The
+// system domain comes first, so that the changes made by the others when the
+// app starts are recorded in the oplogs.
+func Register(a *app.App) error {
+	return a.Register(system.Register, user.Register, article.Register, webhook.Register, notification.Register)
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	domain "bilingo/domains/notification"
+	"bilingo/domains/notification/service"
+	"bilingo/domains/notification/types"
+	"bilingo/server"
+	"bilingo/server/app"
+	"bilingo/server/auth"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Register adds the routes of the inbox and the preference of the user logged
+// in to the app.
+func Register(a *app.App) {
+	api := a.Group("/notifications", auth.UseAuth, auth.RequireAuth)
+	api.Get("/", listNotifications)
+	api.Get("/unread-count", countUnread)
+	api.Post("/read", markAllRead)
+	api.Get("/preference", getPreference)
+	api.Patch("/preference", updatePreference)
+	api.Post("/:id/read", markRead)
+}
+
+// notificationError responds with the status of the error of the notification
+// service.
+func notificationError(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, domain.ErrNotificationNotFound):
+		return server.Error(ctx, 404, err)
+	case errors.Is(err, domain.ErrInvalidPreference):
+		return server.Error(ctx, 400, err)
+	default:
+		return server.Error(ctx, 500, err)
+	}
+}
+
+func listNotifications(ctx *fiber.Ctx) error {
+	var query types.NotificationListQuery
+	if err := ctx.QueryParser(&query); err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("malformed query: %w", err))
+	}
+
+	user := auth.GetUser(ctx.UserContext())
+	result, err := service.ListNotifications(ctx.UserContext(), user.ID, query)
+	if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success(ctx, result)
+}
+
+// countUnread responds with the number of unread notifications in the inbox,
+// e.g. for a badge.
+func countUnread(ctx *fiber.Ctx) error {
+	user := auth.GetUser(ctx.UserContext())
+	count, err := service.CountUnread(ctx.UserContext(), user.ID)
+	if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success(ctx, count)
+}
+
+func markRead(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("invalid notification ID: %w", err))
+	}
+
+	user := auth.GetUser(ctx.UserContext())
+	notification, err := service.MarkRead(ctx.UserContext(), user.ID, uint(id))
+	if err != nil {
+		return notificationError(ctx, err)
+	}
+
+	return server.Success(ctx, notification)
+}
+
+// markAllRead marks all notifications in the inbox as read, and responds with
+// how many there were.
+func markAllRead(ctx *fiber.Ctx) error {
+	user := auth.GetUser(ctx.UserContext())
+	count, err := service.MarkAllRead(ctx.UserContext(), user.ID)
+	if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success(ctx, count)
+}
+
+func getPreference(ctx *fiber.Ctx) error {
+	user := auth.GetUser(ctx.UserContext())
+	preference, err := service.GetPreference(ctx.UserContext(), user.ID)
+	if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success(ctx, preference)
+}
+
+func updatePreference(ctx *fiber.Ctx) error {
+	var data types.NotificationPreferenceUpdate
+	if err := ctx.BodyParser(&data); err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("malformed request body: %w", err))
+	}
+
+	user := auth.GetUser(ctx.UserContext())
+	preference, err := service.UpdatePreference(ctx.UserContext(), user.ID, &data)
+	if err != nil {
+		return notificationError(ctx, err)
+	}
+
+	return server.Success(ctx, preference)
+}
@@ -0,0 +1,34 @@
+import type { ApiResponse, PaginatedResult } from "@/common"
+import { ApiEntry } from "@/client"
+import type { Notification, NotificationPreference } from "../models"
+import type { NotificationListQuery, NotificationPreferenceUpdate } from "../types"
+
+const notificationApi = new ApiEntry("/notifications")
+
+export async function listNotifications(
+    query: Partial<NotificationListQuery>,
+): ApiResponse<PaginatedResult<Notification>> {
+    return await notificationApi.get("/", query)
+}
+
+export async function countUnreadNotifications(): ApiResponse<number> {
+    return await notificationApi.get("/unread-count")
+}
+
+export async function markNotificationRead(id: number): ApiResponse<Notification> {
+    return await notificationApi.post(`/${id}/read`)
+}
+
+export async function markAllNotificationsRead(): ApiResponse<number> {
+    return await notificationApi.post("/read")
+}
+
+export async function getNotificationPreference(): ApiResponse<NotificationPreference> {
+    return await notificationApi.get("/preference")
+}
+
+export async function updateNotificationPreference(
+    data: NotificationPreferenceUpdate,
+): ApiResponse<NotificationPreference> {
+    return await notificationApi.patch("/preference", null, data)
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Notification tells a user that something happened to their content, e.g. a
+// comment on their article. It's shown in the inbox of the user, mailed in a
+// digest, or both, depending on the preference of the user when it's created.
+type Notification struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`                                                             // Changes as reactions are added up
+	UserID    string    `json:"user_id" gorm:"size:36;not null;index:idx_notification_user,priority:1"` // The recipient
+	Kind      string    `json:"kind" gorm:"size:16;not null"`                                           // One of the types.Kind* kinds
+	// The user who did it, the last one if added up, empty for anonymous readers
+	Actor     string `json:"actor" gorm:"size:36"`
+	ActorName string `json:"actor_name" gorm:"-"` // Derived from the actor when the notification is returned, not stored
+	// How many times it happened, the reactions to an object are added up until
+	// the notification is read or mailed
+	Count      int    `json:"count" gorm:"not null;default:1"`
+	ObjectType string `json:"object_type" gorm:"size:32;not null"` // What it's about, e.g. article
+	ObjectId   string `json:"object_id" gorm:"size:64;not null"`
+	CommentID  *uint  `json:"comment_id"`
+	Excerpt    string `json:"excerpt"` // The beginning of the comment, empty for reactions
+	// Whether it's in the inbox of the user, see NotificationPreference.InApp
+	Inbox  bool       `json:"-" gorm:"not null;index:idx_notification_user,priority:2"`
+	ReadAt *time.Time `json:"read_at"`
+	// Whether it's to be mailed in a digest, unless read before, see
+	// NotificationPreference.Digest
+	Digest   bool       `json:"-" gorm:"not null"`
+	MailedAt *time.Time `json:"-"`
+}
+
+func (n *Notification) TableName() string {
+	return "notification"
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"bilingo/domains/notification/types"
+)
+
+// NotificationPreference is how a user is notified, users without one are
+// notified in the inbox and by daily digests.
+type NotificationPreference struct {
+	UserID    string    `json:"user_id" gorm:"primaryKey;size:36"`
+	InApp     bool      `json:"in_app"`                         // Whether notifications are shown in the inbox
+	Digest    string    `json:"digest" gorm:"size:16;not null"` // How often unread notifications are mailed, one of the types.Digest* values
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (p *NotificationPreference) TableName() string {
+	return "notification_preference"
+}
+
+// DefaultPreference returns the preference of the users who haven't set one.
+func DefaultPreference(userId string) *NotificationPreference {
+	return &NotificationPreference{UserID: userId, InApp: true, Digest: types.DigestDaily}
+}
@@ -0,0 +1,24 @@
+// Package module assembles the notification domain into an app.
+package module
+
+import (
+	"bilingo/domains/notification/api"
+	"bilingo/domains/notification/repo"
+	"bilingo/domains/notification/service"
+	"bilingo/server/app"
+	"bilingo/server/events"
+	"bilingo/server/jobs"
+	"bilingo/server/realtime"
+)
+
+// Register provides the repositories of the notifications, creates them from
+// the events of the other domains, pushes them to the realtime topics of the
+// users, schedules the digests, and adds the routes of the inbox.
+func Register(a *app.App) error {
+	repo.Provide(a)
+	service.Subscribe(events.ForApp(a))
+	service.AuthorizeRealtime(realtime.ForApp(a))
+	service.RegisterJobs(jobs.ForApp(a), a.Config.Notification)
+	api.Register(a)
+	return nil
+}
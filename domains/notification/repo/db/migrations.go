@@ -0,0 +1,15 @@
+package impl
+
+import (
+	domain "bilingo/domains/notification"
+	"bilingo/domains/notification/models"
+	"bilingo/server/db"
+)
+
+func init() {
+	db.RegisterMigrations(
+		domain.DBBinding,
+		db.Migration{ID: "2026101916_notification_create_notification_table", Up: db.CreateTableIfNotExists(&models.Notification{})},
+		db.Migration{ID: "2026101916_notification_create_notification_preference_table", Up: db.CreateTableIfNotExists(&models.NotificationPreference{})},
+	)
+}
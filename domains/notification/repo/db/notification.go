@@ -0,0 +1,315 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"bilingo/common"
+	domain "bilingo/domains/notification"
+	"bilingo/domains/notification/models"
+	"bilingo/domains/notification/tables"
+	"bilingo/domains/notification/types"
+	"bilingo/server/db"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NotificationRepo struct{}
+
+func (r *NotificationRepo) Get(ctx context.Context, userId string, id uint) (*models.Notification, error) {
+	conn, err := db.Reader(ctx, domain.DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	notification, err := gorm.G[models.Notification](conn).
+		Where(tables.Notification.ID.Eq(id), tables.Notification.UserID.Eq(userId)).
+		First(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrNotificationNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to find notification: %w", err)
+	}
+
+	return &notification, nil
+}
+
+func (r *NotificationRepo) List(
+	ctx context.Context,
+	userId string,
+	query *types.NotificationListQuery,
+) (*common.PaginatedResult[models.Notification], error) {
+	conn, err := db.Reader(ctx, domain.DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	q := gorm.G[models.Notification](conn).
+		Where(tables.Notification.UserID.Eq(userId), tables.Notification.Inbox.Eq(true))
+	if query.Unread != nil && *query.Unread {
+		q = q.Where(tables.Notification.ReadAt.IsNull())
+	}
+
+	// Count total before applying pagination
+	total, err := q.Count(ctx, "*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to count notifications: %w", err)
+	}
+
+	q = q.Order(tables.Notification.UpdatedAt.Desc()).Order(tables.Notification.ID.Desc())
+	q = q.Limit(query.PageSize)
+	q = q.Offset(query.PageSize * (query.Page - 1))
+
+	notifications, err := q.Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification list: %w", err)
+	} else if len(notifications) == 0 {
+		return &common.PaginatedResult[models.Notification]{Total: 0, List: []models.Notification{}}, nil
+	}
+
+	return &common.PaginatedResult[models.Notification]{Total: int(total), List: notifications}, nil
+}
+
+func (r *NotificationRepo) CountUnread(ctx context.Context, userId string) (int, error) {
+	conn, err := db.Reader(ctx, domain.DBBinding)
+	if err != nil {
+		return 0, db.ConnError(err)
+	}
+
+	count, err := gorm.G[models.Notification](conn).
+		Where(
+			tables.Notification.UserID.Eq(userId),
+			tables.Notification.Inbox.Eq(true),
+			tables.Notification.ReadAt.IsNull(),
+		).
+		Count(ctx, "*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+
+	return int(count), nil
+}
+
+func (r *NotificationRepo) Create(ctx context.Context, notification *models.Notification) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	if err := gorm.G[models.Notification](conn).Create(ctx, notification); err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+
+	return nil
+}
+
+func (r *NotificationRepo) ExistsForComment(ctx context.Context, userId string, commentId uint) (bool, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return false, db.ConnError(err)
+	}
+
+	count, err := gorm.G[models.Notification](conn).
+		Where(tables.Notification.UserID.Eq(userId), tables.Notification.CommentID.Eq(commentId)).
+		Count(ctx, "*")
+	if err != nil {
+		return false, fmt.Errorf("failed to find notification of comment: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+func (r *NotificationRepo) AddUp(
+	ctx context.Context,
+	userId string,
+	kind string,
+	objectType string,
+	objectId string,
+	actor string,
+	at time.Time,
+) (*models.Notification, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	pending := clause.And(
+		tables.Notification.UserID.Eq(userId),
+		tables.Notification.Kind.Eq(kind),
+		tables.Notification.ObjectType.Eq(objectType),
+		tables.Notification.ObjectId.Eq(objectId),
+		tables.Notification.ReadAt.IsNull(),
+		tables.Notification.MailedAt.IsNull(),
+	)
+	notifications, err := gorm.G[models.Notification](conn).
+		Where(pending).
+		Order(tables.Notification.ID.Desc()).
+		Limit(1).
+		Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find notification: %w", err)
+	} else if len(notifications) == 0 {
+		return nil, nil
+	}
+
+	// Counted in the database, since reactions come in concurrently
+	id := notifications[0].ID
+	rowsAffected, err := gorm.G[models.Notification](conn).
+		Where(pending, tables.Notification.ID.Eq(id)).
+		Set(
+			tables.Notification.Count.Incr(1),
+			tables.Notification.Actor.Set(actor),
+			tables.Notification.UpdatedAt.Set(at),
+		).
+		Update(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add up notification: %w", err)
+	} else if rowsAffected == 0 {
+		// Read or mailed in the meantime
+		return nil, nil
+	}
+
+	return r.Get(db.UsePrimary(ctx), userId, id)
+}
+
+func (r *NotificationRepo) MarkRead(ctx context.Context, userId string, ids []uint, at time.Time) (int, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return 0, db.ConnError(err)
+	}
+
+	q := gorm.G[models.Notification](conn).
+		Where(
+			tables.Notification.UserID.Eq(userId),
+			tables.Notification.Inbox.Eq(true),
+			tables.Notification.ReadAt.IsNull(),
+		)
+	if len(ids) > 0 {
+		q = q.Where(tables.Notification.ID.In(ids...))
+	}
+	rowsAffected, err := q.Set(tables.Notification.ReadAt.Set(at)).Update(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications as read: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
+// digestPending is the condition of the notifications to be mailed in a
+// digest.
+func digestPending() clause.Expression {
+	return clause.And(
+		tables.Notification.Digest.Eq(true),
+		tables.Notification.MailedAt.IsNull(),
+		tables.Notification.ReadAt.IsNull(),
+	)
+}
+
+func (r *NotificationRepo) ListDigestUsers(ctx context.Context) ([]string, error) {
+	conn, err := db.Reader(ctx, domain.DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	var users []string
+	err = conn.WithContext(ctx).Model(&models.Notification{}).
+		Where(digestPending()).
+		Distinct("user_id").
+		Order("user_id").
+		Pluck("user_id", &users).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get digest users: %w", err)
+	}
+
+	return users, nil
+}
+
+func (r *NotificationRepo) ListDigest(ctx context.Context, userId string, limit int) ([]models.Notification, int, error) {
+	conn, err := db.Reader(ctx, domain.DBBinding)
+	if err != nil {
+		return nil, 0, db.ConnError(err)
+	}
+
+	q := gorm.G[models.Notification](conn).
+		Where(tables.Notification.UserID.Eq(userId), digestPending())
+	total, err := q.Count(ctx, "*")
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count digest notifications: %w", err)
+	}
+
+	notifications, err := q.Order(tables.Notification.ID.Desc()).Limit(limit).Find(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get digest notifications: %w", err)
+	}
+
+	return notifications, int(total), nil
+}
+
+func (r *NotificationRepo) MarkMailed(ctx context.Context, userId string, listedAt time.Time, at time.Time) (int, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return 0, db.ConnError(err)
+	}
+
+	rowsAffected, err := gorm.G[models.Notification](conn).
+		Where(tables.Notification.UserID.Eq(userId), tables.Notification.UpdatedAt.Lte(listedAt), digestPending()).
+		Set(tables.Notification.MailedAt.Set(at)).
+		Update(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications as mailed: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
+func (r *NotificationRepo) ListByUser(ctx context.Context, userId string) ([]models.Notification, error) {
+	conn, err := db.Reader(ctx, domain.DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	notifications, err := gorm.G[models.Notification](conn).
+		Where(tables.Notification.UserID.Eq(userId)).
+		Order(tables.Notification.ID.Asc()).
+		Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notifications of user: %w", err)
+	}
+
+	return notifications, nil
+}
+
+func (r *NotificationRepo) DeleteByUser(ctx context.Context, userId string) (int, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return 0, db.ConnError(err)
+	}
+
+	rowsAffected, err := gorm.G[models.Notification](conn).
+		Where(tables.Notification.UserID.Eq(userId)).
+		Delete(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete notifications of user: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
+func (r *NotificationRepo) DeleteBefore(ctx context.Context, before time.Time) (int, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return 0, db.ConnError(err)
+	}
+
+	rowsAffected, err := gorm.G[models.Notification](conn).
+		Where(tables.Notification.UpdatedAt.Lt(before)).
+		Delete(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old notifications: %w", err)
+	}
+
+	return rowsAffected, nil
+}
@@ -0,0 +1,17 @@
+package impl_test
+
+import (
+	"testing"
+
+	"bilingo/domains/notification/repo"
+	impl "bilingo/domains/notification/repo/db"
+	"bilingo/domains/notification/repo/repotest"
+	"bilingo/server/testutil"
+)
+
+func TestNotificationRepo(t *testing.T) {
+	repotest.TestNotificationRepo(t, func(t *testing.T) repo.INotificationRepo {
+		testutil.NewDB(t)
+		return &impl.NotificationRepo{}
+	})
+}
@@ -0,0 +1,65 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+
+	domain "bilingo/domains/notification"
+	"bilingo/domains/notification/models"
+	"bilingo/domains/notification/tables"
+	"bilingo/server/db"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PreferenceRepo struct{}
+
+func (r *PreferenceRepo) Get(ctx context.Context, userId string) (*models.NotificationPreference, error) {
+	conn, err := db.Reader(ctx, domain.DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	preferences, err := gorm.G[models.NotificationPreference](conn).
+		Where(tables.NotificationPreference.UserID.Eq(userId)).
+		Limit(1).
+		Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find notification preference: %w", err)
+	} else if len(preferences) == 0 {
+		return models.DefaultPreference(userId), nil
+	}
+
+	return &preferences[0], nil
+}
+
+func (r *PreferenceRepo) Save(ctx context.Context, preference *models.NotificationPreference) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	err = gorm.G[models.NotificationPreference](conn, clause.OnConflict{UpdateAll: true}).Create(ctx, preference)
+	if err != nil {
+		return fmt.Errorf("failed to save notification preference: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PreferenceRepo) Delete(ctx context.Context, userId string) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	_, err = gorm.G[models.NotificationPreference](conn).
+		Where(tables.NotificationPreference.UserID.Eq(userId)).
+		Delete(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete notification preference: %w", err)
+	}
+
+	return nil
+}
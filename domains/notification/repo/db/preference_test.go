@@ -0,0 +1,17 @@
+package impl_test
+
+import (
+	"testing"
+
+	"bilingo/domains/notification/repo"
+	impl "bilingo/domains/notification/repo/db"
+	"bilingo/domains/notification/repo/repotest"
+	"bilingo/server/testutil"
+)
+
+func TestPreferenceRepo(t *testing.T) {
+	repotest.TestPreferenceRepo(t, func(t *testing.T) repo.IPreferenceRepo {
+		testutil.NewDB(t)
+		return &impl.PreferenceRepo{}
+	})
+}
@@ -0,0 +1,22 @@
+package repo
+
+import (
+	"bilingo/config"
+	impl "bilingo/domains/notification/repo/db"
+	"bilingo/domains/notification/repo/memory"
+	"bilingo/server/app"
+)
+
+// Provide provides the repositories of the driver in the configuration of the
+// app, which keep their records in memory rather than the databases with the
+// memory driver, see config.RepoMemory.
+func Provide(a *app.App) {
+	if a.Config.Repo == config.RepoMemory {
+		app.Provide[INotificationRepo](a, &memory.NotificationRepo{})
+		app.Provide[IPreferenceRepo](a, &memory.PreferenceRepo{})
+		return
+	}
+
+	app.Provide[INotificationRepo](a, &impl.NotificationRepo{})
+	app.Provide[IPreferenceRepo](a, &impl.PreferenceRepo{})
+}
@@ -0,0 +1,183 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"bilingo/common"
+	domain "bilingo/domains/notification"
+	"bilingo/domains/notification/models"
+	"bilingo/domains/notification/types"
+	"bilingo/server/memdb"
+)
+
+type NotificationRepo struct {
+	notifications memdb.Table[uint, models.Notification]
+}
+
+func (r *NotificationRepo) Get(ctx context.Context, userId string, id uint) (*models.Notification, error) {
+	notification, ok := r.notifications.Get(id)
+	if !ok || notification.UserID != userId {
+		return nil, domain.ErrNotificationNotFound
+	}
+
+	return &notification, nil
+}
+
+func (r *NotificationRepo) List(
+	ctx context.Context,
+	userId string,
+	query *types.NotificationListQuery,
+) (*common.PaginatedResult[models.Notification], error) {
+	unread := query.Unread != nil && *query.Unread
+	notifications := r.notifications.Find(func(notification models.Notification) bool {
+		return notification.UserID == userId && notification.Inbox && (!unread || notification.ReadAt == nil)
+	})
+	slices.SortStableFunc(notifications, func(a, b models.Notification) int {
+		if c := b.UpdatedAt.Compare(a.UpdatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.ID, a.ID)
+	})
+
+	return memdb.Paginate(notifications, query.PaginatedQuery), nil
+}
+
+func (r *NotificationRepo) CountUnread(ctx context.Context, userId string) (int, error) {
+	return r.notifications.Count(func(notification models.Notification) bool {
+		return notification.UserID == userId && notification.Inbox && notification.ReadAt == nil
+	}), nil
+}
+
+func (r *NotificationRepo) Create(ctx context.Context, notification *models.Notification) error {
+	if notification.ID == 0 {
+		notification.ID = r.notifications.NextID()
+	}
+	now := time.Now()
+	if notification.CreatedAt.IsZero() {
+		notification.CreatedAt = now
+	}
+	if notification.UpdatedAt.IsZero() {
+		notification.UpdatedAt = now
+	}
+	if notification.Count == 0 {
+		notification.Count = 1
+	}
+	if !r.notifications.Insert(notification.ID, *notification) {
+		return fmt.Errorf("failed to create notification: notification %d exists", notification.ID)
+	}
+
+	return nil
+}
+
+func (r *NotificationRepo) ExistsForComment(ctx context.Context, userId string, commentId uint) (bool, error) {
+	count := r.notifications.Count(func(notification models.Notification) bool {
+		return notification.UserID == userId && notification.CommentID != nil && *notification.CommentID == commentId
+	})
+	return count > 0, nil
+}
+
+func (r *NotificationRepo) AddUp(
+	ctx context.Context,
+	userId string,
+	kind string,
+	objectType string,
+	objectId string,
+	actor string,
+	at time.Time,
+) (*models.Notification, error) {
+	pending := func(notification models.Notification) bool {
+		return notification.UserID == userId && notification.Kind == kind &&
+			notification.ObjectType == objectType && notification.ObjectId == objectId &&
+			notification.ReadAt == nil && notification.MailedAt == nil
+	}
+	notifications := r.notifications.Find(pending)
+	if len(notifications) == 0 {
+		return nil, nil
+	}
+
+	id := notifications[len(notifications)-1].ID
+	var result *models.Notification
+	r.notifications.Update(id, func(notification *models.Notification) bool {
+		// Read or mailed in the meantime
+		if !pending(*notification) {
+			return false
+		}
+		notification.Count++
+		notification.Actor = actor
+		notification.UpdatedAt = at
+		added := *notification
+		result = &added
+		return true
+	})
+
+	return result, nil
+}
+
+func (r *NotificationRepo) MarkRead(ctx context.Context, userId string, ids []uint, at time.Time) (int, error) {
+	return r.notifications.UpdateWhere(
+		func(notification models.Notification) bool {
+			return notification.UserID == userId && notification.Inbox && notification.ReadAt == nil &&
+				(len(ids) == 0 || slices.Contains(ids, notification.ID))
+		},
+		func(notification *models.Notification) {
+			notification.ReadAt = &at
+		},
+	), nil
+}
+
+// digestPending reports whether the notification is to be mailed in a digest.
+func digestPending(notification models.Notification) bool {
+	return notification.Digest && notification.MailedAt == nil && notification.ReadAt == nil
+}
+
+func (r *NotificationRepo) ListDigestUsers(ctx context.Context) ([]string, error) {
+	users := []string{}
+	for _, notification := range r.notifications.Find(digestPending) {
+		users = append(users, notification.UserID)
+	}
+	slices.Sort(users)
+
+	return slices.Compact(users), nil
+}
+
+func (r *NotificationRepo) ListDigest(ctx context.Context, userId string, limit int) ([]models.Notification, int, error) {
+	notifications := r.notifications.Find(func(notification models.Notification) bool {
+		return notification.UserID == userId && digestPending(notification)
+	})
+	slices.SortFunc(notifications, func(a, b models.Notification) int { return cmp.Compare(b.ID, a.ID) })
+
+	return notifications[:min(len(notifications), limit)], len(notifications), nil
+}
+
+func (r *NotificationRepo) MarkMailed(ctx context.Context, userId string, listedAt time.Time, at time.Time) (int, error) {
+	return r.notifications.UpdateWhere(
+		func(notification models.Notification) bool {
+			return notification.UserID == userId && !notification.UpdatedAt.After(listedAt) && digestPending(notification)
+		},
+		func(notification *models.Notification) {
+			notification.MailedAt = &at
+		},
+	), nil
+}
+
+func (r *NotificationRepo) ListByUser(ctx context.Context, userId string) ([]models.Notification, error) {
+	return r.notifications.Find(func(notification models.Notification) bool {
+		return notification.UserID == userId
+	}), nil
+}
+
+func (r *NotificationRepo) DeleteByUser(ctx context.Context, userId string) (int, error) {
+	return r.notifications.DeleteWhere(func(notification models.Notification) bool {
+		return notification.UserID == userId
+	}), nil
+}
+
+func (r *NotificationRepo) DeleteBefore(ctx context.Context, before time.Time) (int, error) {
+	return r.notifications.DeleteWhere(func(notification models.Notification) bool {
+		return notification.UpdatedAt.Before(before)
+	}), nil
+}
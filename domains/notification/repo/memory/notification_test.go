@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"bilingo/domains/notification/repo"
+	"bilingo/domains/notification/repo/memory"
+	"bilingo/domains/notification/repo/repotest"
+)
+
+func TestNotificationRepo(t *testing.T) {
+	repotest.TestNotificationRepo(t, func(t *testing.T) repo.INotificationRepo {
+		return &memory.NotificationRepo{}
+	})
+}
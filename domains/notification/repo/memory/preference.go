@@ -0,0 +1,37 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"bilingo/domains/notification/models"
+	"bilingo/server/memdb"
+)
+
+type PreferenceRepo struct {
+	preferences memdb.Table[string, models.NotificationPreference]
+}
+
+func (r *PreferenceRepo) Get(ctx context.Context, userId string) (*models.NotificationPreference, error) {
+	preference, ok := r.preferences.Get(userId)
+	if !ok {
+		return models.DefaultPreference(userId), nil
+	}
+
+	return &preference, nil
+}
+
+func (r *PreferenceRepo) Save(ctx context.Context, preference *models.NotificationPreference) error {
+	if preference.UpdatedAt.IsZero() {
+		preference.UpdatedAt = time.Now()
+	}
+	r.preferences.Put(preference.UserID, *preference)
+
+	return nil
+}
+
+func (r *PreferenceRepo) Delete(ctx context.Context, userId string) error {
+	r.preferences.Delete(userId)
+
+	return nil
+}
@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"bilingo/domains/notification/repo"
+	"bilingo/domains/notification/repo/memory"
+	"bilingo/domains/notification/repo/repotest"
+)
+
+func TestPreferenceRepo(t *testing.T) {
+	repotest.TestPreferenceRepo(t, func(t *testing.T) repo.IPreferenceRepo {
+		return &memory.PreferenceRepo{}
+	})
+}
@@ -0,0 +1,60 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"bilingo/common"
+	"bilingo/domains/notification/models"
+	impl "bilingo/domains/notification/repo/db"
+	"bilingo/domains/notification/types"
+	"bilingo/server/app"
+)
+
+// Notifications returns the notification repository of the app the context
+// carries, or the database implementation outside of apps.
+func Notifications(ctx context.Context) INotificationRepo {
+	return app.Resolve[INotificationRepo](ctx, &impl.NotificationRepo{})
+}
+
+type INotificationRepo interface {
+	// Get returns the notification of the user.
+	Get(ctx context.Context, userId string, id uint) (*models.Notification, error)
+	// List returns the notifications in the inbox of the user, the latest
+	// updated first.
+	List(ctx context.Context, userId string, query *types.NotificationListQuery) (*common.PaginatedResult[models.Notification], error)
+	// CountUnread returns the number of unread notifications in the inbox of
+	// the user.
+	CountUnread(ctx context.Context, userId string) (int, error)
+	Create(ctx context.Context, notification *models.Notification) error
+	// ExistsForComment reports whether the user has a notification about the
+	// comment.
+	ExistsForComment(ctx context.Context, userId string, commentId uint) (bool, error)
+	// AddUp counts another occurrence of the notification of the kind about the
+	// object by the actor, if the user has one that's neither read nor mailed
+	// yet, and returns it, or nil if there's none.
+	AddUp(ctx context.Context, userId string, kind string, objectType string, objectId string, actor string, at time.Time) (*models.Notification, error)
+	// MarkRead marks the notifications in the inbox of the user as read, all
+	// unread ones if ids is empty, and returns the number of them.
+	MarkRead(ctx context.Context, userId string, ids []uint, at time.Time) (int, error)
+	// ListDigestUsers returns the users with notifications to be mailed in a
+	// digest.
+	ListDigestUsers(ctx context.Context) ([]string, error)
+	// ListDigest returns up to limit notifications of the user to be mailed in
+	// a digest, the latest first, and the number of them in total.
+	ListDigest(ctx context.Context, userId string, limit int) ([]models.Notification, int, error)
+	// MarkMailed marks the notifications of the user to be mailed in a digest
+	// as mailed, along with the ones left out of the digest, except the ones
+	// created or added up after the digest was listed, and returns the number
+	// of them.
+	MarkMailed(ctx context.Context, userId string, listedAt time.Time, at time.Time) (int, error)
+	// ListByUser returns all notifications of the user in the order of
+	// creation.
+	ListByUser(ctx context.Context, userId string) ([]models.Notification, error)
+	// DeleteByUser deletes all notifications of the user, and returns the
+	// number of them.
+	DeleteByUser(ctx context.Context, userId string) (int, error)
+	// DeleteBefore deletes the notifications last updated before the time, and
+	// returns the number of them.
+	DeleteBefore(ctx context.Context, before time.Time) (int, error)
+}
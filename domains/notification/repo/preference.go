@@ -0,0 +1,24 @@
+package repo
+
+import (
+	"context"
+
+	"bilingo/domains/notification/models"
+	impl "bilingo/domains/notification/repo/db"
+	"bilingo/server/app"
+)
+
+// Preferences returns the notification preference repository of the app the
+// context carries, or the database implementation outside of apps.
+func Preferences(ctx context.Context) IPreferenceRepo {
+	return app.Resolve[IPreferenceRepo](ctx, &impl.PreferenceRepo{})
+}
+
+type IPreferenceRepo interface {
+	// Get returns the preference of the user, which is the default one if the
+	// user hasn't set it, see models.DefaultPreference.
+	Get(ctx context.Context, userId string) (*models.NotificationPreference, error)
+	// Save creates or replaces the preference of the user.
+	Save(ctx context.Context, preference *models.NotificationPreference) error
+	Delete(ctx context.Context, userId string) error
+}
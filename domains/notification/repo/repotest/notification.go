@@ -0,0 +1,240 @@
+// Package repotest is the contract the implementations of the notification
+// and preference repositories must fulfil, which is run against each of them by
+// their tests.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"bilingo/common"
+	domain "bilingo/domains/notification"
+	"bilingo/domains/notification/models"
+	"bilingo/domains/notification/repo"
+	"bilingo/domains/notification/types"
+)
+
+// TestNotificationRepo tests the notification repository against the contract.
+func TestNotificationRepo(t *testing.T, newRepo func(t *testing.T) repo.INotificationRepo) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	create := func(t *testing.T, r repo.INotificationRepo, userId string, kind string, objectId string, at time.Time) *models.Notification {
+		t.Helper()
+		notification := &models.Notification{
+			CreatedAt:  at,
+			UpdatedAt:  at,
+			UserID:     userId,
+			Kind:       kind,
+			Actor:      "actor",
+			Count:      1,
+			ObjectType: "article",
+			ObjectId:   objectId,
+			Inbox:      true,
+			Digest:     true,
+		}
+		if err := r.Create(ctx, notification); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		return notification
+	}
+
+	objects := func(notifications []models.Notification) []string {
+		var result []string
+		for _, notification := range notifications {
+			result = append(result, notification.ObjectId)
+		}
+		return result
+	}
+
+	t.Run("CreateAndGet", func(t *testing.T) {
+		r := newRepo(t)
+		created := create(t, r, "u1", types.KindComment, "1", now)
+		if created.ID == 0 {
+			t.Fatalf("Create: got no ID")
+		}
+
+		commentId := uint(7)
+		if err := r.Create(ctx, &models.Notification{UserID: "u1", Kind: types.KindReply, ObjectType: "article", ObjectId: "1", CommentID: &commentId}); err != nil {
+			t.Fatalf("Create of a comment: %v", err)
+		}
+		if exists, err := r.ExistsForComment(ctx, "u1", commentId); err != nil || !exists {
+			t.Errorf("ExistsForComment: got %v, %v, want true", exists, err)
+		}
+		if exists, _ := r.ExistsForComment(ctx, "u2", commentId); exists {
+			t.Errorf("ExistsForComment of another user: got true")
+		}
+
+		notification, err := r.Get(ctx, "u1", created.ID)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if notification.Kind != types.KindComment || notification.ObjectId != "1" || notification.Count != 1 || !notification.Inbox {
+			t.Errorf("Get: got %+v", notification)
+		}
+
+		if _, err := r.Get(ctx, "u2", created.ID); !errors.Is(err, domain.ErrNotificationNotFound) {
+			t.Errorf("Get of a notification of another user: got %v, want ErrNotificationNotFound", err)
+		}
+		if _, err := r.Get(ctx, "u1", created.ID+100); !errors.Is(err, domain.ErrNotificationNotFound) {
+			t.Errorf("Get of a missing notification: got %v, want ErrNotificationNotFound", err)
+		}
+	})
+
+	t.Run("ListAndMarkRead", func(t *testing.T) {
+		r := newRepo(t)
+		a := create(t, r, "u1", types.KindComment, "a", now.Add(-2*time.Minute))
+		create(t, r, "u1", types.KindComment, "b", now)
+		create(t, r, "u1", types.KindComment, "c", now.Add(-time.Minute))
+		create(t, r, "u2", types.KindComment, "d", now)
+		create(t, r, "u1", types.KindComment, "e", now)
+		if err := r.Create(ctx, &models.Notification{UserID: "u1", Kind: types.KindComment, ObjectType: "article", ObjectId: "f"}); err != nil {
+			t.Fatalf("Create out of the inbox: %v", err)
+		}
+
+		query := &types.NotificationListQuery{PaginatedQuery: common.PaginatedQuery{Page: 1, PageSize: 10}}
+		result, err := r.List(ctx, "u1", query)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if result.Total != 4 {
+			t.Errorf("List: got a total of %d, want 4", result.Total)
+		}
+		assertOrder(t, "List", objects(result.List), "e", "b", "c", "a")
+
+		if n, err := r.CountUnread(ctx, "u1"); err != nil || n != 4 {
+			t.Errorf("CountUnread: got %d, %v, want 4", n, err)
+		}
+
+		if n, err := r.MarkRead(ctx, "u1", []uint{a.ID}, now); err != nil || n != 1 {
+			t.Fatalf("MarkRead: got %d, %v, want 1", n, err)
+		}
+		if n, _ := r.MarkRead(ctx, "u1", []uint{a.ID}, now); n != 0 {
+			t.Errorf("MarkRead of a read notification: got %d, want 0", n)
+		}
+		unread := true
+		query.Unread = &unread
+		result, _ = r.List(ctx, "u1", query)
+		assertOrder(t, "List unread", objects(result.List), "e", "b", "c")
+
+		if n, err := r.MarkRead(ctx, "u1", nil, now); err != nil || n != 3 {
+			t.Fatalf("MarkRead of all: got %d, %v, want 3", n, err)
+		}
+		if n, _ := r.CountUnread(ctx, "u1"); n != 0 {
+			t.Errorf("CountUnread after MarkRead: got %d, want 0", n)
+		}
+		if n, _ := r.CountUnread(ctx, "u2"); n != 1 {
+			t.Errorf("CountUnread of another user: got %d, want 1", n)
+		}
+	})
+
+	t.Run("AddUp", func(t *testing.T) {
+		r := newRepo(t)
+		if added, err := r.AddUp(ctx, "u1", types.KindReaction, "article", "1", "a1", now); err != nil || added != nil {
+			t.Fatalf("AddUp without a notification: got %+v, %v, want nil", added, err)
+		}
+
+		created := create(t, r, "u1", types.KindReaction, "1", now.Add(-time.Minute))
+		added, err := r.AddUp(ctx, "u1", types.KindReaction, "article", "1", "a2", now)
+		if err != nil || added == nil || added.ID != created.ID || added.Count != 2 || added.Actor != "a2" || !added.UpdatedAt.Equal(now) {
+			t.Fatalf("AddUp: got %+v, %v", added, err)
+		}
+		if added, _ := r.AddUp(ctx, "u1", types.KindReaction, "article", "2", "a2", now); added != nil {
+			t.Errorf("AddUp of another object: got %+v, want nil", added)
+		}
+		if added, _ := r.AddUp(ctx, "u1", types.KindComment, "article", "1", "a2", now); added != nil {
+			t.Errorf("AddUp of another kind: got %+v, want nil", added)
+		}
+
+		if _, err := r.MarkRead(ctx, "u1", nil, now); err != nil {
+			t.Fatalf("MarkRead: %v", err)
+		}
+		if added, _ := r.AddUp(ctx, "u1", types.KindReaction, "article", "1", "a3", now); added != nil {
+			t.Errorf("AddUp of a read notification: got %+v, want nil", added)
+		}
+	})
+
+	t.Run("Digest", func(t *testing.T) {
+		r := newRepo(t)
+		a := create(t, r, "u1", types.KindComment, "a", now)
+		create(t, r, "u2", types.KindComment, "b", now)
+		create(t, r, "u1", types.KindComment, "c", now)
+		read := create(t, r, "u3", types.KindComment, "d", now)
+		if _, err := r.MarkRead(ctx, "u3", []uint{read.ID}, now); err != nil {
+			t.Fatalf("MarkRead: %v", err)
+		}
+		if err := r.Create(ctx, &models.Notification{UserID: "u4", Kind: types.KindComment, ObjectType: "article", ObjectId: "e", Inbox: true}); err != nil {
+			t.Fatalf("Create out of digests: %v", err)
+		}
+
+		users, err := r.ListDigestUsers(ctx)
+		if err != nil {
+			t.Fatalf("ListDigestUsers: %v", err)
+		}
+		assertOrder(t, "ListDigestUsers", users, "u1", "u2")
+
+		notifications, total, err := r.ListDigest(ctx, "u1", 1)
+		if err != nil || total != 2 {
+			t.Fatalf("ListDigest: got a total of %d, %v, want 2", total, err)
+		}
+		assertOrder(t, "ListDigest", objects(notifications), "c")
+
+		// The notifications created or added up after the digest was listed
+		// aren't mailed
+		later := now.Add(time.Second)
+		create(t, r, "u1", types.KindComment, "f", later)
+		if added, err := r.AddUp(ctx, "u1", types.KindComment, "article", "c", "a2", later); err != nil || added == nil {
+			t.Fatalf("AddUp: got %+v, %v", added, err)
+		}
+		if n, err := r.MarkMailed(ctx, "u1", now, now); err != nil || n != 1 {
+			t.Fatalf("MarkMailed: got %d, %v, want 1", n, err)
+		}
+		notifications, total, _ = r.ListDigest(ctx, "u1", 10)
+		if total != 2 {
+			t.Errorf("ListDigest after MarkMailed: got a total of %d, want 2", total)
+		}
+		assertOrder(t, "ListDigest after MarkMailed", objects(notifications), "f", "c")
+
+		mailed, _ := r.Get(ctx, "u1", a.ID)
+		if mailed.MailedAt == nil {
+			t.Errorf("Get after MarkMailed: got no mail time")
+		}
+		if added, _ := r.AddUp(ctx, "u1", types.KindComment, "article", "a", "a2", now); added != nil && added.ID == a.ID {
+			t.Errorf("AddUp of a mailed notification: got %+v", added)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		r := newRepo(t)
+		create(t, r, "u1", types.KindComment, "a", now.Add(-time.Hour))
+		create(t, r, "u1", types.KindComment, "b", now)
+		create(t, r, "u2", types.KindComment, "c", now.Add(-time.Hour))
+		d := create(t, r, "u2", types.KindComment, "d", now)
+
+		notifications, err := r.ListByUser(ctx, "u1")
+		if err != nil {
+			t.Fatalf("ListByUser: %v", err)
+		}
+		assertOrder(t, "ListByUser", objects(notifications), "a", "b")
+
+		if n, err := r.DeleteByUser(ctx, "u1"); err != nil || n != 2 {
+			t.Fatalf("DeleteByUser: got %d, %v, want 2", n, err)
+		}
+		if n, err := r.DeleteBefore(ctx, now.Add(-time.Minute)); err != nil || n != 1 {
+			t.Fatalf("DeleteBefore: got %d, %v, want 1", n, err)
+		}
+		if _, err := r.Get(ctx, "u2", d.ID); err != nil {
+			t.Errorf("Get of a recent notification: %v", err)
+		}
+	})
+}
+
+func assertOrder(t *testing.T, name string, got []string, want ...string) {
+	t.Helper()
+	if !slices.Equal(got, want) {
+		t.Errorf("%s: got %v, want %v", name, got, want)
+	}
+}
@@ -0,0 +1,61 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bilingo/domains/notification/models"
+	"bilingo/domains/notification/repo"
+	"bilingo/domains/notification/types"
+)
+
+// TestPreferenceRepo tests the notification preference repository against the
+// contract.
+func TestPreferenceRepo(t *testing.T, newRepo func(t *testing.T) repo.IPreferenceRepo) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	t.Run("Save", func(t *testing.T) {
+		r := newRepo(t)
+		preference, err := r.Get(ctx, "u1")
+		if err != nil || preference.UserID != "u1" || !preference.InApp || preference.Digest != types.DigestDaily {
+			t.Fatalf("Get of a new preference: got %+v, %v, want the default", preference, err)
+		}
+
+		preference.InApp = false
+		preference.Digest = types.DigestNever
+		preference.UpdatedAt = now
+		if err := r.Save(ctx, preference); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		saved, err := r.Get(ctx, "u1")
+		if err != nil || saved.InApp || saved.Digest != types.DigestNever || !saved.UpdatedAt.Equal(now) {
+			t.Fatalf("Get after Save: got %+v, %v", saved, err)
+		}
+
+		saved.Digest = types.DigestHourly
+		if err := r.Save(ctx, saved); err != nil {
+			t.Fatalf("Save again: %v", err)
+		}
+		if saved, _ := r.Get(ctx, "u1"); saved.Digest != types.DigestHourly || saved.InApp {
+			t.Errorf("Get after saving again: got %+v", saved)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		r := newRepo(t)
+		if err := r.Save(ctx, &models.NotificationPreference{UserID: "u1", Digest: types.DigestNever}); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		if err := r.Delete(ctx, "u1"); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if preference, _ := r.Get(ctx, "u1"); !preference.InApp || preference.Digest != types.DigestDaily {
+			t.Errorf("Get after Delete: got %+v, want the default", preference)
+		}
+		if err := r.Delete(ctx, "u2"); err != nil {
+			t.Errorf("Delete of a missing preference: %v", err)
+		}
+	})
+}
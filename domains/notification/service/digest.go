@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"bilingo/config"
+	"bilingo/domains/notification/models"
+	"bilingo/domains/notification/repo"
+	"bilingo/domains/notification/types"
+	userDomain "bilingo/domains/user"
+	userRepo "bilingo/domains/user/repo"
+	"bilingo/server/app"
+	"bilingo/server/jobs"
+	"bilingo/server/mailer"
+)
+
+//go:embed mails/*.tmpl
+var mailFiles embed.FS
+
+var mails = mailer.MustParseTemplates(mailFiles, "mails/*.tmpl")
+
+type digestData struct {
+	AppName   string
+	Name      string
+	Total     int
+	Items     []digestItem
+	More      int // The notifications left out of the items
+	InboxLink string
+}
+
+type digestItem struct {
+	Summary string
+	Excerpt string
+	Link    string
+}
+
+// sendDigests enqueues the digests of the users who get them as often as the
+// job runs.
+type sendDigests struct {
+	Digest string `json:"digest"` // One of the types.Digest* values
+}
+
+func (sendDigests) JobName() string {
+	return "notification.send-digests"
+}
+
+// sendDigest mails the digest of the notifications of the user.
+type sendDigest struct {
+	UserID string `json:"user_id"`
+}
+
+func (sendDigest) JobName() string {
+	return "notification.send-digest"
+}
+
+// pruneNotifications deletes the notifications after the retention period.
+type pruneNotifications struct{}
+
+func (pruneNotifications) JobName() string {
+	return "notification.prune"
+}
+
+// RegisterJobs registers the handlers of the jobs of the notifications, and
+// schedules the hourly and daily digests, and the pruning of the notifications
+// after Notification.Retention unless it's negative.
+func RegisterJobs(q *jobs.Queue, cfg config.NotificationConfig) {
+	jobs.Handle(q, enqueueDigests)
+	jobs.Handle(q, func(ctx context.Context, job sendDigest) error {
+		return SendDigest(ctx, job.UserID)
+	})
+	jobs.Schedule(q, "notification.send-hourly-digests", "@hourly", sendDigests{Digest: types.DigestHourly})
+	jobs.Schedule(q, "notification.send-daily-digests", cfg.DigestSchedule, sendDigests{Digest: types.DigestDaily})
+
+	if cfg.Retention > 0 {
+		jobs.Handle(q, func(ctx context.Context, job pruneNotifications) error {
+			_, err := repo.Notifications(ctx).DeleteBefore(ctx, time.Now().Add(-cfg.Retention))
+			return err
+		})
+		jobs.Schedule(q, "notification.prune", "@daily", pruneNotifications{})
+	}
+}
+
+// enqueueDigests enqueues a job for the digest of each user who gets them as
+// often as the job runs, so that a failing mail is retried on its own. The
+// digest of a user is only enqueued once an hour, should the job run twice.
+func enqueueDigests(ctx context.Context, job sendDigests) error {
+	userIds, err := repo.Notifications(ctx).ListDigestUsers(ctx)
+	if err != nil {
+		return err
+	}
+
+	hour := time.Now().UTC().Format("2006010215")
+	for _, userId := range userIds {
+		preference, err := repo.Preferences(ctx).Get(ctx, userId)
+		if err != nil {
+			return err
+		} else if preference.Digest != job.Digest {
+			continue
+		}
+
+		_, err = jobs.EnqueueWith(ctx, sendDigest{UserID: userId}, jobs.EnqueueOptions{
+			UniqueKey: "notification.digest:" + userId + ":" + hour,
+		})
+		if err != nil && !errors.Is(err, jobs.ErrDuplicateJob) {
+			return err
+		}
+	}
+	return nil
+}
+
+// SendDigest mails the user a digest of the notifications neither read nor
+// mailed yet, listing the latest Notification.DigestLimit of them, and marks
+// them as mailed. Nothing is mailed if there are none. The digest may be mailed
+// again if marking them fails, rather than not at all.
+func SendDigest(ctx context.Context, userId string) error {
+	cfg := app.Config(ctx)
+	listedAt := time.Now()
+	notifications, total, err := repo.Notifications(ctx).ListDigest(ctx, userId, cfg.Notification.DigestLimit)
+	if err != nil {
+		return err
+	} else if len(notifications) == 0 {
+		return nil
+	}
+
+	user, err := userRepo.Users(ctx).Get(ctx, userId)
+	if errors.Is(err, userDomain.ErrUserNotFound) {
+		// The notifications are deleted along with the user
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err := fillActorNames(ctx, notifications); err != nil {
+		return err
+	}
+
+	data := digestData{
+		AppName:   cfg.AppName,
+		Name:      user.Name,
+		Total:     total,
+		More:      total - len(notifications),
+		InboxLink: appLink(ctx, "/notifications"),
+	}
+	for _, notification := range notifications {
+		data.Items = append(data.Items, digestItem{
+			Summary: summarize(notification),
+			Excerpt: notification.Excerpt,
+			Link:    appLink(ctx, fmt.Sprintf("/%ss/%s", notification.ObjectType, notification.ObjectId)),
+		})
+	}
+
+	msg, err := mails.Render("digest", user.Email, data)
+	if err != nil {
+		return err
+	}
+	if err := mailer.Send(ctx, msg); err != nil {
+		return err
+	}
+
+	// The notifications created or added up since they were listed are left
+	// for the next digest
+	_, err = repo.Notifications(ctx).MarkMailed(ctx, userId, listedAt, time.Now())
+	return err
+}
+
+// summarize describes the notification in a sentence, e.g. `Jane replied to
+// your comment`.
+func summarize(notification models.Notification) string {
+	actor := notification.ActorName
+	if actor == "" {
+		actor = "Someone"
+	}
+
+	switch notification.Kind {
+	case types.KindReply:
+		return actor + " replied to your comment"
+	case types.KindMention:
+		return actor + " mentioned you in a comment"
+	case types.KindReaction:
+		if notification.Count == 2 {
+			return fmt.Sprintf("%s and 1 other liked your %s", actor, notification.ObjectType)
+		} else if notification.Count > 2 {
+			return fmt.Sprintf("%s and %d others liked your %s", actor, notification.Count-1, notification.ObjectType)
+		}
+		return fmt.Sprintf("%s liked your %s", actor, notification.ObjectType)
+	default:
+		return fmt.Sprintf("%s commented on your %s", actor, notification.ObjectType)
+	}
+}
+
+func appLink(ctx context.Context, path string) string {
+	return strings.TrimSuffix(app.Config(ctx).AppUrl, "/") + path
+}
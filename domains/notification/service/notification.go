@@ -0,0 +1,157 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"bilingo/common"
+	domain "bilingo/domains/notification"
+	"bilingo/domains/notification/models"
+	"bilingo/domains/notification/repo"
+	"bilingo/domains/notification/types"
+	systemService "bilingo/domains/system/service"
+	userDomain "bilingo/domains/user"
+	userModels "bilingo/domains/user/models"
+	userRepo "bilingo/domains/user/repo"
+)
+
+func init() {
+	// The notifications of deleted users are deleted on UserDeleted whatever
+	// the policy, since they're about the content of others
+	systemService.RegisterUserData("notification", systemService.UserDataHandler{Export: exportUserNotifications})
+}
+
+func exportUserNotifications(ctx context.Context, userId string, zw *zip.Writer) error {
+	notifications, err := repo.Notifications(ctx).ListByUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+	preference, err := repo.Preferences(ctx).Get(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	w, err := zw.Create("notifications.jsonl")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	for i := range notifications {
+		if err := enc.Encode(&notifications[i]); err != nil {
+			return err
+		}
+	}
+
+	w, err = zw.Create("notification_preference.json")
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(preference)
+}
+
+// ListNotifications returns the notifications in the inbox of the user, the
+// latest updated first.
+func ListNotifications(ctx context.Context, userId string, query types.NotificationListQuery) (*common.PaginatedResult[models.Notification], error) {
+	result, err := repo.Notifications(ctx).List(ctx, userId, &query)
+	if err != nil {
+		return nil, err
+	}
+	if err := fillActorNames(ctx, result.List); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// fillActorNames sets the names of the actors of the notifications, the users
+// deleted since are shown as userModels.DeletedUserName.
+func fillActorNames(ctx context.Context, notifications []models.Notification) error {
+	names := map[string]string{}
+	for i := range notifications {
+		actor := notifications[i].Actor
+		if actor == "" {
+			continue
+		}
+		name, ok := names[actor]
+		if !ok {
+			user, err := userRepo.Users(ctx).Get(ctx, actor)
+			if errors.Is(err, userDomain.ErrUserNotFound) {
+				name = userModels.DeletedUserName
+			} else if err != nil {
+				return err
+			} else {
+				name = user.Name
+			}
+			names[actor] = name
+		}
+		notifications[i].ActorName = name
+	}
+	return nil
+}
+
+// CountUnread returns the number of unread notifications in the inbox of the
+// user.
+func CountUnread(ctx context.Context, userId string) (int, error) {
+	return repo.Notifications(ctx).CountUnread(ctx, userId)
+}
+
+// MarkRead marks the notification of the user as read, which is a no-op if
+// it's read already.
+func MarkRead(ctx context.Context, userId string, id uint) (*models.Notification, error) {
+	notification, err := repo.Notifications(ctx).Get(ctx, userId, id)
+	if err != nil {
+		return nil, err
+	} else if !notification.Inbox {
+		return nil, domain.ErrNotificationNotFound
+	}
+	if notification.ReadAt != nil {
+		return notification, nil
+	}
+
+	now := time.Now()
+	if _, err := repo.Notifications(ctx).MarkRead(ctx, userId, []uint{id}, now); err != nil {
+		return nil, err
+	}
+	notification.ReadAt = &now
+	return notification, nil
+}
+
+// MarkAllRead marks all notifications in the inbox of the user as read, and
+// returns the number of them.
+func MarkAllRead(ctx context.Context, userId string) (int, error) {
+	return repo.Notifications(ctx).MarkRead(ctx, userId, nil, time.Now())
+}
+
+func GetPreference(ctx context.Context, userId string) (*models.NotificationPreference, error) {
+	return repo.Preferences(ctx).Get(ctx, userId)
+}
+
+// UpdatePreference changes the given fields of the preference of the user,
+// which apply to the notifications created from then on.
+func UpdatePreference(ctx context.Context, userId string, data *types.NotificationPreferenceUpdate) (*models.NotificationPreference, error) {
+	preference, err := repo.Preferences(ctx).Get(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.InApp != nil {
+		preference.InApp = *data.InApp
+	}
+	if data.Digest != nil {
+		switch *data.Digest {
+		case types.DigestNever, types.DigestHourly, types.DigestDaily:
+			preference.Digest = *data.Digest
+		default:
+			return nil, fmt.Errorf("%w: unknown digest %s", domain.ErrInvalidPreference, *data.Digest)
+		}
+	}
+
+	preference.UpdatedAt = time.Now()
+	if err := repo.Preferences(ctx).Save(ctx, preference); err != nil {
+		return nil, err
+	}
+	return preference, nil
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	articleDomain "bilingo/domains/article"
+	domain "bilingo/domains/notification"
+	"bilingo/domains/notification/models"
+	"bilingo/domains/notification/repo"
+	"bilingo/domains/notification/types"
+	systemDomain "bilingo/domains/system"
+	systemService "bilingo/domains/system/service"
+	userDomain "bilingo/domains/user"
+	userModels "bilingo/domains/user/models"
+	userRepo "bilingo/domains/user/repo"
+	"bilingo/server/db"
+	"bilingo/server/events"
+	"bilingo/server/realtime"
+)
+
+// The realtime events of the topics of the notifications, see NotificationsTopic
+const (
+	EventCreated = "notification.created" // The data is the notification
+	EventUpdated = "notification.updated" // The data is the notification added up to
+)
+
+// The most users a comment notifies by mentions, the others are ignored
+const maxMentions = 10
+
+// How long the excerpts of the comments in the notifications are, in runes
+const excerptLength = 140
+
+// mentionPattern matches the mentions of users in comments, which are `@`
+// followed by their emails, e.g. `@jane@example.com`.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([\w.+-]+@[\w-]+(?:\.[\w-]+)+)`)
+
+// Subscribe creates the notifications of the events of the bus in the
+// background, and deletes those of deleted users.
+func Subscribe(bus *events.Bus) {
+	events.Subscribe(bus, "notification.notify-comment", notifyComment)
+	events.Subscribe(bus, "notification.notify-reaction", notifyReaction)
+	events.Subscribe(bus, "notification.delete-user", func(ctx context.Context, event userDomain.UserDeleted) error {
+		return db.Transaction(ctx, domain.DBBinding, func(ctx context.Context) error {
+			if _, err := repo.Notifications(ctx).DeleteByUser(ctx, event.ID); err != nil {
+				return err
+			}
+			return repo.Preferences(ctx).Delete(ctx, event.ID)
+		})
+	})
+}
+
+// notifyComment notifies the author of the comment replied to, the owner of
+// the object commented on, and the users mentioned, but each of them once, of
+// the first of these kinds that applies.
+func notifyComment(ctx context.Context, event systemDomain.CommentCreated) error {
+	type recipient struct {
+		userId string
+		kind   string
+	}
+	var recipients []recipient
+	add := func(userId string, kind string) {
+		for _, r := range recipients {
+			if r.userId == userId {
+				return
+			}
+		}
+		recipients = append(recipients, recipient{userId, kind})
+	}
+
+	if event.ParentId != nil {
+		author, err := systemService.GetObjectOwner(ctx, "comment", strconv.FormatUint(uint64(*event.ParentId), 10))
+		if err == nil {
+			add(author, types.KindReply)
+		} else if !isNotFound(err) {
+			return err
+		}
+	}
+
+	owner, err := systemService.GetObjectOwner(ctx, event.ObjectType, event.ObjectId)
+	if err == nil {
+		add(owner, types.KindComment)
+	} else if !errors.Is(err, systemDomain.ErrUnknownObjectType) && !isNotFound(err) {
+		return err
+	}
+
+	for _, email := range mentions(event.Content) {
+		user, err := userRepo.Users(ctx).GetByEmail(ctx, email)
+		if errors.Is(err, userDomain.ErrUserNotFound) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		add(user.ID, types.KindMention)
+	}
+
+	excerpt := excerptOf(event.Content)
+	return db.Transaction(ctx, domain.DBBinding, func(ctx context.Context) error {
+		for _, r := range recipients {
+			// The handler runs again if it fails, the users notified before
+			// aren't notified twice
+			exists, err := repo.Notifications(ctx).ExistsForComment(ctx, r.userId, event.ID)
+			if err != nil {
+				return err
+			} else if exists {
+				continue
+			}
+
+			commentId := event.ID
+			err = notify(ctx, &models.Notification{
+				UserID:     r.userId,
+				Kind:       r.kind,
+				Actor:      event.Author,
+				ObjectType: event.ObjectType,
+				ObjectId:   event.ObjectId,
+				CommentID:  &commentId,
+				Excerpt:    excerpt,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// isNotFound reports whether the error is of an object that was deleted since
+// it was commented on.
+func isNotFound(err error) bool {
+	return errors.Is(err, articleDomain.ErrArticleNotFound) || errors.Is(err, systemDomain.ErrCommentNotFound) ||
+		errors.Is(err, systemDomain.ErrAttachmentNotFound) || errors.Is(err, userDomain.ErrUserNotFound)
+}
+
+// notifyReaction notifies the author of the article of likes, which are added
+// up until the notification is read or mailed, so that popular articles don't
+// flood the inbox. Dislikes and reactions taken back aren't notified.
+func notifyReaction(ctx context.Context, event articleDomain.ArticleReacted) error {
+	if event.Action != "like" || event.User == event.Author {
+		return nil
+	}
+
+	objectId := strconv.FormatUint(uint64(event.ID), 10)
+	return db.Transaction(ctx, domain.DBBinding, func(ctx context.Context) error {
+		now := time.Now()
+		added, err := repo.Notifications(ctx).AddUp(ctx, event.Author, types.KindReaction, "article", objectId, event.User, now)
+		if err != nil {
+			return err
+		} else if added != nil {
+			return publish(ctx, added, EventUpdated)
+		}
+
+		return notify(ctx, &models.Notification{
+			CreatedAt:  now,
+			UpdatedAt:  now,
+			UserID:     event.Author,
+			Kind:       types.KindReaction,
+			Actor:      event.User,
+			ObjectType: "article",
+			ObjectId:   objectId,
+		})
+	})
+}
+
+// notify creates the notification in the inbox of the user, in the digests, or
+// both, according to the preference of the user. Users aren't notified of what
+// they did themselves.
+func notify(ctx context.Context, notification *models.Notification) error {
+	userId := notification.UserID
+	if userId == "" || userId == notification.Actor || userId == userModels.DeletedUserID {
+		return nil
+	}
+
+	preference, err := repo.Preferences(ctx).Get(ctx, userId)
+	if err != nil {
+		return err
+	}
+	notification.Inbox = preference.InApp
+	notification.Digest = preference.Digest != types.DigestNever
+	if !notification.Inbox && !notification.Digest {
+		return nil
+	}
+
+	notification.Count = 1
+	if err := repo.Notifications(ctx).Create(ctx, notification); err != nil {
+		return err
+	}
+	return publish(ctx, notification, EventCreated)
+}
+
+// publish publishes the notification to the topic of the user once committed,
+// if it's in the inbox.
+func publish(ctx context.Context, notification *models.Notification, event string) error {
+	if !notification.Inbox {
+		return nil
+	}
+	filled := []models.Notification{*notification}
+	if err := fillActorNames(ctx, filled); err != nil {
+		return err
+	}
+	return realtime.Publish(ctx, NotificationsTopic(notification.UserID), event, filled[0])
+}
+
+// mentions returns the distinct emails mentioned in the content, up to
+// maxMentions.
+func mentions(content string) []string {
+	var emails []string
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		email := strings.TrimRight(match[1], ".")
+		if !slices.Contains(emails, email) {
+			emails = append(emails, email)
+		}
+		if len(emails) == maxMentions {
+			break
+		}
+	}
+	return emails
+}
+
+// excerptOf returns the beginning of the content on a single line.
+func excerptOf(content string) string {
+	excerpt := strings.Join(strings.Fields(content), " ")
+	if runes := []rune(excerpt); len(runes) > excerptLength {
+		return strings.TrimSpace(string(runes[:excerptLength])) + "…"
+	}
+	return excerpt
+}
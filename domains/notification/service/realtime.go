@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+
+	"bilingo/domains/user/models"
+	"bilingo/server/auth"
+	"bilingo/server/realtime"
+)
+
+// NotificationsTopic returns the realtime topic of the notifications of the
+// user, e.g. `notifications:<user id>`, which gets the notification.created and
+// notification.updated events.
+func NotificationsTopic(userId string) string {
+	return realtime.Topic("notifications", userId)
+}
+
+// AuthorizeRealtime lets users subscribe to the topics of their own
+// notifications, and admins to those of anyone.
+func AuthorizeRealtime(hub *realtime.Hub) {
+	realtime.Authorize(hub, "notifications", func(ctx context.Context, user *models.User, key string) error {
+		if user == nil {
+			return auth.ErrUnauthorized
+		} else if user.ID != key && !auth.IsAdmin(ctx, user) {
+			return auth.ErrForbidden
+		}
+		return nil
+	})
+}
@@ -0,0 +1,28 @@
+package types
+
+import (
+	"bilingo/common"
+)
+
+const (
+	KindComment  = "comment"  // Someone commented on an object of the user
+	KindReply    = "reply"    // Someone replied to a comment of the user
+	KindMention  = "mention"  // Someone mentioned the user in a comment, by `@` and the email
+	KindReaction = "reaction" // Readers liked an object of the user
+)
+
+const (
+	DigestNever  = "never"
+	DigestHourly = "hourly"
+	DigestDaily  = "daily" // At Notification.DigestSchedule of the configuration
+)
+
+type NotificationListQuery struct {
+	common.PaginatedQuery `tstype:",extends"`
+	Unread                *bool `json:"unread" query:"unread"` // Only the unread notifications if true
+}
+
+type NotificationPreferenceUpdate struct {
+	InApp  *bool   `json:"in_app"`
+	Digest *string `json:"digest" validate:"omitempty,oneof=never hourly daily"`
+}
@@ -0,0 +1,13 @@
+package api
+
+import "bilingo/server/app"
+
+// Register adds the routes of the system domain to the app.
+func Register(a *app.App) {
+	registerHealth(a)
+	registerFiles(a)
+	registerAttachments(a)
+	registerComments(a)
+	registerOpLogs(a)
+	registerJobs(a)
+}
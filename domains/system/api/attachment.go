@@ -0,0 +1,181 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	domain "bilingo/domains/system"
+	"bilingo/domains/system/service"
+	"bilingo/domains/system/types"
+	"bilingo/server"
+	"bilingo/server/app"
+	"bilingo/server/auth"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func registerAttachments(a *app.App) {
+	api := a.Group("/system/attachments", auth.UseAuth)
+	api.Get("/", listAttachments)
+	api.Get("/:id", getAttachment)
+	api.Get("/:id/download", downloadAttachment)
+	api.Post("/", auth.RequireAuth, uploadAttachment)
+	api.Delete("/:id", auth.RequireAuth, deleteAttachment)
+}
+
+// getAttachment returns the attachment with a signed URL, to the users who can
+// read the object it's attached to.
+func getAttachment(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("invalid attachment ID: %w", err))
+	}
+
+	attachment, err := service.GetAttachment(ctx.UserContext(), uint(id))
+	if errors.Is(err, domain.ErrAttachmentNotFound) {
+		return server.Error(ctx, 404, domain.ErrAttachmentNotFound)
+	} else if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+	if code, err := checkReadable(ctx.UserContext(), attachment.ObjectInfo); err != nil {
+		return server.Error(ctx, code, err)
+	}
+	if err := service.SignAttachment(ctx.UserContext(), attachment); err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success(ctx, attachment)
+}
+
+// downloadAttachment redirects to a fresh signed URL of the attachment, which
+// gives the attachment a permanent link, e.g. for embedding in articles.
+func downloadAttachment(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("invalid attachment ID: %w", err))
+	}
+
+	attachment, err := service.GetAttachment(ctx.UserContext(), uint(id))
+	if errors.Is(err, domain.ErrAttachmentNotFound) {
+		return server.Error(ctx, 404, domain.ErrAttachmentNotFound)
+	} else if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+	if code, err := checkReadable(ctx.UserContext(), attachment.ObjectInfo); err != nil {
+		return server.Error(ctx, code, err)
+	}
+	if err := service.SignAttachment(ctx.UserContext(), attachment); err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	return ctx.Redirect(attachment.URL, fiber.StatusFound)
+}
+
+// listAttachments lists the attachments of the object of the `object_type` and
+// `object_id`, to the users who can read it.
+func listAttachments(ctx *fiber.Ctx) error {
+	var query types.ObjectInfo
+	if err := ctx.QueryParser(&query); err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("malformed query: %w", err))
+	}
+	if code, err := checkReadable(ctx.UserContext(), query); err != nil {
+		return server.Error(ctx, code, err)
+	}
+
+	attachments, err := service.ListAttachments(ctx.UserContext(), query)
+	if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success(ctx, attachments)
+}
+
+// uploadAttachment attaches the `file` of the multipart form to the object of
+// the `object_type` and `object_id` fields, which only its owner and admins can
+// attach files to.
+func uploadAttachment(ctx *fiber.Ctx) error {
+	info := types.ObjectInfo{ObjectType: ctx.FormValue("object_type"), ObjectId: ctx.FormValue("object_id")}
+	if info.ObjectType == "" || info.ObjectId == "" {
+		return server.Error(ctx, 400, fmt.Errorf("malformed input: missing the object"))
+	}
+	header, err := ctx.FormFile("file")
+	if err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("malformed input: %w", err))
+	}
+
+	user := auth.GetUser(ctx.UserContext())
+	owner, err := service.GetObjectOwner(ctx.UserContext(), info.ObjectType, info.ObjectId)
+	if errors.Is(err, domain.ErrUnknownObjectType) {
+		return server.Error(ctx, 400, err)
+	} else if err != nil {
+		return server.Error(ctx, 404, err)
+	}
+	if owner != user.ID && !auth.IsAdmin(ctx.UserContext(), user) {
+		return server.Error(ctx, 403, auth.ErrForbidden)
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("malformed input: %w", err))
+	}
+	defer file.Close()
+
+	attachment, err := service.UploadAttachment(ctx.UserContext(), info, header.Filename, file, user.ID)
+	if errors.Is(err, domain.ErrInvalidAttachment) {
+		return server.Error(ctx, 400, err)
+	} else if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success(ctx, attachment)
+}
+
+// deleteAttachment deletes the attachment, which the uploader, the owner of
+// the object and admins can do.
+func deleteAttachment(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("invalid attachment ID: %w", err))
+	}
+
+	attachment, err := service.GetAttachment(ctx.UserContext(), uint(id))
+	if errors.Is(err, domain.ErrAttachmentNotFound) {
+		return server.Error(ctx, 404, domain.ErrAttachmentNotFound)
+	} else if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	user := auth.GetUser(ctx.UserContext())
+	if attachment.UploadedBy != user.ID && !auth.IsAdmin(ctx.UserContext(), user) {
+		owner, err := service.GetObjectOwner(ctx.UserContext(), attachment.ObjectType, attachment.ObjectId)
+		if err != nil || owner != user.ID {
+			return server.Error(ctx, 403, auth.ErrForbidden)
+		}
+	}
+
+	if err := service.DeleteAttachment(ctx.UserContext(), uint(id)); err != nil {
+		if errors.Is(err, domain.ErrAttachmentNotFound) {
+			return server.Error(ctx, 404, domain.ErrAttachmentNotFound)
+		}
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success[any](ctx, nil)
+}
+
+// checkReadable checks that the user can read the object attachments are
+// attached to, and returns the status to respond with otherwise.
+func checkReadable(ctx context.Context, info types.ObjectInfo) (int, error) {
+	ok, err := service.CanReadObject(ctx, info.ObjectType, info.ObjectId)
+	if errors.Is(err, domain.ErrUnknownObjectType) {
+		return 400, err
+	} else if err != nil {
+		return 404, err
+	} else if !ok {
+		return 403, auth.ErrForbidden
+	}
+	return 0, nil
+}
@@ -0,0 +1,37 @@
+import type { ApiResponse } from "@/common"
+import { ApiEntry } from "@/client"
+import type { Attachment } from "../models"
+import type { ObjectInfo } from "../types"
+
+const attachmentApi = new ApiEntry("/system/attachments")
+
+export async function getAttachment(id: number): ApiResponse<Attachment> {
+    return await attachmentApi.get("/" + id)
+}
+
+export async function listAttachments(query: ObjectInfo): ApiResponse<Attachment[]> {
+    return await attachmentApi.get("/", query)
+}
+
+/**
+ * Returns the permanent link of the attachment, which redirects to a fresh
+ * signed URL, e.g. for embedding images in articles.
+ */
+export function getAttachmentLink(id: number): string {
+    return `/api/system/attachments/${id}/download`
+}
+
+/**
+ * Attaches the file to the object, which only its owner and admins can do.
+ */
+export async function uploadAttachment(object: ObjectInfo, file: Blob, name?: string): ApiResponse<Attachment> {
+    const data = new FormData()
+    data.append("object_type", object.object_type)
+    data.append("object_id", object.object_id)
+    data.append("file", file, name ?? (file instanceof File ? file.name : "attachment"))
+    return await attachmentApi.post("/", null, data)
+}
+
+export async function deleteAttachment(id: number): ApiResponse<null> {
+    return await attachmentApi.delete("/" + id)
+}
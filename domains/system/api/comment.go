@@ -9,19 +9,20 @@ import (
 	"bilingo/domains/system/service"
 	"bilingo/domains/system/types"
 	"bilingo/server"
+	"bilingo/server/app"
 	"bilingo/server/auth"
+	"bilingo/server/markdown"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-var CommentApi = server.NewApiEntry("/system/comments", auth.UseAuth)
-
-func init() {
-	CommentApi.Get("/", listComments)
-	CommentApi.Get("/:id", getComment)
-	CommentApi.Post("/", createComment)
-	CommentApi.Patch("/:id", auth.RequireAuth, updateComment)
-	CommentApi.Delete("/:id", auth.RequireAuth, deleteComment)
+func registerComments(a *app.App) {
+	api := a.Group("/system/comments", auth.UseAuth)
+	api.Get("/", listComments)
+	api.Get("/:id", getComment)
+	api.Post("/", createComment)
+	api.Patch("/:id", auth.RequireAuth, updateComment)
+	api.Delete("/:id", auth.RequireAuth, deleteComment)
 }
 
 func getComment(ctx *fiber.Ctx) error {
@@ -29,6 +30,10 @@ func getComment(ctx *fiber.Ctx) error {
 	if err != nil {
 		return server.Error(ctx, 400, fmt.Errorf("invalid comment ID: %w", err))
 	}
+	format := ctx.Query("format", markdown.FormatMarkdown)
+	if format != markdown.FormatMarkdown && format != markdown.FormatHtml {
+		return server.Error(ctx, 400, fmt.Errorf("invalid format: %s", format))
+	}
 
 	comment, err := service.GetComment(ctx.UserContext(), uint(id))
 	if errors.Is(err, domain.ErrCommentNotFound) {
@@ -37,6 +42,11 @@ func getComment(ctx *fiber.Ctx) error {
 		return server.Error(ctx, 500, err)
 	}
 
+	if format == markdown.FormatHtml {
+		if err := service.RenderComment(comment); err != nil {
+			return server.Error(ctx, 500, err)
+		}
+	}
 	return server.Success(ctx, comment)
 }
 
@@ -45,6 +55,9 @@ func listComments(ctx *fiber.Ctx) error {
 	if err := ctx.QueryParser(&query); err != nil {
 		return server.Error(ctx, 400, fmt.Errorf("malformed query: %w", err))
 	}
+	if query.Format != nil && *query.Format != markdown.FormatMarkdown && *query.Format != markdown.FormatHtml {
+		return server.Error(ctx, 400, fmt.Errorf("invalid format: %s", *query.Format))
+	}
 
 	result, err := service.ListComments(ctx.UserContext(), query)
 	if err != nil {
@@ -60,7 +73,15 @@ func createComment(ctx *fiber.Ctx) error {
 		return server.Error(ctx, 400, fmt.Errorf("malformed request body: %w", err))
 	}
 
-	comment, err := service.CreateComment(ctx.UserContext(), &data)
+	// Anonymous comments have no author
+	author := ""
+	if user := auth.GetUser(ctx.UserContext()); user != nil {
+		author = user.ID
+	} else if !app.Config(ctx.UserContext()).Comment.AllowAnonymous {
+		return server.Error(ctx, 401, auth.ErrUnauthorized)
+	}
+
+	comment, err := service.CreateComment(ctx.UserContext(), &data, author)
 	if err != nil {
 		return server.Error(ctx, 500, err)
 	}
@@ -83,7 +104,7 @@ func updateComment(ctx *fiber.Ctx) error {
 
 	// Check if user is the author
 	user := auth.GetUser(ctx.UserContext())
-	if user == nil || comment.Author != user.Email {
+	if user == nil || comment.Author != user.ID {
 		return server.Error(ctx, 403, auth.ErrForbidden)
 	}
 
@@ -118,7 +139,7 @@ func deleteComment(ctx *fiber.Ctx) error {
 
 	// Check if user is the author
 	user := auth.GetUser(ctx.UserContext())
-	if user == nil || comment.Author != user.Email {
+	if user == nil || comment.Author != user.ID {
 		return server.Error(ctx, 403, auth.ErrForbidden)
 	}
 
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"errors"
+	"mime"
+	"net/url"
+
+	"bilingo/server"
+	"bilingo/server/app"
+	"bilingo/server/storage"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// registerFiles adds the route serving the files in the storage, whose keys are
+// unguessable where the files aren't public, e.g. versioned avatars. The private
+// files, such as attachments, are only served at the signed URLs.
+func registerFiles(a *app.App) {
+	api := a.Group("/system/files")
+	api.Get("/*", getFile)
+}
+
+func getFile(ctx *fiber.Ctx) error {
+	key := ctx.Params("*")
+
+	var filename string
+	private := storage.IsPrivate(key)
+	if private {
+		query, err := url.ParseQuery(string(ctx.Request().URI().QueryString()))
+		if err == nil {
+			filename, err = storage.VerifySignedURL(ctx.UserContext(), key, query)
+		}
+		if errors.Is(err, storage.ErrInvalidKey) {
+			return server.Error(ctx, 404, storage.ErrNotFound)
+		} else if err != nil {
+			return server.Error(ctx, 403, storage.ErrInvalidSignature)
+		}
+	}
+
+	obj, err := storage.FromContext(ctx.UserContext()).Get(ctx.UserContext(), key)
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+		return server.Error(ctx, 404, storage.ErrNotFound)
+	} else if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	ctx.Set(fiber.HeaderContentType, obj.ContentType)
+	ctx.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	if private {
+		ctx.Set(fiber.HeaderCacheControl, "private, max-age=300")
+	} else {
+		ctx.Set(fiber.HeaderCacheControl, "public, max-age=86400")
+	}
+	if filename != "" {
+		ctx.Set(fiber.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	}
+	// The body is closed by fasthttp once it's written
+	return ctx.SendStream(obj, int(obj.Size))
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"bilingo/server"
+	"bilingo/server/app"
+	"bilingo/server/auth"
+	"bilingo/server/db"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// registerHealth adds the route reporting whether the server can serve requests,
+// for load balancers and monitoring.
+func registerHealth(a *app.App) {
+	api := a.Group("/system/health", auth.UseAuth)
+	api.Get("/", getHealth)
+}
+
+var errDatabaseUnavailable = errors.New("the database is unavailable")
+
+type healthResult struct {
+	Status      string          `json:"status"`                // ok, or degraded if any other database or replica is down
+	Connections []db.ConnHealth `json:"connections,omitempty"` // Only shown to admins
+}
+
+// getHealth responds 503 if the default database is down, with the error only
+// shown to admins, and reports the state of the other databases, the replicas
+// and the pools to admins.
+func getHealth(ctx *fiber.Ctx) error {
+	conns := db.Health(ctx.UserContext(), 2*time.Second)
+	admin := auth.IsAdmin(ctx.UserContext(), auth.GetUser(ctx.UserContext()))
+	if !conns[0].Ok {
+		// The errors of the drivers may reveal the hosts and users of the
+		// databases
+		if !admin {
+			return server.Error(ctx, 503, errDatabaseUnavailable)
+		}
+		return server.Error(ctx, 503, errors.New(conns[0].Error))
+	}
+
+	result := healthResult{Status: "ok"}
+	for _, conn := range conns {
+		if !conn.Ok {
+			result.Status = "degraded"
+		}
+	}
+	if admin {
+		result.Connections = conns
+	}
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	return server.Success(ctx, result)
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"bilingo/server"
+	"bilingo/server/app"
+	"bilingo/server/auth"
+	"bilingo/server/jobs"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// registerJobs adds the routes inspecting the job queue, which are for admins
+// only.
+func registerJobs(a *app.App) {
+	api := a.Group("/system/jobs", auth.UseAuth, auth.RequireAdmin)
+	api.Get("/", listJobs)
+	api.Get("/stats", getJobStats)
+	api.Post("/retry", retryFailedJobs)
+	api.Get("/:id", getJob)
+	api.Post("/:id/retry", retryJob)
+	api.Post("/:id/cancel", cancelJob)
+}
+
+func parseJobId(ctx *fiber.Ctx) (uint, error) {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid job ID: %w", err)
+	}
+	return uint(id), nil
+}
+
+// jobError responds with the status of the error of the job queue.
+func jobError(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, jobs.ErrJobNotFound):
+		return server.Error(ctx, 404, err)
+	case errors.Is(err, jobs.ErrInvalidJobState):
+		return server.Error(ctx, 409, err)
+	default:
+		return server.Error(ctx, 500, err)
+	}
+}
+
+func listJobs(ctx *fiber.Ctx) error {
+	var query jobs.ListQuery
+	if err := ctx.QueryParser(&query); err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("malformed query: %w", err))
+	}
+
+	result, err := jobs.FromContext(ctx.UserContext()).List(ctx.UserContext(), query)
+	if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success(ctx, result)
+}
+
+func getJobStats(ctx *fiber.Ctx) error {
+	stats, err := jobs.FromContext(ctx.UserContext()).Stats(ctx.UserContext())
+	if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success(ctx, stats)
+}
+
+// retryFailedJobs retries the failed jobs of the `name` in the query, or all of
+// them, and responds with how many there were.
+func retryFailedJobs(ctx *fiber.Ctx) error {
+	count, err := jobs.FromContext(ctx.UserContext()).RetryFailed(ctx.UserContext(), ctx.Query("name"))
+	if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success(ctx, count)
+}
+
+func getJob(ctx *fiber.Ctx) error {
+	id, err := parseJobId(ctx)
+	if err != nil {
+		return server.Error(ctx, 400, err)
+	}
+
+	job, err := jobs.FromContext(ctx.UserContext()).Get(ctx.UserContext(), id)
+	if err != nil {
+		return jobError(ctx, err)
+	}
+
+	return server.Success(ctx, job)
+}
+
+func retryJob(ctx *fiber.Ctx) error {
+	id, err := parseJobId(ctx)
+	if err != nil {
+		return server.Error(ctx, 400, err)
+	}
+
+	job, err := jobs.FromContext(ctx.UserContext()).Retry(ctx.UserContext(), id)
+	if err != nil {
+		return jobError(ctx, err)
+	}
+
+	return server.Success(ctx, job)
+}
+
+func cancelJob(ctx *fiber.Ctx) error {
+	id, err := parseJobId(ctx)
+	if err != nil {
+		return server.Error(ctx, 400, err)
+	}
+
+	job, err := jobs.FromContext(ctx.UserContext()).Cancel(ctx.UserContext(), id)
+	if err != nil {
+		return jobError(ctx, err)
+	}
+
+	return server.Success(ctx, job)
+}
@@ -0,0 +1,66 @@
+import { ApiEntry } from "@/client"
+import type { ApiResponse, PaginatedQuery, PaginatedResult } from "@/common"
+
+const jobApi = new ApiEntry("/system/jobs")
+
+export interface QueuedJob {
+    id: number
+    created_at: string
+    updated_at: string
+    name: string
+    payload: string
+    status: string
+    run_at: string
+    attempts: number
+    max_attempts: number
+    last_error?: string
+    unique_key?: string
+    locked_by?: string
+    locked_until?: string
+    finished_at?: string
+}
+
+export interface JobListQuery extends PaginatedQuery {
+    name?: string
+    status?: string
+}
+
+export interface JobStats {
+    counts: Record<string, number>
+    running: number
+    handlers: {
+        name: string
+        concurrency: number
+        running: number
+    }[]
+    schedules: {
+        name: string
+        spec: string
+        job: string
+        next_run: string
+    }[]
+}
+
+export async function listJobs(query: Partial<JobListQuery>): ApiResponse<PaginatedResult<QueuedJob>> {
+    return await jobApi.get("/", query)
+}
+
+export async function getJobStats(): ApiResponse<JobStats> {
+    return await jobApi.get("/stats")
+}
+
+export async function getJob(id: number): ApiResponse<QueuedJob> {
+    return await jobApi.get("/" + id)
+}
+
+export async function retryJob(id: number): ApiResponse<QueuedJob> {
+    return await jobApi.post(`/${id}/retry`)
+}
+
+export async function retryFailedJobs(name?: string): ApiResponse<number> {
+    return await jobApi.post("/retry", name ? { name } : null)
+}
+
+export async function cancelJob(id: number): ApiResponse<QueuedJob> {
+    return await jobApi.post(`/${id}/cancel`)
+}
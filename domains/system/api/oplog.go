@@ -6,15 +6,18 @@ import (
 	"bilingo/domains/system/service"
 	"bilingo/domains/system/types"
 	"bilingo/server"
+	"bilingo/server/app"
 	"bilingo/server/auth"
+	"bilingo/server/oplog"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-var OpLogApi = server.NewApiEntry("/system/oplogs", auth.UseAuth)
-
-func init() {
-	OpLogApi.Get("/", auth.RequireAuth, listOpLogs)
+func registerOpLogs(a *app.App) {
+	api := a.Group("/system/oplogs", auth.UseAuth)
+	api.Get("/", auth.RequireAuth, listOpLogs)
+	api.Get("/stats", auth.RequireAuth, getOpLogStats)
+	api.Get("/verify", auth.RequireAuth, verifyOpLogChain)
 }
 
 func listOpLogs(ctx *fiber.Ctx) error {
@@ -23,6 +26,20 @@ func listOpLogs(ctx *fiber.Ctx) error {
 		return server.Error(ctx, 400, fmt.Errorf("malformed query: %w", err))
 	}
 
+	user := auth.GetUser(ctx.UserContext())
+	if user == nil {
+		return server.Error(ctx, 401, auth.ErrUnauthorized)
+	}
+
+	// Admins can see all oplogs, others can only see the history of the
+	// objects they own, or the operations they performed themselves.
+	if !auth.IsAdmin(ctx.UserContext(), user) && !isObjectOwner(ctx, query, user.ID) {
+		if query.User != nil && *query.User != user.ID {
+			return server.Error(ctx, 403, auth.ErrForbidden)
+		}
+		query.User = &user.ID
+	}
+
 	result, err := service.ListOpLogs(ctx.UserContext(), query)
 	if err != nil {
 		return server.Error(ctx, 500, err)
@@ -30,3 +47,33 @@ func listOpLogs(ctx *fiber.Ctx) error {
 
 	return server.Success(ctx, result)
 }
+
+func isObjectOwner(ctx *fiber.Ctx, query types.OpLogListQuery, userId string) bool {
+	if query.ObjectType == nil || query.ObjectId == nil {
+		return false
+	}
+
+	owner, err := service.GetObjectOwner(ctx.UserContext(), *query.ObjectType, *query.ObjectId)
+	return err == nil && owner == userId
+}
+
+func getOpLogStats(ctx *fiber.Ctx) error {
+	if !auth.IsAdmin(ctx.UserContext(), auth.GetUser(ctx.UserContext())) {
+		return server.Error(ctx, 403, auth.ErrForbidden)
+	}
+
+	return server.Success(ctx, oplog.FromContext(ctx.UserContext()).Stats())
+}
+
+func verifyOpLogChain(ctx *fiber.Ctx) error {
+	if !auth.IsAdmin(ctx.UserContext(), auth.GetUser(ctx.UserContext())) {
+		return server.Error(ctx, 403, auth.ErrForbidden)
+	}
+
+	report, err := service.VerifyOpLogChain(ctx.UserContext())
+	if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success(ctx, report)
+}
@@ -1,10 +1,28 @@
 import { ApiEntry } from "@/client"
 import type { ApiResponse, PaginatedResult } from "@/common"
-import type { OpLogListQuery } from "../types"
-import type { OpLog } from "../models"
+import type { OpLogChainReport, OpLogListQuery } from "../types"
+import type { OpLogEntry } from "../models"
 
 const opLogApi = new ApiEntry("/system/oplogs")
 
-export async function listOpLogs(query: OpLogListQuery): ApiResponse<PaginatedResult<OpLog>> {
+export async function listOpLogs(
+    query: OpLogListQuery,
+): ApiResponse<PaginatedResult<OpLogEntry>> {
     return await opLogApi.get("/", query)
 }
+
+export interface OpLogStats {
+    queued: number
+    written: number
+    dropped: number
+    failed: number
+    pruned: number
+}
+
+export async function getOpLogStats(): ApiResponse<OpLogStats> {
+    return await opLogApi.get("/stats")
+}
+
+export async function verifyOpLogChain(): ApiResponse<OpLogChainReport> {
+    return await opLogApi.get("/verify")
+}
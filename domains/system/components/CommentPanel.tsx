@@ -5,6 +5,8 @@ import type { Comment } from "../models/index.ts"
 import type { CommentCreate, CommentUpdate } from "../types/index.ts"
 import { createComment, deleteComment, listComments, updateComment } from "../api/comment.ts"
 import { useAuth } from "@/client/contexts/AuthContext.tsx"
+import { listProfiles } from "@/domains/user/api/user.ts"
+import type { Profile } from "@/domains/user/types"
 
 interface CommentPanelProps {
     objectType: string
@@ -16,12 +18,12 @@ export default function CommentPanel({ objectType, objectId }: CommentPanelProps
     const [comments, setComments] = useState<Comment[]>([])
     const [loading, setLoading] = useState(true)
     const [newComment, setNewComment] = useState("")
-    const [authorEmail, setAuthorEmail] = useState("")
+    const [authors, setAuthors] = useState<Record<string, Profile>>({})
     const [submitting, setSubmitting] = useState(false)
     const [editingId, setEditingId] = useState<number | null>(null)
     const [editContent, setEditContent] = useState("")
     const [replyToId, setReplyToId] = useState<number | null>(null)
-    const [replyToEmail, setReplyToEmail] = useState<string | null>(null)
+    const [replyToName, setReplyToName] = useState<string | null>(null)
     const commentTextareaRef = useRef<HTMLTextAreaElement>(null)
 
     async function loadComments(): Promise<void> {
@@ -30,11 +32,13 @@ export default function CommentPanel({ objectType, objectId }: CommentPanelProps
             const result = await listComments({
                 object_type: objectType,
                 object_id: String(objectId),
+                format: "html",
                 page: 1,
                 page_size: 100,
             })
             if (result.success) {
                 setComments(result.data.list)
+                await loadAuthors(result.data.list)
             } else {
                 await alert("加载评论失败: " + result.message)
             }
@@ -43,19 +47,36 @@ export default function CommentPanel({ objectType, objectId }: CommentPanelProps
         }
     }
 
-    useEffect(() => {
-        loadComments()
-    }, [objectType, objectId])
+    // Comments refer to their authors by user IDs, whose profiles tell who they
+    // are
+    async function loadAuthors(list: Comment[]): Promise<void> {
+        const ids = [...new Set(list.map((c) => c.author).filter(Boolean))]
+        if (ids.length === 0) {
+            return
+        }
 
-    useEffect(() => {
-        if (user) {
-            setAuthorEmail(user.email)
+        const result = await listProfiles(ids)
+        if (result.success) {
+            setAuthors(Object.fromEntries(result.data.map((p) => [p.id, p])))
+        }
+    }
+
+    // Anonymous comments have no author
+    function authorName(authorId: string): string {
+        if (!authorId) {
+            return "匿名用户"
         }
-    }, [user])
+        const profile = authors[authorId]
+        return profile?.display_name ?? profile?.name ?? authorId
+    }
+
+    useEffect(() => {
+        loadComments()
+    }, [objectType, objectId, user])
 
     async function handleSubmit(e: React.FormEvent): Promise<void> {
         e.preventDefault()
-        if (!newComment.trim() || !authorEmail.trim()) {
+        if (!newComment.trim()) {
             return
         }
 
@@ -65,7 +86,6 @@ export default function CommentPanel({ objectType, objectId }: CommentPanelProps
                 object_type: objectType,
                 object_id: String(objectId),
                 content: newComment.trim(),
-                author: authorEmail.trim(),
                 parent_id: replyToId || undefined,
             }
 
@@ -73,7 +93,7 @@ export default function CommentPanel({ objectType, objectId }: CommentPanelProps
             if (result.success) {
                 setNewComment("")
                 setReplyToId(null)
-                setReplyToEmail(null)
+                setReplyToName(null)
                 await loadComments()
             } else {
                 await alert("发表评论失败: " + result.message)
@@ -148,7 +168,7 @@ export default function CommentPanel({ objectType, objectId }: CommentPanelProps
 
     function renderComment(comment: Comment, level = 0): JSX.Element {
         const isEditing = editingId === comment.id
-        const isAuthor = user && user.email === comment.author
+        const isAuthor = user && user.id === comment.author
         const replies = getCommentsByParent(comment.id)
 
         return (
@@ -160,7 +180,7 @@ export default function CommentPanel({ objectType, objectId }: CommentPanelProps
                     <div className="flex items-start justify-between mb-2">
                         <div className="flex-1">
                             <span className="font-semibold text-gray-900">
-                                {comment.author}
+                                {authorName(comment.author)}
                             </span>
                             <span className="text-sm text-gray-500 ml-2">
                                 {formatDate(comment.created_at)}
@@ -221,14 +241,24 @@ export default function CommentPanel({ objectType, objectId }: CommentPanelProps
                         )
                         : (
                             <>
-                                <p className="text-gray-700 whitespace-pre-wrap">
-                                    {comment.content}
-                                </p>
+                                {comment.html !== undefined
+                                    ? (
+                                        <div
+                                            className="prose prose-sm max-w-none text-gray-700"
+                                            // The HTML is sanitized by the server
+                                            dangerouslySetInnerHTML={{ __html: comment.html }}
+                                        />
+                                    )
+                                    : (
+                                        <p className="text-gray-700 whitespace-pre-wrap">
+                                            {comment.content}
+                                        </p>
+                                    )}
                                 <button
                                     type="button"
                                     onClick={() => {
                                         setReplyToId(comment.id)
-                                        setReplyToEmail(comment.author)
+                                        setReplyToName(authorName(comment.author))
                                         commentTextareaRef.current?.focus()
                                     }}
                                     className="text-sm text-blue-600 hover:text-blue-700 mt-2"
@@ -265,7 +295,7 @@ export default function CommentPanel({ objectType, objectId }: CommentPanelProps
                             type="button"
                             onClick={() => {
                                 setReplyToId(null)
-                                setReplyToEmail(null)
+                                setReplyToName(null)
                             }}
                             className="ml-2 text-blue-600 hover:text-blue-700"
                         >
@@ -277,32 +307,18 @@ export default function CommentPanel({ objectType, objectId }: CommentPanelProps
                     ref={commentTextareaRef}
                     value={newComment}
                     onChange={(e) => setNewComment(e.target.value)}
-                    placeholder={replyToEmail ? `回复 ${replyToEmail}` : "写下你的评论..."}
+                    placeholder={replyToName ? `回复 ${replyToName}` : "写下你的评论..."}
                     required
                     className="w-full px-4 py-3 border border-gray-300 rounded-lg focus:ring-2 focus:ring-blue-500 focus:border-transparent"
                     rows={4}
                 />
                 <div className="flex justify-between items-center mt-2 gap-4">
-                    <div className="flex items-center gap-2 flex-1">
-                        <label
-                            htmlFor="author-email"
-                            className="text-sm font-medium text-gray-700 whitespace-nowrap"
-                        >
-                            您的邮箱:
-                        </label>
-                        <input
-                            id="author-email"
-                            type="email"
-                            value={authorEmail}
-                            onChange={(e) => setAuthorEmail(e.target.value)}
-                            placeholder="your@email.com"
-                            required
-                            className="flex-1 px-4 py-2 border border-gray-300 rounded-lg focus:ring-2 focus:ring-blue-500 focus:border-transparent"
-                        />
-                    </div>
+                    <span className="text-sm text-gray-500">
+                        {user ? `以 ${user.name} 的身份发表` : "未登录，将以匿名身份发表（如站点允许）"}
+                    </span>
                     <button
                         type="submit"
-                        disabled={submitting || !newComment.trim() || !authorEmail.trim()}
+                        disabled={submitting || !newComment.trim()}
                         className="px-6 py-2 bg-blue-600 text-white rounded-lg hover:bg-blue-700 disabled:opacity-50 disabled:cursor-not-allowed"
                     >
                         {submitting ? "发表中..." : "发表评论"}
@@ -0,0 +1,9 @@
+package system
+
+// The bindings of the databases the tables of the domain are in, see db.Bind.
+const (
+	DBBinding = "system"
+	// The oplogs have no relations with the other tables, and are written far
+	// more often, so they can be kept in a database of their own
+	OpLogDBBinding = "oplog"
+)
@@ -2,4 +2,10 @@ package system
 
 import "errors"
 
-var ErrCommentNotFound = errors.New("comment not found")
+var (
+	ErrCommentNotFound    = errors.New("comment not found")
+	ErrAttachmentNotFound = errors.New("attachment not found")
+	ErrInvalidAttachment  = errors.New("invalid attachment")
+	ErrUnknownObjectType  = errors.New("unknown object type")
+	ErrOpLogAppendOnly    = errors.New("oplogs are append-only in the audit mode")
+)
@@ -0,0 +1,38 @@
+package system
+
+// The events of the domain, published by the service, see events.Publish.
+
+// CommentCreated is published when a comment is posted on an object, e.g. an
+// article, or in reply to another comment.
+type CommentCreated struct {
+	ID         uint   `json:"id"`
+	ObjectType string `json:"object_type"`
+	ObjectId   string `json:"object_id"`
+	ParentId   *uint  `json:"parent_id"` // The comment replied to, if any
+	Author     string `json:"author"`    // The user ID of the author
+	Content    string `json:"content"`
+}
+
+func (CommentCreated) EventName() string { return "comment.created" }
+
+// CommentUpdated is published when the content of a comment is edited.
+type CommentUpdated struct {
+	ID         uint   `json:"id"`
+	ObjectType string `json:"object_type"`
+	ObjectId   string `json:"object_id"`
+	Author     string `json:"author"`
+	Content    string `json:"content"`
+}
+
+func (CommentUpdated) EventName() string { return "comment.updated" }
+
+// CommentDeleted is published when a comment is deleted, but not for the
+// comments deleted along with their objects.
+type CommentDeleted struct {
+	ID         uint   `json:"id"`
+	ObjectType string `json:"object_type"`
+	ObjectId   string `json:"object_id"`
+	Author     string `json:"author"`
+}
+
+func (CommentDeleted) EventName() string { return "comment.deleted" }
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"strconv"
+	"time"
+
+	"bilingo/domains/system/types"
+)
+
+// Attachment is a file attached to an object, attachments of the same content
+// share the stored file, which is deleted along with the last of them.
+type Attachment struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt        time.Time `json:"created_at"`
+	types.ObjectInfo `tstype:",extends"`
+	Name             string `json:"name" gorm:"size:255"`
+	ContentType      string `json:"content_type" gorm:"size:127"`
+	Size             int64  `json:"size"`
+	Hash             string `json:"hash" gorm:"size:64;index"` // The SHA-256 of the content
+	UploadedBy       string `json:"uploaded_by" gorm:"size:36;index"`
+	// The signed download URL, which expires after Attachment.UrlExpiry of the
+	// configuration
+	URL string `json:"url" gorm:"-"`
+}
+
+func (a *Attachment) TableName() string {
+	return "attachment"
+}
+
+func (a *Attachment) AuditInfo() (string, string) {
+	return "attachment", strconv.FormatUint(uint64(a.ID), 10)
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strconv"
 	"time"
 
 	"bilingo/domains/system/types"
@@ -13,10 +14,16 @@ type Comment struct {
 	UpdatedAt        time.Time `json:"updated_at"`
 	types.ObjectInfo `tstype:",extends"`
 	Content          string `json:"content"`
-	Author           string `json:"author"`
+	Author           string `json:"author" gorm:"size:36;index"`
 	ParentId         *uint  `json:"parent_id"`
+	// The sanitized HTML of the content, if requested, not stored
+	Html *string `json:"html,omitempty" gorm:"-"`
 }
 
 func (a *Comment) TableName() string {
 	return "comment"
 }
+
+func (a *Comment) AuditInfo() (string, string) {
+	return "comment", strconv.FormatUint(uint64(a.ID), 10)
+}
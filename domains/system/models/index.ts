@@ -1,10 +1,32 @@
 // Code generated by tygo. DO NOT EDIT.
 
 //////////
-// source: comment.go
+// source: attachment.go
 
 import type * as types from "../types"
 
+/**
+ * Attachment is a file attached to an object, attachments of the same content
+ * share the stored file, which is deleted along with the last of them.
+ */
+export interface Attachment extends types.ObjectInfo {
+    id: number /* uint */
+    created_at: string /* RFC3339 */
+    name: string
+    content_type: string
+    size: number /* int64 */
+    hash: string // The SHA-256 of the content
+    uploaded_by: string
+    /**
+     * The signed download URL, which expires after Attachment.UrlExpiry of the
+     * configuration
+     */
+    url: string
+}
+
+//////////
+// source: comment.go
+
 export interface Comment extends types.ObjectInfo {
     id: number /* uint */
     created_at: string /* RFC3339 */
@@ -12,6 +34,10 @@ export interface Comment extends types.ObjectInfo {
     content: string
     author: string
     parent_id?: number /* uint */
+    /**
+     * The sanitized HTML of the content, if requested, not stored
+     */
+    html?: string
 }
 
 //////////
@@ -23,4 +49,14 @@ export interface OpLog extends types.ObjectInfo, types.OpLogBase {
     old_data?: string
     timestamp: string /* RFC3339 */
     times: number /* uint32 */
+    hash: string
+    seq?: number /* uint64 */ // The position in the audit chain, nil if not chained
+    prev_hash?: string // The chain hash of the previous entry
+    chain_hash?: string
+}
+/**
+ * OpLogEntry is an OpLog along with the field-level diff of its data.
+ */
+export interface OpLogEntry extends OpLog {
+    diff: types.FieldDiff[]
 }
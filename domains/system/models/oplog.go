@@ -1,6 +1,10 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
 	"time"
 
 	"bilingo/domains/system/types"
@@ -14,8 +18,70 @@ type OpLog struct {
 	OldData          *string   `json:"old_data"`
 	Timestamp        time.Time `json:"timestamp"`
 	Times            uint32    `json:"times"`
+	Hash             string    `json:"hash" gorm:"size:64;index"`
+	Seq              *uint64   `json:"seq" gorm:"uniqueIndex"`   // The position in the audit chain, nil if not chained
+	PrevHash         *string   `json:"prev_hash" gorm:"size:64"` // The chain hash of the previous entry
+	ChainHash        *string   `json:"chain_hash" gorm:"size:64"`
 }
 
 func (o *OpLog) TableName() string {
 	return "op_log"
 }
+
+// ContentHash returns the hash of the content of the oplog, which identifies
+// repeated operations regardless of when they happened.
+func (o *OpLog) ContentHash() string {
+	h := sha256.New()
+	fields := []*string{
+		&o.ObjectType, &o.ObjectId, &o.Operation, &o.Result,
+		o.Description, o.User, o.Ip, o.NewData, o.OldData,
+	}
+	for _, field := range fields {
+		// Length-prefix each field so that different field boundaries or nil
+		// values never produce the same input
+		if field == nil {
+			_ = binary.Write(h, binary.BigEndian, int64(-1))
+			continue
+		}
+		_ = binary.Write(h, binary.BigEndian, int64(len(*field)))
+		h.Write([]byte(*field))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ChainContent returns the canonical content of a chained oplog, which is
+// hashed along with the hash of the previous entry.
+func (o *OpLog) ChainContent() (string, error) {
+	var seq uint64
+	if o.Seq != nil {
+		seq = *o.Seq
+	}
+
+	content, err := json.Marshal(types.OpLogChainEntry{
+		Seq:         seq,
+		ID:          o.ID,
+		ObjectType:  o.ObjectType,
+		ObjectId:    o.ObjectId,
+		Operation:   o.Operation,
+		Description: o.Description,
+		Result:      o.Result,
+		User:        o.User,
+		Ip:          o.Ip,
+		NewData:     o.NewData,
+		OldData:     o.OldData,
+		Timestamp:   o.Timestamp.UTC().Format(time.RFC3339Nano),
+	})
+	return string(content), err
+}
+
+// ChainHashOf returns the hash of an entry in the audit chain.
+func ChainHashOf(prevHash string, content string) string {
+	sum := sha256.Sum256([]byte(prevHash + content))
+	return hex.EncodeToString(sum[:])
+}
+
+// OpLogEntry is an OpLog along with the field-level diff of its data.
+type OpLogEntry struct {
+	OpLog `tstype:",extends"`
+	Diff  []types.FieldDiff `json:"diff"`
+}
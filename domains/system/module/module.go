@@ -0,0 +1,25 @@
+// Package module assembles the system domain into an app.
+package module
+
+import (
+	"bilingo/domains/system/api"
+	"bilingo/domains/system/repo"
+	"bilingo/domains/system/service"
+	"bilingo/server/app"
+	"bilingo/server/events"
+	"bilingo/server/oplog"
+	"bilingo/server/realtime"
+)
+
+// Register provides the repositories of the comments and attachments and the
+// oplog pipeline, publishes the changes of the comments to the realtime topics
+// of the objects, and adds the routes of the system.
+func Register(a *app.App) error {
+	repo.Provide(a)
+	if err := oplog.Register(a); err != nil {
+		return err
+	}
+	service.PublishRealtime(events.ForApp(a), realtime.ForApp(a))
+	api.Register(a)
+	return nil
+}
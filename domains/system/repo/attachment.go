@@ -0,0 +1,31 @@
+package repo
+
+import (
+	"context"
+
+	"bilingo/domains/system/models"
+	impl "bilingo/domains/system/repo/db"
+	"bilingo/server/app"
+)
+
+// Attachments returns the attachment repository of the app the context carries, or the
+// database implementation outside of apps.
+func Attachments(ctx context.Context) IAttachmentRepo {
+	return app.Resolve[IAttachmentRepo](ctx, &impl.AttachmentRepo{})
+}
+
+type IAttachmentRepo interface {
+	Get(ctx context.Context, id uint) (*models.Attachment, error)
+	// List returns the attachments of the object in the order of uploading.
+	List(ctx context.Context, objectType string, objectId string) ([]models.Attachment, error)
+	Create(ctx context.Context, attachment *models.Attachment) error
+	Delete(ctx context.Context, id uint) error
+	// LockByHash locks the attachments of the content until the end of the
+	// transaction of the context, and returns their number.
+	LockByHash(ctx context.Context, hash string) (int, error)
+	ListByUploader(ctx context.Context, uploadedBy string) ([]models.Attachment, error)
+	ListByObjects(ctx context.Context, objectType string, objectIds []string) ([]models.Attachment, error)
+	// ReassignUploader attributes all attachments uploaded by the user to
+	// another user, and returns the number of changed attachments.
+	ReassignUploader(ctx context.Context, from string, to string) (int, error)
+}
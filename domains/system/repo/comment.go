@@ -7,14 +7,29 @@ import (
 	"bilingo/domains/system/models"
 	impl "bilingo/domains/system/repo/db"
 	"bilingo/domains/system/types"
+	"bilingo/server/app"
 )
 
-var CommentRepo ICommentRepo = &impl.CommentRepo{}
+// Comments returns the comment repository of the app the context carries, or the
+// database implementation outside of apps.
+func Comments(ctx context.Context) ICommentRepo {
+	return app.Resolve[ICommentRepo](ctx, &impl.CommentRepo{})
+}
 
 type ICommentRepo interface {
 	Get(ctx context.Context, id uint) (*models.Comment, error)
 	List(ctx context.Context, query *types.CommentListQuery) (*common.PaginatedResult[models.Comment], error)
-	Create(ctx context.Context, data *types.CommentCreate) (*models.Comment, error)
+	Create(ctx context.Context, data *types.CommentCreate, author string) (*models.Comment, error)
 	Update(ctx context.Context, id uint, updates *types.CommentUpdate) (*models.Comment, error)
 	Delete(ctx context.Context, id uint) error
+	ListByAuthor(ctx context.Context, author string) ([]models.Comment, error)
+	// DeleteByAuthor deletes all comments of the author, and returns the number
+	// of deleted comments.
+	DeleteByAuthor(ctx context.Context, author string) (int, error)
+	// DeleteByObjects deletes all comments on the given objects, and returns
+	// the number of deleted comments.
+	DeleteByObjects(ctx context.Context, objectType string, objectIds []string) (int, error)
+	// ReassignAuthor attributes all comments of the author to another user, and
+	// returns the number of changed comments.
+	ReassignAuthor(ctx context.Context, from string, to string) (int, error)
 }
@@ -0,0 +1,150 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	domain "bilingo/domains/system"
+	"bilingo/domains/system/models"
+	"bilingo/domains/system/tables"
+	"bilingo/server/db"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AttachmentRepo struct{}
+
+func (r *AttachmentRepo) Get(ctx context.Context, id uint) (*models.Attachment, error) {
+	conn, err := db.Reader(ctx, domain.DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	attachment, err := gorm.G[models.Attachment](conn).Where(tables.Attachment.ID.Eq(id)).First(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrAttachmentNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to find attachment: %w", err)
+	}
+
+	return &attachment, nil
+}
+
+func (r *AttachmentRepo) List(ctx context.Context, objectType string, objectId string) ([]models.Attachment, error) {
+	conn, err := db.Reader(ctx, domain.DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	attachments, err := gorm.G[models.Attachment](conn).
+		Where(tables.Attachment.ObjectType.Eq(objectType), tables.Attachment.ObjectId.Eq(objectId)).
+		Order(tables.Attachment.ID.Asc()).
+		Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachment list: %w", err)
+	}
+
+	return attachments, nil
+}
+
+func (r *AttachmentRepo) Create(ctx context.Context, attachment *models.Attachment) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	if err := gorm.G[models.Attachment](conn).Create(ctx, attachment); err != nil {
+		return fmt.Errorf("failed to create attachment: %w", err)
+	}
+
+	return nil
+}
+
+func (r *AttachmentRepo) Delete(ctx context.Context, id uint) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	rowsAffected, err := gorm.G[models.Attachment](conn).Where(tables.Attachment.ID.Eq(id)).Delete(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	} else if rowsAffected == 0 {
+		return domain.ErrAttachmentNotFound
+	}
+
+	return nil
+}
+
+func (r *AttachmentRepo) LockByHash(ctx context.Context, hash string) (int, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return 0, db.ConnError(err)
+	}
+
+	// SQLite has no row locks, its transactions write one at a time instead
+	attachments, err := gorm.G[models.Attachment](conn, clause.Locking{Strength: clause.LockingStrengthUpdate}).
+		Where(tables.Attachment.Hash.Eq(hash)).
+		Find(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to lock attachments: %w", err)
+	}
+
+	return len(attachments), nil
+}
+
+func (r *AttachmentRepo) ListByUploader(ctx context.Context, uploadedBy string) ([]models.Attachment, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	attachments, err := gorm.G[models.Attachment](conn).
+		Where(tables.Attachment.UploadedBy.Eq(uploadedBy)).
+		Order(tables.Attachment.ID.Asc()).
+		Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachments of uploader: %w", err)
+	}
+
+	return attachments, nil
+}
+
+func (r *AttachmentRepo) ListByObjects(ctx context.Context, objectType string, objectIds []string) ([]models.Attachment, error) {
+	if len(objectIds) == 0 {
+		return nil, nil
+	}
+
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	attachments, err := gorm.G[models.Attachment](conn).
+		Where(tables.Attachment.ObjectType.Eq(objectType), tables.Attachment.ObjectId.In(objectIds...)).
+		Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachments of objects: %w", err)
+	}
+
+	return attachments, nil
+}
+
+func (r *AttachmentRepo) ReassignUploader(ctx context.Context, from string, to string) (int, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return 0, db.ConnError(err)
+	}
+
+	rowsAffected, err := gorm.G[models.Attachment](conn).
+		Where(tables.Attachment.UploadedBy.Eq(from)).
+		Set(tables.Attachment.UploadedBy.Set(to)).
+		Update(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reassign attachments: %w", err)
+	}
+
+	return rowsAffected, nil
+}
@@ -0,0 +1,17 @@
+package impl_test
+
+import (
+	"testing"
+
+	"bilingo/domains/system/repo"
+	impl "bilingo/domains/system/repo/db"
+	"bilingo/domains/system/repo/repotest"
+	"bilingo/server/testutil"
+)
+
+func TestAttachmentRepo(t *testing.T) {
+	repotest.TestAttachmentRepo(t, func(t *testing.T) repo.IAttachmentRepo {
+		testutil.NewDB(t)
+		return &impl.AttachmentRepo{}
+	})
+}
@@ -20,7 +20,7 @@ import (
 type CommentRepo struct{}
 
 func (r *CommentRepo) Get(ctx context.Context, id uint) (*models.Comment, error) {
-	conn, err := db.Default()
+	conn, err := db.Reader(ctx, domain.DBBinding)
 	if err != nil {
 		return nil, db.ConnError(err)
 	}
@@ -36,7 +36,7 @@ func (r *CommentRepo) Get(ctx context.Context, id uint) (*models.Comment, error)
 }
 
 func (r *CommentRepo) List(ctx context.Context, query *types.CommentListQuery) (*common.PaginatedResult[models.Comment], error) {
-	conn, err := db.Default()
+	conn, err := db.Reader(ctx, domain.DBBinding)
 	if err != nil {
 		return nil, db.ConnError(err)
 	}
@@ -73,8 +73,8 @@ func (r *CommentRepo) List(ctx context.Context, query *types.CommentListQuery) (
 	return &common.PaginatedResult[models.Comment]{Total: int(total), List: comments}, nil
 }
 
-func (r *CommentRepo) Create(ctx context.Context, data *types.CommentCreate) (*models.Comment, error) {
-	conn, err := db.Default()
+func (r *CommentRepo) Create(ctx context.Context, data *types.CommentCreate, author string) (*models.Comment, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
 	if err != nil {
 		return nil, db.ConnError(err)
 	}
@@ -88,11 +88,17 @@ func (r *CommentRepo) Create(ctx context.Context, data *types.CommentCreate) (*m
 			ObjectId:   data.ObjectId,
 		},
 		Content:  data.Content,
-		Author:   data.Author,
+		Author:   author,
 		ParentId: data.ParentId,
 	}
 
-	if err := gorm.G[models.Comment](conn).Create(ctx, comment); err != nil {
+	// Anonymous comments have no author, which is stored as NULL for the
+	// foreign key to user
+	var q gorm.CreateInterface[models.Comment] = gorm.G[models.Comment](conn)
+	if author == "" {
+		q = q.Omit(tables.Comment.Author.Column().Name)
+	}
+	if err := q.Create(ctx, comment); err != nil {
 		return nil, fmt.Errorf("failed to create comment: %w", err)
 	}
 
@@ -100,6 +106,7 @@ func (r *CommentRepo) Create(ctx context.Context, data *types.CommentCreate) (*m
 }
 
 func (r *CommentRepo) Update(ctx context.Context, id uint, data *types.CommentUpdate) (*models.Comment, error) {
+	ctx = db.UsePrimary(ctx)
 	comment, err := r.Get(ctx, id)
 	if err != nil {
 		return nil, err
@@ -117,7 +124,7 @@ func (r *CommentRepo) Update(ctx context.Context, id uint, data *types.CommentUp
 
 	updates = append(updates, tables.Comment.UpdatedAt.Set(time.Now()))
 
-	conn, err := db.Default()
+	conn, err := db.For(ctx, domain.DBBinding)
 	if err != nil {
 		return nil, db.ConnError(err)
 	}
@@ -133,7 +140,7 @@ func (r *CommentRepo) Update(ctx context.Context, id uint, data *types.CommentUp
 }
 
 func (r *CommentRepo) Delete(ctx context.Context, id uint) error {
-	conn, err := db.Default()
+	conn, err := db.For(ctx, domain.DBBinding)
 	if err != nil {
 		return db.ConnError(err)
 	}
@@ -147,3 +154,71 @@ func (r *CommentRepo) Delete(ctx context.Context, id uint) error {
 
 	return nil
 }
+
+func (r *CommentRepo) ListByAuthor(ctx context.Context, author string) ([]models.Comment, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	comments, err := gorm.G[models.Comment](conn).
+		Where(tables.Comment.Author.Eq(author)).
+		Order(tables.Comment.CreatedAt.Asc()).
+		Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments of author: %w", err)
+	}
+
+	return comments, nil
+}
+
+func (r *CommentRepo) DeleteByAuthor(ctx context.Context, author string) (int, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return 0, db.ConnError(err)
+	}
+
+	rowsAffected, err := gorm.G[models.Comment](conn).Where(tables.Comment.Author.Eq(author)).Delete(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete comments of author: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
+func (r *CommentRepo) DeleteByObjects(ctx context.Context, objectType string, objectIds []string) (int, error) {
+	if len(objectIds) == 0 {
+		return 0, nil
+	}
+
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return 0, db.ConnError(err)
+	}
+
+	rowsAffected, err := gorm.G[models.Comment](conn).
+		Where(tables.Comment.ObjectType.Eq(objectType), tables.Comment.ObjectId.In(objectIds...)).
+		Delete(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete comments of objects: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
+func (r *CommentRepo) ReassignAuthor(ctx context.Context, from string, to string) (int, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return 0, db.ConnError(err)
+	}
+
+	rowsAffected, err := gorm.G[models.Comment](conn).
+		Where(tables.Comment.Author.Eq(from)).
+		Set(tables.Comment.Author.Set(to)).
+		Update(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reassign comments: %w", err)
+	}
+
+	return rowsAffected, nil
+}
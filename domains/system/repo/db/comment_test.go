@@ -0,0 +1,18 @@
+package impl_test
+
+import (
+	"testing"
+
+	"bilingo/domains/system/repo"
+	impl "bilingo/domains/system/repo/db"
+	"bilingo/domains/system/repo/repotest"
+	"bilingo/server/testutil"
+)
+
+func TestCommentRepo(t *testing.T) {
+	repotest.TestCommentRepo(t, func(t *testing.T, authors ...string) repo.ICommentRepo {
+		testutil.NewDB(t)
+		testutil.CreateUsers(t, authors...)
+		return &impl.CommentRepo{}
+	})
+}
@@ -0,0 +1,191 @@
+package impl
+
+import (
+	"fmt"
+
+	domain "bilingo/domains/system"
+	"bilingo/domains/system/models"
+	"bilingo/domains/system/tables"
+	userModels "bilingo/domains/user/models"
+	userImpl "bilingo/domains/user/repo/db"
+	"bilingo/server/db"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	db.RegisterMigrations(
+		domain.DBBinding,
+		db.Migration{ID: "2026101900_system_create_comment_table", Up: db.CreateTableIfNotExists(&models.Comment{})},
+		db.Migration{ID: "2026101908_system_comment_author_user_id", Up: addCommentAuthorForeignKey},
+		db.Migration{ID: "2026101911_system_create_attachment_table", Up: db.CreateTableIfNotExists(&models.Attachment{})},
+	)
+	db.RegisterMigrations(
+		domain.OpLogDBBinding,
+		db.Migration{ID: "2026101900_system_create_op_log_table", Up: db.CreateTableIfNotExists(&models.OpLog{})},
+		db.Migration{ID: "2026101901_system_op_log_hash", Up: addOpLogHash},
+		db.Migration{ID: "2026101902_system_op_log_chain", Up: addOpLogChain},
+		db.Migration{ID: "2026101908_system_op_log_user_id", Up: rewriteOpLogUsers},
+	)
+}
+
+// addOpLogHash adds the content hash column to the op_log table, which is used
+// to find repeated operations instead of comparing every column.
+func addOpLogHash(tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	if !migrator.HasColumn(&models.OpLog{}, "Hash") {
+		if err := migrator.AddColumn(&models.OpLog{}, "Hash"); err != nil {
+			return err
+		}
+	}
+
+	var logs []models.OpLog
+	update := tx.Session(&gorm.Session{NewDB: true})
+	err := tx.Where("hash IS NULL OR hash = ''").FindInBatches(&logs, 500, func(_ *gorm.DB, _ int) error {
+		for _, log := range logs {
+			err := update.Model(&models.OpLog{}).Where("id = ?", log.ID).Update("hash", log.ContentHash()).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to compute op log hashes: %w", err)
+	}
+
+	if !migrator.HasIndex(&models.OpLog{}, "Hash") {
+		return migrator.CreateIndex(&models.OpLog{}, "Hash")
+	}
+	return nil
+}
+
+// addOpLogChain adds the columns of the hash-chained audit mode to op_log.
+func addOpLogChain(tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	for _, field := range []string{"Seq", "PrevHash", "ChainHash"} {
+		if migrator.HasColumn(&models.OpLog{}, field) {
+			continue
+		}
+		if err := migrator.AddColumn(&models.OpLog{}, field); err != nil {
+			return err
+		}
+	}
+
+	if !migrator.HasIndex(&models.OpLog{}, "Seq") {
+		return migrator.CreateIndex(&models.OpLog{}, "Seq")
+	}
+	return nil
+}
+
+// commentAuthor declares the relation between comments and their authors for
+// the foreign key, which the models leave out to keep the domains apart.
+type commentAuthor struct {
+	models.Comment
+	AuthorUser userModels.User `gorm:"foreignKey:Author;references:ID"`
+}
+
+// addCommentAuthorForeignKey replaces the authors of comments with their user
+// IDs, and adds the foreign key to user. Authors without an account, including
+// the names of anonymous comments, get a placeholder user.
+func addCommentAuthorForeignKey(tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	if migrator.HasConstraint(&commentAuthor{}, "AuthorUser") {
+		return nil
+	}
+
+	var authors []string
+	if err := tx.Model(&models.Comment{}).Distinct().Pluck("author", &authors).Error; err != nil {
+		return err
+	}
+	ids, err := userImpl.ResolveUserRefs(tx, authors, true)
+	if err != nil {
+		return err
+	}
+	for author, id := range ids {
+		if author == id {
+			continue
+		}
+		err := tx.Model(&models.Comment{}).Where("author = ?", author).UpdateColumn("author", id).Error
+		if err != nil {
+			return fmt.Errorf("failed to migrate comments of %s: %w", author, err)
+		}
+	}
+
+	// SQLite doesn't enforce the lengths of columns
+	if tx.Dialector.Name() != "sqlite" {
+		if err := migrator.AlterColumn(&models.Comment{}, "Author"); err != nil {
+			return err
+		}
+	}
+	if err := migrator.CreateConstraint(&commentAuthor{}, "AuthorUser"); err != nil {
+		return err
+	}
+
+	// SQLite rebuilds the table for the constraint, so the index comes after it
+	if !migrator.HasIndex(&models.Comment{}, "Author") {
+		return migrator.CreateIndex(&models.Comment{}, "Author")
+	}
+	return nil
+}
+
+// rewriteOpLogUsers replaces the emails of the users who performed operations,
+// or were operated on, with their user IDs. Oplogs in the audit chain are left
+// as they are, since they can't change, and so are the oplogs of users who
+// don't exist anymore. There's no foreign key, oplogs outlive their users.
+func rewriteOpLogUsers(tx *gorm.DB) error {
+	var refs []string
+	err := tx.Model(&models.OpLog{}).
+		Where(tables.OpLog.Seq.IsNull(), tables.OpLog.User.IsNotNull()).
+		Distinct().Pluck("user", &refs).Error
+	if err != nil {
+		return err
+	}
+	var objectIds []string
+	err = tx.Model(&models.OpLog{}).
+		Where(tables.OpLog.Seq.IsNull(), tables.OpLog.ObjectType.Eq("user")).
+		Distinct().Pluck("object_id", &objectIds).Error
+	if err != nil {
+		return err
+	}
+
+	ids, err := userImpl.ResolveUserRefs(tx, append(refs, objectIds...), false)
+	if err != nil {
+		return err
+	}
+
+	var logs []models.OpLog
+	update := tx.Session(&gorm.Session{NewDB: true})
+	err = tx.Where(tables.OpLog.Seq.IsNull()).FindInBatches(&logs, 500, func(_ *gorm.DB, _ int) error {
+		for _, log := range logs {
+			changed := false
+			if log.User != nil && ids[*log.User] != "" && ids[*log.User] != *log.User {
+				id := ids[*log.User]
+				log.User = &id
+				changed = true
+			}
+			if log.ObjectType == "user" && ids[log.ObjectId] != "" && ids[log.ObjectId] != log.ObjectId {
+				log.ObjectId = ids[log.ObjectId]
+				changed = true
+			}
+			if !changed {
+				continue
+			}
+
+			err := update.Model(&models.OpLog{}).Where(tables.OpLog.ID.Eq(log.ID)).UpdateColumns(map[string]any{
+				"user":      log.User,
+				"object_id": log.ObjectId,
+				"hash":      log.ContentHash(),
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to migrate op log users: %w", err)
+	}
+
+	return nil
+}
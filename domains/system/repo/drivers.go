@@ -0,0 +1,23 @@
+package repo
+
+import (
+	"bilingo/config"
+	impl "bilingo/domains/system/repo/db"
+	"bilingo/domains/system/repo/memory"
+	"bilingo/server/app"
+)
+
+// Provide provides the repositories of the driver in the configuration of the
+// app, which keep their records in memory rather than the databases with the
+// memory driver, see config.RepoMemory. The oplogs are always kept in a
+// database.
+func Provide(a *app.App) {
+	if a.Config.Repo == config.RepoMemory {
+		app.Provide[ICommentRepo](a, &memory.CommentRepo{})
+		app.Provide[IAttachmentRepo](a, &memory.AttachmentRepo{})
+		return
+	}
+
+	app.Provide[ICommentRepo](a, &impl.CommentRepo{})
+	app.Provide[IAttachmentRepo](a, &impl.AttachmentRepo{})
+}
@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	domain "bilingo/domains/system"
+	"bilingo/domains/system/models"
+	"bilingo/server/memdb"
+)
+
+type AttachmentRepo struct {
+	attachments memdb.Table[uint, models.Attachment]
+}
+
+func (r *AttachmentRepo) Get(ctx context.Context, id uint) (*models.Attachment, error) {
+	attachment, ok := r.attachments.Get(id)
+	if !ok {
+		return nil, domain.ErrAttachmentNotFound
+	}
+
+	return &attachment, nil
+}
+
+func (r *AttachmentRepo) List(ctx context.Context, objectType string, objectId string) ([]models.Attachment, error) {
+	return r.attachments.Find(func(attachment models.Attachment) bool {
+		return attachment.ObjectType == objectType && attachment.ObjectId == objectId
+	}), nil
+}
+
+func (r *AttachmentRepo) Create(ctx context.Context, attachment *models.Attachment) error {
+	if attachment.ID == 0 {
+		attachment.ID = r.attachments.NextID()
+	}
+	if attachment.CreatedAt.IsZero() {
+		attachment.CreatedAt = time.Now()
+	}
+	if !r.attachments.Insert(attachment.ID, *attachment) {
+		return fmt.Errorf("failed to create attachment: attachment %d exists", attachment.ID)
+	}
+
+	return nil
+}
+
+func (r *AttachmentRepo) Delete(ctx context.Context, id uint) error {
+	if !r.attachments.Delete(id) {
+		return domain.ErrAttachmentNotFound
+	}
+
+	return nil
+}
+
+func (r *AttachmentRepo) LockByHash(ctx context.Context, hash string) (int, error) {
+	return r.attachments.Count(func(attachment models.Attachment) bool {
+		return attachment.Hash == hash
+	}), nil
+}
+
+func (r *AttachmentRepo) ListByUploader(ctx context.Context, uploadedBy string) ([]models.Attachment, error) {
+	return r.attachments.Find(func(attachment models.Attachment) bool {
+		return attachment.UploadedBy == uploadedBy
+	}), nil
+}
+
+func (r *AttachmentRepo) ListByObjects(ctx context.Context, objectType string, objectIds []string) ([]models.Attachment, error) {
+	if len(objectIds) == 0 {
+		return nil, nil
+	}
+
+	return r.attachments.Find(func(attachment models.Attachment) bool {
+		return attachment.ObjectType == objectType && slices.Contains(objectIds, attachment.ObjectId)
+	}), nil
+}
+
+func (r *AttachmentRepo) ReassignUploader(ctx context.Context, from string, to string) (int, error) {
+	return r.attachments.UpdateWhere(
+		func(attachment models.Attachment) bool { return attachment.UploadedBy == from },
+		func(attachment *models.Attachment) { attachment.UploadedBy = to },
+	), nil
+}
@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"bilingo/domains/system/repo"
+	"bilingo/domains/system/repo/memory"
+	"bilingo/domains/system/repo/repotest"
+)
+
+func TestAttachmentRepo(t *testing.T) {
+	repotest.TestAttachmentRepo(t, func(t *testing.T) repo.IAttachmentRepo {
+		return &memory.AttachmentRepo{}
+	})
+}
@@ -0,0 +1,127 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"bilingo/common"
+	domain "bilingo/domains/system"
+	"bilingo/domains/system/models"
+	"bilingo/domains/system/types"
+	"bilingo/server/memdb"
+)
+
+type CommentRepo struct {
+	comments memdb.Table[uint, models.Comment]
+}
+
+func (r *CommentRepo) Get(ctx context.Context, id uint) (*models.Comment, error) {
+	comment, ok := r.comments.Get(id)
+	if !ok {
+		return nil, domain.ErrCommentNotFound
+	}
+
+	return &comment, nil
+}
+
+func (r *CommentRepo) List(ctx context.Context, query *types.CommentListQuery) (*common.PaginatedResult[models.Comment], error) {
+	comments := r.comments.Find(func(comment models.Comment) bool {
+		if comment.ObjectType != query.ObjectType || comment.ObjectId != query.ObjectId {
+			return false
+		}
+		if query.Author != nil && *query.Author != "" && comment.Author != *query.Author {
+			return false
+		}
+		if query.ParentId != nil && (comment.ParentId == nil || *comment.ParentId != *query.ParentId) {
+			return false
+		}
+		return true
+	})
+	sortComments(comments)
+
+	return memdb.Paginate(comments, query.PaginatedQuery), nil
+}
+
+func (r *CommentRepo) Create(ctx context.Context, data *types.CommentCreate, author string) (*models.Comment, error) {
+	now := time.Now()
+	comment := &models.Comment{
+		ID:        r.comments.NextID(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		ObjectInfo: types.ObjectInfo{
+			ObjectType: data.ObjectType,
+			ObjectId:   data.ObjectId,
+		},
+		Content:  data.Content,
+		Author:   author,
+		ParentId: data.ParentId,
+	}
+	r.comments.Put(comment.ID, *comment)
+
+	return comment, nil
+}
+
+func (r *CommentRepo) Update(ctx context.Context, id uint, data *types.CommentUpdate) (*models.Comment, error) {
+	var updated models.Comment
+	found := false
+	r.comments.Update(id, func(comment *models.Comment) bool {
+		found = true
+		changed := false
+		if data.Content != nil && *data.Content != "" && *data.Content != comment.Content {
+			comment.Content = *data.Content
+			comment.UpdatedAt = time.Now()
+			changed = true
+		}
+		updated = *comment
+		return changed
+	})
+	if !found {
+		return nil, domain.ErrCommentNotFound
+	}
+
+	return &updated, nil
+}
+
+func (r *CommentRepo) Delete(ctx context.Context, id uint) error {
+	if !r.comments.Delete(id) {
+		return domain.ErrCommentNotFound
+	}
+
+	return nil
+}
+
+func (r *CommentRepo) ListByAuthor(ctx context.Context, author string) ([]models.Comment, error) {
+	comments := r.comments.Find(func(comment models.Comment) bool {
+		return comment.Author == author
+	})
+	sortComments(comments)
+
+	return comments, nil
+}
+
+func (r *CommentRepo) DeleteByAuthor(ctx context.Context, author string) (int, error) {
+	return r.comments.DeleteWhere(func(comment models.Comment) bool {
+		return comment.Author == author
+	}), nil
+}
+
+func (r *CommentRepo) DeleteByObjects(ctx context.Context, objectType string, objectIds []string) (int, error) {
+	return r.comments.DeleteWhere(func(comment models.Comment) bool {
+		return comment.ObjectType == objectType && slices.Contains(objectIds, comment.ObjectId)
+	}), nil
+}
+
+func (r *CommentRepo) ReassignAuthor(ctx context.Context, from string, to string) (int, error) {
+	return r.comments.UpdateWhere(
+		func(comment models.Comment) bool { return comment.Author == from },
+		func(comment *models.Comment) { comment.Author = to },
+	), nil
+}
+
+// sortComments sorts the comments by the time of creation, oldest first.
+func sortComments(comments []models.Comment) {
+	slices.SortStableFunc(comments, func(a, b models.Comment) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+}
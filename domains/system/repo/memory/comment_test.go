@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"bilingo/domains/system/repo"
+	"bilingo/domains/system/repo/memory"
+	"bilingo/domains/system/repo/repotest"
+)
+
+func TestCommentRepo(t *testing.T) {
+	repotest.TestCommentRepo(t, func(t *testing.T, authors ...string) repo.ICommentRepo {
+		return &memory.CommentRepo{}
+	})
+}
@@ -0,0 +1,114 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	domain "bilingo/domains/system"
+	"bilingo/domains/system/models"
+	"bilingo/domains/system/repo"
+	"bilingo/domains/system/types"
+)
+
+// TestAttachmentRepo tests the attachment repository against the contract.
+func TestAttachmentRepo(t *testing.T, newRepo func(t *testing.T) repo.IAttachmentRepo) {
+	ctx := context.Background()
+
+	create := func(t *testing.T, r repo.IAttachmentRepo, objectId string, name string, hash string, uploadedBy string) *models.Attachment {
+		t.Helper()
+		attachment := &models.Attachment{
+			ObjectInfo:  types.ObjectInfo{ObjectType: "article", ObjectId: objectId},
+			Name:        name,
+			ContentType: "text/plain",
+			Size:        int64(len(name)),
+			Hash:        hash,
+			UploadedBy:  uploadedBy,
+		}
+		if err := r.Create(ctx, attachment); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		return attachment
+	}
+
+	names := func(attachments []models.Attachment) []string {
+		var result []string
+		for _, attachment := range attachments {
+			result = append(result, attachment.Name)
+		}
+		return result
+	}
+
+	t.Run("CreateAndGet", func(t *testing.T) {
+		r := newRepo(t)
+		created := create(t, r, "1", "a.txt", "hash-a", Author1)
+		if created.ID == 0 || created.CreatedAt.IsZero() {
+			t.Fatalf("Create: got ID %d and time %v, want both set", created.ID, created.CreatedAt)
+		}
+
+		attachment, err := r.Get(ctx, created.ID)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if attachment.Name != "a.txt" || attachment.Hash != "hash-a" || attachment.UploadedBy != Author1 {
+			t.Errorf("Get: got %+v", attachment)
+		}
+
+		if _, err := r.Get(ctx, created.ID+100); !errors.Is(err, domain.ErrAttachmentNotFound) {
+			t.Errorf("Get of a missing attachment: got %v, want ErrAttachmentNotFound", err)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		r := newRepo(t)
+		create(t, r, "1", "a.txt", "hash-a", Author1)
+		create(t, r, "2", "b.txt", "hash-b", Author2)
+		create(t, r, "1", "c.txt", "hash-a", Author2)
+
+		attachments, err := r.List(ctx, "article", "1")
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		assertContents(t, "List", names(attachments), "a.txt", "c.txt")
+
+		attachments, err = r.ListByUploader(ctx, Author2)
+		if err != nil {
+			t.Fatalf("ListByUploader: %v", err)
+		}
+		assertContents(t, "ListByUploader", names(attachments), "b.txt", "c.txt")
+
+		attachments, err = r.ListByObjects(ctx, "article", []string{"2", "3"})
+		if err != nil {
+			t.Fatalf("ListByObjects: %v", err)
+		}
+		assertContents(t, "ListByObjects", names(attachments), "b.txt")
+
+		if n, err := r.LockByHash(ctx, "hash-a"); err != nil || n != 2 {
+			t.Errorf("LockByHash: got %d, %v, want 2", n, err)
+		}
+	})
+
+	t.Run("DeleteAndReassign", func(t *testing.T) {
+		r := newRepo(t)
+		a := create(t, r, "1", "a.txt", "hash-a", Author1)
+		create(t, r, "1", "b.txt", "hash-b", Author1)
+
+		if err := r.Delete(ctx, a.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if err := r.Delete(ctx, a.ID); !errors.Is(err, domain.ErrAttachmentNotFound) {
+			t.Errorf("Delete of a missing attachment: got %v, want ErrAttachmentNotFound", err)
+		}
+		if n, err := r.LockByHash(ctx, "hash-a"); err != nil || n != 0 {
+			t.Errorf("LockByHash after Delete: got %d, %v, want 0", n, err)
+		}
+
+		if n, err := r.ReassignUploader(ctx, Author1, Author2); err != nil || n != 1 {
+			t.Errorf("ReassignUploader: got %d, %v, want 1", n, err)
+		}
+		attachments, err := r.ListByUploader(ctx, Author2)
+		if err != nil || len(attachments) != 1 {
+			t.Errorf("ListByUploader after ReassignUploader: got %d, %v, want 1", len(attachments), err)
+		}
+	})
+}
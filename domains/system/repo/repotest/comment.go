@@ -0,0 +1,167 @@
+// Package repotest is the contract the implementations of the comment and
+// attachment repositories must fulfil, which is run against each of them by
+// their tests.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	domain "bilingo/domains/system"
+	"bilingo/domains/system/models"
+	"bilingo/domains/system/repo"
+	"bilingo/domains/system/types"
+)
+
+// NewCommentRepo creates an empty repository for a test, in which the users of
+// the authors exist if the implementation checks the references.
+type NewCommentRepo func(t *testing.T, authors ...string) repo.ICommentRepo
+
+// The authors of the comments created by the tests.
+const (
+	Author1 = "00000000-0000-4000-8000-000000000001"
+	Author2 = "00000000-0000-4000-8000-000000000002"
+)
+
+// TestCommentRepo tests the comment repository against the contract.
+func TestCommentRepo(t *testing.T, newRepo NewCommentRepo) {
+	ctx := context.Background()
+
+	create := func(t *testing.T, r repo.ICommentRepo, objectId string, content string, author string, parentId *uint) *models.Comment {
+		t.Helper()
+		comment, err := r.Create(ctx, &types.CommentCreate{
+			ObjectInfo: types.ObjectInfo{ObjectType: "article", ObjectId: objectId},
+			Content:    content,
+			ParentId:   parentId,
+		}, author)
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond) // Orders the comments by the time of creation
+		return comment
+	}
+
+	contents := func(comments []models.Comment) []string {
+		var result []string
+		for _, comment := range comments {
+			result = append(result, comment.Content)
+		}
+		return result
+	}
+
+	t.Run("CreateAndGet", func(t *testing.T) {
+		r := newRepo(t, Author1)
+		created := create(t, r, "1", "Hello", Author1, nil)
+		if created.ID == 0 || created.CreatedAt.IsZero() {
+			t.Fatalf("Create: got ID %d and time %v, want both set", created.ID, created.CreatedAt)
+		}
+
+		comment, err := r.Get(ctx, created.ID)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if comment.Content != "Hello" || comment.Author != Author1 || comment.ObjectType != "article" || comment.ObjectId != "1" {
+			t.Errorf("Get: got %+v", comment)
+		}
+
+		if _, err := r.Get(ctx, created.ID+100); !errors.Is(err, domain.ErrCommentNotFound) {
+			t.Errorf("Get of a missing comment: got %v, want ErrCommentNotFound", err)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		r := newRepo(t, Author1, Author2)
+		first := create(t, r, "1", "First", Author1, nil)
+		create(t, r, "1", "Reply", Author2, &first.ID)
+		create(t, r, "1", "Second", Author2, nil)
+		create(t, r, "2", "Elsewhere", Author1, nil)
+
+		list := func(query types.CommentListQuery) []string {
+			t.Helper()
+			query.ObjectType, query.ObjectId = "article", "1"
+			query.Page, query.PageSize = 1, 10
+			result, err := r.List(ctx, &query)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if result.Total != len(result.List) {
+				t.Errorf("List: got total %d for %d comments", result.Total, len(result.List))
+			}
+			return contents(result.List)
+		}
+
+		assertContents(t, "List", list(types.CommentListQuery{}), "First", "Reply", "Second")
+		author := Author2
+		assertContents(t, "List by author", list(types.CommentListQuery{Author: &author}), "Reply", "Second")
+		assertContents(t, "List by parent", list(types.CommentListQuery{ParentId: &first.ID}), "Reply")
+
+		comments, err := r.ListByAuthor(ctx, Author1)
+		if err != nil {
+			t.Fatalf("ListByAuthor: %v", err)
+		}
+		assertContents(t, "ListByAuthor", contents(comments), "First", "Elsewhere")
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		r := newRepo(t, Author1)
+		created := create(t, r, "1", "Typo", Author1, nil)
+
+		content := "Fixed"
+		comment, err := r.Update(ctx, created.ID, &types.CommentUpdate{Content: &content})
+		if err != nil || comment.Content != "Fixed" {
+			t.Fatalf("Update: got %+v, %v", comment, err)
+		}
+		if comment, err = r.Get(ctx, created.ID); err != nil || comment.Content != "Fixed" {
+			t.Errorf("Get after Update: got %+v, %v", comment, err)
+		}
+
+		if _, err := r.Update(ctx, created.ID+100, &types.CommentUpdate{Content: &content}); !errors.Is(err, domain.ErrCommentNotFound) {
+			t.Errorf("Update of a missing comment: got %v, want ErrCommentNotFound", err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		r := newRepo(t, Author1)
+		created := create(t, r, "1", "Doomed", Author1, nil)
+
+		if err := r.Delete(ctx, created.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := r.Get(ctx, created.ID); !errors.Is(err, domain.ErrCommentNotFound) {
+			t.Errorf("Get after Delete: got %v, want ErrCommentNotFound", err)
+		}
+		if err := r.Delete(ctx, created.ID); !errors.Is(err, domain.ErrCommentNotFound) {
+			t.Errorf("Delete of a missing comment: got %v, want ErrCommentNotFound", err)
+		}
+	})
+
+	t.Run("Bulk", func(t *testing.T) {
+		r := newRepo(t, Author1, Author2)
+		create(t, r, "1", "One", Author1, nil)
+		create(t, r, "2", "Two", Author1, nil)
+		create(t, r, "3", "Three", Author2, nil)
+
+		if n, err := r.DeleteByObjects(ctx, "article", nil); err != nil || n != 0 {
+			t.Errorf("DeleteByObjects of no objects: got %d, %v, want 0", n, err)
+		}
+		if n, err := r.DeleteByObjects(ctx, "article", []string{"1", "4"}); err != nil || n != 1 {
+			t.Errorf("DeleteByObjects: got %d, %v, want 1", n, err)
+		}
+		if n, err := r.ReassignAuthor(ctx, Author1, Author2); err != nil || n != 1 {
+			t.Errorf("ReassignAuthor: got %d, %v, want 1", n, err)
+		}
+		if n, err := r.DeleteByAuthor(ctx, Author2); err != nil || n != 2 {
+			t.Errorf("DeleteByAuthor: got %d, %v, want 2", n, err)
+		}
+	})
+}
+
+func assertContents(t *testing.T, name string, got []string, want ...string) {
+	t.Helper()
+	if !slices.Equal(got, want) {
+		t.Errorf("%s: got %v, want %v", name, got, want)
+	}
+}
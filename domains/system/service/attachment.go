@@ -0,0 +1,260 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	domain "bilingo/domains/system"
+	"bilingo/domains/system/models"
+	"bilingo/domains/system/repo"
+	"bilingo/domains/system/types"
+	"bilingo/server/app"
+	"bilingo/server/db"
+	"bilingo/server/storage"
+)
+
+func init() {
+	RegisterObjectOwner("attachment", func(ctx context.Context, objectId string) (string, error) {
+		id, err := strconv.ParseUint(objectId, 10, 32)
+		if err != nil {
+			return "", fmt.Errorf("invalid attachment ID: %w", err)
+		}
+
+		attachment, err := repo.Attachments(ctx).Get(ctx, uint(id))
+		if err != nil {
+			return "", err
+		}
+		return attachment.UploadedBy, nil
+	})
+
+	RegisterUserData("attachment", UserDataHandler{
+		Export: exportUserAttachments,
+		Delete: func(ctx context.Context, userId string) error {
+			attachments, err := repo.Attachments(ctx).ListByUploader(ctx, userId)
+			if err != nil {
+				return err
+			}
+			return deleteAttachments(ctx, attachments)
+		},
+		Reassign: func(ctx context.Context, userId string, toUserId string) error {
+			_, err := repo.Attachments(ctx).ReassignUploader(ctx, userId, toUserId)
+			return err
+		},
+	})
+}
+
+// exportUserAttachments writes the attachments uploaded by the user to the zip
+// archive, the records to attachments.jsonl and the files to the attachments
+// directory.
+func exportUserAttachments(ctx context.Context, userId string, zw *zip.Writer) error {
+	attachments, err := repo.Attachments(ctx).ListByUploader(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	w, err := zw.Create("attachments.jsonl")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	for i := range attachments {
+		if err := enc.Encode(&attachments[i]); err != nil {
+			return err
+		}
+	}
+
+	for _, attachment := range attachments {
+		obj, err := storage.FromContext(ctx).Get(ctx, attachmentKey(attachment.Hash))
+		if err != nil {
+			return fmt.Errorf("failed to read attachment %d: %w", attachment.ID, err)
+		}
+		w, err := zw.Create(fmt.Sprintf("attachments/%d/%s", attachment.ID, attachment.Name))
+		if err == nil {
+			_, err = io.Copy(w, obj)
+		}
+		obj.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAttachment returns the attachment without its URL, see SignAttachment.
+func GetAttachment(ctx context.Context, id uint) (*models.Attachment, error) {
+	return repo.Attachments(ctx).Get(ctx, id)
+}
+
+// SignAttachment sets the URL of the attachment to a fresh signed one, which
+// gives access to the file to anyone who has it, so the user should be checked
+// with CanReadObject first.
+func SignAttachment(ctx context.Context, attachment *models.Attachment) error {
+	expiry := app.Config(ctx).Attachment.UrlExpiry
+	url, err := storage.FromContext(ctx).SignedURL(ctx, attachmentKey(attachment.Hash), expiry, attachment.Name)
+	if err != nil {
+		return fmt.Errorf("failed to sign attachment URL: %w", err)
+	}
+	attachment.URL = url
+	return nil
+}
+
+// ListAttachments returns the attachments of the object with signed URLs, the
+// user should be checked with CanReadObject first.
+func ListAttachments(ctx context.Context, query types.ObjectInfo) ([]models.Attachment, error) {
+	attachments, err := repo.Attachments(ctx).List(ctx, query.ObjectType, query.ObjectId)
+	if err != nil {
+		return nil, err
+	}
+	for i := range attachments {
+		if err := SignAttachment(ctx, &attachments[i]); err != nil {
+			return nil, err
+		}
+	}
+	return attachments, nil
+}
+
+// UploadAttachment attaches the file to the object. The type of the content is
+// sniffed and checked against Attachment.ContentTypes of the configuration, and
+// content which is stored already isn't stored again.
+func UploadAttachment(ctx context.Context, info types.ObjectInfo, name string, r io.Reader, uploadedBy string) (*models.Attachment, error) {
+	cfg := app.Config(ctx).Attachment
+	data, err := io.ReadAll(io.LimitReader(r, cfg.MaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	} else if int64(len(data)) > cfg.MaxSize {
+		return nil, fmt.Errorf("%w: larger than %d bytes", domain.ErrInvalidAttachment, cfg.MaxSize)
+	} else if len(data) == 0 {
+		return nil, fmt.Errorf("%w: the file is empty", domain.ErrInvalidAttachment)
+	}
+
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(data))
+	if err != nil || !allowedContentType(contentType, cfg.ContentTypes) {
+		return nil, fmt.Errorf("%w: %s is not allowed", domain.ErrInvalidAttachment, contentType)
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	attachment := &models.Attachment{
+		CreatedAt:   time.Now(),
+		ObjectInfo:  info,
+		Name:        attachmentName(name),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Hash:        hash,
+		UploadedBy:  uploadedBy,
+	}
+	err = db.Transaction(ctx, domain.DBBinding, func(ctx context.Context) error {
+		if err := repo.Attachments(ctx).Create(ctx, attachment); err != nil {
+			return err
+		}
+
+		// The other attachments of the content are locked, so that its file
+		// isn't deleted along with them until this one is committed
+		count, err := repo.Attachments(ctx).LockByHash(ctx, hash)
+		if err != nil || count > 1 {
+			return err
+		}
+		return storage.FromContext(ctx).Put(ctx, attachmentKey(hash), bytes.NewReader(data), contentType)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := SignAttachment(ctx, attachment); err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+func DeleteAttachment(ctx context.Context, id uint) error {
+	attachment, err := repo.Attachments(ctx).Get(db.UsePrimary(ctx), id)
+	if err != nil {
+		return err
+	}
+	return deleteAttachments(ctx, []models.Attachment{*attachment})
+}
+
+// DeleteAttachmentsOf deletes all attachments of the given objects, e.g. when
+// the objects are deleted.
+func DeleteAttachmentsOf(ctx context.Context, objectType string, objectIds []string) error {
+	attachments, err := repo.Attachments(ctx).ListByObjects(ctx, objectType, objectIds)
+	if err != nil {
+		return err
+	}
+	return deleteAttachments(ctx, attachments)
+}
+
+// deleteAttachments deletes the attachments, and the stored files no other
+// attachments share. Failures to delete the files are only logged, since the
+// files aren't referred to anymore.
+func deleteAttachments(ctx context.Context, attachments []models.Attachment) error {
+	for _, attachment := range attachments {
+		err := db.Transaction(ctx, domain.DBBinding, func(ctx context.Context) error {
+			if err := repo.Attachments(ctx).Delete(ctx, attachment.ID); err != nil {
+				return err
+			}
+
+			// The file is deleted while the attachments of the content are
+			// locked, so that uploads of it wait and store it again
+			count, err := repo.Attachments(ctx).LockByHash(ctx, attachment.Hash)
+			if err != nil || count > 0 {
+				return err
+			}
+			if err := storage.FromContext(ctx).Delete(ctx, attachmentKey(attachment.Hash)); err != nil {
+				log.Printf("failed to delete attachment file %s: %v", attachment.Hash, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachmentKey returns the storage key of the content of the hash, which is
+// private, so that the files are only available at the signed URLs.
+func attachmentKey(hash string) string {
+	return storage.PrivatePrefix + "attachments/" + hash[:2] + "/" + hash
+}
+
+// attachmentName returns the base name of the uploaded file name, limited to
+// 255 characters.
+func attachmentName(name string) string {
+	name = strings.TrimSpace(path.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	if utf8.RuneCountInString(name) > 255 {
+		name = string([]rune(name)[:255])
+	}
+	return name
+}
+
+// allowedContentType reports whether the content type matches any of the
+// allowed ones, which may be wildcards like `image/*`.
+func allowedContentType(contentType string, allowed []string) bool {
+	for _, pattern := range allowed {
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+			if strings.HasPrefix(contentType, prefix+"/") {
+				return true
+			}
+		} else if contentType == pattern {
+			return true
+		}
+	}
+	return false
+}
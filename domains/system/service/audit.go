@@ -0,0 +1,246 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	domain "bilingo/domains/system"
+	"bilingo/domains/system/models"
+	"bilingo/domains/system/tables"
+	"bilingo/domains/system/types"
+	"bilingo/server/db"
+
+	"gorm.io/gorm"
+)
+
+// appendOpLogChain appends the oplogs to the audit chain as they are, each one
+// is hashed along with the hash of the previous entry.
+func appendOpLogChain(ctx context.Context, conn *gorm.DB, batch []*types.OpLogData) error {
+	logs := make([]models.OpLog, 0, len(batch))
+	for _, data := range batch {
+		log, err := newOpLog(data)
+		if err != nil {
+			return err
+		}
+		// Keep the precision every supported database can store, so that the
+		// content read back hashes the same
+		log.Timestamp = log.Timestamp.UTC().Truncate(time.Millisecond)
+		logs = append(logs, *log)
+	}
+
+	// Another process may append to the chain at the same time, in which case
+	// the unique seq conflicts, or SQLite refuses to write after the tail read
+	// is stale, and the batch is retried on the new tail.
+	var err error
+	for range 3 {
+		err = conn.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			seq, prevHash, err := getChainTail(ctx, tx)
+			if err != nil {
+				return err
+			}
+
+			for i := range logs {
+				entrySeq, entryPrevHash := seq+1, prevHash
+				logs[i].Seq = &entrySeq
+				logs[i].PrevHash = &entryPrevHash
+
+				content, err := logs[i].ChainContent()
+				if err != nil {
+					return err
+				}
+
+				hash := models.ChainHashOf(entryPrevHash, content)
+				logs[i].ChainHash = &hash
+				seq, prevHash = entrySeq, hash
+			}
+
+			return gorm.G[models.OpLog](tx).CreateInBatches(ctx, &logs, 100)
+		})
+		if err == nil || ctx.Err() != nil {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to append op logs to the chain: %w", err)
+	}
+
+	return nil
+}
+
+func getChainTail(ctx context.Context, tx *gorm.DB) (uint64, string, error) {
+	tail, err := gorm.G[models.OpLog](tx).
+		Where(tables.OpLog.Seq.IsNotNull()).
+		Order(tables.OpLog.Seq.Desc()).
+		Limit(1).
+		Find(ctx)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to find the tail of the chain: %w", err)
+	} else if len(tail) == 0 {
+		return 0, "", nil
+	} else if tail[0].ChainHash == nil {
+		return 0, "", errors.New("the tail of the chain is incomplete")
+	}
+
+	return *tail[0].Seq, *tail[0].ChainHash, nil
+}
+
+// eachChainLink walks through the audit chain in the database in order.
+func eachChainLink(ctx context.Context, fn func(link *types.OpLogChainLink) error) error {
+	conn, err := db.For(ctx, domain.OpLogDBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	var lastSeq uint64
+	for {
+		logs, err := gorm.G[models.OpLog](conn).
+			Where(tables.OpLog.Seq.Gt(lastSeq)).
+			Order(tables.OpLog.Seq.Asc()).
+			Limit(500).
+			Find(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read the chain: %w", err)
+		} else if len(logs) == 0 {
+			return nil
+		}
+
+		for i := range logs {
+			content, err := logs[i].ChainContent()
+			if err != nil {
+				return err
+			}
+
+			link := &types.OpLogChainLink{
+				Seq:     *logs[i].Seq,
+				Content: content,
+			}
+			if logs[i].PrevHash != nil {
+				link.PrevHash = *logs[i].PrevHash
+			}
+			if logs[i].ChainHash != nil {
+				link.Hash = *logs[i].ChainHash
+			}
+
+			if err := fn(link); err != nil {
+				return err
+			}
+			lastSeq = link.Seq
+		}
+	}
+}
+
+// ExportOpLogChain writes the audit chain to the writer in JSON Lines, one
+// OpLogChainLink per line, which can be verified offline.
+func ExportOpLogChain(ctx context.Context, w io.Writer) error {
+	buf := bufio.NewWriter(w)
+	enc := json.NewEncoder(buf)
+	if err := eachChainLink(ctx, func(link *types.OpLogChainLink) error {
+		return enc.Encode(link)
+	}); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+// VerifyOpLogChain walks through the audit chain in the database and reports
+// the first entry that breaks it, since its content has been altered or its
+// previous entries have been removed or reordered. Entries cut off the end of
+// the chain leave it valid, which can only be detected against the seq and
+// hash of the last entry kept elsewhere.
+func VerifyOpLogChain(ctx context.Context) (*types.OpLogChainReport, error) {
+	v := &chainVerifier{}
+	err := eachChainLink(ctx, func(link *types.OpLogChainLink) error {
+		if !v.check(link) {
+			return errChainBroken
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errChainBroken) {
+		return nil, err
+	}
+
+	return v.result(), nil
+}
+
+// VerifyOpLogChainExport verifies an audit chain exported by ExportOpLogChain,
+// which can't tell either if the export was cut off at the end.
+func VerifyOpLogChainExport(r io.Reader) (*types.OpLogChainReport, error) {
+	v := &chainVerifier{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var link types.OpLogChainLink
+		if err := json.Unmarshal(scanner.Bytes(), &link); err != nil {
+			return nil, fmt.Errorf("malformed chain link after seq %d: %w", v.prevSeq, err)
+		}
+
+		// The content must describe the same entry as the link
+		var entry types.OpLogChainEntry
+		if err := json.Unmarshal([]byte(link.Content), &entry); err != nil || entry.Seq != link.Seq {
+			v.fail(link.Seq, "content doesn't match the entry")
+			return v.result(), nil
+		}
+
+		if !v.check(&link) {
+			return v.result(), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return v.result(), nil
+}
+
+var errChainBroken = errors.New("chain broken")
+
+type chainVerifier struct {
+	checked  int
+	prevSeq  uint64
+	prevHash string
+	brokenAt *uint64
+	reason   *string
+}
+
+func (v *chainVerifier) check(link *types.OpLogChainLink) bool {
+	v.checked++
+
+	switch {
+	case link.Seq != v.prevSeq+1:
+		v.fail(link.Seq, fmt.Sprintf("expected seq %d, the entries in between are missing", v.prevSeq+1))
+	case link.PrevHash != v.prevHash:
+		v.fail(link.Seq, "previous hash doesn't match the previous entry")
+	case link.Hash != models.ChainHashOf(link.PrevHash, link.Content):
+		v.fail(link.Seq, "hash doesn't match the content")
+	default:
+		v.prevSeq = link.Seq
+		v.prevHash = link.Hash
+		return true
+	}
+
+	return false
+}
+
+func (v *chainVerifier) fail(seq uint64, reason string) {
+	v.brokenAt = &seq
+	v.reason = &reason
+}
+
+func (v *chainVerifier) result() *types.OpLogChainReport {
+	return &types.OpLogChainReport{
+		Valid:    v.brokenAt == nil,
+		Checked:  v.checked,
+		BrokenAt: v.brokenAt,
+		Reason:   v.reason,
+	}
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"bilingo/config"
+	"bilingo/domains/system/models"
+	"bilingo/domains/system/types"
+	"bilingo/server/app"
+	"bilingo/server/db"
+
+	"gorm.io/gorm"
+)
+
+// openChainDB opens a connection to a SQLite database in the directory, whose
+// journal lets other connections write while it reads, like the other
+// databases do.
+func openChainDB(t *testing.T, dir string) *gorm.DB {
+	t.Helper()
+	conn, err := db.CreateConn("sqlite://"+filepath.Join(dir, "oplog.db"), config.DBConfig{
+		Params:   map[string]string{"_journal_mode": "WAL", "_busy_timeout": "5000"},
+		LogLevel: config.LogSilent,
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := conn.AutoMigrate(&models.OpLog{}); err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := conn.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	return conn
+}
+
+// newChainContext returns the context of an app in the audit mode whose oplogs
+// are in the database.
+func newChainContext(conn *gorm.DB) context.Context {
+	cfg := config.ForEnv("test")
+	cfg.OpLog.Chained = true
+	a := app.New(cfg)
+	r := db.NewRegistry(cfg)
+	r.Set(db.DefaultName, conn)
+	app.Provide(a, r)
+	return a.Context(context.Background())
+}
+
+func chainOpLog(operation string) *types.OpLogData {
+	return &types.OpLogData{
+		ObjectInfo: types.ObjectInfo{ObjectType: "article", ObjectId: "1"},
+		OpLogBase:  types.OpLogBase{Operation: operation, Result: "success"},
+		NewData:    map[string]string{"title": operation},
+	}
+}
+
+// appendChain appends n oplogs to the chain, in batches of two.
+func appendChain(t *testing.T, ctx context.Context, n int) {
+	t.Helper()
+	var batch []*types.OpLogData
+	for i := range n {
+		batch = append(batch, chainOpLog("update"+strings.Repeat("!", i)))
+		if len(batch) == 2 || i == n-1 {
+			if err := CreateOpLogs(ctx, batch); err != nil {
+				t.Fatalf("failed to append op logs: %v", err)
+			}
+			batch = nil
+		}
+	}
+}
+
+func expectBroken(t *testing.T, report *types.OpLogChainReport, seq uint64, reason string) {
+	t.Helper()
+	if report.Valid || report.BrokenAt == nil || *report.BrokenAt != seq || report.Reason == nil || !strings.Contains(*report.Reason, reason) {
+		t.Fatalf("expected the chain to break at %d since the %s, got %+v", seq, reason, report)
+	}
+}
+
+func TestVerifyOpLogChain(t *testing.T) {
+	t.Parallel()
+	// Seqs are unique, so rows are moved through one that's free
+	swapSeqs := func(conn *gorm.DB, a uint64, b uint64) error {
+		return conn.Transaction(func(tx *gorm.DB) error {
+			for _, move := range [][2]uint64{{a, 0}, {b, a}, {0, b}} {
+				err := tx.Model(&models.OpLog{}).Where("seq = ?", move[0]).Update("seq", move[1]).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	tests := []struct {
+		name    string
+		tamper  func(conn *gorm.DB) error
+		seq     uint64
+		reason  string
+		checked int
+	}{
+		{"edited row", func(conn *gorm.DB) error {
+			return conn.Model(&models.OpLog{}).Where("seq = ?", 3).Update("user", "someone else").Error
+		}, 3, "hash doesn't match the content", 3},
+		{"rehashed row", func(conn *gorm.DB) error {
+			var log models.OpLog
+			if err := conn.Where("seq = ?", 3).First(&log).Error; err != nil {
+				return err
+			}
+			user := "someone else"
+			log.User = &user
+			content, _ := log.ChainContent()
+			return conn.Model(&log).Updates(map[string]any{"user": user, "chain_hash": models.ChainHashOf(*log.PrevHash, content)}).Error
+		}, 4, "previous hash doesn't match", 4},
+		{"deleted row", func(conn *gorm.DB) error {
+			return conn.Where("seq = ?", 3).Delete(&models.OpLog{}).Error
+		}, 4, "expected seq 3", 3},
+		{"reordered rows", func(conn *gorm.DB) error {
+			return swapSeqs(conn, 2, 3)
+		}, 2, "previous hash doesn't match", 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			conn := openChainDB(t, t.TempDir())
+			ctx := newChainContext(conn)
+			appendChain(t, ctx, 5)
+
+			if report, err := VerifyOpLogChain(ctx); err != nil || !report.Valid || report.Checked != 5 {
+				t.Fatalf("expected the chain to be valid, got %+v %v", report, err)
+			}
+			if err := test.tamper(conn); err != nil {
+				t.Fatalf("failed to tamper with the chain: %v", err)
+			}
+			report, err := VerifyOpLogChain(ctx)
+			if err != nil {
+				t.Fatalf("failed to verify chain: %v", err)
+			}
+			expectBroken(t, report, test.seq, test.reason)
+			if report.Checked != test.checked {
+				t.Fatalf("expected %d entries to be checked, got %d", test.checked, report.Checked)
+			}
+		})
+	}
+
+	// Rows cut off the end leave a valid chain, unless the head is known
+	t.Run("truncated tail", func(t *testing.T) {
+		t.Parallel()
+		conn := openChainDB(t, t.TempDir())
+		ctx := newChainContext(conn)
+		appendChain(t, ctx, 5)
+		if err := conn.Where("seq >= ?", 4).Delete(&models.OpLog{}).Error; err != nil {
+			t.Fatalf("failed to delete rows: %v", err)
+		}
+		if report, err := VerifyOpLogChain(ctx); err != nil || !report.Valid || report.Checked != 3 {
+			t.Fatalf("expected the truncated chain to be valid, got %+v %v", report, err)
+		}
+	})
+}
+
+func TestVerifyOpLogChainExport(t *testing.T) {
+	t.Parallel()
+	conn := openChainDB(t, t.TempDir())
+	ctx := newChainContext(conn)
+	appendChain(t, ctx, 5)
+
+	var buf bytes.Buffer
+	if err := ExportOpLogChain(ctx, &buf); err != nil {
+		t.Fatalf("failed to export chain: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected 5 links, got %d", len(lines))
+	}
+	verify := func(lines []string) *types.OpLogChainReport {
+		t.Helper()
+		report, err := VerifyOpLogChainExport(strings.NewReader(strings.Join(lines, "\n") + "\n"))
+		if err != nil {
+			t.Fatalf("failed to verify export: %v", err)
+		}
+		return report
+	}
+	if report := verify(lines); !report.Valid || report.Checked != 5 {
+		t.Fatalf("expected the export to be valid, got %+v", report)
+	}
+
+	// The content of the third link is changed
+	edited := append([]string(nil), lines...)
+	var link types.OpLogChainLink
+	var entry types.OpLogChainEntry
+	if err := json.Unmarshal([]byte(edited[2]), &link); err != nil {
+		t.Fatalf("invalid link: %v", err)
+	}
+	if err := json.Unmarshal([]byte(link.Content), &entry); err != nil {
+		t.Fatalf("invalid entry: %v", err)
+	}
+	entry.Operation = "delete"
+	content, _ := json.Marshal(entry)
+	link.Content = string(content)
+	line, _ := json.Marshal(link)
+	edited[2] = string(line)
+	expectBroken(t, verify(edited), 3, "hash doesn't match the content")
+
+	deleted := append(append([]string(nil), lines[:2]...), lines[3:]...)
+	expectBroken(t, verify(deleted), 4, "expected seq 3")
+
+	reordered := append([]string(nil), lines...)
+	reordered[1], reordered[2] = reordered[2], reordered[1]
+	expectBroken(t, verify(reordered), 3, "expected seq 2")
+
+	// Links moved along with their seqs are caught by their hashes
+	var second, third types.OpLogChainLink
+	_ = json.Unmarshal([]byte(lines[1]), &second)
+	_ = json.Unmarshal([]byte(lines[2]), &third)
+	second.Seq, third.Seq = third.Seq, second.Seq
+	renumbered := append([]string(nil), lines...)
+	for i, link := range []types.OpLogChainLink{third, second} {
+		line, _ := json.Marshal(link)
+		renumbered[i+1] = string(line)
+	}
+	expectBroken(t, verify(renumbered), 2, "content doesn't match the entry")
+
+	if report := verify(lines[:3]); !report.Valid || report.Checked != 3 {
+		t.Fatalf("expected the truncated export to be valid, got %+v", report)
+	}
+}
+
+func TestAppendOpLogChainRetries(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	conn := openChainDB(t, dir)
+	other := openChainDB(t, dir)
+	ctx := newChainContext(conn)
+	appendChain(t, ctx, 2)
+
+	// Another process appends to the chain once the batch has read the tail
+	appended := false
+	err := conn.Callback().Query().After("gorm:query").Register("test:append_concurrently", func(tx *gorm.DB) {
+		if appended || tx.Statement.Table != "op_log" {
+			return
+		}
+		appended = true
+		if err := appendOpLogChain(ctx, other, []*types.OpLogData{chainOpLog("concurrent")}); err != nil {
+			t.Errorf("failed to append concurrently: %v", err)
+		}
+	})
+	if err != nil {
+		t.Fatalf("failed to register callback: %v", err)
+	}
+
+	if err := appendOpLogChain(ctx, conn, []*types.OpLogData{chainOpLog("retried"), chainOpLog("retried")}); err != nil {
+		t.Fatalf("failed to append op logs: %v", err)
+	}
+	if !appended {
+		t.Fatal("nothing was appended concurrently")
+	}
+
+	// The batch is appended after the entry appended concurrently
+	var operations []string
+	if err := conn.Model(&models.OpLog{}).Order("seq").Pluck("operation", &operations).Error; err != nil {
+		t.Fatalf("failed to read chain: %v", err)
+	}
+	if strings.Join(operations[2:], ",") != "concurrent,retried,retried" {
+		t.Fatalf("unexpected order of the chain: %v", operations)
+	}
+	if report, err := VerifyOpLogChain(ctx); err != nil || !report.Valid || report.Checked != 5 {
+		t.Fatalf("expected the chain to be valid, got %+v %v", report, err)
+	}
+}
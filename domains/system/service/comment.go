@@ -1,30 +1,166 @@
 package service
 
 import (
+	"archive/zip"
 	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
 
 	"bilingo/common"
+	domain "bilingo/domains/system"
 	"bilingo/domains/system/models"
 	"bilingo/domains/system/repo"
 	"bilingo/domains/system/types"
+	"bilingo/server/db"
+	"bilingo/server/events"
+	"bilingo/server/markdown"
 )
 
+func init() {
+	RegisterObjectOwner("comment", func(ctx context.Context, objectId string) (string, error) {
+		id, err := strconv.ParseUint(objectId, 10, 32)
+		if err != nil {
+			return "", fmt.Errorf("invalid comment ID: %w", err)
+		}
+
+		comment, err := repo.Comments(ctx).Get(ctx, uint(id))
+		if err != nil {
+			return "", err
+		}
+		return comment.Author, nil
+	})
+	// Comments are public
+	RegisterObjectReader("comment", func(ctx context.Context, objectId string) (bool, error) {
+		_, err := GetObjectOwner(ctx, "comment", objectId)
+		return err == nil, err
+	})
+
+	RegisterUserData("comment", UserDataHandler{
+		Export: exportUserComments,
+		Delete: func(ctx context.Context, userId string) error {
+			_, err := repo.Comments(ctx).DeleteByAuthor(ctx, userId)
+			return err
+		},
+		Reassign: func(ctx context.Context, userId string, toUserId string) error {
+			_, err := repo.Comments(ctx).ReassignAuthor(ctx, userId, toUserId)
+			return err
+		},
+	})
+}
+
+func exportUserComments(ctx context.Context, userId string, zw *zip.Writer) error {
+	comments, err := repo.Comments(ctx).ListByAuthor(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	w, err := zw.Create("comments.jsonl")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	for i := range comments {
+		if err := enc.Encode(&comments[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func GetComment(ctx context.Context, id uint) (*models.Comment, error) {
-	return repo.CommentRepo.Get(ctx, id)
+	return repo.Comments(ctx).Get(ctx, id)
 }
 
 func ListComments(ctx context.Context, query types.CommentListQuery) (*common.PaginatedResult[models.Comment], error) {
-	return repo.CommentRepo.List(ctx, &query)
+	result, err := repo.Comments(ctx).List(ctx, &query)
+	if err != nil {
+		return nil, err
+	}
+	if query.Format != nil && *query.Format == markdown.FormatHtml {
+		for i := range result.List {
+			if err := RenderComment(&result.List[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// RenderComment fills the sanitized HTML of the content of the comment.
+func RenderComment(comment *models.Comment) error {
+	rendered, err := markdown.Render(comment.Content)
+	if err != nil {
+		return fmt.Errorf("failed to render comment %d: %w", comment.ID, err)
+	}
+	comment.Html = &rendered.Html
+	return nil
 }
 
-func CreateComment(ctx context.Context, data *types.CommentCreate) (*models.Comment, error) {
-	return repo.CommentRepo.Create(ctx, data)
+func CreateComment(ctx context.Context, data *types.CommentCreate, author string) (*models.Comment, error) {
+	var comment *models.Comment
+	err := db.Transaction(ctx, domain.DBBinding, func(ctx context.Context) error {
+		var err error
+		comment, err = repo.Comments(ctx).Create(ctx, data, author)
+		if err != nil {
+			return err
+		}
+		return events.Publish(ctx, domain.CommentCreated{
+			ID:         comment.ID,
+			ObjectType: comment.ObjectType,
+			ObjectId:   comment.ObjectId,
+			ParentId:   comment.ParentId,
+			Author:     comment.Author,
+			Content:    comment.Content,
+		})
+	})
+	return comment, err
 }
 
 func UpdateComment(ctx context.Context, id uint, updates *types.CommentUpdate) (*models.Comment, error) {
-	return repo.CommentRepo.Update(ctx, id, updates)
+	var comment *models.Comment
+	err := db.Transaction(ctx, domain.DBBinding, func(ctx context.Context) error {
+		var err error
+		comment, err = repo.Comments(ctx).Update(ctx, id, updates)
+		if err != nil {
+			return err
+		}
+		return events.Publish(ctx, domain.CommentUpdated{
+			ID:         comment.ID,
+			ObjectType: comment.ObjectType,
+			ObjectId:   comment.ObjectId,
+			Author:     comment.Author,
+			Content:    comment.Content,
+		})
+	})
+	return comment, err
 }
 
 func DeleteComment(ctx context.Context, id uint) error {
-	return repo.CommentRepo.Delete(ctx, id)
+	err := db.Transaction(ctx, domain.DBBinding, func(ctx context.Context) error {
+		comment, err := repo.Comments(ctx).Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := repo.Comments(ctx).Delete(ctx, id); err != nil {
+			return err
+		}
+		return events.Publish(ctx, domain.CommentDeleted{
+			ID:         comment.ID,
+			ObjectType: comment.ObjectType,
+			ObjectId:   comment.ObjectId,
+			Author:     comment.Author,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return DeleteAttachmentsOf(ctx, "comment", []string{strconv.FormatUint(uint64(id), 10)})
+}
+
+// DeleteCommentsOf deletes all comments on the given objects, e.g. when the
+// objects are deleted along with the account of their owner.
+func DeleteCommentsOf(ctx context.Context, objectType string, objectIds []string) error {
+	_, err := repo.Comments(ctx).DeleteByObjects(ctx, objectType, objectIds)
+	return err
 }
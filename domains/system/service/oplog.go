@@ -1,23 +1,35 @@
 package service
 
 import (
+	"archive/zip"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
+	"slices"
 	"time"
 
 	"bilingo/common"
+	domain "bilingo/domains/system"
 	"bilingo/domains/system/models"
 	"bilingo/domains/system/tables"
 	"bilingo/domains/system/types"
+	"bilingo/server/app"
 	"bilingo/server/db"
 
 	"github.com/google/uuid"
+	"gorm.io/cli/gorm/field"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
+func init() {
+	// Oplogs of the actions of users are kept when they delete their accounts,
+	// they only refer to the users by their IDs
+	RegisterUserData("oplog", UserDataHandler{Export: exportUserOpLogs})
+}
+
 func structToJsonString(data any) (*string, error) {
 	if data == nil {
 		return nil, nil
@@ -48,103 +60,260 @@ func structToJsonString(data any) (*string, error) {
 }
 
 func CreateOpLog(ctx context.Context, data *types.OpLogData) error {
-	conn, err := db.Default()
+	return CreateOpLogs(ctx, []*types.OpLogData{data})
+}
+
+// CreateOpLogs writes a batch of oplogs in one transaction. Repeated operations,
+// identified by the content hash, are merged into the existing entry by
+// increasing its times and updating its timestamp, unless the audit mode is
+// enabled, in which every oplog is appended to the hash chain.
+func CreateOpLogs(ctx context.Context, batch []*types.OpLogData) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	conn, err := db.For(ctx, domain.OpLogDBBinding)
 	if err != nil {
 		return db.ConnError(err)
 	}
 
-	q := gorm.G[models.OpLog](conn).
-		Where(tables.OpLog.ObjectType.Eq(data.ObjectType)).
-		Where(tables.OpLog.ObjectId.Eq(data.ObjectId)).
-		Where(tables.OpLog.Operation.Eq(data.Operation)).
-		Where(tables.OpLog.Result.Eq(data.Result))
-
-	if data.Description != nil {
-		q = q.Where(tables.OpLog.Description.Eq(*data.Description))
-	} else {
-		q = q.Where(tables.OpLog.Description.IsNull())
-	}
-	if data.User != nil {
-		q = q.Where(tables.OpLog.User.Eq(*data.User))
-	} else {
-		q = q.Where(tables.OpLog.User.IsNull())
-	}
-	if data.Ip != nil {
-		q = q.Where(tables.OpLog.Ip.Eq(*data.Ip))
-	} else {
-		q = q.Where(tables.OpLog.Ip.IsNull())
+	if app.Config(ctx).OpLog.Chained {
+		return appendOpLogChain(ctx, conn, batch)
 	}
 
-	var newDataJson *string
-	if data.NewData != nil {
-		var err error
-		newDataJson, err = structToJsonString(data.NewData)
+	// Merge repeated operations within the batch first
+	logs := make(map[string]*models.OpLog, len(batch))
+	hashes := make([]string, 0, len(batch))
+	for _, data := range batch {
+		log, err := newOpLog(data)
 		if err != nil {
 			return err
 		}
-		q = q.Where(tables.OpLog.NewData.Eq(*newDataJson))
-	} else {
-		q = q.Where(tables.OpLog.NewData.IsNull())
+
+		if existing, ok := logs[log.Hash]; ok {
+			existing.Times++
+			if log.Timestamp.After(existing.Timestamp) {
+				existing.Timestamp = log.Timestamp
+			}
+			continue
+		}
+
+		logs[log.Hash] = log
+		hashes = append(hashes, log.Hash)
 	}
 
-	var oldDataJson *string
-	if data.OldData != nil {
-		var err error
-		oldDataJson, err = structToJsonString(data.OldData)
+	return conn.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing []string
+		// Entries of the audit chain are never changed
+		err := tx.Model(&models.OpLog{}).
+			Where(tables.OpLog.Hash.In(hashes...), tables.OpLog.Seq.IsNull()).
+			Distinct().
+			Pluck("hash", &existing).Error
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to find repeated op logs: %w", err)
+		}
+
+		for _, hash := range existing {
+			log := logs[hash]
+			rowsAffected, err := gorm.G[models.OpLog](tx).
+				Where(tables.OpLog.Hash.Eq(hash), tables.OpLog.Seq.IsNull()).
+				Set(
+					tables.OpLog.Timestamp.Set(log.Timestamp),
+					tables.OpLog.Times.Incr(log.Times),
+				).
+				Update(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to update op log: %w", err)
+			} else if rowsAffected > 0 {
+				delete(logs, hash)
+			}
+		}
+
+		created := make([]models.OpLog, 0, len(logs))
+		for _, hash := range hashes {
+			if log, ok := logs[hash]; ok {
+				created = append(created, *log)
+			}
+		}
+		if len(created) == 0 {
+			return nil
 		}
-		q = q.Where(tables.OpLog.OldData.Eq(*oldDataJson))
-	} else {
-		q = q.Where(tables.OpLog.OldData.IsNull())
+
+		if err := gorm.G[models.OpLog](tx).CreateInBatches(ctx, &created, 100); err != nil {
+			return fmt.Errorf("failed to create op logs: %w", err)
+		}
+		return nil
+	})
+}
+
+func newOpLog(data *types.OpLogData) (*models.OpLog, error) {
+	newDataJson, err := structToJsonString(data.NewData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode new data: %w", err)
+	}
+
+	oldDataJson, err := structToJsonString(data.OldData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode old data: %w", err)
+	}
+
+	timestamp := time.Now()
+	if data.Timestamp != nil {
+		timestamp = *data.Timestamp
+	}
+
+	log := &models.OpLog{
+		ID: uuid.NewString(),
+		ObjectInfo: types.ObjectInfo{
+			ObjectType: data.ObjectType,
+			ObjectId:   data.ObjectId,
+		},
+		OpLogBase: types.OpLogBase{
+			Operation:   data.Operation,
+			Description: data.Description,
+			Result:      data.Result,
+			User:        data.User,
+			Ip:          data.Ip,
+		},
+		NewData:   newDataJson,
+		OldData:   oldDataJson,
+		Timestamp: timestamp,
+		Times:     1,
+	}
+	log.Hash = log.ContentHash()
+
+	return log, nil
+}
+
+// PruneOpLogs deletes the oplogs that haven't happened since the given time,
+// and returns the number of deleted entries. Oplogs can't be pruned in the audit
+// mode, since that would break the chain.
+func PruneOpLogs(ctx context.Context, before time.Time) (int, error) {
+	if app.Config(ctx).OpLog.Chained {
+		return 0, domain.ErrOpLogAppendOnly
 	}
 
-	var updates = []clause.Assigner{
-		tables.OpLog.Timestamp.Set(time.Now()),
-		tables.OpLog.Times.Incr(1),
+	conn, err := db.For(ctx, domain.OpLogDBBinding)
+	if err != nil {
+		return 0, db.ConnError(err)
 	}
 
-	rowsAffected, err := q.Set(updates...).Update(ctx)
+	rowsAffected, err := gorm.G[models.OpLog](conn).Where(tables.OpLog.Timestamp.Lt(before)).Delete(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to update op log: %w", err)
-	} else if rowsAffected == 0 {
-		log := models.OpLog{
-			ID: uuid.NewString(),
-			ObjectInfo: types.ObjectInfo{
-				ObjectType: data.ObjectType,
-				ObjectId:   data.ObjectId,
-			},
-			OpLogBase: types.OpLogBase{
-				Operation:   data.Operation,
-				Description: data.Description,
-				Result:      data.Result,
-				User:        data.User,
-				Ip:          data.Ip,
-			},
-			NewData:   newDataJson,
-			OldData:   oldDataJson,
-			Timestamp: time.Now(),
-			Times:     1,
-		}
+		return 0, fmt.Errorf("failed to prune op logs: %w", err)
+	}
 
-		if err := gorm.G[models.OpLog](conn).Create(ctx, &log); err != nil {
-			return fmt.Errorf("failed to create op log: %w", err)
-		}
+	return rowsAffected, nil
+}
+
+// exportUserOpLogs writes the oplogs of the actions of the user to the zip
+// archive.
+func exportUserOpLogs(ctx context.Context, userId string, zw *zip.Writer) error {
+	conn, err := db.For(ctx, domain.OpLogDBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	w, err := zw.Create("oplogs.jsonl")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+
+	var logs []models.OpLog
+	err = conn.WithContext(ctx).
+		Where(tables.OpLog.User.Eq(userId)).
+		Order(tables.OpLog.Timestamp.Asc()).
+		FindInBatches(&logs, 500, func(_ *gorm.DB, _ int) error {
+			for i := range logs {
+				if err := enc.Encode(&logs[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to export oplogs: %w", err)
 	}
 
 	return nil
 }
 
-func ListOpLogs(ctx context.Context, query types.OpLogListQuery) (*common.PaginatedResult[models.OpLog], error) {
-	conn, err := db.Default()
+// ScrubOpLogs clears the data recorded in the oplogs of the object, e.g. the
+// personal data of a deleted user, and returns the number of changed oplogs.
+// Oplogs in the audit chain are never changed, so they keep the data.
+func ScrubOpLogs(ctx context.Context, objectType string, objectId string) (int, error) {
+	conn, err := db.For(ctx, domain.OpLogDBBinding)
+	if err != nil {
+		return 0, db.ConnError(err)
+	}
+
+	var logs []models.OpLog
+	scrubbed := 0
+	update := conn.WithContext(ctx).Session(&gorm.Session{NewDB: true})
+	err = conn.WithContext(ctx).
+		Where(
+			tables.OpLog.ObjectType.Eq(objectType),
+			tables.OpLog.ObjectId.Eq(objectId),
+			tables.OpLog.Seq.IsNull(),
+		).
+		Where(clause.Or(tables.OpLog.NewData.IsNotNull(), tables.OpLog.OldData.IsNotNull())).
+		FindInBatches(&logs, 500, func(_ *gorm.DB, _ int) error {
+			for _, log := range logs {
+				log.NewData = nil
+				log.OldData = nil
+				err := update.Model(&models.OpLog{}).Where(tables.OpLog.ID.Eq(log.ID)).UpdateColumns(map[string]any{
+					"new_data": nil,
+					"old_data": nil,
+					"hash":     log.ContentHash(),
+				}).Error
+				if err != nil {
+					return err
+				}
+				scrubbed++
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return scrubbed, fmt.Errorf("failed to scrub op logs: %w", err)
+	}
+
+	return scrubbed, nil
+}
+
+func ListOpLogs(ctx context.Context, query types.OpLogListQuery) (*common.PaginatedResult[models.OpLogEntry], error) {
+	conn, err := db.For(ctx, domain.OpLogDBBinding)
 	if err != nil {
 		return nil, db.ConnError(err)
 	}
 
-	q := gorm.G[models.OpLog](conn).
-		Where(tables.OpLog.ObjectType.Eq(query.ObjectType)).
-		Where(tables.OpLog.ObjectId.Eq(query.ObjectId)).
-		Order(tables.OpLog.Timestamp.Asc())
+	q := gorm.G[models.OpLog](conn).Where("1 = 1")
+
+	filters := []struct {
+		field field.String
+		value *string
+	}{
+		{tables.OpLog.ObjectType, query.ObjectType},
+		{tables.OpLog.ObjectId, query.ObjectId},
+		{tables.OpLog.User, query.User},
+		{tables.OpLog.Ip, query.Ip},
+		{tables.OpLog.Operation, query.Operation},
+		{tables.OpLog.Result, query.Result},
+	}
+	for _, filter := range filters {
+		if filter.value != nil && *filter.value != "" {
+			q = q.Where(filter.field.Eq(*filter.value))
+		}
+	}
+
+	if query.Timestamp != nil {
+		if query.Timestamp.Start != nil {
+			q = q.Where(tables.OpLog.Timestamp.Gte(*query.Timestamp.Start))
+		}
+		if query.Timestamp.End != nil {
+			q = q.Where(tables.OpLog.Timestamp.Lte(*query.Timestamp.End))
+		}
+	}
 
 	// Count total before applying pagination
 	total, err := q.Count(ctx, "*")
@@ -152,6 +321,7 @@ func ListOpLogs(ctx context.Context, query types.OpLogListQuery) (*common.Pagina
 		return nil, fmt.Errorf("failed to count oplogs: %w", err)
 	}
 
+	q = q.Order(tables.OpLog.Timestamp.Desc())
 	q = q.Limit(query.PageSize)
 	q = q.Offset(query.PageSize * (query.Page - 1))
 
@@ -159,8 +329,71 @@ func ListOpLogs(ctx context.Context, query types.OpLogListQuery) (*common.Pagina
 	if err != nil {
 		return nil, fmt.Errorf("failed to get oplog list: %w", err)
 	} else if len(logs) == 0 {
-		return &common.PaginatedResult[models.OpLog]{Total: 0, List: []models.OpLog{}}, nil
+		return &common.PaginatedResult[models.OpLogEntry]{Total: 0, List: []models.OpLogEntry{}}, nil
+	}
+
+	entries := make([]models.OpLogEntry, 0, len(logs))
+	for _, log := range logs {
+		entries = append(entries, models.OpLogEntry{
+			OpLog: log,
+			Diff:  DiffOpLogData(log.OldData, log.NewData),
+		})
+	}
+
+	return &common.PaginatedResult[models.OpLogEntry]{Total: int(total), List: entries}, nil
+}
+
+// DiffOpLogData computes the field-level diff between the old and new data of
+// an oplog, which are JSON objects. Data that cannot be decoded is treated as
+// an empty object.
+func DiffOpLogData(oldData *string, newData *string) []types.FieldDiff {
+	decode := func(data *string) map[string]any {
+		obj := map[string]any{}
+		if data != nil {
+			_ = json.Unmarshal([]byte(*data), &obj)
+		}
+		return obj
+	}
+
+	diffs := []types.FieldDiff{}
+	diffObjects("", decode(oldData), decode(newData), &diffs)
+	return diffs
+}
+
+func diffObjects(prefix string, oldObj map[string]any, newObj map[string]any, diffs *[]types.FieldDiff) {
+	keys := make([]string, 0, len(oldObj)+len(newObj))
+	for key := range oldObj {
+		keys = append(keys, key)
+	}
+	for key := range newObj {
+		if _, ok := oldObj[key]; !ok {
+			keys = append(keys, key)
+		}
 	}
+	slices.Sort(keys)
 
-	return &common.PaginatedResult[models.OpLog]{Total: int(total), List: logs}, nil
+	for _, key := range keys {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		oldValue, inOld := oldObj[key]
+		newValue, inNew := newObj[key]
+
+		switch {
+		case !inOld:
+			*diffs = append(*diffs, types.FieldDiff{Field: path, Kind: "added", New: newValue})
+		case !inNew:
+			*diffs = append(*diffs, types.FieldDiff{Field: path, Kind: "removed", Old: oldValue})
+		default:
+			oldNested, oldIsObj := oldValue.(map[string]any)
+			newNested, newIsObj := newValue.(map[string]any)
+			if oldIsObj && newIsObj {
+				diffObjects(path, oldNested, newNested, diffs)
+			} else if !reflect.DeepEqual(oldValue, newValue) {
+				*diffs = append(*diffs, types.FieldDiff{Field: path, Kind: "changed", Old: oldValue, New: newValue})
+			}
+		}
+	}
 }
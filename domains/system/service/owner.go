@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	domain "bilingo/domains/system"
+	"bilingo/server/auth"
+)
+
+// OwnerResolver returns the owner (user ID) of the object with the given ID.
+type OwnerResolver func(ctx context.Context, objectId string) (string, error)
+
+var ownerResolvers sync.Map
+
+// RegisterObjectOwner registers the function to resolve the owners of objects
+// of the given type, domains call it to let the system domain authorize
+// access to the records (e.g. oplogs) attached to their objects.
+func RegisterObjectOwner(objectType string, resolver OwnerResolver) {
+	ownerResolvers.Store(objectType, resolver)
+}
+
+// GetObjectOwner returns the owner of the given object.
+func GetObjectOwner(ctx context.Context, objectType string, objectId string) (string, error) {
+	resolver, ok := ownerResolvers.Load(objectType)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", domain.ErrUnknownObjectType, objectType)
+	}
+
+	fn, ok := resolver.(OwnerResolver)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", domain.ErrUnknownObjectType, objectType)
+	}
+
+	return fn(ctx, objectId)
+}
+
+// ReadChecker reports whether the user of the context can read the object with
+// the given ID.
+type ReadChecker func(ctx context.Context, objectId string) (bool, error)
+
+var readCheckers sync.Map
+
+// RegisterObjectReader registers the function to check who can read objects of
+// the given type, the objects of types without one can only be read by their
+// owners and admins.
+func RegisterObjectReader(objectType string, checker ReadChecker) {
+	readCheckers.Store(objectType, checker)
+}
+
+// CanReadObject reports whether the user of the context can read the given
+// object, and with it the records attached to it, e.g. attachments.
+func CanReadObject(ctx context.Context, objectType string, objectId string) (bool, error) {
+	if checker, ok := readCheckers.Load(objectType); ok {
+		if fn, ok := checker.(ReadChecker); ok {
+			return fn(ctx, objectId)
+		}
+	}
+
+	owner, err := GetObjectOwner(ctx, objectType, objectId)
+	if err != nil {
+		return false, err
+	}
+	user := auth.GetUser(ctx)
+	return user != nil && (user.ID == owner || auth.IsAdmin(ctx, user)), nil
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	domain "bilingo/domains/system"
+	"bilingo/domains/user/models"
+	"bilingo/server/events"
+	"bilingo/server/realtime"
+)
+
+// CommentsTopic returns the realtime topic of the comments on the object, e.g.
+// `comments:article:42`, which gets the comment.created, comment.updated and
+// comment.deleted events.
+func CommentsTopic(objectType string, objectId string) string {
+	return realtime.Topic("comments", objectType, objectId)
+}
+
+// PublishRealtime lets anyone subscribe to the comments on the objects that
+// exist, like anyone may read them, and publishes the changes of the comments
+// to them once committed.
+func PublishRealtime(bus *events.Bus, hub *realtime.Hub) {
+	realtime.Authorize(hub, "comments", func(ctx context.Context, user *models.User, key string) error {
+		objectType, objectId, ok := strings.Cut(key, ":")
+		if !ok || objectId == "" {
+			return realtime.ErrInvalidTopic
+		}
+		_, err := GetObjectOwner(ctx, objectType, objectId)
+		if errors.Is(err, domain.ErrUnknownObjectType) {
+			return realtime.ErrInvalidTopic
+		} else if err != nil {
+			return realtime.ErrTopicNotFound
+		}
+		return nil
+	})
+
+	events.SubscribeSync(bus, func(ctx context.Context, event domain.CommentCreated) error {
+		return hub.Publish(ctx, CommentsTopic(event.ObjectType, event.ObjectId), event.EventName(), event)
+	})
+	events.SubscribeSync(bus, func(ctx context.Context, event domain.CommentUpdated) error {
+		return hub.Publish(ctx, CommentsTopic(event.ObjectType, event.ObjectId), event.EventName(), event)
+	})
+	events.SubscribeSync(bus, func(ctx context.Context, event domain.CommentDeleted) error {
+		return hub.Publish(ctx, CommentsTopic(event.ObjectType, event.ObjectId), event.EventName(), event)
+	})
+}
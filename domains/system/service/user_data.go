@@ -0,0 +1,45 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"slices"
+	"sync"
+)
+
+// UserDataHandler handles the data a domain keeps about a user for the privacy
+// requests of the user, any of the functions may be nil.
+type UserDataHandler struct {
+	// Export writes the data of the user as files to the zip archive.
+	Export func(ctx context.Context, userId string, zw *zip.Writer) error
+	// Delete deletes the content authored by the user.
+	Delete func(ctx context.Context, userId string) error
+	// Reassign attributes the content authored by the user to another user.
+	Reassign func(ctx context.Context, userId string, toUserId string) error
+}
+
+var userDataHandlers sync.Map
+
+// RegisterUserData registers the handler of the user data kept by a domain
+// under the given name, the user domain calls the handlers in the order of
+// their names when a user exports the data or deletes the account.
+func RegisterUserData(name string, handler UserDataHandler) {
+	userDataHandlers.Store(name, handler)
+}
+
+// UserDataHandlers returns the registered handlers ordered by their names.
+func UserDataHandlers() []UserDataHandler {
+	var names []string
+	userDataHandlers.Range(func(key, _ any) bool {
+		names = append(names, key.(string))
+		return true
+	})
+	slices.Sort(names)
+
+	handlers := make([]UserDataHandler, 0, len(names))
+	for _, name := range names {
+		handler, _ := userDataHandlers.Load(name)
+		handlers = append(handlers, handler.(UserDataHandler))
+	}
+	return handlers
+}
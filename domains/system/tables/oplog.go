@@ -19,6 +19,10 @@ var OpLog = struct {
 	OldData     field.String
 	Timestamp   field.Time
 	Times       field.Number[uint32]
+	Hash        field.String
+	Seq         field.Number[uint64]
+	PrevHash    field.String
+	ChainHash   field.String
 }{
 	ID:          field.String{}.WithColumn("id"),
 	ObjectType:  field.String{}.WithColumn("object_type"),
@@ -32,4 +36,8 @@ var OpLog = struct {
 	OldData:     field.String{}.WithColumn("old_data"),
 	Timestamp:   field.Time{}.WithColumn("timestamp"),
 	Times:       field.Number[uint32]{}.WithColumn("times"),
+	Hash:        field.String{}.WithColumn("hash"),
+	Seq:         field.Number[uint64]{}.WithColumn("seq"),
+	PrevHash:    field.String{}.WithColumn("prev_hash"),
+	ChainHash:   field.String{}.WithColumn("chain_hash"),
 }
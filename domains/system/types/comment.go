@@ -6,7 +6,6 @@ import "bilingo/common"
 type CommentCreate struct {
 	ObjectInfo `tstype:",extends"`
 	Content    string `json:"content" validate:"required,min=1"`
-	Author     string `json:"author" validate:"required,min=1,max=100"`
 	ParentId   *uint  `json:"parent_id" validate:"omitempty"`
 }
 
@@ -19,4 +18,5 @@ type CommentListQuery struct {
 	ObjectInfo            `tstype:",extends"`
 	Author                *string `json:"author" query:"author"`
 	ParentId              *uint   `json:"parent_id" query:"parent_id"`
+	Format                *string `json:"format" query:"format"` // Either markdown (the default) or html
 }
@@ -7,7 +7,6 @@ import type * as common from "@/common"
 
 export interface CommentCreate extends ObjectInfo {
     content: string
-    author: string
     parent_id?: number /* uint */
 }
 export interface CommentUpdate {
@@ -16,6 +15,7 @@ export interface CommentUpdate {
 export interface CommentListQuery extends common.PaginatedQuery, ObjectInfo {
     author?: string
     parent_id?: number /* uint */
+    format?: string // Either markdown (the default) or html
 }
 
 //////////
@@ -41,5 +41,60 @@ export interface OpLogData extends ObjectInfo, OpLogBase {
     old_data: object
     timestamp?: string /* RFC3339 */ // Manually set timestamp if needed
 }
-export interface OpLogListQuery extends common.PaginatedQuery, ObjectInfo {
+export interface OpLogListQuery extends common.PaginatedQuery {
+    object_type?: string
+    object_id?: string
+    user?: string
+    ip?: string
+    operation?: string
+    result?: string
+    timestamp?: common.Range<string>
+}
+/**
+ * FieldDiff describes the change of a single field between the old and new
+ * data of an operation, nested fields are addressed with dotted paths.
+ */
+export interface FieldDiff {
+    field: string
+    kind: string
+    old: unknown
+    new: unknown
+}
+/**
+ * OpLogChainEntry is the canonical content of an oplog in the audit chain, its
+ * JSON encoding, with the fields in this order, is what gets hashed.
+ */
+export interface OpLogChainEntry {
+    seq: number /* uint64 */
+    id: string
+    object_type: string
+    object_id: string
+    operation: string
+    description?: string
+    result: string
+    user?: string
+    ip?: string
+    new_data?: string
+    old_data?: string
+    timestamp: string // RFC3339 in UTC
+}
+/**
+ * OpLogChainLink is a line of the audit chain export, it can be verified
+ * offline by checking that hash = hex(sha256(prev_hash + content)), prev_hash
+ * equals the hash of the previous line, and seq increases by one each line.
+ */
+export interface OpLogChainLink {
+    seq: number /* uint64 */
+    prev_hash: string
+    hash: string
+    content: string // The JSON encoded OpLogChainEntry
+}
+/**
+ * OpLogChainReport is the result of verifying the audit chain.
+ */
+export interface OpLogChainReport {
+    valid: boolean
+    checked: number /* int */ // The number of entries checked
+    broken_at?: number /* uint64 */ // The seq of the first entry that breaks the chain
+    reason?: string
 }
@@ -24,5 +24,55 @@ type OpLogData struct {
 
 type OpLogListQuery struct {
 	common.PaginatedQuery `tstype:",extends"`
-	ObjectInfo            `tstype:",extends"`
+	ObjectType            *string                   `json:"object_type" query:"object_type" validate:"omitempty,max=16"`
+	ObjectId              *string                   `json:"object_id" query:"object_id" validate:"omitempty,max=64"`
+	User                  *string                   `json:"user" query:"user"`
+	Ip                    *string                   `json:"ip" query:"ip"`
+	Operation             *string                   `json:"operation" query:"operation"`
+	Result                *string                   `json:"result" query:"result" validate:"omitempty,oneof=success failure"`
+	Timestamp             *common.Range[*time.Time] `tstype:"common.Range<string>" json:"timestamp" query:"timestamp"`
+}
+
+// FieldDiff describes the change of a single field between the old and new
+// data of an operation, nested fields are addressed with dotted paths.
+type FieldDiff struct {
+	Field string `json:"field"`
+	Kind  string `json:"kind" validate:"oneof=added removed changed"`
+	Old   any    `json:"old" tstype:"unknown"`
+	New   any    `json:"new" tstype:"unknown"`
+}
+
+// OpLogChainEntry is the canonical content of an oplog in the audit chain, its
+// JSON encoding, with the fields in this order, is what gets hashed.
+type OpLogChainEntry struct {
+	Seq         uint64  `json:"seq"`
+	ID          string  `json:"id"`
+	ObjectType  string  `json:"object_type"`
+	ObjectId    string  `json:"object_id"`
+	Operation   string  `json:"operation"`
+	Description *string `json:"description"`
+	Result      string  `json:"result"`
+	User        *string `json:"user"`
+	Ip          *string `json:"ip"`
+	NewData     *string `json:"new_data"`
+	OldData     *string `json:"old_data"`
+	Timestamp   string  `json:"timestamp"` // RFC3339 in UTC
+}
+
+// OpLogChainLink is a line of the audit chain export, it can be verified
+// offline by checking that hash = hex(sha256(prev_hash + content)), prev_hash
+// equals the hash of the previous line, and seq increases by one each line.
+type OpLogChainLink struct {
+	Seq      uint64 `json:"seq"`
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash"`
+	Content  string `json:"content"` // The JSON encoded OpLogChainEntry
+}
+
+// OpLogChainReport is the result of verifying the audit chain.
+type OpLogChainReport struct {
+	Valid    bool    `json:"valid"`
+	Checked  int     `json:"checked"`             // The number of entries checked
+	BrokenAt *uint64 `json:"broken_at,omitempty"` // The seq of the first entry that breaks the chain
+	Reason   *string `json:"reason,omitempty"`
 }
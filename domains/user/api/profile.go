@@ -0,0 +1,124 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	domain "bilingo/domains/user"
+	"bilingo/domains/user/service"
+	"bilingo/domains/user/types"
+	"bilingo/server"
+	"bilingo/server/auth"
+	"bilingo/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxProfileIds is the most profiles listProfiles returns at once.
+const maxProfileIds = 100
+
+// getProfile returns the profile of the user, with the fields visible to the
+// requester: everything to the user and admins, the fields visible to users to
+// the logged in users, and the public fields to anyone else.
+func getProfile(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+
+	profile, err := service.GetProfile(ctx.UserContext(), id, audienceOf(ctx, id))
+	if errors.Is(err, domain.ErrUserNotFound) {
+		return server.Error(ctx, 404, domain.ErrUserNotFound)
+	} else if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success(ctx, profile)
+}
+
+// listProfiles returns the profiles of the users of the `ids` query, with the
+// fields visible to the requester like getProfile.
+func listProfiles(ctx *fiber.Ctx) error {
+	ids := utils.ParseArrayQuery(ctx, "ids")
+	if len(ids) > maxProfileIds {
+		return server.Error(ctx, 400, fmt.Errorf("at most %d profiles can be listed at once", maxProfileIds))
+	}
+
+	profiles, err := service.ListProfiles(ctx.UserContext(), ids, func(userId string) string {
+		return audienceOf(ctx, userId)
+	})
+	if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success(ctx, profiles)
+}
+
+// audienceOf returns the audience the requester is in for the profile of the
+// user.
+func audienceOf(ctx *fiber.Ctx, userId string) string {
+	user := auth.GetUser(ctx.UserContext())
+	if user == nil {
+		return types.VisibilityPublic
+	} else if user.ID == userId || auth.IsAdmin(ctx.UserContext(), user) {
+		return types.VisibilityPrivate
+	}
+	return types.VisibilityUsers
+}
+
+func updateProfile(ctx *fiber.Ctx) error {
+	var data types.ProfileUpdate
+	if err := ctx.BodyParser(&data); err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("malformed input: %w", err))
+	}
+
+	user := auth.GetUser(ctx.UserContext())
+	profile, err := service.UpdateProfile(ctx.UserContext(), user.ID, &data)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		return server.Error(ctx, 404, domain.ErrUserNotFound)
+	} else if errors.Is(err, domain.ErrInvalidProfile) {
+		return server.Error(ctx, 400, err)
+	} else if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success(ctx, profile)
+}
+
+// uploadAvatar sets the avatar of the user to the image in the `avatar` field of
+// a multipart form, or to the request body otherwise.
+func uploadAvatar(ctx *fiber.Ctx) error {
+	var r io.Reader = bytes.NewReader(ctx.Body())
+	if form, err := ctx.MultipartForm(); err == nil {
+		files := form.File["avatar"]
+		if len(files) == 0 {
+			return server.Error(ctx, 400, fmt.Errorf("malformed input: missing the avatar file"))
+		}
+		file, err := files[0].Open()
+		if err != nil {
+			return server.Error(ctx, 400, fmt.Errorf("malformed input: %w", err))
+		}
+		defer file.Close()
+		r = file
+	}
+
+	user := auth.GetUser(ctx.UserContext())
+	profile, err := service.SetAvatar(ctx.UserContext(), user.ID, r)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		return server.Error(ctx, 404, domain.ErrUserNotFound)
+	} else if errors.Is(err, domain.ErrInvalidAvatar) {
+		return server.Error(ctx, 400, err)
+	} else if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success(ctx, profile)
+}
+
+func deleteAvatar(ctx *fiber.Ctx) error {
+	user := auth.GetUser(ctx.UserContext())
+	if err := service.DeleteAvatar(ctx.UserContext(), user.ID); err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success[any](ctx, nil)
+}
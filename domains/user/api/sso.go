@@ -0,0 +1,137 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+
+	domain "bilingo/domains/user"
+	"bilingo/domains/user/service"
+	"bilingo/server"
+	"bilingo/server/app"
+	"bilingo/server/auth"
+	"bilingo/server/sso"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ssoStateCookie keeps the state of the login in the browser while the user is
+// at the identity provider.
+const ssoStateCookie = "sso_state"
+
+// ssoChallengeCookie keeps the login challenge of users with two-factor
+// authentication after single sign-on, until they complete it with a code at
+// `POST /users/login/2fa`.
+const ssoChallengeCookie = "sso_challenge"
+
+func listSsoProviders(ctx *fiber.Ctx) error {
+	return server.Success(ctx, service.ListSsoProviders(ctx.UserContext()))
+}
+
+func beginSsoLogin(ctx *fiber.Ctx) error {
+	redirect := safeRedirect(ctx.Query("redirect"))
+	authUrl, stateToken, err := service.BeginSsoLogin(ctx.UserContext(), ctx.Params("provider"), redirect)
+	if errors.Is(err, sso.ErrProviderNotFound) {
+		return server.Error(ctx, 404, sso.ErrProviderNotFound)
+	} else if err != nil {
+		return server.Error(ctx, 502, err)
+	}
+
+	ctx.Cookie(&fiber.Cookie{
+		Name:     ssoStateCookie,
+		Value:    stateToken,
+		Path:     "/",
+		HTTPOnly: true,
+		SameSite: "Lax", // Sent along with the redirect back from the provider
+		MaxAge:   int(service.SsoStateDuration.Seconds()),
+	})
+
+	return ctx.Redirect(authUrl, fiber.StatusFound)
+}
+
+func completeSsoLogin(ctx *fiber.Ctx) error {
+	stateToken := ctx.Cookies(ssoStateCookie)
+	ctx.Cookie(&fiber.Cookie{
+		Name:     ssoStateCookie,
+		Value:    "",
+		Path:     "/",
+		HTTPOnly: true,
+		MaxAge:   -1,
+	})
+
+	// The provider reports errors such as the user denying the consent
+	if providerErr := ctx.Query("error"); providerErr != "" {
+		return redirectSsoError(ctx, "/", providerErr)
+	}
+
+	user, challenge, redirect, err := service.CompleteSsoLogin(
+		ctx.UserContext(), ctx.Params("provider"), stateToken, ctx.Query("state"), ctx.Query("code"))
+	if errors.Is(err, sso.ErrProviderNotFound) {
+		return server.Error(ctx, 404, sso.ErrProviderNotFound)
+	} else if errors.Is(err, auth.ErrInvalidToken) {
+		return redirectSsoError(ctx, "/", "login expired, please try again")
+	} else if errors.Is(err, domain.ErrEmailNotVerified) || errors.Is(err, domain.ErrAccountNotLinked) {
+		return redirectSsoError(ctx, redirect, err.Error())
+	} else if err != nil {
+		log.Printf("failed to complete single sign-on: %v", err)
+		return redirectSsoError(ctx, redirect, "single sign-on failed")
+	}
+
+	// Users with two-factor authentication are sent back to complete the login
+	// with a code, the challenge token is kept out of the URL
+	if challenge != nil {
+		ctx.Cookie(&fiber.Cookie{
+			Name:     ssoChallengeCookie,
+			Value:    challenge.ChallengeToken,
+			Path:     "/",
+			HTTPOnly: true,
+			SameSite: "Lax",
+			Expires:  challenge.ExpiresAt,
+		})
+		target, _ := url.Parse(appUrl(ctx, safeRedirect(redirect)))
+		query := target.Query()
+		query.Set("two_factor_required", "true")
+		target.RawQuery = query.Encode()
+		return ctx.Redirect(target.String(), fiber.StatusFound)
+	}
+
+	if err := setAuthCookie(ctx, user.ID); err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return ctx.Redirect(appUrl(ctx, redirect), fiber.StatusFound)
+}
+
+func listIdentities(ctx *fiber.Ctx) error {
+	user := auth.GetUser(ctx.UserContext())
+	identities, err := service.ListIdentities(ctx.UserContext(), user.ID)
+	if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success(ctx, identities)
+}
+
+// redirectSsoError sends the user back to the application with the error in the
+// `sso_error` query.
+func redirectSsoError(ctx *fiber.Ctx, redirect string, message string) error {
+	target, _ := url.Parse(appUrl(ctx, safeRedirect(redirect)))
+	query := target.Query()
+	query.Set("sso_error", message)
+	target.RawQuery = query.Encode()
+	return ctx.Redirect(target.String(), fiber.StatusFound)
+}
+
+// safeRedirect only allows paths within the application, so the login can't be
+// abused to redirect users to other sites.
+func safeRedirect(redirect string) string {
+	if !strings.HasPrefix(redirect, "/") || strings.HasPrefix(redirect, "//") || strings.Contains(redirect, "\\") {
+		return "/"
+	}
+	return redirect
+}
+
+func appUrl(ctx *fiber.Ctx, path string) string {
+	return strings.TrimSuffix(app.Config(ctx.UserContext()).AppUrl, "/") + path
+}
@@ -0,0 +1,228 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"bilingo/config"
+	"bilingo/domains/user/models"
+	"bilingo/domains/user/types"
+	"bilingo/server/app"
+	"bilingo/server/sso/mockidp"
+	"bilingo/server/testutil"
+)
+
+// newSsoApp creates an app with the `mock` provider, served by a mock issuer
+// of the users.
+func newSsoApp(t *testing.T, autoProvision bool, users ...mockidp.User) (*app.App, *testutil.Fixtures) {
+	t.Helper()
+	idp := &mockidp.Server{ClientId: "bilingo", Users: users}
+	server := httptest.NewServer(idp)
+	t.Cleanup(server.Close)
+	idp.Issuer = server.URL
+
+	a := testutil.NewApp(t, func(cfg *config.Config) {
+		cfg.Auth.Sso = []config.SsoProviderConfig{{
+			Name:          "mock",
+			Issuer:        server.URL,
+			ClientId:      "bilingo",
+			AutoProvision: autoProvision,
+		}}
+	})
+	return a, testutil.LoadFixtures(t, a)
+}
+
+// ssoLogin logs in with the mock provider as the user of the email, and returns
+// where the user is sent back to in the app. The parameters of the redirects
+// to the provider and back to the callback are changed by the tamper functions,
+// if not nil.
+func ssoLogin(t *testing.T, client *testutil.Client, email string, tamperAuthorize func(url.Values), tamperCallback func(url.Values)) *url.URL {
+	t.Helper()
+	resp := client.Get("/users/sso/mock/login?redirect=/articles")
+	if resp.Status != http.StatusFound {
+		t.Fatalf("failed to begin login: %s", resp)
+	}
+	authorize, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("invalid authorization URL: %v", err)
+	}
+	query := authorize.Query()
+	query.Set("login_hint", email)
+	if tamperAuthorize != nil {
+		tamperAuthorize(query)
+	}
+	authorize.RawQuery = query.Encode()
+
+	// The mock issuer logs the user in at once
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	idpResp, err := noRedirect.Get(authorize.String())
+	if err != nil {
+		t.Fatalf("failed to authorize: %v", err)
+	}
+	idpResp.Body.Close()
+	callback, err := url.Parse(idpResp.Header.Get("Location"))
+	if err != nil || idpResp.StatusCode != http.StatusFound {
+		t.Fatalf("the issuer didn't redirect back: %d %v", idpResp.StatusCode, err)
+	}
+	query = callback.Query()
+	if tamperCallback != nil {
+		tamperCallback(query)
+	}
+
+	path, _ := strings.CutPrefix(callback.Path, "/api")
+	resp = client.Get(path + "?" + query.Encode())
+	if resp.Status != http.StatusFound {
+		t.Fatalf("failed to complete login: %s", resp)
+	}
+	target, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("invalid redirect: %v", err)
+	}
+	return target
+}
+
+// expectSsoError checks that the login was rejected, and the user is still
+// logged out.
+func expectSsoError(t *testing.T, client *testutil.Client, target *url.URL) {
+	t.Helper()
+	if target.Query().Get("sso_error") == "" {
+		t.Fatalf("expected the login to fail, was sent to %s", target)
+	}
+	if resp := client.Get("/users/me"); resp.Status != http.StatusUnauthorized {
+		t.Fatalf("logged in after a failed login: %s", resp)
+	}
+}
+
+func TestSsoLogin(t *testing.T) {
+	t.Parallel()
+	a, fixtures := newSsoApp(t, false)
+	alice := fixtures.User(t, "alice@example.com")
+	client := testutil.NewClient(t, a)
+
+	target := ssoLogin(t, client, alice.Email, nil, nil)
+	if target.Path != "/articles" || target.Query().Get("sso_error") != "" {
+		t.Fatalf("expected to be sent back to /articles, was sent to %s", target)
+	}
+	me := testutil.Decode[models.User](t, client.Get("/users/me"))
+	if me.ID != alice.ID {
+		t.Fatalf("logged in as %s, expected %s", me.ID, alice.ID)
+	}
+	identities := testutil.Decode[[]models.UserIdentity](t, client.Get("/users/me/identities"))
+	if len(identities) != 1 || identities[0].Provider != "mock" {
+		t.Fatalf("expected the identity to be linked, got %+v", identities)
+	}
+
+	// The linked identity logs in again
+	client.Logout()
+	ssoLogin(t, client, alice.Email, nil, nil)
+	if me := testutil.Decode[models.User](t, client.Get("/users/me")); me.ID != alice.ID {
+		t.Fatalf("logged in as %s, expected %s", me.ID, alice.ID)
+	}
+}
+
+func TestSsoLoginRejectsTampering(t *testing.T) {
+	t.Parallel()
+	a, fixtures := newSsoApp(t, false)
+	alice := fixtures.User(t, "alice@example.com")
+
+	tests := []struct {
+		name      string
+		authorize func(url.Values)
+		callback  func(url.Values)
+	}{
+		{"state", nil, func(q url.Values) { q.Set("state", "forged") }},
+		{"missing state", nil, func(q url.Values) { q.Del("state") }},
+		{"nonce", func(q url.Values) { q.Set("nonce", "forged") }, nil},
+		{"code challenge", func(q url.Values) { q.Set("code_challenge", "forged") }, nil},
+		{"code", nil, func(q url.Values) { q.Set("code", "forged") }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := testutil.NewClient(t, a)
+			expectSsoError(t, client, ssoLogin(t, client, alice.Email, test.authorize, test.callback))
+		})
+	}
+
+	// The state is bound to the browser which began the login
+	client := testutil.NewClient(t, a)
+	resp := client.Get("/users/sso/mock/login")
+	other := testutil.NewClient(t, a)
+	authorize, _ := url.Parse(resp.Header.Get("Location"))
+	resp = other.Get("/users/sso/mock/callback?code=code&state=" + url.QueryEscape(authorize.Query().Get("state")))
+	target, _ := url.Parse(resp.Header.Get("Location"))
+	expectSsoError(t, other, target)
+}
+
+func TestSsoLinking(t *testing.T) {
+	t.Parallel()
+	a, fixtures := newSsoApp(t, true, mockidp.User{Subject: "mock|bob", Email: "bob@example.com", Name: "Bob"})
+	carol := fixtures.User(t, "carol@example.com")
+	client := testutil.NewClient(t, a)
+
+	// Anyone could have signed up with the email of a user who hasn't verified
+	// it, so the identity isn't linked
+	expectSsoError(t, client, ssoLogin(t, client, carol.Email, nil, nil))
+	client.LoginAs(carol.ID)
+	if identities := testutil.Decode[[]models.UserIdentity](t, client.Get("/users/me/identities")); len(identities) != 0 {
+		t.Fatalf("linked an identity to an unverified user: %+v", identities)
+	}
+	if me := testutil.Decode[models.User](t, client.Get("/users/me")); me.EmailVerifiedAt != nil {
+		t.Fatal("the email of the unverified user was verified")
+	}
+
+	// Neither are emails the provider hasn't verified
+	client.Logout()
+	expectSsoError(t, client, ssoLogin(t, client, "bob@example.com", nil, nil))
+}
+
+func TestSsoAutoProvision(t *testing.T) {
+	t.Parallel()
+	for _, autoProvision := range []bool{false, true} {
+		a, _ := newSsoApp(t, autoProvision)
+		client := testutil.NewClient(t, a)
+		target := ssoLogin(t, client, "dave@example.com", nil, nil)
+		if !autoProvision {
+			expectSsoError(t, client, target)
+			continue
+		}
+
+		me := testutil.Decode[models.User](t, client.Get("/users/me"))
+		if me.Email != "dave@example.com" || me.Name != "dave" || me.EmailVerifiedAt == nil {
+			t.Fatalf("unexpected user: %+v", me)
+		}
+	}
+}
+
+func TestSsoTwoFactor(t *testing.T) {
+	t.Parallel()
+	a, fixtures := newSsoApp(t, false)
+	alice := fixtures.User(t, "alice@example.com")
+	client := testutil.NewClient(t, a)
+	client.Login(alice.Email, *alice.Password)
+	secret, step, _ := enableTwoFactor(t, client, *alice.Password)
+	client.Logout()
+
+	target := ssoLogin(t, client, alice.Email, nil, nil)
+	if target.Query().Get("two_factor_required") != "true" {
+		t.Fatalf("expected a two-factor challenge, was sent to %s", target)
+	}
+	if resp := client.Get("/users/me"); resp.Status != http.StatusUnauthorized {
+		t.Fatalf("logged in without the second factor: %s", resp)
+	}
+
+	// The challenge is kept in a cookie
+	login := types.TwoFactorLogin{Code: totpAt(t, secret, step+1)}
+	if me := testutil.Decode[models.User](t, client.Post("/users/login/2fa", login)); me.ID != alice.ID {
+		t.Fatalf("logged in as %s, expected %s", me.ID, alice.ID)
+	}
+
+	identities := testutil.Decode[[]models.UserIdentity](t, client.Get("/users/me/identities"))
+	if len(identities) != 1 {
+		t.Fatalf("expected the identity to be linked, got %+v", identities)
+	}
+}
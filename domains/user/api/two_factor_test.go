@@ -0,0 +1,201 @@
+package api_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"bilingo/domains/user/models"
+	repo "bilingo/domains/user/repo"
+	"bilingo/domains/user/types"
+	"bilingo/server/testutil"
+)
+
+// totpAt computes the TOTP code of the secret at the time step, as
+// authenticator apps do.
+func totpAt(t testing.TB, secret string, step int64) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatalf("invalid secret: %v", err)
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1_000_000)
+}
+
+// wrongTotp returns a code which is valid at none of the steps accepted now.
+func wrongTotp(t testing.TB, secret string, step int64) string {
+	t.Helper()
+	for i := 0; ; i++ {
+		code := fmt.Sprintf("%06d", i)
+		if code != totpAt(t, secret, step-1) && code != totpAt(t, secret, step) && code != totpAt(t, secret, step+1) {
+			return code
+		}
+	}
+}
+
+// enableTwoFactor enables two-factor authentication for the user logged in
+// with the client, and returns the secret, the step of the code used to enable
+// it, and the recovery codes.
+func enableTwoFactor(t testing.TB, client *testutil.Client, password string) (string, int64, []string) {
+	t.Helper()
+	enrollment := testutil.Decode[types.TwoFactorEnrollment](t, client.Post("/users/me/2fa/enroll", types.TwoFactorEnroll{Password: password}))
+	step := time.Now().Unix() / 30
+	codes := testutil.Decode[types.RecoveryCodes](t, client.Post("/users/me/2fa/enable", types.TwoFactorEnable{Code: totpAt(t, enrollment.Secret, step)}))
+	return enrollment.Secret, step, codes.Codes
+}
+
+// loginChallenge logs in with the password, and returns the challenge of the
+// second factor.
+func loginChallenge(t testing.TB, client *testutil.Client, email string, password string) string {
+	t.Helper()
+	client.Logout()
+	challenge := testutil.Decode[types.LoginChallenge](t, client.Login(email, password))
+	if !challenge.TwoFactorRequired || challenge.ChallengeToken == "" {
+		t.Fatalf("expected a two-factor challenge, got %+v", challenge)
+	}
+	return challenge.ChallengeToken
+}
+
+func TestTwoFactorReplay(t *testing.T) {
+	t.Parallel()
+	a := testutil.NewApp(t)
+	alice := testutil.LoadFixtures(t, a).User(t, "alice@example.com")
+	client := testutil.NewClient(t, a)
+	client.Login(alice.Email, *alice.Password)
+	secret, step, _ := enableTwoFactor(t, client, *alice.Password)
+
+	// The code enabling two-factor authentication is used up
+	challenge := loginChallenge(t, client, alice.Email, *alice.Password)
+	resp := client.Post("/users/login/2fa", types.TwoFactorLogin{ChallengeToken: challenge, Code: totpAt(t, secret, step)})
+	if resp.Status != http.StatusUnauthorized {
+		t.Fatalf("logged in with a code used before: %s", resp)
+	}
+
+	login := types.TwoFactorLogin{ChallengeToken: challenge, Code: totpAt(t, secret, step+1)}
+	user := testutil.Decode[models.User](t, client.Post("/users/login/2fa", login))
+	if user.ID != alice.ID {
+		t.Fatalf("logged in as %s, expected %s", user.ID, alice.ID)
+	}
+	if resp := client.Post("/users/login/2fa", login); resp.Status != http.StatusUnauthorized {
+		t.Fatalf("logged in twice with the same code: %s", resp)
+	}
+}
+
+func TestTwoFactorLockout(t *testing.T) {
+	t.Parallel()
+	a := testutil.NewApp(t)
+	alice := testutil.LoadFixtures(t, a).User(t, "alice@example.com")
+	client := testutil.NewClient(t, a)
+	client.Login(alice.Email, *alice.Password)
+	secret, step, _ := enableTwoFactor(t, client, *alice.Password)
+
+	challenge := loginChallenge(t, client, alice.Email, *alice.Password)
+	wrong := types.TwoFactorLogin{ChallengeToken: challenge, Code: wrongTotp(t, secret, step)}
+	for i := range 5 {
+		if resp := client.Post("/users/login/2fa", wrong); resp.Status != http.StatusUnauthorized {
+			t.Fatalf("expected the wrong code %d to be rejected, got %s", i+1, resp)
+		}
+	}
+
+	// Even the right code is rejected while the user is locked out
+	right := types.TwoFactorLogin{ChallengeToken: challenge, Code: totpAt(t, secret, step+1)}
+	if resp := client.Post("/users/login/2fa", right); resp.Status != http.StatusTooManyRequests {
+		t.Fatalf("expected the user to be locked out, got %s", resp)
+	}
+
+	// The lockout ends 15 minutes after the last failure
+	ctx := a.Context(context.Background())
+	if err := repo.TwoFactors(ctx).RecordFailure(ctx, alice.ID, time.Now().Add(-14*time.Minute)); err != nil {
+		t.Fatalf("failed to record failure: %v", err)
+	}
+	if resp := client.Post("/users/login/2fa", right); resp.Status != http.StatusTooManyRequests {
+		t.Fatalf("expected the user to be locked out for 15 minutes, got %s", resp)
+	}
+	if err := repo.TwoFactors(ctx).RecordFailure(ctx, alice.ID, time.Now().Add(-16*time.Minute)); err != nil {
+		t.Fatalf("failed to record failure: %v", err)
+	}
+	testutil.Decode[models.User](t, client.Post("/users/login/2fa", right))
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	t.Parallel()
+	a := testutil.NewApp(t)
+	alice := testutil.LoadFixtures(t, a).User(t, "alice@example.com")
+	client := testutil.NewClient(t, a)
+	client.Login(alice.Email, *alice.Password)
+	_, _, codes := enableTwoFactor(t, client, *alice.Password)
+	if len(codes) != 10 {
+		t.Fatalf("expected 10 recovery codes, got %d", len(codes))
+	}
+
+	challenge := loginChallenge(t, client, alice.Email, *alice.Password)
+	login := types.TwoFactorLogin{ChallengeToken: challenge, Code: codes[0]}
+	testutil.Decode[models.User](t, client.Post("/users/login/2fa", login))
+
+	status := testutil.Decode[types.TwoFactorStatus](t, client.Get("/users/me/2fa"))
+	if status.RecoveryCodesLeft != 9 {
+		t.Fatalf("expected 9 recovery codes left, got %d", status.RecoveryCodesLeft)
+	}
+
+	challenge = loginChallenge(t, client, alice.Email, *alice.Password)
+	login = types.TwoFactorLogin{ChallengeToken: challenge, Code: codes[0]}
+	if resp := client.Post("/users/login/2fa", login); resp.Status != http.StatusUnauthorized {
+		t.Fatalf("logged in twice with the same recovery code: %s", resp)
+	}
+}
+
+func TestTwoFactorReauthentication(t *testing.T) {
+	t.Parallel()
+	a := testutil.NewApp(t)
+	alice := testutil.LoadFixtures(t, a).User(t, "alice@example.com")
+	client := testutil.NewClient(t, a)
+	client.Login(alice.Email, *alice.Password)
+	secret, step, codes := enableTwoFactor(t, client, *alice.Password)
+
+	// Both the password and a code are required
+	reauths := []types.TwoFactorReauth{
+		{Password: *alice.Password},
+		{Password: *alice.Password, Code: wrongTotp(t, secret, step)},
+		{Password: "wrong password", Code: totpAt(t, secret, step+1)},
+		{Code: codes[0]},
+	}
+	for _, reauth := range reauths {
+		if resp := client.Post("/users/me/2fa/recovery-codes", reauth); resp.Status != http.StatusUnauthorized {
+			t.Fatalf("regenerated recovery codes with %+v: %s", reauth, resp)
+		}
+		if resp := client.Post("/users/me/2fa/disable", reauth); resp.Status != http.StatusUnauthorized {
+			t.Fatalf("disabled two-factor authentication with %+v: %s", reauth, resp)
+		}
+	}
+
+	reauth := types.TwoFactorReauth{Password: *alice.Password, Code: totpAt(t, secret, step+1)}
+	regenerated := testutil.Decode[types.RecoveryCodes](t, client.Post("/users/me/2fa/recovery-codes", reauth))
+
+	// The old recovery codes are replaced
+	reauth = types.TwoFactorReauth{Password: *alice.Password, Code: codes[1]}
+	if resp := client.Post("/users/me/2fa/disable", reauth); resp.Status != http.StatusUnauthorized {
+		t.Fatalf("disabled two-factor authentication with a replaced recovery code: %s", resp)
+	}
+	reauth = types.TwoFactorReauth{Password: *alice.Password, Code: regenerated.Codes[0]}
+	testutil.Decode[any](t, client.Post("/users/me/2fa/disable", reauth))
+
+	status := testutil.Decode[types.TwoFactorStatus](t, client.Get("/users/me/2fa"))
+	if status.Enabled {
+		t.Fatal("two-factor authentication is still enabled")
+	}
+}
@@ -1,40 +1,91 @@
 package api
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
+	"log"
+	"slices"
 
 	"bilingo/config"
 	domain "bilingo/domains/user"
 	"bilingo/domains/user/service"
 	"bilingo/domains/user/types"
 	"bilingo/server"
+	"bilingo/server/app"
 	"bilingo/server/auth"
 	"bilingo/utils"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-var UserApi = server.NewApiEntry("/users", auth.UseAuth)
-
-func init() {
-	// Authentication routes (must come before /:email to avoid conflicts)
-	UserApi.Post("/login", login)
-	UserApi.Post("/logout", auth.RequireAuth, logout)
-	UserApi.Get("/me", auth.RequireAuth, getMe)
-
-	// User CRUD routes
-	UserApi.Get("/", auth.RequireAuth, listUsers)
-	UserApi.Post("/", auth.RequireAuth, createUser)
-	UserApi.Get("/:email", auth.RequireAuth, getUser)
-	UserApi.Patch("/:email", auth.RequireAuth, updateUser)
-	UserApi.Patch("/:email/password", auth.RequireAuth, changePassword)
-	UserApi.Delete("/:email", auth.RequireAuth, deleteUser)
+// Register adds the routes of the users to the app.
+func Register(a *app.App) {
+	api := a.Group("/users", auth.UseAuth)
+	// Authentication routes (must come before /:id to avoid conflicts)
+	api.Post("/login", login)
+	api.Post("/login/2fa", loginTwoFactor)
+	api.Post("/logout", auth.RequireAuth, logout)
+	api.Get("/me", auth.RequireAuth, getMe)
+
+	// Registration routes
+	api.Post("/register", register)
+	api.Post("/verify", verifyEmail)
+	api.Post("/verify/resend", resendVerification)
+	api.Post("/invite", auth.RequireAuth, inviteUser)
+	api.Post("/password/forgot", forgotPassword)
+	api.Post("/password/reset", resetPassword)
+
+	// Email change routes
+	api.Post("/me/email", auth.RequireAuth, requestEmailChange)
+	api.Delete("/me/email", auth.RequireAuth, cancelEmailChange)
+	api.Post("/email/verify", confirmEmailChange)
+
+	// Single sign-on routes
+	api.Get("/sso", listSsoProviders)
+	api.Get("/sso/:provider/login", beginSsoLogin)
+	api.Get("/sso/:provider/callback", completeSsoLogin)
+	api.Get("/me/identities", auth.RequireAuth, listIdentities)
+
+	// Two-factor authentication routes
+	api.Get("/me/2fa", auth.RequireAuth, getTwoFactorStatus)
+	api.Post("/me/2fa/enroll", auth.RequireAuth, enrollTwoFactor)
+	api.Post("/me/2fa/enable", auth.RequireAuth, enableTwoFactor)
+	api.Post("/me/2fa/disable", auth.RequireAuth, disableTwoFactor)
+	api.Post("/me/2fa/recovery-codes", auth.RequireAuth, regenerateRecoveryCodes)
+	api.Get("/2fa", auth.RequireAuth, listTwoFactorStatuses)
+
+	// Profile routes, profiles are public with the fields visible to everyone
+	api.Get("/profiles", listProfiles)
+	api.Get("/:id/profile", getProfile)
+	api.Patch("/me/profile", auth.RequireAuth, updateProfile)
+	api.Put("/me/avatar", auth.RequireAuth, uploadAvatar)
+	api.Delete("/me/avatar", auth.RequireAuth, deleteAvatar)
+
+	// Privacy routes
+	api.Get("/deletions/:id", auth.RequireAuth, getDeletionStatus)
+	api.Get("/:id/export", auth.RequireAuth, exportUserData)
+
+	// User CRUD routes, the accounts are only shown to the users themselves and
+	// admins, anyone else sees the profiles. Only admins create accounts
+	// directly, others sign up subject to Auth.Registration.
+	api.Get("/", auth.RequireAdmin, listUsers)
+	api.Post("/", auth.RequireAdmin, createUser)
+	api.Get("/:id", auth.RequireAuth, getUser)
+	api.Patch("/:id", auth.RequireAuth, updateUser)
+	api.Patch("/:id/password", auth.RequireAuth, changePassword)
+	api.Delete("/:id", auth.RequireAuth, requestDeletion)
 }
 
 func getUser(ctx *fiber.Ctx) error {
-	email := ctx.Params("email")
-	user, err := service.GetUser(ctx.UserContext(), email)
+	id := ctx.Params("id")
+
+	current := auth.GetUser(ctx.UserContext())
+	if current == nil || (id != current.ID && !auth.IsAdmin(ctx.UserContext(), current)) {
+		return server.Error(ctx, 403, auth.ErrForbidden)
+	}
+
+	user, err := service.GetUser(ctx.UserContext(), id)
 	if errors.Is(err, domain.ErrUserNotFound) {
 		return server.Error(ctx, 404, domain.ErrUserNotFound)
 	} else if err != nil {
@@ -51,6 +102,9 @@ func listUsers(ctx *fiber.Ctx) error {
 	}
 
 	// Manually parse array parameters
+	if idList := utils.ParseArrayQuery(ctx, "ids"); len(idList) > 0 {
+		query.Ids = &idList
+	}
 	if emailList := utils.ParseArrayQuery(ctx, "emails"); len(emailList) > 0 {
 		query.Emails = &emailList
 	}
@@ -69,8 +123,13 @@ func createUser(ctx *fiber.Ctx) error {
 		return server.Error(ctx, 400, fmt.Errorf("malformed input: %w", err))
 	}
 
+	data.EmailVerifiedAt = nil // Only set by the service
 	user, err := service.CreateUser(ctx.UserContext(), &data)
-	if err != nil {
+	if errors.Is(err, domain.ErrNotAnEmail) || errors.Is(err, domain.ErrWeakPassword) {
+		return server.Error(ctx, 400, err)
+	} else if errors.Is(err, domain.ErrUserExists) {
+		return server.Error(ctx, 409, domain.ErrUserExists)
+	} else if err != nil {
 		return server.Error(ctx, 500, err)
 	}
 
@@ -78,11 +137,11 @@ func createUser(ctx *fiber.Ctx) error {
 }
 
 func updateUser(ctx *fiber.Ctx) error {
-	email := ctx.Params("email")
+	id := ctx.Params("id")
 
 	// Check if user is updating their own profile
 	user := auth.GetUser(ctx.UserContext())
-	if user == nil || email != user.Email {
+	if user == nil || id != user.ID {
 		return server.Error(ctx, 403, auth.ErrForbidden)
 	}
 
@@ -95,7 +154,7 @@ func updateUser(ctx *fiber.Ctx) error {
 		data.Password = nil // Prevent password updates via this endpoint
 	}
 
-	updatedUser, err := service.UpdateUser(ctx.UserContext(), email, &data)
+	updatedUser, err := service.UpdateUser(ctx.UserContext(), id, &data)
 	if errors.Is(err, domain.ErrUserNotFound) {
 		return server.Error(ctx, 404, domain.ErrUserNotFound)
 	} else if err != nil {
@@ -105,32 +164,106 @@ func updateUser(ctx *fiber.Ctx) error {
 	return server.Success(ctx, updatedUser)
 }
 
-func deleteUser(ctx *fiber.Ctx) error {
-	email := ctx.Params("email")
+// requestDeletion starts deleting the account in the background, users may
+// delete their own accounts with the allowed policies, and admins any account
+// with any policy. Only admins may transfer the content, since it would be
+// pushed onto the other user without their consent.
+func requestDeletion(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
 
-	// Check if user is deleting their own account
 	user := auth.GetUser(ctx.UserContext())
-	if user == nil || email != user.Email {
+	isAdmin := auth.IsAdmin(ctx.UserContext(), user)
+	if user == nil || (id != user.ID && !isAdmin) {
 		return server.Error(ctx, 403, auth.ErrForbidden)
 	}
 
-	err := service.DeleteUser(ctx.UserContext(), email)
+	var data types.DeletionRequest
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&data); err != nil {
+			return server.Error(ctx, 400, fmt.Errorf("malformed input: %w", err))
+		}
+	}
 
+	cfg := app.Config(ctx.UserContext())
+	policy := cfg.Privacy.DeletionPolicy
+	if data.Policy != nil && *data.Policy != "" {
+		policy = *data.Policy
+	}
+	if !isAdmin && (policy == config.DeletionTransfer || !slices.Contains(cfg.Privacy.DeletionPolicies, policy)) {
+		return server.Error(ctx, 403, fmt.Errorf("%w: %q", domain.ErrDeletionPolicy, policy))
+	}
+
+	deletion, err := service.RequestDeletion(ctx.UserContext(), id, &data, user.ID)
 	if errors.Is(err, domain.ErrUserNotFound) {
 		return server.Error(ctx, 404, domain.ErrUserNotFound)
+	} else if errors.Is(err, domain.ErrDeletionPolicy) {
+		return server.Error(ctx, 400, err)
+	} else if errors.Is(err, domain.ErrDeletedUser) {
+		return server.Error(ctx, 403, domain.ErrDeletedUser)
+	} else if errors.Is(err, domain.ErrDeletionInProgress) {
+		return server.Error(ctx, 409, domain.ErrDeletionInProgress)
 	} else if err != nil {
 		return server.Error(ctx, 500, err)
 	}
 
-	return server.Success[any](ctx, nil)
+	return server.Success(ctx, deletion)
+}
+
+// getDeletionStatus returns the progress of a deletion to the user being
+// deleted, until the account is gone, and to admins.
+func getDeletionStatus(ctx *fiber.Ctx) error {
+	deletion, err := service.GetDeletion(ctx.UserContext(), ctx.Params("id"))
+	if errors.Is(err, domain.ErrDeletionNotFound) {
+		return server.Error(ctx, 404, domain.ErrDeletionNotFound)
+	} else if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	user := auth.GetUser(ctx.UserContext())
+	if deletion.UserID != user.ID && !auth.IsAdmin(ctx.UserContext(), user) {
+		return server.Error(ctx, 404, domain.ErrDeletionNotFound)
+	}
+
+	return server.Success(ctx, service.GetDeletionStatus(deletion))
+}
+
+func exportUserData(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+
+	user := auth.GetUser(ctx.UserContext())
+	if user == nil || (id != user.ID && !auth.IsAdmin(ctx.UserContext(), user)) {
+		return server.Error(ctx, 403, auth.ErrForbidden)
+	}
+
+	if _, err := service.GetUser(ctx.UserContext(), id); errors.Is(err, domain.ErrUserNotFound) {
+		return server.Error(ctx, 404, domain.ErrUserNotFound)
+	} else if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	ctx.Set(fiber.HeaderContentType, "application/zip")
+	ctx.Attachment("user-data.zip")
+
+	// The archive is streamed since attachments make it large, failures past
+	// this point leave it truncated, which clients detect as a corrupt zip.
+	// The handler returns before the body is written, so the user context
+	// must be captured here.
+	userCtx := ctx.UserContext()
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := service.ExportUserData(userCtx, id, w); err != nil {
+			log.Printf("failed to export the data of user %s: %v", id, err)
+		}
+	})
+
+	return nil
 }
 
 func changePassword(ctx *fiber.Ctx) error {
-	email := ctx.Params("email")
+	id := ctx.Params("id")
 
 	// Check if user is changing their own password
 	user := auth.GetUser(ctx.UserContext())
-	if user == nil || email != user.Email {
+	if user == nil || id != user.ID {
 		return server.Error(ctx, 403, auth.ErrForbidden)
 	}
 
@@ -139,11 +272,13 @@ func changePassword(ctx *fiber.Ctx) error {
 		return server.Error(ctx, 400, fmt.Errorf("malformed input: %w", err))
 	}
 
-	err := service.ChangePassword(ctx.UserContext(), email, &data)
+	err := service.ChangePassword(ctx.UserContext(), id, &data)
 	if errors.Is(err, domain.ErrUserNotFound) {
 		return server.Error(ctx, 404, domain.ErrUserNotFound)
 	} else if errors.Is(err, domain.ErrInvalidPassword) {
 		return server.Error(ctx, 401, domain.ErrInvalidPassword)
+	} else if errors.Is(err, domain.ErrWeakPassword) {
+		return server.Error(ctx, 400, err)
 	} else if err != nil {
 		return server.Error(ctx, 500, err)
 	}
@@ -160,18 +295,71 @@ func login(ctx *fiber.Ctx) error {
 	user, err := service.Login(ctx.UserContext(), &credentials)
 	if errors.Is(err, domain.ErrUserNotFound) || errors.Is(err, domain.ErrInvalidPassword) {
 		return server.Error(ctx, 401, fmt.Errorf("invalid email or password"))
+	} else if errors.Is(err, domain.ErrEmailNotVerified) {
+		return server.Error(ctx, 403, domain.ErrEmailNotVerified)
 	} else if err != nil {
 		return server.Error(ctx, 500, err)
 	}
 
-	// Generate JWT token
-	token, err := auth.GenerateToken(user.Email)
+	// Users with two-factor authentication complete the login with a code
+	challenge, err := service.ChallengeTwoFactor(ctx.UserContext(), user)
 	if err != nil {
 		return server.Error(ctx, 500, err)
+	} else if challenge != nil {
+		return server.Success(ctx, challenge)
+	}
+
+	if err := setAuthCookie(ctx, user.ID); err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success(ctx, user)
+}
+
+func loginTwoFactor(ctx *fiber.Ctx) error {
+	var data types.TwoFactorLogin
+	if err := ctx.BodyParser(&data); err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("malformed input: %w", err))
+	}
+	// The challenge of a single sign-on is kept in a cookie
+	if data.ChallengeToken == "" {
+		data.ChallengeToken = ctx.Cookies(ssoChallengeCookie)
+	}
+
+	user, err := service.LoginTwoFactor(ctx.UserContext(), &data)
+	if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, domain.ErrTwoFactorNotEnabled) {
+		return server.Error(ctx, 401, auth.ErrInvalidToken)
+	} else if errors.Is(err, domain.ErrInvalidTwoFactorCode) {
+		return server.Error(ctx, 401, domain.ErrInvalidTwoFactorCode)
+	} else if errors.Is(err, domain.ErrTooManyAttempts) {
+		return server.Error(ctx, 429, domain.ErrTooManyAttempts)
+	} else if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	ctx.Cookie(&fiber.Cookie{
+		Name:     ssoChallengeCookie,
+		Value:    "",
+		Path:     "/",
+		HTTPOnly: true,
+		MaxAge:   -1,
+	})
+	if err := setAuthCookie(ctx, user.ID); err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success(ctx, user)
+}
+
+func setAuthCookie(ctx *fiber.Ctx, userId string) error {
+	// Generate JWT token
+	token, err := auth.GenerateToken(ctx.UserContext(), userId)
+	if err != nil {
+		return err
 	}
 
 	// Set cookie with token
-	cfg := config.GetConfig()
+	cfg := app.Config(ctx.UserContext())
 	ctx.Cookie(&fiber.Cookie{
 		Name:     cfg.Auth.CookieName,
 		Value:    token,
@@ -182,12 +370,12 @@ func login(ctx *fiber.Ctx) error {
 		MaxAge:   int(cfg.Auth.Duration.Seconds()),
 	})
 
-	return server.Success(ctx, user)
+	return nil
 }
 
 func logout(ctx *fiber.Ctx) error {
 	// Clear the auth cookie
-	cfg := config.GetConfig()
+	cfg := app.Config(ctx.UserContext())
 	ctx.Cookie(&fiber.Cookie{
 		Name:     cfg.Auth.CookieName,
 		Value:    "",
@@ -207,3 +395,251 @@ func getMe(ctx *fiber.Ctx) error {
 
 	return server.Success(ctx, user)
 }
+
+func register(ctx *fiber.Ctx) error {
+	var data types.UserRegister
+	if err := ctx.BodyParser(&data); err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("malformed input: %w", err))
+	}
+
+	user, err := service.Register(ctx.UserContext(), &data)
+	if errors.Is(err, domain.ErrRegistrationClosed) || errors.Is(err, domain.ErrInvalidInvitation) {
+		return server.Error(ctx, 403, err)
+	} else if errors.Is(err, domain.ErrNotAnEmail) || errors.Is(err, domain.ErrWeakPassword) {
+		return server.Error(ctx, 400, err)
+	} else if errors.Is(err, domain.ErrUserExists) {
+		return server.Error(ctx, 409, domain.ErrUserExists)
+	} else if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success(ctx, user)
+}
+
+func verifyEmail(ctx *fiber.Ctx) error {
+	var data types.EmailVerification
+	if err := ctx.BodyParser(&data); err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("malformed input: %w", err))
+	}
+
+	user, err := service.VerifyEmail(ctx.UserContext(), data.Token)
+	if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, domain.ErrUserNotFound) {
+		return server.Error(ctx, 400, auth.ErrInvalidToken)
+	} else if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success(ctx, user)
+}
+
+func resendVerification(ctx *fiber.Ctx) error {
+	var data types.VerificationResend
+	if err := ctx.BodyParser(&data); err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("malformed input: %w", err))
+	}
+
+	if err := service.ResendVerification(ctx.UserContext(), data.Email); err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success[any](ctx, nil)
+}
+
+func inviteUser(ctx *fiber.Ctx) error {
+	if !auth.IsAdmin(ctx.UserContext(), auth.GetUser(ctx.UserContext())) {
+		return server.Error(ctx, 403, auth.ErrForbidden)
+	}
+
+	var data types.UserInvite
+	if err := ctx.BodyParser(&data); err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("malformed input: %w", err))
+	}
+
+	err := service.InviteUser(ctx.UserContext(), data.Email)
+	if errors.Is(err, domain.ErrNotAnEmail) {
+		return server.Error(ctx, 400, err)
+	} else if errors.Is(err, domain.ErrUserExists) {
+		return server.Error(ctx, 409, domain.ErrUserExists)
+	} else if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success[any](ctx, nil)
+}
+
+func forgotPassword(ctx *fiber.Ctx) error {
+	var data types.PasswordForgot
+	if err := ctx.BodyParser(&data); err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("malformed input: %w", err))
+	}
+
+	if err := service.ForgotPassword(ctx.UserContext(), data.Email); err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success[any](ctx, nil)
+}
+
+func resetPassword(ctx *fiber.Ctx) error {
+	var data types.PasswordReset
+	if err := ctx.BodyParser(&data); err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("malformed input: %w", err))
+	}
+
+	err := service.ResetPassword(ctx.UserContext(), &data)
+	if errors.Is(err, domain.ErrInvalidResetToken) {
+		return server.Error(ctx, 400, domain.ErrInvalidResetToken)
+	} else if errors.Is(err, domain.ErrWeakPassword) {
+		return server.Error(ctx, 400, err)
+	} else if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success[any](ctx, nil)
+}
+
+func requestEmailChange(ctx *fiber.Ctx) error {
+	var data types.EmailChange
+	if err := ctx.BodyParser(&data); err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("malformed input: %w", err))
+	}
+
+	user := auth.GetUser(ctx.UserContext())
+	err := service.RequestEmailChange(ctx.UserContext(), user.ID, &data)
+	if errors.Is(err, domain.ErrInvalidPassword) {
+		return server.Error(ctx, 401, domain.ErrInvalidPassword)
+	} else if errors.Is(err, domain.ErrNotAnEmail) {
+		return server.Error(ctx, 400, err)
+	} else if errors.Is(err, domain.ErrUserExists) {
+		return server.Error(ctx, 409, domain.ErrUserExists)
+	} else if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success[any](ctx, nil)
+}
+
+func cancelEmailChange(ctx *fiber.Ctx) error {
+	user := auth.GetUser(ctx.UserContext())
+	if err := service.CancelEmailChange(ctx.UserContext(), user.ID); err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success[any](ctx, nil)
+}
+
+func confirmEmailChange(ctx *fiber.Ctx) error {
+	var data types.EmailChangeConfirm
+	if err := ctx.BodyParser(&data); err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("malformed input: %w", err))
+	}
+
+	user, err := service.ConfirmEmailChange(ctx.UserContext(), data.Token)
+	if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, domain.ErrInvalidEmailChange) ||
+		errors.Is(err, domain.ErrUserNotFound) {
+		return server.Error(ctx, 400, domain.ErrInvalidEmailChange)
+	} else if errors.Is(err, domain.ErrUserExists) {
+		return server.Error(ctx, 409, domain.ErrUserExists)
+	} else if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success(ctx, user)
+}
+
+// twoFactorError responds with the status of the errors of the two-factor
+// routes.
+func twoFactorError(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, domain.ErrInvalidPassword), errors.Is(err, domain.ErrInvalidTwoFactorCode):
+		return server.Error(ctx, 401, err)
+	case errors.Is(err, domain.ErrTwoFactorNotEnabled), errors.Is(err, domain.ErrTwoFactorEnabled):
+		return server.Error(ctx, 409, err)
+	case errors.Is(err, domain.ErrTooManyAttempts):
+		return server.Error(ctx, 429, err)
+	default:
+		return server.Error(ctx, 500, err)
+	}
+}
+
+func getTwoFactorStatus(ctx *fiber.Ctx) error {
+	user := auth.GetUser(ctx.UserContext())
+	status, err := service.GetTwoFactorStatus(ctx.UserContext(), user.ID)
+	if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success(ctx, status)
+}
+
+func enrollTwoFactor(ctx *fiber.Ctx) error {
+	var data types.TwoFactorEnroll
+	if err := ctx.BodyParser(&data); err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("malformed input: %w", err))
+	}
+
+	user := auth.GetUser(ctx.UserContext())
+	enrollment, err := service.EnrollTwoFactor(ctx.UserContext(), user.ID, &data)
+	if err != nil {
+		return twoFactorError(ctx, err)
+	}
+
+	return server.Success(ctx, enrollment)
+}
+
+func enableTwoFactor(ctx *fiber.Ctx) error {
+	var data types.TwoFactorEnable
+	if err := ctx.BodyParser(&data); err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("malformed input: %w", err))
+	}
+
+	user := auth.GetUser(ctx.UserContext())
+	codes, err := service.EnableTwoFactor(ctx.UserContext(), user.ID, &data)
+	if err != nil {
+		return twoFactorError(ctx, err)
+	}
+
+	return server.Success(ctx, codes)
+}
+
+func disableTwoFactor(ctx *fiber.Ctx) error {
+	var data types.TwoFactorReauth
+	if err := ctx.BodyParser(&data); err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("malformed input: %w", err))
+	}
+
+	user := auth.GetUser(ctx.UserContext())
+	if err := service.DisableTwoFactor(ctx.UserContext(), user.ID, &data); err != nil {
+		return twoFactorError(ctx, err)
+	}
+
+	return server.Success[any](ctx, nil)
+}
+
+func regenerateRecoveryCodes(ctx *fiber.Ctx) error {
+	var data types.TwoFactorReauth
+	if err := ctx.BodyParser(&data); err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("malformed input: %w", err))
+	}
+
+	user := auth.GetUser(ctx.UserContext())
+	codes, err := service.RegenerateRecoveryCodes(ctx.UserContext(), user.ID, &data)
+	if err != nil {
+		return twoFactorError(ctx, err)
+	}
+
+	return server.Success(ctx, codes)
+}
+
+func listTwoFactorStatuses(ctx *fiber.Ctx) error {
+	if !auth.IsAdmin(ctx.UserContext(), auth.GetUser(ctx.UserContext())) {
+		return server.Error(ctx, 403, auth.ErrForbidden)
+	}
+
+	statuses, err := service.ListTwoFactorStatuses(ctx.UserContext())
+	if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success(ctx, statuses)
+}
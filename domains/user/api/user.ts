@@ -1,20 +1,46 @@
 import type { ApiResponse, PaginatedResult } from "../../../common"
 import { ApiEntry } from "../../../client"
-import type { User } from "../models"
+import type { User, UserDeletion, UserIdentity } from "../models"
 import type {
+    DeletionRequest,
+    DeletionStatus,
+    EmailChange,
+    LoginChallenge,
     LoginCredentials,
     PasswordChange,
+    PasswordReset,
+    Profile,
+    ProfileUpdate,
+    RecoveryCodes,
+    SsoProvider,
+    TwoFactorEnrollment,
+    TwoFactorLogin,
+    TwoFactorReauth,
+    TwoFactorStatus,
     UserCreate,
     UserListQuery,
+    UserRegister,
     UserUpdate,
 } from "../types"
 
 const userApi = new ApiEntry("/users")
 
-export async function login(credentials: LoginCredentials): ApiResponse<User> {
+/**
+ * Logs in with the credentials, users with two-factor authentication get a
+ * challenge instead, which is completed with `loginTwoFactor`.
+ */
+export async function login(credentials: LoginCredentials): ApiResponse<User | LoginChallenge> {
     return await userApi.post("/login", null, credentials)
 }
 
+export function isLoginChallenge(data: User | LoginChallenge): data is LoginChallenge {
+    return "two_factor_required" in data && data.two_factor_required
+}
+
+export async function loginTwoFactor(data: TwoFactorLogin): ApiResponse<User> {
+    return await userApi.post("/login/2fa", null, data)
+}
+
 export async function logout(): ApiResponse<null> {
     return await userApi.post("/logout")
 }
@@ -23,10 +49,30 @@ export async function getMe(): ApiResponse<User> {
     return await userApi.get("/me")
 }
 
-export async function getUser(email: string): ApiResponse<User> {
-    return await userApi.get(`/${email}`)
+export async function register(data: UserRegister): ApiResponse<User> {
+    return await userApi.post("/register", null, data)
+}
+
+export async function verifyEmail(token: string): ApiResponse<User> {
+    return await userApi.post("/verify", null, { token })
+}
+
+export async function resendVerification(email: string): ApiResponse<null> {
+    return await userApi.post("/verify/resend", null, { email })
+}
+
+export async function inviteUser(email: string): ApiResponse<null> {
+    return await userApi.post("/invite", null, { email })
 }
 
+export async function getUser(id: string): ApiResponse<User> {
+    return await userApi.get(`/${id}`)
+}
+
+/**
+ * Lists the accounts of the users, which is only allowed to admins, anyone else
+ * looks up the users with `listProfiles`.
+ */
 export async function listUsers(query: UserListQuery): ApiResponse<PaginatedResult<User>> {
     return await userApi.get("/", query)
 }
@@ -35,14 +81,127 @@ export async function createUser(data: UserCreate): ApiResponse<User> {
     return await userApi.post("/", null, data)
 }
 
-export async function updateUser(email: string, data: UserUpdate): ApiResponse<User> {
-    return await userApi.patch(`/${email}`, null, data)
+export async function updateUser(id: string, data: UserUpdate): ApiResponse<User> {
+    return await userApi.patch(`/${id}`, null, data)
+}
+
+/**
+ * Starts deleting the account in the background, the progress is available with
+ * `getDeletionStatus`.
+ */
+export async function deleteUser(id: string, data: DeletionRequest = {}): ApiResponse<UserDeletion> {
+    return await userApi.delete(`/${id}`, null, data)
+}
+
+/**
+ * Returns the profiles of the users with the fields visible to the current
+ * user, at most 100 at once.
+ */
+export async function listProfiles(ids: string[]): ApiResponse<Profile[]> {
+    return await userApi.get("/profiles", { ids })
+}
+
+/**
+ * Returns the profile of the user with the fields visible to the current user.
+ */
+export async function getProfile(id: string): ApiResponse<Profile> {
+    return await userApi.get(`/${id}/profile`)
+}
+
+export async function updateProfile(data: ProfileUpdate): ApiResponse<Profile> {
+    return await userApi.patch("/me/profile", null, data)
+}
+
+/**
+ * Replaces the avatar with the image, a JPEG, PNG or GIF of at most 5 MiB.
+ */
+export async function uploadAvatar(file: Blob): ApiResponse<Profile> {
+    const data = new FormData()
+    data.append("avatar", file)
+    return await userApi.put("/me/avatar", null, data)
+}
+
+export async function deleteAvatar(): ApiResponse<null> {
+    return await userApi.delete("/me/avatar")
+}
+
+export async function getDeletionStatus(id: string): ApiResponse<DeletionStatus> {
+    return await userApi.get(`/deletions/${id}`)
+}
+
+/**
+ * Returns the URL of the zip archive of all the data kept about the user.
+ */
+export function getUserExportUrl(id: string): string {
+    return `/api/users/${id}/export`
+}
+
+export async function changePassword(id: string, data: PasswordChange): ApiResponse<null> {
+    return await userApi.patch(`/${id}/password`, null, data)
+}
+
+/**
+ * Sends a confirmation link to the new email, which replaces the current one
+ * once it's opened.
+ */
+export async function requestEmailChange(data: EmailChange): ApiResponse<null> {
+    return await userApi.post("/me/email", null, data)
+}
+
+export async function cancelEmailChange(): ApiResponse<null> {
+    return await userApi.delete("/me/email")
+}
+
+export async function confirmEmailChange(token: string): ApiResponse<User> {
+    return await userApi.post("/email/verify", null, { token })
+}
+
+export async function forgotPassword(email: string): ApiResponse<null> {
+    return await userApi.post("/password/forgot", null, { email })
+}
+
+export async function resetPassword(data: PasswordReset): ApiResponse<null> {
+    return await userApi.post("/password/reset", null, data)
+}
+
+export async function getTwoFactorStatus(): ApiResponse<TwoFactorStatus> {
+    return await userApi.get("/me/2fa")
+}
+
+export async function enrollTwoFactor(password: string): ApiResponse<TwoFactorEnrollment> {
+    return await userApi.post("/me/2fa/enroll", null, { password })
+}
+
+export async function enableTwoFactor(code: string): ApiResponse<RecoveryCodes> {
+    return await userApi.post("/me/2fa/enable", null, { code })
+}
+
+export async function disableTwoFactor(data: TwoFactorReauth): ApiResponse<null> {
+    return await userApi.post("/me/2fa/disable", null, data)
+}
+
+export async function regenerateRecoveryCodes(data: TwoFactorReauth): ApiResponse<RecoveryCodes> {
+    return await userApi.post("/me/2fa/recovery-codes", null, data)
+}
+
+export async function listTwoFactorStatuses(): ApiResponse<TwoFactorStatus[]> {
+    return await userApi.get("/2fa")
+}
+
+export async function listSsoProviders(): ApiResponse<SsoProvider[]> {
+    return await userApi.get("/sso")
 }
 
-export async function deleteUser(email: string): ApiResponse<null> {
-    return await userApi.delete(`/${email}`)
+/**
+ * Returns the URL to navigate the browser to for logging in with the identity
+ * provider, the user is sent back to the `redirect` path afterwards, or to the
+ * same path with an `sso_error` query if the login fails.
+ */
+export function getSsoLoginUrl(provider: string, redirect = "/"): string {
+    const query = new URLSearchParams({ redirect })
+    return `/api/users/sso/${encodeURIComponent(provider)}/login?${query}`
 }
 
-export async function changePassword(email: string, data: PasswordChange): ApiResponse<null> {
-    return await userApi.patch(`/${email}/password`, null, data)
+export async function listIdentities(): ApiResponse<UserIdentity[]> {
+    return await userApi.get("/me/identities")
 }
@@ -0,0 +1,62 @@
+package api_test
+
+import (
+	"net/http"
+	"testing"
+
+	"bilingo/config"
+	"bilingo/domains/user/models"
+	"bilingo/domains/user/types"
+	"bilingo/server/testutil"
+)
+
+func TestCreateUserRequiresAdmin(t *testing.T) {
+	t.Parallel()
+	a := testutil.NewApp(t)
+	fixtures := testutil.LoadFixtures(t, a)
+	alice := fixtures.User(t, "alice@example.com")
+	admin := fixtures.User(t, "admin@example.com")
+	client := testutil.NewClient(t, a)
+
+	data := types.UserCreate{Email: "dave@example.com", Name: "Dave", Password: "correct horse battery"}
+	if resp := client.Post("/users", data); resp.Status != http.StatusUnauthorized {
+		t.Fatalf("created a user logged out: %s", resp)
+	}
+
+	client.Login(alice.Email, *alice.Password)
+	if resp := client.Post("/users", data); resp.Status != http.StatusForbidden {
+		t.Fatalf("created a user as a user who isn't an admin: %s", resp)
+	}
+
+	client.Login(admin.Email, *admin.Password)
+	created := testutil.Decode[models.User](t, client.Post("/users", data))
+	if created.Email != data.Email {
+		t.Fatalf("unexpected user: %+v", created)
+	}
+}
+
+func TestTransferDeletionRequiresAdmin(t *testing.T) {
+	t.Parallel()
+	a := testutil.NewApp(t, func(cfg *config.Config) {
+		cfg.Privacy.DeletionPolicies = []string{config.DeletionAnonymize, config.DeletionTransfer}
+	})
+	fixtures := testutil.LoadFixtures(t, a)
+	alice := fixtures.User(t, "alice@example.com")
+	bob := fixtures.User(t, "bob@example.com")
+	admin := fixtures.User(t, "admin@example.com")
+	client := testutil.NewClient(t, a)
+
+	// Users can't push their content onto others, even if the policy is allowed
+	policy := config.DeletionTransfer
+	data := types.DeletionRequest{Policy: &policy, TransferTo: &bob.ID}
+	client.Login(alice.Email, *alice.Password)
+	if resp := client.Do(http.MethodDelete, "/users/"+alice.ID, data); resp.Status != http.StatusForbidden {
+		t.Fatalf("transferred the content as the user: %s", resp)
+	}
+
+	client.Login(admin.Email, *admin.Password)
+	deletion := testutil.Decode[models.UserDeletion](t, client.Do(http.MethodDelete, "/users/"+alice.ID, data))
+	if deletion.TransferTo == nil || *deletion.TransferTo != bob.ID {
+		t.Fatalf("unexpected deletion: %+v", deletion)
+	}
+}
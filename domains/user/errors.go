@@ -5,8 +5,27 @@ import (
 )
 
 var (
-	ErrUserNotFound    = e.New("user not found")
-	ErrAuthorNotFound  = e.New("author not found")
-	ErrNotAnEmail      = e.New("not an email")
-	ErrInvalidPassword = e.New("invalid password")
+	ErrUserNotFound         = e.New("user not found")
+	ErrAuthorNotFound       = e.New("author not found")
+	ErrNotAnEmail           = e.New("not an email")
+	ErrInvalidPassword      = e.New("invalid password")
+	ErrUserExists           = e.New("user already exists")
+	ErrEmailNotVerified     = e.New("email not verified")
+	ErrRegistrationClosed   = e.New("registration is closed")
+	ErrInvalidInvitation    = e.New("invalid or expired invitation")
+	ErrInvalidResetToken    = e.New("invalid or expired reset token")
+	ErrWeakPassword         = e.New("weak password")
+	ErrTwoFactorNotEnabled  = e.New("two-factor authentication is not enabled")
+	ErrTwoFactorEnabled     = e.New("two-factor authentication is enabled already")
+	ErrInvalidTwoFactorCode = e.New("invalid two-factor code")
+	ErrTooManyAttempts      = e.New("too many failed attempts, try again later")
+	ErrAccountNotLinked     = e.New("no account is linked to the identity")
+	ErrUserHasContent       = e.New("the user still owns content")
+	ErrInvalidEmailChange   = e.New("invalid or expired email change")
+	ErrDeletionNotFound     = e.New("account deletion not found")
+	ErrDeletionInProgress   = e.New("the account is being deleted already")
+	ErrDeletionPolicy       = e.New("deletion policy not allowed")
+	ErrDeletedUser          = e.New("the placeholder of deleted users can't be changed")
+	ErrInvalidProfile       = e.New("invalid profile")
+	ErrInvalidAvatar        = e.New("invalid avatar image")
 )
@@ -0,0 +1,12 @@
+package user
+
+// The events of the domain, published by the service, see events.Publish.
+
+// UserDeleted is published when the deletion of an account completes, after
+// the content of the user was handled according to the policy.
+type UserDeleted struct {
+	ID     string `json:"id"`
+	Policy string `json:"policy"` // One of the config.Deletion* policies
+}
+
+func (UserDeleted) EventName() string { return "user.deleted" }
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// UserDeletion is a request to delete the account of a user, it's carried out
+// in the background, since handling the content of the user may take a while.
+type UserDeletion struct {
+	ID          string     `json:"id" gorm:"primaryKey;size:36"`
+	UserID      string     `json:"user_id" gorm:"index;size:36"` // Not a foreign key, the request outlives the user
+	Policy      string     `json:"policy" gorm:"size:16"`        // What happens to the content of the user
+	TransferTo  *string    `json:"transfer_to" gorm:"size:36"`   // The user the content is transferred to
+	RequestedBy *string    `json:"requested_by" gorm:"size:36"`
+	Status      string     `json:"status" gorm:"size:16;index"`
+	Error       *string    `json:"error"` // Why the deletion failed
+	CreatedAt   time.Time  `json:"created_at"`
+	StartedAt   *time.Time `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
+func (d *UserDeletion) TableName() string {
+	return "user_deletion"
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// UserIdentity links the identity of a user at an external identity provider
+// to the user.
+type UserIdentity struct {
+	Provider    string     `json:"provider" gorm:"primaryKey"`
+	Subject     string     `json:"subject" gorm:"primaryKey"` // The ID of the user at the provider
+	UserID      string     `json:"user_id" gorm:"index;size:36"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+}
+
+func (i *UserIdentity) TableName() string {
+	return "user_identity"
+}
@@ -3,11 +3,124 @@
 //////////
 // source: user.go
 
+import type * as types from "../types"
+
+/**
+ * The user the content of deleted accounts is attributed to when it's
+ * anonymized, it can't log in since it has neither a password nor a reachable
+ * email.
+ */
+export const DeletedUserID = "00000000-0000-0000-0000-000000000000"
+export const DeletedUserEmail = "deleted-user@users.invalid"
+export const DeletedUserName = "Deleted user"
 export interface User {
+    id: string // Never changes, unlike the email
     email: string
     name: string
     password?: string
     birthdate?: string
     created_at: string /* RFC3339 */
     updated_at: string /* RFC3339 */
+    /**
+     * The time the user verified the email, users can't log in before that
+     */
+    email_verified_at?: string /* RFC3339 */
+    /**
+     * The new email the user is changing to, which takes effect once verified
+     */
+    pending_email?: string
+}
+
+//////////
+// source: password_reset.go
+
+/**
+ * PasswordResetToken is a token sent to a user to reset the password, only the
+ * hash of the token is stored.
+ */
+export interface PasswordResetToken {
+    user_id: string
+    created_at: string /* RFC3339 */
+    expires_at: string /* RFC3339 */
+    used_at?: string /* RFC3339 */ // The time the token was used or revoked
+}
+
+//////////
+// source: two_factor.go
+
+/**
+ * UserTotp is the TOTP second factor of a user, it's pending until the user
+ * confirms the enrolment with a valid code.
+ */
+export interface UserTotp {
+    user_id: string
+    created_at: string /* RFC3339 */
+    enabled_at?: string /* RFC3339 */
+}
+/**
+ * RecoveryCode is a single-use code that can be used instead of a TOTP code,
+ * only the hash of the code is stored.
+ */
+export interface RecoveryCode {
+    id: number /* uint */
+    user_id: string
+    created_at: string /* RFC3339 */
+    used_at?: string /* RFC3339 */
+}
+
+//////////
+// source: identity.go
+
+/**
+ * UserIdentity links the identity of a user at an external identity provider
+ * to the user.
+ */
+export interface UserIdentity {
+    provider: string
+    subject: string // The ID of the user at the provider
+    user_id: string
+    created_at: string /* RFC3339 */
+    last_login_at?: string /* RFC3339 */
+}
+
+//////////
+// source: deletion.go
+
+/**
+ * UserDeletion is a request to delete the account of a user, it's carried out
+ * in the background, since handling the content of the user may take a while.
+ */
+export interface UserDeletion {
+    id: string
+    user_id: string // Not a foreign key, the request outlives the user
+    policy: string // What happens to the content of the user
+    transfer_to?: string // The user the content is transferred to
+    requested_by?: string
+    status: string
+    error?: string // Why the deletion failed
+    created_at: string /* RFC3339 */
+    started_at?: string /* RFC3339 */
+    completed_at?: string /* RFC3339 */
+}
+
+//////////
+// source: profile.go
+
+/**
+ * UserProfile is the profile of a user beyond the account, its fields are shown
+ * to others according to their visibility.
+ */
+export interface UserProfile {
+    user_id: string
+    display_name?: string
+    bio?: string
+    links: types.ProfileLink[]
+    locale?: string
+    timezone?: string
+    /**
+     * The visibility of the fields by their names, the fields left out have
+     * the default visibility
+     */
+    visibility: { [key: string]: string }
+    updated_at: string /* RFC3339 */
 }
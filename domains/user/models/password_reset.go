@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// PasswordResetToken is a token sent to a user to reset the password, only the
+// hash of the token is stored.
+type PasswordResetToken struct {
+	TokenHash string     `json:"-" gorm:"primaryKey;size:64"`
+	UserID    string     `json:"user_id" gorm:"index;size:36"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"` // The time the token was used or revoked
+}
+
+func (t *PasswordResetToken) TableName() string {
+	return "password_reset_token"
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"bilingo/domains/user/types"
+)
+
+// UserProfile is the profile of a user beyond the account, its fields are shown
+// to others according to their visibility.
+type UserProfile struct {
+	UserID      string              `json:"user_id" gorm:"primaryKey;size:36"`
+	DisplayName *string             `json:"display_name" gorm:"size:64"`
+	Bio         *string             `json:"bio"`
+	Avatar      *string             `json:"-" gorm:"size:128"` // The storage key prefix of the avatar images
+	Links       []types.ProfileLink `json:"links" gorm:"serializer:json"`
+	Locale      *string             `json:"locale" gorm:"size:35"`
+	Timezone    *string             `json:"timezone" gorm:"size:64"`
+	// The visibility of the fields by their names, the fields left out have
+	// the default visibility
+	Visibility map[string]string `json:"visibility" gorm:"serializer:json"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+func (p *UserProfile) TableName() string {
+	return "user_profile"
+}
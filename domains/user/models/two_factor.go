@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// UserTotp is the TOTP second factor of a user, it's pending until the user
+// confirms the enrolment with a valid code.
+type UserTotp struct {
+	UserID         string     `json:"user_id" gorm:"primaryKey;size:36"`
+	Secret         string     `json:"-"` // The base32 encoded shared secret
+	CreatedAt      time.Time  `json:"created_at"`
+	EnabledAt      *time.Time `json:"enabled_at"`
+	LastUsedStep   int64      `json:"-"` // The time step of the last accepted code, codes can't be replayed
+	FailedAttempts int        `json:"-"` // The number of consecutive failed attempts
+	LastFailedAt   *time.Time `json:"-"`
+}
+
+func (t *UserTotp) TableName() string {
+	return "user_totp"
+}
+
+// RecoveryCode is a single-use code that can be used instead of a TOTP code,
+// only the hash of the code is stored.
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    string     `json:"user_id" gorm:"index;size:36"`
+	CodeHash  string     `json:"-" gorm:"size:64"`
+	CreatedAt time.Time  `json:"created_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+func (c *RecoveryCode) TableName() string {
+	return "recovery_code"
+}
@@ -4,15 +4,40 @@ import (
 	"time"
 )
 
+// The user the content of deleted accounts is attributed to when it's
+// anonymized, it can't log in since it has neither a password nor a reachable
+// email.
+const (
+	DeletedUserID    = "00000000-0000-0000-0000-000000000000"
+	DeletedUserEmail = "deleted-user@users.invalid"
+	DeletedUserName  = "Deleted user"
+)
+
 type User struct {
-	Email     string    `json:"email" gorm:"primaryKey"`
+	ID        string    `json:"id" gorm:"primaryKey;size:36"` // Never changes, unlike the email
+	Email     string    `json:"email" gorm:"uniqueIndex;size:255"`
 	Name      string    `json:"name"`
 	Password  *string   `json:"password"`
 	Birthdate *string   `json:"birthdate"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	// The time the user verified the email, users can't log in before that
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	// The new email the user is changing to, which takes effect once verified
+	PendingEmail *string `json:"pending_email" gorm:"size:255"`
 }
 
 func (u *User) TableName() string {
 	return "user"
 }
+
+func (u *User) AuditInfo() (string, string) {
+	return "user", u.ID
+}
+
+// AuditSnapshot omits the password hash from the oplogs.
+func (u *User) AuditSnapshot() any {
+	snapshot := *u
+	snapshot.Password = nil
+	return &snapshot
+}
@@ -0,0 +1,21 @@
+// Package module assembles the user domain into an app.
+package module
+
+import (
+	"bilingo/domains/user/api"
+	"bilingo/domains/user/repo"
+	"bilingo/domains/user/service"
+	"bilingo/server/app"
+	"bilingo/server/jobs"
+	"bilingo/server/sso"
+)
+
+// Register provides the repositories of the users and the identity providers,
+// registers the jobs of the users, and adds the routes of the users.
+func Register(a *app.App) error {
+	repo.Provide(a)
+	app.Provide(a, sso.NewRegistry(a.Config.Auth.Sso))
+	service.RegisterJobs(jobs.ForApp(a))
+	api.Register(a)
+	return nil
+}
@@ -0,0 +1,131 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	domain "bilingo/domains/user"
+	"bilingo/domains/user/models"
+	"bilingo/domains/user/tables"
+	"bilingo/domains/user/types"
+	"bilingo/server/db"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DeletionRepo struct{}
+
+func (r *DeletionRepo) Create(ctx context.Context, deletion *models.UserDeletion) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	if err := gorm.G[models.UserDeletion](conn).Create(ctx, deletion); err != nil {
+		return fmt.Errorf("failed to create account deletion: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DeletionRepo) Get(ctx context.Context, id string) (*models.UserDeletion, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	deletions, err := gorm.G[models.UserDeletion](conn).
+		Where(tables.UserDeletion.ID.Eq(id)).
+		Limit(1).
+		Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find account deletion: %w", err)
+	} else if len(deletions) == 0 {
+		return nil, domain.ErrDeletionNotFound
+	}
+
+	return &deletions[0], nil
+}
+
+func (r *DeletionRepo) GetUnfinished(ctx context.Context, userId string) (*models.UserDeletion, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	deletions, err := gorm.G[models.UserDeletion](conn).
+		Where(
+			tables.UserDeletion.UserID.Eq(userId),
+			tables.UserDeletion.Status.In(types.DeletionPending, types.DeletionRunning),
+		).
+		Limit(1).
+		Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find account deletion: %w", err)
+	} else if len(deletions) == 0 {
+		return nil, domain.ErrDeletionNotFound
+	}
+
+	return &deletions[0], nil
+}
+
+func (r *DeletionRepo) ListUnfinished(ctx context.Context) ([]models.UserDeletion, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	deletions, err := gorm.G[models.UserDeletion](conn).
+		Where(tables.UserDeletion.Status.In(types.DeletionPending, types.DeletionRunning)).
+		Order(tables.UserDeletion.CreatedAt.Asc()).
+		Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list account deletions: %w", err)
+	}
+
+	return deletions, nil
+}
+
+func (r *DeletionRepo) Start(ctx context.Context, id string, now time.Time) error {
+	return r.setStatus(ctx, id,
+		tables.UserDeletion.Status.Set(types.DeletionRunning),
+		tables.UserDeletion.StartedAt.Set(now),
+	)
+}
+
+func (r *DeletionRepo) Finish(ctx context.Context, id string, reason *string, now time.Time) error {
+	if reason != nil {
+		return r.setStatus(ctx, id,
+			tables.UserDeletion.Status.Set(types.DeletionFailed),
+			tables.UserDeletion.Error.Set(*reason),
+			tables.UserDeletion.CompletedAt.Set(now),
+		)
+	}
+
+	return r.setStatus(ctx, id,
+		tables.UserDeletion.Status.Set(types.DeletionCompleted),
+		tables.UserDeletion.Error.SetExpr(gorm.Expr("NULL")),
+		tables.UserDeletion.CompletedAt.Set(now),
+	)
+}
+
+func (r *DeletionRepo) setStatus(ctx context.Context, id string, updates ...clause.Assigner) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	rowsAffected, err := gorm.G[models.UserDeletion](conn).
+		Where(tables.UserDeletion.ID.Eq(id)).
+		Set(updates...).
+		Update(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to update account deletion: %w", err)
+	} else if rowsAffected == 0 {
+		return domain.ErrDeletionNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,17 @@
+package impl_test
+
+import (
+	"testing"
+
+	"bilingo/domains/user/repo"
+	impl "bilingo/domains/user/repo/db"
+	"bilingo/domains/user/repo/repotest"
+	"bilingo/server/testutil"
+)
+
+func TestDeletionRepo(t *testing.T) {
+	repotest.TestDeletionRepo(t, func(t *testing.T) repo.IDeletionRepo {
+		testutil.NewDB(t)
+		return &impl.DeletionRepo{}
+	})
+}
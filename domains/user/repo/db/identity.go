@@ -0,0 +1,102 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	domain "bilingo/domains/user"
+	"bilingo/domains/user/models"
+	"bilingo/domains/user/tables"
+	"bilingo/server/db"
+
+	"gorm.io/gorm"
+)
+
+type IdentityRepo struct{}
+
+func (r *IdentityRepo) Get(ctx context.Context, provider string, subject string) (*models.UserIdentity, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	identities, err := gorm.G[models.UserIdentity](conn).
+		Where(
+			tables.UserIdentity.Provider.Eq(provider),
+			tables.UserIdentity.Subject.Eq(subject),
+		).
+		Limit(1).
+		Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find identity: %w", err)
+	} else if len(identities) == 0 {
+		return nil, domain.ErrAccountNotLinked
+	}
+
+	return &identities[0], nil
+}
+
+func (r *IdentityRepo) Create(ctx context.Context, identity *models.UserIdentity) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	if err := gorm.G[models.UserIdentity](conn).Create(ctx, identity); err != nil {
+		return fmt.Errorf("failed to create identity: %w", err)
+	}
+
+	return nil
+}
+
+func (r *IdentityRepo) List(ctx context.Context, userId string) ([]models.UserIdentity, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	identities, err := gorm.G[models.UserIdentity](conn).
+		Where(tables.UserIdentity.UserID.Eq(userId)).
+		Order(tables.UserIdentity.CreatedAt.Asc()).
+		Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+
+	return identities, nil
+}
+
+func (r *IdentityRepo) TouchLogin(ctx context.Context, provider string, subject string, now time.Time) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	_, err = gorm.G[models.UserIdentity](conn).
+		Where(
+			tables.UserIdentity.Provider.Eq(provider),
+			tables.UserIdentity.Subject.Eq(subject),
+		).
+		Set(tables.UserIdentity.LastLoginAt.Set(now)).
+		Update(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to update identity: %w", err)
+	}
+
+	return nil
+}
+
+func (r *IdentityRepo) DeleteAll(ctx context.Context, userId string) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	_, err = gorm.G[models.UserIdentity](conn).Where(tables.UserIdentity.UserID.Eq(userId)).Delete(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete identities: %w", err)
+	}
+
+	return nil
+}
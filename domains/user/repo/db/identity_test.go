@@ -0,0 +1,17 @@
+package impl_test
+
+import (
+	"testing"
+
+	"bilingo/domains/user/repo"
+	impl "bilingo/domains/user/repo/db"
+	"bilingo/domains/user/repo/repotest"
+	"bilingo/server/testutil"
+)
+
+func TestIdentityRepo(t *testing.T) {
+	repotest.TestIdentityRepo(t, func(t *testing.T) repo.IIdentityRepo {
+		testutil.NewDB(t)
+		return &impl.IdentityRepo{}
+	})
+}
@@ -0,0 +1,210 @@
+package impl
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	domain "bilingo/domains/user"
+	"bilingo/domains/user/models"
+	"bilingo/domains/user/tables"
+	"bilingo/server/db"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func init() {
+	db.RegisterMigrations(
+		domain.DBBinding,
+		db.Migration{ID: "2026101900_user_create_table", Up: db.CreateTableIfNotExists(&models.User{})},
+		db.Migration{ID: "2026101903_user_email_verified_at", Up: addEmailVerifiedAt},
+		db.Migration{ID: "2026101904_user_create_password_reset_token_table", Up: db.CreateTableIfNotExists(&models.PasswordResetToken{})},
+		db.Migration{ID: "2026101905_user_create_user_totp_table", Up: db.CreateTableIfNotExists(&models.UserTotp{})},
+		db.Migration{ID: "2026101905_user_create_recovery_code_table", Up: db.CreateTableIfNotExists(&models.RecoveryCode{})},
+		db.Migration{ID: "2026101906_user_create_user_identity_table", Up: db.CreateTableIfNotExists(&models.UserIdentity{})},
+		db.Migration{ID: "2026101907_user_surrogate_id", Up: addUserId},
+		db.Migration{ID: "2026101909_user_create_user_deletion_table", Up: db.CreateTableIfNotExists(&models.UserDeletion{})},
+		db.Migration{ID: "2026101909_user_create_deleted_user", Up: createDeletedUser},
+		db.Migration{ID: "2026101910_user_create_user_profile_table", Up: db.CreateTableIfNotExists(&models.UserProfile{})},
+	)
+}
+
+// addEmailVerifiedAt adds the email_verified_at column to user, users created
+// before email verification are considered verified.
+func addEmailVerifiedAt(tx *gorm.DB) error {
+	if !tx.Migrator().HasColumn(&models.User{}, "EmailVerifiedAt") {
+		if err := tx.Migrator().AddColumn(&models.User{}, "EmailVerifiedAt"); err != nil {
+			return err
+		}
+	}
+
+	return tx.Model(&models.User{}).
+		Where(tables.User.EmailVerifiedAt.IsNull()).
+		Update("email_verified_at", gorm.Expr("created_at")).Error
+}
+
+// createDeletedUser creates the user the content of deleted accounts is
+// attributed to when it's anonymized.
+func createDeletedUser(tx *gorm.DB) error {
+	now := time.Now()
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.User{
+		ID:              models.DeletedUserID,
+		Email:           models.DeletedUserEmail,
+		Name:            models.DeletedUserName,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		EmailVerifiedAt: &now,
+	}).Error
+}
+
+// addUserId replaces the email primary key of user with a generated ID, and
+// the emails the other user tables refer to users by with the IDs. The tables
+// are rebuilt, since primary keys can't be altered everywhere.
+func addUserId(tx *gorm.DB) error {
+	if !tx.Migrator().HasColumn(&models.User{}, "ID") {
+		if err := rebuildUserTable(tx); err != nil {
+			return fmt.Errorf("failed to migrate user: %w", err)
+		}
+	}
+
+	for _, model := range []any{
+		&models.PasswordResetToken{},
+		&models.UserTotp{},
+		&models.RecoveryCode{},
+		&models.UserIdentity{},
+	} {
+		if !tx.Migrator().HasColumn(model, "email") {
+			continue // Created with the user ID already
+		}
+		if err := rebuildWithUserId(tx, model); err != nil {
+			return fmt.Errorf("failed to migrate %T: %w", model, err)
+		}
+	}
+
+	return nil
+}
+
+func rebuildUserTable(tx *gorm.DB) error {
+	return rebuildTable(tx, &models.User{}, func(table string, newTable string) error {
+		// Columns missing in the old table are left empty
+		var users []models.User
+		if err := tx.Table(table).Find(&users).Error; err != nil {
+			return err
+		}
+		for i := range users {
+			users[i].ID = uuid.NewString()
+		}
+		if len(users) == 0 {
+			return nil
+		}
+		return tx.Table(newTable).CreateInBatches(&users, 500).Error
+	})
+}
+
+// rebuildWithUserId rebuilds the table of the model with the user IDs instead
+// of the emails, rows of users who don't exist anymore are dropped.
+func rebuildWithUserId(tx *gorm.DB, model any) error {
+	return rebuildTable(tx, model, func(table string, newTable string) error {
+		stmt := &gorm.Statement{DB: tx}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+
+		columns := []string{"u.id"}
+		names := []string{"user_id"}
+		for _, name := range stmt.Schema.DBNames {
+			if name != "user_id" {
+				columns = append(columns, "t."+tx.Statement.Quote(name))
+				names = append(names, tx.Statement.Quote(name))
+			}
+		}
+
+		return tx.Exec(
+			fmt.Sprintf("INSERT INTO ? (%s) SELECT %s FROM ? t JOIN ? u ON u.email = t.email",
+				strings.Join(names, ", "), strings.Join(columns, ", ")),
+			clause.Table{Name: newTable}, clause.Table{Name: table}, clause.Table{Name: (&models.User{}).TableName()},
+		).Error
+	})
+}
+
+// rebuildTable replaces the table of the model with a new one created from the
+// model, which fill copies the rows of the old table to.
+func rebuildTable(tx *gorm.DB, model any, fill func(table string, newTable string) error) error {
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(model); err != nil {
+		return err
+	}
+	table := stmt.Schema.Table
+	newTable := table + "__new"
+
+	migrator := tx.Migrator()
+	if err := tx.Table(newTable).Migrator().CreateTable(model); err != nil {
+		return err
+	}
+	if err := fill(table, newTable); err != nil {
+		return err
+	}
+	if err := migrator.DropTable(table); err != nil {
+		return err
+	}
+	if err := migrator.RenameTable(newTable, table); err != nil {
+		return err
+	}
+
+	// The indexes are named after the new table, which is renamed
+	for _, idx := range stmt.Schema.ParseIndexes() {
+		tmpName := strings.Replace(idx.Name, table, newTable, 1)
+		if err := migrator.RenameIndex(model, tmpName, idx.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResolveUserRefs maps the references to users stored before user IDs, which
+// are emails, to the IDs of the users, references which are IDs already are
+// mapped to themselves. It's meant for the migrations of other domains.
+//
+// With placeholders, a user without password is created for each reference to
+// no user, e.g. the authors whose accounts were deleted, otherwise such
+// references are left out. Placeholders are named after the reference, but
+// their emails are unreachable ones under users.invalid, so no mail goes to
+// whoever owns the address now and nobody can claim the account with it.
+func ResolveUserRefs(tx *gorm.DB, refs []string, placeholders bool) (map[string]string, error) {
+	ids := make(map[string]string, len(refs))
+	if len(refs) == 0 {
+		return ids, nil
+	}
+
+	var users []models.User
+	err := tx.Session(&gorm.Session{NewDB: true}).
+		Where(tables.User.Email.In(refs...)).
+		Or(tables.User.ID.In(refs...)).
+		Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		ids[user.Email] = user.ID
+		ids[user.ID] = user.ID
+	}
+
+	now := time.Now()
+	for _, ref := range refs {
+		if _, ok := ids[ref]; ok || !placeholders || ref == "" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(ref, "@")
+		id := uuid.NewString()
+		user := models.User{ID: id, Email: id + "@users.invalid", Name: name, CreatedAt: now, UpdatedAt: now}
+		if err := tx.Session(&gorm.Session{NewDB: true}).Create(&user).Error; err != nil {
+			return nil, fmt.Errorf("failed to create placeholder user %s: %w", ref, err)
+		}
+		ids[ref] = user.ID
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,128 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	domain "bilingo/domains/user"
+	"bilingo/domains/user/models"
+	"bilingo/domains/user/tables"
+	"bilingo/server/db"
+
+	"gorm.io/gorm"
+)
+
+type PasswordResetRepo struct{}
+
+func (r *PasswordResetRepo) Create(ctx context.Context, token *models.PasswordResetToken) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	if err := gorm.G[models.PasswordResetToken](conn).Create(ctx, token); err != nil {
+		return fmt.Errorf("failed to create password reset token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PasswordResetRepo) Get(ctx context.Context, tokenHash string, now time.Time) (*models.PasswordResetToken, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	tokens, err := gorm.G[models.PasswordResetToken](conn).
+		Where(
+			tables.PasswordResetToken.TokenHash.Eq(tokenHash),
+			tables.PasswordResetToken.UsedAt.IsNull(),
+			tables.PasswordResetToken.ExpiresAt.Gt(now),
+		).
+		Limit(1).
+		Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find password reset token: %w", err)
+	} else if len(tokens) == 0 {
+		return nil, domain.ErrInvalidResetToken
+	}
+
+	return &tokens[0], nil
+}
+
+func (r *PasswordResetRepo) Consume(ctx context.Context, tokenHash string, now time.Time) (*models.PasswordResetToken, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	var token models.PasswordResetToken
+	err = conn.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The conditional update makes sure the token is only used once even if
+		// requests race
+		rowsAffected, err := gorm.G[models.PasswordResetToken](tx).
+			Where(
+				tables.PasswordResetToken.TokenHash.Eq(tokenHash),
+				tables.PasswordResetToken.UsedAt.IsNull(),
+				tables.PasswordResetToken.ExpiresAt.Gt(now),
+			).
+			Set(tables.PasswordResetToken.UsedAt.Set(now)).
+			Update(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to use password reset token: %w", err)
+		} else if rowsAffected == 0 {
+			return domain.ErrInvalidResetToken
+		}
+
+		token, err = gorm.G[models.PasswordResetToken](tx).
+			Where(tables.PasswordResetToken.TokenHash.Eq(tokenHash)).
+			First(ctx)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.ErrInvalidResetToken
+		} else if err != nil {
+			return fmt.Errorf("failed to find password reset token: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (r *PasswordResetRepo) RevokeAll(ctx context.Context, userId string, now time.Time) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	_, err = gorm.G[models.PasswordResetToken](conn).
+		Where(
+			tables.PasswordResetToken.UserID.Eq(userId),
+			tables.PasswordResetToken.UsedAt.IsNull(),
+		).
+		Set(tables.PasswordResetToken.UsedAt.Set(now)).
+		Update(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to revoke password reset tokens: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PasswordResetRepo) DeleteAll(ctx context.Context, userId string) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	_, err = gorm.G[models.PasswordResetToken](conn).Where(tables.PasswordResetToken.UserID.Eq(userId)).Delete(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete password reset tokens: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,17 @@
+package impl_test
+
+import (
+	"testing"
+
+	"bilingo/domains/user/repo"
+	impl "bilingo/domains/user/repo/db"
+	"bilingo/domains/user/repo/repotest"
+	"bilingo/server/testutil"
+)
+
+func TestPasswordResetRepo(t *testing.T) {
+	repotest.TestPasswordResetRepo(t, func(t *testing.T) repo.IPasswordResetRepo {
+		testutil.NewDB(t)
+		return &impl.PasswordResetRepo{}
+	})
+}
@@ -0,0 +1,63 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+
+	domain "bilingo/domains/user"
+	"bilingo/domains/user/models"
+	"bilingo/domains/user/tables"
+	"bilingo/server/db"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ProfileRepo struct{}
+
+func (r *ProfileRepo) Get(ctx context.Context, userId string) (*models.UserProfile, error) {
+	conn, err := db.Reader(ctx, domain.DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	profiles, err := gorm.G[models.UserProfile](conn).
+		Where(tables.UserProfile.UserID.Eq(userId)).
+		Limit(1).
+		Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find profile: %w", err)
+	} else if len(profiles) == 0 {
+		return &models.UserProfile{UserID: userId}, nil
+	}
+
+	return &profiles[0], nil
+}
+
+func (r *ProfileRepo) Save(ctx context.Context, profile *models.UserProfile) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	err = gorm.G[models.UserProfile](conn, clause.OnConflict{UpdateAll: true}).Create(ctx, profile)
+	if err != nil {
+		return fmt.Errorf("failed to save profile: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ProfileRepo) Delete(ctx context.Context, userId string) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	_, err = gorm.G[models.UserProfile](conn).Where(tables.UserProfile.UserID.Eq(userId)).Delete(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete profile: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,17 @@
+package impl_test
+
+import (
+	"testing"
+
+	"bilingo/domains/user/repo"
+	impl "bilingo/domains/user/repo/db"
+	"bilingo/domains/user/repo/repotest"
+	"bilingo/server/testutil"
+)
+
+func TestProfileRepo(t *testing.T) {
+	repotest.TestProfileRepo(t, func(t *testing.T) repo.IProfileRepo {
+		testutil.NewDB(t)
+		return &impl.ProfileRepo{}
+	})
+}
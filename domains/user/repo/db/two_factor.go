@@ -0,0 +1,208 @@
+package impl
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	domain "bilingo/domains/user"
+	"bilingo/domains/user/models"
+	"bilingo/domains/user/tables"
+	"bilingo/server/db"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TwoFactorRepo struct{}
+
+func (r *TwoFactorRepo) GetTotp(ctx context.Context, userId string) (*models.UserTotp, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	totps, err := gorm.G[models.UserTotp](conn).Where(tables.UserTotp.UserID.Eq(userId)).Limit(1).Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find totp: %w", err)
+	} else if len(totps) == 0 {
+		return nil, domain.ErrTwoFactorNotEnabled
+	}
+
+	return &totps[0], nil
+}
+
+func (r *TwoFactorRepo) SaveTotp(ctx context.Context, totp *models.UserTotp) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	err = gorm.G[models.UserTotp](conn, clause.OnConflict{UpdateAll: true}).Create(ctx, totp)
+	if err != nil {
+		return fmt.Errorf("failed to save totp: %w", err)
+	}
+
+	return nil
+}
+
+func (r *TwoFactorRepo) EnableTotp(ctx context.Context, userId string, step int64, now time.Time) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	rowsAffected, err := gorm.G[models.UserTotp](conn).
+		Where(tables.UserTotp.UserID.Eq(userId)).
+		Set(
+			tables.UserTotp.EnabledAt.Set(now),
+			tables.UserTotp.LastUsedStep.Set(step),
+			tables.UserTotp.FailedAttempts.Set(0),
+		).
+		Update(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to enable totp: %w", err)
+	} else if rowsAffected == 0 {
+		return domain.ErrTwoFactorNotEnabled
+	}
+
+	return nil
+}
+
+func (r *TwoFactorRepo) UseTotpStep(ctx context.Context, userId string, step int64) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	// The conditional update rejects replays even if requests race
+	rowsAffected, err := gorm.G[models.UserTotp](conn).
+		Where(tables.UserTotp.UserID.Eq(userId), tables.UserTotp.LastUsedStep.Lt(step)).
+		Set(
+			tables.UserTotp.LastUsedStep.Set(step),
+			tables.UserTotp.FailedAttempts.Set(0),
+		).
+		Update(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to update totp: %w", err)
+	} else if rowsAffected == 0 {
+		return domain.ErrInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+func (r *TwoFactorRepo) RecordFailure(ctx context.Context, userId string, now time.Time) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	_, err = gorm.G[models.UserTotp](conn).
+		Where(tables.UserTotp.UserID.Eq(userId)).
+		Set(
+			tables.UserTotp.FailedAttempts.Incr(1),
+			tables.UserTotp.LastFailedAt.Set(now),
+		).
+		Update(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to update totp: %w", err)
+	}
+
+	return nil
+}
+
+func (r *TwoFactorRepo) ListEnabled(ctx context.Context) ([]models.UserTotp, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	totps, err := gorm.G[models.UserTotp](conn).
+		Where(tables.UserTotp.EnabledAt.IsNotNull()).
+		Order(tables.UserTotp.UserID.Asc()).
+		Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list totps: %w", err)
+	}
+
+	return totps, nil
+}
+
+func (r *TwoFactorRepo) Delete(ctx context.Context, userId string) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	return conn.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := gorm.G[models.UserTotp](tx).Where(tables.UserTotp.UserID.Eq(userId)).Delete(ctx); err != nil {
+			return fmt.Errorf("failed to delete totp: %w", err)
+		}
+		if _, err := gorm.G[models.RecoveryCode](tx).Where(tables.RecoveryCode.UserID.Eq(userId)).Delete(ctx); err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *TwoFactorRepo) ReplaceRecoveryCodes(ctx context.Context, userId string, codeHashes []string, now time.Time) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	codes := make([]models.RecoveryCode, 0, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes = append(codes, models.RecoveryCode{UserID: userId, CodeHash: hash, CreatedAt: now})
+	}
+
+	return conn.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := gorm.G[models.RecoveryCode](tx).Where(tables.RecoveryCode.UserID.Eq(userId)).Delete(ctx); err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+		if err := gorm.G[models.RecoveryCode](tx).CreateInBatches(ctx, &codes, 100); err != nil {
+			return fmt.Errorf("failed to create recovery codes: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *TwoFactorRepo) UseRecoveryCode(ctx context.Context, userId string, codeHash string, now time.Time) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	rowsAffected, err := gorm.G[models.RecoveryCode](conn).
+		Where(
+			tables.RecoveryCode.UserID.Eq(userId),
+			tables.RecoveryCode.CodeHash.Eq(codeHash),
+			tables.RecoveryCode.UsedAt.IsNull(),
+		).
+		Set(tables.RecoveryCode.UsedAt.Set(now)).
+		Update(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	} else if rowsAffected == 0 {
+		return domain.ErrInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+func (r *TwoFactorRepo) CountRecoveryCodes(ctx context.Context, userId string) (int, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return 0, db.ConnError(err)
+	}
+
+	count, err := gorm.G[models.RecoveryCode](conn).
+		Where(tables.RecoveryCode.UserID.Eq(userId), tables.RecoveryCode.UsedAt.IsNull()).
+		Count(ctx, "*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+
+	return int(count), nil
+}
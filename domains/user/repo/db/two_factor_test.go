@@ -0,0 +1,17 @@
+package impl_test
+
+import (
+	"testing"
+
+	"bilingo/domains/user/repo"
+	impl "bilingo/domains/user/repo/db"
+	"bilingo/domains/user/repo/repotest"
+	"bilingo/server/testutil"
+)
+
+func TestTwoFactorRepo(t *testing.T) {
+	repotest.TestTwoFactorRepo(t, func(t *testing.T) repo.ITwoFactorRepo {
+		testutil.NewDB(t)
+		return &impl.TwoFactorRepo{}
+	})
+}
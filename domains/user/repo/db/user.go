@@ -13,30 +13,39 @@ import (
 	"bilingo/domains/user/types"
 	"bilingo/server/db"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
 type UserRepo struct{}
 
-func (r *UserRepo) Get(ctx context.Context, email string) (*models.User, error) {
-	conn, err := db.Default()
+func (r *UserRepo) Get(ctx context.Context, id string) (*models.User, error) {
+	return r.find(ctx, tables.User.ID.Eq(id))
+}
+
+func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.find(ctx, tables.User.Email.Eq(email))
+}
+
+func (r *UserRepo) find(ctx context.Context, cond clause.Expression) (*models.User, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
 	if err != nil {
 		return nil, db.ConnError(err)
 	}
 
-	user, err := gorm.G[models.User](conn).Where(tables.User.Email.Eq(email)).First(ctx)
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, domain.ErrUserNotFound
-	} else if err != nil {
+	users, err := gorm.G[models.User](conn).Where(cond).Limit(1).Find(ctx)
+	if err != nil {
 		return nil, fmt.Errorf("failed to find user: %w", err)
+	} else if len(users) == 0 {
+		return nil, domain.ErrUserNotFound
 	}
 
-	return &user, nil
+	return &users[0], nil
 }
 
 func (r *UserRepo) List(ctx context.Context, query types.UserListQuery) (*common.PaginatedResult[models.User], error) {
-	conn, err := db.Default()
+	conn, err := db.Reader(ctx, domain.DBBinding)
 	if err != nil {
 		return nil, db.ConnError(err)
 	}
@@ -45,12 +54,14 @@ func (r *UserRepo) List(ctx context.Context, query types.UserListQuery) (*common
 
 	if query.Search != nil && *query.Search != "" {
 		likePattern := "%" + *query.Search + "%"
-		q = q.Where(
-			q.Or(
-				tables.User.Name.Like(likePattern),
-				tables.User.Email.Like(likePattern),
-			),
-		)
+		q = q.Where(clause.Or(
+			tables.User.Name.Like(likePattern),
+			tables.User.Email.Like(likePattern),
+		))
+	}
+
+	if query.Ids != nil && len(*query.Ids) > 0 {
+		q = q.Where(tables.User.ID.In(*query.Ids...))
 	}
 
 	if query.Emails != nil && len(*query.Emails) > 0 {
@@ -85,30 +96,37 @@ func (r *UserRepo) List(ctx context.Context, query types.UserListQuery) (*common
 }
 
 func (r *UserRepo) Create(ctx context.Context, data *types.UserCreate) (*models.User, error) {
-	conn, err := db.Default()
+	conn, err := db.For(ctx, domain.DBBinding)
 	if err != nil {
 		return nil, db.ConnError(err)
 	}
 
 	now := time.Now()
 	user := models.User{
+		ID:        uuid.NewString(),
 		CreatedAt: now,
 		UpdatedAt: now,
 		Email:     data.Email,
 		Name:      data.Name,
-		Password:  &data.Password,
 		Birthdate: data.Birthdate,
+
+		EmailVerifiedAt: data.EmailVerifiedAt,
+	}
+	if data.Password != "" {
+		user.Password = &data.Password // Users signed up through SSO have no password
 	}
 
-	if err := gorm.G[models.User](conn).Create(ctx, &user); err != nil {
+	if err := gorm.G[models.User](conn).Create(ctx, &user); errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, domain.ErrUserExists
+	} else if err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
 	return &user, nil
 }
 
-func (r *UserRepo) Update(ctx context.Context, email string, data *types.UserUpdate) (*models.User, error) {
-	user, err := r.Get(ctx, email)
+func (r *UserRepo) Update(ctx context.Context, id string, data *types.UserUpdate) (*models.User, error) {
+	user, err := r.Get(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -119,10 +137,10 @@ func (r *UserRepo) Update(ctx context.Context, email string, data *types.UserUpd
 	if data.Name != nil && *data.Name != "" && *data.Name != user.Name {
 		updates = append(updates, tables.User.Name.Set(*data.Name))
 	}
-	if data.Password != nil && *data.Password != "" && *data.Password != *user.Password {
+	if data.Password != nil && *data.Password != "" && (user.Password == nil || *data.Password != *user.Password) {
 		updates = append(updates, tables.User.Password.Set(*data.Password))
 	}
-	if data.Birthdate != nil && *data.Birthdate != "" && *data.Birthdate != *user.Birthdate {
+	if data.Birthdate != nil && *data.Birthdate != "" && (user.Birthdate == nil || *data.Birthdate != *user.Birthdate) {
 		updates = append(updates, tables.User.Birthdate.Set(*data.Birthdate))
 	}
 
@@ -132,29 +150,31 @@ func (r *UserRepo) Update(ctx context.Context, email string, data *types.UserUpd
 
 	updates = append(updates, tables.User.UpdatedAt.Set(time.Now()))
 
-	conn, err := db.Default()
+	conn, err := db.For(ctx, domain.DBBinding)
 	if err != nil {
 		return nil, db.ConnError(err)
 	}
 
-	rowsAffected, err := gorm.G[models.User](conn).Where(tables.User.Email.Eq(email)).Set(updates...).Update(ctx)
+	rowsAffected, err := gorm.G[models.User](conn).Where(tables.User.ID.Eq(id)).Set(updates...).Update(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	} else if rowsAffected == 0 {
 		return nil, domain.ErrUserNotFound
 	}
 
-	return r.Get(ctx, email)
+	return r.Get(ctx, id)
 }
 
-func (r *UserRepo) Delete(ctx context.Context, email string) error {
-	conn, err := db.Default()
+func (r *UserRepo) Delete(ctx context.Context, id string) error {
+	conn, err := db.For(ctx, domain.DBBinding)
 	if err != nil {
 		return db.ConnError(err)
 	}
 
-	rowsAffected, err := gorm.G[models.User](conn).Where(tables.User.Email.Eq(email)).Delete(ctx)
-	if err != nil {
+	rowsAffected, err := gorm.G[models.User](conn).Where(tables.User.ID.Eq(id)).Delete(ctx)
+	if errors.Is(err, gorm.ErrForeignKeyViolated) {
+		return domain.ErrUserHasContent
+	} else if err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	} else if rowsAffected == 0 {
 		return domain.ErrUserNotFound
@@ -162,3 +182,73 @@ func (r *UserRepo) Delete(ctx context.Context, email string) error {
 
 	return nil
 }
+
+func (r *UserRepo) SetEmailVerified(ctx context.Context, id string, verifiedAt time.Time) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	rowsAffected, err := gorm.G[models.User](conn).
+		Where(tables.User.ID.Eq(id)).
+		Set(tables.User.EmailVerifiedAt.Set(verifiedAt)).
+		Update(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	} else if rowsAffected == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *UserRepo) SetPendingEmail(ctx context.Context, id string, email *string) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	assignment := tables.User.PendingEmail.SetExpr(gorm.Expr("NULL"))
+	if email != nil {
+		assignment = tables.User.PendingEmail.Set(*email)
+	}
+
+	rowsAffected, err := gorm.G[models.User](conn).
+		Where(tables.User.ID.Eq(id)).
+		Set(assignment, tables.User.UpdatedAt.Set(time.Now())).
+		Update(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	} else if rowsAffected == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *UserRepo) ChangeEmail(ctx context.Context, id string, email string, verifiedAt time.Time) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	// The condition makes sure only the latest requested change is applied
+	rowsAffected, err := gorm.G[models.User](conn).
+		Where(tables.User.ID.Eq(id), tables.User.PendingEmail.Eq(email)).
+		Set(
+			tables.User.Email.Set(email),
+			tables.User.PendingEmail.SetExpr(gorm.Expr("NULL")),
+			tables.User.EmailVerifiedAt.Set(verifiedAt),
+			tables.User.UpdatedAt.Set(verifiedAt),
+		).
+		Update(ctx)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return domain.ErrUserExists
+	} else if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	} else if rowsAffected == 0 {
+		return domain.ErrInvalidEmailChange
+	}
+
+	return nil
+}
@@ -0,0 +1,17 @@
+package impl_test
+
+import (
+	"testing"
+
+	"bilingo/domains/user/repo"
+	impl "bilingo/domains/user/repo/db"
+	"bilingo/domains/user/repo/repotest"
+	"bilingo/server/testutil"
+)
+
+func TestUserRepo(t *testing.T) {
+	repotest.TestUserRepo(t, func(t *testing.T) repo.IUserRepo {
+		testutil.NewDB(t)
+		return &impl.UserRepo{}
+	})
+}
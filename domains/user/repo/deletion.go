@@ -0,0 +1,30 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"bilingo/domains/user/models"
+	impl "bilingo/domains/user/repo/db"
+	"bilingo/server/app"
+)
+
+// Deletions returns the deletion repository of the app the context carries, or the
+// database implementation outside of apps.
+func Deletions(ctx context.Context) IDeletionRepo {
+	return app.Resolve[IDeletionRepo](ctx, &impl.DeletionRepo{})
+}
+
+type IDeletionRepo interface {
+	Create(ctx context.Context, deletion *models.UserDeletion) error
+	Get(ctx context.Context, id string) (*models.UserDeletion, error)
+	// GetUnfinished returns the pending or running deletion of the user, it
+	// fails with ErrDeletionNotFound if there's none.
+	GetUnfinished(ctx context.Context, userId string) (*models.UserDeletion, error)
+	// ListUnfinished returns all pending or running deletions, oldest first.
+	ListUnfinished(ctx context.Context) ([]models.UserDeletion, error)
+	Start(ctx context.Context, id string, now time.Time) error
+	// Finish marks the deletion as completed, or as failed if the reason is
+	// given.
+	Finish(ctx context.Context, id string, reason *string, now time.Time) error
+}
@@ -0,0 +1,30 @@
+package repo
+
+import (
+	"bilingo/config"
+	impl "bilingo/domains/user/repo/db"
+	"bilingo/domains/user/repo/memory"
+	"bilingo/server/app"
+)
+
+// Provide provides the repositories of the driver in the configuration of the
+// app, which keep their records in memory rather than the databases with the
+// memory driver, see config.RepoMemory.
+func Provide(a *app.App) {
+	if a.Config.Repo == config.RepoMemory {
+		app.Provide[IUserRepo](a, &memory.UserRepo{})
+		app.Provide[IDeletionRepo](a, &memory.DeletionRepo{})
+		app.Provide[IIdentityRepo](a, &memory.IdentityRepo{})
+		app.Provide[IPasswordResetRepo](a, &memory.PasswordResetRepo{})
+		app.Provide[IProfileRepo](a, &memory.ProfileRepo{})
+		app.Provide[ITwoFactorRepo](a, &memory.TwoFactorRepo{})
+		return
+	}
+
+	app.Provide[IUserRepo](a, &impl.UserRepo{})
+	app.Provide[IDeletionRepo](a, &impl.DeletionRepo{})
+	app.Provide[IIdentityRepo](a, &impl.IdentityRepo{})
+	app.Provide[IPasswordResetRepo](a, &impl.PasswordResetRepo{})
+	app.Provide[IProfileRepo](a, &impl.ProfileRepo{})
+	app.Provide[ITwoFactorRepo](a, &impl.TwoFactorRepo{})
+}
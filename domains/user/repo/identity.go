@@ -0,0 +1,28 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"bilingo/domains/user/models"
+	impl "bilingo/domains/user/repo/db"
+	"bilingo/server/app"
+)
+
+// Identities returns the identity repository of the app the context carries, or the
+// database implementation outside of apps.
+func Identities(ctx context.Context) IIdentityRepo {
+	return app.Resolve[IIdentityRepo](ctx, &impl.IdentityRepo{})
+}
+
+type IIdentityRepo interface {
+	// Get returns the identity of the provider, it fails with
+	// ErrAccountNotLinked if it's not linked to any user.
+	Get(ctx context.Context, provider string, subject string) (*models.UserIdentity, error)
+	Create(ctx context.Context, identity *models.UserIdentity) error
+	// List returns the identities linked to the user.
+	List(ctx context.Context, userId string) ([]models.UserIdentity, error)
+	TouchLogin(ctx context.Context, provider string, subject string, now time.Time) error
+	// DeleteAll unlinks all the identities of the user.
+	DeleteAll(ctx context.Context, userId string) error
+}
@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	domain "bilingo/domains/user"
+	"bilingo/domains/user/models"
+	"bilingo/domains/user/types"
+	"bilingo/server/memdb"
+)
+
+type DeletionRepo struct {
+	deletions memdb.Table[string, models.UserDeletion]
+}
+
+func (r *DeletionRepo) Create(ctx context.Context, deletion *models.UserDeletion) error {
+	if !r.deletions.Insert(deletion.ID, *deletion) {
+		return fmt.Errorf("failed to create account deletion: deletion %s exists", deletion.ID)
+	}
+
+	return nil
+}
+
+func (r *DeletionRepo) Get(ctx context.Context, id string) (*models.UserDeletion, error) {
+	deletion, ok := r.deletions.Get(id)
+	if !ok {
+		return nil, domain.ErrDeletionNotFound
+	}
+
+	return &deletion, nil
+}
+
+func (r *DeletionRepo) GetUnfinished(ctx context.Context, userId string) (*models.UserDeletion, error) {
+	deletions := r.deletions.Find(func(deletion models.UserDeletion) bool {
+		return deletion.UserID == userId && unfinished(deletion)
+	})
+	if len(deletions) == 0 {
+		return nil, domain.ErrDeletionNotFound
+	}
+
+	return &deletions[0], nil
+}
+
+func (r *DeletionRepo) ListUnfinished(ctx context.Context) ([]models.UserDeletion, error) {
+	deletions := r.deletions.Find(unfinished)
+	slices.SortStableFunc(deletions, func(a, b models.UserDeletion) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return deletions, nil
+}
+
+func unfinished(deletion models.UserDeletion) bool {
+	return deletion.Status == types.DeletionPending || deletion.Status == types.DeletionRunning
+}
+
+func (r *DeletionRepo) Start(ctx context.Context, id string, now time.Time) error {
+	return r.setStatus(id, func(deletion *models.UserDeletion) {
+		deletion.Status = types.DeletionRunning
+		deletion.StartedAt = &now
+	})
+}
+
+func (r *DeletionRepo) Finish(ctx context.Context, id string, reason *string, now time.Time) error {
+	return r.setStatus(id, func(deletion *models.UserDeletion) {
+		if reason != nil {
+			deletion.Status = types.DeletionFailed
+			deletion.Error = reason
+		} else {
+			deletion.Status = types.DeletionCompleted
+			deletion.Error = nil
+		}
+		deletion.CompletedAt = &now
+	})
+}
+
+func (r *DeletionRepo) setStatus(id string, fn func(deletion *models.UserDeletion)) error {
+	updated := r.deletions.Update(id, func(deletion *models.UserDeletion) bool {
+		fn(deletion)
+		return true
+	})
+	if !updated {
+		return domain.ErrDeletionNotFound
+	}
+
+	return nil
+}
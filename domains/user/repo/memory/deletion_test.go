@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"bilingo/domains/user/repo"
+	"bilingo/domains/user/repo/memory"
+	"bilingo/domains/user/repo/repotest"
+)
+
+func TestDeletionRepo(t *testing.T) {
+	repotest.TestDeletionRepo(t, func(t *testing.T) repo.IDeletionRepo {
+		return &memory.DeletionRepo{}
+	})
+}
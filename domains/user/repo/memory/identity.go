@@ -0,0 +1,69 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	domain "bilingo/domains/user"
+	"bilingo/domains/user/models"
+	"bilingo/server/memdb"
+)
+
+type IdentityRepo struct {
+	identities memdb.Table[identityKey, models.UserIdentity]
+}
+
+type identityKey struct {
+	provider string
+	subject  string
+}
+
+func (r *IdentityRepo) Get(ctx context.Context, provider string, subject string) (*models.UserIdentity, error) {
+	identity, ok := r.identities.Get(identityKey{provider, subject})
+	if !ok {
+		return nil, domain.ErrAccountNotLinked
+	}
+
+	return &identity, nil
+}
+
+func (r *IdentityRepo) Create(ctx context.Context, identity *models.UserIdentity) error {
+	if identity.CreatedAt.IsZero() {
+		identity.CreatedAt = time.Now()
+	}
+	if !r.identities.Insert(identityKey{identity.Provider, identity.Subject}, *identity) {
+		return fmt.Errorf("failed to create identity: %s of %s is linked already", identity.Subject, identity.Provider)
+	}
+
+	return nil
+}
+
+func (r *IdentityRepo) List(ctx context.Context, userId string) ([]models.UserIdentity, error) {
+	identities := r.identities.Find(func(identity models.UserIdentity) bool {
+		return identity.UserID == userId
+	})
+	slices.SortStableFunc(identities, func(a, b models.UserIdentity) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return identities, nil
+}
+
+func (r *IdentityRepo) TouchLogin(ctx context.Context, provider string, subject string, now time.Time) error {
+	r.identities.Update(identityKey{provider, subject}, func(identity *models.UserIdentity) bool {
+		identity.LastLoginAt = &now
+		return true
+	})
+
+	return nil
+}
+
+func (r *IdentityRepo) DeleteAll(ctx context.Context, userId string) error {
+	r.identities.DeleteWhere(func(identity models.UserIdentity) bool {
+		return identity.UserID == userId
+	})
+
+	return nil
+}
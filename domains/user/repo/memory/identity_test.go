@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"bilingo/domains/user/repo"
+	"bilingo/domains/user/repo/memory"
+	"bilingo/domains/user/repo/repotest"
+)
+
+func TestIdentityRepo(t *testing.T) {
+	repotest.TestIdentityRepo(t, func(t *testing.T) repo.IIdentityRepo {
+		return &memory.IdentityRepo{}
+	})
+}
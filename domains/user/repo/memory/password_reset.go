@@ -0,0 +1,74 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	domain "bilingo/domains/user"
+	"bilingo/domains/user/models"
+	"bilingo/server/memdb"
+)
+
+type PasswordResetRepo struct {
+	tokens memdb.Table[string, models.PasswordResetToken]
+}
+
+func (r *PasswordResetRepo) Create(ctx context.Context, token *models.PasswordResetToken) error {
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	if !r.tokens.Insert(token.TokenHash, *token) {
+		return fmt.Errorf("failed to create password reset token: token exists")
+	}
+
+	return nil
+}
+
+func (r *PasswordResetRepo) Get(ctx context.Context, tokenHash string, now time.Time) (*models.PasswordResetToken, error) {
+	token, ok := r.tokens.Get(tokenHash)
+	if !ok || !usable(token, now) {
+		return nil, domain.ErrInvalidResetToken
+	}
+
+	return &token, nil
+}
+
+func (r *PasswordResetRepo) Consume(ctx context.Context, tokenHash string, now time.Time) (*models.PasswordResetToken, error) {
+	var consumed models.PasswordResetToken
+	// The update is atomic, so the token is only used once even if requests race
+	used := r.tokens.Update(tokenHash, func(token *models.PasswordResetToken) bool {
+		if !usable(*token, now) {
+			return false
+		}
+		token.UsedAt = &now
+		consumed = *token
+		return true
+	})
+	if !used {
+		return nil, domain.ErrInvalidResetToken
+	}
+
+	return &consumed, nil
+}
+
+func usable(token models.PasswordResetToken, now time.Time) bool {
+	return token.UsedAt == nil && token.ExpiresAt.After(now)
+}
+
+func (r *PasswordResetRepo) RevokeAll(ctx context.Context, userId string, now time.Time) error {
+	r.tokens.UpdateWhere(
+		func(token models.PasswordResetToken) bool { return token.UserID == userId && token.UsedAt == nil },
+		func(token *models.PasswordResetToken) { token.UsedAt = &now },
+	)
+
+	return nil
+}
+
+func (r *PasswordResetRepo) DeleteAll(ctx context.Context, userId string) error {
+	r.tokens.DeleteWhere(func(token models.PasswordResetToken) bool {
+		return token.UserID == userId
+	})
+
+	return nil
+}
@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"bilingo/domains/user/repo"
+	"bilingo/domains/user/repo/memory"
+	"bilingo/domains/user/repo/repotest"
+)
+
+func TestPasswordResetRepo(t *testing.T) {
+	repotest.TestPasswordResetRepo(t, func(t *testing.T) repo.IPasswordResetRepo {
+		return &memory.PasswordResetRepo{}
+	})
+}
@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"bilingo/domains/user/models"
+	"bilingo/server/memdb"
+)
+
+type ProfileRepo struct {
+	profiles memdb.Table[string, models.UserProfile]
+}
+
+func (r *ProfileRepo) Get(ctx context.Context, userId string) (*models.UserProfile, error) {
+	profile, ok := r.profiles.Get(userId)
+	if !ok {
+		return &models.UserProfile{UserID: userId}, nil
+	}
+
+	profile = cloneProfile(profile)
+	return &profile, nil
+}
+
+func (r *ProfileRepo) Save(ctx context.Context, profile *models.UserProfile) error {
+	if profile.UpdatedAt.IsZero() {
+		profile.UpdatedAt = time.Now()
+	}
+	r.profiles.Put(profile.UserID, cloneProfile(*profile))
+
+	return nil
+}
+
+func (r *ProfileRepo) Delete(ctx context.Context, userId string) error {
+	r.profiles.Delete(userId)
+
+	return nil
+}
+
+// cloneProfile copies the links and the visibility, which are changed in place
+// by the services.
+func cloneProfile(profile models.UserProfile) models.UserProfile {
+	profile.Links = slices.Clone(profile.Links)
+	profile.Visibility = maps.Clone(profile.Visibility)
+	return profile
+}
@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"bilingo/domains/user/repo"
+	"bilingo/domains/user/repo/memory"
+	"bilingo/domains/user/repo/repotest"
+)
+
+func TestProfileRepo(t *testing.T) {
+	repotest.TestProfileRepo(t, func(t *testing.T) repo.IProfileRepo {
+		return &memory.ProfileRepo{}
+	})
+}
@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	domain "bilingo/domains/user"
+	"bilingo/domains/user/models"
+	"bilingo/server/memdb"
+)
+
+type TwoFactorRepo struct {
+	totps memdb.Table[string, models.UserTotp]
+	// The recovery codes of a user are replaced as a whole, which codes being
+	// used must not interleave with
+	codesMu sync.Mutex
+	codes   memdb.Table[uint, models.RecoveryCode]
+}
+
+func (r *TwoFactorRepo) GetTotp(ctx context.Context, userId string) (*models.UserTotp, error) {
+	totp, ok := r.totps.Get(userId)
+	if !ok {
+		return nil, domain.ErrTwoFactorNotEnabled
+	}
+
+	return &totp, nil
+}
+
+func (r *TwoFactorRepo) SaveTotp(ctx context.Context, totp *models.UserTotp) error {
+	if totp.CreatedAt.IsZero() {
+		totp.CreatedAt = time.Now()
+	}
+	r.totps.Put(totp.UserID, *totp)
+
+	return nil
+}
+
+func (r *TwoFactorRepo) EnableTotp(ctx context.Context, userId string, step int64, now time.Time) error {
+	enabled := r.totps.Update(userId, func(totp *models.UserTotp) bool {
+		totp.EnabledAt = &now
+		totp.LastUsedStep = step
+		totp.FailedAttempts = 0
+		return true
+	})
+	if !enabled {
+		return domain.ErrTwoFactorNotEnabled
+	}
+
+	return nil
+}
+
+func (r *TwoFactorRepo) UseTotpStep(ctx context.Context, userId string, step int64) error {
+	// The update is atomic, so replays are rejected even if requests race
+	used := r.totps.Update(userId, func(totp *models.UserTotp) bool {
+		if totp.LastUsedStep >= step {
+			return false
+		}
+		totp.LastUsedStep = step
+		totp.FailedAttempts = 0
+		return true
+	})
+	if !used {
+		return domain.ErrInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+func (r *TwoFactorRepo) RecordFailure(ctx context.Context, userId string, now time.Time) error {
+	r.totps.Update(userId, func(totp *models.UserTotp) bool {
+		totp.FailedAttempts++
+		totp.LastFailedAt = &now
+		return true
+	})
+
+	return nil
+}
+
+func (r *TwoFactorRepo) ListEnabled(ctx context.Context) ([]models.UserTotp, error) {
+	totps := r.totps.Find(func(totp models.UserTotp) bool { return totp.EnabledAt != nil })
+	slices.SortFunc(totps, func(a, b models.UserTotp) int {
+		return strings.Compare(a.UserID, b.UserID)
+	})
+
+	return totps, nil
+}
+
+func (r *TwoFactorRepo) Delete(ctx context.Context, userId string) error {
+	r.codesMu.Lock()
+	defer r.codesMu.Unlock()
+	r.totps.Delete(userId)
+	r.codes.DeleteWhere(func(code models.RecoveryCode) bool { return code.UserID == userId })
+
+	return nil
+}
+
+func (r *TwoFactorRepo) ReplaceRecoveryCodes(ctx context.Context, userId string, codeHashes []string, now time.Time) error {
+	r.codesMu.Lock()
+	defer r.codesMu.Unlock()
+	r.codes.DeleteWhere(func(code models.RecoveryCode) bool { return code.UserID == userId })
+	for _, hash := range codeHashes {
+		id := r.codes.NextID()
+		r.codes.Put(id, models.RecoveryCode{ID: id, UserID: userId, CodeHash: hash, CreatedAt: now})
+	}
+
+	return nil
+}
+
+func (r *TwoFactorRepo) UseRecoveryCode(ctx context.Context, userId string, codeHash string, now time.Time) error {
+	r.codesMu.Lock()
+	defer r.codesMu.Unlock()
+	used := r.codes.UpdateWhere(
+		func(code models.RecoveryCode) bool {
+			return code.UserID == userId && code.CodeHash == codeHash && code.UsedAt == nil
+		},
+		func(code *models.RecoveryCode) { code.UsedAt = &now },
+	)
+	if used == 0 {
+		return domain.ErrInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+func (r *TwoFactorRepo) CountRecoveryCodes(ctx context.Context, userId string) (int, error) {
+	return r.codes.Count(func(code models.RecoveryCode) bool {
+		return code.UserID == userId && code.UsedAt == nil
+	}), nil
+}
@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"bilingo/domains/user/repo"
+	"bilingo/domains/user/repo/memory"
+	"bilingo/domains/user/repo/repotest"
+)
+
+func TestTwoFactorRepo(t *testing.T) {
+	repotest.TestTwoFactorRepo(t, func(t *testing.T) repo.ITwoFactorRepo {
+		return &memory.TwoFactorRepo{}
+	})
+}
@@ -0,0 +1,206 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"bilingo/common"
+	domain "bilingo/domains/user"
+	"bilingo/domains/user/models"
+	"bilingo/domains/user/types"
+	"bilingo/server/memdb"
+
+	"github.com/google/uuid"
+)
+
+// UserRepo keeps the users in memory, unlike the database it can't tell if
+// anything of other repositories refers to a user being deleted.
+type UserRepo struct {
+	emailMu sync.Mutex // Keeps the emails unique
+	users   memdb.Table[string, models.User]
+}
+
+func (r *UserRepo) Get(ctx context.Context, id string) (*models.User, error) {
+	user, ok := r.users.Get(id)
+	if !ok {
+		return nil, domain.ErrUserNotFound
+	}
+
+	return &user, nil
+}
+
+func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	users := r.users.Find(func(user models.User) bool { return user.Email == email })
+	if len(users) == 0 {
+		return nil, domain.ErrUserNotFound
+	}
+
+	return &users[0], nil
+}
+
+func (r *UserRepo) List(ctx context.Context, query types.UserListQuery) (*common.PaginatedResult[models.User], error) {
+	users := r.users.Find(func(user models.User) bool {
+		if query.Search != nil && *query.Search != "" {
+			search := strings.ToLower(*query.Search)
+			if !strings.Contains(strings.ToLower(user.Name), search) &&
+				!strings.Contains(strings.ToLower(user.Email), search) {
+				return false
+			}
+		}
+
+		if query.Ids != nil && len(*query.Ids) > 0 && !slices.Contains(*query.Ids, user.ID) {
+			return false
+		}
+
+		if query.Emails != nil && len(*query.Emails) > 0 && !slices.Contains(*query.Emails, user.Email) {
+			return false
+		}
+
+		if query.Birthdate != nil {
+			if query.Birthdate.Start != nil && (user.Birthdate == nil || *user.Birthdate < *query.Birthdate.Start) {
+				return false
+			}
+			if query.Birthdate.End != nil && (user.Birthdate == nil || *user.Birthdate > *query.Birthdate.End) {
+				return false
+			}
+		}
+
+		return true
+	})
+
+	return memdb.Paginate(users, query.PaginatedQuery), nil
+}
+
+func (r *UserRepo) Create(ctx context.Context, data *types.UserCreate) (*models.User, error) {
+	now := time.Now()
+	user := models.User{
+		ID:        uuid.NewString(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Email:     data.Email,
+		Name:      data.Name,
+		Birthdate: data.Birthdate,
+
+		EmailVerifiedAt: data.EmailVerifiedAt,
+	}
+	if data.Password != "" {
+		user.Password = &data.Password // Users signed up through SSO have no password
+	}
+
+	r.emailMu.Lock()
+	defer r.emailMu.Unlock()
+	if r.emailTaken(data.Email) {
+		return nil, domain.ErrUserExists
+	}
+	r.users.Put(user.ID, user)
+
+	return &user, nil
+}
+
+func (r *UserRepo) emailTaken(email string) bool {
+	return r.users.Count(func(user models.User) bool { return user.Email == email }) > 0
+}
+
+func (r *UserRepo) Update(ctx context.Context, id string, data *types.UserUpdate) (*models.User, error) {
+	var updated models.User
+	found := false
+	r.users.Update(id, func(user *models.User) bool {
+		found = true
+		changed := false
+
+		// Update only the fields that are provided
+		if data.Name != nil && *data.Name != "" && *data.Name != user.Name {
+			user.Name = *data.Name
+			changed = true
+		}
+		if data.Password != nil && *data.Password != "" && (user.Password == nil || *data.Password != *user.Password) {
+			password := *data.Password
+			user.Password = &password
+			changed = true
+		}
+		if data.Birthdate != nil && *data.Birthdate != "" && (user.Birthdate == nil || *data.Birthdate != *user.Birthdate) {
+			birthdate := *data.Birthdate
+			user.Birthdate = &birthdate
+			changed = true
+		}
+
+		if changed {
+			user.UpdatedAt = time.Now()
+		}
+		updated = *user
+		return changed
+	})
+	if !found {
+		return nil, domain.ErrUserNotFound
+	}
+
+	return &updated, nil
+}
+
+func (r *UserRepo) Delete(ctx context.Context, id string) error {
+	if !r.users.Delete(id) {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *UserRepo) SetEmailVerified(ctx context.Context, id string, verifiedAt time.Time) error {
+	updated := r.users.Update(id, func(user *models.User) bool {
+		user.EmailVerifiedAt = &verifiedAt
+		return true
+	})
+	if !updated {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *UserRepo) SetPendingEmail(ctx context.Context, id string, email *string) error {
+	updated := r.users.Update(id, func(user *models.User) bool {
+		if email != nil {
+			pendingEmail := *email
+			user.PendingEmail = &pendingEmail
+		} else {
+			user.PendingEmail = nil
+		}
+		user.UpdatedAt = time.Now()
+		return true
+	})
+	if !updated {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *UserRepo) ChangeEmail(ctx context.Context, id string, email string, verifiedAt time.Time) error {
+	r.emailMu.Lock()
+	defer r.emailMu.Unlock()
+
+	taken := r.users.Count(func(user models.User) bool { return user.Email == email && user.ID != id }) > 0
+	pending := false
+	changed := r.users.Update(id, func(user *models.User) bool {
+		// Only the latest requested change is applied
+		pending = user.PendingEmail != nil && *user.PendingEmail == email
+		if !pending || taken {
+			return false
+		}
+		user.Email = email
+		user.PendingEmail = nil
+		user.EmailVerifiedAt = &verifiedAt
+		user.UpdatedAt = verifiedAt
+		return true
+	})
+	if pending && taken {
+		return domain.ErrUserExists
+	} else if !changed {
+		return domain.ErrInvalidEmailChange
+	}
+
+	return nil
+}
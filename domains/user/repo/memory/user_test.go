@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"bilingo/domains/user/repo"
+	"bilingo/domains/user/repo/memory"
+	"bilingo/domains/user/repo/repotest"
+)
+
+func TestUserRepo(t *testing.T) {
+	repotest.TestUserRepo(t, func(t *testing.T) repo.IUserRepo {
+		return &memory.UserRepo{}
+	})
+}
@@ -0,0 +1,30 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"bilingo/domains/user/models"
+	impl "bilingo/domains/user/repo/db"
+	"bilingo/server/app"
+)
+
+// PasswordResets returns the password reset repository of the app the context carries, or the
+// database implementation outside of apps.
+func PasswordResets(ctx context.Context) IPasswordResetRepo {
+	return app.Resolve[IPasswordResetRepo](ctx, &impl.PasswordResetRepo{})
+}
+
+type IPasswordResetRepo interface {
+	Create(ctx context.Context, token *models.PasswordResetToken) error
+	// Get returns the token if it's neither used nor expired, it fails with
+	// ErrInvalidResetToken otherwise.
+	Get(ctx context.Context, tokenHash string, now time.Time) (*models.PasswordResetToken, error)
+	// Consume marks the token as used if it's neither used nor expired, and
+	// returns it, it fails with ErrInvalidResetToken otherwise.
+	Consume(ctx context.Context, tokenHash string, now time.Time) (*models.PasswordResetToken, error)
+	// RevokeAll marks all the outstanding tokens of the user as used.
+	RevokeAll(ctx context.Context, userId string, now time.Time) error
+	// DeleteAll deletes all the tokens of the user, used or not.
+	DeleteAll(ctx context.Context, userId string) error
+}
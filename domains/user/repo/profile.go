@@ -0,0 +1,24 @@
+package repo
+
+import (
+	"context"
+
+	"bilingo/domains/user/models"
+	impl "bilingo/domains/user/repo/db"
+	"bilingo/server/app"
+)
+
+// Profiles returns the profile repository of the app the context carries, or the
+// database implementation outside of apps.
+func Profiles(ctx context.Context) IProfileRepo {
+	return app.Resolve[IProfileRepo](ctx, &impl.ProfileRepo{})
+}
+
+type IProfileRepo interface {
+	// Get returns the profile of the user, which is empty if the user hasn't
+	// set it up yet.
+	Get(ctx context.Context, userId string) (*models.UserProfile, error)
+	// Save creates or replaces the profile of the user.
+	Save(ctx context.Context, profile *models.UserProfile) error
+	Delete(ctx context.Context, userId string) error
+}
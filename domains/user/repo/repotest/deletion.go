@@ -0,0 +1,110 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	domain "bilingo/domains/user"
+	"bilingo/domains/user/models"
+	"bilingo/domains/user/repo"
+	"bilingo/domains/user/types"
+)
+
+// TestDeletionRepo tests the account deletion repository against the contract.
+func TestDeletionRepo(t *testing.T, newRepo func(t *testing.T) repo.IDeletionRepo) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	create := func(t *testing.T, r repo.IDeletionRepo, id string, userId string, createdAt time.Time) {
+		t.Helper()
+		err := r.Create(ctx, &models.UserDeletion{
+			ID:        id,
+			UserID:    userId,
+			Policy:    "anonymize",
+			Status:    types.DeletionPending,
+			CreatedAt: createdAt,
+		})
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	t.Run("Lifecycle", func(t *testing.T) {
+		r := newRepo(t)
+		create(t, r, "d1", "u1", now)
+
+		deletion, err := r.GetUnfinished(ctx, "u1")
+		if err != nil || deletion.ID != "d1" {
+			t.Fatalf("GetUnfinished: got %+v, %v", deletion, err)
+		}
+
+		if err := r.Start(ctx, "d1", now); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+		deletion, err = r.Get(ctx, "d1")
+		if err != nil || deletion.Status != types.DeletionRunning || deletion.StartedAt == nil {
+			t.Fatalf("Get after Start: got %+v, %v", deletion, err)
+		}
+		if _, err := r.GetUnfinished(ctx, "u1"); err != nil {
+			t.Errorf("GetUnfinished of a running deletion: %v", err)
+		}
+
+		if err := r.Finish(ctx, "d1", nil, now); err != nil {
+			t.Fatalf("Finish: %v", err)
+		}
+		deletion, err = r.Get(ctx, "d1")
+		if err != nil || deletion.Status != types.DeletionCompleted || deletion.CompletedAt == nil || deletion.Error != nil {
+			t.Fatalf("Get after Finish: got %+v, %v", deletion, err)
+		}
+		if _, err := r.GetUnfinished(ctx, "u1"); !errors.Is(err, domain.ErrDeletionNotFound) {
+			t.Errorf("GetUnfinished of a completed deletion: got %v, want ErrDeletionNotFound", err)
+		}
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		r := newRepo(t)
+		create(t, r, "d1", "u1", now)
+
+		reason := "storage unavailable"
+		if err := r.Finish(ctx, "d1", &reason, now); err != nil {
+			t.Fatalf("Finish: %v", err)
+		}
+		deletion, err := r.Get(ctx, "d1")
+		if err != nil || deletion.Status != types.DeletionFailed || deletion.Error == nil || *deletion.Error != reason {
+			t.Errorf("Get after failing: got %+v, %v", deletion, err)
+		}
+	})
+
+	t.Run("ListUnfinished", func(t *testing.T) {
+		r := newRepo(t)
+		create(t, r, "d2", "u2", now.Add(time.Minute))
+		create(t, r, "d1", "u1", now)
+		create(t, r, "d3", "u3", now.Add(2*time.Minute))
+		if err := r.Finish(ctx, "d3", nil, now); err != nil {
+			t.Fatalf("Finish: %v", err)
+		}
+
+		deletions, err := r.ListUnfinished(ctx)
+		if err != nil {
+			t.Fatalf("ListUnfinished: %v", err)
+		}
+		if len(deletions) != 2 || deletions[0].ID != "d1" || deletions[1].ID != "d2" {
+			t.Errorf("ListUnfinished: got %+v, want d1 and d2", deletions)
+		}
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		r := newRepo(t)
+		if _, err := r.Get(ctx, "missing"); !errors.Is(err, domain.ErrDeletionNotFound) {
+			t.Errorf("Get: got %v, want ErrDeletionNotFound", err)
+		}
+		if err := r.Start(ctx, "missing", now); !errors.Is(err, domain.ErrDeletionNotFound) {
+			t.Errorf("Start: got %v, want ErrDeletionNotFound", err)
+		}
+		if err := r.Finish(ctx, "missing", nil, now); !errors.Is(err, domain.ErrDeletionNotFound) {
+			t.Errorf("Finish: got %v, want ErrDeletionNotFound", err)
+		}
+	})
+}
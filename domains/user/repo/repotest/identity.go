@@ -0,0 +1,65 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	domain "bilingo/domains/user"
+	"bilingo/domains/user/models"
+	"bilingo/domains/user/repo"
+)
+
+// TestIdentityRepo tests the identity repository against the contract.
+func TestIdentityRepo(t *testing.T, newRepo func(t *testing.T) repo.IIdentityRepo) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	t.Run("Link", func(t *testing.T) {
+		r := newRepo(t)
+		for i, identity := range []models.UserIdentity{
+			{Provider: "github", Subject: "1", UserID: "u1"},
+			{Provider: "google", Subject: "1", UserID: "u1"},
+			{Provider: "github", Subject: "2", UserID: "u2"},
+		} {
+			identity.CreatedAt = now.Add(time.Duration(i) * time.Minute)
+			if err := r.Create(ctx, &identity); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+		if err := r.Create(ctx, &models.UserIdentity{Provider: "github", Subject: "1", UserID: "u2"}); err == nil {
+			t.Errorf("Create of a linked identity: got no error")
+		}
+
+		identity, err := r.Get(ctx, "github", "1")
+		if err != nil || identity.UserID != "u1" {
+			t.Fatalf("Get: got %+v, %v", identity, err)
+		}
+		if _, err := r.Get(ctx, "github", "3"); !errors.Is(err, domain.ErrAccountNotLinked) {
+			t.Errorf("Get of an unlinked identity: got %v, want ErrAccountNotLinked", err)
+		}
+
+		identities, err := r.List(ctx, "u1")
+		if err != nil || len(identities) != 2 || identities[0].Provider != "github" || identities[1].Provider != "google" {
+			t.Errorf("List: got %+v, %v", identities, err)
+		}
+
+		if err := r.TouchLogin(ctx, "github", "1", now); err != nil {
+			t.Fatalf("TouchLogin: %v", err)
+		}
+		if identity, err := r.Get(ctx, "github", "1"); err != nil || identity.LastLoginAt == nil || !identity.LastLoginAt.Equal(now) {
+			t.Errorf("Get after TouchLogin: got %+v, %v", identity, err)
+		}
+
+		if err := r.DeleteAll(ctx, "u1"); err != nil {
+			t.Fatalf("DeleteAll: %v", err)
+		}
+		if identities, err := r.List(ctx, "u1"); err != nil || len(identities) != 0 {
+			t.Errorf("List after DeleteAll: got %+v, %v", identities, err)
+		}
+		if _, err := r.Get(ctx, "github", "2"); err != nil {
+			t.Errorf("Get of another user after DeleteAll: %v", err)
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	domain "bilingo/domains/user"
+	"bilingo/domains/user/models"
+	"bilingo/domains/user/repo"
+)
+
+// TestPasswordResetRepo tests the password reset repository against the
+// contract.
+func TestPasswordResetRepo(t *testing.T, newRepo func(t *testing.T) repo.IPasswordResetRepo) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	create := func(t *testing.T, r repo.IPasswordResetRepo, hash string, userId string, expiresAt time.Time) {
+		t.Helper()
+		token := &models.PasswordResetToken{TokenHash: hash, UserID: userId, CreatedAt: now, ExpiresAt: expiresAt}
+		if err := r.Create(ctx, token); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	t.Run("Consume", func(t *testing.T) {
+		r := newRepo(t)
+		create(t, r, "valid", "u1", now.Add(time.Hour))
+		create(t, r, "expired", "u1", now.Add(-time.Minute))
+
+		if token, err := r.Get(ctx, "valid", now); err != nil || token.UserID != "u1" {
+			t.Fatalf("Get: got %+v, %v", token, err)
+		}
+		if _, err := r.Get(ctx, "expired", now); !errors.Is(err, domain.ErrInvalidResetToken) {
+			t.Errorf("Get of an expired token: got %v, want ErrInvalidResetToken", err)
+		}
+		if _, err := r.Consume(ctx, "expired", now); !errors.Is(err, domain.ErrInvalidResetToken) {
+			t.Errorf("Consume of an expired token: got %v, want ErrInvalidResetToken", err)
+		}
+
+		// Only one of the racing requests uses the token
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		used := 0
+		for range 5 {
+			wg.Go(func() {
+				if token, err := r.Consume(ctx, "valid", now); err == nil && token.UsedAt != nil {
+					mu.Lock()
+					used++
+					mu.Unlock()
+				} else if err != nil && !errors.Is(err, domain.ErrInvalidResetToken) {
+					t.Errorf("Consume: %v", err)
+				}
+			})
+		}
+		wg.Wait()
+		if used != 1 {
+			t.Errorf("Consume: the token is used %d times, want once", used)
+		}
+		if _, err := r.Get(ctx, "valid", now); !errors.Is(err, domain.ErrInvalidResetToken) {
+			t.Errorf("Get of a used token: got %v, want ErrInvalidResetToken", err)
+		}
+	})
+
+	t.Run("RevokeAndDelete", func(t *testing.T) {
+		r := newRepo(t)
+		create(t, r, "a", "u1", now.Add(time.Hour))
+		create(t, r, "b", "u1", now.Add(time.Hour))
+		create(t, r, "c", "u2", now.Add(time.Hour))
+
+		if err := r.RevokeAll(ctx, "u1", now); err != nil {
+			t.Fatalf("RevokeAll: %v", err)
+		}
+		for _, hash := range []string{"a", "b"} {
+			if _, err := r.Get(ctx, hash, now); !errors.Is(err, domain.ErrInvalidResetToken) {
+				t.Errorf("Get of a revoked token: got %v, want ErrInvalidResetToken", err)
+			}
+		}
+		if _, err := r.Get(ctx, "c", now); err != nil {
+			t.Errorf("Get of a token of another user: %v", err)
+		}
+
+		if err := r.DeleteAll(ctx, "u2"); err != nil {
+			t.Fatalf("DeleteAll: %v", err)
+		}
+		if _, err := r.Get(ctx, "c", now); !errors.Is(err, domain.ErrInvalidResetToken) {
+			t.Errorf("Get of a deleted token: got %v, want ErrInvalidResetToken", err)
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bilingo/domains/user/models"
+	"bilingo/domains/user/repo"
+	"bilingo/domains/user/types"
+)
+
+// TestProfileRepo tests the profile repository against the contract.
+func TestProfileRepo(t *testing.T, newRepo func(t *testing.T) repo.IProfileRepo) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	t.Run("Save", func(t *testing.T) {
+		r := newRepo(t)
+		profile, err := r.Get(ctx, "u1")
+		if err != nil || profile.UserID != "u1" || profile.Bio != nil {
+			t.Fatalf("Get of a new profile: got %+v, %v", profile, err)
+		}
+
+		bio := "Hello"
+		profile.Bio = &bio
+		profile.Links = []types.ProfileLink{{Label: "Blog", Url: "https://example.com"}}
+		profile.Visibility = map[string]string{"bio": "public"}
+		profile.UpdatedAt = now
+		if err := r.Save(ctx, profile); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+
+		// Changes to the saved profile are not stored until it's saved again
+		profile.Links[0].Label = "Changed"
+		profile.Visibility["bio"] = "private"
+
+		saved, err := r.Get(ctx, "u1")
+		if err != nil || saved.Bio == nil || *saved.Bio != bio || len(saved.Links) != 1 ||
+			saved.Links[0].Label != "Blog" || saved.Visibility["bio"] != "public" {
+			t.Fatalf("Get after Save: got %+v, %v", saved, err)
+		}
+
+		saved.Bio = nil
+		if err := r.Save(ctx, saved); err != nil {
+			t.Fatalf("Save to replace: %v", err)
+		}
+		if saved, err := r.Get(ctx, "u1"); err != nil || saved.Bio != nil || len(saved.Links) != 1 {
+			t.Errorf("Get after replacing: got %+v, %v", saved, err)
+		}
+
+		if err := r.Delete(ctx, "u1"); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if profile, err := r.Get(ctx, "u1"); err != nil || len(profile.Links) != 0 {
+			t.Errorf("Get after Delete: got %+v, %v", profile, err)
+		}
+		if err := r.Delete(ctx, "u1"); err != nil {
+			t.Errorf("Delete of a missing profile: %v", err)
+		}
+	})
+
+	t.Run("Separate", func(t *testing.T) {
+		r := newRepo(t)
+		for _, userId := range []string{"u1", "u2"} {
+			name := "Name of " + userId
+			if err := r.Save(ctx, &models.UserProfile{UserID: userId, DisplayName: &name, UpdatedAt: now}); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+		}
+		profile, err := r.Get(ctx, "u2")
+		if err != nil || profile.DisplayName == nil || *profile.DisplayName != "Name of u2" {
+			t.Errorf("Get: got %+v, %v", profile, err)
+		}
+	})
+}
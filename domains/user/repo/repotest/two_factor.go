@@ -0,0 +1,121 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	domain "bilingo/domains/user"
+	"bilingo/domains/user/models"
+	"bilingo/domains/user/repo"
+)
+
+// TestTwoFactorRepo tests the two-factor authentication repository against
+// the contract.
+func TestTwoFactorRepo(t *testing.T, newRepo func(t *testing.T) repo.ITwoFactorRepo) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	t.Run("Totp", func(t *testing.T) {
+		r := newRepo(t)
+		if _, err := r.GetTotp(ctx, "u1"); !errors.Is(err, domain.ErrTwoFactorNotEnabled) {
+			t.Errorf("GetTotp of a missing TOTP: got %v, want ErrTwoFactorNotEnabled", err)
+		}
+		if err := r.EnableTotp(ctx, "u1", 1, now); !errors.Is(err, domain.ErrTwoFactorNotEnabled) {
+			t.Errorf("EnableTotp of a missing TOTP: got %v, want ErrTwoFactorNotEnabled", err)
+		}
+
+		for _, userId := range []string{"u2", "u1"} {
+			if err := r.SaveTotp(ctx, &models.UserTotp{UserID: userId, Secret: "secret", CreatedAt: now}); err != nil {
+				t.Fatalf("SaveTotp: %v", err)
+			}
+		}
+		if totps, err := r.ListEnabled(ctx); err != nil || len(totps) != 0 {
+			t.Errorf("ListEnabled of pending TOTPs: got %+v, %v", totps, err)
+		}
+
+		for _, userId := range []string{"u2", "u1"} {
+			if err := r.EnableTotp(ctx, userId, 10, now); err != nil {
+				t.Fatalf("EnableTotp: %v", err)
+			}
+		}
+		totps, err := r.ListEnabled(ctx)
+		if err != nil || len(totps) != 2 || totps[0].UserID != "u1" || totps[1].UserID != "u2" {
+			t.Errorf("ListEnabled: got %+v, %v", totps, err)
+		}
+
+		if err := r.UseTotpStep(ctx, "u1", 10); !errors.Is(err, domain.ErrInvalidTwoFactorCode) {
+			t.Errorf("UseTotpStep of a used step: got %v, want ErrInvalidTwoFactorCode", err)
+		}
+		if err := r.RecordFailure(ctx, "u1", now); err != nil {
+			t.Fatalf("RecordFailure: %v", err)
+		}
+		totp, err := r.GetTotp(ctx, "u1")
+		if err != nil || totp.FailedAttempts != 1 || totp.LastFailedAt == nil {
+			t.Errorf("GetTotp after RecordFailure: got %+v, %v", totp, err)
+		}
+		if err := r.UseTotpStep(ctx, "u1", 11); err != nil {
+			t.Fatalf("UseTotpStep: %v", err)
+		}
+		totp, err = r.GetTotp(ctx, "u1")
+		if err != nil || totp.LastUsedStep != 11 || totp.FailedAttempts != 0 {
+			t.Errorf("GetTotp after UseTotpStep: got %+v, %v", totp, err)
+		}
+
+		// Saving replaces the TOTP, e.g. when enrolling again
+		if err := r.SaveTotp(ctx, &models.UserTotp{UserID: "u1", Secret: "other", CreatedAt: now}); err != nil {
+			t.Fatalf("SaveTotp to replace: %v", err)
+		}
+		totp, err = r.GetTotp(ctx, "u1")
+		if err != nil || totp.Secret != "other" || totp.EnabledAt != nil {
+			t.Errorf("GetTotp after replacing: got %+v, %v", totp, err)
+		}
+	})
+
+	t.Run("RecoveryCodes", func(t *testing.T) {
+		r := newRepo(t)
+		if err := r.SaveTotp(ctx, &models.UserTotp{UserID: "u1", Secret: "secret", CreatedAt: now}); err != nil {
+			t.Fatalf("SaveTotp: %v", err)
+		}
+		if err := r.ReplaceRecoveryCodes(ctx, "u1", []string{"a", "b", "c"}, now); err != nil {
+			t.Fatalf("ReplaceRecoveryCodes: %v", err)
+		}
+		if err := r.ReplaceRecoveryCodes(ctx, "u2", []string{"a"}, now); err != nil {
+			t.Fatalf("ReplaceRecoveryCodes: %v", err)
+		}
+
+		if err := r.UseRecoveryCode(ctx, "u1", "a", now); err != nil {
+			t.Fatalf("UseRecoveryCode: %v", err)
+		}
+		if err := r.UseRecoveryCode(ctx, "u1", "a", now); !errors.Is(err, domain.ErrInvalidTwoFactorCode) {
+			t.Errorf("UseRecoveryCode of a used code: got %v, want ErrInvalidTwoFactorCode", err)
+		}
+		if err := r.UseRecoveryCode(ctx, "u1", "x", now); !errors.Is(err, domain.ErrInvalidTwoFactorCode) {
+			t.Errorf("UseRecoveryCode of a missing code: got %v, want ErrInvalidTwoFactorCode", err)
+		}
+		if n, err := r.CountRecoveryCodes(ctx, "u1"); err != nil || n != 2 {
+			t.Errorf("CountRecoveryCodes: got %d, %v, want 2", n, err)
+		}
+
+		if err := r.ReplaceRecoveryCodes(ctx, "u1", []string{"d", "e"}, now); err != nil {
+			t.Fatalf("ReplaceRecoveryCodes to replace: %v", err)
+		}
+		if err := r.UseRecoveryCode(ctx, "u1", "b", now); !errors.Is(err, domain.ErrInvalidTwoFactorCode) {
+			t.Errorf("UseRecoveryCode of a replaced code: got %v, want ErrInvalidTwoFactorCode", err)
+		}
+
+		if err := r.Delete(ctx, "u1"); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if n, err := r.CountRecoveryCodes(ctx, "u1"); err != nil || n != 0 {
+			t.Errorf("CountRecoveryCodes after Delete: got %d, %v, want 0", n, err)
+		}
+		if _, err := r.GetTotp(ctx, "u1"); !errors.Is(err, domain.ErrTwoFactorNotEnabled) {
+			t.Errorf("GetTotp after Delete: got %v, want ErrTwoFactorNotEnabled", err)
+		}
+		if n, err := r.CountRecoveryCodes(ctx, "u2"); err != nil || n != 1 {
+			t.Errorf("CountRecoveryCodes of another user: got %d, %v, want 1", n, err)
+		}
+	})
+}
@@ -0,0 +1,201 @@
+// Package repotest is the contract the implementations of the user
+// repositories must fulfil, which is run against each of them by their tests.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"bilingo/common"
+	domain "bilingo/domains/user"
+	"bilingo/domains/user/models"
+	"bilingo/domains/user/repo"
+	"bilingo/domains/user/types"
+)
+
+// TestUserRepo tests the user repository against the contract.
+func TestUserRepo(t *testing.T, newRepo func(t *testing.T) repo.IUserRepo) {
+	ctx := context.Background()
+
+	create := func(t *testing.T, r repo.IUserRepo, email string, name string, birthdate string) *models.User {
+		t.Helper()
+		user, err := r.Create(ctx, &types.UserCreate{Email: email, Name: name, Password: "hash", Birthdate: &birthdate})
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		return user
+	}
+
+	t.Run("CreateAndGet", func(t *testing.T) {
+		r := newRepo(t)
+		created := create(t, r, "alice@example.com", "Alice", "1990-01-01")
+		if created.ID == "" || created.CreatedAt.IsZero() {
+			t.Fatalf("Create: got ID %q and time %v, want both set", created.ID, created.CreatedAt)
+		}
+
+		user, err := r.Get(ctx, created.ID)
+		if err != nil || user.Email != "alice@example.com" || user.Password == nil || *user.Password != "hash" {
+			t.Errorf("Get: got %+v, %v", user, err)
+		}
+		user, err = r.GetByEmail(ctx, "alice@example.com")
+		if err != nil || user.ID != created.ID {
+			t.Errorf("GetByEmail: got %+v, %v", user, err)
+		}
+
+		if _, err := r.Get(ctx, "missing"); !errors.Is(err, domain.ErrUserNotFound) {
+			t.Errorf("Get of a missing user: got %v, want ErrUserNotFound", err)
+		}
+		if _, err := r.GetByEmail(ctx, "missing@example.com"); !errors.Is(err, domain.ErrUserNotFound) {
+			t.Errorf("GetByEmail of a missing user: got %v, want ErrUserNotFound", err)
+		}
+		if _, err := r.Create(ctx, &types.UserCreate{Email: "alice@example.com", Name: "Other"}); !errors.Is(err, domain.ErrUserExists) {
+			t.Errorf("Create with a taken email: got %v, want ErrUserExists", err)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		r := newRepo(t)
+		alice := create(t, r, "alice@example.com", "Alice", "1990-01-01")
+		bob := create(t, r, "bob@example.com", "Bob", "1985-06-01")
+		carol := create(t, r, "carol@example.com", "Carol", "2000-12-31")
+		created := []string{alice.ID, bob.ID, carol.ID}
+
+		// Only the users created here are compared, the repository may have
+		// users of its own, e.g. the placeholder of deleted users
+		list := func(query types.UserListQuery) []string {
+			t.Helper()
+			query.Page, query.PageSize = 1, 10
+			result, err := r.List(ctx, query)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			var names []string
+			for _, user := range result.List {
+				if slices.Contains(created, user.ID) {
+					names = append(names, user.Name)
+				}
+			}
+			slices.Sort(names)
+			return names
+		}
+
+		assertNames(t, "List", list(types.UserListQuery{}), "Alice", "Bob", "Carol")
+		search := "ob"
+		assertNames(t, "List by search", list(types.UserListQuery{Search: &search}), "Bob")
+		ids := []string{alice.ID, carol.ID}
+		assertNames(t, "List by IDs", list(types.UserListQuery{Ids: &ids}), "Alice", "Carol")
+		emails := []string{"bob@example.com"}
+		assertNames(t, "List by emails", list(types.UserListQuery{Emails: &emails}), "Bob")
+		start, end := "1986-01-01", "1999-12-31"
+		birthdate := &common.Range[*string]{Start: &start, End: &end}
+		assertNames(t, "List by birthdate", list(types.UserListQuery{Birthdate: birthdate}), "Alice")
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		r := newRepo(t)
+		created, err := r.Create(ctx, &types.UserCreate{Email: "sso@example.com", Name: "Sso"})
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if created.Password != nil {
+			t.Errorf("Create without a password: got a password")
+		}
+
+		// Users signed up through SSO have neither a password nor a birthdate
+		name, password, birthdate := "Renamed", "new-hash", "1995-05-05"
+		user, err := r.Update(ctx, created.ID, &types.UserUpdate{Name: &name, Password: &password, Birthdate: &birthdate})
+		if err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if user.Name != name || user.Password == nil || *user.Password != password || user.Birthdate == nil || *user.Birthdate != birthdate {
+			t.Errorf("Update: got %+v", user)
+		}
+
+		if _, err := r.Update(ctx, "missing", &types.UserUpdate{Name: &name}); !errors.Is(err, domain.ErrUserNotFound) {
+			t.Errorf("Update of a missing user: got %v, want ErrUserNotFound", err)
+		}
+	})
+
+	t.Run("Email", func(t *testing.T) {
+		r := newRepo(t)
+		alice := create(t, r, "alice@example.com", "Alice", "1990-01-01")
+		create(t, r, "bob@example.com", "Bob", "1985-06-01")
+		now := time.Now().UTC().Truncate(time.Second)
+
+		if err := r.SetEmailVerified(ctx, alice.ID, now); err != nil {
+			t.Fatalf("SetEmailVerified: %v", err)
+		}
+		if user, err := r.Get(ctx, alice.ID); err != nil || user.EmailVerifiedAt == nil || !user.EmailVerifiedAt.Equal(now) {
+			t.Errorf("Get after SetEmailVerified: got %+v, %v", user, err)
+		}
+
+		if err := r.ChangeEmail(ctx, alice.ID, "new@example.com", now); !errors.Is(err, domain.ErrInvalidEmailChange) {
+			t.Errorf("ChangeEmail without a pending email: got %v, want ErrInvalidEmailChange", err)
+		}
+
+		taken := "bob@example.com"
+		if err := r.SetPendingEmail(ctx, alice.ID, &taken); err != nil {
+			t.Fatalf("SetPendingEmail: %v", err)
+		}
+		if err := r.ChangeEmail(ctx, alice.ID, taken, now); !errors.Is(err, domain.ErrUserExists) {
+			t.Errorf("ChangeEmail to a taken email: got %v, want ErrUserExists", err)
+		}
+
+		email := "new@example.com"
+		if err := r.SetPendingEmail(ctx, alice.ID, &email); err != nil {
+			t.Fatalf("SetPendingEmail: %v", err)
+		}
+		if err := r.ChangeEmail(ctx, alice.ID, taken, now); !errors.Is(err, domain.ErrInvalidEmailChange) {
+			t.Errorf("ChangeEmail to a replaced pending email: got %v, want ErrInvalidEmailChange", err)
+		}
+		if err := r.ChangeEmail(ctx, alice.ID, email, now); err != nil {
+			t.Fatalf("ChangeEmail: %v", err)
+		}
+		user, err := r.GetByEmail(ctx, email)
+		if err != nil || user.ID != alice.ID || user.PendingEmail != nil {
+			t.Errorf("GetByEmail after ChangeEmail: got %+v, %v", user, err)
+		}
+
+		if err := r.SetPendingEmail(ctx, alice.ID, &taken); err != nil {
+			t.Fatalf("SetPendingEmail: %v", err)
+		}
+		if err := r.SetPendingEmail(ctx, alice.ID, nil); err != nil {
+			t.Fatalf("SetPendingEmail to cancel: %v", err)
+		}
+		if user, err := r.Get(ctx, alice.ID); err != nil || user.PendingEmail != nil {
+			t.Errorf("Get after cancelling the email change: got %+v, %v", user, err)
+		}
+
+		if err := r.SetPendingEmail(ctx, "missing", &email); !errors.Is(err, domain.ErrUserNotFound) {
+			t.Errorf("SetPendingEmail of a missing user: got %v, want ErrUserNotFound", err)
+		}
+		if err := r.SetEmailVerified(ctx, "missing", now); !errors.Is(err, domain.ErrUserNotFound) {
+			t.Errorf("SetEmailVerified of a missing user: got %v, want ErrUserNotFound", err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		r := newRepo(t)
+		created := create(t, r, "alice@example.com", "Alice", "1990-01-01")
+
+		if err := r.Delete(ctx, created.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := r.Get(ctx, created.ID); !errors.Is(err, domain.ErrUserNotFound) {
+			t.Errorf("Get after Delete: got %v, want ErrUserNotFound", err)
+		}
+		if err := r.Delete(ctx, created.ID); !errors.Is(err, domain.ErrUserNotFound) {
+			t.Errorf("Delete of a missing user: got %v, want ErrUserNotFound", err)
+		}
+	})
+}
+
+func assertNames(t *testing.T, name string, got []string, want ...string) {
+	t.Helper()
+	if !slices.Equal(got, want) {
+		t.Errorf("%s: got %v, want %v", name, got, want)
+	}
+}
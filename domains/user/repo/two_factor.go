@@ -0,0 +1,42 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"bilingo/domains/user/models"
+	impl "bilingo/domains/user/repo/db"
+	"bilingo/server/app"
+)
+
+// TwoFactors returns the two-factor repository of the app the context carries, or the
+// database implementation outside of apps.
+func TwoFactors(ctx context.Context) ITwoFactorRepo {
+	return app.Resolve[ITwoFactorRepo](ctx, &impl.TwoFactorRepo{})
+}
+
+type ITwoFactorRepo interface {
+	// GetTotp returns the TOTP of the user, pending or enabled, it fails with
+	// ErrTwoFactorNotEnabled if there's none.
+	GetTotp(ctx context.Context, userId string) (*models.UserTotp, error)
+	// SaveTotp creates or replaces the TOTP of the user.
+	SaveTotp(ctx context.Context, totp *models.UserTotp) error
+	// EnableTotp enables the TOTP of the user with the first accepted step.
+	EnableTotp(ctx context.Context, userId string, step int64, now time.Time) error
+	// UseTotpStep records the step of an accepted code, and fails with
+	// ErrInvalidTwoFactorCode if a code of the same or a later step has been
+	// accepted already.
+	UseTotpStep(ctx context.Context, userId string, step int64) error
+	RecordFailure(ctx context.Context, userId string, now time.Time) error
+	// ListEnabled returns the enabled TOTPs of all users.
+	ListEnabled(ctx context.Context) ([]models.UserTotp, error)
+	// Delete deletes the TOTP and recovery codes of the user.
+	Delete(ctx context.Context, userId string) error
+
+	// ReplaceRecoveryCodes replaces the recovery codes of the user.
+	ReplaceRecoveryCodes(ctx context.Context, userId string, codeHashes []string, now time.Time) error
+	// UseRecoveryCode marks the recovery code as used, and fails with
+	// ErrInvalidTwoFactorCode if it doesn't exist or has been used already.
+	UseRecoveryCode(ctx context.Context, userId string, codeHash string, now time.Time) error
+	CountRecoveryCodes(ctx context.Context, userId string) (int, error)
+}
@@ -2,19 +2,37 @@ package repo
 
 import (
 	"context"
+	"time"
 
 	"bilingo/common"
 	"bilingo/domains/user/models"
 	impl "bilingo/domains/user/repo/db"
 	"bilingo/domains/user/types"
+	"bilingo/server/app"
 )
 
-var UserRepo IUserRepo = &impl.UserRepo{}
+// Users returns the user repository of the app the context carries, or the
+// database implementation outside of apps.
+func Users(ctx context.Context) IUserRepo {
+	return app.Resolve[IUserRepo](ctx, &impl.UserRepo{})
+}
 
 type IUserRepo interface {
-	Get(ctx context.Context, email string) (*models.User, error)
+	Get(ctx context.Context, id string) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
 	List(ctx context.Context, query types.UserListQuery) (*common.PaginatedResult[models.User], error)
+	// Create creates a user with a new ID, it fails with ErrUserExists if the
+	// email is taken.
 	Create(ctx context.Context, data *types.UserCreate) (*models.User, error)
-	Update(ctx context.Context, email string, data *types.UserUpdate) (*models.User, error)
-	Delete(ctx context.Context, email string) error
+	Update(ctx context.Context, id string, data *types.UserUpdate) (*models.User, error)
+	// Delete deletes the user, it fails with ErrUserHasContent if anything still
+	// refers to the user.
+	Delete(ctx context.Context, id string) error
+	SetEmailVerified(ctx context.Context, id string, verifiedAt time.Time) error
+	// SetPendingEmail sets the email the user is changing to, nil cancels it.
+	SetPendingEmail(ctx context.Context, id string, email *string) error
+	// ChangeEmail replaces the email of the user with the pending one, as long
+	// as it's still pending, otherwise it fails with ErrInvalidEmailChange. It fails
+	// with ErrUserExists if the email has been taken meanwhile.
+	ChangeEmail(ctx context.Context, id string, email string, verifiedAt time.Time) error
 }
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // Register the decoders of the accepted formats
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"strconv"
+	"time"
+
+	domain "bilingo/domains/user"
+	repo "bilingo/domains/user/repo"
+	"bilingo/domains/user/types"
+	"bilingo/server/db"
+	"bilingo/server/oplog"
+	"bilingo/server/storage"
+)
+
+// avatarSizes are the widths and heights the avatars are stored in.
+var avatarSizes = []int{64, 128, 256}
+
+const (
+	maxAvatarBytes  = 5 << 20 // 5 MiB
+	maxAvatarPixels = 4096    // The maximum width and height of uploaded images
+)
+
+// SetAvatar replaces the avatar of the user with the image, a JPEG, PNG or GIF,
+// which is cropped to a square and stored as PNG in each of the avatarSizes.
+// It returns the profile as seen by the user.
+func SetAvatar(ctx context.Context, userId string, r io.Reader) (*types.Profile, error) {
+	user, err := repo.Users(ctx).Get(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := repo.Profiles(ctx).Get(db.UsePrimary(ctx), userId)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, maxAvatarBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read avatar: %w", err)
+	} else if len(data) > maxAvatarBytes {
+		return nil, fmt.Errorf("%w: larger than %d MiB", domain.ErrInvalidAvatar, maxAvatarBytes>>20)
+	}
+
+	// Check the dimensions before decoding, so that small files of huge images
+	// don't take up the memory
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidAvatar, err)
+	} else if cfg.Width > maxAvatarPixels || cfg.Height > maxAvatarPixels {
+		return nil, fmt.Errorf("%w: larger than %dx%d pixels", domain.ErrInvalidAvatar, maxAvatarPixels, maxAvatarPixels)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidAvatar, err)
+	}
+
+	// A new random prefix for every upload, so that cached images are never
+	// stale and private avatars can't be guessed
+	version := make([]byte, 8)
+	if _, err := rand.Read(version); err != nil {
+		return nil, err
+	}
+	prefix := "avatars/" + userId + "/" + hex.EncodeToString(version)
+
+	square := cropSquare(img)
+	for _, size := range avatarSizes {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, resize(square, size)); err != nil {
+			return nil, fmt.Errorf("failed to encode avatar: %w", err)
+		}
+		err := storage.FromContext(ctx).Put(ctx, avatarKey(prefix, size), &buf, "image/png")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	oldPrefix := profile.Avatar
+	profile.Avatar = &prefix
+	profile.UpdatedAt = time.Now()
+	if err := repo.Profiles(ctx).Save(ctx, profile); err != nil {
+		return nil, err
+	}
+	if oldPrefix != nil {
+		deleteAvatarFiles(ctx, *oldPrefix)
+	}
+
+	logger.Success(ctx, oplog.LogData{ObjectId: userId, Operation: "set_avatar"})
+	return toProfile(ctx, user, profile, types.VisibilityPrivate), nil
+}
+
+// DeleteAvatar removes the avatar of the user.
+func DeleteAvatar(ctx context.Context, userId string) error {
+	profile, err := repo.Profiles(ctx).Get(db.UsePrimary(ctx), userId)
+	if err != nil {
+		return err
+	} else if profile.Avatar == nil {
+		return nil
+	}
+
+	oldPrefix := *profile.Avatar
+	profile.Avatar = nil
+	profile.UpdatedAt = time.Now()
+	if err := repo.Profiles(ctx).Save(ctx, profile); err != nil {
+		return err
+	}
+	deleteAvatarFiles(ctx, oldPrefix)
+
+	logger.Success(ctx, oplog.LogData{ObjectId: userId, Operation: "delete_avatar"})
+	return nil
+}
+
+// deleteProfile deletes the profile of the user along with the avatar.
+func deleteProfile(ctx context.Context, userId string) error {
+	profile, err := repo.Profiles(ctx).Get(db.UsePrimary(ctx), userId)
+	if err != nil {
+		return err
+	}
+	if err := repo.Profiles(ctx).Delete(ctx, userId); err != nil {
+		return err
+	}
+	if profile.Avatar != nil {
+		deleteAvatarFiles(ctx, *profile.Avatar)
+	}
+	return nil
+}
+
+// deleteAvatarFiles deletes the images of the avatar, failures are only logged
+// since the avatar isn't referred to anymore.
+func deleteAvatarFiles(ctx context.Context, prefix string) {
+	for _, size := range avatarSizes {
+		if err := storage.FromContext(ctx).Delete(ctx, avatarKey(prefix, size)); err != nil {
+			log.Printf("failed to delete avatar %s: %v", avatarKey(prefix, size), err)
+		}
+	}
+}
+
+func avatarKey(prefix string, size int) string {
+	return prefix + "/" + strconv.Itoa(size) + ".png"
+}
+
+func avatarUrls(ctx context.Context, prefix string) map[string]string {
+	urls := make(map[string]string, len(avatarSizes))
+	for _, size := range avatarSizes {
+		urls[strconv.Itoa(size)] = storage.FromContext(ctx).URL(avatarKey(prefix, size))
+	}
+	return urls
+}
+
+// cropSquare crops the largest centered square of the image.
+func cropSquare(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	side := min(b.Dx(), b.Dy())
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+
+	square := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(square, square.Bounds(), img, image.Pt(x0, y0), draw.Src)
+	return square
+}
+
+// resize scales the square image to the size by averaging the source pixels
+// each target pixel covers, which keeps downscaled images smooth.
+func resize(src *image.RGBA, size int) *image.RGBA {
+	side := src.Bounds().Dx()
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := range size {
+		sy0, sy1 := span(y, size, side)
+		for x := range size {
+			sx0, sx1 := span(x, size, side)
+
+			var r, g, b, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					i := src.PixOffset(sx, sy)
+					r += uint64(src.Pix[i])
+					g += uint64(src.Pix[i+1])
+					b += uint64(src.Pix[i+2])
+					a += uint64(src.Pix[i+3])
+					n++
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// span returns the range of source pixels the target pixel covers, at least
+// one pixel when upscaling.
+func span(i int, size int, side int) (int, int) {
+	start := i * side / size
+	end := (i + 1) * side / size
+	if end <= start {
+		end = start + 1
+	}
+	return start, end
+}
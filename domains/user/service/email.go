@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	domain "bilingo/domains/user"
+	"bilingo/domains/user/models"
+	repo "bilingo/domains/user/repo"
+	"bilingo/domains/user/types"
+	"bilingo/server/app"
+	"bilingo/server/auth"
+	"bilingo/server/mailer"
+	"bilingo/server/oplog"
+)
+
+// RequestEmailChange sends a confirmation link to the new email of the user,
+// the email is only changed once the link is opened. Requesting another change
+// makes the previous link invalid.
+func RequestEmailChange(ctx context.Context, userId string, data *types.EmailChange) error {
+	user, err := repo.Users(ctx).Get(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if user.Password == nil {
+		return domain.ErrInvalidPassword
+	} else if err := verifyPassword(*user.Password, data.Password); err != nil {
+		return domain.ErrInvalidPassword
+	}
+
+	email := strings.TrimSpace(data.NewEmail)
+	if err := validateEmail(email); err != nil {
+		return err
+	}
+	if _, err := repo.Users(ctx).GetByEmail(ctx, email); err == nil {
+		return domain.ErrUserExists
+	} else if !errors.Is(err, domain.ErrUserNotFound) {
+		return err
+	}
+
+	cfg := app.Config(ctx)
+	token, err := auth.GeneratePurposeClaims(ctx, auth.PurposeChangeEmail, map[string]string{
+		"sub":   user.ID,
+		"email": email,
+	}, cfg.Auth.VerificationDuration)
+	if err != nil {
+		return fmt.Errorf("failed to generate email change: %w", err)
+	}
+
+	if err := repo.Users(ctx).SetPendingEmail(ctx, user.ID, &email); err != nil {
+		return err
+	}
+
+	msg, err := mails.Render("change_email", email, mailData{
+		AppName: cfg.AppName,
+		Name:    user.Name,
+		Link:    appLink(ctx, "/users/email/verify", "token", token),
+		Expires: formatDuration(cfg.Auth.VerificationDuration),
+	})
+	if err != nil {
+		return err
+	}
+
+	return mailer.SendLater(ctx, msg)
+}
+
+// CancelEmailChange cancels the pending email change of the user.
+func CancelEmailChange(ctx context.Context, userId string) error {
+	return repo.Users(ctx).SetPendingEmail(ctx, userId, nil)
+}
+
+// ConfirmEmailChange changes the email of the user with the token sent in the
+// confirmation mail. The password reset links sent to the old email are
+// revoked, since they may be in the hands of whoever owns it now.
+func ConfirmEmailChange(ctx context.Context, token string) (*models.User, error) {
+	claims, err := auth.ParsePurposeClaims(ctx, auth.PurposeChangeEmail, token)
+	if err != nil {
+		return nil, err
+	}
+	userId, email := claims["sub"], claims["email"]
+	if userId == "" || email == "" {
+		return nil, auth.ErrInvalidToken
+	}
+
+	user, err := repo.Users(ctx).Get(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	// The change is recorded below instead of as a plain update
+	now := time.Now()
+	if err := repo.Users(ctx).ChangeEmail(oplog.SkipAudit(ctx), userId, email, now); err != nil {
+		return nil, err
+	}
+
+	if err := repo.PasswordResets(ctx).RevokeAll(ctx, userId, now); err != nil {
+		return nil, err
+	}
+
+	logger.Success(ctx, oplog.LogData{
+		ObjectId:  userId,
+		Operation: "change_email",
+		OldData:   map[string]string{"email": user.Email},
+		NewData:   map[string]string{"email": email},
+	})
+	return GetUser(ctx, userId)
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"bilingo/config"
+	"bilingo/server/app"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var errMalformedHash = errors.New("malformed password hash")
+
+// hashPassword hashes a plain text password with the algorithm set in the
+// configuration.
+func hashPassword(ctx context.Context, password string) (string, error) {
+	cfg := app.Config(ctx).Password
+	switch cfg.Algorithm {
+	case config.HashArgon2id:
+		return hashArgon2id(password, argon2Params{
+			time:    cfg.Argon2Time,
+			memory:  cfg.Argon2Memory,
+			threads: cfg.Argon2Threads,
+		})
+	case config.HashBcrypt:
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), cfg.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hashedPassword), nil
+	default:
+		return "", fmt.Errorf("unsupported hash algorithm: %s", cfg.Algorithm)
+	}
+}
+
+// verifyPassword verifies if the provided password matches the stored hash,
+// which can be of any supported algorithm.
+func verifyPassword(hashedPassword string, password string) error {
+	if strings.HasPrefix(hashedPassword, "$argon2id$") {
+		params, salt, key, err := decodeArgon2id(hashedPassword)
+		if err != nil {
+			return err
+		}
+
+		other := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+		if subtle.ConstantTimeCompare(key, other) != 1 {
+			return bcrypt.ErrMismatchedHashAndPassword
+		}
+		return nil
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+}
+
+// needsRehash reports whether the hash isn't of the algorithm or parameters set
+// in the configuration, so it should be upgraded.
+func needsRehash(ctx context.Context, hashedPassword string) bool {
+	cfg := app.Config(ctx).Password
+	switch cfg.Algorithm {
+	case config.HashArgon2id:
+		params, _, _, err := decodeArgon2id(hashedPassword)
+		return err != nil ||
+			params.time != cfg.Argon2Time ||
+			params.memory != cfg.Argon2Memory ||
+			params.threads != cfg.Argon2Threads
+	case config.HashBcrypt:
+		cost, err := bcrypt.Cost([]byte(hashedPassword))
+		return err != nil || cost != cfg.BcryptCost
+	default:
+		return false
+	}
+}
+
+type argon2Params struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+}
+
+// hashArgon2id hashes the password with argon2id, encoded in the PHC string
+// format, e.g. `$argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>`.
+func hashArgon2id(password string, params argon2Params) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, 32)
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		params.memory,
+		params.time,
+		params.threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func decodeArgon2id(hash string) (params argon2Params, salt []byte, key []byte, err error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, errMalformedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, errMalformedHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return params, nil, nil, errMalformedHash
+	}
+
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return params, nil, nil, errMalformedHash
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(key) == 0 {
+		return params, nil, nil, errMalformedHash
+	}
+
+	return params, salt, key, nil
+}
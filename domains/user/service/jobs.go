@@ -0,0 +1,17 @@
+package service
+
+import (
+	"context"
+
+	"bilingo/server/jobs"
+)
+
+// RegisterJobs registers the handlers of the jobs of the users.
+func RegisterJobs(q *jobs.Queue) {
+	jobs.Handle(q, func(ctx context.Context, job runDeletion) error {
+		return RunDeletion(ctx, job.DeletionID, job.Ip)
+	})
+	jobs.Handle(q, func(ctx context.Context, job passwordReset) error {
+		return sendPasswordReset(ctx, job.Email, job.Ip)
+	})
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	domain "bilingo/domains/user"
+	"bilingo/domains/user/models"
+	repo "bilingo/domains/user/repo"
+	"bilingo/domains/user/types"
+	"bilingo/server"
+	"bilingo/server/app"
+	"bilingo/server/jobs"
+	"bilingo/server/mailer"
+	"bilingo/server/oplog"
+)
+
+var logger = oplog.NewOpLogger("user")
+
+// passwordReset sends a password reset link to the email in the job queue, on
+// behalf of the IP it was requested from.
+type passwordReset struct {
+	Email string `json:"email"`
+	Ip    string `json:"ip"`
+}
+
+func (passwordReset) JobName() string {
+	return "user.send-password-reset"
+}
+
+// ForgotPassword sends a password reset link to the email in the background.
+// The job is enqueued whether the email is registered or not, so that neither
+// the outcome nor the time it takes can be used to find out registered emails.
+func ForgotPassword(ctx context.Context, email string) error {
+	_, err := jobs.Enqueue(ctx, passwordReset{Email: email, Ip: server.GetClientIp(ctx)})
+	return err
+}
+
+// sendPasswordReset issues a reset token to the user with the email and mails
+// them the link, it does nothing if the email isn't registered.
+func sendPasswordReset(ctx context.Context, email string, ip string) error {
+	user, err := repo.Users(ctx).GetByEmail(ctx, email)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if ip != "" {
+		ctx = server.WithClientIp(ctx, ip)
+	}
+
+	token, err := newRandomToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate reset token: %w", err)
+	}
+
+	cfg := app.Config(ctx)
+	now := time.Now()
+	err = repo.PasswordResets(ctx).Create(ctx, &models.PasswordResetToken{
+		TokenHash: hashResetToken(token),
+		UserID:    user.ID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(cfg.Auth.ResetDuration),
+	})
+	if err != nil {
+		return err
+	}
+
+	msg, err := mails.Render("reset_password", user.Email, mailData{
+		AppName: cfg.AppName,
+		Name:    user.Name,
+		Link:    appLink(ctx, "/users/password/reset", "token", token),
+		Expires: formatDuration(cfg.Auth.ResetDuration),
+	})
+	if err != nil {
+		return err
+	}
+
+	// The job is retried with a new token, the one issued here expires unused
+	if err := mailer.Send(ctx, msg); err != nil {
+		log.Printf("failed to send password reset to %s: %v", user.Email, err)
+		logger.Failure(ctx, oplog.LogData{
+			ObjectId:  user.ID,
+			Operation: "forgot_password",
+		})
+		return err
+	}
+
+	logger.Success(ctx, oplog.LogData{
+		ObjectId:  user.ID,
+		Operation: "forgot_password",
+	})
+	return nil
+}
+
+// ResetPassword sets the password of the user the token was issued to. The
+// token can only be used once, and the other outstanding tokens of the user
+// are revoked as well.
+func ResetPassword(ctx context.Context, data *types.PasswordReset) error {
+	now := time.Now()
+	tokenHash := hashResetToken(data.Token)
+
+	// Check the new password before using up the token, so the user can try
+	// another one
+	token, err := repo.PasswordResets(ctx).Get(ctx, tokenHash, now)
+	if err != nil {
+		return err
+	}
+	user, err := repo.Users(ctx).Get(ctx, token.UserID)
+	if err != nil {
+		return err
+	}
+	if err := validatePassword(ctx, data.NewPassword, user.Email, user.Name); err != nil {
+		return err
+	}
+
+	if _, err := repo.PasswordResets(ctx).Consume(ctx, tokenHash, now); err != nil {
+		return err
+	}
+
+	if err := repo.PasswordResets(ctx).RevokeAll(ctx, token.UserID, now); err != nil {
+		return err
+	}
+
+	hashedPassword, err := hashPassword(ctx, data.NewPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash new password: %w", err)
+	}
+
+	// The reset is recorded below instead of as a plain update
+	auditCtx := oplog.SkipAudit(ctx)
+	if _, err := repo.Users(ctx).Update(auditCtx, token.UserID, &types.UserUpdate{Password: &hashedPassword}); err != nil {
+		return err
+	}
+
+	// Following the link proves the ownership of the email as well
+	if user.EmailVerifiedAt == nil {
+		if err := repo.Users(ctx).SetEmailVerified(auditCtx, user.ID, now); err != nil {
+			return err
+		}
+	}
+
+	logger.Success(ctx, oplog.LogData{
+		ObjectId:  token.UserID,
+		Operation: "reset_password",
+	})
+	return nil
+}
+
+// newRandomToken generates a random URL-safe token of 256 bits.
+func newRandomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	domain "bilingo/domains/user"
+	"bilingo/server/app"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordsFile []byte
+
+var (
+	builtinCommonPasswords = sync.OnceValue(func() map[string]struct{} {
+		return parseCommonPasswords(commonPasswordsFile)
+	})
+	commonPasswordFiles sync.Map // The lists of the files in the configurations, by path
+)
+
+// isCommonPassword reports whether the password is in the embedded list of
+// common passwords or the one set in the configuration of the app the context
+// carries.
+func isCommonPassword(ctx context.Context, password string) bool {
+	password = strings.ToLower(password)
+	if _, ok := builtinCommonPasswords()[password]; ok {
+		return true
+	}
+	if file := app.Config(ctx).Password.CommonPasswordsFile; file != "" {
+		_, ok := loadCommonPasswords(file)[password]
+		return ok
+	}
+	return false
+}
+
+// loadCommonPasswords returns the common passwords listed in the file, which is
+// only read once.
+func loadCommonPasswords(file string) map[string]struct{} {
+	load, _ := commonPasswordFiles.LoadOrStore(file, sync.OnceValue(func() map[string]struct{} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			log.Printf("failed to load common passwords: %v", err)
+			return nil
+		}
+		return parseCommonPasswords(data)
+	}))
+	return load.(func() map[string]struct{})()
+}
+
+// parseCommonPasswords parses a list of passwords, one per line, ignoring the
+// blank lines and the comments starting with #.
+func parseCommonPasswords(data []byte) map[string]struct{} {
+	passwords := map[string]struct{}{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			passwords[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	return passwords
+}
+
+// validatePassword checks the password against the policy in the configuration,
+// and returns an ErrWeakPassword error listing all the violated rules.
+func validatePassword(ctx context.Context, password string, email string, name string) error {
+	cfg := app.Config(ctx).Password
+	var violations []string
+
+	if utf8.RuneCountInString(password) < cfg.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", cfg.MinLength))
+	}
+	if len(password) > cfg.MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d bytes long", cfg.MaxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case !unicode.IsLetter(r):
+			hasSymbol = true
+		}
+	}
+	if cfg.RequireUpper && !hasUpper {
+		violations = append(violations, "must contain an upper case letter")
+	}
+	if cfg.RequireLower && !hasLower {
+		violations = append(violations, "must contain a lower case letter")
+	}
+	if cfg.RequireDigit && !hasDigit {
+		violations = append(violations, "must contain a digit")
+	}
+	if cfg.RequireSymbol && !hasSymbol {
+		violations = append(violations, "must contain a symbol")
+	}
+
+	localPart, _, _ := strings.Cut(email, "@")
+	for _, personal := range []string{email, localPart, name} {
+		if personal != "" && strings.EqualFold(password, personal) {
+			violations = append(violations, "must not be the email or name")
+			break
+		}
+	}
+
+	if cfg.RejectCommon && isCommonPassword(ctx, password) {
+		violations = append(violations, "is too common")
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("%w: password %s", domain.ErrWeakPassword, strings.Join(violations, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"bilingo/config"
+	systemService "bilingo/domains/system/service"
+	domain "bilingo/domains/user"
+	"bilingo/domains/user/models"
+	repo "bilingo/domains/user/repo"
+	"bilingo/domains/user/types"
+	"bilingo/server"
+	"bilingo/server/app"
+	"bilingo/server/auth"
+	"bilingo/server/db"
+	"bilingo/server/events"
+	"bilingo/server/jobs"
+	"bilingo/server/oplog"
+
+	"github.com/google/uuid"
+)
+
+// profileRecord is the profile of the user in the data export.
+type profileRecord struct {
+	User       *models.User           `json:"user"`
+	Profile    *types.Profile         `json:"profile"`
+	TwoFactor  *types.TwoFactorStatus `json:"two_factor"`
+	Identities []models.UserIdentity  `json:"identities"`
+}
+
+// ExportUserData writes a zip archive of the data kept about the user to the
+// writer, the profile along with the data the domains registered with
+// RegisterUserData keep, e.g. articles, comments, reactions and oplogs.
+func ExportUserData(ctx context.Context, userId string, w io.Writer) error {
+	user, err := GetUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+	profile, err := GetProfile(ctx, userId, types.VisibilityPrivate)
+	if err != nil {
+		return err
+	}
+	twoFactor, err := GetTwoFactorStatus(ctx, userId)
+	if err != nil {
+		return err
+	}
+	identities, err := repo.Identities(ctx).List(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	f, err := zw.Create("profile.json")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(profileRecord{User: user, Profile: profile, TwoFactor: twoFactor, Identities: identities}); err != nil {
+		return err
+	}
+
+	for _, handler := range systemService.UserDataHandlers() {
+		if handler.Export == nil {
+			continue
+		}
+		if err := handler.Export(ctx, userId, zw); err != nil {
+			return fmt.Errorf("failed to export user data: %w", err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	logger.Success(ctx, oplog.LogData{ObjectId: userId, Operation: "export_data"})
+	return nil
+}
+
+// runDeletion carries out the account deletion in the job queue, on behalf of
+// the user who requested it from the IP.
+type runDeletion struct {
+	DeletionID string `json:"deletion_id"`
+	Ip         string `json:"ip"`
+}
+
+func (runDeletion) JobName() string {
+	return "user.run-deletion"
+}
+
+// RequestDeletion starts deleting the account of the user in the background,
+// the progress is available with GetDeletionStatus. The content of the user is
+// deleted, anonymized or transferred to another user according to the policy,
+// which defaults to the configured one.
+func RequestDeletion(ctx context.Context, userId string, data *types.DeletionRequest, requestedBy string) (*models.UserDeletion, error) {
+	if userId == models.DeletedUserID {
+		return nil, domain.ErrDeletedUser
+	}
+	if _, err := repo.Users(ctx).Get(ctx, userId); err != nil {
+		return nil, err
+	}
+
+	deletion := &models.UserDeletion{
+		ID:          uuid.NewString(),
+		UserID:      userId,
+		Policy:      app.Config(ctx).Privacy.DeletionPolicy,
+		RequestedBy: &requestedBy,
+		Status:      types.DeletionPending,
+		CreatedAt:   time.Now(),
+	}
+	if data.Policy != nil && *data.Policy != "" {
+		deletion.Policy = *data.Policy
+	}
+
+	switch deletion.Policy {
+	case config.DeletionCascade, config.DeletionAnonymize:
+	case config.DeletionTransfer:
+		if data.TransferTo == nil || *data.TransferTo == "" || *data.TransferTo == userId {
+			return nil, fmt.Errorf("%w: the content must be transferred to another user", domain.ErrDeletionPolicy)
+		}
+		if _, err := repo.Users(ctx).Get(ctx, *data.TransferTo); err != nil {
+			return nil, err
+		}
+		deletion.TransferTo = data.TransferTo
+	default:
+		return nil, fmt.Errorf("%w: %q", domain.ErrDeletionPolicy, deletion.Policy)
+	}
+
+	if _, err := repo.Deletions(ctx).GetUnfinished(ctx, userId); err == nil {
+		return nil, domain.ErrDeletionInProgress
+	} else if !errors.Is(err, domain.ErrDeletionNotFound) {
+		return nil, err
+	}
+
+	// The job only runs once the deletion is committed
+	err := db.Transaction(ctx, domain.DBBinding, func(ctx context.Context) error {
+		if err := repo.Deletions(ctx).Create(ctx, deletion); err != nil {
+			return err
+		}
+		_, err := jobs.EnqueueWith(ctx, runDeletion{DeletionID: deletion.ID, Ip: server.GetClientIp(ctx)}, jobs.EnqueueOptions{
+			UniqueKey: "user.run-deletion:" + deletion.ID,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Success(ctx, oplog.LogData{
+		ObjectId:    userId,
+		Operation:   "request_deletion",
+		Description: &deletion.Policy,
+	})
+	return deletion, nil
+}
+
+// GetDeletion returns the account deletion.
+func GetDeletion(ctx context.Context, id string) (*models.UserDeletion, error) {
+	return repo.Deletions(ctx).Get(ctx, id)
+}
+
+// GetDeletionStatus returns the progress of the account deletion.
+func GetDeletionStatus(deletion *models.UserDeletion) *types.DeletionStatus {
+	return &types.DeletionStatus{
+		ID:          deletion.ID,
+		Policy:      deletion.Policy,
+		Status:      deletion.Status,
+		CreatedAt:   deletion.CreatedAt,
+		CompletedAt: deletion.CompletedAt,
+	}
+}
+
+// RunDeletion carries out the account deletion, the steps can be repeated
+// safely. A failed deletion is marked failed with the reason, and retried by
+// the job queue as long as the job has attempts left.
+func RunDeletion(ctx context.Context, id string, ip string) error {
+	deletion, err := repo.Deletions(ctx).Get(ctx, id)
+	if err != nil {
+		return err
+	} else if deletion.Status == types.DeletionCompleted {
+		return nil
+	}
+
+	// The oplogs of the deletion keep the requester and the IP of the request
+	if deletion.RequestedBy != nil {
+		ctx = auth.WithUser(ctx, &models.User{ID: *deletion.RequestedBy})
+	}
+	if ip != "" {
+		ctx = server.WithClientIp(ctx, ip)
+	}
+
+	if err := repo.Deletions(ctx).Start(ctx, deletion.ID, time.Now()); err != nil {
+		return err
+	}
+	if err := deleteAccount(ctx, deletion); err != nil {
+		log.Printf("failed to delete account %s: %v", deletion.UserID, err)
+		reason := err.Error()
+		if err := repo.Deletions(ctx).Finish(ctx, deletion.ID, &reason, time.Now()); err != nil {
+			log.Printf("failed to record the failure of account deletion %s: %v", deletion.ID, err)
+		}
+		return err
+	}
+	return repo.Deletions(ctx).Finish(ctx, deletion.ID, nil, time.Now())
+}
+
+// deleteAccount handles the content of the user according to the policy of the
+// deletion, then deletes the account and clears the personal data recorded in
+// the oplogs of the user. The oplogs of the actions of the user are kept, they
+// only refer to the user by the ID.
+//
+// Deleted content is not recorded row by row, which would keep it in the
+// oplogs, but by a single oplog of the deletion.
+func deleteAccount(ctx context.Context, deletion *models.UserDeletion) error {
+	userId := deletion.UserID
+	for _, handler := range systemService.UserDataHandlers() {
+		var err error
+		switch deletion.Policy {
+		case config.DeletionCascade:
+			if handler.Delete != nil {
+				err = handler.Delete(oplog.SkipAudit(ctx), userId)
+			}
+		case config.DeletionAnonymize:
+			if handler.Reassign != nil {
+				err = handler.Reassign(ctx, userId, models.DeletedUserID)
+			}
+		case config.DeletionTransfer:
+			if handler.Reassign != nil && deletion.TransferTo != nil {
+				err = handler.Reassign(ctx, userId, *deletion.TransferTo)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if deletion.Policy == config.DeletionCascade {
+		logger.Success(ctx, oplog.LogData{ObjectId: userId, Operation: "delete_content"})
+	}
+
+	// The deletion is recorded below, without the personal data a plain
+	// deletion would record. Resumed deletions may have deleted the user
+	// already.
+	err := DeleteUser(oplog.SkipAudit(ctx), userId)
+	if err != nil && !errors.Is(err, domain.ErrUserNotFound) {
+		return err
+	}
+
+	if _, err := systemService.ScrubOpLogs(ctx, "user", userId); err != nil {
+		return err
+	}
+
+	logger.Success(ctx, oplog.LogData{
+		ObjectId:    userId,
+		Operation:   "delete",
+		Description: &deletion.Policy,
+	})
+	// The account is gone either way, so the deletion doesn't fail for it
+	if err := events.Publish(ctx, domain.UserDeleted{ID: userId, Policy: deletion.Policy}); err != nil {
+		log.Printf("failed to publish the deletion of user %s: %v", userId, err)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+	_ "time/tzdata" // Time zones are validated regardless of the system
+	"unicode/utf8"
+
+	domain "bilingo/domains/user"
+	"bilingo/domains/user/models"
+	repo "bilingo/domains/user/repo"
+	"bilingo/domains/user/types"
+	"bilingo/server/db"
+	"bilingo/server/oplog"
+
+	"golang.org/x/text/language"
+)
+
+// profileVisibility is the default visibility of the profile fields, the
+// account name is always public since it's shown as the author of content.
+var profileVisibility = map[string]string{
+	"display_name": types.VisibilityPublic,
+	"bio":          types.VisibilityPublic,
+	"avatar":       types.VisibilityPublic,
+	"links":        types.VisibilityPublic,
+	"locale":       types.VisibilityPublic,
+	"timezone":     types.VisibilityPublic,
+	"email":        types.VisibilityPrivate,
+	"birthdate":    types.VisibilityPrivate,
+}
+
+// visibilityRanks orders the visibilities from the widest audience.
+var visibilityRanks = map[string]int{
+	types.VisibilityPublic:  0,
+	types.VisibilityUsers:   1,
+	types.VisibilityPrivate: 2,
+}
+
+const maxProfileLinks = 5
+
+// GetProfile returns the profile of the user as seen by the audience, which is
+// one of the Visibility* values, e.g. VisibilityUsers for logged in users. The
+// fields of a narrower visibility than the audience are left out.
+func GetProfile(ctx context.Context, userId string, audience string) (*types.Profile, error) {
+	user, err := repo.Users(ctx).Get(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := repo.Profiles(ctx).Get(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return toProfile(ctx, user, profile, audience), nil
+}
+
+// ListProfiles returns the profiles of the users as seen by the audience of
+// each of them, e.g. to show the authors of articles, in the order of the IDs.
+// Users not found are left out.
+func ListProfiles(ctx context.Context, ids []string, audienceOf func(userId string) string) ([]types.Profile, error) {
+	profiles := make([]types.Profile, 0, len(ids))
+	for _, id := range ids {
+		profile, err := GetProfile(ctx, id, audienceOf(id))
+		if errors.Is(err, domain.ErrUserNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, *profile)
+	}
+	return profiles, nil
+}
+
+// UpdateProfile updates the profile of the user, and returns it as seen by the
+// user.
+func UpdateProfile(ctx context.Context, userId string, data *types.ProfileUpdate) (*types.Profile, error) {
+	user, err := repo.Users(ctx).Get(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := repo.Profiles(ctx).Get(db.UsePrimary(ctx), userId)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateProfile(data); err != nil {
+		return nil, err
+	}
+
+	oldProfile := *profile
+	if data.DisplayName != nil {
+		profile.DisplayName = emptyToNil(*data.DisplayName)
+	}
+	if data.Bio != nil {
+		profile.Bio = emptyToNil(*data.Bio)
+	}
+	if data.Links != nil {
+		profile.Links = *data.Links
+	}
+	if data.Locale != nil {
+		profile.Locale = emptyToNil(*data.Locale)
+	}
+	if data.Timezone != nil {
+		profile.Timezone = emptyToNil(*data.Timezone)
+	}
+	if len(data.Visibility) > 0 {
+		visibility := make(map[string]string, len(profile.Visibility)+len(data.Visibility))
+		for field, value := range profile.Visibility {
+			visibility[field] = value
+		}
+		for field, value := range data.Visibility {
+			visibility[field] = value
+		}
+		profile.Visibility = visibility
+	}
+	profile.UpdatedAt = time.Now()
+
+	if err := repo.Profiles(ctx).Save(ctx, profile); err != nil {
+		return nil, err
+	}
+
+	logger.Success(ctx, oplog.LogData{
+		ObjectId:  userId,
+		Operation: "update_profile",
+		OldData:   &oldProfile,
+		NewData:   profile,
+	})
+	return toProfile(ctx, user, profile, types.VisibilityPrivate), nil
+}
+
+func validateProfile(data *types.ProfileUpdate) error {
+	if data.DisplayName != nil && utf8.RuneCountInString(*data.DisplayName) > 64 {
+		return fmt.Errorf("%w: the display name is longer than 64 characters", domain.ErrInvalidProfile)
+	}
+	if data.Bio != nil && utf8.RuneCountInString(*data.Bio) > 1000 {
+		return fmt.Errorf("%w: the bio is longer than 1000 characters", domain.ErrInvalidProfile)
+	}
+
+	if data.Links != nil {
+		if len(*data.Links) > maxProfileLinks {
+			return fmt.Errorf("%w: more than %d links", domain.ErrInvalidProfile, maxProfileLinks)
+		}
+		for _, link := range *data.Links {
+			u, err := url.Parse(link.Url)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("%w: invalid link %q", domain.ErrInvalidProfile, link.Url)
+			}
+			if utf8.RuneCountInString(link.Label) > 32 {
+				return fmt.Errorf("%w: the link label is longer than 32 characters", domain.ErrInvalidProfile)
+			}
+		}
+	}
+
+	if data.Locale != nil && *data.Locale != "" {
+		if _, err := language.Parse(*data.Locale); err != nil {
+			return fmt.Errorf("%w: invalid locale %q", domain.ErrInvalidProfile, *data.Locale)
+		}
+	}
+	// Names like `Local` are accepted by LoadLocation but meaningless to others
+	if data.Timezone != nil && *data.Timezone != "" {
+		if loc, err := time.LoadLocation(*data.Timezone); err != nil || loc == time.Local {
+			return fmt.Errorf("%w: invalid time zone %q", domain.ErrInvalidProfile, *data.Timezone)
+		}
+	}
+
+	for field, value := range data.Visibility {
+		if _, ok := profileVisibility[field]; !ok {
+			return fmt.Errorf("%w: unknown field %q", domain.ErrInvalidProfile, field)
+		}
+		if _, ok := visibilityRanks[value]; !ok {
+			return fmt.Errorf("%w: invalid visibility %q", domain.ErrInvalidProfile, value)
+		}
+	}
+
+	return nil
+}
+
+// toProfile returns the profile as seen by the audience.
+func toProfile(ctx context.Context, user *models.User, profile *models.UserProfile, audience string) *types.Profile {
+	visibility := make(map[string]string, len(profileVisibility))
+	for field, value := range profileVisibility {
+		visibility[field] = value
+	}
+	for field, value := range profile.Visibility {
+		if _, ok := visibility[field]; ok {
+			visibility[field] = value
+		}
+	}
+	visible := func(field string) bool {
+		return visibilityRanks[visibility[field]] <= visibilityRanks[audience]
+	}
+
+	result := &types.Profile{ID: user.ID, Name: user.Name}
+	if visible("display_name") {
+		result.DisplayName = profile.DisplayName
+	}
+	if visible("bio") {
+		result.Bio = profile.Bio
+	}
+	if visible("avatar") && profile.Avatar != nil {
+		result.Avatar = avatarUrls(ctx, *profile.Avatar)
+	}
+	if visible("links") {
+		result.Links = profile.Links
+	}
+	if visible("locale") {
+		result.Locale = profile.Locale
+	}
+	if visible("timezone") {
+		result.Timezone = profile.Timezone
+	}
+	if visible("email") {
+		result.Email = &user.Email
+	}
+	if visible("birthdate") {
+		result.Birthdate = user.Birthdate
+	}
+	if audience == types.VisibilityPrivate {
+		result.Visibility = visibility
+	}
+
+	return result
+}
+
+func emptyToNil(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"bilingo/config"
+	domain "bilingo/domains/user"
+	"bilingo/domains/user/models"
+	repo "bilingo/domains/user/repo"
+	"bilingo/domains/user/types"
+	"bilingo/server/app"
+	"bilingo/server/auth"
+	"bilingo/server/mailer"
+)
+
+//go:embed mails/*.tmpl
+var mailFiles embed.FS
+
+var mails = mailer.MustParseTemplates(mailFiles, "mails/*.tmpl")
+
+type mailData struct {
+	AppName string
+	Name    string
+	Link    string
+	Expires string
+}
+
+// Register signs up a new user according to the registration mode. The user
+// needs to verify the email before logging in, unless they were invited, since
+// the invitation was sent to the email already.
+func Register(ctx context.Context, data *types.UserRegister) (*models.User, error) {
+	cfg := app.Config(ctx)
+
+	verified := false
+	switch cfg.Auth.Registration {
+	case config.RegistrationOpen:
+	case config.RegistrationInvite:
+		if data.InviteToken == nil {
+			return nil, domain.ErrInvalidInvitation
+		}
+		email, err := auth.ParsePurposeToken(ctx, auth.PurposeInvite, *data.InviteToken)
+		if err != nil || email != data.Email {
+			return nil, domain.ErrInvalidInvitation
+		}
+		verified = true
+	default:
+		return nil, domain.ErrRegistrationClosed
+	}
+
+	create := &types.UserCreate{
+		Email:     data.Email,
+		Name:      data.Name,
+		Password:  data.Password,
+		Birthdate: data.Birthdate,
+	}
+	if verified {
+		now := time.Now()
+		create.EmailVerifiedAt = &now
+	}
+
+	return CreateUser(ctx, create)
+}
+
+// VerifyEmail marks the email of the user as verified with the token sent in
+// the verification mail.
+func VerifyEmail(ctx context.Context, token string) (*models.User, error) {
+	email, err := auth.ParsePurposeToken(ctx, auth.PurposeVerifyEmail, token)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	} else if user.EmailVerifiedAt != nil {
+		return user, nil // Verified already
+	}
+
+	now := time.Now()
+	if err := repo.Users(ctx).SetEmailVerified(ctx, user.ID, now); err != nil {
+		return nil, err
+	}
+	user.EmailVerifiedAt = &now
+
+	return user, nil
+}
+
+// ResendVerification sends the verification mail to the user again, it does
+// nothing if the user doesn't exist or is verified already, so that it can't
+// be used to find out registered emails.
+func ResendVerification(ctx context.Context, email string) error {
+	user, err := repo.Users(ctx).GetByEmail(ctx, email)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	} else if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	return sendVerification(ctx, user)
+}
+
+// InviteUser sends an invitation to sign up to the email.
+func InviteUser(ctx context.Context, email string) error {
+	if err := validateEmail(email); err != nil {
+		return err
+	}
+
+	if _, err := repo.Users(ctx).GetByEmail(ctx, email); err == nil {
+		return domain.ErrUserExists
+	} else if !errors.Is(err, domain.ErrUserNotFound) {
+		return err
+	}
+
+	cfg := app.Config(ctx)
+	token, err := auth.GeneratePurposeToken(ctx, auth.PurposeInvite, email, cfg.Auth.InviteDuration)
+	if err != nil {
+		return fmt.Errorf("failed to generate invitation: %w", err)
+	}
+
+	msg, err := mails.Render("invite", email, mailData{
+		AppName: cfg.AppName,
+		Link:    appLink(ctx, "/users/register", "invite", token),
+		Expires: formatDuration(cfg.Auth.InviteDuration),
+	})
+	if err != nil {
+		return err
+	}
+
+	return mailer.SendLater(ctx, msg)
+}
+
+func sendVerification(ctx context.Context, user *models.User) error {
+	cfg := app.Config(ctx)
+	token, err := auth.GeneratePurposeToken(ctx, auth.PurposeVerifyEmail, user.Email, cfg.Auth.VerificationDuration)
+	if err != nil {
+		return fmt.Errorf("failed to generate verification: %w", err)
+	}
+
+	msg, err := mails.Render("verify_email", user.Email, mailData{
+		AppName: cfg.AppName,
+		Name:    user.Name,
+		Link:    appLink(ctx, "/users/verify", "token", token),
+		Expires: formatDuration(cfg.Auth.VerificationDuration),
+	})
+	if err != nil {
+		return err
+	}
+
+	return mailer.SendLater(ctx, msg)
+}
+
+// trySendVerification sends the verification mail without failing the
+// operation, the user can ask for it again if it's lost.
+func trySendVerification(ctx context.Context, user *models.User) {
+	if err := sendVerification(ctx, user); err != nil {
+		log.Printf("failed to send verification to %s: %v", user.Email, err)
+	}
+}
+
+func validateEmail(email string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return fmt.Errorf("%w: %s", domain.ErrNotAnEmail, email)
+	}
+	return nil
+}
+
+func appLink(ctx context.Context, path string, key string, value string) string {
+	return strings.TrimSuffix(app.Config(ctx).AppUrl, "/") + path + "?" + url.Values{key: {value}}.Encode()
+}
+
+func formatDuration(d time.Duration) string {
+	if d >= 24*time.Hour && d%(24*time.Hour) == 0 {
+		if days := int(d / (24 * time.Hour)); days > 1 {
+			return fmt.Sprintf("%d days", days)
+		}
+		return "1 day"
+	} else if d >= time.Hour && d%time.Hour == 0 {
+		if hours := int(d / time.Hour); hours > 1 {
+			return fmt.Sprintf("%d hours", hours)
+		}
+		return "1 hour"
+	}
+	return d.String()
+}
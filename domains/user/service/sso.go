@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"bilingo/config"
+	domain "bilingo/domains/user"
+	"bilingo/domains/user/models"
+	repo "bilingo/domains/user/repo"
+	"bilingo/domains/user/types"
+	"bilingo/server/app"
+	"bilingo/server/auth"
+	"bilingo/server/oplog"
+	"bilingo/server/sso"
+)
+
+// SsoStateDuration is how long users have to log in at the identity provider.
+const SsoStateDuration = 10 * time.Minute
+
+// ListSsoProviders returns the identity providers users can log in with.
+func ListSsoProviders(ctx context.Context) []types.SsoProvider {
+	providers := sso.FromContext(ctx).List()
+	list := make([]types.SsoProvider, 0, len(providers))
+	for _, p := range providers {
+		list = append(list, types.SsoProvider{Name: p.Name(), DisplayName: p.DisplayName()})
+	}
+	return list
+}
+
+// BeginSsoLogin starts the login with the identity provider, it returns the URL
+// to redirect the user to, and the state token to keep in the browser until
+// the user comes back to the callback.
+func BeginSsoLogin(ctx context.Context, providerName string, redirect string) (string, string, error) {
+	provider, err := sso.FromContext(ctx).Get(providerName)
+	if err != nil {
+		return "", "", err
+	}
+
+	var secrets [3]string // state, nonce and PKCE verifier
+	for i := range secrets {
+		if secrets[i], err = newRandomToken(); err != nil {
+			return "", "", err
+		}
+	}
+	state, nonce, verifier := secrets[0], secrets[1], secrets[2]
+
+	stateToken, err := auth.GeneratePurposeClaims(ctx, auth.PurposeSso, map[string]string{
+		"provider": provider.Name(),
+		"state":    state,
+		"nonce":    nonce,
+		"verifier": verifier,
+		"redirect": redirect,
+	}, SsoStateDuration)
+	if err != nil {
+		return "", "", err
+	}
+
+	authUrl, err := provider.AuthCodeURL(ctx, ssoCallbackUri(ctx, provider.Name()), state, nonce, verifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	return authUrl, stateToken, nil
+}
+
+// CompleteSsoLogin completes the login when the user comes back from the
+// identity provider with the authorization code, and returns the user logged
+// in, or a login challenge if the user has enabled two-factor authentication,
+// along with the path to redirect to.
+func CompleteSsoLogin(ctx context.Context, providerName string, stateToken string, state string, code string) (*models.User, *types.LoginChallenge, string, error) {
+	claims, err := auth.ParsePurposeClaims(ctx, auth.PurposeSso, stateToken)
+	if err != nil {
+		return nil, nil, "", err
+	} else if claims["provider"] != providerName || state == "" ||
+		subtle.ConstantTimeCompare([]byte(claims["state"]), []byte(state)) != 1 {
+		return nil, nil, "", auth.ErrInvalidToken
+	}
+
+	provider, err := sso.FromContext(ctx).Get(providerName)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	identity, err := provider.Exchange(ctx, ssoCallbackUri(ctx, providerName), code, claims["nonce"], claims["verifier"])
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	user, challenge, err := LoginWithIdentity(ctx, provider.Settings(), identity)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	return user, challenge, claims["redirect"], nil
+}
+
+// LoginWithIdentity finds the user of the identity asserted by the provider.
+// An identity not linked yet is linked to the user of the same email if both
+// the provider and the user have verified it, or to a new user if the provider
+// auto-provisions. Users who haven't verified their email have to log in with
+// the password first, since anyone could have signed up with the email to get
+// the account linked once its owner logs in with the provider.
+//
+// Users who have enabled two-factor authentication get a login challenge
+// instead, like the login with a password. An identity of the email of such a
+// user is only linked once the challenge is completed, so that the provider
+// alone can't take over the account.
+func LoginWithIdentity(ctx context.Context, settings config.SsoProviderConfig, identity *sso.Identity) (*models.User, *types.LoginChallenge, error) {
+	now := time.Now()
+
+	linked, err := repo.Identities(ctx).Get(ctx, identity.Provider, identity.Subject)
+	if err == nil {
+		if err := repo.Identities(ctx).TouchLogin(ctx, linked.Provider, linked.Subject, now); err != nil {
+			return nil, nil, err
+		}
+		user, err := GetUser(ctx, linked.UserID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if challenge, err := ChallengeTwoFactor(ctx, user); err != nil || challenge != nil {
+			return nil, challenge, err
+		}
+		logger.Success(ctx, oplog.LogData{ObjectId: linked.UserID, Operation: "sso_login"})
+		return user, nil, nil
+	} else if !errors.Is(err, domain.ErrAccountNotLinked) {
+		return nil, nil, err
+	}
+
+	// Only verified emails prove the ownership of the accounts
+	email := strings.ToLower(strings.TrimSpace(identity.Email))
+	if email == "" || validateEmail(email) != nil {
+		return nil, nil, fmt.Errorf("%w: the identity has no valid email", domain.ErrAccountNotLinked)
+	} else if !identity.EmailVerified {
+		return nil, nil, domain.ErrEmailNotVerified
+	} else if !sso.IsEmailAllowed(settings, email) {
+		return nil, nil, fmt.Errorf("%w: the email domain is not allowed", domain.ErrAccountNotLinked)
+	}
+
+	user, err := repo.Users(ctx).GetByEmail(ctx, email)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		if !settings.AutoProvision {
+			return nil, nil, domain.ErrAccountNotLinked
+		}
+
+		name := identity.Name
+		if name == "" {
+			name, _, _ = strings.Cut(email, "@")
+		}
+		user, err = repo.Users(ctx).Create(ctx, &types.UserCreate{
+			Email:           email,
+			Name:            name,
+			EmailVerifiedAt: &now,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	} else if err != nil {
+		return nil, nil, err
+	} else if user.EmailVerifiedAt == nil {
+		return nil, nil, fmt.Errorf("%w: verify the email of the account before logging in with the provider", domain.ErrAccountNotLinked)
+	} else {
+		challenge, err := challengeTwoFactor(ctx, user, map[string]string{
+			"link_provider": identity.Provider,
+			"link_subject":  identity.Subject,
+		})
+		if err != nil || challenge != nil {
+			return nil, challenge, err
+		}
+	}
+
+	if err := linkIdentity(ctx, user, identity.Provider, identity.Subject, now); err != nil {
+		return nil, nil, err
+	}
+	logger.Success(ctx, oplog.LogData{ObjectId: user.ID, Operation: "sso_login"})
+	user, err = GetUser(ctx, user.ID)
+	return user, nil, err
+}
+
+// linkIdentity links the identity to the user, whose email is verified.
+func linkIdentity(ctx context.Context, user *models.User, provider string, subject string, now time.Time) error {
+	err := repo.Identities(ctx).Create(ctx, &models.UserIdentity{
+		Provider:    provider,
+		Subject:     subject,
+		UserID:      user.ID,
+		CreatedAt:   now,
+		LastLoginAt: &now,
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.Success(ctx, oplog.LogData{ObjectId: user.ID, Operation: "link_identity"})
+	return nil
+}
+
+// ListIdentities returns the identities linked to the user.
+func ListIdentities(ctx context.Context, userId string) ([]models.UserIdentity, error) {
+	return repo.Identities(ctx).List(ctx, userId)
+}
+
+func ssoCallbackUri(ctx context.Context, providerName string) string {
+	return strings.TrimSuffix(app.Config(ctx).AppUrl, "/") + "/api/users/sso/" + providerName + "/callback"
+}
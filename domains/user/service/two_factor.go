@@ -0,0 +1,395 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	domain "bilingo/domains/user"
+	"bilingo/domains/user/models"
+	repo "bilingo/domains/user/repo"
+	"bilingo/domains/user/types"
+	"bilingo/server/app"
+	"bilingo/server/auth"
+	"bilingo/server/oplog"
+)
+
+const (
+	totpPeriod  = 30 // seconds
+	totpDigits  = 6
+	totpSkew    = 1 // The number of steps before and after the current one accepted
+	recoveryLen = 10
+
+	maxFailedAttempts = 5
+	lockoutDuration   = 15 * time.Minute
+)
+
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// EnrollTwoFactor generates a new TOTP secret for the user after checking the
+// password, the TOTP stays pending until it's enabled with a valid code.
+func EnrollTwoFactor(ctx context.Context, userId string, data *types.TwoFactorEnroll) (*types.TwoFactorEnrollment, error) {
+	user, err := checkPassword(ctx, userId, data.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	if totp, err := repo.TwoFactors(ctx).GetTotp(ctx, userId); err == nil && totp.EnabledAt != nil {
+		return nil, domain.ErrTwoFactorEnabled
+	} else if err != nil && !errors.Is(err, domain.ErrTwoFactorNotEnabled) {
+		return nil, err
+	}
+
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate totp secret: %w", err)
+	}
+	encoded := base32NoPadding.EncodeToString(secret)
+
+	err = repo.TwoFactors(ctx).SaveTotp(ctx, &models.UserTotp{
+		UserID:    userId,
+		Secret:    encoded,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.TwoFactorEnrollment{
+		Secret: encoded,
+		Uri:    totpUri(app.Config(ctx).AppName, user.Email, encoded),
+	}, nil
+}
+
+// EnableTwoFactor enables the pending TOTP of the user with a valid code, and
+// returns the recovery codes.
+func EnableTwoFactor(ctx context.Context, userId string, data *types.TwoFactorEnable) (*types.RecoveryCodes, error) {
+	totp, err := repo.TwoFactors(ctx).GetTotp(ctx, userId)
+	if err != nil {
+		return nil, err
+	} else if totp.EnabledAt != nil {
+		return nil, domain.ErrTwoFactorEnabled
+	}
+
+	now := time.Now()
+	step, ok := matchTotp(totp.Secret, data.Code, now)
+	if !ok {
+		return nil, domain.ErrInvalidTwoFactorCode
+	}
+
+	if err := repo.TwoFactors(ctx).EnableTotp(ctx, userId, step, now); err != nil {
+		return nil, err
+	}
+
+	codes, err := generateRecoveryCodes(ctx, userId, now)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Success(ctx, oplog.LogData{ObjectId: userId, Operation: "enable_2fa"})
+	return codes, nil
+}
+
+// DisableTwoFactor disables the TOTP of the user and discards the recovery
+// codes, after re-authenticating the user with both factors.
+func DisableTwoFactor(ctx context.Context, userId string, data *types.TwoFactorReauth) error {
+	if err := reauthenticate(ctx, userId, data); err != nil {
+		return err
+	}
+
+	if err := repo.TwoFactors(ctx).Delete(ctx, userId); err != nil {
+		return err
+	}
+
+	logger.Success(ctx, oplog.LogData{ObjectId: userId, Operation: "disable_2fa"})
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user, after
+// re-authenticating the user with both factors.
+func RegenerateRecoveryCodes(ctx context.Context, userId string, data *types.TwoFactorReauth) (*types.RecoveryCodes, error) {
+	if err := reauthenticate(ctx, userId, data); err != nil {
+		return nil, err
+	}
+
+	codes, err := generateRecoveryCodes(ctx, userId, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Success(ctx, oplog.LogData{ObjectId: userId, Operation: "regenerate_recovery_codes"})
+	return codes, nil
+}
+
+func GetTwoFactorStatus(ctx context.Context, userId string) (*types.TwoFactorStatus, error) {
+	user, err := repo.Users(ctx).Get(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	totp, err := repo.TwoFactors(ctx).GetTotp(ctx, userId)
+	if errors.Is(err, domain.ErrTwoFactorNotEnabled) || (err == nil && totp.EnabledAt == nil) {
+		return &types.TwoFactorStatus{UserID: userId, Email: user.Email}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	count, err := repo.TwoFactors(ctx).CountRecoveryCodes(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.TwoFactorStatus{
+		UserID:            userId,
+		Email:             user.Email,
+		Enabled:           true,
+		EnabledAt:         totp.EnabledAt,
+		RecoveryCodesLeft: count,
+	}, nil
+}
+
+// ListTwoFactorStatuses returns the statuses of the users who have enabled
+// two-factor authentication.
+func ListTwoFactorStatuses(ctx context.Context) ([]types.TwoFactorStatus, error) {
+	totps, err := repo.TwoFactors(ctx).ListEnabled(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]types.TwoFactorStatus, 0, len(totps))
+	for _, totp := range totps {
+		count, err := repo.TwoFactors(ctx).CountRecoveryCodes(ctx, totp.UserID)
+		if err != nil {
+			return nil, err
+		}
+		user, err := repo.Users(ctx).Get(ctx, totp.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		statuses = append(statuses, types.TwoFactorStatus{
+			UserID:            totp.UserID,
+			Email:             user.Email,
+			Enabled:           true,
+			EnabledAt:         totp.EnabledAt,
+			RecoveryCodesLeft: count,
+		})
+	}
+
+	return statuses, nil
+}
+
+// ChallengeTwoFactor returns a login challenge if the user has enabled
+// two-factor authentication, or nil if the login is complete already.
+func ChallengeTwoFactor(ctx context.Context, user *models.User) (*types.LoginChallenge, error) {
+	return challengeTwoFactor(ctx, user, nil)
+}
+
+// challengeTwoFactor is ChallengeTwoFactor with the claims carried by the
+// challenge token, e.g. the identity to link once the login is complete.
+func challengeTwoFactor(ctx context.Context, user *models.User, claims map[string]string) (*types.LoginChallenge, error) {
+	totp, err := repo.TwoFactors(ctx).GetTotp(ctx, user.ID)
+	if errors.Is(err, domain.ErrTwoFactorNotEnabled) || (err == nil && totp.EnabledAt == nil) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	duration := app.Config(ctx).Auth.ChallengeDuration
+	tokenClaims := map[string]string{"sub": user.ID}
+	for key, value := range claims {
+		tokenClaims[key] = value
+	}
+	token, err := auth.GeneratePurposeClaims(ctx, auth.PurposeLogin, tokenClaims, duration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate login challenge: %w", err)
+	}
+
+	return &types.LoginChallenge{
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+		ExpiresAt:         time.Now().Add(duration),
+	}, nil
+}
+
+// LoginTwoFactor completes the login with the challenge token and a TOTP or
+// recovery code, and links the identity of a single sign-on waiting for it.
+func LoginTwoFactor(ctx context.Context, data *types.TwoFactorLogin) (*models.User, error) {
+	claims, err := auth.ParsePurposeClaims(ctx, auth.PurposeLogin, data.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+	userId := claims["sub"]
+	if userId == "" {
+		return nil, auth.ErrInvalidToken
+	}
+
+	totp, err := repo.TwoFactors(ctx).GetTotp(ctx, userId)
+	if err != nil {
+		return nil, err
+	} else if totp.EnabledAt == nil {
+		return nil, domain.ErrTwoFactorNotEnabled
+	}
+
+	if err := verifySecondFactor(ctx, totp, data.Code); err != nil {
+		return nil, err
+	}
+
+	user, err := GetUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if provider, subject := claims["link_provider"], claims["link_subject"]; provider != "" && subject != "" {
+		_, err := repo.Identities(ctx).Get(ctx, provider, subject)
+		if errors.Is(err, domain.ErrAccountNotLinked) {
+			err = linkIdentity(ctx, user, provider, subject, time.Now())
+		}
+		if err != nil {
+			return nil, err
+		}
+		logger.Success(ctx, oplog.LogData{ObjectId: user.ID, Operation: "sso_login"})
+	}
+	return user, nil
+}
+
+// checkPassword verifies the password of the user.
+func checkPassword(ctx context.Context, userId string, password string) (*models.User, error) {
+	user, err := repo.Users(ctx).Get(ctx, userId)
+	if err != nil {
+		return nil, err
+	} else if user.Password == nil || verifyPassword(*user.Password, password) != nil {
+		return nil, domain.ErrInvalidPassword
+	}
+
+	user.Password = nil
+	return user, nil
+}
+
+func reauthenticate(ctx context.Context, userId string, data *types.TwoFactorReauth) error {
+	if _, err := checkPassword(ctx, userId, data.Password); err != nil {
+		return err
+	}
+
+	totp, err := repo.TwoFactors(ctx).GetTotp(ctx, userId)
+	if err != nil {
+		return err
+	} else if totp.EnabledAt == nil {
+		return domain.ErrTwoFactorNotEnabled
+	}
+
+	return verifySecondFactor(ctx, totp, data.Code)
+}
+
+// verifySecondFactor accepts a TOTP code or an unused recovery code, the user
+// is locked out for a while after too many consecutive failures.
+func verifySecondFactor(ctx context.Context, totp *models.UserTotp, code string) error {
+	now := time.Now()
+	if totp.FailedAttempts >= maxFailedAttempts && totp.LastFailedAt != nil && now.Sub(*totp.LastFailedAt) < lockoutDuration {
+		return domain.ErrTooManyAttempts
+	}
+
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), " ", ""))
+
+	var err error
+	if step, ok := matchTotp(totp.Secret, code, now); ok {
+		err = repo.TwoFactors(ctx).UseTotpStep(ctx, totp.UserID, step)
+	} else if len(code) == totpDigits {
+		err = domain.ErrInvalidTwoFactorCode
+	} else {
+		err = repo.TwoFactors(ctx).UseRecoveryCode(ctx, totp.UserID, hashRecoveryCode(code), now)
+	}
+
+	if errors.Is(err, domain.ErrInvalidTwoFactorCode) {
+		if err := repo.TwoFactors(ctx).RecordFailure(ctx, totp.UserID, now); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+func generateRecoveryCodes(ctx context.Context, userId string, now time.Time) (*types.RecoveryCodes, error) {
+	codes := make([]string, 0, recoveryLen)
+	hashes := make([]string, 0, recoveryLen)
+	for range recoveryLen {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+
+		// e.g. abcd-efgh
+		encoded := strings.ToLower(base32NoPadding.EncodeToString(buf))
+		code := encoded[:4] + "-" + encoded[4:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	if err := repo.TwoFactors(ctx).ReplaceRecoveryCodes(ctx, userId, hashes, now); err != nil {
+		return nil, err
+	}
+
+	return &types.RecoveryCodes{Codes: codes}, nil
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(strings.ReplaceAll(code, "-", "")))
+	return hex.EncodeToString(sum[:])
+}
+
+// totpCode computes the TOTP code of the time step as specified by RFC 6238,
+// with HMAC-SHA1 and 6 digits.
+func totpCode(secret []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1_000_000)
+}
+
+// matchTotp returns the time step of the code if it's valid around the time.
+func matchTotp(encodedSecret string, code string, now time.Time) (int64, bool) {
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	secret, err := base32NoPadding.DecodeString(encodedSecret)
+	if err != nil {
+		return 0, false
+	}
+
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if hmac.Equal([]byte(totpCode(secret, step)), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpUri returns the provisioning URI of the secret understood by
+// authenticator apps.
+func totpUri(issuer string, email string, secret string) string {
+	query := url.Values{
+		"secret":    {secret},
+		"issuer":    {issuer},
+		"algorithm": {"SHA1"},
+		"digits":    {fmt.Sprint(totpDigits)},
+		"period":    {fmt.Sprint(totpPeriod)},
+	}
+	label := url.PathEscape(issuer + ":" + email)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+// The test vectors of RFC 6238 for HMAC-SHA1, truncated to the 6 digits of the
+// codes, which are the last 6 of the 8 digits of the RFC.
+func TestTotpCode(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	tests := []struct {
+		time int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, test := range tests {
+		if code := totpCode(secret, test.time/totpPeriod); code != test.code {
+			t.Errorf("expected %s at %d, got %s", test.code, test.time, code)
+		}
+	}
+}
+
+func TestMatchTotp(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	encoded := base32NoPadding.EncodeToString(secret)
+	now := time.Unix(1234567890, 0)
+	current := now.Unix() / totpPeriod
+
+	for offset := int64(-2); offset <= 2; offset++ {
+		step, ok := matchTotp(encoded, totpCode(secret, current+offset), now)
+		if inWindow := offset >= -totpSkew && offset <= totpSkew; ok != inWindow {
+			t.Errorf("code of the step %+d matched: %v", offset, ok)
+		} else if ok && step != current+offset {
+			t.Errorf("code of the step %+d matched the step %d", offset, step-current)
+		}
+	}
+
+	if _, ok := matchTotp(encoded, "12345", now); ok {
+		t.Error("matched a code of 5 digits")
+	}
+}
@@ -2,23 +2,48 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 
 	"bilingo/common"
+	systemService "bilingo/domains/system/service"
 	domain "bilingo/domains/user"
 	"bilingo/domains/user/models"
 	repo "bilingo/domains/user/repo"
 	"bilingo/domains/user/types"
+	"bilingo/server/oplog"
 	"bilingo/server/timing"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
-func GetUser(ctx context.Context, email string) (*models.User, error) {
+func init() {
+	// A user account is owned by the user itself
+	systemService.RegisterObjectOwner("user", func(ctx context.Context, objectId string) (string, error) {
+		user, err := repo.Users(ctx).Get(ctx, objectId)
+		if err != nil {
+			return "", err
+		}
+		return user.ID, nil
+	})
+}
+
+func GetUser(ctx context.Context, id string) (*models.User, error) {
 	timing.Start(ctx, "user.service.GetUser")
 	defer timing.End(ctx, "user.service.GetUser")
 
-	user, err := repo.UserRepo.Get(ctx, email)
+	user, err := repo.Users(ctx).Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Clear password before returning
+	user.Password = nil
+
+	return user, nil
+}
+
+func GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	user, err := repo.Users(ctx).GetByEmail(ctx, email)
 	if err != nil {
 		return nil, err
 	}
@@ -33,7 +58,7 @@ func ListUsers(ctx context.Context, query types.UserListQuery) (*common.Paginate
 	timing.Start(ctx, "user.service.ListUser")
 	defer timing.End(ctx, "user.service.ListUser")
 
-	result, err := repo.UserRepo.List(ctx, query)
+	result, err := repo.Users(ctx).List(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -46,9 +71,24 @@ func ListUsers(ctx context.Context, query types.UserListQuery) (*common.Paginate
 	return result, nil
 }
 
+// CreateUser creates a user, and sends the verification mail unless the email
+// is verified already.
 func CreateUser(ctx context.Context, user *types.UserCreate) (*models.User, error) {
+	if err := validateEmail(user.Email); err != nil {
+		return nil, err
+	}
+	if err := validatePassword(ctx, user.Password, user.Email, user.Name); err != nil {
+		return nil, err
+	}
+
+	if _, err := repo.Users(ctx).GetByEmail(ctx, user.Email); err == nil {
+		return nil, domain.ErrUserExists
+	} else if !errors.Is(err, domain.ErrUserNotFound) {
+		return nil, err
+	}
+
 	// Hash password before passing to repo
-	hashedPassword, err := hashPassword(user.Password)
+	hashedPassword, err := hashPassword(ctx, user.Password)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
@@ -57,7 +97,7 @@ func CreateUser(ctx context.Context, user *types.UserCreate) (*models.User, erro
 	userWithHashedPassword := *user
 	userWithHashedPassword.Password = hashedPassword
 
-	createdUser, err := repo.UserRepo.Create(ctx, &userWithHashedPassword)
+	createdUser, err := repo.Users(ctx).Create(ctx, &userWithHashedPassword)
 	if err != nil {
 		return nil, err
 	}
@@ -65,20 +105,32 @@ func CreateUser(ctx context.Context, user *types.UserCreate) (*models.User, erro
 	// Clear password before returning
 	createdUser.Password = nil
 
+	if createdUser.EmailVerifiedAt == nil {
+		trySendVerification(ctx, createdUser)
+	}
+
 	return createdUser, nil
 }
 
-func UpdateUser(ctx context.Context, email string, user *types.UserUpdate) (*models.User, error) {
+func UpdateUser(ctx context.Context, id string, user *types.UserUpdate) (*models.User, error) {
 	// Hash password if provided
 	if user.Password != nil {
-		hashedPassword, err := hashPassword(*user.Password)
+		existing, err := repo.Users(ctx).Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := validatePassword(ctx, *user.Password, existing.Email, existing.Name); err != nil {
+			return nil, err
+		}
+
+		hashedPassword, err := hashPassword(ctx, *user.Password)
 		if err != nil {
 			return nil, fmt.Errorf("failed to hash password: %w", err)
 		}
 		user.Password = &hashedPassword
 	}
 
-	updatedUser, err := repo.UserRepo.Update(ctx, email, user)
+	updatedUser, err := repo.Users(ctx).Update(ctx, id, user)
 	if err != nil {
 		return nil, err
 	}
@@ -89,13 +141,34 @@ func UpdateUser(ctx context.Context, email string, user *types.UserUpdate) (*mod
 	return updatedUser, nil
 }
 
-func DeleteUser(ctx context.Context, email string) error {
-	return repo.UserRepo.Delete(ctx, email)
+// DeleteUser deletes the user along with the credentials and profile, it fails
+// with ErrUserHasContent as long as the user is still the author of articles or
+// comments, RequestDeletion takes care of the content first.
+func DeleteUser(ctx context.Context, id string) error {
+	if id == models.DeletedUserID {
+		return domain.ErrDeletedUser
+	}
+	if err := repo.TwoFactors(ctx).Delete(ctx, id); err != nil {
+		return err
+	}
+	if err := repo.Identities(ctx).DeleteAll(ctx, id); err != nil {
+		return err
+	}
+	if err := repo.PasswordResets(ctx).DeleteAll(ctx, id); err != nil {
+		return err
+	}
+	if err := deleteProfile(ctx, id); err != nil {
+		return err
+	}
+	if err := systemService.DeleteAttachmentsOf(ctx, "user", []string{id}); err != nil {
+		return err
+	}
+	return repo.Users(ctx).Delete(ctx, id)
 }
 
-func ChangePassword(ctx context.Context, email string, data *types.PasswordChange) error {
+func ChangePassword(ctx context.Context, id string, data *types.PasswordChange) error {
 	// Find the user
-	user, err := repo.UserRepo.Get(ctx, email)
+	user, err := repo.Users(ctx).Get(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -108,8 +181,12 @@ func ChangePassword(ctx context.Context, email string, data *types.PasswordChang
 		return domain.ErrInvalidPassword
 	}
 
+	if err := validatePassword(ctx, data.NewPassword, user.Email, user.Name); err != nil {
+		return err
+	}
+
 	// Hash new password
-	hashedPassword, err := hashPassword(data.NewPassword)
+	hashedPassword, err := hashPassword(ctx, data.NewPassword)
 	if err != nil {
 		return fmt.Errorf("failed to hash new password: %w", err)
 	}
@@ -118,12 +195,12 @@ func ChangePassword(ctx context.Context, email string, data *types.PasswordChang
 	updateData := &types.UserUpdate{
 		Password: &hashedPassword,
 	}
-	_, err = repo.UserRepo.Update(ctx, email, updateData)
+	_, err = repo.Users(ctx).Update(ctx, id, updateData)
 	return err
 }
 
 func Login(ctx context.Context, credentials *types.LoginCredentials) (*models.User, error) {
-	user, err := repo.UserRepo.Get(ctx, credentials.Email)
+	user, err := repo.Users(ctx).GetByEmail(ctx, credentials.Email)
 	if err != nil {
 		return nil, err
 	}
@@ -136,22 +213,32 @@ func Login(ctx context.Context, credentials *types.LoginCredentials) (*models.Us
 		return nil, domain.ErrInvalidPassword
 	}
 
+	// Only tell about the verification after the password is checked, so it
+	// doesn't reveal whether the email is registered
+	if user.EmailVerifiedAt == nil {
+		return nil, domain.ErrEmailNotVerified
+	}
+
+	if needsRehash(ctx, *user.Password) {
+		upgradePasswordHash(ctx, user.ID, credentials.Password)
+	}
+
 	// Clear password before returning
 	user.Password = nil
 
 	return user, nil
 }
 
-// hashPassword hashes a plain text password using bcrypt
-func hashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// upgradePasswordHash rehashes the password with the algorithm and parameters
+// in the configuration, failures are only logged since the login succeeded
+// anyway and the upgrade is tried again next time.
+func upgradePasswordHash(ctx context.Context, userId string, password string) {
+	hashedPassword, err := hashPassword(ctx, password)
+	if err == nil {
+		// The hash is an implementation detail, not a change worth recording
+		_, err = repo.Users(ctx).Update(oplog.SkipAudit(ctx), userId, &types.UserUpdate{Password: &hashedPassword})
+	}
 	if err != nil {
-		return "", err
+		log.Printf("failed to upgrade password hash of %s: %v", userId, err)
 	}
-	return string(hashedPassword), nil
-}
-
-// verifyPassword verifies if the provided password matches the stored hash
-func verifyPassword(hashedPassword string, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
@@ -7,17 +7,23 @@ import (
 )
 
 var User = struct {
-	Email     field.String
-	Name      field.String
-	Password  field.String
-	Birthdate field.String
-	CreatedAt field.Time
-	UpdatedAt field.Time
+	ID              field.String
+	Email           field.String
+	Name            field.String
+	Password        field.String
+	Birthdate       field.String
+	CreatedAt       field.Time
+	UpdatedAt       field.Time
+	EmailVerifiedAt field.Time
+	PendingEmail    field.String
 }{
-	Email:     field.String{}.WithColumn("email"),
-	Name:      field.String{}.WithColumn("name"),
-	Password:  field.String{}.WithColumn("password"),
-	Birthdate: field.String{}.WithColumn("birthdate"),
-	CreatedAt: field.Time{}.WithColumn("created_at"),
-	UpdatedAt: field.Time{}.WithColumn("updated_at"),
+	ID:              field.String{}.WithColumn("id"),
+	Email:           field.String{}.WithColumn("email"),
+	Name:            field.String{}.WithColumn("name"),
+	Password:        field.String{}.WithColumn("password"),
+	Birthdate:       field.String{}.WithColumn("birthdate"),
+	CreatedAt:       field.Time{}.WithColumn("created_at"),
+	UpdatedAt:       field.Time{}.WithColumn("updated_at"),
+	EmailVerifiedAt: field.Time{}.WithColumn("email_verified_at"),
+	PendingEmail:    field.String{}.WithColumn("pending_email"),
 }
@@ -7,6 +7,7 @@ import type * as common from "@/common"
 
 export interface UserListQuery extends common.PaginatedQuery {
     search?: string
+    ids?: string[]
     emails?: string[]
     birthdate?: common.Range<string>
 }
@@ -25,7 +26,174 @@ export interface PasswordChange {
     old_password: string
     new_password: string
 }
+export interface UserRegister {
+    email: string
+    name: string
+    password: string
+    birthdate?: string
+    invite_token?: string // Required when registration is invite-only
+}
+export interface EmailVerification {
+    token: string
+}
+export interface VerificationResend {
+    email: string
+}
+export interface UserInvite {
+    email: string
+}
+export interface PasswordForgot {
+    email: string
+}
+export interface PasswordReset {
+    token: string
+    new_password: string
+}
+export interface EmailChange {
+    new_email: string
+    password: string
+}
+export interface EmailChangeConfirm {
+    token: string
+}
 export interface LoginCredentials {
     email: string
     password: string
 }
+
+//////////
+// source: two_factor.go
+
+/**
+ * LoginChallenge is returned by the login instead of the user if the user has
+ * enabled two-factor authentication, the challenge token is then sent along
+ * with the code to complete the login.
+ */
+export interface LoginChallenge {
+    two_factor_required: boolean
+    challenge_token: string
+    expires_at: string /* RFC3339 */
+}
+export interface TwoFactorLogin {
+    /**
+     * Defaults to the challenge of a single sign-on, kept in a cookie
+     */
+    challenge_token: string
+    code: string // A TOTP code or a recovery code
+}
+export interface TwoFactorEnroll {
+    password: string
+}
+/**
+ * TwoFactorEnrollment is the secret of a pending TOTP, to be added to an
+ * authenticator app, usually by scanning the provisioning URI as a QR code.
+ */
+export interface TwoFactorEnrollment {
+    secret: string
+    uri: string
+}
+export interface TwoFactorEnable {
+    code: string
+}
+/**
+ * TwoFactorReauth re-authenticates the user with both factors for sensitive
+ * operations.
+ */
+export interface TwoFactorReauth {
+    password: string
+    code: string // A TOTP code or a recovery code
+}
+/**
+ * RecoveryCodes are shown to the user only once, when they're generated.
+ */
+export interface RecoveryCodes {
+    codes: string[]
+}
+export interface TwoFactorStatus {
+    user_id: string
+    email: string
+    enabled: boolean
+    enabled_at?: string /* RFC3339 */
+    recovery_codes_left: number /* int */
+}
+
+//////////
+// source: sso.go
+
+/**
+ * SsoProvider is an external identity provider users can log in with.
+ */
+export interface SsoProvider {
+    name: string
+    display_name: string
+}
+
+//////////
+// source: privacy.go
+
+export const DeletionPending = "pending"
+export const DeletionRunning = "running"
+export const DeletionCompleted = "completed"
+export const DeletionFailed = "failed"
+/**
+ * DeletionRequest requests the deletion of an account, the content of the user
+ * is handled according to the policy, which defaults to the configured one.
+ */
+export interface DeletionRequest {
+    policy?: string
+    transfer_to?: string // The user the content is transferred to
+}
+/**
+ * DeletionStatus is the progress of an account deletion, it's available to the
+ * user being deleted until the account is gone, and to admins.
+ */
+export interface DeletionStatus {
+    id: string
+    policy: string
+    status: string
+    created_at: string /* RFC3339 */
+    completed_at?: string /* RFC3339 */
+}
+
+//////////
+// source: profile.go
+
+export const VisibilityPublic = "public" // Anyone can see the field
+export const VisibilityUsers = "users" // Only logged in users can see the field
+export const VisibilityPrivate = "private" // Only the user and admins can see the field
+export interface ProfileLink {
+    label: string
+    url: string
+}
+export interface ProfileUpdate {
+    display_name?: string
+    bio?: string
+    links?: ProfileLink[]
+    locale?: string // A BCP 47 language tag, e.g. `zh-CN`
+    timezone?: string // An IANA time zone, e.g. `Asia/Shanghai`
+    /**
+     * The visibility of the fields by their names, one of the Visibility*
+     * values, the fields left out keep their visibility
+     */
+    visibility?: { [key: string]: string }
+}
+/**
+ * Profile is the profile of a user as seen by the viewer, the fields hidden
+ * from the viewer are left out.
+ */
+export interface Profile {
+    id: string
+    name: string
+    display_name?: string
+    bio?: string
+    avatar?: { [key: string]: string } // The URLs of the avatar by the sizes, e.g. `64`
+    links?: ProfileLink[]
+    locale?: string
+    timezone?: string
+    email?: string
+    birthdate?: string
+    /**
+     * The visibility of all the fields, only shown to the user and admins
+     */
+    visibility?: { [key: string]: string }
+}
@@ -0,0 +1,27 @@
+package types
+
+import "time"
+
+const (
+	DeletionPending   = "pending"
+	DeletionRunning   = "running"
+	DeletionCompleted = "completed"
+	DeletionFailed    = "failed"
+)
+
+// DeletionRequest requests the deletion of an account, the content of the user
+// is handled according to the policy, which defaults to the configured one.
+type DeletionRequest struct {
+	Policy     *string `json:"policy" form:"policy" validate:"omitempty,oneof=cascade anonymize transfer"`
+	TransferTo *string `json:"transfer_to" form:"transfer_to"` // The user the content is transferred to
+}
+
+// DeletionStatus is the progress of an account deletion, it's available to the
+// user being deleted until the account is gone, and to admins.
+type DeletionStatus struct {
+	ID          string     `json:"id"`
+	Policy      string     `json:"policy"`
+	Status      string     `json:"status" validate:"oneof=pending running completed failed"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
@@ -0,0 +1,40 @@
+package types
+
+const (
+	VisibilityPublic  = "public"  // Anyone can see the field
+	VisibilityUsers   = "users"   // Only logged in users can see the field
+	VisibilityPrivate = "private" // Only the user and admins can see the field
+)
+
+type ProfileLink struct {
+	Label string `json:"label" validate:"max=32"`
+	Url   string `json:"url" validate:"url"`
+}
+
+type ProfileUpdate struct {
+	DisplayName *string        `json:"display_name" validate:"omitempty,max=64"`
+	Bio         *string        `json:"bio" validate:"omitempty,max=1000"`
+	Links       *[]ProfileLink `json:"links" validate:"omitempty,max=5"`
+	Locale      *string        `json:"locale"`   // A BCP 47 language tag, e.g. `zh-CN`
+	Timezone    *string        `json:"timezone"` // An IANA time zone, e.g. `Asia/Shanghai`
+	// The visibility of the fields by their names, one of the Visibility*
+	// values, the fields left out keep their visibility
+	Visibility map[string]string `json:"visibility"`
+}
+
+// Profile is the profile of a user as seen by the viewer, the fields hidden
+// from the viewer are left out.
+type Profile struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	DisplayName *string           `json:"display_name,omitempty"`
+	Bio         *string           `json:"bio,omitempty"`
+	Avatar      map[string]string `json:"avatar,omitempty"` // The URLs of the avatar by the sizes, e.g. `64`
+	Links       []ProfileLink     `json:"links,omitempty"`
+	Locale      *string           `json:"locale,omitempty"`
+	Timezone    *string           `json:"timezone,omitempty"`
+	Email       *string           `json:"email,omitempty"`
+	Birthdate   *string           `json:"birthdate,omitempty"`
+	// The visibility of all the fields, only shown to the user and admins
+	Visibility map[string]string `json:"visibility,omitempty"`
+}
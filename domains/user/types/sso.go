@@ -0,0 +1,7 @@
+package types
+
+// SsoProvider is an external identity provider users can log in with.
+type SsoProvider struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
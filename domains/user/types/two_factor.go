@@ -0,0 +1,53 @@
+package types
+
+import "time"
+
+// LoginChallenge is returned by the login instead of the user if the user has
+// enabled two-factor authentication, the challenge token is then sent along
+// with the code to complete the login.
+type LoginChallenge struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	ChallengeToken    string    `json:"challenge_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+type TwoFactorLogin struct {
+	// Defaults to the challenge of a single sign-on, kept in a cookie
+	ChallengeToken string `json:"challenge_token" form:"challenge_token"`
+	Code           string `json:"code" form:"code"` // A TOTP code or a recovery code
+}
+
+type TwoFactorEnroll struct {
+	Password string `json:"password" form:"password"`
+}
+
+// TwoFactorEnrollment is the secret of a pending TOTP, to be added to an
+// authenticator app, usually by scanning the provisioning URI as a QR code.
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	Uri    string `json:"uri"`
+}
+
+type TwoFactorEnable struct {
+	Code string `json:"code" form:"code"`
+}
+
+// TwoFactorReauth re-authenticates the user with both factors for sensitive
+// operations.
+type TwoFactorReauth struct {
+	Password string `json:"password" form:"password"`
+	Code     string `json:"code" form:"code"` // A TOTP code or a recovery code
+}
+
+// RecoveryCodes are shown to the user only once, when they're generated.
+type RecoveryCodes struct {
+	Codes []string `json:"codes"`
+}
+
+type TwoFactorStatus struct {
+	UserID            string     `json:"user_id"`
+	Email             string     `json:"email"`
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabled_at"`
+	RecoveryCodesLeft int        `json:"recovery_codes_left"`
+}
@@ -1,11 +1,16 @@
 package types
 
-import "bilingo/common"
+import (
+	"time"
+
+	"bilingo/common"
+)
 
 //tygo:emit import type * as common from "@/common"
 type UserListQuery struct {
 	common.PaginatedQuery `tstype:",extends"`
 	Search                *string                `json:"search" query:"search"`
+	Ids                   *[]string              `json:"ids" query:"ids"`
 	Emails                *[]string              `json:"emails" query:"emails"`
 	Birthdate             *common.Range[*string] `tstype:"common.Range<string>" json:"birthdate" query:"birthdate"`
 }
@@ -15,6 +20,8 @@ type UserCreate struct {
 	Name      string  `json:"name" form:"name"`
 	Password  string  `json:"password" form:"password"`
 	Birthdate *string `json:"birthdate" form:"birthdate"`
+	// Set when the email is known to be owned by the user, e.g. invited
+	EmailVerifiedAt *time.Time `json:"-"`
 }
 
 type UserUpdate struct {
@@ -28,6 +35,44 @@ type PasswordChange struct {
 	NewPassword string `json:"new_password" form:"new_password"`
 }
 
+type UserRegister struct {
+	Email       string  `json:"email" form:"email"`
+	Name        string  `json:"name" form:"name"`
+	Password    string  `json:"password" form:"password"`
+	Birthdate   *string `json:"birthdate" form:"birthdate"`
+	InviteToken *string `json:"invite_token" form:"invite_token"` // Required when registration is invite-only
+}
+
+type EmailVerification struct {
+	Token string `json:"token" form:"token"`
+}
+
+type VerificationResend struct {
+	Email string `json:"email" form:"email"`
+}
+
+type UserInvite struct {
+	Email string `json:"email" form:"email"`
+}
+
+type PasswordForgot struct {
+	Email string `json:"email" form:"email"`
+}
+
+type PasswordReset struct {
+	Token       string `json:"token" form:"token"`
+	NewPassword string `json:"new_password" form:"new_password"`
+}
+
+type EmailChange struct {
+	NewEmail string `json:"new_email" form:"new_email"`
+	Password string `json:"password" form:"password"`
+}
+
+type EmailChangeConfirm struct {
+	Token string `json:"token" form:"token"`
+}
+
 type LoginCredentials struct {
 	Email    string `json:"email" form:"email"`
 	Password string `json:"password" form:"password"`
@@ -1,21 +1,29 @@
 import type { JSX } from "react"
 import { useEffect, useState } from "react"
 import { useNavigate, useParams, useSearchParams } from "react-router-dom"
-import { changePassword, deleteUser, getUser, updateUser } from "../api/user"
+import {
+    changePassword,
+    deleteUser,
+    getProfile,
+    getUser,
+    getUserExportUrl,
+    updateUser,
+} from "../api/user"
 import { PasswordChangeForm, UserForm } from "../components"
 import type { User } from "../models"
-import type { PasswordChange, UserUpdate } from "../types"
+import type { PasswordChange, Profile, UserUpdate } from "../types"
 import { ProtectedRoute } from "@/client/components"
 import { useAuth } from "@/client/contexts/AuthContext.tsx"
 import { alert, confirm } from "@ayonli/jsext/dialog"
 import { CommentAndLogSection } from "@/domains/system/components"
 
 export default function UserDetailPage(): JSX.Element {
-    const { email } = useParams<{ email: string }>()
+    const { id } = useParams<{ id: string }>()
     const navigate = useNavigate()
     const [searchParams, setSearchParams] = useSearchParams()
-    const { user: currentUser } = useAuth()
+    const { user: currentUser, setUser: setCurrentUser } = useAuth()
     const [user, setUser] = useState<User | null>(null)
+    const [profile, setProfile] = useState<Profile | null>(null)
     const [loading, setLoading] = useState(true)
     const [error, setError] = useState("")
 
@@ -23,41 +31,50 @@ export default function UserDetailPage(): JSX.Element {
     const isChangePasswordMode = searchParams.get("changePassword") === "true"
 
     // Check if current user is viewing their own profile
-    const isOwnProfile = currentUser && user && currentUser.email === user.email
+    const isOwnProfile = currentUser && user && currentUser.id === user.id
 
     useEffect(() => {
-        if (!email) {
+        if (!id) {
             return
         }
 
         loadUser()
-    }, [email])
+    }, [id])
 
     async function loadUser(): Promise<void> {
-        if (!email) {
+        if (!id) {
             return
         }
 
         setLoading(true)
         setError("")
 
-        const { success, data, message } = await getUser(decodeURIComponent(email))
+        const [{ success, data, message }, profileResult] = await Promise.all([
+            getUser(id),
+            getProfile(id),
+        ])
 
+        // Accounts are only shown to the users themselves and admins, anyone
+        // else sees the profile
         if (success) {
             setUser(data)
-        } else {
+        } else if (!profileResult.success) {
             setError(message)
         }
 
+        if (profileResult.success) {
+            setProfile(profileResult.data)
+        }
+
         setLoading(false)
     }
 
     async function handleUpdate(updates: UserUpdate): Promise<void> {
-        if (!email) {
+        if (!id) {
             return
         }
 
-        const { success, data, message } = await updateUser(decodeURIComponent(email), updates)
+        const { success, data, message } = await updateUser(id, updates)
 
         if (success) {
             setUser(data)
@@ -68,11 +85,11 @@ export default function UserDetailPage(): JSX.Element {
     }
 
     async function handleChangePassword(change: PasswordChange): Promise<void> {
-        if (!email) {
+        if (!id) {
             return
         }
 
-        const { success, message } = await changePassword(decodeURIComponent(email), change)
+        const { success, message } = await changePassword(id, change)
 
         if (success) {
             setSearchParams({})
@@ -95,19 +112,22 @@ export default function UserDetailPage(): JSX.Element {
     }
 
     async function handleDelete(): Promise<void> {
-        if (!email || !user) {
+        if (!id || !user) {
             return
         }
 
-        if (!await confirm(`确定要删除用户 ${user.email} 吗？`)) {
+        if (!await confirm(`确定要删除用户 ${user.email} 吗？删除前可以先导出数据。`)) {
             return
         }
 
-        const { success, message } = await deleteUser(decodeURIComponent(email))
+        const { success, message } = await deleteUser(id)
 
         if (success) {
-            await alert("用户删除成功！")
-            navigate("/users")
+            // The account is deleted in the background, and the session ends
+            // along with it
+            await alert("账户删除已开始，完成后将无法再登录。")
+            setCurrentUser(null)
+            navigate("/")
         } else {
             await alert(`删除用户失败: ${message}`)
         }
@@ -125,6 +145,35 @@ export default function UserDetailPage(): JSX.Element {
         )
     }
 
+    if (!user && profile) {
+        return (
+            <ProtectedRoute>
+                <div className="min-h-screen bg-gray-50 py-8 px-4">
+                    <div className="max-w-4xl mx-auto">
+                        <h1 className="text-3xl font-bold text-gray-900 mb-8">用户详情</h1>
+                        <div className="bg-white shadow-md rounded-lg p-6">
+                            <div className="flex items-center gap-4">
+                                {profile.avatar && (
+                                    <img
+                                        src={profile.avatar["128"]}
+                                        alt={profile.display_name || profile.name}
+                                        className="w-16 h-16 rounded-full"
+                                    />
+                                )}
+                                <div>
+                                    <p className="text-xl font-semibold text-gray-900">
+                                        {profile.display_name || profile.name}
+                                    </p>
+                                    {profile.bio && <p className="mt-1 text-gray-600">{profile.bio}</p>}
+                                </div>
+                            </div>
+                        </div>
+                    </div>
+                </div>
+            </ProtectedRoute>
+        )
+    }
+
     if (error || !user) {
         return (
             <ProtectedRoute>
@@ -183,6 +232,12 @@ export default function UserDetailPage(): JSX.Element {
                                     >
                                         修改密码
                                     </button>
+                                    <a
+                                        href={getUserExportUrl(user.id)}
+                                        className="bg-gray-600 text-white px-6 py-2 rounded-lg hover:bg-gray-700 transition-colors"
+                                    >
+                                        导出数据
+                                    </a>
                                     <button
                                         type="button"
                                         onClick={handleDelete}
@@ -227,10 +282,32 @@ export default function UserDetailPage(): JSX.Element {
                             )
                         : (
                             <div className="bg-white shadow-md rounded-lg p-6">
+                                {profile && (profile.avatar || profile.display_name || profile.bio) && (
+                                    <div className="flex items-center gap-4 mb-6">
+                                        {profile.avatar && (
+                                            <img
+                                                src={profile.avatar["128"]}
+                                                alt={profile.display_name || user.name}
+                                                className="w-16 h-16 rounded-full"
+                                            />
+                                        )}
+                                        <div>
+                                            <p className="text-xl font-medium text-gray-900">
+                                                {profile.display_name || user.name}
+                                            </p>
+                                            {profile.bio && <p className="mt-1 text-gray-600">{profile.bio}</p>}
+                                        </div>
+                                    </div>
+                                )}
                                 <dl className="grid grid-cols-1 gap-6">
                                     <div>
                                         <dt className="text-sm font-medium text-gray-500">邮箱</dt>
                                         <dd className="mt-1 text-lg text-gray-900">{user.email}</dd>
+                                        {user.pending_email && (
+                                            <dd className="mt-1 text-sm text-gray-500">
+                                                待验证的新邮箱: {user.pending_email}
+                                            </dd>
+                                        )}
                                     </div>
                                     <div>
                                         <dt className="text-sm font-medium text-gray-500">姓名</dt>
@@ -265,7 +342,7 @@ export default function UserDetailPage(): JSX.Element {
                     {/* Comments Section */}
                     {!isEditMode && !isChangePasswordMode && (
                         <div className="bg-white shadow-md rounded-lg p-6 mt-6">
-                            <CommentAndLogSection objectType="user" objectId={user.email} />
+                            <CommentAndLogSection objectType="user" objectId={user.id} />
                         </div>
                     )}
                 </div>
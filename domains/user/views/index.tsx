@@ -275,12 +275,10 @@ export default function UserIndexPage(): JSX.Element {
                                         </thead>
                                         <tbody className="bg-white divide-y divide-gray-200">
                                             {users.map((user) => (
-                                                <tr key={user.email} className="hover:bg-gray-50">
+                                                <tr key={user.id} className="hover:bg-gray-50">
                                                     <td className="px-6 py-4 whitespace-nowrap text-sm">
                                                         <Link
-                                                            to={`/users/${
-                                                                encodeURIComponent(user.email)
-                                                            }`}
+                                                            to={`/users/${user.id}`}
                                                             className="text-blue-600 hover:text-blue-900 hover:underline"
                                                         >
                                                             {user.email}
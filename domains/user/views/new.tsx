@@ -11,7 +11,7 @@ export default function NewUserPage(): JSX.Element {
         const { success, data: user, message } = await createUser(data)
 
         if (success) {
-            navigate(`/users/${encodeURIComponent(user.email)}`)
+            navigate(`/users/${user.id}`)
         } else {
             throw new Error(message)
         }
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	domain "bilingo/domains/webhook"
+	"bilingo/domains/webhook/service"
+	"bilingo/domains/webhook/types"
+	"bilingo/server"
+	"bilingo/server/app"
+	"bilingo/server/auth"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Register adds the routes of the webhooks to the app, which are for admins
+// only.
+func Register(a *app.App) {
+	api := a.Group("/webhooks", auth.UseAuth, auth.RequireAdmin)
+	api.Get("/", listWebhooks)
+	api.Get("/events", listEvents)
+	api.Post("/", createWebhook)
+	api.Get("/:id", getWebhook)
+	api.Patch("/:id", updateWebhook)
+	api.Delete("/:id", deleteWebhook)
+	api.Post("/:id/secret", rotateWebhookSecret)
+	api.Post("/:id/ping", pingWebhook)
+	api.Get("/:id/deliveries", listDeliveries)
+	api.Get("/:id/deliveries/:deliveryId", getDelivery)
+	api.Post("/:id/deliveries/:deliveryId/redeliver", redeliver)
+}
+
+func parseId(ctx *fiber.Ctx) (uint, error) {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid webhook ID: %w", err)
+	}
+	return uint(id), nil
+}
+
+// webhookError responds with the status of the error of the webhook service.
+func webhookError(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, domain.ErrWebhookNotFound), errors.Is(err, domain.ErrDeliveryNotFound):
+		return server.Error(ctx, 404, err)
+	case errors.Is(err, domain.ErrInvalidWebhook):
+		return server.Error(ctx, 400, err)
+	case errors.Is(err, domain.ErrWebhookDisabled):
+		return server.Error(ctx, 409, err)
+	default:
+		return server.Error(ctx, 500, err)
+	}
+}
+
+func listWebhooks(ctx *fiber.Ctx) error {
+	webhooks, err := service.ListWebhooks(ctx.UserContext())
+	if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success(ctx, webhooks)
+}
+
+func listEvents(ctx *fiber.Ctx) error {
+	return server.Success(ctx, service.EventNames())
+}
+
+func getWebhook(ctx *fiber.Ctx) error {
+	id, err := parseId(ctx)
+	if err != nil {
+		return server.Error(ctx, 400, err)
+	}
+
+	webhook, err := service.GetWebhook(ctx.UserContext(), id)
+	if err != nil {
+		return webhookError(ctx, err)
+	}
+
+	return server.Success(ctx, webhook)
+}
+
+func createWebhook(ctx *fiber.Ctx) error {
+	var data types.WebhookCreate
+	if err := ctx.BodyParser(&data); err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("malformed request body: %w", err))
+	}
+
+	webhook, err := service.CreateWebhook(ctx.UserContext(), &data)
+	if err != nil {
+		return webhookError(ctx, err)
+	}
+
+	return server.Success(ctx, webhook)
+}
+
+func updateWebhook(ctx *fiber.Ctx) error {
+	id, err := parseId(ctx)
+	if err != nil {
+		return server.Error(ctx, 400, err)
+	}
+
+	var data types.WebhookUpdate
+	if err := ctx.BodyParser(&data); err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("malformed request body: %w", err))
+	}
+
+	webhook, err := service.UpdateWebhook(ctx.UserContext(), id, &data)
+	if err != nil {
+		return webhookError(ctx, err)
+	}
+
+	return server.Success(ctx, webhook)
+}
+
+func deleteWebhook(ctx *fiber.Ctx) error {
+	id, err := parseId(ctx)
+	if err != nil {
+		return server.Error(ctx, 400, err)
+	}
+
+	if err := service.DeleteWebhook(ctx.UserContext(), id); err != nil {
+		return webhookError(ctx, err)
+	}
+
+	return server.Success[any](ctx, nil)
+}
+
+func rotateWebhookSecret(ctx *fiber.Ctx) error {
+	id, err := parseId(ctx)
+	if err != nil {
+		return server.Error(ctx, 400, err)
+	}
+
+	webhook, err := service.RotateWebhookSecret(ctx.UserContext(), id)
+	if err != nil {
+		return webhookError(ctx, err)
+	}
+
+	return server.Success(ctx, webhook)
+}
+
+func pingWebhook(ctx *fiber.Ctx) error {
+	id, err := parseId(ctx)
+	if err != nil {
+		return server.Error(ctx, 400, err)
+	}
+
+	delivery, err := service.PingWebhook(ctx.UserContext(), id)
+	if err != nil {
+		return webhookError(ctx, err)
+	}
+
+	return server.Success(ctx, delivery)
+}
+
+func listDeliveries(ctx *fiber.Ctx) error {
+	id, err := parseId(ctx)
+	if err != nil {
+		return server.Error(ctx, 400, err)
+	}
+
+	var query types.WebhookDeliveryListQuery
+	if err := ctx.QueryParser(&query); err != nil {
+		return server.Error(ctx, 400, fmt.Errorf("malformed query: %w", err))
+	}
+
+	if _, err := service.GetWebhook(ctx.UserContext(), id); err != nil {
+		return webhookError(ctx, err)
+	}
+	result, err := service.ListDeliveries(ctx.UserContext(), id, query)
+	if err != nil {
+		return server.Error(ctx, 500, err)
+	}
+
+	return server.Success(ctx, result)
+}
+
+func parseDeliveryId(ctx *fiber.Ctx) (uint, uint, error) {
+	id, err := parseId(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	deliveryId, err := strconv.ParseUint(ctx.Params("deliveryId"), 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid delivery ID: %w", err)
+	}
+	return id, uint(deliveryId), nil
+}
+
+func getDelivery(ctx *fiber.Ctx) error {
+	id, deliveryId, err := parseDeliveryId(ctx)
+	if err != nil {
+		return server.Error(ctx, 400, err)
+	}
+
+	delivery, err := service.GetDelivery(ctx.UserContext(), deliveryId)
+	if err != nil {
+		return webhookError(ctx, err)
+	} else if delivery.WebhookID != id {
+		return server.Error(ctx, 404, domain.ErrDeliveryNotFound)
+	}
+
+	return server.Success(ctx, delivery)
+}
+
+func redeliver(ctx *fiber.Ctx) error {
+	id, deliveryId, err := parseDeliveryId(ctx)
+	if err != nil {
+		return server.Error(ctx, 400, err)
+	}
+
+	delivery, err := service.GetDelivery(ctx.UserContext(), deliveryId)
+	if err != nil {
+		return webhookError(ctx, err)
+	} else if delivery.WebhookID != id {
+		return server.Error(ctx, 404, domain.ErrDeliveryNotFound)
+	}
+
+	delivery, err = service.Redeliver(ctx.UserContext(), deliveryId)
+	if err != nil {
+		return webhookError(ctx, err)
+	}
+
+	return server.Success(ctx, delivery)
+}
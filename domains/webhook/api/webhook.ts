@@ -0,0 +1,53 @@
+import type { ApiResponse, PaginatedResult } from "@/common"
+import { ApiEntry } from "@/client"
+import type { Webhook, WebhookDelivery, WebhookWithSecret } from "../models"
+import type { WebhookCreate, WebhookDeliveryListQuery, WebhookUpdate } from "../types"
+
+const webhookApi = new ApiEntry("/webhooks")
+
+export async function listWebhooks(): ApiResponse<Webhook[]> {
+    return await webhookApi.get("/")
+}
+
+export async function listWebhookEvents(): ApiResponse<string[]> {
+    return await webhookApi.get("/events")
+}
+
+export async function getWebhook(id: number): ApiResponse<Webhook> {
+    return await webhookApi.get("/" + id)
+}
+
+export async function createWebhook(data: WebhookCreate): ApiResponse<WebhookWithSecret> {
+    return await webhookApi.post("/", null, data)
+}
+
+export async function updateWebhook(id: number, data: WebhookUpdate): ApiResponse<Webhook> {
+    return await webhookApi.patch("/" + id, null, data)
+}
+
+export async function deleteWebhook(id: number): ApiResponse<null> {
+    return await webhookApi.delete("/" + id)
+}
+
+export async function rotateWebhookSecret(id: number): ApiResponse<WebhookWithSecret> {
+    return await webhookApi.post(`/${id}/secret`)
+}
+
+export async function pingWebhook(id: number): ApiResponse<WebhookDelivery> {
+    return await webhookApi.post(`/${id}/ping`)
+}
+
+export async function listWebhookDeliveries(
+    id: number,
+    query: Partial<WebhookDeliveryListQuery>,
+): ApiResponse<PaginatedResult<WebhookDelivery>> {
+    return await webhookApi.get(`/${id}/deliveries`, query)
+}
+
+export async function getWebhookDelivery(id: number, deliveryId: number): ApiResponse<WebhookDelivery> {
+    return await webhookApi.get(`/${id}/deliveries/${deliveryId}`)
+}
+
+export async function redeliverWebhookDelivery(id: number, deliveryId: number): ApiResponse<WebhookDelivery> {
+    return await webhookApi.post(`/${id}/deliveries/${deliveryId}/redeliver`)
+}
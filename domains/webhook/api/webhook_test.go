@@ -0,0 +1,292 @@
+package api_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"bilingo/config"
+	"bilingo/domains/webhook/models"
+	"bilingo/domains/webhook/service"
+	"bilingo/domains/webhook/types"
+	"bilingo/server/testutil"
+)
+
+// receiver is an endpoint of webhooks, which records the requests and responds
+// with the status of respond.
+type receiver struct {
+	mu       sync.Mutex
+	requests []received
+	respond  func(n int, w http.ResponseWriter) // n counts the requests from 1
+}
+
+type received struct {
+	header http.Header
+	body   []byte
+	at     time.Time
+}
+
+func newReceiver(t *testing.T, respond func(n int, w http.ResponseWriter)) (*receiver, string) {
+	r := &receiver{respond: respond}
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return r, server.URL
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	r.requests = append(r.requests, received{header: req.Header, body: body, at: time.Now()})
+	n := len(r.requests)
+	r.mu.Unlock()
+	r.respond(n, w)
+}
+
+func (r *receiver) received() []received {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]received(nil), r.requests...)
+}
+
+func respondStatus(status int) func(int, http.ResponseWriter) {
+	return func(_ int, w http.ResponseWriter) { w.WriteHeader(status) }
+}
+
+// newWebhookClient creates an app whose deliveries are retried quickly, and
+// returns a client logged in as an admin.
+func newWebhookClient(t *testing.T, configure func(cfg *config.WebhookConfig)) *testutil.Client {
+	a := testutil.NewApp(t, func(cfg *config.Config) {
+		cfg.Webhook.PollInterval = 20 * time.Millisecond
+		cfg.Webhook.RetryDelay = 10 * time.Millisecond
+		cfg.Webhook.MaxRetryDelay = 10 * time.Millisecond
+		cfg.Webhook.MaxAttempts = 1
+		if configure != nil {
+			configure(&cfg.Webhook)
+		}
+	})
+	admin := testutil.LoadFixtures(t, a).User(t, "admin@example.com")
+	client := testutil.NewClient(t, a)
+	client.Login(admin.Email, *admin.Password)
+	return client
+}
+
+func createWebhook(t *testing.T, client *testutil.Client, url string) *models.WebhookWithSecret {
+	t.Helper()
+	secret := "0123456789abcdef0123456789abcdef"
+	data := types.WebhookCreate{URL: url, Events: []string{types.AllEvents}, Secret: &secret}
+	webhook := testutil.Decode[models.WebhookWithSecret](t, client.Post("/webhooks", data))
+	return &webhook
+}
+
+func ping(t *testing.T, client *testutil.Client, webhookId uint) models.WebhookDelivery {
+	t.Helper()
+	return testutil.Decode[models.WebhookDelivery](t, client.Post(fmt.Sprintf("/webhooks/%d/ping", webhookId), nil))
+}
+
+// waitForDelivery waits until the delivery is finished, and returns it.
+func waitForDelivery(t *testing.T, client *testutil.Client, delivery models.WebhookDelivery) models.WebhookDelivery {
+	t.Helper()
+	path := fmt.Sprintf("/webhooks/%d/deliveries/%d", delivery.WebhookID, delivery.ID)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		delivery = testutil.Decode[models.WebhookDelivery](t, client.Get(path))
+		if delivery.Status != types.DeliveryPending {
+			return delivery
+		} else if time.Now().After(deadline) {
+			t.Fatalf("delivery %d is still pending after %d attempts", delivery.ID, delivery.Attempts)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	t.Parallel()
+	client := newWebhookClient(t, nil)
+	receiver, url := newReceiver(t, respondStatus(http.StatusNoContent))
+	webhook := createWebhook(t, client, url)
+
+	delivery := waitForDelivery(t, client, ping(t, client, webhook.ID))
+	if delivery.Status != types.DeliverySucceeded || delivery.ResponseCode == nil || *delivery.ResponseCode != http.StatusNoContent {
+		t.Fatalf("unexpected delivery: %+v", delivery)
+	}
+
+	requests := receiver.received()
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(requests))
+	}
+	header, body := requests[0].header, requests[0].body
+	timestamp, err := strconv.ParseInt(header.Get(service.HeaderTimestamp), 10, 64)
+	if err != nil || time.Since(time.Unix(timestamp, 0)) > time.Minute {
+		t.Fatalf("invalid timestamp %q", header.Get(service.HeaderTimestamp))
+	}
+	signature := header.Get(service.HeaderSignature)
+	if !service.VerifySignature(webhook.Secret, timestamp, body, signature) {
+		t.Fatalf("invalid signature %q of %s", signature, body)
+	}
+	if string(body) != delivery.Payload || header.Get(service.HeaderEvent) != service.PingEvent ||
+		header.Get(service.HeaderDelivery) != strconv.FormatUint(uint64(delivery.ID), 10) {
+		t.Fatalf("unexpected request: %v %s", header, body)
+	}
+
+	// The signature covers both the timestamp and the body
+	if service.VerifySignature(webhook.Secret, timestamp+1, body, signature) {
+		t.Fatal("the signature is valid at another timestamp")
+	}
+	if service.VerifySignature(webhook.Secret, timestamp, append(body, ' '), signature) {
+		t.Fatal("the signature is valid for another body")
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	t.Parallel()
+	delay, maxDelay := 300*time.Millisecond, 500*time.Millisecond
+	client := newWebhookClient(t, func(cfg *config.WebhookConfig) {
+		cfg.RetryDelay = delay
+		cfg.MaxRetryDelay = maxDelay
+		cfg.MaxAttempts = 3
+	})
+	receiver, url := newReceiver(t, respondStatus(http.StatusInternalServerError))
+	webhook := createWebhook(t, client, url)
+	pinged := ping(t, client, webhook.ID)
+
+	// The first retry is scheduled after the delay
+	path := fmt.Sprintf("/webhooks/%d/deliveries/%d", webhook.ID, pinged.ID)
+	for {
+		delivery := testutil.Decode[models.WebhookDelivery](t, client.Get(path))
+		if delivery.Attempts == 0 {
+			time.Sleep(5 * time.Millisecond)
+			continue
+		} else if delivery.Attempts != 1 || delivery.Status != types.DeliveryPending || delivery.NextAttemptAt == nil {
+			t.Fatalf("expected a retry to be scheduled, got %+v", delivery)
+		}
+		if scheduled := delivery.NextAttemptAt.Sub(delivery.UpdatedAt); scheduled < delay-50*time.Millisecond || scheduled > delay+50*time.Millisecond {
+			t.Fatalf("expected the retry in %s, got %s", delay, scheduled)
+		}
+		break
+	}
+
+	// Every attempt failed, so the delivery is dead
+	delivery := waitForDelivery(t, client, pinged)
+	if delivery.Status != types.DeliveryFailed || delivery.Attempts != 3 || delivery.NextAttemptAt != nil ||
+		delivery.Error == nil || delivery.ResponseCode == nil || *delivery.ResponseCode != http.StatusInternalServerError {
+		t.Fatalf("unexpected delivery: %+v", delivery)
+	}
+	requests := receiver.received()
+	if len(requests) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(requests))
+	}
+	// The delay doubles up to the maximum
+	for i, expected := range []time.Duration{delay, maxDelay} {
+		if gap := requests[i+1].at.Sub(requests[i].at); gap < expected {
+			t.Errorf("expected the retry %d after %s, got %s", i+1, expected, gap)
+		}
+	}
+}
+
+func TestWebhookDisabledAfterFailures(t *testing.T) {
+	t.Parallel()
+	client := newWebhookClient(t, func(cfg *config.WebhookConfig) {
+		cfg.DisableAfter = 2
+		cfg.Concurrency = 1
+	})
+	release := make(chan struct{})
+	receiver, url := newReceiver(t, func(_ int, w http.ResponseWriter) {
+		<-release
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	webhook := createWebhook(t, client, url)
+
+	// The other deliveries are queued while the first one is being sent
+	first := ping(t, client, webhook.ID)
+	for len(receiver.received()) == 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	second := ping(t, client, webhook.ID)
+	third := ping(t, client, webhook.ID)
+	close(release)
+
+	for _, delivery := range []models.WebhookDelivery{first, second} {
+		if delivery = waitForDelivery(t, client, delivery); delivery.Status != types.DeliveryFailed || delivery.Attempts != 1 {
+			t.Fatalf("unexpected delivery: %+v", delivery)
+		}
+	}
+
+	// The webhook is disabled once 2 deliveries failed in a row, which fails the
+	// deliveries left without sending them
+	if delivery := waitForDelivery(t, client, third); delivery.Status != types.DeliveryFailed || delivery.Attempts != 0 {
+		t.Fatalf("expected the pending delivery to fail, got %+v", delivery)
+	}
+	if n := len(receiver.received()); n != 2 {
+		t.Fatalf("expected 2 requests, got %d", n)
+	}
+	disabled := testutil.Decode[models.Webhook](t, client.Get(fmt.Sprintf("/webhooks/%d", webhook.ID)))
+	if disabled.Enabled || disabled.DisabledReason == nil {
+		t.Fatalf("expected the webhook to be disabled, got %+v", disabled)
+	}
+	if resp := client.Post(fmt.Sprintf("/webhooks/%d/ping", webhook.ID), nil); resp.Status != http.StatusConflict {
+		t.Fatalf("pinged a disabled webhook: %s", resp)
+	}
+}
+
+func TestWebhookRedirect(t *testing.T) {
+	t.Parallel()
+	client := newWebhookClient(t, nil)
+	target, targetUrl := newReceiver(t, respondStatus(http.StatusOK))
+	_, url := newReceiver(t, func(_ int, w http.ResponseWriter) {
+		w.Header().Set("Location", targetUrl)
+		w.WriteHeader(http.StatusFound)
+	})
+	webhook := createWebhook(t, client, url)
+
+	delivery := waitForDelivery(t, client, ping(t, client, webhook.ID))
+	if delivery.Status != types.DeliveryFailed || delivery.ResponseCode == nil || *delivery.ResponseCode != http.StatusFound {
+		t.Fatalf("expected the redirect to fail the delivery, got %+v", delivery)
+	}
+	if n := len(target.received()); n != 0 {
+		t.Fatalf("followed the redirect with %d requests", n)
+	}
+}
+
+func TestRedeliver(t *testing.T) {
+	t.Parallel()
+	client := newWebhookClient(t, nil)
+	receiver, url := newReceiver(t, func(n int, w http.ResponseWriter) {
+		if n == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+	})
+	webhook := createWebhook(t, client, url)
+
+	original := waitForDelivery(t, client, ping(t, client, webhook.ID))
+	if original.Status != types.DeliveryFailed {
+		t.Fatalf("unexpected delivery: %+v", original)
+	}
+
+	path := fmt.Sprintf("/webhooks/%d/deliveries/%d/redeliver", webhook.ID, original.ID)
+	redelivery := testutil.Decode[models.WebhookDelivery](t, client.Post(path, nil))
+	if redelivery.ID == original.ID || redelivery.RedeliveryOf == nil || *redelivery.RedeliveryOf != original.ID ||
+		redelivery.EventID != original.EventID || redelivery.Payload != original.Payload {
+		t.Fatalf("unexpected redelivery: %+v", redelivery)
+	}
+	if redelivery = waitForDelivery(t, client, redelivery); redelivery.Status != types.DeliverySucceeded {
+		t.Fatalf("unexpected redelivery: %+v", redelivery)
+	}
+
+	// Receivers tell redeliveries apart by the event ID
+	requests := receiver.received()
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+	first, second := requests[0].header, requests[1].header
+	if first.Get(service.HeaderEventId) != second.Get(service.HeaderEventId) ||
+		first.Get(service.HeaderDelivery) == second.Get(service.HeaderDelivery) {
+		t.Fatalf("unexpected headers of the redelivery: %v and %v", first, second)
+	}
+}
@@ -0,0 +1,5 @@
+package webhook
+
+// DBBinding is the binding of the database the tables of the domain are in,
+// see db.Registry.Bind.
+const DBBinding = "webhook"
@@ -0,0 +1,10 @@
+package webhook
+
+import "errors"
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrInvalidWebhook   = errors.New("invalid webhook")
+	ErrWebhookDisabled  = errors.New("webhook is disabled")
+)
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// WebhookDelivery is an event sent, or to be sent, to a webhook, along with the
+// outcome of the last attempt.
+type WebhookDelivery struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	WebhookID uint      `json:"webhook_id" gorm:"index"`
+	// Identifies the event, it's the same for the redeliveries, so receivers can
+	// tell them apart from new events
+	EventID string `json:"event_id" gorm:"size:36;index"`
+	Event   string `json:"event" gorm:"size:128"`
+	Payload string `json:"payload"` // The JSON body of the requests
+	Status  string `json:"status" gorm:"size:16;index:idx_webhook_delivery_due,priority:1"`
+	// The number of attempts made so far
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at" gorm:"index:idx_webhook_delivery_due,priority:2"` // Nil once finished
+	LockedUntil   *time.Time `json:"-"`                                                                // Set while the delivery is being sent
+	// The outcome of the last attempt
+	ResponseCode *int       `json:"response_code"` // Nil if there was no response
+	ResponseBody *string    `json:"response_body"` // The beginning of the response body
+	Error        *string    `json:"error"`
+	Duration     int64      `json:"duration"` // In milliseconds
+	DeliveredAt  *time.Time `json:"delivered_at"`
+	RedeliveryOf *uint      `json:"redelivery_of"` // The delivery this one was redelivered from by hand
+}
+
+func (d *WebhookDelivery) TableName() string {
+	return "webhook_delivery"
+}
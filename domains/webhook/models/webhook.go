@@ -0,0 +1,40 @@
+package models
+
+import (
+	"strconv"
+	"time"
+)
+
+// Webhook is a subscription of an external endpoint to the events of the
+// domains, which are delivered to its URL as JSON requests signed with the
+// secret.
+type Webhook struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	URL       string    `json:"url" gorm:"size:2048"`
+	Secret    string    `json:"-" gorm:"size:128"` // The key of the HMAC-SHA256 signatures
+	// The names of the events delivered, e.g. `article.created`, or `*` for all
+	Events      []string `json:"events" gorm:"serializer:json;size:1024"`
+	Description *string  `json:"description" gorm:"size:255"`
+	Enabled     bool     `json:"enabled"`
+	// The number of deliveries in a row that failed every attempt, the webhook
+	// is disabled once it reaches Webhook.DisableAfter of the configuration
+	Failures       int     `json:"failures"`
+	DisabledReason *string `json:"disabled_reason" gorm:"size:255"`
+}
+
+func (w *Webhook) TableName() string {
+	return "webhook"
+}
+
+func (w *Webhook) AuditInfo() (string, string) {
+	return "webhook", strconv.FormatUint(uint64(w.ID), 10)
+}
+
+// WebhookWithSecret is a webhook along with its secret, which is only returned
+// when the webhook is created or the secret is rotated.
+type WebhookWithSecret struct {
+	Webhook `tstype:",extends"`
+	Secret  string `json:"secret"`
+}
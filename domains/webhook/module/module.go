@@ -0,0 +1,30 @@
+// Package module assembles the webhook domain into an app.
+package module
+
+import (
+	"context"
+
+	"bilingo/domains/webhook/api"
+	"bilingo/domains/webhook/repo"
+	"bilingo/domains/webhook/service"
+	"bilingo/server/app"
+	"bilingo/server/events"
+)
+
+// Register provides the repositories of the webhooks and the dispatcher of
+// their deliveries, which runs while the app is running, subscribes the
+// webhooks to the events, and adds the routes of the webhooks.
+func Register(a *app.App) error {
+	repo.Provide(a)
+	d := service.NewDispatcher(a.Context(context.Background()), a.Config.Webhook)
+	app.Provide(a, d)
+	a.OnStart(func(ctx context.Context) error {
+		d.Start()
+		return nil
+	})
+	a.OnStop(d.Shutdown)
+
+	service.Subscribe(events.ForApp(a))
+	api.Register(a)
+	return nil
+}
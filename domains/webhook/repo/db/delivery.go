@@ -0,0 +1,191 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"bilingo/common"
+	domain "bilingo/domains/webhook"
+	"bilingo/domains/webhook/models"
+	"bilingo/domains/webhook/tables"
+	"bilingo/domains/webhook/types"
+	"bilingo/server/db"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DeliveryRepo struct{}
+
+func (r *DeliveryRepo) Get(ctx context.Context, id uint) (*models.WebhookDelivery, error) {
+	conn, err := db.Reader(ctx, domain.DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	delivery, err := gorm.G[models.WebhookDelivery](conn).Where(tables.WebhookDelivery.ID.Eq(id)).First(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrDeliveryNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to find webhook delivery: %w", err)
+	}
+
+	return &delivery, nil
+}
+
+func (r *DeliveryRepo) List(
+	ctx context.Context,
+	webhookId uint,
+	query *types.WebhookDeliveryListQuery,
+) (*common.PaginatedResult[models.WebhookDelivery], error) {
+	conn, err := db.Reader(ctx, domain.DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	q := gorm.G[models.WebhookDelivery](conn).Where(tables.WebhookDelivery.WebhookID.Eq(webhookId))
+	if query.Status != nil && *query.Status != "" {
+		q = q.Where(tables.WebhookDelivery.Status.Eq(*query.Status))
+	}
+
+	// Count total before applying pagination
+	total, err := q.Count(ctx, "*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to count webhook deliveries: %w", err)
+	}
+
+	q = q.Order(tables.WebhookDelivery.ID.Desc())
+	q = q.Limit(query.PageSize)
+	q = q.Offset(query.PageSize * (query.Page - 1))
+
+	deliveries, err := q.Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery list: %w", err)
+	} else if len(deliveries) == 0 {
+		return &common.PaginatedResult[models.WebhookDelivery]{Total: 0, List: []models.WebhookDelivery{}}, nil
+	}
+
+	return &common.PaginatedResult[models.WebhookDelivery]{Total: int(total), List: deliveries}, nil
+}
+
+func (r *DeliveryRepo) Create(ctx context.Context, delivery *models.WebhookDelivery) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	if err := gorm.G[models.WebhookDelivery](conn).Create(ctx, delivery); err != nil {
+		return fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DeliveryRepo) ListDue(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	deliveries, err := gorm.G[models.WebhookDelivery](conn).
+		Where(
+			tables.WebhookDelivery.Status.Eq(types.DeliveryPending),
+			tables.WebhookDelivery.NextAttemptAt.Lte(now),
+			clause.Or(tables.WebhookDelivery.LockedUntil.IsNull(), tables.WebhookDelivery.LockedUntil.Lt(now)),
+		).
+		Order(tables.WebhookDelivery.ID.Asc()).
+		Limit(limit).
+		Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func (r *DeliveryRepo) Claim(ctx context.Context, id uint, now time.Time, until time.Time) (bool, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return false, db.ConnError(err)
+	}
+
+	// Another instance may have claimed the delivery since it was listed
+	rowsAffected, err := gorm.G[models.WebhookDelivery](conn).
+		Where(
+			tables.WebhookDelivery.ID.Eq(id),
+			tables.WebhookDelivery.Status.Eq(types.DeliveryPending),
+			clause.Or(tables.WebhookDelivery.LockedUntil.IsNull(), tables.WebhookDelivery.LockedUntil.Lt(now)),
+		).
+		Set(tables.WebhookDelivery.LockedUntil.Set(until)).
+		Update(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim webhook delivery: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (r *DeliveryRepo) Update(ctx context.Context, delivery *models.WebhookDelivery) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	delivery.LockedUntil = nil
+	// The fields are selected so that the zero values are saved too
+	result := conn.WithContext(ctx).Model(&models.WebhookDelivery{ID: delivery.ID}).
+		Select(
+			"status", "attempts", "next_attempt_at", "locked_until", "response_code",
+			"response_body", "error", "duration", "delivered_at", "updated_at",
+		).
+		Updates(delivery)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", result.Error)
+	} else if result.RowsAffected == 0 {
+		return domain.ErrDeliveryNotFound
+	}
+
+	return nil
+}
+
+func (r *DeliveryRepo) FailPending(ctx context.Context, webhookId uint, reason string) (int, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return 0, db.ConnError(err)
+	}
+
+	result := conn.WithContext(ctx).Model(&models.WebhookDelivery{}).
+		Where(
+			tables.WebhookDelivery.WebhookID.Eq(webhookId),
+			tables.WebhookDelivery.Status.Eq(types.DeliveryPending),
+		).
+		Updates(map[string]any{
+			"status":          types.DeliveryFailed,
+			"error":           reason,
+			"next_attempt_at": nil,
+			"updated_at":      time.Now(),
+		})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to fail pending webhook deliveries: %w", result.Error)
+	}
+
+	return int(result.RowsAffected), nil
+}
+
+func (r *DeliveryRepo) DeleteByWebhook(ctx context.Context, webhookId uint) (int, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return 0, db.ConnError(err)
+	}
+
+	rowsAffected, err := gorm.G[models.WebhookDelivery](conn).
+		Where(tables.WebhookDelivery.WebhookID.Eq(webhookId)).
+		Delete(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete webhook deliveries: %w", err)
+	}
+
+	return rowsAffected, nil
+}
@@ -0,0 +1,17 @@
+package impl_test
+
+import (
+	"testing"
+
+	"bilingo/domains/webhook/repo"
+	impl "bilingo/domains/webhook/repo/db"
+	"bilingo/domains/webhook/repo/repotest"
+	"bilingo/server/testutil"
+)
+
+func TestDeliveryRepo(t *testing.T) {
+	repotest.TestDeliveryRepo(t, func(t *testing.T) repo.IDeliveryRepo {
+		testutil.NewDB(t)
+		return &impl.DeliveryRepo{}
+	})
+}
@@ -0,0 +1,15 @@
+package impl
+
+import (
+	domain "bilingo/domains/webhook"
+	"bilingo/domains/webhook/models"
+	"bilingo/server/db"
+)
+
+func init() {
+	db.RegisterMigrations(
+		domain.DBBinding,
+		db.Migration{ID: "2026101913_webhook_create_webhook_table", Up: db.CreateTableIfNotExists(&models.Webhook{})},
+		db.Migration{ID: "2026101913_webhook_create_webhook_delivery_table", Up: db.CreateTableIfNotExists(&models.WebhookDelivery{})},
+	)
+}
@@ -0,0 +1,148 @@
+package impl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	domain "bilingo/domains/webhook"
+	"bilingo/domains/webhook/models"
+	"bilingo/domains/webhook/tables"
+	"bilingo/server/db"
+
+	"gorm.io/gorm"
+)
+
+type WebhookRepo struct{}
+
+func (r *WebhookRepo) Get(ctx context.Context, id uint) (*models.Webhook, error) {
+	conn, err := db.Reader(ctx, domain.DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	webhook, err := gorm.G[models.Webhook](conn).Where(tables.Webhook.ID.Eq(id)).First(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrWebhookNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to find webhook: %w", err)
+	}
+
+	return &webhook, nil
+}
+
+func (r *WebhookRepo) List(ctx context.Context) ([]models.Webhook, error) {
+	conn, err := db.Reader(ctx, domain.DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	webhooks, err := gorm.G[models.Webhook](conn).Order(tables.Webhook.ID.Asc()).Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook list: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+func (r *WebhookRepo) ListEnabled(ctx context.Context) ([]models.Webhook, error) {
+	conn, err := db.Reader(ctx, domain.DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	webhooks, err := gorm.G[models.Webhook](conn).
+		Where(tables.Webhook.Enabled.Eq(true)).
+		Order(tables.Webhook.ID.Asc()).
+		Find(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get enabled webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+func (r *WebhookRepo) Create(ctx context.Context, webhook *models.Webhook) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	if err := gorm.G[models.Webhook](conn).Create(ctx, webhook); err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	return nil
+}
+
+func (r *WebhookRepo) Update(ctx context.Context, webhook *models.Webhook) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	// The fields are selected so that the zero values are saved too
+	result := conn.WithContext(ctx).Model(&models.Webhook{ID: webhook.ID}).
+		Select("url", "secret", "events", "description", "enabled", "failures", "disabled_reason", "updated_at").
+		Updates(webhook)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update webhook: %w", result.Error)
+	} else if result.RowsAffected == 0 {
+		return domain.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (r *WebhookRepo) Delete(ctx context.Context, id uint) error {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	rowsAffected, err := gorm.G[models.Webhook](conn).Where(tables.Webhook.ID.Eq(id)).Delete(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	} else if rowsAffected == 0 {
+		return domain.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (r *WebhookRepo) RecordDelivery(ctx context.Context, id uint, succeeded bool, disableAfter int, reason string) (bool, error) {
+	conn, err := db.For(ctx, domain.DBBinding)
+	if err != nil {
+		return false, db.ConnError(err)
+	}
+
+	if succeeded {
+		_, err := gorm.G[models.Webhook](conn).
+			Where(tables.Webhook.ID.Eq(id), tables.Webhook.Failures.Neq(0)).
+			Set(tables.Webhook.Failures.Set(0)).
+			Update(ctx)
+		if err != nil {
+			return false, fmt.Errorf("failed to reset webhook failures: %w", err)
+		}
+		return false, nil
+	}
+
+	// Counted in the database, since deliveries fail concurrently
+	_, err = gorm.G[models.Webhook](conn).
+		Where(tables.Webhook.ID.Eq(id)).
+		Set(tables.Webhook.Failures.Incr(1)).
+		Update(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to count webhook failure: %w", err)
+	}
+
+	rowsAffected, err := gorm.G[models.Webhook](conn).
+		Where(tables.Webhook.ID.Eq(id), tables.Webhook.Enabled.Eq(true), tables.Webhook.Failures.Gte(disableAfter)).
+		Set(tables.Webhook.Enabled.Set(false), tables.Webhook.DisabledReason.Set(reason)).
+		Update(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to disable webhook: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
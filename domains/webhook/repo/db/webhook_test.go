@@ -0,0 +1,17 @@
+package impl_test
+
+import (
+	"testing"
+
+	"bilingo/domains/webhook/repo"
+	impl "bilingo/domains/webhook/repo/db"
+	"bilingo/domains/webhook/repo/repotest"
+	"bilingo/server/testutil"
+)
+
+func TestWebhookRepo(t *testing.T) {
+	repotest.TestWebhookRepo(t, func(t *testing.T) repo.IWebhookRepo {
+		testutil.NewDB(t)
+		return &impl.WebhookRepo{}
+	})
+}
@@ -0,0 +1,40 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"bilingo/common"
+	"bilingo/domains/webhook/models"
+	impl "bilingo/domains/webhook/repo/db"
+	"bilingo/domains/webhook/types"
+	"bilingo/server/app"
+)
+
+// Deliveries returns the webhook delivery repository of the app the context
+// carries, or the database implementation outside of apps.
+func Deliveries(ctx context.Context) IDeliveryRepo {
+	return app.Resolve[IDeliveryRepo](ctx, &impl.DeliveryRepo{})
+}
+
+type IDeliveryRepo interface {
+	Get(ctx context.Context, id uint) (*models.WebhookDelivery, error)
+	// List returns the deliveries of the webhook, newest first.
+	List(ctx context.Context, webhookId uint, query *types.WebhookDeliveryListQuery) (*common.PaginatedResult[models.WebhookDelivery], error)
+	Create(ctx context.Context, delivery *models.WebhookDelivery) error
+	// ListDue returns up to limit pending deliveries whose next attempts are due
+	// and which aren't claimed, oldest first.
+	ListDue(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error)
+	// Claim locks the delivery until the given time, unless it's claimed
+	// already, and returns whether it was claimed.
+	Claim(ctx context.Context, id uint, now time.Time, until time.Time) (bool, error)
+	// Update saves the outcome of an attempt, i.e. the status, the attempts,
+	// the response and the time of the next attempt, and releases the claim.
+	Update(ctx context.Context, delivery *models.WebhookDelivery) error
+	// FailPending marks the pending deliveries of the webhook as failed for the
+	// reason, and returns the number of them.
+	FailPending(ctx context.Context, webhookId uint, reason string) (int, error)
+	// DeleteByWebhook deletes all deliveries of the webhook, and returns the
+	// number of them.
+	DeleteByWebhook(ctx context.Context, webhookId uint) (int, error)
+}
@@ -0,0 +1,22 @@
+package repo
+
+import (
+	"bilingo/config"
+	impl "bilingo/domains/webhook/repo/db"
+	"bilingo/domains/webhook/repo/memory"
+	"bilingo/server/app"
+)
+
+// Provide provides the repositories of the driver in the configuration of the
+// app, which keep their records in memory rather than the databases with the
+// memory driver, see config.RepoMemory.
+func Provide(a *app.App) {
+	if a.Config.Repo == config.RepoMemory {
+		app.Provide[IWebhookRepo](a, &memory.WebhookRepo{})
+		app.Provide[IDeliveryRepo](a, &memory.DeliveryRepo{})
+		return
+	}
+
+	app.Provide[IWebhookRepo](a, &impl.WebhookRepo{})
+	app.Provide[IDeliveryRepo](a, &impl.DeliveryRepo{})
+}
@@ -0,0 +1,127 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"bilingo/common"
+	domain "bilingo/domains/webhook"
+	"bilingo/domains/webhook/models"
+	"bilingo/domains/webhook/types"
+	"bilingo/server/memdb"
+)
+
+type DeliveryRepo struct {
+	deliveries memdb.Table[uint, models.WebhookDelivery]
+}
+
+func (r *DeliveryRepo) Get(ctx context.Context, id uint) (*models.WebhookDelivery, error) {
+	delivery, ok := r.deliveries.Get(id)
+	if !ok {
+		return nil, domain.ErrDeliveryNotFound
+	}
+
+	return &delivery, nil
+}
+
+func (r *DeliveryRepo) List(
+	ctx context.Context,
+	webhookId uint,
+	query *types.WebhookDeliveryListQuery,
+) (*common.PaginatedResult[models.WebhookDelivery], error) {
+	deliveries := r.deliveries.Find(func(delivery models.WebhookDelivery) bool {
+		if delivery.WebhookID != webhookId {
+			return false
+		}
+		return query.Status == nil || *query.Status == "" || delivery.Status == *query.Status
+	})
+	slices.Reverse(deliveries)
+
+	return memdb.Paginate(deliveries, query.PaginatedQuery), nil
+}
+
+func (r *DeliveryRepo) Create(ctx context.Context, delivery *models.WebhookDelivery) error {
+	if delivery.ID == 0 {
+		delivery.ID = r.deliveries.NextID()
+	}
+	now := time.Now()
+	if delivery.CreatedAt.IsZero() {
+		delivery.CreatedAt = now
+	}
+	if delivery.UpdatedAt.IsZero() {
+		delivery.UpdatedAt = now
+	}
+	if !r.deliveries.Insert(delivery.ID, *delivery) {
+		return fmt.Errorf("failed to create webhook delivery: delivery %d exists", delivery.ID)
+	}
+
+	return nil
+}
+
+func (r *DeliveryRepo) ListDue(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	deliveries := r.deliveries.Find(func(delivery models.WebhookDelivery) bool {
+		return due(delivery, now) && delivery.NextAttemptAt != nil && !delivery.NextAttemptAt.After(now)
+	})
+	return deliveries[:min(len(deliveries), limit)], nil
+}
+
+// due reports whether the delivery is pending and not claimed.
+func due(delivery models.WebhookDelivery, now time.Time) bool {
+	return delivery.Status == types.DeliveryPending && (delivery.LockedUntil == nil || delivery.LockedUntil.Before(now))
+}
+
+func (r *DeliveryRepo) Claim(ctx context.Context, id uint, now time.Time, until time.Time) (bool, error) {
+	return r.deliveries.Update(id, func(delivery *models.WebhookDelivery) bool {
+		if !due(*delivery, now) {
+			return false
+		}
+		delivery.LockedUntil = &until
+		return true
+	}), nil
+}
+
+func (r *DeliveryRepo) Update(ctx context.Context, delivery *models.WebhookDelivery) error {
+	delivery.LockedUntil = nil
+	delivery.UpdatedAt = time.Now()
+	updated := r.deliveries.Update(delivery.ID, func(stored *models.WebhookDelivery) bool {
+		stored.Status = delivery.Status
+		stored.Attempts = delivery.Attempts
+		stored.NextAttemptAt = delivery.NextAttemptAt
+		stored.LockedUntil = nil
+		stored.ResponseCode = delivery.ResponseCode
+		stored.ResponseBody = delivery.ResponseBody
+		stored.Error = delivery.Error
+		stored.Duration = delivery.Duration
+		stored.DeliveredAt = delivery.DeliveredAt
+		stored.UpdatedAt = delivery.UpdatedAt
+		return true
+	})
+	if !updated {
+		return domain.ErrDeliveryNotFound
+	}
+
+	return nil
+}
+
+func (r *DeliveryRepo) FailPending(ctx context.Context, webhookId uint, reason string) (int, error) {
+	now := time.Now()
+	return r.deliveries.UpdateWhere(
+		func(delivery models.WebhookDelivery) bool {
+			return delivery.WebhookID == webhookId && delivery.Status == types.DeliveryPending
+		},
+		func(delivery *models.WebhookDelivery) {
+			delivery.Status = types.DeliveryFailed
+			delivery.Error = &reason
+			delivery.NextAttemptAt = nil
+			delivery.UpdatedAt = now
+		},
+	), nil
+}
+
+func (r *DeliveryRepo) DeleteByWebhook(ctx context.Context, webhookId uint) (int, error) {
+	return r.deliveries.DeleteWhere(func(delivery models.WebhookDelivery) bool {
+		return delivery.WebhookID == webhookId
+	}), nil
+}
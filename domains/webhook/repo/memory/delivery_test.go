@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"bilingo/domains/webhook/repo"
+	"bilingo/domains/webhook/repo/memory"
+	"bilingo/domains/webhook/repo/repotest"
+)
+
+func TestDeliveryRepo(t *testing.T) {
+	repotest.TestDeliveryRepo(t, func(t *testing.T) repo.IDeliveryRepo {
+		return &memory.DeliveryRepo{}
+	})
+}
@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	domain "bilingo/domains/webhook"
+	"bilingo/domains/webhook/models"
+	"bilingo/server/memdb"
+)
+
+type WebhookRepo struct {
+	webhooks memdb.Table[uint, models.Webhook]
+}
+
+// clone copies the events of the webhook, which would be shared otherwise.
+func clone(webhook models.Webhook) models.Webhook {
+	webhook.Events = slices.Clone(webhook.Events)
+	return webhook
+}
+
+func (r *WebhookRepo) Get(ctx context.Context, id uint) (*models.Webhook, error) {
+	webhook, ok := r.webhooks.Get(id)
+	if !ok {
+		return nil, domain.ErrWebhookNotFound
+	}
+
+	webhook = clone(webhook)
+	return &webhook, nil
+}
+
+func (r *WebhookRepo) List(ctx context.Context) ([]models.Webhook, error) {
+	webhooks := r.webhooks.Find(nil)
+	for i := range webhooks {
+		webhooks[i] = clone(webhooks[i])
+	}
+	return webhooks, nil
+}
+
+func (r *WebhookRepo) ListEnabled(ctx context.Context) ([]models.Webhook, error) {
+	webhooks := r.webhooks.Find(func(webhook models.Webhook) bool {
+		return webhook.Enabled
+	})
+	for i := range webhooks {
+		webhooks[i] = clone(webhooks[i])
+	}
+	return webhooks, nil
+}
+
+func (r *WebhookRepo) Create(ctx context.Context, webhook *models.Webhook) error {
+	if webhook.ID == 0 {
+		webhook.ID = r.webhooks.NextID()
+	}
+	now := time.Now()
+	if webhook.CreatedAt.IsZero() {
+		webhook.CreatedAt = now
+	}
+	if webhook.UpdatedAt.IsZero() {
+		webhook.UpdatedAt = now
+	}
+	if !r.webhooks.Insert(webhook.ID, clone(*webhook)) {
+		return fmt.Errorf("failed to create webhook: webhook %d exists", webhook.ID)
+	}
+
+	return nil
+}
+
+func (r *WebhookRepo) Update(ctx context.Context, webhook *models.Webhook) error {
+	webhook.UpdatedAt = time.Now()
+	updated := r.webhooks.Update(webhook.ID, func(stored *models.Webhook) bool {
+		createdAt := stored.CreatedAt
+		*stored = clone(*webhook)
+		stored.CreatedAt = createdAt
+		return true
+	})
+	if !updated {
+		return domain.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (r *WebhookRepo) Delete(ctx context.Context, id uint) error {
+	if !r.webhooks.Delete(id) {
+		return domain.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (r *WebhookRepo) RecordDelivery(ctx context.Context, id uint, succeeded bool, disableAfter int, reason string) (bool, error) {
+	disabled := false
+	r.webhooks.Update(id, func(webhook *models.Webhook) bool {
+		if succeeded {
+			webhook.Failures = 0
+			return true
+		}
+
+		webhook.Failures++
+		if webhook.Enabled && webhook.Failures >= disableAfter {
+			webhook.Enabled = false
+			webhook.DisabledReason = &reason
+			disabled = true
+		}
+		return true
+	})
+	return disabled, nil
+}
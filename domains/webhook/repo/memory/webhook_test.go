@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"bilingo/domains/webhook/repo"
+	"bilingo/domains/webhook/repo/memory"
+	"bilingo/domains/webhook/repo/repotest"
+)
+
+func TestWebhookRepo(t *testing.T) {
+	repotest.TestWebhookRepo(t, func(t *testing.T) repo.IWebhookRepo {
+		return &memory.WebhookRepo{}
+	})
+}
@@ -0,0 +1,161 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"bilingo/common"
+	domain "bilingo/domains/webhook"
+	"bilingo/domains/webhook/models"
+	"bilingo/domains/webhook/repo"
+	"bilingo/domains/webhook/types"
+)
+
+// TestDeliveryRepo tests the webhook delivery repository against the contract.
+func TestDeliveryRepo(t *testing.T, newRepo func(t *testing.T) repo.IDeliveryRepo) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	create := func(t *testing.T, r repo.IDeliveryRepo, webhookId uint, event string, nextAttemptAt time.Time) *models.WebhookDelivery {
+		t.Helper()
+		delivery := &models.WebhookDelivery{
+			WebhookID:     webhookId,
+			EventID:       "event-" + event,
+			Event:         event,
+			Payload:       `{"event":"` + event + `"}`,
+			Status:        types.DeliveryPending,
+			NextAttemptAt: &nextAttemptAt,
+		}
+		if err := r.Create(ctx, delivery); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		return delivery
+	}
+
+	events := func(deliveries []models.WebhookDelivery) []string {
+		var result []string
+		for _, delivery := range deliveries {
+			result = append(result, delivery.Event)
+		}
+		return result
+	}
+
+	t.Run("CreateAndGet", func(t *testing.T) {
+		r := newRepo(t)
+		created := create(t, r, 1, "article.created", now)
+		if created.ID == 0 || created.CreatedAt.IsZero() {
+			t.Fatalf("Create: got ID %d and time %v, want both set", created.ID, created.CreatedAt)
+		}
+
+		delivery, err := r.Get(ctx, created.ID)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if delivery.Event != "article.created" || delivery.Payload != created.Payload || delivery.Status != types.DeliveryPending {
+			t.Errorf("Get: got %+v", delivery)
+		}
+
+		if _, err := r.Get(ctx, created.ID+100); !errors.Is(err, domain.ErrDeliveryNotFound) {
+			t.Errorf("Get of a missing delivery: got %v, want ErrDeliveryNotFound", err)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		r := newRepo(t)
+		create(t, r, 1, "a", now)
+		create(t, r, 2, "b", now)
+		failed := create(t, r, 1, "c", now)
+		failed.Status = types.DeliveryFailed
+		if err := r.Update(ctx, failed); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+
+		query := &types.WebhookDeliveryListQuery{PaginatedQuery: common.PaginatedQuery{Page: 1, PageSize: 10}}
+		result, err := r.List(ctx, 1, query)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if result.Total != 2 {
+			t.Errorf("List: got a total of %d, want 2", result.Total)
+		}
+		assertOrder(t, "List", events(result.List), "c", "a")
+
+		status := types.DeliveryFailed
+		query.Status = &status
+		result, err = r.List(ctx, 1, query)
+		if err != nil {
+			t.Fatalf("List by status: %v", err)
+		}
+		assertOrder(t, "List by status", events(result.List), "c")
+	})
+
+	t.Run("ClaimAndUpdate", func(t *testing.T) {
+		r := newRepo(t)
+		a := create(t, r, 1, "a", now.Add(-time.Minute))
+		create(t, r, 1, "b", now.Add(time.Minute))
+		c := create(t, r, 1, "c", now)
+
+		due, err := r.ListDue(ctx, now, 10)
+		if err != nil {
+			t.Fatalf("ListDue: %v", err)
+		}
+		assertOrder(t, "ListDue", events(due), "a", "c")
+
+		if claimed, err := r.Claim(ctx, a.ID, now, now.Add(time.Minute)); err != nil || !claimed {
+			t.Fatalf("Claim: got %v, %v, want claimed", claimed, err)
+		}
+		if claimed, err := r.Claim(ctx, a.ID, now, now.Add(time.Minute)); err != nil || claimed {
+			t.Errorf("Claim of a claimed delivery: got %v, %v, want not claimed", claimed, err)
+		}
+		if claimed, err := r.Claim(ctx, a.ID, now.Add(2*time.Minute), now.Add(3*time.Minute)); err != nil || !claimed {
+			t.Errorf("Claim of an expired claim: got %v, %v, want claimed", claimed, err)
+		}
+		due, _ = r.ListDue(ctx, now, 10)
+		assertOrder(t, "ListDue after Claim", events(due), "c")
+
+		code := 200
+		a.Status = types.DeliverySucceeded
+		a.Attempts = 1
+		a.NextAttemptAt = nil
+		a.ResponseCode = &code
+		a.DeliveredAt = &now
+		if err := r.Update(ctx, a); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		delivery, err := r.Get(ctx, a.ID)
+		if err != nil || delivery.Status != types.DeliverySucceeded || delivery.Attempts != 1 ||
+			delivery.NextAttemptAt != nil || delivery.LockedUntil != nil || delivery.ResponseCode == nil || *delivery.ResponseCode != 200 {
+			t.Fatalf("Get after Update: got %+v, %v", delivery, err)
+		}
+		if claimed, _ := r.Claim(ctx, a.ID, now, now.Add(time.Minute)); claimed {
+			t.Errorf("Claim of a finished delivery: got claimed")
+		}
+
+		if n, err := r.FailPending(ctx, 1, "disabled"); err != nil || n != 2 {
+			t.Fatalf("FailPending: got %d, %v, want 2", n, err)
+		}
+		delivery, _ = r.Get(ctx, c.ID)
+		if delivery.Status != types.DeliveryFailed || delivery.Error == nil || *delivery.Error != "disabled" || delivery.NextAttemptAt != nil {
+			t.Errorf("Get after FailPending: got %+v", delivery)
+		}
+		if due, _ := r.ListDue(ctx, now.Add(time.Hour), 10); len(due) != 0 {
+			t.Errorf("ListDue after FailPending: got %v, want none", events(due))
+		}
+	})
+
+	t.Run("DeleteByWebhook", func(t *testing.T) {
+		r := newRepo(t)
+		create(t, r, 1, "a", now)
+		create(t, r, 1, "b", now)
+		b := create(t, r, 2, "c", now)
+
+		if n, err := r.DeleteByWebhook(ctx, 1); err != nil || n != 2 {
+			t.Fatalf("DeleteByWebhook: got %d, %v, want 2", n, err)
+		}
+		if _, err := r.Get(ctx, b.ID); err != nil {
+			t.Errorf("Get of a delivery of another webhook: %v", err)
+		}
+	})
+}
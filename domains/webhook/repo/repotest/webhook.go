@@ -0,0 +1,144 @@
+// Package repotest is the contract the implementations of the webhook and
+// delivery repositories must fulfil, which is run against each of them by their
+// tests.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	domain "bilingo/domains/webhook"
+	"bilingo/domains/webhook/models"
+	"bilingo/domains/webhook/repo"
+)
+
+// TestWebhookRepo tests the webhook repository against the contract.
+func TestWebhookRepo(t *testing.T, newRepo func(t *testing.T) repo.IWebhookRepo) {
+	ctx := context.Background()
+
+	create := func(t *testing.T, r repo.IWebhookRepo, url string, enabled bool, events ...string) *models.Webhook {
+		t.Helper()
+		webhook := &models.Webhook{URL: url, Secret: "secret-of-" + url, Events: events, Enabled: enabled}
+		if err := r.Create(ctx, webhook); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		return webhook
+	}
+
+	urls := func(webhooks []models.Webhook) []string {
+		var result []string
+		for _, webhook := range webhooks {
+			result = append(result, webhook.URL)
+		}
+		return result
+	}
+
+	t.Run("CreateAndGet", func(t *testing.T) {
+		r := newRepo(t)
+		created := create(t, r, "http://a.test/hook", true, "article.created", "comment.created")
+		if created.ID == 0 || created.CreatedAt.IsZero() {
+			t.Fatalf("Create: got ID %d and time %v, want both set", created.ID, created.CreatedAt)
+		}
+
+		webhook, err := r.Get(ctx, created.ID)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if webhook.URL != created.URL || webhook.Secret != created.Secret || !webhook.Enabled ||
+			!slices.Equal(webhook.Events, []string{"article.created", "comment.created"}) {
+			t.Errorf("Get: got %+v", webhook)
+		}
+
+		if _, err := r.Get(ctx, created.ID+100); !errors.Is(err, domain.ErrWebhookNotFound) {
+			t.Errorf("Get of a missing webhook: got %v, want ErrWebhookNotFound", err)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		r := newRepo(t)
+		create(t, r, "http://a.test", true, "*")
+		create(t, r, "http://b.test", false, "*")
+		create(t, r, "http://c.test", true, "*")
+
+		webhooks, err := r.List(ctx)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		assertOrder(t, "List", urls(webhooks), "http://a.test", "http://b.test", "http://c.test")
+
+		webhooks, err = r.ListEnabled(ctx)
+		if err != nil {
+			t.Fatalf("ListEnabled: %v", err)
+		}
+		assertOrder(t, "ListEnabled", urls(webhooks), "http://a.test", "http://c.test")
+	})
+
+	t.Run("UpdateAndDelete", func(t *testing.T) {
+		r := newRepo(t)
+		webhook := create(t, r, "http://a.test", true, "article.created")
+
+		reason := "failing"
+		webhook.URL = "http://b.test"
+		webhook.Events = []string{"*"}
+		webhook.Enabled = false
+		webhook.DisabledReason = &reason
+		if err := r.Update(ctx, webhook); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		updated, err := r.Get(ctx, webhook.ID)
+		if err != nil || updated.URL != "http://b.test" || updated.Enabled || !slices.Equal(updated.Events, []string{"*"}) ||
+			updated.DisabledReason == nil || *updated.DisabledReason != reason {
+			t.Fatalf("Get after Update: got %+v, %v", updated, err)
+		}
+
+		if err := r.Delete(ctx, webhook.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if err := r.Delete(ctx, webhook.ID); !errors.Is(err, domain.ErrWebhookNotFound) {
+			t.Errorf("Delete of a missing webhook: got %v, want ErrWebhookNotFound", err)
+		}
+		if err := r.Update(ctx, webhook); !errors.Is(err, domain.ErrWebhookNotFound) {
+			t.Errorf("Update of a missing webhook: got %v, want ErrWebhookNotFound", err)
+		}
+	})
+
+	t.Run("RecordDelivery", func(t *testing.T) {
+		r := newRepo(t)
+		webhook := create(t, r, "http://a.test", true, "*")
+
+		for i := range 2 {
+			if disabled, err := r.RecordDelivery(ctx, webhook.ID, false, 3, "failing"); err != nil || disabled {
+				t.Fatalf("RecordDelivery of failure %d: got %v, %v, want not disabled", i+1, disabled, err)
+			}
+		}
+		if _, err := r.RecordDelivery(ctx, webhook.ID, true, 3, "failing"); err != nil {
+			t.Fatalf("RecordDelivery of success: %v", err)
+		}
+		if got, _ := r.Get(ctx, webhook.ID); got.Failures != 0 {
+			t.Fatalf("Failures after success: got %d, want 0", got.Failures)
+		}
+
+		for i := range 3 {
+			disabled, err := r.RecordDelivery(ctx, webhook.ID, false, 3, "failing")
+			if err != nil || disabled != (i == 2) {
+				t.Fatalf("RecordDelivery of failure %d: got %v, %v", i+1, disabled, err)
+			}
+		}
+		got, _ := r.Get(ctx, webhook.ID)
+		if got.Enabled || got.Failures != 3 || got.DisabledReason == nil || *got.DisabledReason != "failing" {
+			t.Errorf("Get after disabling: got %+v", got)
+		}
+		if disabled, _ := r.RecordDelivery(ctx, webhook.ID, false, 3, "failing"); disabled {
+			t.Errorf("RecordDelivery of a disabled webhook: got disabled again")
+		}
+	})
+}
+
+func assertOrder(t *testing.T, name string, got []string, want ...string) {
+	t.Helper()
+	if !slices.Equal(got, want) {
+		t.Errorf("%s: got %v, want %v", name, got, want)
+	}
+}
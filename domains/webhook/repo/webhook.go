@@ -0,0 +1,32 @@
+package repo
+
+import (
+	"context"
+
+	"bilingo/domains/webhook/models"
+	impl "bilingo/domains/webhook/repo/db"
+	"bilingo/server/app"
+)
+
+// Webhooks returns the webhook repository of the app the context carries, or the
+// database implementation outside of apps.
+func Webhooks(ctx context.Context) IWebhookRepo {
+	return app.Resolve[IWebhookRepo](ctx, &impl.WebhookRepo{})
+}
+
+type IWebhookRepo interface {
+	Get(ctx context.Context, id uint) (*models.Webhook, error)
+	// List returns all webhooks in the order of creation.
+	List(ctx context.Context) ([]models.Webhook, error)
+	// ListEnabled returns the enabled webhooks in the order of creation.
+	ListEnabled(ctx context.Context) ([]models.Webhook, error)
+	Create(ctx context.Context, webhook *models.Webhook) error
+	// Update saves all fields of the webhook.
+	Update(ctx context.Context, webhook *models.Webhook) error
+	Delete(ctx context.Context, id uint) error
+	// RecordDelivery resets the failures of the webhook if the delivery
+	// succeeded, or counts the failure otherwise, and disables the webhook for
+	// the reason once it has failed disableAfter deliveries in a row. It returns
+	// whether the webhook was disabled by this failure.
+	RecordDelivery(ctx context.Context, id uint, succeeded bool, disableAfter int, reason string) (bool, error)
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"bilingo/common"
+	domain "bilingo/domains/webhook"
+	"bilingo/domains/webhook/models"
+	"bilingo/domains/webhook/repo"
+	"bilingo/domains/webhook/types"
+	"bilingo/server/app"
+	"bilingo/server/db"
+
+	"github.com/google/uuid"
+)
+
+// The headers of the requests of deliveries
+const (
+	HeaderEvent     = "X-Webhook-Event"     // The name of the event
+	HeaderEventId   = "X-Webhook-Id"        // The ID of the event, the same for redeliveries
+	HeaderDelivery  = "X-Webhook-Delivery"  // The ID of the delivery
+	HeaderTimestamp = "X-Webhook-Timestamp" // The Unix time the request was signed at
+	HeaderSignature = "X-Webhook-Signature" // The signature, see Sign
+)
+
+// PingEvent is sent to webhooks by PingWebhook to check their endpoints, it
+// can't be subscribed to.
+const PingEvent = "ping"
+
+// Sign returns the signature of the body of a delivery sent at the timestamp,
+// which is `sha256=` followed by the hex-encoded HMAC-SHA256 of the timestamp,
+// a dot and the body, keyed with the secret of the webhook. Receivers compute
+// it the same way and compare, and should reject old timestamps to prevent
+// replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature reports whether the signature of the body is valid, see
+// Sign, for receivers written in Go.
+func VerifySignature(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+func GetDelivery(ctx context.Context, id uint) (*models.WebhookDelivery, error) {
+	return repo.Deliveries(ctx).Get(ctx, id)
+}
+
+func ListDeliveries(
+	ctx context.Context,
+	webhookId uint,
+	query types.WebhookDeliveryListQuery,
+) (*common.PaginatedResult[models.WebhookDelivery], error) {
+	return repo.Deliveries(ctx).List(ctx, webhookId, &query)
+}
+
+// Redeliver sends the event of the delivery to its webhook again as a new
+// delivery, with the same event ID, e.g. after the receiver was fixed.
+func Redeliver(ctx context.Context, id uint) (*models.WebhookDelivery, error) {
+	original, err := repo.Deliveries(ctx).Get(db.UsePrimary(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+	webhook, err := repo.Webhooks(ctx).Get(db.UsePrimary(ctx), original.WebhookID)
+	if err != nil {
+		return nil, err
+	} else if !webhook.Enabled {
+		return nil, domain.ErrWebhookDisabled
+	}
+
+	delivery := newDelivery(webhook.ID, original.EventID, original.Event, original.Payload)
+	delivery.RedeliveryOf = &original.ID
+	if err := repo.Deliveries(ctx).Create(ctx, delivery); err != nil {
+		return nil, err
+	}
+
+	notify(ctx)
+	return delivery, nil
+}
+
+// PingWebhook sends a ping event to the webhook to check its endpoint.
+func PingWebhook(ctx context.Context, id uint) (*models.WebhookDelivery, error) {
+	webhook, err := repo.Webhooks(ctx).Get(db.UsePrimary(ctx), id)
+	if err != nil {
+		return nil, err
+	} else if !webhook.Enabled {
+		return nil, domain.ErrWebhookDisabled
+	}
+
+	eventId := uuid.NewString()
+	payload, err := encodePayload(eventId, PingEvent, map[string]uint{"webhook_id": webhook.ID})
+	if err != nil {
+		return nil, err
+	}
+	delivery := newDelivery(webhook.ID, eventId, PingEvent, payload)
+	if err := repo.Deliveries(ctx).Create(ctx, delivery); err != nil {
+		return nil, err
+	}
+
+	notify(ctx)
+	return delivery, nil
+}
+
+// enqueue creates the deliveries of the event to the enabled webhooks
+// subscribed to it, which are sent after the transaction of the context is
+// committed.
+func enqueue(ctx context.Context, name string, data any) error {
+	webhooks, err := repo.Webhooks(ctx).ListEnabled(ctx)
+	if err != nil {
+		return err
+	}
+	webhooks = slices.DeleteFunc(webhooks, func(webhook models.Webhook) bool {
+		return !slices.Contains(webhook.Events, name) && !slices.Contains(webhook.Events, types.AllEvents)
+	})
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	eventId := uuid.NewString()
+	payload, err := encodePayload(eventId, name, data)
+	if err != nil {
+		return err
+	}
+	for _, webhook := range webhooks {
+		if err := repo.Deliveries(ctx).Create(ctx, newDelivery(webhook.ID, eventId, name, payload)); err != nil {
+			return err
+		}
+	}
+
+	db.AfterCommit(ctx, func() { notify(ctx) })
+	return nil
+}
+
+func encodePayload(eventId string, name string, data any) (string, error) {
+	payload, err := json.Marshal(types.WebhookPayload{
+		ID:        eventId,
+		Event:     name,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	return string(payload), nil
+}
+
+func newDelivery(webhookId uint, eventId string, name string, payload string) *models.WebhookDelivery {
+	now := time.Now()
+	return &models.WebhookDelivery{
+		WebhookID:     webhookId,
+		EventID:       eventId,
+		Event:         name,
+		Payload:       payload,
+		Status:        types.DeliveryPending,
+		NextAttemptAt: &now,
+	}
+}
+
+// notify wakes the dispatcher of the app the context carries up, if any.
+func notify(ctx context.Context) {
+	if d := app.Resolve[*Dispatcher](ctx, nil); d != nil {
+		d.poller.Notify()
+	}
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bilingo/config"
+	domain "bilingo/domains/webhook"
+	"bilingo/domains/webhook/models"
+	"bilingo/domains/webhook/repo"
+	"bilingo/domains/webhook/types"
+	"bilingo/server/poller"
+)
+
+const (
+	dispatchBatchSize = 100
+	responseBodyLimit = 1024 // The length of the beginning of response bodies kept in deliveries
+)
+
+// Dispatcher sends the due deliveries in the background, an app has a
+// dispatcher of its own. Other instances of the server may send the deliveries
+// of the same database, each delivery is claimed by one of them.
+type Dispatcher struct {
+	cfg    config.WebhookConfig
+	client *http.Client
+	poller *poller.Poller
+}
+
+// NewDispatcher creates a dispatcher sending the deliveries in the context,
+// which carries the app whose deliveries they are.
+func NewDispatcher(ctx context.Context, cfg config.WebhookConfig) *Dispatcher {
+	d := &Dispatcher{
+		cfg: cfg,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			// Redirects count as failures, the URL of the webhook should be
+			// changed instead
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+	d.poller = poller.New(ctx, "webhook deliveries", cfg.PollInterval, dispatchBatchSize, d.dispatch)
+	return d
+}
+
+// Start starts sending the due deliveries in the background.
+func (d *Dispatcher) Start() {
+	d.poller.Start()
+}
+
+// Shutdown stops sending deliveries and waits for the ones being sent, or
+// until the context is done. The deliveries left are sent after the next start.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	return d.poller.Shutdown(ctx)
+}
+
+// dispatch claims a batch of the due deliveries and sends them, and returns the
+// number of due deliveries found.
+func (d *Dispatcher) dispatch(ctx context.Context) (int, error) {
+	now := time.Now()
+	due, err := repo.Deliveries(ctx).ListDue(ctx, now, dispatchBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	// Deliveries whose requests outlive the lease are claimed again
+	lease := now.Add(2 * d.cfg.Timeout)
+	sem := make(chan struct{}, d.cfg.Concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for _, delivery := range due {
+		claimed, err := repo.Deliveries(ctx).Claim(ctx, delivery.ID, now, lease)
+		if err != nil {
+			return len(due), err
+		} else if !claimed {
+			continue
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			d.deliver(ctx, delivery)
+		}()
+	}
+	return len(due), nil
+}
+
+// deliver sends the delivery to its webhook, and records the outcome, which
+// counts towards disabling the webhook once the delivery is finished.
+func (d *Dispatcher) deliver(ctx context.Context, delivery models.WebhookDelivery) {
+	// The outcome is recorded even while shutting down
+	ctx = context.WithoutCancel(ctx)
+	webhook, err := repo.Webhooks(ctx).Get(ctx, delivery.WebhookID)
+	if err == nil && !webhook.Enabled {
+		err = domain.ErrWebhookDisabled
+	}
+	if errors.Is(err, domain.ErrWebhookNotFound) || errors.Is(err, domain.ErrWebhookDisabled) {
+		msg := err.Error()
+		delivery.Status = types.DeliveryFailed
+		delivery.NextAttemptAt = nil
+		delivery.Error = &msg
+		if err := repo.Deliveries(ctx).Update(ctx, &delivery); err != nil {
+			log.Printf("failed to update webhook delivery %d: %v", delivery.ID, err)
+		}
+		return
+	} else if err != nil {
+		// The delivery is claimed again once the lease expires
+		log.Printf("failed to get webhook of delivery %d: %v", delivery.ID, err)
+		return
+	}
+
+	start := time.Now()
+	code, body, sendErr := d.send(ctx, webhook, &delivery)
+	now := time.Now()
+	delivery.Attempts++
+	delivery.Duration = now.Sub(start).Milliseconds()
+	delivery.ResponseCode = code
+	delivery.ResponseBody = body
+	delivery.Error = nil
+	switch {
+	case sendErr == nil:
+		delivery.Status = types.DeliverySucceeded
+		delivery.NextAttemptAt = nil
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= d.cfg.MaxAttempts:
+		msg := sendErr.Error()
+		delivery.Status = types.DeliveryFailed
+		delivery.NextAttemptAt = nil
+		delivery.Error = &msg
+	default:
+		msg := sendErr.Error()
+		next := now.Add(poller.Backoff(delivery.Attempts, d.cfg.RetryDelay, d.cfg.MaxRetryDelay))
+		delivery.NextAttemptAt = &next
+		delivery.Error = &msg
+	}
+	if err := repo.Deliveries(ctx).Update(ctx, &delivery); err != nil {
+		log.Printf("failed to update webhook delivery %d: %v", delivery.ID, err)
+	}
+	if delivery.Status == types.DeliveryPending {
+		return
+	}
+
+	reason := fmt.Sprintf("%d deliveries in a row failed", d.cfg.DisableAfter)
+	succeeded := delivery.Status == types.DeliverySucceeded
+	disabled, err := repo.Webhooks(ctx).RecordDelivery(ctx, webhook.ID, succeeded, d.cfg.DisableAfter, reason)
+	if err != nil {
+		log.Printf("failed to record delivery of webhook %d: %v", webhook.ID, err)
+	} else if disabled {
+		log.Printf("webhook %d is disabled: %s", webhook.ID, reason)
+		if _, err := repo.Deliveries(ctx).FailPending(ctx, webhook.ID, domain.ErrWebhookDisabled.Error()); err != nil {
+			log.Printf("failed to fail pending deliveries of webhook %d: %v", webhook.ID, err)
+		}
+	}
+}
+
+// send posts the payload of the delivery to the webhook, and returns the status
+// code and the beginning of the body of the response if any. Responses other
+// than 2xx are errors.
+func (d *Dispatcher) send(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) (*int, *string, error) {
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Bilingo-Webhook")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderEventId, delivery.EventID)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	// Kept as text, whatever the receiver sent
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, responseBodyLimit))
+	text := strings.ToValidUTF8(string(data), "�")
+	code := resp.StatusCode
+	if code < 200 || code >= 300 {
+		return &code, &text, fmt.Errorf("unexpected status %d", code)
+	}
+	return &code, &text, nil
+}
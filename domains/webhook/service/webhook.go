@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"slices"
+
+	articleDomain "bilingo/domains/article"
+	systemDomain "bilingo/domains/system"
+	userDomain "bilingo/domains/user"
+	domain "bilingo/domains/webhook"
+	"bilingo/domains/webhook/models"
+	"bilingo/domains/webhook/repo"
+	"bilingo/domains/webhook/types"
+	"bilingo/server/db"
+	"bilingo/server/events"
+)
+
+// eventType is an event of the domains webhooks can subscribe to.
+type eventType struct {
+	name      string
+	subscribe func(bus *events.Bus)
+}
+
+func eventOf[T events.Event]() eventType {
+	var zero T
+	return eventType{
+		name: zero.EventName(),
+		subscribe: func(bus *events.Bus) {
+			events.Subscribe(bus, "webhook.enqueue", func(ctx context.Context, event T) error {
+				// All the deliveries of the event are created or none, so
+				// retries don't duplicate them
+				return db.Transaction(ctx, domain.DBBinding, func(ctx context.Context) error {
+					return enqueue(ctx, event.EventName(), event)
+				})
+			})
+		},
+	}
+}
+
+// The events webhooks can subscribe to, the fields of which are delivered as
+// they are, so they must stay compatible
+var eventTypes = []eventType{
+	eventOf[articleDomain.ArticleCreated](),
+	eventOf[articleDomain.ArticleUpdated](),
+	eventOf[articleDomain.ArticleDeleted](),
+	eventOf[articleDomain.ArticleReacted](),
+	eventOf[systemDomain.CommentCreated](),
+	eventOf[systemDomain.CommentUpdated](),
+	eventOf[systemDomain.CommentDeleted](),
+	eventOf[userDomain.UserDeleted](),
+}
+
+// EventNames returns the names of the events webhooks can subscribe to.
+func EventNames() []string {
+	names := make([]string, len(eventTypes))
+	for i, t := range eventTypes {
+		names[i] = t.name
+	}
+	return names
+}
+
+// Subscribe subscribes the webhooks to the events of the bus, the deliveries of
+// an event are created from the outbox once the transaction it's published in
+// is committed, so publishers don't wait for them.
+func Subscribe(bus *events.Bus) {
+	for _, t := range eventTypes {
+		t.subscribe(bus)
+	}
+}
+
+func GetWebhook(ctx context.Context, id uint) (*models.Webhook, error) {
+	return repo.Webhooks(ctx).Get(ctx, id)
+}
+
+func ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	return repo.Webhooks(ctx).List(ctx)
+}
+
+// CreateWebhook creates an enabled webhook, and returns it along with the
+// secret, which is generated unless given.
+func CreateWebhook(ctx context.Context, data *types.WebhookCreate) (*models.WebhookWithSecret, error) {
+	if err := validateUrl(data.URL); err != nil {
+		return nil, err
+	}
+	eventNames, err := validateEvents(data.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	var secret string
+	if data.Secret != nil {
+		if len(*data.Secret) < 16 || len(*data.Secret) > 128 {
+			return nil, fmt.Errorf("%w: the secret must be 16 to 128 characters", domain.ErrInvalidWebhook)
+		}
+		secret = *data.Secret
+	} else if secret, err = generateSecret(); err != nil {
+		return nil, err
+	}
+
+	webhook := &models.Webhook{
+		URL:         data.URL,
+		Secret:      secret,
+		Events:      eventNames,
+		Description: data.Description,
+		Enabled:     true,
+	}
+	if err := repo.Webhooks(ctx).Create(ctx, webhook); err != nil {
+		return nil, err
+	}
+
+	return &models.WebhookWithSecret{Webhook: *webhook, Secret: secret}, nil
+}
+
+func UpdateWebhook(ctx context.Context, id uint, data *types.WebhookUpdate) (*models.Webhook, error) {
+	webhook, err := repo.Webhooks(ctx).Get(db.UsePrimary(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.URL != nil {
+		if err := validateUrl(*data.URL); err != nil {
+			return nil, err
+		}
+		webhook.URL = *data.URL
+	}
+	if data.Events != nil {
+		if webhook.Events, err = validateEvents(data.Events); err != nil {
+			return nil, err
+		}
+	}
+	if data.Description != nil {
+		webhook.Description = data.Description
+	}
+	if data.Enabled != nil && *data.Enabled != webhook.Enabled {
+		webhook.Enabled = *data.Enabled
+		webhook.Failures = 0
+		webhook.DisabledReason = nil
+	}
+
+	if err := repo.Webhooks(ctx).Update(ctx, webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// RotateWebhookSecret replaces the secret of the webhook with a new one, the
+// pending deliveries are signed with the new secret.
+func RotateWebhookSecret(ctx context.Context, id uint) (*models.WebhookWithSecret, error) {
+	webhook, err := repo.Webhooks(ctx).Get(db.UsePrimary(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+
+	if webhook.Secret, err = generateSecret(); err != nil {
+		return nil, err
+	}
+	if err := repo.Webhooks(ctx).Update(ctx, webhook); err != nil {
+		return nil, err
+	}
+
+	return &models.WebhookWithSecret{Webhook: *webhook, Secret: webhook.Secret}, nil
+}
+
+// DeleteWebhook deletes the webhook along with its deliveries.
+func DeleteWebhook(ctx context.Context, id uint) error {
+	return db.Transaction(ctx, domain.DBBinding, func(ctx context.Context) error {
+		if _, err := repo.Deliveries(ctx).DeleteByWebhook(ctx, id); err != nil {
+			return err
+		}
+		return repo.Webhooks(ctx).Delete(ctx, id)
+	})
+}
+
+// validateUrl checks that the URL of a webhook is an absolute HTTP(S) URL.
+func validateUrl(rawUrl string) error {
+	u, err := url.Parse(rawUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: the URL must be an absolute HTTP or HTTPS URL", domain.ErrInvalidWebhook)
+	}
+	if len(rawUrl) > 2048 {
+		return fmt.Errorf("%w: the URL is too long", domain.ErrInvalidWebhook)
+	}
+	return nil
+}
+
+// validateEvents checks that the events of a webhook are known, and returns
+// them without duplicates.
+func validateEvents(eventNames []string) ([]string, error) {
+	if len(eventNames) == 0 {
+		return nil, fmt.Errorf("%w: no events", domain.ErrInvalidWebhook)
+	}
+
+	known := EventNames()
+	var result []string
+	for _, name := range eventNames {
+		if name != types.AllEvents && !slices.Contains(known, name) {
+			return nil, fmt.Errorf("%w: unknown event %s", domain.ErrInvalidWebhook, name)
+		}
+		if !slices.Contains(result, name) {
+			result = append(result, name)
+		}
+	}
+	return result, nil
+}
+
+func generateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,46 @@
+package types
+
+import (
+	"time"
+
+	"bilingo/common"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed" // Every attempt failed, or the webhook was disabled
+)
+
+// AllEvents subscribes a webhook to all events, including those added later.
+const AllEvents = "*"
+
+type WebhookCreate struct {
+	URL         string   `json:"url" validate:"required,url,max=2048"`
+	Events      []string `json:"events" validate:"required,min=1"`
+	Description *string  `json:"description" validate:"omitempty,max=255"`
+	// The key of the signatures, generated if not given
+	Secret *string `json:"secret" validate:"omitempty,min=16,max=128"`
+}
+
+type WebhookUpdate struct {
+	URL         *string  `json:"url" validate:"omitempty,url,max=2048"`
+	Events      []string `json:"events" validate:"omitempty,min=1"`
+	Description *string  `json:"description" validate:"omitempty,max=255"`
+	// Enabling a disabled webhook resets its failures, the deliveries failed
+	// while it was disabled can be redelivered by hand
+	Enabled *bool `json:"enabled"`
+}
+
+type WebhookDeliveryListQuery struct {
+	common.PaginatedQuery `tstype:",extends"`
+	Status                *string `json:"status" query:"status" validate:"omitempty,oneof=pending succeeded failed"`
+}
+
+// WebhookPayload is the JSON body of the requests of deliveries.
+type WebhookPayload struct {
+	ID        string    `json:"id"`    // The ID of the event, the same for redeliveries
+	Event     string    `json:"event"` // The name of the event, e.g. `article.created`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"` // The event, whose fields depend on the name
+}
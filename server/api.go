@@ -7,17 +7,15 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-var Api = fiber.New(fiber.Config{
-	Immutable: true,
-})
-
-func NewApiEntry(path string, handlers ...fiber.Handler) fiber.Router {
+// NewApiEntry adds a group of routes at the path to the API of an app, see
+// app.App.Group.
+func NewApiEntry(api fiber.Router, path string, handlers ...fiber.Handler) fiber.Router {
 	// Prepend timing.UseTiming middleware to all handlers
 	allHandlers := make([]fiber.Handler, 0, len(handlers)+2)
 	allHandlers = append(allHandlers, ipMiddleware, timing.UseTiming)
 	allHandlers = append(allHandlers, handlers...)
 
-	return Api.Group(path, allHandlers...)
+	return api.Group(path, allHandlers...)
 }
 
 func Success[T any](ctx *fiber.Ctx, data T, message ...string) error {
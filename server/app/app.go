@@ -0,0 +1,182 @@
+// Package app assembles the application from its modules. An App owns the
+// configuration, the services provided by the modules, e.g. the databases and
+// the repositories, and the HTTP server. Apps are isolated from each other, so
+// tests can run several of them in one process.
+//
+// Requests handled by an app carry it in their contexts, through which the
+// packages resolve the services of the app, falling back to their defaults
+// outside of apps, e.g. in the commands.
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"bilingo/config"
+	"bilingo/server"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Module adds a part of the application to the app, e.g. a domain registers its
+// repositories, routes and lifecycle hooks.
+type Module func(a *App) error
+
+// Hook is run when the app starts or stops, the context carries the app.
+type Hook func(ctx context.Context) error
+
+type App struct {
+	Config config.Config
+	Fiber  *fiber.App // The HTTP server, with Api mounted at /api
+	Api    *fiber.App // The routes added with Group
+
+	mu       sync.RWMutex
+	services map[reflect.Type]any
+	onStart  []Hook
+	onStop   []Hook
+	started  bool
+}
+
+type contextKey struct{}
+
+// New creates an app of the configuration, without any modules.
+func New(cfg config.Config) *App {
+	a := &App{
+		Config: cfg,
+		Fiber: fiber.New(fiber.Config{
+			AppName:   cfg.AppName,
+			Immutable: true,
+			// Leaves room for the uploads of attachments and 5 MiB avatars
+			BodyLimit: int(max(cfg.Attachment.MaxSize, 5<<20)) + 1<<20,
+		}),
+		Api:      fiber.New(fiber.Config{Immutable: true}),
+		services: map[reflect.Type]any{},
+	}
+	a.Fiber.Mount("/api", a.Api)
+	return a
+}
+
+// Register adds the modules to the app in order, the modules depending on the
+// services of others must come after them.
+func (a *App) Register(modules ...Module) error {
+	for _, module := range modules {
+		if err := module(a); err != nil {
+			return fmt.Errorf("failed to register module: %w", err)
+		}
+	}
+	return nil
+}
+
+// Context returns a context carrying the app, for the work done outside of
+// requests, e.g. in the background.
+func (a *App) Context(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, a)
+}
+
+// FromContext returns the app the context carries, or nil.
+func FromContext(ctx context.Context) *App {
+	a, _ := ctx.Value(contextKey{}).(*App)
+	return a
+}
+
+// Config returns the configuration of the app the context carries, or the one
+// of the environment outside of apps.
+func Config(ctx context.Context) config.Config {
+	if a := FromContext(ctx); a != nil {
+		return a.Config
+	}
+	return config.GetConfig()
+}
+
+// Provide makes the service available as T in the app, replacing the one
+// provided before, e.g. with a fake in tests.
+func Provide[T any](a *App, service T) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.services[reflect.TypeFor[T]()] = service
+}
+
+// Get returns the service provided as T in the app.
+func Get[T any](a *App) (T, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	service, ok := a.services[reflect.TypeFor[T]()].(T)
+	return service, ok
+}
+
+// Resolve returns the service provided as T in the app the context carries, or
+// the fallback outside of apps or if the app has none.
+func Resolve[T any](ctx context.Context, fallback T) T {
+	if a := FromContext(ctx); a != nil {
+		if service, ok := Get[T](a); ok {
+			return service
+		}
+	}
+	return fallback
+}
+
+// Group adds a group of routes at the path under /api, the requests to which
+// carry the app in their user contexts.
+func (a *App) Group(path string, handlers ...fiber.Handler) fiber.Router {
+	return server.NewApiEntry(a.Api, path, append([]fiber.Handler{a.useApp}, handlers...)...)
+}
+
+func (a *App) useApp(ctx *fiber.Ctx) error {
+	ctx.SetUserContext(a.Context(ctx.UserContext()))
+	return ctx.Next()
+}
+
+// OnStart adds a hook run by Start, after the ones added before.
+func (a *App) OnStart(hook Hook) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.onStart = append(a.onStart, hook)
+}
+
+// OnStop adds a hook run by Stop, before the ones added before, so that the
+// modules stop in the reverse order of starting.
+func (a *App) OnStop(hook Hook) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.onStop = append(a.onStop, hook)
+}
+
+// Start runs the start hooks in order, stopping at the first error. The app
+// can be stopped either way, which runs the stop hooks of all modules.
+func (a *App) Start(ctx context.Context) error {
+	a.mu.Lock()
+	if a.started {
+		a.mu.Unlock()
+		return errors.New("app already started")
+	}
+	a.started = true
+	hooks := a.onStart
+	a.mu.Unlock()
+
+	ctx = a.Context(ctx)
+	for _, hook := range hooks {
+		if err := hook(ctx); err != nil {
+			return fmt.Errorf("failed to start app: %w", err)
+		}
+	}
+	return nil
+}
+
+// Stop shuts down the HTTP server and runs the stop hooks in the reverse order,
+// all of them even if some fail, or until the context is done.
+func (a *App) Stop(ctx context.Context) error {
+	a.mu.Lock()
+	hooks := a.onStop
+	a.onStop = nil
+	a.mu.Unlock()
+
+	errs := []error{a.Fiber.ShutdownWithContext(ctx)}
+	ctx = a.Context(ctx)
+	for i := len(hooks) - 1; i >= 0; i-- {
+		errs = append(errs, hooks[i](ctx))
+	}
+	return errors.Join(errs...)
+}
@@ -3,12 +3,13 @@ package auth
 import (
 	"context"
 	"errors"
+	"slices"
 	"time"
 
 	"bilingo/common"
-	"bilingo/config"
 	"bilingo/domains/user/models"
 	"bilingo/domains/user/repo"
+	"bilingo/server/app"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
@@ -17,32 +18,39 @@ import (
 var (
 	ErrUnauthorized = errors.New("unauthorized")
 	ErrForbidden    = errors.New("forbidden")
+	ErrInvalidToken = errors.New("invalid or expired token")
 )
 
-var authSecret []byte
+// Purposes of the tokens sent to users, a token is only accepted for the
+// purpose it was generated for, and never as an authentication token.
+const (
+	PurposeVerifyEmail = "verify_email"
+	PurposeInvite      = "invite"
+	PurposeChangeEmail = "change_email"
+	PurposeLogin       = "login" // The challenge between the steps of two-factor login
+	PurposeSso         = "sso"   // The state kept between the steps of single sign-on
+)
 
 type contextKey string
 
 const userContextKey = contextKey("user")
 
-func init() {
-	cfg := config.GetConfig()
-	if cfg.Auth.Secret != "" {
-		authSecret = []byte(cfg.Auth.Secret)
-	}
+// secret returns the key tokens are signed with in the app the context carries.
+func secret(ctx context.Context) []byte {
+	return []byte(app.Config(ctx).Auth.Secret)
 }
 
-// GenerateToken generates a JWT token for the given email
-func GenerateToken(email string) (string, error) {
+// GenerateToken generates a JWT token for the user of the given ID
+func GenerateToken(ctx context.Context, userId string) (string, error) {
 	now := time.Now()
-	cfg := config.GetConfig()
+	cfg := app.Config(ctx)
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"email": email,
-		"iat":   now.Unix(),
-		"exp":   now.Add(cfg.Auth.Duration).Unix(),
+		"sub": userId,
+		"iat": now.Unix(),
+		"exp": now.Add(cfg.Auth.Duration).Unix(),
 	})
 
-	tokenString, err := token.SignedString(authSecret)
+	tokenString, err := token.SignedString(secret(ctx))
 	if err != nil {
 		return "", err
 	}
@@ -50,15 +58,87 @@ func GenerateToken(email string) (string, error) {
 	return tokenString, nil
 }
 
+// GeneratePurposeToken generates a signed token for the subject, e.g. an email
+// or a user ID, which is only valid for the purpose and within the duration.
+func GeneratePurposeToken(ctx context.Context, purpose string, subject string, duration time.Duration) (string, error) {
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":     subject,
+		"purpose": purpose,
+		"iat":     now.Unix(),
+		"exp":     now.Add(duration).Unix(),
+	})
+
+	return token.SignedString(secret(ctx))
+}
+
+// ParsePurposeToken validates a token generated by GeneratePurposeToken for the
+// purpose, and returns the subject it was generated for.
+func ParsePurposeToken(ctx context.Context, purpose string, tokenString string) (string, error) {
+	claims, ok := parseToken(ctx, tokenString)
+	if !ok {
+		return "", ErrInvalidToken
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		// Tokens issued before user IDs carry the email instead
+		subject, _ = claims["email"].(string)
+	}
+	if p, _ := claims["purpose"].(string); p != purpose || subject == "" {
+		return "", ErrInvalidToken
+	}
+
+	return subject, nil
+}
+
+// GeneratePurposeClaims generates a signed token carrying the claims instead of
+// an email, which is only valid for the purpose and within the duration.
+func GeneratePurposeClaims(ctx context.Context, purpose string, claims map[string]string, duration time.Duration) (string, error) {
+	now := time.Now()
+	mapClaims := jwt.MapClaims{
+		"purpose": purpose,
+		"iat":     now.Unix(),
+		"exp":     now.Add(duration).Unix(),
+	}
+	for key, value := range claims {
+		if _, ok := mapClaims[key]; !ok {
+			mapClaims[key] = value
+		}
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, mapClaims)
+	return token.SignedString(secret(ctx))
+}
+
+// ParsePurposeClaims validates a token generated by GeneratePurposeClaims for
+// the purpose, and returns the claims it carries.
+func ParsePurposeClaims(ctx context.Context, purpose string, tokenString string) (map[string]string, error) {
+	mapClaims, ok := parseToken(ctx, tokenString)
+	if !ok {
+		return nil, ErrInvalidToken
+	} else if p, _ := mapClaims["purpose"].(string); p != purpose {
+		return nil, ErrInvalidToken
+	}
+
+	claims := make(map[string]string, len(mapClaims))
+	for key, value := range mapClaims {
+		if str, ok := value.(string); ok && key != "purpose" {
+			claims[key] = str
+		}
+	}
+	return claims, nil
+}
+
 func UseAuth(ctx *fiber.Ctx) error {
 	// Extract and validate JWT token
-	email, ok := extractEmailFromToken(ctx)
+	userId, ok := extractUserIdFromToken(ctx)
 	if !ok {
 		return ctx.Next()
 	}
 
 	// Fetch user from database
-	user, err := repo.UserRepo.Get(ctx.UserContext(), email)
+	user, err := repo.Users(ctx.UserContext()).Get(ctx.UserContext(), userId)
 	if err != nil {
 		return ctx.Next()
 	}
@@ -68,35 +148,51 @@ func UseAuth(ctx *fiber.Ctx) error {
 	return ctx.Next()
 }
 
-func extractEmailFromToken(ctx *fiber.Ctx) (string, bool) {
-	cfg := config.GetConfig()
+func extractUserIdFromToken(ctx *fiber.Ctx) (string, bool) {
+	cfg := app.Config(ctx.UserContext())
 	tokenString := ctx.Cookies(cfg.Auth.CookieName)
 	if tokenString == "" {
 		return "", false
 	}
 
+	claims, ok := parseToken(ctx.UserContext(), tokenString)
+	if !ok {
+		return "", false
+	}
+
+	// Tokens generated for other purposes don't authenticate
+	if _, ok := claims["purpose"]; ok {
+		return "", false
+	}
+
+	// Sessions issued before user IDs carry the email, which may have been
+	// taken by another user since, so they're not accepted
+	userId, ok := claims["sub"].(string)
+	if !ok || userId == "" {
+		return "", false
+	}
+
+	return userId, true
+}
+
+func parseToken(ctx context.Context, tokenString string) (jwt.MapClaims, bool) {
 	token, err := jwt.ParseWithClaims(tokenString, &jwt.MapClaims{}, func(token *jwt.Token) (any, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("invalid signing method")
 		}
-		return authSecret, nil
+		return secret(ctx), nil
 	})
 
 	if err != nil || !token.Valid {
-		return "", false
+		return nil, false
 	}
 
 	claims, ok := token.Claims.(*jwt.MapClaims)
 	if !ok {
-		return "", false
-	}
-
-	email, ok := (*claims)["email"].(string)
-	if !ok || email == "" {
-		return "", false
+		return nil, false
 	}
 
-	return email, true
+	return *claims, true
 }
 
 func storeUserInContext(ctx *fiber.Ctx, user *models.User) {
@@ -111,6 +207,12 @@ func storeUserInContext(ctx *fiber.Ctx, user *models.User) {
 	ctx.SetUserContext(newCtx)
 }
 
+// WithUser returns a context carrying the user as the authenticated one, e.g.
+// for background work done on behalf of the user who requested it.
+func WithUser(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(ctx, userContextKey, user)
+}
+
 // GetUser retrieves the authenticated user from fiber context;
 // Pass ctx.UserContext() when calling this function;
 // Returns nil if user is not authenticated.
@@ -132,3 +234,34 @@ func RequireAuth(ctx *fiber.Ctx) error {
 	}
 	return ctx.Next()
 }
+
+// RequireAdmin middleware ensures user is authenticated and an admin
+func RequireAdmin(ctx *fiber.Ctx) error {
+	user := GetUser(ctx.UserContext())
+	if user == nil {
+		msg := ErrUnauthorized.Error()
+		return ctx.Status(401).JSON(common.ApiResult[any]{
+			Success: false,
+			Code:    401,
+			Message: &msg,
+		})
+	}
+	if !IsAdmin(ctx.UserContext(), user) {
+		msg := ErrForbidden.Error()
+		return ctx.Status(403).JSON(common.ApiResult[any]{
+			Success: false,
+			Code:    403,
+			Message: &msg,
+		})
+	}
+	return ctx.Next()
+}
+
+// IsAdmin reports whether the given user is granted administrative privileges
+// by the configuration of the app the context carries.
+func IsAdmin(ctx context.Context, user *models.User) bool {
+	if user == nil {
+		return false
+	}
+	return slices.Contains(app.Config(ctx).Auth.Admins, user.Email)
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ConnHealth is the state of a database connection, with the statistics of
+// its pool.
+type ConnHealth struct {
+	Name         string        `json:"name"` // The name of the database, with /replica-<n> for its replicas in the configured order
+	Replica      bool          `json:"replica"`
+	Ok           bool          `json:"ok"`
+	Error        string        `json:"error,omitempty"`
+	Latency      time.Duration `json:"latency"` // The time taken to ping the database, in nanoseconds
+	OpenConns    int           `json:"open_conns"`
+	InUse        int           `json:"in_use"`
+	Idle         int           `json:"idle"`
+	WaitCount    int64         `json:"wait_count"`     // The total number of waits for a connection
+	WaitDuration time.Duration `json:"wait_duration"`  // The total time waited for connections, in nanoseconds
+	MaxOpenConns int           `json:"max_open_conns"` // Zero means unlimited
+}
+
+// Health pings the databases of the app the context carries, or the ones of
+// the environment outside of apps, see Registry.Health.
+func Health(ctx context.Context, timeout time.Duration) []ConnHealth {
+	return FromContext(ctx).Health(ctx, timeout)
+}
+
+// Health pings the databases and their replicas, each within the timeout, the
+// default database comes first. Databases that fail to connect are reported
+// along with the others.
+func (r *Registry) Health(ctx context.Context, timeout time.Duration) []ConnHealth {
+	var result []ConnHealth
+	for _, name := range r.Names() {
+		conn, err := r.getConnection(name)
+		if err != nil {
+			result = append(result, ConnHealth{Name: name, Error: ConnError(err).Error()})
+			continue
+		}
+
+		result = append(result, checkConn(ctx, name, false, conn.primary, timeout))
+		for i, replica := range conn.replicas {
+			result = append(result, checkConn(ctx, fmt.Sprintf("%s/replica-%d", name, i+1), true, replica, timeout))
+		}
+	}
+	return result
+}
+
+func checkConn(ctx context.Context, name string, replica bool, conn *gorm.DB, timeout time.Duration) ConnHealth {
+	health := ConnHealth{Name: name, Replica: replica}
+	sqlDB, err := conn.DB()
+	if err != nil {
+		health.Error = err.Error()
+		return health
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	err = sqlDB.PingContext(ctx)
+	health.Latency = time.Since(start)
+	if err != nil {
+		health.Error = err.Error()
+	} else {
+		health.Ok = true
+	}
+
+	stats := sqlDB.Stats()
+	health.OpenConns = stats.OpenConnections
+	health.InUse = stats.InUse
+	health.Idle = stats.Idle
+	health.WaitCount = stats.WaitCount
+	health.WaitDuration = stats.WaitDuration
+	health.MaxOpenConns = stats.MaxOpenConnections
+	return health
+}
@@ -0,0 +1,179 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is a schema or data change applied once to a database.
+type Migration struct {
+	// ID identifies the migration, migrations are applied in the order of their
+	// IDs, so they should be prefixed with a sortable timestamp, e.g.
+	// `2026101900_user_create_table`.
+	ID string
+	Up func(tx *gorm.DB) error
+	// The binding of the tables the migration changes, which decides the
+	// database it's applied to, set by RegisterMigrations
+	Binding string
+}
+
+type schemaMigration struct {
+	ID        string    `gorm:"primaryKey;size:128"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (m *schemaMigration) TableName() string {
+	return "schema_migration"
+}
+
+var (
+	migrations   []Migration
+	migrationsMu sync.Mutex
+)
+
+// RegisterMigrations registers migrations of the tables of the binding to be
+// applied by Migrate, domains call it in the init function of their repository
+// implementations.
+func RegisterMigrations(binding string, items ...Migration) {
+	migrationsMu.Lock()
+	defer migrationsMu.Unlock()
+	for _, m := range items {
+		m.Binding = binding
+		migrations = append(migrations, m)
+	}
+}
+
+// Migrate applies the pending migrations to the databases of the app the
+// context carries, or the ones of the environment outside of apps, see
+// Registry.Migrate.
+func Migrate(ctx context.Context) error {
+	return FromContext(ctx).Migrate(ctx)
+}
+
+// Migrate applies the registered migrations that haven't been applied yet to
+// the databases their bindings are in, each in its own transaction. Every
+// database keeps track of the migrations applied to it, so a binding moved to
+// another database gets its tables created there.
+func (r *Registry) Migrate(ctx context.Context) error {
+	pending := map[string][]Migration{}
+	func() {
+		migrationsMu.Lock()
+		defer migrationsMu.Unlock()
+		sorted := slices.SortedFunc(slices.Values(migrations), func(a Migration, b Migration) int {
+			return cmp.Compare(a.ID, b.ID)
+		})
+		for _, m := range sorted {
+			name := r.Bind(m.Binding)
+			pending[name] = append(pending[name], m)
+		}
+	}()
+
+	// The default database first
+	names := slices.Sorted(maps.Keys(pending))
+	if i := slices.Index(names, DefaultName); i > 0 {
+		names = append([]string{DefaultName}, slices.Delete(names, i, i+1)...)
+	}
+	for _, name := range names {
+		conn, err := r.Get(name)
+		if err != nil {
+			return ConnError(err)
+		}
+		if err := migrateConn(ctx, conn, pending[name]); err != nil {
+			return fmt.Errorf("database %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func migrateConn(ctx context.Context, conn *gorm.DB, pending []Migration) error {
+	conn = conn.WithContext(ctx)
+	if conn.Dialector.Name() != "sqlite" {
+		return applyMigrations(conn, pending, false)
+	}
+
+	// SQLite rebuilds the tables for most schema changes, which enforced foreign
+	// keys would break, so they're checked at the end of each migration instead.
+	// The pragma only applies to the connection, and not within transactions.
+	return conn.Connection(func(conn *gorm.DB) error {
+		// The connection shares one statement among the chained calls otherwise
+		conn = conn.Session(&gorm.Session{})
+		if err := conn.Exec("PRAGMA foreign_keys = OFF").Error; err != nil {
+			return err
+		}
+		defer conn.Exec("PRAGMA foreign_keys = ON")
+
+		return applyMigrations(conn, pending, true)
+	})
+}
+
+func applyMigrations(conn *gorm.DB, pending []Migration, checkForeignKeys bool) error {
+	if err := conn.AutoMigrate(&schemaMigration{}); err != nil {
+		return fmt.Errorf("failed to create migration table: %w", err)
+	}
+
+	var applied []string
+	if err := conn.Model(&schemaMigration{}).Pluck("id", &applied).Error; err != nil {
+		return fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	for _, m := range pending {
+		if slices.Contains(applied, m.ID) {
+			continue
+		}
+
+		err := conn.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			} else if checkForeignKeys {
+				if err := checkSqliteForeignKeys(tx); err != nil {
+					return err
+				}
+			}
+			return tx.Create(&schemaMigration{ID: m.ID, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", m.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// checkSqliteForeignKeys reports the first row that violates a foreign key.
+func checkSqliteForeignKeys(conn *gorm.DB) error {
+	rows, err := conn.Raw("PRAGMA foreign_key_check").Rows()
+	if err != nil {
+		return fmt.Errorf("failed to check foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		var table, parent string
+		var rowId sql.NullInt64
+		var fkId int
+		if err := rows.Scan(&table, &rowId, &parent, &fkId); err != nil {
+			return fmt.Errorf("failed to check foreign keys: %w", err)
+		}
+		return fmt.Errorf("row %d of %s refers to a missing row of %s", rowId.Int64, table, parent)
+	}
+	return rows.Err()
+}
+
+// CreateTableIfNotExists returns a migration function that creates the table
+// of the model if it doesn't exist.
+func CreateTableIfNotExists(model any) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		if tx.Migrator().HasTable(model) {
+			return nil
+		}
+		return tx.Migrator().CreateTable(model)
+	}
+}
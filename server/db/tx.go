@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// transaction is a transaction in the context, within the transactions of the
+// other databases it's nested in.
+type transaction struct {
+	name        string // The name of the database
+	tx          *gorm.DB
+	outer       *transaction
+	afterCommit []func()
+}
+
+// Transaction runs fn in a transaction of the database the binding is in, which
+// is committed if fn returns nil, and rolled back otherwise. Within the context
+// passed to fn, For and Reader return the transaction for the bindings in the
+// same database, so the repositories join it, and so do nested calls.
+func Transaction(ctx context.Context, binding string, fn func(ctx context.Context) error) error {
+	r := FromContext(ctx)
+	name := r.Bind(binding)
+	if transactionOf(ctx, name) != nil {
+		return fn(ctx)
+	}
+
+	conn, err := r.Get(name)
+	if err != nil {
+		return ConnError(err)
+	}
+	outer, _ := ctx.Value(txKey{}).(*transaction)
+	t := &transaction{name: name, outer: outer}
+	err = conn.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		t.tx = tx
+		return fn(context.WithValue(ctx, txKey{}, t))
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, fn := range t.afterCommit {
+		fn()
+	}
+	return nil
+}
+
+// AfterCommit runs fn after the innermost transaction of the context is
+// committed, or right away outside of transactions. It's not run if the
+// transaction is rolled back.
+func AfterCommit(ctx context.Context, fn func()) {
+	if t, ok := ctx.Value(txKey{}).(*transaction); ok {
+		t.afterCommit = append(t.afterCommit, fn)
+		return
+	}
+	fn()
+}
+
+// transactionOf returns the transaction of the named database in the context.
+func transactionOf(ctx context.Context, name string) *gorm.DB {
+	t, _ := ctx.Value(txKey{}).(*transaction)
+	for ; t != nil; t = t.outer {
+		if t.name == name {
+			return t.tx
+		}
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+// Package events is the in-process bus through which the domains learn what
+// happens in the others without importing them, e.g. the author of an article
+// is notified of comments on it by a subscriber of CommentCreated.
+//
+// Synchronous subscribers run within Publish, in the transaction of the
+// publisher if any, and fail it by returning errors. Asynchronous subscribers
+// run in the background: Publish writes the event to an outbox table, along with
+// the changes it's about when in the same transaction, and the events are only
+// dispatched once committed. Failed handlers are retried with backoff.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"bilingo/config"
+	"bilingo/server/app"
+	"bilingo/server/db"
+	"bilingo/server/poller"
+
+	"gorm.io/gorm"
+)
+
+// DBBinding is the binding of the database the outbox is in. Events are written
+// in the transactions of the publishers only if the outbox is in the same
+// database as the tables they change, so it's best left in the default one.
+const DBBinding = "event"
+
+// Event is something that happened in a domain, e.g. an article was created.
+// Events are encoded in JSON for the asynchronous subscribers, so they should
+// carry the IDs of the records rather than the records themselves.
+type Event interface {
+	// EventName returns the name the event is subscribed to by, e.g.
+	// `article.created`, which must be the same for the zero value.
+	EventName() string
+}
+
+type subscriber struct {
+	name   string
+	handle func(ctx context.Context, event Event) error
+	decode func(payload []byte) (Event, error)
+}
+
+// Bus dispatches the events published in an app to its subscribers, an app has
+// a bus of its own.
+type Bus struct {
+	cfg    config.EventsConfig
+	mu     sync.RWMutex
+	sync   map[string][]subscriber
+	async  map[string]map[string]subscriber // By event name and subscriber name
+	poller *poller.Poller                   // Dispatches the outbox
+}
+
+// The bus of the configuration of the environment, used outside of apps
+var defaultBus = sync.OnceValue(func() *Bus {
+	return NewBus(context.Background(), config.GetConfig().Events)
+})
+
+// NewBus creates a bus whose asynchronous handlers run in the context, which
+// carries the app whose outbox they're dispatched from.
+func NewBus(ctx context.Context, cfg config.EventsConfig) *Bus {
+	b := &Bus{
+		cfg:   cfg,
+		sync:  map[string][]subscriber{},
+		async: map[string]map[string]subscriber{},
+	}
+	b.poller = poller.New(ctx, "events", cfg.PollInterval, cfg.BatchSize, b.dispatch)
+	return b
+}
+
+// Register provides the event bus of the app, which dispatches the outbox while
+// the app is running. Modules subscribing to events must come after it.
+func Register(a *app.App) error {
+	b := NewBus(a.Context(context.Background()), a.Config.Events)
+	app.Provide(a, b)
+	a.OnStart(func(ctx context.Context) error {
+		b.Start()
+		return nil
+	})
+	a.OnStop(b.Shutdown)
+	return nil
+}
+
+// FromContext returns the bus of the app the context carries, or the one of the
+// configuration of the environment outside of apps.
+func FromContext(ctx context.Context) *Bus {
+	if a := app.FromContext(ctx); a != nil {
+		if b, ok := app.Get[*Bus](a); ok {
+			return b
+		}
+	}
+	return defaultBus()
+}
+
+// ForApp returns the bus of the app, for the modules to subscribe to.
+func ForApp(a *app.App) *Bus {
+	return FromContext(a.Context(context.Background()))
+}
+
+// SubscribeSync subscribes the handler to the events of type T, it's run by
+// Publish in the context of the publisher, and its error fails the publishing.
+// Synchronous handlers should be quick and are not retried.
+func SubscribeSync[T Event](b *Bus, handler func(ctx context.Context, event T) error) {
+	var zero T
+	name := zero.EventName()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sync[name] = append(b.sync[name], subscriber{
+		handle: func(ctx context.Context, event Event) error {
+			return handler(ctx, event.(T))
+		},
+	})
+}
+
+// Subscribe subscribes the handler to the events of type T, it's run in the
+// background after the transaction the event is published in is committed, and
+// retried if it fails. The name identifies the subscriber in the outbox, so it
+// must be unique and stay the same across releases, e.g. `user.notify-author`.
+// Handlers may run more than once for an event, e.g. if the server stops while
+// they're running, so they should be idempotent.
+func Subscribe[T Event](b *Bus, name string, handler func(ctx context.Context, event T) error) {
+	var zero T
+	eventName := zero.EventName()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.async[eventName] == nil {
+		b.async[eventName] = map[string]subscriber{}
+	}
+	if _, ok := b.async[eventName][name]; ok {
+		panic(fmt.Sprintf("events: subscriber %s of %s already exists", name, eventName))
+	}
+	b.async[eventName][name] = subscriber{
+		name: name,
+		handle: func(ctx context.Context, event Event) error {
+			return handler(ctx, event.(T))
+		},
+		decode: func(payload []byte) (Event, error) {
+			var event T
+			err := json.Unmarshal(payload, &event)
+			return event, err
+		},
+	}
+}
+
+// Publish publishes the event to the bus of the app the context carries, see
+// Bus.Publish.
+func Publish(ctx context.Context, event Event) error {
+	return FromContext(ctx).Publish(ctx, event)
+}
+
+// Publish runs the synchronous subscribers of the event, returning the first
+// error, and writes the event to the outbox for each asynchronous subscriber,
+// within the transaction of the context if the outbox is in its database. The
+// asynchronous subscribers are dispatched once it's committed.
+func (b *Bus) Publish(ctx context.Context, event Event) error {
+	name := event.EventName()
+	b.mu.RLock()
+	syncSubs := b.sync[name]
+	asyncSubs := make([]string, 0, len(b.async[name]))
+	for subName := range b.async[name] {
+		asyncSubs = append(asyncSubs, subName)
+	}
+	b.mu.RUnlock()
+
+	for _, s := range syncSubs {
+		if err := s.handle(ctx, event); err != nil {
+			return fmt.Errorf("failed to handle event %s: %w", name, err)
+		}
+	}
+	if len(asyncSubs) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event %s: %w", name, err)
+	}
+	conn, err := db.For(ctx, DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	now := time.Now()
+	rows := make([]OutboxEvent, 0, len(asyncSubs))
+	for _, subName := range asyncSubs {
+		rows = append(rows, OutboxEvent{
+			Name:          name,
+			Subscriber:    subName,
+			Payload:       string(payload),
+			Status:        StatusPending,
+			NextAttemptAt: now,
+		})
+	}
+	if err := gorm.G[OutboxEvent](conn).CreateInBatches(ctx, &rows, 100); err != nil {
+		return fmt.Errorf("failed to write event %s to the outbox: %w", name, err)
+	}
+
+	db.AfterCommit(ctx, b.poller.Notify)
+	return nil
+}
+
+// subscriber returns the asynchronous subscriber of the event by name.
+func (b *Bus) subscriber(eventName string, name string) (subscriber, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	s, ok := b.async[eventName][name]
+	return s, ok
+}
@@ -0,0 +1,187 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"bilingo/server/db"
+	"bilingo/server/poller"
+
+	"gorm.io/gorm"
+)
+
+const (
+	StatusPending = "pending" // The event is waiting to be dispatched, or retried
+	StatusFailed  = "failed"  // The handler failed every attempt, the event is kept until retried by hand
+)
+
+// OutboxEvent is an event waiting to be dispatched to an asynchronous
+// subscriber, it's deleted once handled.
+type OutboxEvent struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	Name          string     `gorm:"size:128;not null" json:"name"`
+	Subscriber    string     `gorm:"size:128;not null" json:"subscriber"`
+	Payload       string     `gorm:"type:text;not null" json:"payload"` // The event in JSON
+	Status        string     `gorm:"size:16;not null;index:idx_event_outbox_due,priority:1" json:"status"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	LastError     *string    `gorm:"type:text" json:"lastError"`
+	NextAttemptAt time.Time  `gorm:"not null;index:idx_event_outbox_due,priority:2" json:"nextAttemptAt"`
+	LockedUntil   *time.Time `json:"lockedUntil"` // Set while a dispatcher is handling the event
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+func (e *OutboxEvent) TableName() string {
+	return "event_outbox"
+}
+
+func init() {
+	db.RegisterMigrations(
+		DBBinding,
+		db.Migration{ID: "2026101912_events_create_event_outbox_table", Up: db.CreateTableIfNotExists(&OutboxEvent{})},
+	)
+}
+
+// Start starts dispatching the outbox in the background. Other instances of the
+// server may dispatch the same outbox, each event is claimed by one of them.
+func (b *Bus) Start() {
+	b.poller.Start()
+}
+
+// Shutdown stops dispatching the outbox and waits for the running handlers to
+// return, or until the context is done. The events left are dispatched after
+// the next start.
+func (b *Bus) Shutdown(ctx context.Context) error {
+	return b.poller.Shutdown(ctx)
+}
+
+// Retry sets the failed events with the IDs back to pending, or all failed
+// events if there are no IDs, and returns how many there were.
+func (b *Bus) Retry(ctx context.Context, ids ...uint) (int64, error) {
+	conn, err := db.For(ctx, DBBinding)
+	if err != nil {
+		return 0, db.ConnError(err)
+	}
+
+	query := conn.WithContext(ctx).Model(&OutboxEvent{}).Where("status = ?", StatusFailed)
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+	result := query.Updates(map[string]any{
+		"status":          StatusPending,
+		"attempts":        0,
+		"next_attempt_at": time.Now(),
+	})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to retry events: %w", result.Error)
+	}
+
+	b.poller.Notify()
+	return result.RowsAffected, nil
+}
+
+// dispatch claims a batch of the due events and runs their handlers, and returns
+// the number of due events found.
+func (b *Bus) dispatch(ctx context.Context) (int, error) {
+	conn, err := db.For(ctx, DBBinding)
+	if err != nil {
+		return 0, db.ConnError(err)
+	}
+	conn = conn.WithContext(ctx)
+
+	now := time.Now()
+	var due []OutboxEvent
+	err = conn.Where("status = ? AND next_attempt_at <= ?", StatusPending, now).
+		Where("locked_until IS NULL OR locked_until < ?", now).
+		Order("id").Limit(b.cfg.BatchSize).Find(&due).Error
+	if err != nil {
+		return 0, err
+	}
+
+	// Events whose handlers outlive the lease are claimed again
+	lease := now.Add(2 * b.cfg.Timeout)
+	sem := make(chan struct{}, b.cfg.Concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for _, event := range due {
+		// Another instance may have claimed the event since
+		result := conn.Model(&OutboxEvent{}).
+			Where("id = ? AND (locked_until IS NULL OR locked_until < ?)", event.ID, now).
+			Update("locked_until", lease)
+		if result.Error != nil {
+			return len(due), result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			b.deliver(ctx, conn, event)
+		}()
+	}
+	return len(due), nil
+}
+
+// deliver runs the handler of the event, and deletes the event if it succeeds,
+// or schedules the next attempt if it fails.
+func (b *Bus) deliver(ctx context.Context, conn *gorm.DB, event OutboxEvent) {
+	handleErr := b.handle(ctx, event)
+
+	// The outcome is recorded even while shutting down
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	conn = conn.WithContext(ctx)
+
+	if handleErr == nil {
+		if err := conn.Delete(&OutboxEvent{}, event.ID).Error; err != nil {
+			log.Printf("failed to delete dispatched event %d: %v", event.ID, err)
+		}
+		return
+	}
+
+	event.Attempts++
+	message := handleErr.Error()
+	updates := map[string]any{
+		"attempts":     event.Attempts,
+		"last_error":   message,
+		"locked_until": nil,
+	}
+	if event.Attempts >= b.cfg.MaxAttempts {
+		updates["status"] = StatusFailed
+		log.Printf("event %s (%d) failed in %s after %d attempts: %v", event.Name, event.ID, event.Subscriber, event.Attempts, handleErr)
+	} else {
+		updates["next_attempt_at"] = time.Now().Add(poller.Backoff(event.Attempts, b.cfg.RetryDelay, b.cfg.MaxRetryDelay))
+	}
+	if err := conn.Model(&OutboxEvent{}).Where("id = ?", event.ID).Updates(updates).Error; err != nil {
+		log.Printf("failed to record the failure of event %d: %v", event.ID, err)
+	}
+}
+
+// handle decodes the event and runs the handler of its subscriber.
+func (b *Bus) handle(ctx context.Context, event OutboxEvent) (err error) {
+	s, ok := b.subscriber(event.Name, event.Subscriber)
+	if !ok {
+		return fmt.Errorf("no subscriber %s of %s", event.Subscriber, event.Name)
+	}
+	decoded, err := s.decode([]byte(event.Payload))
+	if err != nil {
+		return fmt.Errorf("failed to decode event: %w", err)
+	}
+
+	// Handlers run to the end even while shutting down, within the timeout
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), b.cfg.Timeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+	return s.handle(ctx, decoded)
+}
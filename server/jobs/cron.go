@@ -0,0 +1,213 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed cron expression, see ParseCron.
+type Cron struct {
+	minute, hour, dom, month, dow uint64 // The bits of the values matched
+	domAny, dowAny                bool   // Whether the day fields start with `*`, see matchDay
+	every                         time.Duration
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// The bounds of the fields: minute, hour, day of month, month and day of week
+var cronBounds = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+// ParseCron parses a cron expression of five fields, the minute, hour, day of
+// month, month and day of week (0 or 7 for Sunday), each of which is `*`, a
+// value, a range `a-b`, any of them with a step `/n`, or a list of them, e.g.
+// `*/15 9-17 * * 1-5`. The macros `@hourly`, `@daily`, `@weekly`, `@monthly`
+// and `@yearly` are supported, as well as `@every <duration>`, e.g.
+// `@every 10m`. Expressions are evaluated in UTC.
+func ParseCron(spec string) (*Cron, error) {
+	spec = strings.TrimSpace(spec)
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		every, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil || every < time.Second {
+			return nil, fmt.Errorf("invalid interval %q", rest)
+		}
+		return &Cron{every: every}, nil
+	}
+	if macro, ok := cronMacros[spec]; ok {
+		spec = macro
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, got %d", len(fields))
+	}
+	c := &Cron{
+		domAny: strings.HasPrefix(fields[2], "*"),
+		dowAny: strings.HasPrefix(fields[4], "*"),
+	}
+	targets := []*uint64{&c.minute, &c.hour, &c.dom, &c.month, &c.dow}
+	for i, field := range fields {
+		bits, err := parseCronField(field, cronBounds[i][0], cronBounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid field %q: %w", field, err)
+		}
+		*targets[i] = bits
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+
+	if c.Next(time.Now()).IsZero() {
+		return nil, errors.New("the expression never matches")
+	}
+	return c, nil
+}
+
+func parseCronField(field string, lo int, hi int) (uint64, error) {
+	var bits uint64
+	for part := range strings.SplitSeq(field, ",") {
+		values, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepText)
+			}
+		}
+
+		start, end := lo, hi
+		if values != "*" {
+			first, last, isRange := strings.Cut(values, "-")
+			var err error
+			if start, err = strconv.Atoi(first); err != nil {
+				return 0, fmt.Errorf("invalid value %q", first)
+			}
+			if isRange {
+				if end, err = strconv.Atoi(last); err != nil {
+					return 0, fmt.Errorf("invalid value %q", last)
+				}
+			} else if !hasStep {
+				end = start
+			}
+		}
+		if start < lo || end > hi || start > end {
+			return 0, fmt.Errorf("%q is out of %d-%d", values, lo, hi)
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Next returns the first time after t the expression matches, in UTC, or the
+// zero time if it doesn't within five years. Intervals of `@every` are counted
+// from the zero time, so all servers agree on them.
+func (c *Cron) Next(t time.Time) time.Time {
+	if c.every > 0 {
+		return t.UTC().Truncate(c.every).Add(c.every)
+	}
+
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	for limit := t.AddDate(5, 0, 0); t.Before(limit); {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !c.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// matchDay reports whether the day matches, like cron does: either day field
+// if both are restricted, and both otherwise.
+func (c *Cron) matchDay(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// schedule is a recurring job of a queue.
+type schedule struct {
+	name string
+	spec string
+	cron *Cron
+	job  Job
+	next time.Time // The next run
+}
+
+// Schedule enqueues the job whenever the cron expression matches, see
+// ParseCron, from the time the queue starts. All servers enqueue it, and the
+// job of each run is only enqueued once, by the unique key of the name and the
+// time. The runs missed while no server was running are skipped. The name
+// identifies the recurring job, e.g. `user.send-digests`, so it must be unique
+// and stay the same across releases. It panics if the expression is invalid.
+func Schedule(q *Queue, name string, spec string, job Job) {
+	c, err := ParseCron(spec)
+	if err != nil {
+		panic(fmt.Sprintf("jobs: invalid schedule %s: %v", name, err))
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.schedules[name]; ok {
+		panic(fmt.Sprintf("jobs: schedule %s already exists", name))
+	}
+	q.schedules[name] = &schedule{name: name, spec: spec, cron: c, job: job, next: c.Next(time.Now())}
+	q.notify()
+}
+
+// enqueueSchedules enqueues the jobs of the recurring jobs due at the time, and
+// returns how long until the next one is.
+func (q *Queue) enqueueSchedules(now time.Time) time.Duration {
+	type run struct {
+		name string
+		job  Job
+		at   time.Time
+	}
+
+	q.mu.Lock()
+	var due []run
+	wait := time.Hour
+	for _, s := range q.schedules {
+		if !s.next.After(now) {
+			due = append(due, run{name: s.name, job: s.job, at: s.next})
+			s.next = s.cron.Next(now)
+		}
+		wait = min(wait, s.next.Sub(now))
+	}
+	q.mu.Unlock()
+
+	for _, r := range due {
+		_, err := q.EnqueueWith(q.ctx, r.job, EnqueueOptions{
+			RunAt:     r.at,
+			UniqueKey: "schedule:" + r.name + ":" + r.at.UTC().Format(time.RFC3339),
+		})
+		if err != nil && !errors.Is(err, ErrDuplicateJob) && q.ctx.Err() == nil {
+			log.Printf("failed to enqueue the job of schedule %s: %v", r.name, err)
+		}
+	}
+	return wait
+}
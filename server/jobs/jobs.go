@@ -0,0 +1,274 @@
+// Package jobs is the queue of the work done outside of requests, e.g. sending
+// mails, exports and periodic cleanups, which is kept in a database so that it
+// survives restarts and is shared by all instances of the server.
+//
+// Domains register typed handlers with Handle, and enqueue jobs with Enqueue,
+// within the transaction of the context if the queue is in its database, so
+// the jobs only run once it's committed. Failed jobs are retried with backoff,
+// and recurring jobs are enqueued by cron expressions with Schedule.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"bilingo/config"
+	"bilingo/server/app"
+	"bilingo/server/db"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DBBinding is the binding of the database the queue is in. Jobs are enqueued
+// in the transactions of the callers only if the queue is in the same database
+// as the tables they change, so it's best left in the default one.
+const DBBinding = "job"
+
+var (
+	ErrJobNotFound     = errors.New("job not found")
+	ErrDuplicateJob    = errors.New("a job with the same unique key exists")
+	ErrInvalidJobState = errors.New("the job can't be changed in its state")
+)
+
+// Job is the work to be done in the background, e.g. sending a mail. Jobs are
+// encoded in JSON, so they should carry the IDs of the records rather than the
+// records themselves.
+type Job interface {
+	// JobName returns the name the job is handled by, e.g. `user.send-digest`,
+	// which must be the same for the zero value and stay the same across
+	// releases.
+	JobName() string
+}
+
+// HandlerOptions change how the jobs of a handler are run, the zero values
+// leave the configuration in effect.
+type HandlerOptions struct {
+	Concurrency int           // The maximum number of the jobs a server runs at once
+	Timeout     time.Duration // How long a job may run
+	MaxAttempts int           // How many times a job is run before it's marked failed
+}
+
+type handler struct {
+	name    string
+	opts    HandlerOptions
+	handle  func(ctx context.Context, payload []byte) error
+	running int // The number of the jobs running on this server, guarded by Queue.mu
+}
+
+// Queue runs the jobs of the handlers registered to it, an app has a queue of
+// its own. Other instances of the server may run the jobs of the same database,
+// each job is claimed by one of them.
+type Queue struct {
+	cfg       config.JobsConfig
+	ctx       context.Context // The context the jobs run in, which carries the app
+	owner     string          // Identifies the queue in the locks of the jobs it runs
+	mu        sync.Mutex
+	handlers  map[string]*handler
+	schedules map[string]*schedule
+	running   int
+	wg        sync.WaitGroup // The running jobs
+	wake      chan struct{}
+	once      sync.Once
+	done      chan struct{}
+	stop      context.CancelFunc
+}
+
+// The queue of the configuration of the environment, used outside of apps
+var defaultQueue = sync.OnceValue(func() *Queue {
+	return NewQueue(context.Background(), config.GetConfig().Jobs)
+})
+
+// NewQueue creates a queue whose jobs run in the context, which carries the app
+// whose database they're kept in.
+func NewQueue(ctx context.Context, cfg config.JobsConfig) *Queue {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Queue{
+		cfg:       cfg,
+		ctx:       ctx,
+		owner:     uuid.NewString(),
+		handlers:  map[string]*handler{},
+		schedules: map[string]*schedule{},
+		wake:      make(chan struct{}, 1),
+		done:      make(chan struct{}),
+		stop:      cancel,
+	}
+}
+
+// Register provides the job queue of the app, which runs the jobs while the app
+// is running and deletes the finished ones after Jobs.Retention. Modules
+// registering handlers must come after it.
+func Register(a *app.App) error {
+	q := NewQueue(a.Context(context.Background()), a.Config.Jobs)
+	app.Provide(a, q)
+	if a.Config.Jobs.Retention > 0 {
+		Handle(q, func(ctx context.Context, job pruneJobs) error {
+			_, err := q.prune(ctx, time.Now().Add(-a.Config.Jobs.Retention))
+			return err
+		})
+		Schedule(q, "jobs.prune", "@hourly", pruneJobs{})
+	}
+
+	a.OnStart(func(ctx context.Context) error {
+		q.Start()
+		return nil
+	})
+	a.OnStop(q.Shutdown)
+	return nil
+}
+
+// FromContext returns the queue of the app the context carries, or the one of
+// the configuration of the environment outside of apps.
+func FromContext(ctx context.Context) *Queue {
+	if a := app.FromContext(ctx); a != nil {
+		if q, ok := app.Get[*Queue](a); ok {
+			return q
+		}
+	}
+	return defaultQueue()
+}
+
+// ForApp returns the queue of the app, for the modules to register handlers.
+func ForApp(a *app.App) *Queue {
+	return FromContext(a.Context(context.Background()))
+}
+
+// Handle registers the handler of the jobs of type T with the options of the
+// configuration, see HandleWith.
+func Handle[T Job](q *Queue, fn func(ctx context.Context, job T) error) {
+	HandleWith(q, HandlerOptions{}, fn)
+}
+
+// HandleWith registers the handler of the jobs of type T, which is run in the
+// background, and retried if it returns an error or panics. Jobs may run more
+// than once, e.g. if the server stops while they're running, so handlers should
+// be idempotent. A type can only have one handler.
+func HandleWith[T Job](q *Queue, opts HandlerOptions, fn func(ctx context.Context, job T) error) {
+	var zero T
+	name := zero.JobName()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.handlers[name]; ok {
+		panic(fmt.Sprintf("jobs: handler of %s already exists", name))
+	}
+	q.handlers[name] = &handler{
+		name: name,
+		opts: opts,
+		handle: func(ctx context.Context, payload []byte) error {
+			var job T
+			if err := json.Unmarshal(payload, &job); err != nil {
+				return fmt.Errorf("failed to decode job: %w", err)
+			}
+			return fn(ctx, job)
+		},
+	}
+	q.notify()
+}
+
+// EnqueueOptions change when and how a job is run.
+type EnqueueOptions struct {
+	RunAt       time.Time // When the job runs, right away if zero or past
+	MaxAttempts int       // How many times the job is run before it's marked failed
+	// Jobs with the same key are only enqueued once while they're kept, the
+	// others fail with ErrDuplicateJob, e.g. `export:<user id>`
+	UniqueKey string
+}
+
+// Enqueue enqueues the job to the queue of the app the context carries, see
+// Queue.EnqueueWith.
+func Enqueue(ctx context.Context, job Job) (*QueuedJob, error) {
+	return FromContext(ctx).EnqueueWith(ctx, job, EnqueueOptions{})
+}
+
+// EnqueueWith enqueues the job with the options to the queue of the app the
+// context carries, see Queue.EnqueueWith.
+func EnqueueWith(ctx context.Context, job Job, opts EnqueueOptions) (*QueuedJob, error) {
+	return FromContext(ctx).EnqueueWith(ctx, job, opts)
+}
+
+// EnqueueWith writes the job to the queue, within the transaction of the
+// context if the queue is in its database, and wakes the queue up once it's
+// committed. The job is run by the handler of its type on any instance of the
+// server, which doesn't have to be registered yet.
+func (q *Queue) EnqueueWith(ctx context.Context, job Job, opts EnqueueOptions) (*QueuedJob, error) {
+	name := job.JobName()
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode job %s: %w", name, err)
+	}
+	conn, err := db.For(ctx, DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	row := &QueuedJob{
+		Name:        name,
+		Payload:     string(payload),
+		Status:      StatusPending,
+		RunAt:       opts.RunAt,
+		MaxAttempts: opts.MaxAttempts,
+	}
+	if row.RunAt.IsZero() {
+		row.RunAt = time.Now()
+	}
+	if row.MaxAttempts <= 0 {
+		row.MaxAttempts = q.maxAttempts(name)
+	}
+
+	if opts.UniqueKey == "" {
+		if err := gorm.G[QueuedJob](conn).Create(ctx, row); err != nil {
+			return nil, fmt.Errorf("failed to enqueue job %s: %w", name, err)
+		}
+	} else {
+		row.UniqueKey = &opts.UniqueKey
+		result := conn.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(row)
+		if result.Error != nil {
+			return nil, fmt.Errorf("failed to enqueue job %s: %w", name, result.Error)
+		} else if result.RowsAffected == 0 {
+			return nil, ErrDuplicateJob
+		}
+	}
+
+	db.AfterCommit(ctx, q.notify)
+	return row, nil
+}
+
+// maxAttempts returns the attempts of the jobs of the name unless set when
+// they're enqueued.
+func (q *Queue) maxAttempts(name string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if h, ok := q.handlers[name]; ok && h.opts.MaxAttempts > 0 {
+		return h.opts.MaxAttempts
+	}
+	return q.cfg.MaxAttempts
+}
+
+// timeout returns how long the jobs of the handler may run.
+func (q *Queue) timeout(h *handler) time.Duration {
+	if h.opts.Timeout > 0 {
+		return h.opts.Timeout
+	}
+	return q.cfg.Timeout
+}
+
+// notify wakes the queue up without waiting for the next poll.
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// pruneJobs deletes the finished jobs after the retention period.
+type pruneJobs struct{}
+
+func (pruneJobs) JobName() string {
+	return "jobs.prune"
+}
@@ -0,0 +1,256 @@
+package jobs_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"bilingo/config"
+	"bilingo/server/app"
+	"bilingo/server/db"
+	"bilingo/server/jobs"
+	"bilingo/server/testutil"
+)
+
+type countJob struct {
+	N int `json:"n"`
+}
+
+func (countJob) JobName() string {
+	return "test.count"
+}
+
+// counter records how many times each job ran.
+type counter struct {
+	mu   sync.Mutex
+	runs map[int]int
+}
+
+func (c *counter) handle(ctx context.Context, job countJob) error {
+	c.mu.Lock()
+	c.runs[job.N]++
+	c.mu.Unlock()
+	// Gives the other queues the time to claim the same jobs
+	time.Sleep(5 * time.Millisecond)
+	return nil
+}
+
+func (c *counter) count(n int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.runs[n]
+}
+
+// newQueues creates an app with n more queues than its own, which run the jobs
+// with the counter and are stopped after the test. They are started by the
+// caller, so that jobs can be put in place beforehand.
+func newQueues(t *testing.T, n int) (*app.App, []*jobs.Queue, *counter) {
+	t.Helper()
+	a := testutil.NewApp(t, func(cfg *config.Config) {
+		cfg.Jobs.PollInterval = 10 * time.Millisecond
+	})
+
+	c := &counter{runs: map[int]int{}}
+	queues := make([]*jobs.Queue, n)
+	for i := range queues {
+		q := jobs.NewQueue(a.Context(context.Background()), a.Config.Jobs)
+		jobs.Handle(q, c.handle)
+		t.Cleanup(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := q.Shutdown(ctx); err != nil {
+				t.Errorf("failed to stop queue: %v", err)
+			}
+		})
+		queues[i] = q
+	}
+	return a, queues, c
+}
+
+// waitForJob waits until the job is finished, and returns it.
+func waitForJob(t *testing.T, ctx context.Context, q *jobs.Queue, id uint) *jobs.QueuedJob {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := q.Get(ctx, id)
+		if err != nil {
+			t.Fatalf("failed to get job %d: %v", id, err)
+		}
+		if job.Status != jobs.StatusPending && job.Status != jobs.StatusRunning {
+			return job
+		} else if time.Now().After(deadline) {
+			t.Fatalf("job %d is still %s after %d attempts", id, job.Status, job.Attempts)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestQueuesRunJobsOnce(t *testing.T) {
+	t.Parallel()
+	a, queues, c := newQueues(t, 2)
+	ctx := a.Context(context.Background())
+	for _, q := range queues {
+		q.Start()
+	}
+
+	var enqueued []*jobs.QueuedJob
+	for n := range 40 {
+		job, err := queues[n%2].EnqueueWith(ctx, countJob{N: n}, jobs.EnqueueOptions{})
+		if err != nil {
+			t.Fatalf("failed to enqueue job: %v", err)
+		}
+		enqueued = append(enqueued, job)
+	}
+
+	for n, job := range enqueued {
+		if job = waitForJob(t, ctx, queues[0], job.ID); job.Status != jobs.StatusSucceeded || job.Attempts != 1 {
+			t.Fatalf("unexpected job: %+v", job)
+		}
+		if runs := c.count(n); runs != 1 {
+			t.Errorf("job %d ran %d times", n, runs)
+		}
+	}
+
+	// The locks of the databases without `SKIP LOCKED` are released
+	conn, err := db.For(ctx, jobs.DBBinding)
+	if err != nil {
+		t.Fatalf("failed to get database: %v", err)
+	}
+	var locks int64
+	if err := conn.Table("job_lock").Count(&locks).Error; err != nil {
+		t.Fatalf("failed to count locks: %v", err)
+	} else if locks != 0 {
+		t.Fatalf("expected the locks to be released, %d are left", locks)
+	}
+}
+
+func TestLockedJobsAreSkipped(t *testing.T) {
+	t.Parallel()
+	a, queues, c := newQueues(t, 1)
+	ctx := a.Context(context.Background())
+	conn, err := db.For(ctx, jobs.DBBinding)
+	if err != nil {
+		t.Fatalf("failed to get database: %v", err)
+	}
+	if name := conn.Dialector.Name(); name != "sqlite" {
+		t.Skipf("the lock table is only used on SQLite, not %s", name)
+	}
+
+	// Another queue is claiming the job
+	job, err := queues[0].EnqueueWith(ctx, countJob{N: 1}, jobs.EnqueueOptions{})
+	if err != nil {
+		t.Fatalf("failed to enqueue job: %v", err)
+	}
+	lock := map[string]any{"job_id": job.ID, "owner": "other", "expires_at": time.Now().Add(time.Minute)}
+	if err := conn.Table("job_lock").Create(lock).Error; err != nil {
+		t.Fatalf("failed to lock job: %v", err)
+	}
+	queues[0].Start()
+
+	time.Sleep(100 * time.Millisecond)
+	if runs := c.count(1); runs != 0 {
+		t.Fatalf("ran a job locked by another queue %d times", runs)
+	}
+
+	// Expired locks are taken over
+	err = conn.Table("job_lock").Where("job_id = ?", job.ID).Update("expires_at", time.Now().Add(-time.Second)).Error
+	if err != nil {
+		t.Fatalf("failed to expire lock: %v", err)
+	}
+	if job = waitForJob(t, ctx, queues[0], job.ID); job.Status != jobs.StatusSucceeded || c.count(1) != 1 {
+		t.Fatalf("expected the job to run once, got %+v", job)
+	}
+}
+
+func TestExpiredLeases(t *testing.T) {
+	t.Parallel()
+	a, queues, c := newQueues(t, 1)
+	ctx := a.Context(context.Background())
+	conn, err := db.For(ctx, jobs.DBBinding)
+	if err != nil {
+		t.Fatalf("failed to get database: %v", err)
+	}
+
+	// The jobs were claimed by a server which stopped, or is still running them
+	expired, remaining := time.Now().Add(-time.Second), time.Now().Add(time.Minute)
+	gone := "gone"
+	rows := []*jobs.QueuedJob{
+		{Payload: `{"n":1}`, Attempts: 1, MaxAttempts: 3, LockedUntil: &expired},
+		{Payload: `{"n":2}`, Attempts: 3, MaxAttempts: 3, LockedUntil: &expired},
+		{Payload: `{"n":3}`, Attempts: 1, MaxAttempts: 3, LockedUntil: &remaining},
+	}
+	for _, row := range rows {
+		row.Name = countJob{}.JobName()
+		row.Status = jobs.StatusRunning
+		row.RunAt = time.Now().Add(-time.Minute)
+		row.LockedBy = &gone
+		if err := conn.Create(row).Error; err != nil {
+			t.Fatalf("failed to create job: %v", err)
+		}
+	}
+	queues[0].Start()
+
+	// Jobs with attempts left are claimed again
+	if job := waitForJob(t, ctx, queues[0], rows[0].ID); job.Status != jobs.StatusSucceeded || job.Attempts != 2 || c.count(1) != 1 {
+		t.Fatalf("expected the expired job to run again, got %+v", job)
+	}
+
+	// The others fail without running
+	job := waitForJob(t, ctx, queues[0], rows[1].ID)
+	if job.Status != jobs.StatusFailed || job.Attempts != 3 || job.LastError == nil || job.LockedBy != nil || c.count(2) != 0 {
+		t.Fatalf("expected the expired job without attempts left to fail, got %+v", job)
+	}
+
+	// Leases which haven't expired are left alone
+	if job, err := queues[0].Get(ctx, rows[2].ID); err != nil || job.Status != jobs.StatusRunning || c.count(3) != 0 {
+		t.Fatalf("claimed a job whose lease hasn't expired: %+v %v", job, err)
+	}
+}
+
+func TestParseCron(t *testing.T) {
+	t.Parallel()
+	// A Wednesday
+	now := time.Date(2026, 10, 14, 10, 7, 30, 0, time.UTC)
+	tests := []struct {
+		spec string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2026, 10, 14, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 10, 14, 10, 15, 0, 0, time.UTC)},
+		{"5,50 9-17 * * *", time.Date(2026, 10, 14, 10, 50, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		// Either day matches if both are restricted
+		{"0 0 1 * 5", time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 10, 14, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 10m", time.Date(2026, 10, 14, 10, 10, 0, 0, time.UTC)},
+		{"@every 1s", time.Date(2026, 10, 14, 10, 7, 31, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		c, err := jobs.ParseCron(test.spec)
+		if err != nil {
+			t.Errorf("failed to parse %q: %v", test.spec, err)
+			continue
+		}
+		if next := c.Next(now); !next.Equal(test.next) {
+			t.Errorf("expected %q to run next at %s, got %s", test.spec, test.next, next)
+		}
+	}
+
+	invalid := []string{
+		"", "* * * *", "* * * * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8",
+		"*/0 * * * *", "5-1 * * * *", "a * * * *", "0 0 31 2 *", "@every 500ms", "@every soon", "@sometimes",
+	}
+	for _, spec := range invalid {
+		if _, err := jobs.ParseCron(spec); err == nil {
+			t.Errorf("parsed the invalid expression %q", spec)
+		}
+	}
+}
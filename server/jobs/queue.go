@@ -0,0 +1,278 @@
+package jobs
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"bilingo/common"
+	"bilingo/server/db"
+
+	"gorm.io/gorm"
+)
+
+const (
+	StatusPending   = "pending"   // The job is waiting to run, or to be retried
+	StatusRunning   = "running"   // The job is claimed by a server until its lease expires
+	StatusSucceeded = "succeeded" // The handler returned nil
+	StatusFailed    = "failed"    // The handler failed every attempt, the job is kept until retried by hand
+	StatusCanceled  = "canceled"  // The job was canceled by hand before it ran
+)
+
+// QueuedJob is a job in the queue, it's kept after it's finished for
+// Jobs.Retention of the configuration.
+type QueuedJob struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	Name        string     `json:"name" gorm:"size:128;not null;index"`
+	Payload     string     `json:"payload" gorm:"type:text;not null"` // The job in JSON
+	Status      string     `json:"status" gorm:"size:16;not null;index:idx_job_queue_due,priority:1"`
+	RunAt       time.Time  `json:"run_at" gorm:"not null;index:idx_job_queue_due,priority:2"` // When the job is due, or retried
+	Attempts    int        `json:"attempts" gorm:"not null;default:0"`
+	MaxAttempts int        `json:"max_attempts" gorm:"not null"`
+	LastError   *string    `json:"last_error" gorm:"type:text"`
+	UniqueKey   *string    `json:"unique_key" gorm:"size:255;uniqueIndex"` // See EnqueueOptions.UniqueKey
+	LockedBy    *string    `json:"locked_by" gorm:"size:36"`               // The queue running the job
+	LockedUntil *time.Time `json:"locked_until"`                           // The lease, the job is claimed again after it
+	FinishedAt  *time.Time `json:"finished_at" gorm:"index"`
+}
+
+func (j *QueuedJob) TableName() string {
+	return "job_queue"
+}
+
+// jobLock is the claim of a job on databases without `SKIP LOCKED`, the primary
+// key lets only one queue claim the job until the lock expires.
+type jobLock struct {
+	JobID     uint      `gorm:"primaryKey;autoIncrement:false"`
+	Owner     string    `gorm:"size:36;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+}
+
+func (l *jobLock) TableName() string {
+	return "job_lock"
+}
+
+func init() {
+	db.RegisterMigrations(
+		DBBinding,
+		db.Migration{ID: "2026101914_jobs_create_job_queue_table", Up: db.CreateTableIfNotExists(&QueuedJob{})},
+		db.Migration{ID: "2026101914_jobs_create_job_lock_table", Up: db.CreateTableIfNotExists(&jobLock{})},
+	)
+}
+
+// ListQuery filters the jobs in the queue.
+type ListQuery struct {
+	common.PaginatedQuery `tstype:",extends"`
+	Name                  *string `json:"name" query:"name"`
+	Status                *string `json:"status" query:"status"`
+}
+
+// HandlerStats is the state of a handler on this server.
+type HandlerStats struct {
+	Name        string `json:"name"`
+	Concurrency int    `json:"concurrency"` // Zero if only limited by Jobs.Concurrency
+	Running     int    `json:"running"`
+}
+
+// ScheduleStats is the state of a recurring job on this server.
+type ScheduleStats struct {
+	Name    string    `json:"name"`
+	Spec    string    `json:"spec"`
+	Job     string    `json:"job"`
+	NextRun time.Time `json:"next_run"`
+}
+
+// Stats are the numbers of the jobs in the queue by status, along with the
+// handlers and recurring jobs of this server.
+type Stats struct {
+	Counts    map[string]int64 `json:"counts"`
+	Running   int              `json:"running"` // The number of jobs running on this server
+	Handlers  []HandlerStats   `json:"handlers"`
+	Schedules []ScheduleStats  `json:"schedules"`
+}
+
+// List returns the jobs matching the query, the latest first.
+func (q *Queue) List(ctx context.Context, query ListQuery) (*common.PaginatedResult[QueuedJob], error) {
+	conn, err := db.Reader(ctx, DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	tx := conn.WithContext(ctx).Model(&QueuedJob{})
+	if query.Name != nil && *query.Name != "" {
+		tx = tx.Where("name = ?", *query.Name)
+	}
+	if query.Status != nil && *query.Status != "" {
+		tx = tx.Where("status = ?", *query.Status)
+	}
+
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count jobs: %w", err)
+	}
+
+	list := []QueuedJob{}
+	err = tx.Order("id DESC").Limit(query.PageSize).Offset(query.PageSize * (query.Page - 1)).Find(&list).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job list: %w", err)
+	}
+	return &common.PaginatedResult[QueuedJob]{Total: int(total), List: list}, nil
+}
+
+func (q *Queue) Get(ctx context.Context, id uint) (*QueuedJob, error) {
+	conn, err := db.Reader(ctx, DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	job, err := gorm.G[QueuedJob](conn).Where("id = ?", id).First(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrJobNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	return &job, nil
+}
+
+// Retry sets the failed or canceled job back to pending with its attempts
+// reset, and returns it.
+func (q *Queue) Retry(ctx context.Context, id uint) (*QueuedJob, error) {
+	return q.transition(ctx, id, []string{StatusFailed, StatusCanceled}, map[string]any{
+		"status":      StatusPending,
+		"attempts":    0,
+		"run_at":      time.Now(),
+		"finished_at": nil,
+	})
+}
+
+// RetryFailed sets the failed jobs of the name back to pending, or of all names
+// if it's empty, and returns how many there were.
+func (q *Queue) RetryFailed(ctx context.Context, name string) (int64, error) {
+	conn, err := db.For(ctx, DBBinding)
+	if err != nil {
+		return 0, db.ConnError(err)
+	}
+
+	tx := conn.WithContext(ctx).Model(&QueuedJob{}).Where("status = ?", StatusFailed)
+	if name != "" {
+		tx = tx.Where("name = ?", name)
+	}
+	result := tx.Updates(map[string]any{
+		"status":      StatusPending,
+		"attempts":    0,
+		"run_at":      time.Now(),
+		"finished_at": nil,
+	})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to retry jobs: %w", result.Error)
+	}
+
+	db.AfterCommit(ctx, q.notify)
+	return result.RowsAffected, nil
+}
+
+// Cancel cancels the pending job, and returns it. Running jobs can't be
+// canceled.
+func (q *Queue) Cancel(ctx context.Context, id uint) (*QueuedJob, error) {
+	return q.transition(ctx, id, []string{StatusPending}, map[string]any{
+		"status":      StatusCanceled,
+		"finished_at": time.Now(),
+	})
+}
+
+// transition applies the updates to the job if it's in one of the statuses.
+func (q *Queue) transition(ctx context.Context, id uint, from []string, updates map[string]any) (*QueuedJob, error) {
+	conn, err := db.For(ctx, DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	result := conn.WithContext(ctx).Model(&QueuedJob{}).
+		Where("id = ? AND status IN ?", id, from).
+		Updates(updates)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to update job: %w", result.Error)
+	}
+
+	job, err := q.Get(db.UsePrimary(ctx), id)
+	if err != nil {
+		return nil, err
+	} else if result.RowsAffected == 0 {
+		return nil, ErrInvalidJobState
+	}
+
+	db.AfterCommit(ctx, q.notify)
+	return job, nil
+}
+
+// Stats returns the numbers of the jobs in the queue by status, and the state
+// of the handlers and recurring jobs of this server.
+func (q *Queue) Stats(ctx context.Context) (*Stats, error) {
+	conn, err := db.Reader(ctx, DBBinding)
+	if err != nil {
+		return nil, db.ConnError(err)
+	}
+
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	err = conn.WithContext(ctx).Model(&QueuedJob{}).
+		Select("status, COUNT(*) AS count").Group("status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count jobs: %w", err)
+	}
+
+	stats := &Stats{Counts: map[string]int64{}, Handlers: []HandlerStats{}, Schedules: []ScheduleStats{}}
+	for _, row := range rows {
+		stats.Counts[row.Status] = row.Count
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	stats.Running = q.running
+	for _, h := range q.handlers {
+		stats.Handlers = append(stats.Handlers, HandlerStats{
+			Name:        h.name,
+			Concurrency: h.opts.Concurrency,
+			Running:     h.running,
+		})
+	}
+	for _, s := range q.schedules {
+		stats.Schedules = append(stats.Schedules, ScheduleStats{
+			Name:    s.name,
+			Spec:    s.spec,
+			Job:     s.job.JobName(),
+			NextRun: s.next,
+		})
+	}
+	slices.SortFunc(stats.Handlers, func(a, b HandlerStats) int { return cmp.Compare(a.Name, b.Name) })
+	slices.SortFunc(stats.Schedules, func(a, b ScheduleStats) int { return cmp.Compare(a.Name, b.Name) })
+	return stats, nil
+}
+
+// prune deletes the jobs finished before the time, and the locks expired by
+// then, and returns the number of deleted jobs.
+func (q *Queue) prune(ctx context.Context, before time.Time) (int64, error) {
+	conn, err := db.For(ctx, DBBinding)
+	if err != nil {
+		return 0, db.ConnError(err)
+	}
+	conn = conn.WithContext(ctx)
+
+	result := conn.Where("status IN ? AND finished_at < ?", []string{StatusSucceeded, StatusFailed, StatusCanceled}, before).
+		Delete(&QueuedJob{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to prune jobs: %w", result.Error)
+	}
+	if err := conn.Where("expires_at < ?", before).Delete(&jobLock{}).Error; err != nil {
+		return result.RowsAffected, fmt.Errorf("failed to prune job locks: %w", err)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,372 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"bilingo/server/db"
+	"bilingo/server/poller"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// The jobs a queue may claim: pending ones that are due, and running ones whose
+// lease expired with attempts left, e.g. after their server stopped
+const dueCondition = "((status = ? AND run_at <= ?) OR (status = ? AND locked_until < ? AND attempts < max_attempts))"
+
+// errClaimed tells that another queue claimed the job first.
+var errClaimed = errors.New("job claimed by another queue")
+
+// Start starts running the due jobs and enqueuing the recurring ones in the
+// background.
+func (q *Queue) Start() {
+	q.once.Do(func() {
+		go q.run()
+	})
+}
+
+// Shutdown stops claiming jobs and waits for the running ones to return, or
+// until the context is done. The jobs left are run after the next start, the
+// unfinished ones once their leases expire.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.once.Do(func() {
+		close(q.done)
+	})
+	q.stop()
+
+	select {
+	case <-q.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) run() {
+	defer close(q.done)
+	defer q.wg.Wait()
+
+	ticker := time.NewTicker(q.cfg.PollInterval)
+	defer ticker.Stop()
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		timer.Reset(q.enqueueSchedules(time.Now()))
+
+		// Claims filling the free slots mean there may be more due jobs
+		for q.ctx.Err() == nil {
+			more, err := q.dispatch()
+			if err != nil {
+				if q.ctx.Err() == nil {
+					log.Printf("failed to dispatch jobs: %v", err)
+				}
+				break
+			}
+			if !more {
+				break
+			}
+		}
+
+		select {
+		case <-q.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-q.wake:
+		case <-timer.C:
+		}
+	}
+}
+
+// dispatch claims the due jobs the free slots of the queue and of the handlers
+// allow, and starts running them. It reports whether any slot was filled up,
+// in which case there may be more due jobs.
+func (q *Queue) dispatch() (bool, error) {
+	conn, err := db.For(q.ctx, DBBinding)
+	if err != nil {
+		return false, db.ConnError(err)
+	}
+	conn = conn.WithContext(q.ctx)
+
+	now := time.Now()
+	if err := q.failExpired(conn, now); err != nil {
+		return false, err
+	}
+
+	more := false
+	for _, names := range q.groups() {
+		limit := q.slots(names)
+		if limit <= 0 {
+			continue
+		}
+		// The jobs claimed before an error run anyway
+		claimed, err := q.claim(conn, names, limit, now)
+		for _, job := range claimed {
+			q.execute(job)
+		}
+		if err != nil {
+			return false, err
+		}
+		if len(claimed) == limit {
+			more = true
+		}
+	}
+	return more, nil
+}
+
+// groups returns the names of the handlers by the slots they're claimed for,
+// the handlers with a concurrency of their own are claimed for separately.
+func (q *Queue) groups() [][]string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var shared []string
+	var groups [][]string
+	for name, h := range q.handlers {
+		if h.opts.Concurrency > 0 {
+			groups = append(groups, []string{name})
+		} else {
+			shared = append(shared, name)
+		}
+	}
+	if len(shared) > 0 {
+		groups = append(groups, shared)
+	}
+	for _, group := range groups {
+		sort.Strings(group)
+	}
+	return groups
+}
+
+// slots returns how many jobs of the names may be claimed now.
+func (q *Queue) slots(names []string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	free := q.cfg.Concurrency - q.running
+	if len(names) == 1 {
+		if h := q.handlers[names[0]]; h.opts.Concurrency > 0 {
+			free = min(free, h.opts.Concurrency-h.running)
+		}
+	}
+	return free
+}
+
+// failExpired marks the running jobs whose leases expired after the last
+// attempt as failed, since they won't be claimed again.
+func (q *Queue) failExpired(conn *gorm.DB, now time.Time) error {
+	err := conn.Model(&QueuedJob{}).
+		Where("status = ? AND locked_until < ? AND attempts >= max_attempts", StatusRunning, now).
+		Updates(map[string]any{
+			"status":       StatusFailed,
+			"last_error":   "the lease of the last attempt expired before it finished",
+			"finished_at":  now,
+			"locked_by":    nil,
+			"locked_until": nil,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to fail expired jobs: %w", err)
+	}
+	return nil
+}
+
+// claim claims up to limit due jobs of the names, with `SELECT ... FOR UPDATE
+// SKIP LOCKED` on the databases supporting it, and the lock table otherwise.
+func (q *Queue) claim(conn *gorm.DB, names []string, limit int, now time.Time) ([]QueuedJob, error) {
+	switch conn.Dialector.Name() {
+	case "postgres", "mysql":
+		return q.claimSkipLocked(conn, names, limit, now)
+	default:
+		return q.claimWithLocks(conn, names, limit, now)
+	}
+}
+
+// claimSkipLocked locks the due rows in a transaction, skipping the ones other
+// queues are claiming, and leases them.
+func (q *Queue) claimSkipLocked(conn *gorm.DB, names []string, limit int, now time.Time) ([]QueuedJob, error) {
+	var claimed []QueuedJob
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		var due []QueuedJob
+		err := tx.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate, Options: clause.LockingOptionsSkipLocked}).
+			Where("name IN ?", names).
+			Where(dueCondition, StatusPending, now, StatusRunning, now).
+			Order("run_at, id").Limit(limit).Find(&due).Error
+		if err != nil {
+			return err
+		}
+
+		for _, job := range due {
+			if err := q.lease(tx, &job, now); errors.Is(err, errClaimed) {
+				continue
+			} else if err != nil {
+				return err
+			}
+			claimed = append(claimed, job)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim jobs: %w", err)
+	}
+	return claimed, nil
+}
+
+// claimWithLocks inserts a lock of each due job, which fails for the jobs other
+// queues have locked, and leases the jobs locked.
+func (q *Queue) claimWithLocks(conn *gorm.DB, names []string, limit int, now time.Time) ([]QueuedJob, error) {
+	var due []QueuedJob
+	err := conn.Where("name IN ?", names).
+		Where(dueCondition, StatusPending, now, StatusRunning, now).
+		Order("run_at, id").Limit(limit).Find(&due).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due jobs: %w", err)
+	}
+
+	var claimed []QueuedJob
+	for _, job := range due {
+		err := conn.Transaction(func(tx *gorm.DB) error {
+			err := tx.Where("job_id = ? AND expires_at < ?", job.ID, now).Delete(&jobLock{}).Error
+			if err != nil {
+				return err
+			}
+			lock := &jobLock{JobID: job.ID, Owner: q.owner, ExpiresAt: now.Add(q.leaseDuration(job.Name))}
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(lock)
+			if result.Error != nil {
+				return result.Error
+			} else if result.RowsAffected == 0 {
+				return errClaimed
+			}
+			return q.lease(tx, &job, now)
+		})
+		if errors.Is(err, errClaimed) {
+			continue
+		} else if err != nil {
+			return claimed, fmt.Errorf("failed to claim job %d: %w", job.ID, err)
+		}
+		claimed = append(claimed, job)
+	}
+	return claimed, nil
+}
+
+// lease marks the job as running on this queue for twice the timeout of its
+// handler, unless another queue did since it was found due.
+func (q *Queue) lease(tx *gorm.DB, job *QueuedJob, now time.Time) error {
+	until := now.Add(q.leaseDuration(job.Name))
+	result := tx.Model(&QueuedJob{}).
+		Where("id = ?", job.ID).
+		Where(dueCondition, StatusPending, now, StatusRunning, now).
+		Updates(map[string]any{
+			"status":       StatusRunning,
+			"attempts":     gorm.Expr("attempts + 1"),
+			"locked_by":    q.owner,
+			"locked_until": until,
+		})
+	if result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 {
+		return errClaimed
+	}
+
+	job.Status = StatusRunning
+	job.Attempts++
+	job.LockedBy = &q.owner
+	job.LockedUntil = &until
+	return nil
+}
+
+// leaseDuration returns how long the jobs of the name are leased for, which is
+// twice the timeout of their handler.
+func (q *Queue) leaseDuration(name string) time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return 2 * q.timeout(q.handlers[name])
+}
+
+// execute runs the claimed job in the background, and records the outcome.
+func (q *Queue) execute(job QueuedJob) {
+	q.mu.Lock()
+	h := q.handlers[job.Name]
+	h.running++
+	q.running++
+	q.mu.Unlock()
+
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		defer func() {
+			q.mu.Lock()
+			h.running--
+			q.running--
+			q.mu.Unlock()
+			// The slot is free for the next job
+			q.notify()
+		}()
+		q.finish(job, q.handle(h, job))
+	}()
+}
+
+// handle runs the handler of the job, recovering from panics.
+func (q *Queue) handle(h *handler, job QueuedJob) (err error) {
+	// Handlers run to the end even while shutting down, within the timeout
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(q.ctx), q.timeout(h))
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+	return h.handle(ctx, []byte(job.Payload))
+}
+
+// finish records the outcome of the job and releases it: it succeeded, is
+// retried after the backoff, or failed if it has no attempts left.
+func (q *Queue) finish(job QueuedJob, handleErr error) {
+	// The outcome is recorded even while shutting down
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(q.ctx), 10*time.Second)
+	defer cancel()
+	conn, err := db.For(ctx, DBBinding)
+	if err != nil {
+		log.Printf("failed to record the outcome of job %d: %v", job.ID, db.ConnError(err))
+		return
+	}
+	conn = conn.WithContext(ctx)
+
+	now := time.Now()
+	updates := map[string]any{
+		"locked_by":    nil,
+		"locked_until": nil,
+	}
+	switch {
+	case handleErr == nil:
+		updates["status"] = StatusSucceeded
+		updates["last_error"] = nil
+		updates["finished_at"] = now
+	case job.Attempts >= job.MaxAttempts:
+		updates["status"] = StatusFailed
+		updates["last_error"] = handleErr.Error()
+		updates["finished_at"] = now
+		log.Printf("job %s (%d) failed after %d attempts: %v", job.Name, job.ID, job.Attempts, handleErr)
+	default:
+		updates["status"] = StatusPending
+		updates["last_error"] = handleErr.Error()
+		updates["run_at"] = now.Add(poller.Backoff(job.Attempts, q.cfg.RetryDelay, q.cfg.MaxRetryDelay))
+	}
+
+	// The job may have been claimed again if it outlived its lease
+	result := conn.Model(&QueuedJob{}).
+		Where("id = ? AND status = ? AND locked_by = ?", job.ID, StatusRunning, q.owner).
+		Updates(updates)
+	if result.Error != nil {
+		log.Printf("failed to record the outcome of job %d: %v", job.ID, result.Error)
+	} else if result.RowsAffected == 0 {
+		log.Printf("job %s (%d) was claimed again before it finished", job.Name, job.ID)
+	}
+	if err := conn.Where("job_id = ? AND owner = ?", job.ID, q.owner).Delete(&jobLock{}).Error; err != nil {
+		log.Printf("failed to release the lock of job %d: %v", job.ID, err)
+	}
+}
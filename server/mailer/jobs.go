@@ -0,0 +1,38 @@
+package mailer
+
+import (
+	"context"
+
+	"bilingo/server/app"
+	"bilingo/server/jobs"
+)
+
+// sendMail sends a rendered message in the job queue.
+type sendMail struct {
+	Message Message `json:"message"`
+}
+
+func (sendMail) JobName() string {
+	return "mail.send"
+}
+
+// RegisterJobs registers the handler of the messages sent with SendLater on
+// the job queue of the app, so it must come after the queue.
+func RegisterJobs(a *app.App) error {
+	jobs.Handle(jobs.ForApp(a), func(ctx context.Context, job sendMail) error {
+		return Send(ctx, &job.Message)
+	})
+	return nil
+}
+
+// SendLater sends the message in the background with the job queue, which
+// retries it if the mailer fails. It's enqueued within the transaction of the
+// context if any, so it's only sent once the transaction is committed. The
+// sender defaults to the one in the configuration of the app.
+func SendLater(ctx context.Context, msg *Message) error {
+	if msg.From == "" {
+		msg.From = app.Config(ctx).Mail.From
+	}
+	_, err := jobs.Enqueue(ctx, sendMail{Message: *msg})
+	return err
+}
@@ -0,0 +1,170 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+
+	"bilingo/config"
+	"bilingo/server/app"
+
+	"github.com/google/uuid"
+)
+
+// Message is an email message, with a plain text body and an optional HTML
+// alternative.
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	Text    string
+	Html    string
+}
+
+// Mailer sends email messages.
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+var (
+	defaultMailer Mailer
+	defaultMu     sync.RWMutex
+	defaultOnce   sync.Once
+)
+
+// New creates the mailer of the transport set in the configuration.
+func New(cfg config.MailConfig) Mailer {
+	switch cfg.Transport {
+	case config.MailSmtp:
+		return &SmtpMailer{
+			Host:     cfg.SmtpHost,
+			Port:     cfg.SmtpPort,
+			Username: cfg.SmtpUsername,
+			Password: cfg.SmtpPassword,
+		}
+	case config.MailFile:
+		return &FileMailer{Dir: cfg.Dir}
+	default:
+		return &MemoryMailer{}
+	}
+}
+
+// Register provides the mailer of the transport in the configuration of the
+// app.
+func Register(a *app.App) error {
+	app.Provide(a, New(a.Config.Mail))
+	return nil
+}
+
+// FromContext returns the mailer of the app the context carries, or the
+// default one outside of apps.
+func FromContext(ctx context.Context) Mailer {
+	if a := app.FromContext(ctx); a != nil {
+		if m, ok := app.Get[Mailer](a); ok {
+			return m
+		}
+	}
+	return Default()
+}
+
+// Default returns the mailer of the transport set in the configuration of the
+// environment.
+func Default() Mailer {
+	defaultOnce.Do(func() {
+		defaultMu.Lock()
+		defer defaultMu.Unlock()
+		if defaultMailer != nil {
+			return // Replaced with SetDefault already
+		}
+		defaultMailer = New(config.GetConfig().Mail)
+	})
+
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultMailer
+}
+
+// SetDefault replaces the default mailer, e.g. with a MemoryMailer in tests.
+func SetDefault(m Mailer) {
+	defaultOnce.Do(func() {})
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultMailer = m
+}
+
+// Send sends the message with the mailer of the app the context carries, the
+// sender defaults to the one in the configuration of the app.
+func Send(ctx context.Context, msg *Message) error {
+	if msg.From == "" {
+		msg.From = app.Config(ctx).Mail.From
+	}
+	if err := FromContext(ctx).Send(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	return nil
+}
+
+// Bytes encodes the message in the MIME format.
+func (m *Message) Bytes() []byte {
+	var buf bytes.Buffer
+	header := func(key string, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+
+	header("From", m.From)
+	header("To", strings.Join(m.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", fmt.Sprintf("<%s@%s>", uuid.NewString(), domainOf(m.From)))
+	header("MIME-Version", "1.0")
+
+	if m.Html == "" {
+		header("Content-Type", "text/plain; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		writeQuotedPrintable(&buf, m.Text)
+		return buf.Bytes()
+	}
+
+	w := multipart.NewWriter(&buf)
+	header("Content-Type", "multipart/alternative; boundary="+w.Boundary())
+	buf.WriteString("\r\n")
+
+	for _, part := range []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.Html},
+	} {
+		pw, _ := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		writeQuotedPrintable(pw, part.body)
+	}
+	_ = w.Close()
+
+	return buf.Bytes()
+}
+
+func writeQuotedPrintable(w interface{ Write([]byte) (int, error) }, text string) {
+	qw := quotedprintable.NewWriter(w)
+	_, _ = qw.Write([]byte(text))
+	_ = qw.Close()
+}
+
+func domainOf(address string) string {
+	address = strings.TrimSuffix(address, ">")
+	if i := strings.LastIndex(address, "@"); i >= 0 {
+		return address[i+1:]
+	}
+	return "localhost"
+}
@@ -0,0 +1,90 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	htmlTemplate "html/template"
+	"io/fs"
+	"strings"
+	textTemplate "text/template"
+)
+
+// Templates renders messages from template files, each file defines the
+// "subject" and "text" templates, and optionally the "html" one, e.g.
+//
+//	{{define "subject"}}Welcome to {{.AppName}}{{end}}
+//	{{define "text"}}Hello {{.Name}}, ...{{end}}
+//	{{define "html"}}<p>Hello {{.Name}}, ...</p>{{end}}
+//
+// The HTML part is escaped contextually with html/template.
+type Templates struct {
+	text map[string]*textTemplate.Template
+	html map[string]*htmlTemplate.Template
+}
+
+// ParseTemplates parses the template files matching the pattern in fsys, the
+// templates are named after the file names without extension.
+func ParseTemplates(fsys fs.FS, pattern string) (*Templates, error) {
+	files, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Templates{
+		text: make(map[string]*textTemplate.Template, len(files)),
+		html: make(map[string]*htmlTemplate.Template, len(files)),
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(file[strings.LastIndex(file, "/")+1:], ".tmpl")
+
+		if t.text[name], err = textTemplate.ParseFS(fsys, file); err != nil {
+			return nil, fmt.Errorf("failed to parse mail template %s: %w", file, err)
+		}
+		if t.html[name], err = htmlTemplate.ParseFS(fsys, file); err != nil {
+			return nil, fmt.Errorf("failed to parse mail template %s: %w", file, err)
+		}
+	}
+
+	return t, nil
+}
+
+// MustParseTemplates is like ParseTemplates but panics on errors, it's meant
+// for templates embedded in the binary.
+func MustParseTemplates(fsys fs.FS, pattern string) *Templates {
+	t, err := ParseTemplates(fsys, pattern)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Render renders the message of the named template to the recipient.
+func (t *Templates) Render(name string, to string, data any) (*Message, error) {
+	text, ok := t.text[name]
+	if !ok {
+		return nil, fmt.Errorf("mail template %s not found", name)
+	}
+
+	msg := &Message{To: []string{to}}
+	var buf bytes.Buffer
+	if err := text.ExecuteTemplate(&buf, "subject", data); err != nil {
+		return nil, fmt.Errorf("failed to render mail subject: %w", err)
+	}
+	msg.Subject = strings.TrimSpace(buf.String())
+
+	buf.Reset()
+	if err := text.ExecuteTemplate(&buf, "text", data); err != nil {
+		return nil, fmt.Errorf("failed to render mail text: %w", err)
+	}
+	msg.Text = strings.TrimSpace(buf.String())
+
+	if html := t.html[name]; html.Lookup("html") != nil {
+		buf.Reset()
+		if err := html.ExecuteTemplate(&buf, "html", data); err != nil {
+			return nil, fmt.Errorf("failed to render mail html: %w", err)
+		}
+		msg.Html = strings.TrimSpace(buf.String())
+	}
+
+	return msg, nil
+}
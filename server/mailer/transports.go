@@ -0,0 +1,118 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SmtpMailer sends messages through an SMTP server, authenticating with PLAIN
+// if a username is set.
+type SmtpMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+}
+
+func (m *SmtpMailer) Send(ctx context.Context, msg *Message) error {
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender: %w", err)
+	}
+
+	to := make([]string, 0, len(msg.To))
+	for _, address := range msg.To {
+		addr, err := mail.ParseAddress(address)
+		if err != nil {
+			return fmt.Errorf("invalid recipient: %w", err)
+		}
+		to = append(to, addr.Address)
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	// smtp.SendMail doesn't take a context, so it's only checked beforehand
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+	return smtp.SendMail(addr, auth, from.Address, to, msg.Bytes())
+}
+
+// FileMailer writes messages as .eml files to a directory instead of sending
+// them, so they can be opened with a mail client during development.
+type FileMailer struct {
+	Dir string
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg *Message) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create mail directory: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102T150405"), uuid.NewString()[:8])
+	return os.WriteFile(filepath.Join(m.Dir, name), msg.Bytes(), 0o644)
+}
+
+// MemoryMailer keeps messages in memory instead of sending them, so tests can
+// inspect what would have been sent.
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func (m *MemoryMailer) Send(ctx context.Context, msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, *msg)
+	return nil
+}
+
+// Messages returns the messages sent so far.
+func (m *MemoryMailer) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
+
+// Last returns the last message sent to the address, or nil if there's none.
+func (m *MemoryMailer) Last(to string) *Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		for _, address := range m.messages[i].To {
+			if address == to {
+				msg := m.messages[i]
+				return &msg
+			}
+		}
+	}
+	return nil
+}
+
+// Reset discards the messages sent so far.
+func (m *MemoryMailer) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = nil
+}
+
+var (
+	_ Mailer = (*SmtpMailer)(nil)
+	_ Mailer = (*FileMailer)(nil)
+	_ Mailer = (*MemoryMailer)(nil)
+)
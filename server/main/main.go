@@ -1,19 +1,27 @@
 package main
 
 import (
+	"context"
+	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
 	"bilingo/config"
-	"bilingo/server"
+	"bilingo/domains"
+	"bilingo/server/app"
+	"bilingo/server/db"
+	"bilingo/server/events"
+	"bilingo/server/jobs"
+	"bilingo/server/mailer"
+	"bilingo/server/realtime"
+	"bilingo/server/storage"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/joho/godotenv"
-
-	_ "bilingo/domains/article/api"
-	_ "bilingo/domains/system/api"
-	_ "bilingo/domains/user/api"
 )
 
 func init() {
@@ -22,14 +30,16 @@ func init() {
 }
 
 func main() {
-	cfg := config.GetConfig()
-	app := fiber.New(fiber.Config{
-		AppName:   cfg.AppName,
-		Immutable: true,
-	})
-
-	app.Mount("/api", server.Api)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
+	a := app.New(config.GetConfig())
+	if err := a.Register(db.Register, mailer.Register, storage.Register, events.Register, jobs.Register, mailer.RegisterJobs, realtime.Register, domains.Register); err != nil {
+		panic(err)
+	}
+	if err := a.Start(ctx); err != nil {
+		panic(err)
+	}
 	// Check if executable is in /dist/ directory
 	executable, err := os.Executable()
 	if err == nil {
@@ -41,7 +51,7 @@ func main() {
 				indexFile := filepath.Join(staticDir, "index.html")
 
 				// Serve static files
-				app.Static("/", staticDir, fiber.Static{
+				a.Fiber.Static("/", staticDir, fiber.Static{
 					Browse:    false,
 					Index:     "index.html",
 					MaxAge:    86400, // 1 day cache
@@ -49,7 +59,7 @@ func main() {
 				})
 
 				// SPA fallback: serve index.html for all non-API routes
-				app.Use(func(c *fiber.Ctx) error {
+				a.Fiber.Use(func(c *fiber.Ctx) error {
 					// Only handle GET requests
 					if c.Method() != fiber.MethodGet {
 						return fiber.ErrNotFound
@@ -67,8 +77,20 @@ func main() {
 		port = serverUrl[strings.LastIndex(serverUrl, ":"):]
 	}
 
-	err = app.Listen(port)
-	if err != nil {
-		panic(err)
+	go func() {
+		if err := a.Fiber.Listen(port); err != nil {
+			log.Printf("server stopped: %v", err)
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// The modules stop after the server stops handling requests, e.g. the
+	// queued oplogs are flushed
+	if err := a.Stop(shutdownCtx); err != nil {
+		log.Printf("failed to shut down: %v", err)
 	}
 }
@@ -0,0 +1,249 @@
+// Package markdown renders the Markdown content of articles and comments to
+// sanitized HTML, along with the excerpt, table of contents and reading time.
+package markdown
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"bilingo/common"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+)
+
+// The formats of the content in responses, which are requested with the
+// `format` query parameter.
+const (
+	FormatMarkdown = "markdown" // The content as written, the default
+	FormatHtml     = "html"     // The content rendered to sanitized HTML
+)
+
+const (
+	excerptLength  = 200 // The maximum length of excerpts in characters
+	wordsPerMinute = 200 // The reading speed of words
+	charsPerMinute = 400 // The reading speed of CJK characters, which aren't separated into words
+	cacheSize      = 1024
+)
+
+// Rendered is the Markdown rendered for display.
+type Rendered struct {
+	Html        string
+	Excerpt     string // The plain text of the leading paragraphs
+	Toc         []common.TocEntry
+	ReadingTime int // The estimated minutes to read, at least 1
+}
+
+// Raw HTML is allowed in the Markdown, so that users can use elements like
+// <kbd>, and removed along with anything unsafe by the sanitizer.
+var md = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	goldmark.WithRendererOptions(html.WithUnsafe()),
+)
+
+var policy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowElements("kbd", "mark")
+	p.AllowAttrs("id").Matching(regexp.MustCompile(`^[A-Za-z0-9_-]+$`)).OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+	// The task lists of GFM
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	return p
+}()
+
+var cache = &lruCache{size: cacheSize, items: map[[32]byte]*list.Element{}, order: list.New()}
+
+// Render renders the Markdown, the results are cached by the content, so that
+// every revision of the content is rendered once while it's in use. The results
+// are shared and must not be modified.
+func Render(source string) (*Rendered, error) {
+	key := sha256.Sum256([]byte(source))
+	if rendered, ok := cache.get(key); ok {
+		return rendered, nil
+	}
+
+	rendered, err := render([]byte(source))
+	if err != nil {
+		return nil, err
+	}
+	cache.put(key, rendered)
+	return rendered, nil
+}
+
+func render(source []byte) (*Rendered, error) {
+	doc := md.Parser().Parse(text.NewReader(source))
+
+	var buf bytes.Buffer
+	if err := md.Renderer().Render(&buf, source, doc); err != nil {
+		return nil, fmt.Errorf("failed to render markdown: %w", err)
+	}
+
+	rendered := &Rendered{Html: policy.Sanitize(buf.String())}
+	var words, chars int
+	var excerpt strings.Builder
+	for node := doc.FirstChild(); node != nil; node = node.NextSibling() {
+		if heading, ok := node.(*ast.Heading); ok {
+			id, _ := heading.AttributeString("id")
+			idBytes, _ := id.([]byte)
+			rendered.Toc = append(rendered.Toc, common.TocEntry{
+				Level: heading.Level,
+				ID:    string(idBytes),
+				Text:  nodeText(heading, source),
+			})
+		}
+
+		content := nodeText(node, source)
+		w, c := countWords(content)
+		words += w
+		chars += c
+		if node.Kind() == ast.KindParagraph && utf8.RuneCountInString(excerpt.String()) < excerptLength {
+			if excerpt.Len() > 0 {
+				excerpt.WriteString(" ")
+			}
+			excerpt.WriteString(content)
+		}
+	}
+
+	rendered.Excerpt = truncate(excerpt.String(), excerptLength)
+	minutes := float64(words)/wordsPerMinute + float64(chars)/charsPerMinute
+	rendered.ReadingTime = max(1, int(math.Ceil(minutes)))
+	return rendered, nil
+}
+
+// rawScript matches the opening and closing tags of the inline elements whose
+// content isn't text.
+var rawScript = regexp.MustCompile(`(?i)^<(/?)(script|style)\b`)
+
+// nodeText returns the plain text of the node, without the code blocks and the
+// raw HTML.
+func nodeText(node ast.Node, source []byte) string {
+	var sb strings.Builder
+	inScript := false
+	_ = ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			if n.Type() == ast.TypeBlock && sb.Len() > 0 {
+				sb.WriteString(" ")
+			}
+			return ast.WalkContinue, nil
+		}
+
+		switch n := n.(type) {
+		case *ast.FencedCodeBlock, *ast.CodeBlock, *ast.HTMLBlock:
+			return ast.WalkSkipChildren, nil
+		case *ast.RawHTML:
+			if n.Segments.Len() > 0 {
+				segment := n.Segments.At(0)
+				if m := rawScript.FindSubmatch(segment.Value(source)); m != nil {
+					inScript = len(m[1]) == 0
+				}
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		if inScript {
+			return ast.WalkContinue, nil
+		}
+
+		switch n := n.(type) {
+		case *ast.Text:
+			sb.Write(n.Segment.Value(source))
+			if n.SoftLineBreak() || n.HardLineBreak() {
+				sb.WriteString(" ")
+			}
+		case *ast.String:
+			sb.Write(n.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// countWords counts the words separated by spaces and the CJK characters,
+// which are read one by one.
+func countWords(s string) (words int, chars int) {
+	inWord := false
+	for _, r := range s {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			chars++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if !inWord {
+				words++
+			}
+			inWord = true
+		default:
+			inWord = false
+		}
+	}
+	return words, chars
+}
+
+// truncate shortens the text to the length at a word boundary if possible,
+// with an ellipsis.
+func truncate(s string, length int) string {
+	runes := []rune(s)
+	if len(runes) <= length {
+		return s
+	}
+
+	cut := length
+	if i := strings.LastIndex(string(runes[:length]), " "); i > 0 {
+		cut = utf8.RuneCountInString(string(runes[:length])[:i])
+	}
+	return strings.TrimSpace(string(runes[:cut])) + "…"
+}
+
+// lruCache keeps the most recently used results.
+type lruCache struct {
+	mu    sync.Mutex
+	size  int
+	items map[[32]byte]*list.Element
+	order *list.List // From the most recently used
+}
+
+type cacheEntry struct {
+	key      [32]byte
+	rendered *Rendered
+}
+
+func (c *lruCache) get(key [32]byte) (*Rendered, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*cacheEntry).rendered, true
+}
+
+func (c *lruCache) put(key [32]byte, rendered *Rendered) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok {
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&cacheEntry{key: key, rendered: rendered})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+	}
+}
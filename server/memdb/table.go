@@ -0,0 +1,179 @@
+// Package memdb keeps records in memory for the in-memory implementations of
+// the repositories, which stand in for the databases in demos and tests.
+package memdb
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+
+	"bilingo/common"
+)
+
+// Table is a concurrency-safe table of records by their keys, the zero value is
+// an empty table. Records are copied in and out, while the values their
+// pointers, slices and maps refer to are shared, which must not be changed in
+// place.
+type Table[K comparable, V any] struct {
+	mu     sync.RWMutex
+	rows   map[K]row[V]
+	seq    uint64 // The order of insertion
+	lastID uint
+}
+
+type row[V any] struct {
+	seq   uint64
+	value V
+}
+
+// NextID returns a new auto-increment ID, starting from 1.
+func (t *Table[K, V]) NextID() uint {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lastID++
+	return t.lastID
+}
+
+// Get returns the record of the key.
+func (t *Table[K, V]) Get(key K) (V, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	r, ok := t.rows[key]
+	return r.value, ok
+}
+
+// Insert adds the record, unless there's one of the key already.
+func (t *Table[K, V]) Insert(key K, value V) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.rows[key]; ok {
+		return false
+	}
+	t.put(key, value)
+	return true
+}
+
+// Put adds the record, or replaces the one of the key.
+func (t *Table[K, V]) Put(key K, value V) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.put(key, value)
+}
+
+// Update changes the record of the key, which is kept only if fn returns true,
+// and reports whether it's changed.
+func (t *Table[K, V]) Update(key K, fn func(value *V) bool) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r, ok := t.rows[key]
+	if !ok {
+		return false
+	}
+	value := r.value
+	if !fn(&value) {
+		return false
+	}
+	t.rows[key] = row[V]{seq: r.seq, value: value}
+	return true
+}
+
+// UpdateWhere changes the records matching the filter, and returns the number
+// of changed records.
+func (t *Table[K, V]) UpdateWhere(filter func(value V) bool, fn func(value *V)) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	count := 0
+	for key, r := range t.rows {
+		if filter(r.value) {
+			value := r.value
+			fn(&value)
+			t.rows[key] = row[V]{seq: r.seq, value: value}
+			count++
+		}
+	}
+	return count
+}
+
+// Delete deletes the record of the key, and reports whether there was one.
+func (t *Table[K, V]) Delete(key K) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.rows[key]; !ok {
+		return false
+	}
+	delete(t.rows, key)
+	return true
+}
+
+// DeleteWhere deletes the records matching the filter, and returns the number
+// of deleted records.
+func (t *Table[K, V]) DeleteWhere(filter func(value V) bool) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	count := 0
+	for key, r := range t.rows {
+		if filter(r.value) {
+			delete(t.rows, key)
+			count++
+		}
+	}
+	return count
+}
+
+// Find returns the records matching the filter in the order of insertion, all
+// of them if the filter is nil.
+func (t *Table[K, V]) Find(filter func(value V) bool) []V {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	rows := make([]row[V], 0, len(t.rows))
+	for _, r := range t.rows {
+		if filter == nil || filter(r.value) {
+			rows = append(rows, r)
+		}
+	}
+	slices.SortFunc(rows, func(a, b row[V]) int { return cmp.Compare(a.seq, b.seq) })
+
+	values := make([]V, len(rows))
+	for i, r := range rows {
+		values[i] = r.value
+	}
+	return values
+}
+
+// Count returns the number of records matching the filter.
+func (t *Table[K, V]) Count(filter func(value V) bool) int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	count := 0
+	for _, r := range t.rows {
+		if filter == nil || filter(r.value) {
+			count++
+		}
+	}
+	return count
+}
+
+func (t *Table[K, V]) put(key K, value V) {
+	if t.rows == nil {
+		t.rows = map[K]row[V]{}
+	}
+	seq := t.seq
+	if r, ok := t.rows[key]; ok {
+		seq = r.seq
+	} else {
+		t.seq++
+	}
+	t.rows[key] = row[V]{seq: seq, value: value}
+}
+
+// Paginate returns the page of the items like the database implementations,
+// which return no items and a total of 0 for pages beyond the last one.
+func Paginate[T any](items []T, query common.PaginatedQuery) *common.PaginatedResult[T] {
+	offset := max(0, query.PageSize*(query.Page-1))
+	if query.PageSize <= 0 || offset >= len(items) {
+		return &common.PaginatedResult[T]{Total: 0, List: []T{}}
+	}
+
+	end := min(len(items), offset+query.PageSize)
+	return &common.PaginatedResult[T]{Total: len(items), List: items[offset:end]}
+}
@@ -2,8 +2,10 @@ package oplog
 
 import (
 	"context"
+	"encoding/json"
+	"log"
+	"time"
 
-	"bilingo/domains/system/service"
 	"bilingo/domains/system/types"
 	"bilingo/server"
 	"bilingo/server/auth"
@@ -27,10 +29,24 @@ func NewOpLogger(objectType string) *OpLogger {
 	}
 }
 
-func (l *OpLogger) log(ctx context.Context, data LogData, result string) error {
-	var email *string
+// log queues the oplog to be written asynchronously, the user, IP and data are
+// taken at the time of the call, the data is encoded right away so that later
+// changes to it aren't recorded.
+func (l *OpLogger) log(ctx context.Context, data LogData, result string) {
+	newData, err := encodeData(data.NewData)
+	if err == nil {
+		data.OldData, err = encodeData(data.OldData)
+	}
+	if err != nil {
+		FromContext(ctx).dropped.Add(1)
+		log.Printf("oplog dropped: failed to encode data: %v (%s %s:%s)", err, data.Operation, l.objectType, data.ObjectId)
+		return
+	}
+	data.NewData = newData
+
+	var userId *string
 	if user := auth.GetUser(ctx); user != nil {
-		email = &user.Email
+		userId = &user.ID
 	}
 
 	var ip *string
@@ -38,6 +54,7 @@ func (l *OpLogger) log(ctx context.Context, data LogData, result string) error {
 		ip = &_ip
 	}
 
+	now := time.Now()
 	logData := types.OpLogData{
 		ObjectInfo: types.ObjectInfo{
 			ObjectType: l.objectType,
@@ -47,20 +64,33 @@ func (l *OpLogger) log(ctx context.Context, data LogData, result string) error {
 			Operation:   data.Operation,
 			Description: data.Description,
 			Result:      result,
-			User:        email,
+			User:        userId,
 			Ip:          ip,
 		},
-		NewData: data.NewData,
-		OldData: data.OldData,
+		NewData:   data.NewData,
+		OldData:   data.OldData,
+		Timestamp: &now,
 	}
 
-	return service.CreateOpLog(ctx, &logData)
+	FromContext(ctx).enqueue(&logData)
+}
+
+// encodeData encodes the data of an oplog in JSON, leaving nil as is.
+func encodeData(data any) (any, error) {
+	if data == nil {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(encoded), nil
 }
 
-func (l *OpLogger) Success(ctx context.Context, data LogData) error {
-	return l.log(ctx, data, "success")
+func (l *OpLogger) Success(ctx context.Context, data LogData) {
+	l.log(ctx, data, "success")
 }
 
-func (l *OpLogger) Failure(ctx context.Context, data LogData) error {
-	return l.log(ctx, data, "failure")
+func (l *OpLogger) Failure(ctx context.Context, data LogData) {
+	l.log(ctx, data, "failure")
 }
@@ -0,0 +1,273 @@
+package oplog
+
+import (
+	"context"
+	"log"
+	"reflect"
+
+	"bilingo/server/db"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Auditable is implemented by models whose creations, updates and deletions
+// are recorded automatically by the AuditPlugin.
+type Auditable interface {
+	// AuditInfo returns the object type and ID the oplogs are recorded for.
+	AuditInfo() (objectType string, objectId string)
+}
+
+// AuditSnapshotter can be implemented by auditable models to control the data
+// recorded in the oplogs, e.g. to omit sensitive fields.
+type AuditSnapshotter interface {
+	AuditSnapshot() any
+}
+
+type skipAuditKey struct{}
+
+// SkipAudit returns a context in which changes are not recorded automatically,
+// this is useful when the operation is recorded manually with an OpLogger in a
+// more meaningful way.
+func SkipAudit(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipAuditKey{}, true)
+}
+
+const oldRowsKey = "oplog:old_rows"
+
+// AuditPlugin is a GORM plugin that records oplogs for changes of Auditable
+// models, with the user and IP taken from the statement context. Changes made
+// in a transaction of db.Transaction are recorded once it's committed. Install
+// it with `conn.Use(&oplog.AuditPlugin{})`.
+type AuditPlugin struct{}
+
+func (p *AuditPlugin) Name() string {
+	return "oplog:audit"
+}
+
+func (p *AuditPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().After("gorm:create").Register("oplog:after_create", afterCreate); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("oplog:before_update", captureOldRows); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("oplog:after_update", afterUpdate); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("oplog:before_delete", captureOldRows); err != nil {
+		return err
+	}
+	return cb.Delete().After("gorm:delete").Register("oplog:after_delete", afterDelete)
+}
+
+func isAudited(db *gorm.DB) bool {
+	stmt := db.Statement
+	if stmt.Schema == nil || stmt.SkipHooks {
+		return false
+	} else if skip, _ := stmt.Context.Value(skipAuditKey{}).(bool); skip {
+		return false
+	}
+
+	_, ok := reflect.New(stmt.Schema.ModelType).Interface().(Auditable)
+	return ok
+}
+
+func afterCreate(db *gorm.DB) {
+	if db.Error != nil || !isAudited(db) {
+		return
+	}
+
+	for _, row := range rowsOf(db.Statement.ReflectValue) {
+		record(db.Statement.Context, "create", nil, row)
+	}
+}
+
+// captureOldRows loads the rows matching the conditions of the update or
+// delete statement before they're changed.
+func captureOldRows(db *gorm.DB) {
+	if db.Error != nil || !isAudited(db) {
+		return
+	}
+
+	stmt := db.Statement
+	var conds []clause.Expression
+	if where, ok := stmt.Clauses["WHERE"]; ok && where.Expression != nil {
+		conds = append(conds, where.Expression)
+	}
+	// Statements like `db.Delete(&article)` are conditioned on the primary key
+	// of the value, which GORM adds to the clauses later.
+	if stmt.ReflectValue.Kind() == reflect.Struct && stmt.Schema.PrioritizedPrimaryField != nil {
+		field := stmt.Schema.PrioritizedPrimaryField
+		if value, isZero := field.ValueOf(stmt.Context, stmt.ReflectValue); !isZero {
+			conds = append(conds, clause.Eq{Column: clause.Column{Table: stmt.Table, Name: field.DBName}, Value: value})
+		}
+	}
+	if len(conds) == 0 {
+		return // GORM refuses global updates and deletions anyway
+	}
+
+	rows := reflect.New(reflect.SliceOf(stmt.Schema.ModelType))
+	err := db.Session(&gorm.Session{NewDB: true}).
+		Table(stmt.Table).
+		Clauses(conds...).
+		Find(rows.Interface()).Error
+	if err != nil {
+		return
+	}
+
+	db.InstanceSet(oldRowsKey, rowsOf(rows.Elem()))
+}
+
+func afterUpdate(db *gorm.DB) {
+	oldRows, ok := getOldRows(db)
+	if !ok {
+		return
+	}
+
+	stmt := db.Statement
+	field := stmt.Schema.PrioritizedPrimaryField
+	if field == nil {
+		return
+	}
+
+	// Reload the changed rows by their primary keys, since the conditions may
+	// not match them anymore after the update
+	keys := make([]any, 0, len(oldRows))
+	for _, row := range oldRows {
+		value, _ := field.ValueOf(stmt.Context, row)
+		keys = append(keys, value)
+	}
+
+	newRows := reflect.New(reflect.SliceOf(stmt.Schema.ModelType))
+	err := db.Session(&gorm.Session{NewDB: true}).
+		Table(stmt.Table).
+		Where(clause.IN{Column: clause.Column{Table: stmt.Table, Name: field.DBName}, Values: keys}).
+		Find(newRows.Interface()).Error
+	if err != nil {
+		return
+	}
+
+	newByKey := map[any]reflect.Value{}
+	for _, row := range rowsOf(newRows.Elem()) {
+		value, _ := field.ValueOf(stmt.Context, row)
+		newByKey[value] = row
+	}
+
+	for _, oldRow := range oldRows {
+		key, _ := field.ValueOf(stmt.Context, oldRow)
+		if newRow, ok := newByKey[key]; ok && !reflect.DeepEqual(oldRow.Interface(), newRow.Interface()) {
+			record(stmt.Context, "update", &oldRow, newRow)
+		}
+	}
+}
+
+func afterDelete(db *gorm.DB) {
+	oldRows, ok := getOldRows(db)
+	if !ok {
+		return
+	}
+
+	for _, row := range oldRows {
+		recordDeletion(db.Statement.Context, row)
+	}
+}
+
+func getOldRows(db *gorm.DB) ([]reflect.Value, bool) {
+	if db.Error != nil || db.RowsAffected == 0 {
+		return nil, false
+	}
+
+	value, ok := db.InstanceGet(oldRowsKey)
+	if !ok {
+		return nil, false
+	}
+
+	rows, ok := value.([]reflect.Value)
+	return rows, ok && len(rows) > 0
+}
+
+// rowsOf returns the addressable struct values held by the value, which can be
+// a struct, a slice or an array of structs or pointers to structs.
+func rowsOf(value reflect.Value) []reflect.Value {
+	value = reflect.Indirect(value)
+	switch value.Kind() {
+	case reflect.Struct:
+		if !value.CanAddr() {
+			copied := reflect.New(value.Type()).Elem()
+			copied.Set(value)
+			value = copied
+		}
+		return []reflect.Value{value}
+	case reflect.Slice, reflect.Array:
+		rows := make([]reflect.Value, 0, value.Len())
+		for i := range value.Len() {
+			rows = append(rows, rowsOf(value.Index(i))...)
+		}
+		return rows
+	default:
+		return nil
+	}
+}
+
+// snapshot encodes the row, so later changes to it don't affect the oplog
+// which is written after the commit. Rows failing to encode aren't recorded.
+func snapshot(row reflect.Value) (Auditable, any) {
+	copied := reflect.New(row.Type())
+	copied.Elem().Set(row)
+
+	auditable, _ := copied.Interface().(Auditable)
+	var data any = copied.Interface()
+	if snapshotter, ok := copied.Interface().(AuditSnapshotter); ok {
+		data = snapshotter.AuditSnapshot()
+	}
+
+	encoded, err := encodeData(data)
+	if err != nil {
+		log.Printf("oplog dropped: failed to encode %s: %v", row.Type(), err)
+		return nil, nil
+	}
+	return auditable, encoded
+}
+
+func record(ctx context.Context, operation string, oldRow *reflect.Value, newRow reflect.Value) {
+	auditable, newData := snapshot(newRow)
+	if auditable == nil {
+		return
+	}
+
+	var oldData any
+	if oldRow != nil {
+		_, oldData = snapshot(*oldRow)
+	}
+
+	objectType, objectId := auditable.AuditInfo()
+	// Changes rolled back never happened
+	db.AfterCommit(ctx, func() {
+		NewOpLogger(objectType).Success(ctx, LogData{
+			ObjectId:  objectId,
+			Operation: operation,
+			OldData:   oldData,
+			NewData:   newData,
+		})
+	})
+}
+
+func recordDeletion(ctx context.Context, oldRow reflect.Value) {
+	auditable, oldData := snapshot(oldRow)
+	if auditable == nil {
+		return
+	}
+
+	objectType, objectId := auditable.AuditInfo()
+	db.AfterCommit(ctx, func() {
+		NewOpLogger(objectType).Success(ctx, LogData{
+			ObjectId:  objectId,
+			Operation: "delete",
+			OldData:   oldData,
+		})
+	})
+}
+
+var _ gorm.Plugin = (*AuditPlugin)(nil)
@@ -0,0 +1,233 @@
+package oplog
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"bilingo/config"
+	"bilingo/domains/system/service"
+	"bilingo/domains/system/types"
+	"bilingo/server/app"
+	"bilingo/server/db"
+	"bilingo/server/jobs"
+)
+
+var ErrQueueClosed = errors.New("oplog queue is closed")
+
+// Stats are the counters of an oplog pipeline since it was created.
+type Stats struct {
+	Queued  int    `json:"queued"`  // The number of oplogs waiting to be written
+	Written uint64 `json:"written"` // The number of oplogs written to the database
+	Dropped uint64 `json:"dropped"` // The number of oplogs dropped because the queue was full
+	Failed  uint64 `json:"failed"`  // The number of oplogs lost because the database write failed
+	Pruned  uint64 `json:"pruned"`  // The number of expired oplogs deleted by the retention job
+}
+
+// Pipeline writes the oplogs to the database in batches in the background, an
+// app has a pipeline of its own.
+type Pipeline struct {
+	cfg     config.OpLogConfig
+	ctx     context.Context // The context the oplogs are written in, which carries the app
+	queue   chan *types.OpLogData
+	mu      sync.RWMutex // Guards closed against sending on a closed queue
+	closed  bool
+	once    sync.Once
+	done    chan struct{}
+	stop    context.CancelFunc
+	written atomic.Uint64
+	dropped atomic.Uint64
+	failed  atomic.Uint64
+	pruned  atomic.Uint64
+}
+
+// The pipeline of the configuration of the environment, used outside of apps
+var defaultPipeline = sync.OnceValue(func() *Pipeline {
+	return NewPipeline(context.Background(), config.GetConfig().OpLog)
+})
+
+// NewPipeline creates a pipeline writing the oplogs in the context, which
+// carries the app whose database they're written to.
+func NewPipeline(ctx context.Context, cfg config.OpLogConfig) *Pipeline {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Pipeline{
+		cfg:   cfg,
+		ctx:   ctx,
+		queue: make(chan *types.OpLogData, cfg.QueueSize),
+		done:  make(chan struct{}),
+		stop:  cancel,
+	}
+}
+
+// Register provides the oplog pipeline of the app, which starts with the app,
+// and writes the queued oplogs when the app stops. The changes in all of its
+// databases are recorded from the start, including the ones of the modules
+// starting before it. The expired oplogs are pruned by a recurring job, so it
+// must come after the job queue.
+func Register(a *app.App) error {
+	p := NewPipeline(a.Context(context.Background()), a.Config.OpLog)
+	app.Provide(a, p)
+	// The plugin is installed before the job queue starts using the connections
+	db.FromContext(a.Context(context.Background())).Use(&AuditPlugin{})
+	a.OnStart(func(ctx context.Context) error {
+		p.Start()
+		return nil
+	})
+	a.OnStop(p.Shutdown)
+
+	cfg := a.Config.OpLog
+	if cfg.Retention > 0 && cfg.Chained {
+		log.Printf("oplog retention is ignored in the audit mode")
+	} else if cfg.Retention > 0 {
+		q := jobs.ForApp(a)
+		jobs.Handle(q, func(ctx context.Context, job pruneOpLogs) error {
+			return p.prune(ctx, cfg.Retention)
+		})
+		// Recurring jobs are at least a second apart
+		interval := max(cfg.PruneInterval, time.Second)
+		jobs.Schedule(q, "oplog.prune", "@every "+interval.String(), pruneOpLogs{})
+	}
+	return nil
+}
+
+// FromContext returns the pipeline of the app the context carries, or the one
+// of the configuration of the environment outside of apps.
+func FromContext(ctx context.Context) *Pipeline {
+	if a := app.FromContext(ctx); a != nil {
+		if p, ok := app.Get[*Pipeline](a); ok {
+			return p
+		}
+	}
+	return defaultPipeline()
+}
+
+// Start starts the oplog writer in the background, it's optional since the
+// writer starts on the first oplog anyway.
+func (p *Pipeline) Start() {
+	p.once.Do(func() {
+		go p.run(p.cfg.BatchSize, p.cfg.FlushInterval)
+	})
+}
+
+// Shutdown stops accepting new oplogs and waits for the queued ones to be
+// written, or until the context is done.
+func (p *Pipeline) Shutdown(ctx context.Context) error {
+	p.Start()
+	func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if !p.closed {
+			p.closed = true
+			p.stop()
+			close(p.queue)
+		}
+	}()
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown shuts down the pipeline of the app the context carries, or the one
+// of the environment outside of apps.
+func Shutdown(ctx context.Context) error {
+	return FromContext(ctx).Shutdown(ctx)
+}
+
+// Stats returns the counters of the pipeline.
+func (p *Pipeline) Stats() Stats {
+	return Stats{
+		Queued:  len(p.queue),
+		Written: p.written.Load(),
+		Dropped: p.dropped.Load(),
+		Failed:  p.failed.Load(),
+		Pruned:  p.pruned.Load(),
+	}
+}
+
+// enqueue adds the oplog to the queue without blocking, the oplog is dropped if
+// the queue is full.
+func (p *Pipeline) enqueue(data *types.OpLogData) {
+	p.Start()
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		p.dropped.Add(1)
+		log.Printf("oplog dropped: %v (%s %s:%s)", ErrQueueClosed, data.Operation, data.ObjectType, data.ObjectId)
+		return
+	}
+
+	select {
+	case p.queue <- data:
+	default:
+		p.dropped.Add(1)
+		log.Printf("oplog dropped: queue is full (%s %s:%s)", data.Operation, data.ObjectType, data.ObjectId)
+	}
+}
+
+func (p *Pipeline) run(batchSize int, interval time.Duration) {
+	defer close(p.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	batch := make([]*types.OpLogData, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		// The oplogs are written even after the pipeline is stopped
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(p.ctx), 30*time.Second)
+		defer cancel()
+
+		if err := service.CreateOpLogs(ctx, batch); err != nil {
+			p.failed.Add(uint64(len(batch)))
+			log.Printf("failed to write %d oplogs: %v", len(batch), err)
+		} else {
+			p.written.Add(uint64(len(batch)))
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case data, ok := <-p.queue:
+			if !ok {
+				flush()
+				return
+			}
+
+			batch = append(batch, data)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// pruneOpLogs deletes the oplogs older than the retention period.
+type pruneOpLogs struct{}
+
+func (pruneOpLogs) JobName() string {
+	return "oplog.prune"
+}
+
+// prune deletes the oplogs older than the retention period.
+func (p *Pipeline) prune(ctx context.Context, retention time.Duration) error {
+	count, err := service.PruneOpLogs(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return err
+	}
+	p.pruned.Add(uint64(count))
+	return nil
+}
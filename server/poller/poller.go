@@ -0,0 +1,113 @@
+// Package poller runs the background loops that dispatch the due rows of a
+// table, e.g. the outbox of the events or the deliveries of the webhooks, which
+// are polled at an interval and right after new rows are committed.
+package poller
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Poller calls its poll function in the background, at the interval and
+// whenever it's notified, until it's shut down.
+type Poller struct {
+	name      string // What's dispatched, for the logs
+	interval  time.Duration
+	batchSize int
+	poll      func(ctx context.Context) (int, error)
+	ctx       context.Context
+	wake      chan struct{}
+	once      sync.Once
+	done      chan struct{}
+	stop      context.CancelFunc
+}
+
+// New creates a poller calling poll in the context, which carries the app whose
+// rows it dispatches. poll returns the number of due rows found, and is called
+// again right away as long as it finds full batches of batchSize rows. The
+// context passed to poll is canceled on shutdown.
+func New(ctx context.Context, name string, interval time.Duration, batchSize int, poll func(ctx context.Context) (int, error)) *Poller {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Poller{
+		name:      name,
+		interval:  interval,
+		batchSize: batchSize,
+		poll:      poll,
+		ctx:       ctx,
+		wake:      make(chan struct{}, 1),
+		done:      make(chan struct{}),
+		stop:      cancel,
+	}
+}
+
+// Start starts polling in the background.
+func (p *Poller) Start() {
+	p.once.Do(func() {
+		go p.run()
+	})
+}
+
+// Shutdown stops polling and waits for the running poll to return, or until
+// the context is done.
+func (p *Poller) Shutdown(ctx context.Context) error {
+	p.once.Do(func() {
+		close(p.done)
+	})
+	p.stop()
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Notify wakes the poller up without waiting for the next poll.
+func (p *Poller) Notify() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (p *Poller) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		// Full batches mean there may be more due rows
+		for p.ctx.Err() == nil {
+			count, err := p.poll(p.ctx)
+			if err != nil {
+				if p.ctx.Err() == nil {
+					log.Printf("failed to dispatch %s: %v", p.name, err)
+				}
+				break
+			}
+			if count < p.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-p.wake:
+		}
+	}
+}
+
+// Backoff returns the delay before the next attempt after the failed ones,
+// which starts at delay and doubles with every attempt up to maxDelay.
+func Backoff(attempts int, delay time.Duration, maxDelay time.Duration) time.Duration {
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
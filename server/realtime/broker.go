@@ -0,0 +1,166 @@
+package realtime
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"bilingo/config"
+	"bilingo/server/db"
+)
+
+// DBBinding is the binding of the database the db broker passes the messages
+// through, which all instances of the server must share.
+const DBBinding = "realtime"
+
+// How long the db broker keeps the messages, for the instances polling late
+const dbMessageRetention = time.Minute
+
+// Broker delivers the messages published on any instance of the server to the
+// hubs of all instances listening to it. Implementations backed by other
+// message queues, e.g. Redis or NATS, are used by providing them to the app
+// before Register.
+type Broker interface {
+	// Publish delivers the message to the listeners, in the context of the hub,
+	// which carries the app.
+	Publish(ctx context.Context, msg Message) error
+	// Listen calls fn with the messages published from now on, one at a time,
+	// until the context is done.
+	Listen(ctx context.Context, fn func(msg Message)) error
+}
+
+// NewBroker returns the broker of the configuration.
+func NewBroker(cfg config.RealtimeConfig) Broker {
+	switch cfg.Broker {
+	case config.RealtimeDB:
+		return &DBBroker{PollInterval: cfg.PollInterval}
+	default:
+		return &MemoryBroker{}
+	}
+}
+
+// MemoryBroker delivers the messages to the hubs listening to it in the same
+// process, which is enough for a single instance of the server.
+type MemoryBroker struct {
+	mu        sync.RWMutex
+	listeners map[*listener]struct{}
+}
+
+type listener struct {
+	mu sync.Mutex // Delivers the messages to fn one at a time
+	fn func(msg Message)
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, msg Message) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for l := range b.listeners {
+		l.mu.Lock()
+		l.fn(msg)
+		l.mu.Unlock()
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Listen(ctx context.Context, fn func(msg Message)) error {
+	l := &listener{fn: fn}
+	b.mu.Lock()
+	if b.listeners == nil {
+		b.listeners = map[*listener]struct{}{}
+	}
+	b.listeners[l] = struct{}{}
+	b.mu.Unlock()
+
+	<-ctx.Done()
+	b.mu.Lock()
+	delete(b.listeners, l)
+	b.mu.Unlock()
+	return nil
+}
+
+// DBBroker passes the messages through a table of the database, which every
+// instance of the server polls for the ones it hasn't seen.
+type DBBroker struct {
+	PollInterval time.Duration
+}
+
+// realtimeMessage is a message of the db broker, kept for dbMessageRetention.
+type realtimeMessage struct {
+	ID        uint      `gorm:"primaryKey"`
+	Topic     string    `gorm:"size:255;not null"`
+	Event     string    `gorm:"size:128;not null"`
+	Data      string    `gorm:"type:text;not null"`
+	CreatedAt time.Time `gorm:"not null;index"`
+}
+
+func (m *realtimeMessage) TableName() string {
+	return "realtime_message"
+}
+
+func init() {
+	db.RegisterMigrations(
+		DBBinding,
+		db.Migration{ID: "2026101915_realtime_create_realtime_message_table", Up: db.CreateTableIfNotExists(&realtimeMessage{})},
+	)
+}
+
+func (b *DBBroker) Publish(ctx context.Context, msg Message) error {
+	conn, err := db.For(ctx, DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+
+	row := &realtimeMessage{Topic: msg.Topic, Event: msg.Event, Data: string(msg.Data), CreatedAt: msg.Time}
+	if err := conn.WithContext(ctx).Create(row).Error; err != nil {
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+	return nil
+}
+
+func (b *DBBroker) Listen(ctx context.Context, fn func(msg Message)) error {
+	conn, err := db.For(ctx, DBBinding)
+	if err != nil {
+		return db.ConnError(err)
+	}
+	conn = conn.WithContext(ctx)
+
+	// Only the messages published from now on are delivered
+	var last uint
+	if err := conn.Model(&realtimeMessage{}).Select("COALESCE(MAX(id), 0)").Scan(&last).Error; err != nil {
+		return fmt.Errorf("failed to get the last message: %w", err)
+	}
+
+	ticker := time.NewTicker(b.PollInterval)
+	defer ticker.Stop()
+	pruned := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		var rows []realtimeMessage
+		if err := conn.Where("id > ?", last).Order("id").Limit(500).Find(&rows).Error; err != nil {
+			if ctx.Err() == nil {
+				log.Printf("failed to get realtime messages: %v", err)
+			}
+			continue
+		}
+		for _, row := range rows {
+			fn(Message{Topic: row.Topic, Event: row.Event, Data: []byte(row.Data), Time: row.CreatedAt})
+			last = row.ID
+		}
+
+		// Any instance may delete the messages all of them have seen
+		if time.Since(pruned) > dbMessageRetention {
+			pruned = time.Now()
+			err := conn.Where("created_at < ?", pruned.Add(-dbMessageRetention)).Delete(&realtimeMessage{}).Error
+			if err != nil && ctx.Err() == nil {
+				log.Printf("failed to prune realtime messages: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package realtime
+
+import (
+	"fmt"
+	"sync"
+)
+
+// conn is the connection of a client, to which the messages of its topics are
+// sent by the transport serving it.
+type conn struct {
+	messages  chan Message
+	topics    map[string]struct{} // Guarded by Hub.mu
+	dropped   chan struct{}       // Closed once the client falls behind by Realtime.BufferSize
+	closeOnce sync.Once
+}
+
+// connect creates the connection of a client, without topics.
+func (h *Hub) connect() *conn {
+	return &conn{
+		messages: make(chan Message, h.cfg.BufferSize),
+		topics:   map[string]struct{}{},
+		dropped:  make(chan struct{}),
+	}
+}
+
+// subscribe subscribes the connection to the authorized topics, up to
+// Realtime.MaxTopics in total.
+func (h *Hub) subscribe(c *conn, topics []string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	count := len(c.topics)
+	for _, topic := range topics {
+		if _, ok := c.topics[topic]; !ok {
+			count++
+		}
+	}
+	if count > h.cfg.MaxTopics {
+		return fmt.Errorf("%w: at most %d", ErrTooManyTopics, h.cfg.MaxTopics)
+	}
+
+	for _, topic := range topics {
+		c.topics[topic] = struct{}{}
+		conns, ok := h.topics[topic]
+		if !ok {
+			conns = map[*conn]struct{}{}
+			h.topics[topic] = conns
+		}
+		conns[c] = struct{}{}
+	}
+	return nil
+}
+
+// unsubscribe unsubscribes the connection from the topics.
+func (h *Hub) unsubscribe(c *conn, topics []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, topic := range topics {
+		delete(c.topics, topic)
+		if conns, ok := h.topics[topic]; ok {
+			delete(conns, c)
+			if len(conns) == 0 {
+				delete(h.topics, topic)
+			}
+		}
+	}
+}
+
+// disconnect unsubscribes the connection from all of its topics.
+func (h *Hub) disconnect(c *conn) {
+	h.mu.RLock()
+	topics := make([]string, 0, len(c.topics))
+	for topic := range c.topics {
+		topics = append(topics, topic)
+	}
+	h.mu.RUnlock()
+	h.unsubscribe(c, topics)
+}
+
+// push queues the message for the connection, which is dropped rather than
+// holding up the others if it's too far behind.
+func (c *conn) push(msg Message) {
+	select {
+	case c.messages <- msg:
+	default:
+		c.closeOnce.Do(func() {
+			close(c.dropped)
+		})
+	}
+}
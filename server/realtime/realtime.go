@@ -0,0 +1,239 @@
+// Package realtime pushes the changes of the domains to the clients watching
+// them, over Server-Sent Events or WebSocket. Clients subscribe to topics, e.g.
+// `article:42` or `comments:article:42`, the first segment of which is the
+// kind of the topic. Domains authorize the subscriptions to the kinds of their
+// own with Authorize, and publish to the topics from their services with
+// Publish, once the transaction of the context is committed.
+//
+// Messages go through a Broker, which delivers them to the hubs of all
+// instances of the server subscribed to it, so that clients receive them
+// whichever instance they're connected to.
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"bilingo/config"
+	"bilingo/domains/user/models"
+	"bilingo/server/app"
+	"bilingo/server/db"
+)
+
+var (
+	ErrInvalidTopic  = errors.New("invalid topic")
+	ErrTopicNotFound = errors.New("topic not found")
+	ErrTooManyTopics = errors.New("too many topics")
+)
+
+// Message is a change published to a topic.
+type Message struct {
+	Topic string          `json:"topic"`
+	Event string          `json:"event"` // What happened, e.g. `article.updated`
+	Data  json.RawMessage `json:"data"`
+	Time  time.Time       `json:"time"`
+}
+
+// Authorizer checks whether the user, nil if not logged in, may subscribe to a
+// topic of a kind, given the key of the topic after the kind, e.g. `42` of
+// `article:42`. It returns ErrInvalidTopic if the key is malformed,
+// ErrTopicNotFound if what the topic is about doesn't exist, and
+// auth.ErrUnauthorized or auth.ErrForbidden if the user may not subscribe.
+type Authorizer func(ctx context.Context, user *models.User, key string) error
+
+// Hub keeps the connections of the clients of an app by the topics they're
+// subscribed to, and delivers the messages of the broker to them. An app has a
+// hub of its own.
+type Hub struct {
+	cfg         config.RealtimeConfig
+	ctx         context.Context // The context of the hub, which carries the app
+	broker      Broker
+	mu          sync.RWMutex
+	authorizers map[string]Authorizer
+	topics      map[string]map[*conn]struct{}
+	once        sync.Once
+	done        chan struct{}
+	stop        context.CancelFunc
+}
+
+// The hub of the configuration of the environment, used outside of apps
+var defaultHub = sync.OnceValue(func() *Hub {
+	cfg := config.GetConfig().Realtime
+	return NewHub(context.Background(), cfg, NewBroker(cfg))
+})
+
+// NewHub creates a hub delivering the messages of the broker, whose connections
+// are served in the context, which carries the app.
+func NewHub(ctx context.Context, cfg config.RealtimeConfig, broker Broker) *Hub {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Hub{
+		cfg:         cfg,
+		ctx:         ctx,
+		broker:      broker,
+		authorizers: map[string]Authorizer{},
+		topics:      map[string]map[*conn]struct{}{},
+		done:        make(chan struct{}),
+		stop:        cancel,
+	}
+}
+
+// Register provides the realtime hub of the app, which receives the messages of
+// the broker while the app is running, and adds the routes the clients connect
+// to. The broker is the one provided before, if any, e.g. of another message
+// queue, or the one of Realtime.Broker in the configuration. Modules
+// authorizing topics must come after it.
+func Register(a *app.App) error {
+	broker, ok := app.Get[Broker](a)
+	if !ok {
+		broker = NewBroker(a.Config.Realtime)
+		app.Provide(a, broker)
+	}
+	h := NewHub(a.Context(context.Background()), a.Config.Realtime, broker)
+	app.Provide(a, h)
+
+	a.OnStart(func(ctx context.Context) error {
+		h.Start()
+		return nil
+	})
+	a.OnStop(h.Shutdown)
+	registerRoutes(a)
+	return nil
+}
+
+// FromContext returns the hub of the app the context carries, or the one of the
+// configuration of the environment outside of apps.
+func FromContext(ctx context.Context) *Hub {
+	if a := app.FromContext(ctx); a != nil {
+		if h, ok := app.Get[*Hub](a); ok {
+			return h
+		}
+	}
+	return defaultHub()
+}
+
+// ForApp returns the hub of the app, for the modules to authorize topics.
+func ForApp(a *app.App) *Hub {
+	return FromContext(a.Context(context.Background()))
+}
+
+// Topic joins the kind and the keys of a topic, e.g. `comments:article:42`.
+func Topic(kind string, keys ...string) string {
+	return strings.Join(append([]string{kind}, keys...), ":")
+}
+
+// Authorize registers the authorizer of the topics of the kind, e.g. `article`.
+// The topics of kinds without authorizers can't be subscribed to. A kind can
+// only have one authorizer.
+func Authorize(h *Hub, kind string, fn Authorizer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.authorizers[kind]; ok {
+		panic(fmt.Sprintf("realtime: authorizer of %s already exists", kind))
+	}
+	h.authorizers[kind] = fn
+}
+
+// Publish publishes the data to the topic through the hub of the app the
+// context carries, see Hub.Publish.
+func Publish(ctx context.Context, topic string, event string, data any) error {
+	return FromContext(ctx).Publish(ctx, topic, event, data)
+}
+
+// Publish publishes the data, encoded in JSON, to the subscribers of the topic
+// on all instances of the server once the transaction of the context is
+// committed, or right away outside of transactions. Messages are delivered at
+// most once, clients reload what they show after reconnecting, so failures to
+// publish are only logged.
+func (h *Hub) Publish(ctx context.Context, topic string, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode message %s: %w", event, err)
+	}
+
+	msg := Message{Topic: topic, Event: event, Data: payload, Time: time.Now()}
+	db.AfterCommit(ctx, func() {
+		if err := h.broker.Publish(h.ctx, msg); err != nil && h.ctx.Err() == nil {
+			log.Printf("failed to publish message %s to %s: %v", event, topic, err)
+		}
+	})
+	return nil
+}
+
+// Start starts receiving the messages of the broker in the background.
+func (h *Hub) Start() {
+	h.once.Do(func() {
+		go h.run()
+	})
+}
+
+// Shutdown stops receiving messages and closes the connections, waiting for the
+// broker to stop or until the context is done.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.once.Do(func() {
+		close(h.done)
+	})
+	h.stop()
+
+	select {
+	case <-h.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (h *Hub) run() {
+	defer close(h.done)
+	for h.ctx.Err() == nil {
+		err := h.broker.Listen(h.ctx, h.deliver)
+		if err == nil || h.ctx.Err() != nil {
+			return
+		}
+		log.Printf("failed to receive realtime messages: %v", err)
+
+		select {
+		case <-h.ctx.Done():
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// authorize checks that the topics are valid and the user, nil if not logged
+// in, may subscribe to them.
+func (h *Hub) authorize(ctx context.Context, user *models.User, topics []string) error {
+	if len(topics) > h.cfg.MaxTopics {
+		return fmt.Errorf("%w: at most %d", ErrTooManyTopics, h.cfg.MaxTopics)
+	}
+	for _, topic := range topics {
+		kind, key, ok := strings.Cut(topic, ":")
+		if !ok || kind == "" || key == "" {
+			return fmt.Errorf("%w: %s", ErrInvalidTopic, topic)
+		}
+
+		h.mu.RLock()
+		fn, ok := h.authorizers[kind]
+		h.mu.RUnlock()
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrInvalidTopic, topic)
+		}
+		if err := fn(ctx, user, key); err != nil {
+			return fmt.Errorf("%w: %s", err, topic)
+		}
+	}
+	return nil
+}
+
+// deliver sends the message to the connections subscribed to its topic.
+func (h *Hub) deliver(msg Message) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for c := range h.topics[msg.Topic] {
+		c.push(msg)
+	}
+}
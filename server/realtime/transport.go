@@ -0,0 +1,283 @@
+package realtime
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"bilingo/server"
+	"bilingo/server/app"
+	"bilingo/server/auth"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+// The locals passing the request to the WebSocket handler
+const (
+	localContext = "realtime.context" // The user context of the request
+	localTopics  = "realtime.topics"  // The authorized topics of the query
+	localDone    = "realtime.done"    // Closed when the server shuts down
+)
+
+// How long writing a message to a client may take before it's disconnected
+const writeTimeout = 10 * time.Second
+
+// command is a message of a WebSocket client, e.g.
+// `{"action": "subscribe", "topics": ["article:42"]}`.
+type command struct {
+	Action string   `json:"action"` // Either subscribe or unsubscribe
+	Topics []string `json:"topics"`
+}
+
+// The events of the replies to the commands of WebSocket clients, which have
+// no topic
+const (
+	EventSubscribed   = "realtime.subscribed"   // The data is the topics subscribed to
+	EventUnsubscribed = "realtime.unsubscribed" // The data is the topics unsubscribed from
+	EventError        = "realtime.error"        // The data is the message of the error
+)
+
+// registerRoutes adds the routes the clients connect to, where they subscribe
+// to the topics in the `topics` query, separated by commas. Anyone may connect,
+// what they may subscribe to is up to the authorizers of the topics.
+func registerRoutes(a *app.App) {
+	api := a.Group("/realtime", auth.UseAuth)
+	api.Get("/sse", streamEvents)
+	api.Get("/ws", upgradeWebSocket, websocket.New(serveWebSocket))
+}
+
+// parseTopics parses the topics separated by commas, without duplicates.
+func parseTopics(query string) []string {
+	topics := []string{}
+	seen := map[string]bool{}
+	for topic := range strings.SplitSeq(query, ",") {
+		topic = strings.TrimSpace(topic)
+		if topic != "" && !seen[topic] {
+			seen[topic] = true
+			topics = append(topics, topic)
+		}
+	}
+	return topics
+}
+
+// topicError responds with the status of the error of subscribing to topics.
+func topicError(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, ErrInvalidTopic), errors.Is(err, ErrTooManyTopics):
+		return server.Error(ctx, 400, err)
+	case errors.Is(err, ErrTopicNotFound):
+		return server.Error(ctx, 404, err)
+	case errors.Is(err, auth.ErrUnauthorized):
+		return server.Error(ctx, 401, err)
+	case errors.Is(err, auth.ErrForbidden):
+		return server.Error(ctx, 403, err)
+	default:
+		return server.Error(ctx, 500, err)
+	}
+}
+
+// streamEvents streams the messages of the topics as Server-Sent Events, named
+// after the events of the messages, with the messages as their data. Comments
+// are sent while idle to keep the connection open.
+func streamEvents(ctx *fiber.Ctx) error {
+	userCtx := ctx.UserContext()
+	h := FromContext(userCtx)
+	topics := parseTopics(ctx.Query("topics"))
+	if len(topics) == 0 {
+		return server.Error(ctx, 400, fmt.Errorf("%w: no topics", ErrInvalidTopic))
+	}
+	if err := h.authorize(userCtx, auth.GetUser(userCtx), topics); err != nil {
+		return topicError(ctx, err)
+	}
+
+	c := h.connect()
+	if err := h.subscribe(c, topics); err != nil {
+		return topicError(ctx, err)
+	}
+
+	ctx.Set(fiber.HeaderContentType, "text/event-stream")
+	ctx.Set(fiber.HeaderCacheControl, "no-cache")
+	ctx.Set(fiber.HeaderConnection, "keep-alive")
+	ctx.Set("X-Accel-Buffering", "no")
+
+	// The channel is reset once the server is shut down, so it's kept first
+	done := ctx.Context().Done()
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer h.disconnect(c)
+		heartbeat := time.NewTicker(h.cfg.Heartbeat)
+		defer heartbeat.Stop()
+
+		// Clients learn they're subscribed right away
+		fmt.Fprint(w, ": connected\n\n")
+		for w.Flush() == nil {
+			select {
+			case <-done:
+				return
+			case <-h.ctx.Done():
+				return
+			case <-c.dropped:
+				return
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": ping\n\n")
+			case msg := <-c.messages:
+				data, err := json.Marshal(msg)
+				if err != nil {
+					return
+				}
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.Event, data)
+			}
+		}
+	})
+	return nil
+}
+
+// upgradeWebSocket authorizes the topics of the query before upgrading the
+// connection, and passes them on to serveWebSocket. Browsers send the cookies
+// of the app along with WebSocket requests from other sites, so those are
+// rejected.
+func upgradeWebSocket(ctx *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(ctx) {
+		return fiber.ErrUpgradeRequired
+	}
+	if !sameOrigin(ctx) {
+		return server.Error(ctx, 403, fmt.Errorf("%w: origin not allowed", auth.ErrForbidden))
+	}
+
+	userCtx := ctx.UserContext()
+	topics := parseTopics(ctx.Query("topics"))
+	if err := FromContext(userCtx).authorize(userCtx, auth.GetUser(userCtx), topics); err != nil {
+		return topicError(ctx, err)
+	}
+
+	ctx.Locals(localContext, userCtx)
+	ctx.Locals(localTopics, topics)
+	ctx.Locals(localDone, ctx.Context().Done())
+	return ctx.Next()
+}
+
+// sameOrigin reports whether the request comes from the app, or from the
+// server itself, or isn't from a browser.
+func sameOrigin(ctx *fiber.Ctx) bool {
+	origin := ctx.Get(fiber.HeaderOrigin)
+	if origin == "" || origin == ctx.BaseURL() {
+		return true
+	}
+	appUrl, err := url.Parse(app.Config(ctx.UserContext()).AppUrl)
+	return err == nil && origin == appUrl.Scheme+"://"+appUrl.Host
+}
+
+// serveWebSocket sends the messages of the topics as JSON, and takes commands
+// to subscribe to or unsubscribe from topics, which are replied to with the
+// Event* events. Pings are sent while idle, and clients not answering them are
+// disconnected.
+func serveWebSocket(ws *websocket.Conn) {
+	userCtx := ws.Locals(localContext).(context.Context)
+	done := ws.Locals(localDone).(<-chan struct{})
+	h := FromContext(userCtx)
+
+	c := h.connect()
+	defer h.disconnect(c)
+
+	// The commands are read in the background, until the connection is closed
+	replies := make(chan Message, 8)
+	closing := make(chan struct{})
+	reply := func(event string, data any) bool {
+		payload, _ := json.Marshal(data)
+		select {
+		case replies <- Message{Event: event, Data: payload, Time: time.Now()}:
+			return true
+		case <-closing:
+			return false
+		}
+	}
+	if err := h.subscribe(c, ws.Locals(localTopics).([]string)); err != nil {
+		reply(EventError, err.Error())
+	}
+
+	readDone := make(chan struct{})
+	go func() {
+		defer close(readDone)
+		_ = ws.SetReadDeadline(time.Now().Add(2 * h.cfg.Heartbeat))
+		ws.SetPongHandler(func(string) error {
+			return ws.SetReadDeadline(time.Now().Add(2 * h.cfg.Heartbeat))
+		})
+
+		for {
+			_, data, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			_ = ws.SetReadDeadline(time.Now().Add(2 * h.cfg.Heartbeat))
+
+			var cmd command
+			if err := json.Unmarshal(data, &cmd); err != nil {
+				if !reply(EventError, "malformed command") {
+					return
+				}
+				continue
+			}
+			if !reply(h.handleCommand(userCtx, c, cmd)) {
+				return
+			}
+		}
+	}()
+	defer func() {
+		close(closing)
+		_ = ws.Close()
+		<-readDone
+	}()
+
+	heartbeat := time.NewTicker(h.cfg.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		var err error
+		select {
+		case <-done:
+			return
+		case <-h.ctx.Done():
+			return
+		case <-c.dropped:
+			return
+		case <-readDone:
+			return
+		case <-heartbeat.C:
+			err = ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout))
+		case msg := <-replies:
+			_ = ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+			err = ws.WriteJSON(msg)
+		case msg := <-c.messages:
+			_ = ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+			err = ws.WriteJSON(msg)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// handleCommand runs the command of a WebSocket client, and returns the event
+// and the data of the reply.
+func (h *Hub) handleCommand(ctx context.Context, c *conn, cmd command) (string, any) {
+	topics := parseTopics(strings.Join(cmd.Topics, ","))
+	switch cmd.Action {
+	case "subscribe":
+		if err := h.authorize(ctx, auth.GetUser(ctx), topics); err != nil {
+			return EventError, err.Error()
+		}
+		if err := h.subscribe(c, topics); err != nil {
+			return EventError, err.Error()
+		}
+		return EventSubscribed, topics
+	case "unsubscribe":
+		h.unsubscribe(c, topics)
+		return EventUnsubscribed, topics
+	default:
+		return EventError, fmt.Sprintf("unknown action %q", cmd.Action)
+	}
+}
@@ -0,0 +1,48 @@
+package realtime_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"bilingo/server/testutil"
+)
+
+func TestSubscribeAuthorization(t *testing.T) {
+	t.Parallel()
+	a := testutil.NewApp(t)
+	fixtures := testutil.LoadFixtures(t, a)
+	alice := fixtures.User(t, "alice@example.com")
+	bob := fixtures.User(t, "bob@example.com")
+	client := testutil.NewClient(t, a)
+
+	subscribe := func(topics string) *testutil.Response {
+		t.Helper()
+		return client.Get("/realtime/sse?topics=" + url.QueryEscape(topics))
+	}
+
+	if resp := subscribe("notifications:" + alice.ID); resp.Status != http.StatusUnauthorized {
+		t.Fatalf("subscribed to notifications logged out: %s", resp)
+	}
+
+	client.LoginAs(alice.ID)
+	tests := []struct {
+		topics string
+		status int
+	}{
+		{"notifications:" + bob.ID, http.StatusForbidden},
+		// Every topic is authorized, not only the first
+		{"notifications:" + alice.ID + ",notifications:" + bob.ID, http.StatusForbidden},
+		{"unknown:1", http.StatusBadRequest},
+		{"notifications:" + alice.ID + ",unknown:1", http.StatusBadRequest},
+		{"notifications", http.StatusBadRequest},
+		{"notifications:", http.StatusBadRequest},
+		{":" + alice.ID, http.StatusBadRequest},
+		{"", http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		if resp := subscribe(test.topics); resp.Status != test.status {
+			t.Errorf("expected %d subscribing to %q, got %s", test.status, test.topics, resp)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package server
 
 import (
 	"context"
+	"reflect"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -10,6 +12,27 @@ type contextKey string
 
 const ipContextKey = contextKey("ip")
 
+func init() {
+	// Allow query and form parsers to decode RFC3339 timestamps into time.Time
+	fiber.SetParserDecoder(fiber.ParserConfig{
+		IgnoreUnknownKeys: true,
+		ZeroEmpty:         true,
+		ParserType: []fiber.ParserType{{
+			Customtype: time.Time{},
+			Converter:  parseTime,
+		}},
+	})
+}
+
+func parseTime(value string) reflect.Value {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		// An invalid value makes the parser report a conversion error
+		return reflect.Value{}
+	}
+	return reflect.ValueOf(t)
+}
+
 func ipMiddleware(ctx *fiber.Ctx) error {
 	ip := ctx.IP()
 
@@ -19,6 +42,12 @@ func ipMiddleware(ctx *fiber.Ctx) error {
 	return ctx.Next()
 }
 
+// WithClientIp returns a context carrying the client IP, e.g. for background
+// work done on behalf of the request.
+func WithClientIp(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, ipContextKey, ip)
+}
+
 // GetClientIp retrieves the client IP of the HTTP request from the context,
 // if not available, returns an empty string.
 func GetClientIp(ctx context.Context) string {
@@ -0,0 +1,284 @@
+// Package mockidp is a minimal OpenID Connect issuer for developing and testing
+// single sign-on without a real identity provider. It logs users in without
+// asking for credentials, so it must never be exposed publicly.
+package mockidp
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"html/template"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// User is a user of the mock issuer.
+type User struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type authCode struct {
+	clientId      string
+	redirectUri   string
+	nonce         string
+	codeChallenge string
+	user          User
+	expiresAt     time.Time
+}
+
+// Server is the mock issuer, it serves the discovery document, the signing keys
+// and the authorization, token and userinfo endpoints.
+type Server struct {
+	Issuer       string // The URL the server is reached at
+	ClientId     string
+	ClientSecret string // Empty means the client is public
+	// The users to log in, selected by the `login_hint` parameter, which is the
+	// email of the user. Unknown hints log in a new verified user of the email,
+	// and without a hint the first user is logged in, or the email is asked if
+	// there are no users.
+	Users []User
+
+	once   sync.Once
+	key    *rsa.PrivateKey
+	mu     sync.Mutex
+	codes  map[string]*authCode
+	tokens map[string]User // Access tokens
+	mux    *http.ServeMux
+}
+
+func (s *Server) init() {
+	s.once.Do(func() {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			panic(err)
+		}
+		s.key = key
+		s.codes = map[string]*authCode{}
+		s.tokens = map[string]User{}
+
+		s.mux = http.NewServeMux()
+		s.mux.HandleFunc("GET /.well-known/openid-configuration", s.discovery)
+		s.mux.HandleFunc("GET /jwks", s.jwks)
+		s.mux.HandleFunc("GET /authorize", s.authorize)
+		s.mux.HandleFunc("POST /token", s.token)
+		s.mux.HandleFunc("GET /userinfo", s.userinfo)
+	})
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.init()
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	issuer := strings.TrimSuffix(s.Issuer, "/")
+	writeJson(w, http.StatusOK, map[string]any{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/authorize",
+		"token_endpoint":                        issuer + "/token",
+		"userinfo_endpoint":                     issuer + "/userinfo",
+		"jwks_uri":                              issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := s.key.PublicKey
+	writeJson(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": "mock",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// authorize logs the user in at once and redirects back with the code.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectUri, err := url.Parse(query.Get("redirect_uri"))
+	switch {
+	case query.Get("client_id") != s.ClientId:
+		http.Error(w, "unknown client", http.StatusBadRequest)
+		return
+	case err != nil || !redirectUri.IsAbs():
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	case query.Get("response_type") != "code":
+		http.Error(w, "unsupported response_type", http.StatusBadRequest)
+		return
+	case query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256":
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+
+	hint := query.Get("login_hint")
+	if hint == "" && len(s.Users) == 0 {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = loginForm.Execute(w, query)
+		return
+	}
+
+	code := randomString()
+	s.mu.Lock()
+	s.codes[code] = &authCode{
+		clientId:      s.ClientId,
+		redirectUri:   redirectUri.String(),
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+		user:          s.findUser(hint),
+		expiresAt:     time.Now().Add(time.Minute),
+	}
+	s.mu.Unlock()
+
+	params := redirectUri.Query()
+	params.Set("code", code)
+	params.Set("state", query.Get("state"))
+	redirectUri.RawQuery = params.Encode()
+	http.Redirect(w, r, redirectUri.String(), http.StatusFound)
+}
+
+func (s *Server) findUser(hint string) User {
+	if hint == "" && len(s.Users) > 0 {
+		return s.Users[0]
+	}
+	for _, user := range s.Users {
+		if strings.EqualFold(user.Email, hint) {
+			return user
+		}
+	}
+	name, _, _ := strings.Cut(hint, "@")
+	return User{Subject: "mock|" + strings.ToLower(hint), Email: hint, EmailVerified: true, Name: name}
+}
+
+var loginForm = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Mock SSO</title></head>
+<body>
+<form method="get" action="authorize">
+{{range $key, $values := .}}{{if ne $key "login_hint"}}{{range $values}}<input type="hidden" name="{{$key}}" value="{{.}}">
+{{end}}{{end}}{{end}}<label>Log in as <input type="email" name="login_hint" required autofocus></label>
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeTokenError(w, "invalid_request")
+		return
+	}
+
+	clientId, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientId, _ = url.QueryUnescape(clientId)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientId, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientId != s.ClientId || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(s.ClientSecret)) != 1 {
+		writeJson(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	} else if r.PostForm.Get("grant_type") != "authorization_code" {
+		writeTokenError(w, "unsupported_grant_type")
+		return
+	}
+
+	// Codes can only be used once
+	s.mu.Lock()
+	code, ok := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	s.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+	if !ok || time.Now().After(code.expiresAt) || code.clientId != clientId ||
+		code.redirectUri != r.PostForm.Get("redirect_uri") || code.codeChallenge != challenge {
+		writeTokenError(w, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            strings.TrimSuffix(s.Issuer, "/"),
+		"sub":            code.user.Subject,
+		"aud":            clientId,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          code.nonce,
+		"email":          code.user.Email,
+		"email_verified": code.user.EmailVerified,
+		"name":           code.user.Name,
+	})
+	idToken.Header["kid"] = "mock"
+	signed, err := idToken.SignedString(s.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	accessToken := randomString()
+	s.mu.Lock()
+	s.tokens[accessToken] = code.user
+	s.mu.Unlock()
+
+	writeJson(w, http.StatusOK, map[string]any{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     signed,
+	})
+}
+
+func (s *Server) userinfo(w http.ResponseWriter, r *http.Request) {
+	accessToken, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	s.mu.Lock()
+	user, ok := s.tokens[accessToken]
+	s.mu.Unlock()
+	if !ok {
+		writeJson(w, http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+		return
+	}
+
+	writeJson(w, http.StatusOK, map[string]any{
+		"sub":            user.Subject,
+		"email":          user.Email,
+		"email_verified": user.EmailVerified,
+		"name":           user.Name,
+	})
+}
+
+func writeTokenError(w http.ResponseWriter, code string) {
+	writeJson(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeJson(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func randomString() string {
+	buf := make([]byte, 24)
+	_, _ = rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
@@ -0,0 +1,392 @@
+package sso
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"bilingo/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// jwksRefetchInterval is the least time between the fetches of the signing
+// keys, so tokens of unknown key IDs can't make the provider fetch them all
+// the time.
+const jwksRefetchInterval = time.Minute
+
+// OidcProvider is an OpenID Connect identity provider, its endpoints and
+// signing keys are discovered from the issuer.
+type OidcProvider struct {
+	cfg config.SsoProviderConfig
+
+	mu            sync.Mutex
+	discovery     *oidcDiscovery
+	keys          map[string]any // The signing keys by key ID
+	keysMu        sync.Mutex     // Held while fetching the keys
+	keysFetchedAt time.Time      // When the keys were last fetched, or tried to
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+type oidcClaims struct {
+	jwt.RegisteredClaims
+	Nonce         string `json:"nonce"`
+	Email         string `json:"email"`
+	EmailVerified any    `json:"email_verified"` // Some providers send it as a string
+	Name          string `json:"name"`
+}
+
+func NewOidcProvider(cfg config.SsoProviderConfig) *OidcProvider {
+	if cfg.DisplayName == "" {
+		cfg.DisplayName = cfg.Name
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	cfg.Issuer = strings.TrimSuffix(cfg.Issuer, "/")
+	return &OidcProvider{cfg: cfg}
+}
+
+func (p *OidcProvider) Name() string {
+	return p.cfg.Name
+}
+
+func (p *OidcProvider) DisplayName() string {
+	return p.cfg.DisplayName
+}
+
+func (p *OidcProvider) Settings() config.SsoProviderConfig {
+	return p.cfg
+}
+
+func (p *OidcProvider) AuthCodeURL(ctx context.Context, redirectUri string, state string, nonce string, verifier string) (string, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientId},
+		"redirect_uri":          {redirectUri},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {CodeChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	sep := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return discovery.AuthorizationEndpoint + sep + query.Encode(), nil
+}
+
+func (p *OidcProvider) Exchange(ctx context.Context, redirectUri string, code string, nonce string, verifier string) (*Identity, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectUri},
+		"client_id":     {p.cfg.ClientId},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientId), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	var tokens struct {
+		AccessToken string `json:"access_token"`
+		IdToken     string `json:"id_token"`
+	}
+	if err := doJson(req, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to exchange authorization code: %w", err)
+	} else if tokens.IdToken == "" {
+		return nil, fmt.Errorf("%w: missing in the token response", ErrInvalidIdToken)
+	}
+
+	claims, err := p.verifyIdToken(ctx, tokens.IdToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{
+		Provider:      p.cfg.Name,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: isTrue(claims.EmailVerified),
+		Name:          claims.Name,
+	}
+
+	// Some providers only include the profile in the userinfo
+	if identity.Email == "" && discovery.UserinfoEndpoint != "" && tokens.AccessToken != "" {
+		if err := p.fetchUserinfo(ctx, discovery.UserinfoEndpoint, tokens.AccessToken, identity); err != nil {
+			return nil, err
+		}
+	}
+
+	return identity, nil
+}
+
+func (p *OidcProvider) verifyIdToken(ctx context.Context, idToken string, nonce string) (*oidcClaims, error) {
+	var claims oidcClaims
+	_, err := jwt.ParseWithClaims(idToken, &claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.signingKey(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(p.cfg.Issuer),
+		jwt.WithAudience(p.cfg.ClientId),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIdToken, err)
+	} else if claims.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIdToken)
+	} else if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIdToken)
+	}
+
+	return &claims, nil
+}
+
+func (p *OidcProvider) fetchUserinfo(ctx context.Context, endpoint string, accessToken string, identity *Identity) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	var info struct {
+		Subject       string `json:"sub"`
+		Email         string `json:"email"`
+		EmailVerified any    `json:"email_verified"`
+		Name          string `json:"name"`
+	}
+	if err := doJson(req, &info); err != nil {
+		return fmt.Errorf("failed to fetch userinfo: %w", err)
+	} else if info.Subject != identity.Subject {
+		return fmt.Errorf("%w: userinfo subject mismatch", ErrInvalidIdToken)
+	}
+
+	identity.Email = info.Email
+	identity.EmailVerified = isTrue(info.EmailVerified)
+	if identity.Name == "" {
+		identity.Name = info.Name
+	}
+	return nil
+}
+
+// discover fetches the discovery document of the issuer once.
+func (p *OidcProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var discovery oidcDiscovery
+	if err := doJson(req, &discovery); err != nil {
+		return nil, fmt.Errorf("failed to discover %s: %w", p.cfg.Issuer, err)
+	} else if strings.TrimSuffix(discovery.Issuer, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("failed to discover %s: issuer mismatch %s", p.cfg.Issuer, discovery.Issuer)
+	}
+
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+// signingKey returns the key of the ID, the keys are fetched again if the ID
+// is unknown, since the provider may have rotated them, at most once every
+// jwksRefetchInterval.
+func (p *OidcProvider) signingKey(ctx context.Context, kid string) (any, error) {
+	if key, ok := p.cachedKey(kid); ok {
+		return key, nil
+	}
+
+	// Only one of the concurrent logins fetches the keys
+	p.keysMu.Lock()
+	defer p.keysMu.Unlock()
+	if key, ok := p.cachedKey(kid); ok {
+		return key, nil
+	} else if time.Since(p.keysFetchedAt) < jwksRefetchInterval {
+		return nil, fmt.Errorf("signing key %q not found", kid)
+	}
+
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.keysFetchedAt = time.Now()
+	keys, err := fetchJwks(ctx, discovery.JwksUri)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+
+	if key, ok := p.cachedKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("signing key %q not found", kid)
+}
+
+// cachedKey returns the key of the ID among the keys fetched, or the only key
+// if the ID is empty.
+func (p *OidcProvider) cachedKey(kid string) (any, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.keys[kid]; ok {
+		return key, true
+	} else if len(p.keys) == 1 && kid == "" {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func fetchJwks(ctx context.Context, uri string) (map[string]any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := doJson(req, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+
+	keys := make(map[string]any, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		switch k.Kty {
+		case "RSA":
+			n, err1 := base64.RawURLEncoding.DecodeString(k.N)
+			e, err2 := base64.RawURLEncoding.DecodeString(k.E)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+			curve, ok := curves[k.Crv]
+			x, err1 := base64.RawURLEncoding.DecodeString(k.X)
+			y, err2 := base64.RawURLEncoding.DecodeString(k.Y)
+			if !ok || err1 != nil || err2 != nil {
+				continue
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+
+	return keys, nil
+}
+
+func doJson(req *http.Request, v any) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	} else if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s responded %d: %s", req.URL.Redacted(), resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+func isTrue(value any) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	default:
+		return false
+	}
+}
+
+// CodeChallenge returns the S256 PKCE code challenge of the verifier.
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// IsEmailAllowed reports whether the email is of the domains allowed by the
+// provider settings.
+func IsEmailAllowed(cfg config.SsoProviderConfig, email string) bool {
+	if len(cfg.AllowedDomains) == 0 {
+		return true
+	}
+
+	_, domain, ok := strings.Cut(email, "@")
+	return ok && slices.ContainsFunc(cfg.AllowedDomains, func(allowed string) bool {
+		return strings.EqualFold(allowed, domain)
+	})
+}
+
+var _ Provider = (*OidcProvider)(nil)
@@ -0,0 +1,104 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+
+	"bilingo/config"
+	"bilingo/server/app"
+)
+
+var (
+	ErrProviderNotFound = errors.New("identity provider not found")
+	ErrInvalidIdToken   = errors.New("invalid id token")
+)
+
+// Identity is a user identity asserted by an external identity provider.
+type Identity struct {
+	Provider      string // The name of the provider
+	Subject       string // The ID of the user at the provider
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Provider is an external identity provider users log in with through the
+// browser, using the authorization code flow with PKCE.
+type Provider interface {
+	Name() string
+	DisplayName() string
+	// AuthCodeURL returns the URL to redirect the user to for logging in, the
+	// state and nonce are checked when the user comes back, and the verifier is
+	// the PKCE code verifier.
+	AuthCodeURL(ctx context.Context, redirectUri string, state string, nonce string, verifier string) (string, error)
+	// Exchange exchanges the authorization code for the identity of the user.
+	Exchange(ctx context.Context, redirectUri string, code string, nonce string, verifier string) (*Identity, error)
+	// Settings returns the rules applied to the identities of the provider.
+	Settings() config.SsoProviderConfig
+}
+
+// Registry keeps the identity providers users can log in with, an app has a
+// registry of its own.
+type Registry struct {
+	providers []Provider
+	mu        sync.RWMutex
+}
+
+// The registry of the providers in the configuration of the environment, used
+// outside of apps
+var defaultRegistry = sync.OnceValue(func() *Registry {
+	return NewRegistry(config.GetConfig().Auth.Sso)
+})
+
+// NewRegistry creates a registry of the OIDC providers in the configuration.
+func NewRegistry(configs []config.SsoProviderConfig) *Registry {
+	r := &Registry{}
+	for _, cfg := range configs {
+		r.providers = append(r.providers, NewOidcProvider(cfg))
+	}
+	return r
+}
+
+// FromContext returns the registry of the app the context carries, or the one
+// of the configuration of the environment outside of apps.
+func FromContext(ctx context.Context) *Registry {
+	if a := app.FromContext(ctx); a != nil {
+		if r, ok := app.Get[*Registry](a); ok {
+			return r
+		}
+	}
+	return defaultRegistry()
+}
+
+// Register registers an identity provider, replacing the one of the same name.
+func (r *Registry) Register(p Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.providers = slices.DeleteFunc(r.providers, func(existing Provider) bool {
+		return existing.Name() == p.Name()
+	})
+	r.providers = append(r.providers, p)
+}
+
+// Get returns the identity provider of the name.
+func (r *Registry) Get(name string) (Provider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, p := range r.providers {
+		if p.Name() == name {
+			return p, nil
+		}
+	}
+	return nil, ErrProviderNotFound
+}
+
+// List returns the registered identity providers.
+func (r *Registry) List() []Provider {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.providers)
+}
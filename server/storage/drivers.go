@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// LocalStorage stores files in a directory of the local file system, the
+// content types are derived from the extensions of the keys.
+type LocalStorage struct {
+	Dir     string
+	BaseUrl string // The URL the files are served at
+}
+
+func (s *LocalStorage) path(key string) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(key)), nil
+}
+
+func (s *LocalStorage) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	// Write to a temporary file first, so that readers never see a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to store file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to store file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to store file: %w", err)
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func (s *LocalStorage) Get(ctx context.Context, key string) (*Object, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	} else if stat.IsDir() {
+		file.Close()
+		return nil, ErrNotFound
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return &Object{ReadCloser: file, ContentType: contentType, Size: stat.Size()}, nil
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+func (s *LocalStorage) URL(key string) string {
+	return joinUrl(s.BaseUrl, key)
+}
+
+func (s *LocalStorage) SignedURL(ctx context.Context, key string, expires time.Duration, filename string) (string, error) {
+	return signUrl(ctx, s.BaseUrl, key, expires, filename)
+}
+
+// MemoryStorage keeps files in memory, useful for tests.
+type MemoryStorage struct {
+	BaseUrl string
+
+	mu    sync.RWMutex
+	files map[string]memoryFile
+}
+
+type memoryFile struct {
+	data        []byte
+	contentType string
+}
+
+func (s *MemoryStorage) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to store file: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.files == nil {
+		s.files = map[string]memoryFile{}
+	}
+	s.files[key] = memoryFile{data: data, contentType: contentType}
+	return nil
+}
+
+func (s *MemoryStorage) Get(ctx context.Context, key string) (*Object, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	file, ok := s.files[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return &Object{
+		ReadCloser:  io.NopCloser(bytes.NewReader(file.data)),
+		ContentType: file.contentType,
+		Size:        int64(len(file.data)),
+	}, nil
+}
+
+func (s *MemoryStorage) Delete(ctx context.Context, key string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.files, key)
+	return nil
+}
+
+func (s *MemoryStorage) URL(key string) string {
+	return joinUrl(s.BaseUrl, key)
+}
+
+func (s *MemoryStorage) SignedURL(ctx context.Context, key string, expires time.Duration, filename string) (string, error) {
+	return signUrl(ctx, s.BaseUrl, key, expires, filename)
+}
+
+// Keys returns the keys of the stored files.
+func (s *MemoryStorage) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.files))
+	for key := range s.files {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+var (
+	_ Blob = (*LocalStorage)(nil)
+	_ Blob = (*MemoryStorage)(nil)
+	_ Blob = (*S3Storage)(nil)
+)
@@ -0,0 +1,134 @@
+// Package mocks3 is a minimal S3-compatible server for developing and testing
+// the S3 storage without a real bucket. It keeps the objects of a single bucket
+// in memory, addressed in the path, and checks the signatures of the requests
+// like S3 does.
+package mocks3
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bilingo/server/storage/sigv4"
+)
+
+type object struct {
+	data        []byte
+	contentType string
+	modifiedAt  time.Time
+}
+
+// Server is the mock S3 server, which serves PUT, GET, HEAD and DELETE of the
+// objects at `/<bucket>/<key>`.
+type Server struct {
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+
+	mu      sync.RWMutex
+	objects map[string]object
+}
+
+type errorDocument struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != s.Bucket {
+		writeError(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+		return
+	} else if key == "" {
+		writeError(w, http.StatusNotImplemented, "NotImplemented", "Only the object operations are supported")
+		return
+	}
+
+	err := sigv4.Verify(r, func(accessKey string) (sigv4.Credentials, bool) {
+		return sigv4.Credentials{
+			AccessKey: s.AccessKey,
+			SecretKey: s.SecretKey,
+			Region:    s.Region,
+			Service:   "s3",
+		}, accessKey == s.AccessKey
+	}, time.Now())
+	if err != nil {
+		writeError(w, http.StatusForbidden, "SignatureDoesNotMatch", err.Error())
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		s.putObject(w, r, key)
+	case http.MethodGet, http.MethodHead:
+		s.getObject(w, r, key)
+	case http.MethodDelete:
+		s.mu.Lock()
+		delete(s.objects, key)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "The method is not allowed")
+	}
+}
+
+func (s *Server) putObject(w http.ResponseWriter, r *http.Request, key string) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return
+	}
+	hash := r.Header.Get("X-Amz-Content-Sha256")
+	if hash != sigv4.UnsignedPayload && hash != sigv4.HashPayload(data) {
+		writeError(w, http.StatusBadRequest, "XAmzContentSHA256Mismatch", "The payload hash does not match")
+		return
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "binary/octet-stream" // As S3 defaults to
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.objects == nil {
+		s.objects = map[string]object{}
+	}
+	s.objects[key] = object{data: data, contentType: contentType, modifiedAt: time.Now()}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) getObject(w http.ResponseWriter, r *http.Request, key string) {
+	s.mu.RLock()
+	obj, ok := s.objects[key]
+	s.mu.RUnlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist")
+		return
+	}
+
+	w.Header().Set("Content-Type", obj.contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+	w.Header().Set("Last-Modified", obj.modifiedAt.UTC().Format(http.TimeFormat))
+	if disposition := r.URL.Query().Get("response-content-disposition"); disposition != "" {
+		w.Header().Set("Content-Disposition", disposition)
+	}
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		_, _ = io.Copy(w, bytes.NewReader(obj.data))
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(xml.Header))
+	_ = xml.NewEncoder(w).Encode(errorDocument{Code: code, Message: message})
+}
@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"bilingo/server/storage/sigv4"
+)
+
+// S3Storage stores files in a bucket of S3 or an S3-compatible service, e.g.
+// MinIO. The bucket is expected to be private, the public files are served
+// through BaseUrl, which is the application or a CDN in front of the bucket.
+type S3Storage struct {
+	Endpoint  string // e.g. `https://s3.us-east-1.amazonaws.com`
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// Address the bucket in the path rather than the host name, which most
+	// S3-compatible services require
+	PathStyle bool
+	BaseUrl   string
+	Client    *http.Client // Defaults to http.DefaultClient
+}
+
+func (s *S3Storage) credentials() sigv4.Credentials {
+	return sigv4.Credentials{AccessKey: s.AccessKey, SecretKey: s.SecretKey, Region: s.Region, Service: "s3"}
+}
+
+func (s *S3Storage) client() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return http.DefaultClient
+}
+
+// objectUrl returns the URL of the object of the key in the bucket.
+func (s *S3Storage) objectUrl(key string) (*url.URL, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(s.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint: %w", err)
+	}
+	if s.PathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.Bucket + "/" + key
+	} else {
+		u.Host = s.Bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	}
+	// Send the path encoded the way it's signed
+	u.RawPath = sigv4.EscapePath(u.Path)
+	return u, nil
+}
+
+func (s *S3Storage) do(ctx context.Context, method string, key string, body []byte, contentType string) (*http.Response, error) {
+	u, err := s.objectUrl(key)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	sigv4.SignRequest(req, sigv4.HashPayload(body), s.credentials(), time.Now())
+
+	return s.client().Do(req)
+}
+
+// Put uploads the file in a single request, so the file is read into memory,
+// which suits the files of limited sizes the application stores.
+func (s *S3Storage) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to store file: %w", err)
+	}
+
+	resp, err := s.do(ctx, http.MethodPut, key, data, contentType)
+	if err != nil {
+		return fmt.Errorf("failed to store file: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to store file: %w", s3Error(resp))
+	}
+	return nil
+}
+
+func (s *S3Storage) Get(ctx context.Context, key string) (*Object, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	} else if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, fmt.Errorf("failed to open file: %w", s3Error(resp))
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(key))
+	}
+	return &Object{ReadCloser: resp.Body, ContentType: contentType, Size: resp.ContentLength}, nil
+}
+
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, "")
+	if err != nil {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	defer resp.Body.Close()
+	// Deleting a missing object succeeds with 204 as well
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to delete file: %w", s3Error(resp))
+	}
+	return nil
+}
+
+func (s *S3Storage) URL(key string) string {
+	return joinUrl(s.BaseUrl, key)
+}
+
+// SignedURL returns a presigned URL of the object, which is downloaded from the
+// bucket directly.
+func (s *S3Storage) SignedURL(ctx context.Context, key string, expires time.Duration, filename string) (string, error) {
+	u, err := s.objectUrl(key)
+	if err != nil {
+		return "", err
+	}
+	if filename != "" {
+		query := u.Query()
+		query.Set("response-content-disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+		u.RawQuery = query.Encode()
+	}
+
+	return sigv4.PresignURL(http.MethodGet, u, s.credentials(), time.Now(), expires), nil
+}
+
+// s3Error returns the error of the failed response, with the code of the XML
+// error document if there's one.
+func s3Error(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if start := bytes.Index(body, []byte("<Code>")); start >= 0 {
+		if end := bytes.Index(body[start:], []byte("</Code>")); end >= 0 {
+			return fmt.Errorf("S3 responded %d %s", resp.StatusCode, body[start+6:start+end])
+		}
+	}
+	return fmt.Errorf("S3 responded %d", resp.StatusCode)
+}
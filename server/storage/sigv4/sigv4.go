@@ -0,0 +1,253 @@
+// Package sigv4 signs and verifies requests with AWS Signature Version 4, the
+// authentication of S3 and the S3-compatible services.
+package sigv4
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Algorithm = "AWS4-HMAC-SHA256"
+	// UnsignedPayload is the payload hash of presigned URLs, whose bodies
+	// aren't known in advance.
+	UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+	timeFormat = "20060102T150405Z"
+	dateFormat = "20060102"
+)
+
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// Credentials are the keys and the scope requests are signed for.
+type Credentials struct {
+	AccessKey string
+	SecretKey string
+	Region    string
+	Service   string // e.g. `s3`
+}
+
+// HashPayload returns the hex-encoded SHA-256 of the payload.
+func HashPayload(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// SignRequest signs the request with the Authorization header, the payload
+// hash is that of the body, or UnsignedPayload.
+func SignRequest(req *http.Request, payloadHash string, creds Credentials, now time.Time) {
+	now = now.UTC()
+	req.Header.Set("X-Amz-Date", now.Format(timeFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	if req.Header.Get("Content-Type") != "" {
+		headers = append(headers, "content-type")
+		slices.Sort(headers)
+	}
+
+	scope := scope(now, creds)
+	canonical := canonicalRequest(req.Method, req.URL, req.URL.Query(), req.Header, requestHost(req), headers, payloadHash)
+	signature := sign(creds.SecretKey, now, creds, stringToSign(now, scope, canonical))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		Algorithm, creds.AccessKey, scope, strings.Join(headers, ";"), signature))
+}
+
+// PresignURL returns the URL signed with query parameters, which is valid for
+// the given duration without any credentials.
+func PresignURL(method string, u *url.URL, creds Credentials, now time.Time, expires time.Duration) string {
+	now = now.UTC()
+	scope := scope(now, creds)
+
+	query := u.Query()
+	query.Set("X-Amz-Algorithm", Algorithm)
+	query.Set("X-Amz-Credential", creds.AccessKey+"/"+scope)
+	query.Set("X-Amz-Date", now.Format(timeFormat))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	canonical := canonicalRequest(method, u, query, http.Header{}, u.Host, []string{"host"}, UnsignedPayload)
+	query.Set("X-Amz-Signature", sign(creds.SecretKey, now, creds, stringToSign(now, scope, canonical)))
+
+	signed := *u
+	signed.RawQuery = canonicalQuery(query)
+	return signed.String()
+}
+
+// Verify checks the signature of the request, in the Authorization header or
+// the query of a presigned URL, with the secret key of the access key returned
+// by the lookup. The payload hash of the header isn't checked against the body.
+func Verify(req *http.Request, lookup func(accessKey string) (Credentials, bool), now time.Time) error {
+	query := req.URL.Query()
+	var (
+		credential, signedHeaders, signature, date, payloadHash string
+		expires                                                 time.Duration
+	)
+
+	if query.Get("X-Amz-Algorithm") != "" {
+		if query.Get("X-Amz-Algorithm") != Algorithm {
+			return fmt.Errorf("%w: unsupported algorithm", ErrInvalidSignature)
+		}
+		credential = query.Get("X-Amz-Credential")
+		signedHeaders = query.Get("X-Amz-SignedHeaders")
+		signature = query.Get("X-Amz-Signature")
+		date = query.Get("X-Amz-Date")
+		payloadHash = UnsignedPayload
+		seconds, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+		if err != nil {
+			return fmt.Errorf("%w: invalid expiry", ErrInvalidSignature)
+		}
+		expires = time.Duration(seconds) * time.Second
+		query.Del("X-Amz-Signature")
+	} else {
+		auth, ok := strings.CutPrefix(req.Header.Get("Authorization"), Algorithm+" ")
+		if !ok {
+			return fmt.Errorf("%w: missing authorization", ErrInvalidSignature)
+		}
+		for part := range strings.SplitSeq(auth, ",") {
+			key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			switch key {
+			case "Credential":
+				credential = value
+			case "SignedHeaders":
+				signedHeaders = value
+			case "Signature":
+				signature = value
+			}
+		}
+		date = req.Header.Get("X-Amz-Date")
+		payloadHash = req.Header.Get("X-Amz-Content-Sha256")
+		expires = 15 * time.Minute // The clock skew S3 tolerates
+	}
+
+	signedAt, err := time.Parse(timeFormat, date)
+	if err != nil {
+		return fmt.Errorf("%w: invalid date", ErrInvalidSignature)
+	}
+	if now.After(signedAt.Add(expires)) || signedAt.After(now.Add(15*time.Minute)) {
+		return fmt.Errorf("%w: expired", ErrInvalidSignature)
+	}
+
+	accessKey, scopeValue, _ := strings.Cut(credential, "/")
+	creds, ok := lookup(accessKey)
+	if !ok {
+		return fmt.Errorf("%w: unknown access key", ErrInvalidSignature)
+	}
+	if scopeValue != scope(signedAt, creds) {
+		return fmt.Errorf("%w: invalid scope", ErrInvalidSignature)
+	}
+
+	headers := strings.Split(signedHeaders, ";")
+	canonical := canonicalRequest(req.Method, req.URL, query, req.Header, requestHost(req), headers, payloadHash)
+	expected := sign(creds.SecretKey, signedAt, creds, stringToSign(signedAt, scopeValue, canonical))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func requestHost(req *http.Request) string {
+	if req.Host != "" {
+		return req.Host
+	}
+	return req.URL.Host
+}
+
+func scope(t time.Time, creds Credentials) string {
+	return t.Format(dateFormat) + "/" + creds.Region + "/" + creds.Service + "/aws4_request"
+}
+
+func canonicalRequest(method string, u *url.URL, query url.Values, header http.Header, host string, signedHeaders []string, payloadHash string) string {
+	var headers strings.Builder
+	for _, name := range signedHeaders {
+		value := header.Get(name)
+		if name == "host" {
+			value = host
+		}
+		headers.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	return strings.Join([]string{
+		method,
+		escape(path, false),
+		canonicalQuery(query),
+		headers.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+}
+
+// canonicalQuery encodes the query sorted by the keys, with the encoding of
+// escape, which differs from url.Values.Encode for spaces.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var parts []string
+	for _, key := range keys {
+		values := slices.Clone(query[key])
+		slices.Sort(values)
+		for _, value := range values {
+			parts = append(parts, escape(key, true)+"="+escape(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func stringToSign(t time.Time, scope string, canonicalRequest string) string {
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	return Algorithm + "\n" + t.Format(timeFormat) + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+}
+
+func sign(secretKey string, t time.Time, creds Credentials, stringToSign string) string {
+	key := hmacSha256([]byte("AWS4"+secretKey), t.Format(dateFormat))
+	key = hmacSha256(key, creds.Region)
+	key = hmacSha256(key, creds.Service)
+	key = hmacSha256(key, "aws4_request")
+	return hex.EncodeToString(hmacSha256(key, stringToSign))
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// EscapePath percent-encodes the path the way it's signed, requests should be
+// sent with the path encoded the same.
+func EscapePath(path string) string {
+	return escape(path, false)
+}
+
+// escape percent-encodes everything but the unreserved characters of RFC 3986,
+// and the slashes unless encodeSlash is set.
+func escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bilingo/config"
+	"bilingo/server/app"
+)
+
+var (
+	ErrNotFound   = errors.New("file not found")
+	ErrInvalidKey = errors.New("invalid file key")
+	// ErrInvalidSignature is returned for signed URLs which are tampered with
+	// or expired.
+	ErrInvalidSignature = errors.New("invalid or expired signature")
+)
+
+// Object is a stored file being read, the caller must close it.
+type Object struct {
+	io.ReadCloser
+	ContentType string
+	Size        int64
+}
+
+// Blob stores files by keys, which are slash-separated paths such as
+// `avatars/<user>/<size>.png`. The files under PrivatePrefix are only
+// available at the signed URLs.
+type Blob interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	// Get opens the file of the key, it fails with ErrNotFound if there's none.
+	Get(ctx context.Context, key string) (*Object, error)
+	// Delete deletes the file of the key, it does nothing if there's none.
+	Delete(ctx context.Context, key string) error
+	// URL returns the URL the file of the key is available at.
+	URL(key string) string
+	// SignedURL returns a URL the file of the key is available at until it
+	// expires, which is downloaded as the filename unless it's empty. URLs
+	// signed by the app are signed with the secret of the app the context
+	// carries.
+	SignedURL(ctx context.Context, key string, expires time.Duration, filename string) (string, error)
+}
+
+// PrivatePrefix is the prefix of the keys of the files which aren't public.
+const PrivatePrefix = "private/"
+
+var (
+	defaultStorage Blob
+	defaultMu      sync.RWMutex
+	defaultOnce    sync.Once
+)
+
+// New creates the storage of the driver set in the configuration.
+func New(cfg config.StorageConfig) Blob {
+	switch cfg.Driver {
+	case config.StorageLocal:
+		return &LocalStorage{Dir: cfg.Dir, BaseUrl: cfg.BaseUrl}
+	case config.StorageS3:
+		return &S3Storage{
+			Endpoint:  cfg.Endpoint,
+			Region:    cfg.Region,
+			Bucket:    cfg.Bucket,
+			AccessKey: cfg.AccessKey,
+			SecretKey: cfg.SecretKey,
+			PathStyle: cfg.PathStyle,
+			BaseUrl:   cfg.BaseUrl,
+		}
+	default:
+		return &MemoryStorage{BaseUrl: cfg.BaseUrl}
+	}
+}
+
+// Register provides the storage of the driver in the configuration of the app.
+func Register(a *app.App) error {
+	app.Provide(a, New(a.Config.Storage))
+	return nil
+}
+
+// FromContext returns the storage of the app the context carries, or the
+// default one outside of apps.
+func FromContext(ctx context.Context) Blob {
+	if a := app.FromContext(ctx); a != nil {
+		if s, ok := app.Get[Blob](a); ok {
+			return s
+		}
+	}
+	return Default()
+}
+
+// Default returns the storage of the driver set in the configuration of the
+// environment.
+func Default() Blob {
+	defaultOnce.Do(func() {
+		defaultMu.Lock()
+		defer defaultMu.Unlock()
+		if defaultStorage != nil {
+			return // Replaced with SetDefault already
+		}
+		defaultStorage = New(config.GetConfig().Storage)
+	})
+
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultStorage
+}
+
+// SetDefault replaces the default storage, e.g. with a MemoryStorage in tests.
+func SetDefault(s Blob) {
+	defaultOnce.Do(func() {})
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultStorage = s
+}
+
+// CleanKey checks the key is a relative path without `..` elements, so that
+// it can't refer to files outside of the storage, and returns it cleaned.
+func CleanKey(key string) (string, error) {
+	cleaned := path.Clean(strings.TrimPrefix(key, "/"))
+	if key == "" || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") ||
+		strings.Contains(key, "\\") || strings.ContainsRune(key, 0) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return cleaned, nil
+}
+
+// IsPrivate reports whether the file of the key is only available at the
+// signed URLs.
+func IsPrivate(key string) bool {
+	key, err := CleanKey(key)
+	return err != nil || strings.HasPrefix(key, PrivatePrefix)
+}
+
+// signUrl signs the URL of the file served by the application with the secret
+// of the app the context carries, which is checked with VerifySignedURL, for
+// the drivers without their own signing.
+func signUrl(ctx context.Context, baseUrl string, key string, expires time.Duration, filename string) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(time.Now().Add(expires).Unix(), 10))
+	if filename != "" {
+		query.Set("filename", filename)
+	}
+	query.Set("signature", urlSignature(ctx, key, query.Get("expires"), filename))
+	return joinUrl(baseUrl, key) + "?" + query.Encode(), nil
+}
+
+// VerifySignedURL checks the query of a URL returned by the SignedURL of the
+// local or memory storage of the app the context carries, and returns the
+// filename to download the file as.
+func VerifySignedURL(ctx context.Context, key string, query url.Values) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return "", ErrInvalidSignature
+	}
+	filename := query.Get("filename")
+	expected := urlSignature(ctx, key, query.Get("expires"), filename)
+	if !hmac.Equal([]byte(expected), []byte(query.Get("signature"))) {
+		return "", ErrInvalidSignature
+	}
+	return filename, nil
+}
+
+func urlSignature(ctx context.Context, key string, expires string, filename string) string {
+	mac := hmac.New(sha256.New, []byte(app.Config(ctx).Storage.Secret))
+	mac.Write([]byte(key + "\n" + expires + "\n" + filename))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// joinUrl joins the base URL and the key.
+func joinUrl(baseUrl string, key string) string {
+	return strings.TrimSuffix(baseUrl, "/") + "/" + key
+}
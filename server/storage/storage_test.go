@@ -0,0 +1,212 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"bilingo/config"
+	"bilingo/server/app"
+	"bilingo/server/storage"
+	"bilingo/server/storage/mocks3"
+)
+
+// appContext returns a context carrying an app of the test configuration with
+// the secret signing the URLs.
+func appContext(secret string) context.Context {
+	cfg := config.ForEnv("test")
+	cfg.Storage.Secret = secret
+	return app.New(cfg).Context(context.Background())
+}
+
+// openSigned downloads the file at a URL signed by the app, which is served by
+// the application for the drivers without their own signing.
+func openSigned(ctx context.Context, blob storage.Blob) func(t *testing.T, signedUrl string) ([]byte, string) {
+	return func(t *testing.T, signedUrl string) ([]byte, string) {
+		t.Helper()
+		u, err := url.Parse(signedUrl)
+		if err != nil {
+			t.Fatalf("invalid signed URL: %v", err)
+		}
+		key := strings.TrimPrefix(u.Path, "/api/system/files/")
+		filename, err := storage.VerifySignedURL(ctx, key, u.Query())
+		if err != nil {
+			t.Fatalf("failed to verify signed URL: %v", err)
+		}
+		return readFile(t, ctx, blob, key), filename
+	}
+}
+
+func readFile(t *testing.T, ctx context.Context, blob storage.Blob, key string) []byte {
+	t.Helper()
+	obj, err := blob.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("failed to get %s: %v", key, err)
+	}
+	defer obj.Close()
+	data, err := io.ReadAll(obj)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", key, err)
+	}
+	return data
+}
+
+// testBlob checks the behavior every driver has to share, open downloads the
+// file at a signed URL and returns the filename it's downloaded as.
+func testBlob(t *testing.T, ctx context.Context, blob storage.Blob, open func(t *testing.T, signedUrl string) ([]byte, string)) {
+	key := "private/notes/a.txt"
+	if _, err := blob.Get(ctx, key); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound before storing, got %v", err)
+	}
+
+	if err := blob.Put(ctx, key, strings.NewReader("first"), "text/plain"); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+	if err := blob.Put(ctx, key, strings.NewReader("second"), "text/plain"); err != nil {
+		t.Fatalf("failed to overwrite: %v", err)
+	}
+	obj, err := blob.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	data, _ := io.ReadAll(obj)
+	obj.Close()
+	if string(data) != "second" || obj.Size != int64(len("second")) || !strings.HasPrefix(obj.ContentType, "text/plain") {
+		t.Fatalf("unexpected file: %q of %d bytes and type %q", data, obj.Size, obj.ContentType)
+	}
+
+	if u := blob.URL("avatars/a.png"); !strings.HasSuffix(u, "/api/system/files/avatars/a.png") {
+		t.Fatalf("unexpected URL: %s", u)
+	}
+	signed, err := blob.SignedURL(ctx, key, time.Minute, "notes.txt")
+	if err != nil {
+		t.Fatalf("failed to sign URL: %v", err)
+	}
+	if data, filename := open(t, signed); string(data) != "second" || !strings.Contains(filename, "notes.txt") {
+		t.Fatalf("unexpected download: %q as %q", data, filename)
+	}
+
+	for _, invalid := range []string{"", "../a.txt", "a/../../b.txt", `a\b.txt`} {
+		if err := blob.Put(ctx, invalid, strings.NewReader("x"), "text/plain"); !errors.Is(err, storage.ErrInvalidKey) {
+			t.Errorf("expected ErrInvalidKey putting %q, got %v", invalid, err)
+		}
+		if _, err := blob.Get(ctx, invalid); !errors.Is(err, storage.ErrInvalidKey) {
+			t.Errorf("expected ErrInvalidKey getting %q, got %v", invalid, err)
+		}
+	}
+
+	if err := blob.Delete(ctx, key); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+	if _, err := blob.Get(ctx, key); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after deleting, got %v", err)
+	}
+	if err := blob.Delete(ctx, key); err != nil {
+		t.Fatalf("failed to delete a missing file: %v", err)
+	}
+}
+
+func TestMemoryStorage(t *testing.T) {
+	t.Parallel()
+	ctx := appContext("secret")
+	blob := &storage.MemoryStorage{BaseUrl: "/api/system/files"}
+	testBlob(t, ctx, blob, openSigned(ctx, blob))
+}
+
+func TestLocalStorage(t *testing.T) {
+	t.Parallel()
+	ctx := appContext("secret")
+	blob := &storage.LocalStorage{Dir: t.TempDir(), BaseUrl: "/api/system/files"}
+	testBlob(t, ctx, blob, openSigned(ctx, blob))
+}
+
+func TestS3Storage(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(&mocks3.Server{Bucket: "bilingo", Region: "us-east-1", AccessKey: "access", SecretKey: "secret"})
+	t.Cleanup(server.Close)
+
+	blob := &storage.S3Storage{
+		Endpoint:  server.URL,
+		Region:    "us-east-1",
+		Bucket:    "bilingo",
+		AccessKey: "access",
+		SecretKey: "secret",
+		PathStyle: true,
+		BaseUrl:   "/api/system/files",
+	}
+
+	// Presigned URLs are downloaded from the bucket directly
+	testBlob(t, context.Background(), blob, func(t *testing.T, signedUrl string) ([]byte, string) {
+		t.Helper()
+		resp, err := http.Get(signedUrl)
+		if err != nil {
+			t.Fatalf("failed to download: %v", err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("failed to download: %d %s", resp.StatusCode, data)
+		}
+		return data, resp.Header.Get("Content-Disposition")
+	})
+
+	// The bucket rejects requests signed with other keys
+	forged := *blob
+	forged.SecretKey = "forged"
+	if err := forged.Put(context.Background(), "a.txt", strings.NewReader("x"), "text/plain"); err == nil {
+		t.Fatal("stored a file with a forged signature")
+	}
+}
+
+func TestVerifySignedURL(t *testing.T) {
+	t.Parallel()
+	if cfg := config.ForEnv("test"); cfg.Storage.Secret == "" || cfg.Storage.Secret == cfg.Auth.Secret {
+		t.Fatal("the URLs are signed with the key of the tokens by default")
+	}
+
+	ctx := appContext("secret")
+	blob := &storage.MemoryStorage{BaseUrl: "/api/system/files"}
+	key := "private/a.txt"
+
+	sign := func(expires time.Duration) url.Values {
+		signed, err := blob.SignedURL(ctx, key, expires, "a.txt")
+		if err != nil {
+			t.Fatalf("failed to sign URL: %v", err)
+		}
+		u, _ := url.Parse(signed)
+		return u.Query()
+	}
+
+	if filename, err := storage.VerifySignedURL(ctx, key, sign(time.Minute)); err != nil || filename != "a.txt" {
+		t.Fatalf("failed to verify: %q %v", filename, err)
+	}
+
+	tampered := sign(time.Minute)
+	tampered.Set("filename", "b.exe")
+	expiresLater := sign(time.Minute)
+	expiresLater.Set("expires", "99999999999")
+	tests := []struct {
+		name  string
+		ctx   context.Context
+		key   string
+		query url.Values
+	}{
+		{"expired", ctx, key, sign(-time.Minute)},
+		{"key", ctx, "private/b.txt", sign(time.Minute)},
+		{"filename", ctx, key, tampered},
+		{"expiry", ctx, key, expiresLater},
+		{"secret", appContext("other secret"), key, sign(time.Minute)},
+		{"unsigned", ctx, key, url.Values{"expires": {"99999999999"}}},
+	}
+	for _, test := range tests {
+		if _, err := storage.VerifySignedURL(test.ctx, test.key, test.query); !errors.Is(err, storage.ErrInvalidSignature) {
+			t.Errorf("expected ErrInvalidSignature for the tampered %s, got %v", test.name, err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package testutil
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"bilingo/config"
+	"bilingo/domains"
+	"bilingo/server/app"
+	"bilingo/server/db"
+	"bilingo/server/events"
+	"bilingo/server/jobs"
+	"bilingo/server/mailer"
+	"bilingo/server/realtime"
+	"bilingo/server/storage"
+)
+
+// NewApp creates an app of the test configuration, changed by the configure
+// functions, with the event bus, the job queue, the realtime hub and all
+// domains, an isolated database, and an empty in-memory mailer and storage, and
+// starts it. The app is stopped after the test. Apps don't share any of these,
+// so tests using them can run in parallel.
+func NewApp(t testing.TB, configure ...func(cfg *config.Config)) *app.App {
+	t.Helper()
+	cfg := config.ForEnv("test")
+	for _, fn := range configure {
+		fn(&cfg)
+	}
+
+	conn, drop, err := openDB(os.Getenv(TestDBUrlEnv), cfg.DB)
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	t.Cleanup(drop)
+
+	a := app.New(cfg)
+	if err := db.Register(a); err != nil {
+		t.Fatalf("failed to register databases: %v", err)
+	}
+	// Every binding resolves to one of the names, see db.Registry.Bind
+	r, _ := app.Get[*db.Registry](a)
+	for _, name := range r.Names() {
+		r.Set(name, conn)
+	}
+	for _, name := range cfg.DBBindings {
+		r.Set(name, conn)
+	}
+	app.Provide[mailer.Mailer](a, &mailer.MemoryMailer{})
+	app.Provide[storage.Blob](a, &storage.MemoryStorage{BaseUrl: cfg.Storage.BaseUrl})
+	if err := a.Register(events.Register, jobs.Register, mailer.RegisterJobs, realtime.Register, domains.Register); err != nil {
+		t.Fatalf("failed to register modules: %v", err)
+	}
+
+	if err := a.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start app: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := a.Stop(ctx); err != nil {
+			t.Errorf("failed to stop app: %v", err)
+		}
+	})
+	return a
+}
+
+// Mailer returns the in-memory mailer of the app created by NewApp, to inspect
+// the sent mails.
+func Mailer(t testing.TB, a *app.App) *mailer.MemoryMailer {
+	t.Helper()
+	m, _ := app.Get[mailer.Mailer](a)
+	memory, ok := m.(*mailer.MemoryMailer)
+	if !ok {
+		t.Fatalf("the mailer of the app is not in memory")
+	}
+	return memory
+}
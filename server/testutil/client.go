@@ -0,0 +1,166 @@
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bilingo/common"
+	"bilingo/server/app"
+	"bilingo/server/auth"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Client sends requests to the API of an app in process, keeping the cookies
+// between requests like a browser.
+type Client struct {
+	t       testing.TB
+	app     *app.App
+	cookies map[string]*http.Cookie
+}
+
+// Response is the response to a request of the client.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// NewClient creates a client of the app without cookies, which is logged out.
+func NewClient(t testing.TB, a *app.App) *Client {
+	return &Client{t: t, app: a, cookies: map[string]*http.Cookie{}}
+}
+
+// Get sends a GET request to the path under /api, e.g. `/articles?page=1`.
+func (c *Client) Get(path string) *Response {
+	c.t.Helper()
+	return c.Do(http.MethodGet, path, nil)
+}
+
+// Post sends a POST request with the body encoded in JSON, if not nil.
+func (c *Client) Post(path string, body any) *Response {
+	c.t.Helper()
+	return c.Do(http.MethodPost, path, body)
+}
+
+// Patch sends a PATCH request with the body encoded in JSON, if not nil.
+func (c *Client) Patch(path string, body any) *Response {
+	c.t.Helper()
+	return c.Do(http.MethodPatch, path, body)
+}
+
+// Delete sends a DELETE request.
+func (c *Client) Delete(path string) *Response {
+	c.t.Helper()
+	return c.Do(http.MethodDelete, path, nil)
+}
+
+// Do sends a request with the body encoded in JSON, if not nil, and fails the
+// test if it can't be sent. Bodies of other types are sent with DoRaw.
+func (c *Client) Do(method string, path string, body any) *Response {
+	c.t.Helper()
+	if body == nil {
+		return c.DoRaw(method, path, "", nil)
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		c.t.Fatalf("failed to encode request body: %v", err)
+	}
+	return c.DoRaw(method, path, fiber.MIMEApplicationJSON, bytes.NewReader(data))
+}
+
+// DoRaw sends a request with the body of the content type, e.g. a multipart
+// form.
+func (c *Client) DoRaw(method string, path string, contentType string, body io.Reader) *Response {
+	c.t.Helper()
+	req := httptest.NewRequest(method, "/api"+path, body)
+	if contentType != "" {
+		req.Header.Set(fiber.HeaderContentType, contentType)
+	}
+	for _, cookie := range c.cookies {
+		req.AddCookie(cookie)
+	}
+
+	resp, err := c.app.Fiber.Test(req, -1)
+	if err != nil {
+		c.t.Fatalf("failed to send %s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.t.Fatalf("failed to read response of %s %s: %v", method, path, err)
+	}
+
+	for _, cookie := range resp.Cookies() {
+		if cookie.Value == "" || cookie.MaxAge < 0 || (!cookie.Expires.IsZero() && cookie.Expires.Before(time.Now())) {
+			delete(c.cookies, cookie.Name)
+		} else {
+			c.cookies[cookie.Name] = cookie
+		}
+	}
+	return &Response{Status: resp.StatusCode, Header: resp.Header, Body: data}
+}
+
+// Login logs in with the email and password through the API, failing the test
+// if it's rejected.
+func (c *Client) Login(email string, password string) *Response {
+	c.t.Helper()
+	resp := c.Post("/users/login", map[string]string{"email": email, "password": password})
+	if resp.Status != http.StatusOK {
+		c.t.Fatalf("failed to log in as %s: %d %s", email, resp.Status, resp.Body)
+	}
+	return resp
+}
+
+// LoginAs logs in as the user without the password or two-factor
+// authentication, by setting the authentication cookie directly.
+func (c *Client) LoginAs(userId string) {
+	c.t.Helper()
+	token, err := auth.GenerateToken(c.app.Context(context.Background()), userId)
+	if err != nil {
+		c.t.Fatalf("failed to generate token: %v", err)
+	}
+	name := c.app.Config.Auth.CookieName
+	c.cookies[name] = &http.Cookie{Name: name, Value: token}
+}
+
+// Logout drops the cookies of the client.
+func (c *Client) Logout() {
+	clear(c.cookies)
+}
+
+// Decode decodes the data of the API result in the response, failing the test
+// if the request failed or the data doesn't match.
+func Decode[T any](t testing.TB, resp *Response) T {
+	t.Helper()
+	var result common.ApiResult[T]
+	if err := json.Unmarshal(resp.Body, &result); err != nil {
+		t.Fatalf("failed to decode response: %v: %s", err, resp.Body)
+	}
+	if !result.Success {
+		t.Fatalf("request failed: %d %s", resp.Status, resp.Body)
+	}
+	return result.Data
+}
+
+// Message returns the message of the API result in the response, e.g. the
+// reason of an error.
+func (r *Response) Message() string {
+	var result common.ApiResult[json.RawMessage]
+	if err := json.Unmarshal(r.Body, &result); err != nil || result.Message == nil {
+		return ""
+	}
+	return *result.Message
+}
+
+func (r *Response) String() string {
+	return fmt.Sprintf("%d %s", r.Status, r.Body)
+}
@@ -0,0 +1,256 @@
+package testutil
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"bilingo/domains/article"
+	articleModels "bilingo/domains/article/models"
+	"bilingo/domains/system"
+	systemModels "bilingo/domains/system/models"
+	systemTypes "bilingo/domains/system/types"
+	"bilingo/domains/user"
+	userModels "bilingo/domains/user/models"
+	"bilingo/server/app"
+	"bilingo/server/db"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// DefaultFixtures are loaded by LoadFixtures if no files are given, which are
+// an admin, two users and their articles and comments.
+//
+//go:embed fixtures.yaml
+var DefaultFixtures []byte
+
+// FixtureFile is the format of the YAML fixture files, the users are referred
+// to by their emails in the other records.
+type FixtureFile struct {
+	Users    []UserFixture    `yaml:"users"`
+	Articles []ArticleFixture `yaml:"articles"`
+	Comments []CommentFixture `yaml:"comments"`
+}
+
+type UserFixture struct {
+	ID        string  `yaml:"id"` // Defaults to a random UUID
+	Email     string  `yaml:"email"`
+	Name      string  `yaml:"name"`
+	Password  string  `yaml:"password"` // The plain text password, empty means the user can't log in
+	Birthdate *string `yaml:"birthdate"`
+	// Whether the email is verified, users can't log in before that, defaults
+	// to true
+	Verified *bool `yaml:"verified"`
+	Admin    bool  `yaml:"admin"` // Whether the email is added to Auth.Admins of the app
+}
+
+type ArticleFixture struct {
+	ID       uint    `yaml:"id"` // Defaults to the next ID
+	Title    string  `yaml:"title"`
+	Content  string  `yaml:"content"`
+	Author   string  `yaml:"author"` // The email of the author
+	Category *string `yaml:"category"`
+	Tags     *string `yaml:"tags"`
+	Likes    int     `yaml:"likes"`
+	Dislikes int     `yaml:"dislikes"`
+}
+
+type CommentFixture struct {
+	ID         uint   `yaml:"id"`          // Defaults to the next ID
+	ObjectType string `yaml:"object_type"` // Defaults to article
+	ObjectId   string `yaml:"object_id"`
+	Content    string `yaml:"content"`
+	Author     string `yaml:"author"` // The email of the author
+	ParentId   *uint  `yaml:"parent_id"`
+}
+
+// Fixtures are the records loaded into the test database.
+type Fixtures struct {
+	Users    map[string]*userModels.User // By email, with the plain text passwords
+	Articles []*articleModels.Article    // In the order of the files
+	Comments []*systemModels.Comment     // In the order of the files
+}
+
+// User returns the user of the email, failing the test if there's none.
+func (f *Fixtures) User(t testing.TB, email string) *userModels.User {
+	t.Helper()
+	user, ok := f.Users[email]
+	if !ok {
+		t.Fatalf("no fixture user %s", email)
+	}
+	return user
+}
+
+// LoadFixtures loads the YAML fixture files, or DefaultFixtures if there are
+// none, into the database of the app set up by NewApp. The passwords of users
+// are hashed with the lowest bcrypt cost, which are upgraded when they log in
+// like any outdated hash.
+func LoadFixtures(t testing.TB, a *app.App, files ...string) *Fixtures {
+	t.Helper()
+
+	var sources [][]byte
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("failed to read fixtures: %v", err)
+		}
+		sources = append(sources, data)
+	}
+	if len(sources) == 0 {
+		sources = append(sources, DefaultFixtures)
+	}
+
+	fixtures := &Fixtures{Users: map[string]*userModels.User{}}
+	for i, data := range sources {
+		var file FixtureFile
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			t.Fatalf("failed to parse fixtures %d: %v", i+1, err)
+		}
+		if err := fixtures.load(a, &file); err != nil {
+			t.Fatalf("failed to load fixtures %d: %v", i+1, err)
+		}
+	}
+	return fixtures
+}
+
+func (f *Fixtures) load(a *app.App, file *FixtureFile) error {
+	ctx := a.Context(context.Background())
+	now := time.Now()
+
+	users, err := db.For(ctx, user.DBBinding)
+	if err != nil {
+		return err
+	}
+	var admins []string
+	for _, fixture := range file.Users {
+		model := &userModels.User{
+			ID:        fixture.ID,
+			Email:     fixture.Email,
+			Name:      fixture.Name,
+			Birthdate: fixture.Birthdate,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if model.ID == "" {
+			model.ID = uuid.NewString()
+		}
+		if fixture.Password != "" {
+			hash, err := bcrypt.GenerateFromPassword([]byte(fixture.Password), bcrypt.MinCost)
+			if err != nil {
+				return err
+			}
+			password := string(hash)
+			model.Password = &password
+		}
+		if fixture.Verified == nil || *fixture.Verified {
+			model.EmailVerifiedAt = &now
+		}
+		if err := gorm.G[userModels.User](users).Create(ctx, model); err != nil {
+			return fmt.Errorf("user %s: %w", fixture.Email, err)
+		}
+
+		// The plain text password is kept for logging in
+		if fixture.Password != "" {
+			model.Password = &fixture.Password
+		}
+		f.Users[model.Email] = model
+		if fixture.Admin {
+			admins = append(admins, model.Email)
+		}
+	}
+	if len(admins) > 0 {
+		a.Config.Auth.Admins = append(slices.Clone(a.Config.Auth.Admins), admins...)
+	}
+
+	articles, err := db.For(ctx, article.DBBinding)
+	if err != nil {
+		return err
+	}
+	for _, fixture := range file.Articles {
+		author, err := f.userId(fixture.Author)
+		if err != nil {
+			return fmt.Errorf("article %q: %w", fixture.Title, err)
+		}
+		model := &articleModels.Article{
+			ID:        fixture.ID,
+			CreatedAt: now,
+			UpdatedAt: now,
+			Title:     fixture.Title,
+			Content:   fixture.Content,
+			Author:    author,
+			Category:  fixture.Category,
+			Tags:      fixture.Tags,
+			Likes:     fixture.Likes,
+			Dislikes:  fixture.Dislikes,
+		}
+		if err := gorm.G[articleModels.Article](articles).Create(ctx, model); err != nil {
+			return fmt.Errorf("article %q: %w", fixture.Title, err)
+		}
+		f.Articles = append(f.Articles, model)
+	}
+
+	comments, err := db.For(ctx, system.DBBinding)
+	if err != nil {
+		return err
+	}
+	for _, fixture := range file.Comments {
+		author, err := f.userId(fixture.Author)
+		if err != nil {
+			return fmt.Errorf("comment %q: %w", fixture.Content, err)
+		}
+		objectType := fixture.ObjectType
+		if objectType == "" {
+			objectType = "article"
+		}
+		comment := &systemModels.Comment{
+			ID:         fixture.ID,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+			ObjectInfo: systemTypes.ObjectInfo{ObjectType: objectType, ObjectId: fixture.ObjectId},
+			Content:    fixture.Content,
+			Author:     author,
+			ParentId:   fixture.ParentId,
+		}
+		if err := gorm.G[systemModels.Comment](comments).Create(ctx, comment); err != nil {
+			return fmt.Errorf("comment %q: %w", fixture.Content, err)
+		}
+		f.Comments = append(f.Comments, comment)
+	}
+
+	return nil
+}
+
+func (f *Fixtures) userId(email string) (string, error) {
+	user, ok := f.Users[email]
+	if !ok {
+		return "", fmt.Errorf("no fixture user %s", email)
+	}
+	return user.ID, nil
+}
+
+// CreateUsers creates verified users of the IDs in the database set up by
+// NewDB, for the contract tests of the repositories whose records refer to
+// users.
+func CreateUsers(t testing.TB, ids ...string) {
+	t.Helper()
+	ctx := context.Background()
+	conn, err := db.For(ctx, user.DBBinding)
+	if err != nil {
+		t.Fatalf("failed to connect to user database: %v", err)
+	}
+
+	now := time.Now()
+	for _, id := range ids {
+		model := &userModels.User{ID: id, Email: id + "@example.com", Name: id, EmailVerifiedAt: &now}
+		if err := gorm.G[userModels.User](conn).Create(ctx, model); err != nil {
+			t.Fatalf("failed to create user %s: %v", id, err)
+		}
+	}
+}